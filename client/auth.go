@@ -0,0 +1,17 @@
+package client
+
+import "net/http"
+
+// SetToken authenticates the following requests of the client with the
+// bearer token, a static token or a JWT depending on the authentication of
+// the coordinators. An empty token disables it.
+func (c *RaftKVClient) SetToken(token string) {
+	c.token = token
+}
+
+// setAuthHeader sets the bearer token of req, if any.
+func (c *RaftKVClient) setAuthHeader(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
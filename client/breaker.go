@@ -0,0 +1,202 @@
+package client
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is returned without contacting the cluster while the
+	// circuit breaker of the active endpoint is open.
+	ErrCircuitOpen = errors.New("circuit breaker is open, cluster is struggling")
+	// ErrRetryBudgetExhausted is returned when a retry would exceed the retry budget.
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+)
+
+const (
+	breakerThreshold = 5
+	breakerCooldown  = time.Second
+	baseBackoff      = 10 * time.Millisecond
+	maxBackoff       = 500 * time.Millisecond
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker opens after threshold consecutive failures, rejects attempts
+// for cooldown, and then lets a single probe through (half-open) whose outcome
+// closes or re-opens the circuit.
+type circuitBreaker struct {
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+}
+
+func (b *circuitBreaker) allow(now time.Time) bool {
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// a probe is already in flight
+		return false
+	}
+	return true
+}
+
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	if success {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+		b.failures = 0
+	}
+}
+
+// breakers holds one circuit breaker per endpoint.
+type breakers struct {
+	mu sync.Mutex
+	m  map[string]*circuitBreaker
+}
+
+func newBreakers() *breakers {
+	return &breakers{m: make(map[string]*circuitBreaker)}
+}
+
+func (b *breakers) get(addr string) *circuitBreaker {
+	cb, ok := b.m[addr]
+	if !ok {
+		cb = &circuitBreaker{threshold: breakerThreshold, cooldown: breakerCooldown}
+		b.m[addr] = cb
+	}
+	return cb
+}
+
+func (b *breakers) allow(addr string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.get(addr).allow(time.Now())
+}
+
+func (b *breakers) record(addr string, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.get(addr).record(success, time.Now())
+}
+
+// RetryBudget caps retries to a fraction of the requests issued, plus a small
+// floor of retries per second. It is shared by every client of the process
+// so that callers don't all retry in lockstep against a struggling cluster.
+type RetryBudget struct {
+	mu        sync.Mutex
+	ratio     float64
+	minPerSec float64
+	tokens    float64
+	max       float64
+	last      time.Time
+}
+
+// DefaultRetryBudget is shared by all clients unless replaced with SetRetryBudget.
+var DefaultRetryBudget = NewRetryBudget(0.2, 5)
+
+// NewRetryBudget allows ratio retries per request plus minPerSec retries per second.
+func NewRetryBudget(ratio, minPerSec float64) *RetryBudget {
+	max := minPerSec * 10
+	if max < 1 {
+		max = 1
+	}
+	return &RetryBudget{
+		ratio:     ratio,
+		minPerSec: minPerSec,
+		tokens:    max,
+		max:       max,
+		last:      time.Now(),
+	}
+}
+
+func (b *RetryBudget) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.minPerSec
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+	b.last = now
+}
+
+// deposit is called for every first attempt.
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// withdraw reports whether a retry may be issued.
+func (b *RetryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// SetRetryBudget replaces the retry budget used by the client.
+func (c *RaftKVClient) SetRetryBudget(b *RetryBudget) {
+	c.budget = b
+}
+
+// backoff returns a jittered exponential delay for the given retry.
+func backoff(retry int) time.Duration {
+	d := baseBackoff << uint(retry)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// admit is called before every attempt of a retried operation. It fails fast
+// while the breaker of the active endpoint is open and charges retries against
+// the retry budget, sleeping a jittered backoff before them.
+func (c *RaftKVClient) admit(attempt int) error {
+	if attempt == 0 {
+		c.budget.deposit()
+	} else {
+		if !c.budget.withdraw() {
+			return ErrRetryBudgetExhausted
+		}
+		time.Sleep(backoff(attempt))
+	}
+	if !c.breakers.allow(c.serverAddr) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// recordAttempt feeds the outcome of an attempt to the endpoint breaker.
+// Insufficient funds is an application answer, not a cluster failure.
+func (c *RaftKVClient) recordAttempt(err error) {
+	var e *insufficientFundsError
+	c.breakers.record(c.serverAddr, err == nil || errors.As(err, &e))
+}
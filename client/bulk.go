@@ -0,0 +1,72 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// BulkWrite streams the SET and DEL commands received from cmds, until it is
+// closed, to the coordinator, which proposes them in batches by shard, and
+// calls result with the result of each write, in no particular order. The
+// writes are independent: each succeeds or fails on its own. It returns once
+// the result of every write is received, or with the error ending the
+// stream. Like imports, long streams need a client without timeout.
+func (c *RaftKVClient) BulkWrite(cmds <-chan *raftpb.Command, result func(*raftpb.WriteResult)) error {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(u.Path, "bulk")
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		for cmd := range cmds {
+			if err := common.WriteMessage(w, cmd); err != nil {
+				pw.CloseWithError(err)
+				// the senders are not blocked by a failed stream
+				for range cmds {
+				}
+				return
+			}
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+	req, err := http.NewRequest(http.MethodPost, u.String(), pr)
+	if err != nil {
+		pr.CloseWithError(err)
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		pr.CloseWithError(err)
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		pr.CloseWithError(errors.New(resp.Status))
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(string(body))
+	}
+	r := bufio.NewReader(resp.Body)
+	for {
+		res := &raftpb.WriteResult{}
+		if err := common.ReadMessage(r, res); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// the error ending the stream is not the result of a write
+		if res.Key == "" && res.Error != "" {
+			return errors.New(res.Error)
+		}
+		result(res)
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/raft-kv-store/common"
+)
+
+// revisionHeader carries the revision token of the responses.
+const revisionHeader = "X-Revision"
+
+// Revisions returns the revision token of the client: the latest revision of
+// every shard seen by its writes and reads. The reads of the client wait for
+// the node serving them to have applied these revisions, so that they see
+// every write the client saw before, whichever node serves them.
+func (c *RaftKVClient) Revisions() string {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+	return c.revisions.String()
+}
+
+// ObserveRevisions merges token, the Revisions of another client, into the
+// token of the client, whose reads then see the writes the other client saw.
+func (c *RaftKVClient) ObserveRevisions(token string) error {
+	t, err := common.ParseRevisionToken(token)
+	if err != nil {
+		return err
+	}
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+	c.revisions.Merge(t)
+	return nil
+}
+
+// observeResponse merges the revision token of resp, if any, and records how
+// it was served if it is the reply of a read.
+func (c *RaftKVClient) observeResponse(resp *http.Response) {
+	if h := resp.Header.Get(revisionHeader); h != "" {
+		// a malformed token only costs the guarantee of the next reads
+		c.ObserveRevisions(h)
+	}
+	if info := readInfoOf(resp.Header); info != nil {
+		c.observeRead(info)
+	}
+}
+
+// observeRevision records revision rev of shard.
+func (c *RaftKVClient) observeRevision(shard, rev int64) {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+	c.revisions.Observe(shard, rev)
+}
+
+// minRevision returns the revision of shard the reads must see.
+func (c *RaftKVClient) minRevision(shard int64) int64 {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+	return c.revisions[shard]
+}
+
+// setMinRevision has the read req see the revisions of the client.
+func (c *RaftKVClient) setMinRevision(req *http.Request) {
+	token := c.Revisions()
+	if token == "" {
+		return
+	}
+	q := req.URL.Query()
+	q.Set("min_revision", token)
+	req.URL.RawQuery = q.Encode()
+}
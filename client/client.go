@@ -14,6 +14,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fatih/color"
@@ -59,15 +60,63 @@ type RaftKVClient struct {
 	reader    *bufio.Reader
 	inTxn     bool
 	txnCmds   *raftpb.RaftCommand
+	health    *endpointHealth
+	breakers  *breakers
+	budget    *RetryBudget
+	// hedgeAfter is the latency after which reads are hedged, 0 disables hedging
+	hedgeAfter time.Duration
+	// codec is the name of the codec used by SetFrom
+	codec string
+	// protobuf asks for protobuf replies, see EnableProtobuf
+	protobuf bool
+	// session is the id of the session of the writes, if open, and seq the
+	// sequence number of its last write
+	session string
+	seq     int64
+	// priority is the priority of the writes, common.PriorityNormal by
+	// default
+	priority int32
+	// routing is the shard map of the smart routing or of the
+	// coordinator-less mode, nil if disabled
+	routing *shardRouting
+	// token is the bearer token of the requests, if set
+	token string
+	// adminAddr is the address of the management endpoints, serverAddr if
+	// empty
+	adminAddr string
+	// revisions is the revision token of the writes and reads of the client
+	revMu     sync.Mutex
+	revisions common.RevisionToken
+	// lastRead tells how the last read of the client was served
+	lastRead *raftpb.ReadInfo
+	// showReads prints lastRead after the interactive gets
+	showReads bool
+	// cluster is the cluster of the coordinators the client talks to, any
+	// if empty
+	cluster string
 }
 
 func NewRaftKVClient(serverAddr string, timeout time.Duration) *RaftKVClient {
+	return NewRaftKVClientWithPool(serverAddr, timeout, DefaultPoolConfig)
+}
+
+// NewRaftKVClientWithPool returns a client whose connections to the coordinators
+// are pooled and kept alive according to pool.
+func NewRaftKVClientWithPool(serverAddr string, timeout time.Duration, pool PoolConfig) *RaftKVClient {
 	c := &RaftKVClient{
-		client:     &http.Client{Timeout: timeout},
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: newTransport(pool, timeout),
+		},
 		serverAddr: addURLScheme(serverAddr),
 		Terminate:  make(chan os.Signal, 1),
 		reader:     bufio.NewReader(os.Stdin),
 		txnCmds:    &raftpb.RaftCommand{},
+		health:     newEndpointHealth(pool),
+		breakers:   newBreakers(),
+		budget:     DefaultRetryBudget,
+		codec:      jsonCodec{}.Name(),
+		revisions:  common.RevisionToken{},
 	}
 	return c
 }
@@ -224,7 +273,11 @@ func (c *RaftKVClient) TransferTransaction(cmdArr []string) error {
 
 	var retries int
 	for retries < maxTransferRetries {
+		if err = c.admit(retries); err != nil {
+			return err
+		}
 		err = c.attemptTransfer(fromKey, toKey, transferAmount)
+		c.recordAttempt(err)
 		if err != nil {
 			retries++
 			var e *insufficientFundsError
@@ -322,6 +375,8 @@ func (c *RaftKVClient) Run() {
 		case common.GET:
 			if err := c.Get(cmdArr[1]); err != nil {
 				fmt.Println(err)
+			} else if info := c.LastRead(); c.showReads && info != nil {
+				fmt.Println(FormatReadInfo(info))
 			}
 		case common.SET:
 			val, _ := parseInt64(cmdArr[2])
@@ -352,7 +407,11 @@ func (c *RaftKVClient) Run() {
 }
 
 func (c *RaftKVClient) parseServerAddr(key string) (string, error) {
-	u, err := url.Parse(c.serverAddr)
+	return keyURL(c.serverAddr, key)
+}
+
+func keyURL(addr, key string) (string, error) {
+	u, err := url.Parse(addr)
 	if err != nil {
 		return "", err
 	}
@@ -361,7 +420,11 @@ func (c *RaftKVClient) parseServerAddr(key string) (string, error) {
 }
 
 func (c *RaftKVClient) newRequest(method, key string, data []byte) (*http.Response, error) {
-	url, err := c.parseServerAddr(key)
+	return c.newRequestTo(c.serverAddr, method, key, data)
+}
+
+func (c *RaftKVClient) newRequestTo(addr, method, key string, data []byte) (*http.Response, error) {
+	url, err := keyURL(addr, key)
 	if err != nil {
 		return nil, err
 	}
@@ -369,11 +432,40 @@ func (c *RaftKVClient) newRequest(method, key string, data []byte) (*http.Respon
 	if err != nil {
 		return nil, err
 	}
+	c.setSessionHeaders(req)
+	c.setPriorityHeader(req)
+	if method == http.MethodGet {
+		c.setMinRevision(req)
+		c.setAcceptHeader(req)
+	}
+	return c.doAt(addr, req)
+}
+
+// do sends req to the active endpoint.
+func (c *RaftKVClient) do(req *http.Request) (*http.Response, error) {
+	return c.doAt(c.serverAddr, req)
+}
+
+// doAt sends req and records the outcome against the health of endpoint addr,
+// and the revision token and read annotation of the response. A response of
+// another cluster than the client's counts as a failure.
+func (c *RaftKVClient) doAt(addr string, req *http.Request) (*http.Response, error) {
+	c.setAuthHeader(req)
+	if c.cluster != "" {
+		req.Header.Set(clusterHeader, c.cluster)
+	}
 	resp, err := c.client.Do(req)
+	if err == nil {
+		if err = c.checkCluster(resp); err != nil {
+			resp.Body.Close()
+		}
+	}
 	if err != nil {
+		c.health.failure(addr)
 		return nil, err
 	}
-
+	c.health.success(addr)
+	c.observeResponse(resp)
 	return resp, nil
 }
 
@@ -387,12 +479,7 @@ func (c *RaftKVClient) newTxnRequest(data []byte) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	return resp, nil
+	return c.do(req)
 }
 
 func (c *RaftKVClient) redirectReqToLeader(key, method string, data []byte) error {
@@ -432,7 +519,7 @@ func (c *RaftKVClient) retryReqExceptActive(method, key string, data []byte) (*h
 
 	currActiveServer := c.serverAddr
 	for _, value := range staticCoordServers {
-		if value == currActiveServer {
+		if value == currActiveServer || !c.health.healthy(value) {
 			continue
 		}
 		c.serverAddr = value
@@ -449,43 +536,135 @@ func (c *RaftKVClient) retryReqExceptActive(method, key string, data []byte) (*h
 }
 
 func (c *RaftKVClient) Get(key string) error {
+	body, codec, err := c.getValue(key)
+	if err != nil {
+		return err
+	}
+	if codec != "" {
+		color.HiGreen("Key=%s, Codec=%s, Value=%s", key, codec, body)
+	} else {
+		color.HiGreen(string(body))
+	}
+	return nil
+}
+
+// GetInto decodes the value of key into obj with the codec it was stored with.
+func (c *RaftKVClient) GetInto(key string, obj interface{}) error {
+	body, name, err := c.getValue(key)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return fmt.Errorf("Key=%s holds a numerical value", key)
+	}
+	codec, err := lookupCodec(name)
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(body, obj)
+}
+
+// getValue returns the response body of a GET for key and the codec of the
+// value, empty for numerical values.
+func (c *RaftKVClient) getValue(key string) ([]byte, string, error) {
+	if res, ok, err := c.direct(&raftpb.Command{Method: common.GET, Key: key}); ok {
+		if err != nil {
+			return nil, "", err
+		}
+		body, codec := valueOf(key, res)
+		return body, codec, nil
+	}
 	var resp *http.Response
 	var err error
 
-	resp, err = c.newRequest(http.MethodGet, key, nil)
+	if c.hedgeAfter > 0 {
+		resp, err = c.hedgedGet(key)
+	} else {
+		resp, err = c.newRequest(http.MethodGet, key, nil)
+	}
 	if err != nil {
 		fmt.Println(err)
 		resp, err = c.retryReqExceptActive(http.MethodGet, key, nil)
 	}
 
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
-	if resp.StatusCode == http.StatusOK {
-		color.HiGreen(string(body))
-		return nil
-	} else if resp.StatusCode == http.StatusMisdirectedRequest {
+	body = errorText(resp, body)
+	if resp.StatusCode == http.StatusMisdirectedRequest {
 		// Update leader so this request can be retried at the leader
 		c.serverAddr = staticIPLeaderMapping[string(body)]
-		return c.redirectReqToLeader(key, http.MethodGet, nil)
+		fmt.Printf("Redirecting ==> %s\n", c.serverAddr)
+		if resp, err = c.newRequest(http.MethodGet, key, nil); err != nil {
+			return nil, "", err
+		}
+		defer resp.Body.Close()
+		if body, err = ioutil.ReadAll(resp.Body); err != nil {
+			return nil, "", err
+		}
+		body = errorText(resp, body)
 	}
-
-	return errors.New(string(body))
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", replyError(resp, body)
+	}
+	if res, err := decodeReply(resp, body); err != nil {
+		return nil, "", err
+	} else if res != nil {
+		body, codec := valueOf(key, res)
+		return body, codec, nil
+	}
+	return body, resp.Header.Get(codecHeader), nil
 }
 
 func (c *RaftKVClient) Set(key string, value int64) error {
-	var reqBody []byte
-	var err error
-	if reqBody, err = proto.Marshal(&raftpb.Command{
+	return c.setCommand(&raftpb.Command{
 		Method: common.SET,
 		Key:    key,
 		Value:  value,
-	}); err != nil {
+	})
+}
+
+// SetFrom encodes obj with the codec selected by SetCodec and stores it at key
+// together with the codec name.
+func (c *RaftKVClient) SetFrom(key string, obj interface{}) error {
+	codec, err := lookupCodec(c.codec)
+	if err != nil {
+		return err
+	}
+	data, err := codec.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return c.setCommand(&raftpb.Command{
+		Method: common.SET,
+		Key:    key,
+		Blob:   data,
+		Codec:  codec.Name(),
+	})
+}
+
+func (c *RaftKVClient) setCommand(cmd *raftpb.Command) error {
+	var reqBody []byte
+	var err error
+	key := cmd.Key
+	// retries send the same body, they are deduplicated by the session
+	cmd.Session, cmd.Seq = c.nextSeq()
+	cmd.Priority = c.priority
+	// direct writes skip the coordinators, which set the expiry time of the
+	// others
+	cmd.Expires = expiresOf(cmd.Ttl)
+	if _, ok, err := c.direct(cmd); ok {
+		if err == nil {
+			color.HiGreen("OK")
+		}
+		return err
+	}
+	if reqBody, err = proto.Marshal(cmd); err != nil {
 		return err
 	}
 	resp, err := c.newRequest(http.MethodPost, key, reqBody)
@@ -510,10 +689,18 @@ func (c *RaftKVClient) Set(key string, value int64) error {
 		return c.redirectReqToLeader(key, http.MethodPost, reqBody)
 	}
 
-	return errors.New(string(body))
+	return replyError(resp, body)
 }
 
 func (c *RaftKVClient) Delete(key string) error {
+	c.nextSeq()
+	del := &raftpb.Command{Method: common.DEL, Key: key, Session: c.session, Seq: c.seq, Priority: c.priority}
+	if _, ok, err := c.direct(del); ok {
+		if err == nil {
+			color.HiGreen("OK")
+		}
+		return err
+	}
 	resp, err := c.newRequest(http.MethodDelete, key, nil)
 	if err != nil {
 		return err
@@ -537,7 +724,7 @@ func (c *RaftKVClient) Delete(key string) error {
 		return c.redirectReqToLeader(key, http.MethodDelete, nil)
 	}
 
-	return errors.New(string(body))
+	return replyError(resp, body)
 }
 
 func (c *RaftKVClient) OptimizeTxnCommands() {
@@ -586,7 +773,12 @@ func (c *RaftKVClient) AddTransaction(cmdArr []string) error {
 	}
 	var retries int
 	for retries < maxTransferRetries {
-		if err := c.attemptAdd(cmdArr[1], amount); err != nil {
+		if err := c.admit(retries); err != nil {
+			return err
+		}
+		err := c.attemptAdd(cmdArr[1], amount)
+		c.recordAttempt(err)
+		if err != nil {
 			retries++
 			fmt.Printf("%s\nRetrying %d times...\n", err, retries)
 		} else {
@@ -669,7 +861,7 @@ func (c *RaftKVClient) transactionRetryReq(reqBody []byte) (*http.Response, erro
 
 	currActiveServer := c.serverAddr
 	for _, value := range staticCoordServers {
-		if value == currActiveServer {
+		if value == currActiveServer || !c.health.healthy(value) {
 			continue
 		}
 		c.serverAddr = value
@@ -702,7 +894,13 @@ func (c *RaftKVClient) Transaction() (*raftpb.RaftCommand, error) {
 	// To ensure handled by transaction instead of single shard handler
 	// All txnCmds should be handled by transaction if getting here
 	c.txnCmds.IsTxn = true
+	if c.priority != common.PriorityNormal {
+		common.SetPriority(c.txnCmds.Commands, c.priority)
+	}
 	fmt.Printf("Submitting %v\n", c.txnCmds.Commands)
+	if c.routing != nil && c.routing.nodes != nil {
+		return c.standaloneTransaction(c.txnCmds)
+	}
 	var reqBody []byte
 	var err error
 	if reqBody, err = proto.Marshal(c.txnCmds); err != nil {
@@ -721,6 +919,11 @@ func (c *RaftKVClient) Transaction() (*raftpb.RaftCommand, error) {
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		if sizeErr := common.ParseTxnSizeError(errors.New(string(body))); sizeErr != nil {
+			return nil, sizeErr
+		}
+	}
 	txnCmdRsp := &raftpb.RaftCommand{}
 	if err = proto.Unmarshal(body, txnCmdRsp); err != nil {
 		return nil, errors.New(string(body))
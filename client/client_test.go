@@ -1,14 +1,21 @@
 package client
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/google/go-cmp/cmp"
 	"github.com/raft-kv-store/common"
 	"github.com/raft-kv-store/raftpb"
@@ -205,3 +212,273 @@ func TestMultiXfer(t *testing.T) {
 	wg.Wait()
 	log.Println(c.Get("y"))
 }
+
+func TestEndpointHealth(t *testing.T) {
+	h := newEndpointHealth(PoolConfig{EjectAfter: 2, EjectDuration: time.Hour})
+	addr := "http://node0:17000"
+
+	h.failure(addr)
+	assert.True(t, h.healthy(addr), "endpoint should stay healthy below the threshold")
+	h.failure(addr)
+	assert.False(t, h.healthy(addr), "endpoint should be ejected after consecutive failures")
+	h.success(addr)
+	assert.True(t, h.healthy(addr), "a success should bring the endpoint back")
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := &circuitBreaker{threshold: 2, cooldown: time.Second}
+
+	b.record(false, now)
+	assert.True(t, b.allow(now), "breaker should stay closed below the threshold")
+	b.record(false, now)
+	assert.False(t, b.allow(now), "breaker should open after consecutive failures")
+
+	later := now.Add(2 * time.Second)
+	assert.True(t, b.allow(later), "a single probe should be let through after cooldown")
+	assert.False(t, b.allow(later), "only one probe should be in flight")
+	b.record(true, later)
+	assert.True(t, b.allow(later), "a successful probe should close the breaker")
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := NewRetryBudget(0.5, 0)
+	assert.True(t, b.withdraw(), "initial capacity should allow a retry")
+	assert.False(t, b.withdraw(), "budget should be exhausted")
+	b.deposit()
+	b.deposit()
+	assert.True(t, b.withdraw(), "two requests should earn one retry")
+}
+
+func TestCodecs(t *testing.T) {
+	type account struct {
+		Owner   string
+		Balance int64
+	}
+	c := NewRaftKVClient("localhost:17000", time.Second)
+	assert.Error(t, c.SetCodec("msgpack"), "unregistered codecs should be rejected")
+	assert.Nil(t, c.SetCodec("json"))
+
+	codec, err := lookupCodec(c.codec)
+	assert.Nil(t, err)
+	data, err := codec.Marshal(account{Owner: "x", Balance: 10})
+	assert.Nil(t, err)
+	var got account
+	assert.Nil(t, codec.Unmarshal(data, &got))
+	assert.Equal(t, account{Owner: "x", Balance: 10}, got)
+
+	_, err = protoCodec{}.Marshal(got)
+	assert.Error(t, err, "protobuf codec should only accept messages")
+}
+
+func TestSmartRoutingFallback(t *testing.T) {
+	c := NewRaftKVClient("localhost:17000", time.Second)
+	_, ok, err := c.direct(&raftpb.Command{Method: common.GET, Key: "a"})
+	assert.False(t, ok, "commands go through the coordinator without smart routing")
+	assert.Nil(t, err)
+
+	// shards without a known leader are served by the coordinator
+	c.routing = &shardRouting{m: &shardMap{Shards: 2, Leaders: map[int64]string{}}}
+	_, ok, _ = c.direct(&raftpb.Command{Method: common.GET, Key: "a"})
+	assert.False(t, ok)
+	assert.Nil(t, c.routing.m, "the shard map should be fetched again")
+
+	assert.True(t, misrouted(fmt.Errorf("node is not the leader")))
+	assert.False(t, misrouted(fmt.Errorf("Key=a does not exist")))
+}
+
+func TestStandaloneNoLeader(t *testing.T) {
+	c := NewRaftKVClient("localhost:17000", time.Second)
+	assert.Error(t, c.EnableStandalone(nil))
+	c.routing = &shardRouting{nodes: []string{"127.0.0.1:1"}, m: &shardMap{Shards: 1, Leaders: map[int64]string{}}}
+	_, ok, err := c.direct(&raftpb.Command{Method: common.GET, Key: "a"})
+	assert.True(t, ok, "commands never go through a coordinator without coordinator")
+	assert.Equal(t, errMisrouted, err)
+	_, err = c.standaloneTransaction(&raftpb.RaftCommand{Commands: []*raftpb.Command{{Method: common.GET, Key: "a"}}})
+	assert.Error(t, err)
+}
+
+func TestRevisionToken(t *testing.T) {
+	var minRevision string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			minRevision = r.URL.Query().Get("min_revision")
+			w.Header().Set(revisionHeader, "1:12")
+			io.WriteString(w, "Key=a, Value=1")
+			return
+		}
+		w.Header().Set(revisionHeader, "1:10")
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	assert.Nil(t, c.Get("a"))
+	assert.Equal(t, "", minRevision, "nothing to wait for before the first write")
+	assert.Nil(t, c.Set("a", 1))
+	assert.Nil(t, c.ObserveRevisions("0:7"))
+	assert.Nil(t, c.Get("a"))
+	assert.Equal(t, "0:7,1:12", minRevision, "reads see the writes and reads of the client")
+	assert.Equal(t, "0:7,1:12", c.Revisions())
+	assert.Error(t, c.ObserveRevisions("12"))
+}
+
+func TestProtobufReplies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != protobufContentType {
+			io.WriteString(w, "Key=a, Value=1")
+			return
+		}
+		b, _ := proto.Marshal(&raftpb.RPCResponse{Value: 2, Meta: &raftpb.KeyMeta{Version: 1}})
+		w.Header().Set("Content-Type", protobufContentType)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	body, _, err := c.getValue("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "Key=a, Value=1", string(body))
+	c.EnableProtobuf()
+	body, codec, err := c.getValue("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "", codec)
+	assert.Equal(t, "Key=a, Value=2", string(body))
+	old, ok, err := c.GetSet("a", 3)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), old)
+}
+
+func TestBulkWrite(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := bufio.NewReader(r.Body)
+		var results []*raftpb.WriteResult
+		for i := int64(0); ; i++ {
+			cmd := &raftpb.Command{}
+			if err := common.ReadMessage(body, cmd); err == io.EOF {
+				break
+			} else if err != nil {
+				results = append(results, &raftpb.WriteResult{Index: i, Error: err.Error()})
+				break
+			}
+			res := &raftpb.WriteResult{Index: i, Key: cmd.Key, Revision: 10 + i}
+			if cmd.Method != common.SET {
+				res.Revision, res.Error = 0, "unexpected"
+			}
+			results = append(results, res)
+		}
+		for _, res := range results {
+			common.WriteMessage(w, res)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	cmds := make(chan *raftpb.Command)
+	go func() {
+		cmds <- &raftpb.Command{Method: common.SET, Key: "a", Value: 1}
+		cmds <- &raftpb.Command{Method: common.GET, Key: "b"}
+		close(cmds)
+	}()
+	var results []*raftpb.WriteResult
+	assert.Nil(t, c.BulkWrite(cmds, func(res *raftpb.WriteResult) { results = append(results, res) }))
+	if assert.Len(t, results, 2) {
+		assert.Equal(t, int64(10), results[0].Revision)
+		assert.Equal(t, "b", results[1].Key)
+		assert.Equal(t, "unexpected", results[1].Error)
+	}
+}
+
+func TestExportPages(t *testing.T) {
+	pages := map[string]string{"": "a\n", "c1": "b\n", "c2": "c\n"}
+	next := map[string]string{"": "c1", "c1": "c2"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("limit"))
+		cursor := r.URL.Query().Get("cursor")
+		if cursor == "gone" {
+			w.WriteHeader(http.StatusGone)
+			io.WriteString(w, "compacted")
+			return
+		}
+		if n := next[cursor]; n != "" {
+			w.Header().Set("X-Next-Cursor", n)
+		}
+		io.WriteString(w, pages[cursor])
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	var out bytes.Buffer
+	assert.Nil(t, c.ExportPages(&out, "", common.FormatJSONL, 2))
+	assert.Equal(t, "a\nb\nc\n", out.String())
+
+	_, err := c.exportPage(&out, "", common.FormatJSONL, "gone", 2)
+	assert.True(t, common.IsCompacted(err))
+}
+
+func TestLastRead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", "node-a (Follower)")
+		w.Header().Set("X-Shard", "1")
+		w.Header().Set("X-Applied-Index", "42")
+		w.Header().Set("X-Applied-Term", "3")
+		w.Header().Set("X-Consistency", common.ReadStale)
+		io.WriteString(w, "Key=a, Value=1")
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	assert.Nil(t, c.LastRead())
+	assert.Nil(t, c.Get("a"))
+	assert.Equal(t, &raftpb.ReadInfo{Node: "node-a", State: "Follower", Shard: 1, AppliedIndex: 42, AppliedTerm: 3, Consistency: common.ReadStale}, c.LastRead())
+	assert.Equal(t, "served by node-a (Follower) of shard 1 at applied index 42, term 3, consistency stale", FormatReadInfo(c.LastRead()))
+}
+
+func TestProtobufErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := proto.Marshal(&raftpb.Error{Code: http.StatusInternalServerError, Message: "Key=a does not exist"})
+		w.Header().Set("Content-Type", protobufContentType)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	c.EnableProtobuf()
+	assert.EqualError(t, c.Get("a"), "Key=a does not exist")
+}
+
+func TestTransactionTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		io.WriteString(w, "Unable to txn: transaction too large: 3 commands over the limit of 2")
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	c.txnCmds = &raftpb.RaftCommand{IsTxn: true}
+	c.appendTestCmds(common.SET, "a", 1)
+	c.appendTestCmds(common.SET, "b", 2)
+	c.appendTestCmds(common.SET, "c", 3)
+	_, err := c.Transaction()
+	assert.True(t, errors.Is(err, common.ErrTxnTooLarge))
+	assert.Equal(t, &common.TxnSizeError{Ops: 3, MaxOps: 2}, err)
+}
+
+func TestCluster(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cluster-Id", "staging")
+		io.WriteString(w, "Key=a, Value=1")
+	}))
+	defer srv.Close()
+
+	c := NewRaftKVClient(srv.URL, time.Second)
+	assert.Nil(t, c.Get("a"))
+	c.SetCluster("staging")
+	assert.Nil(t, c.Get("a"))
+
+	// the coordinator of another cluster is failed over
+	c = NewRaftKVClient(srv.URL, time.Second)
+	c.SetCluster("prod")
+	assert.NotNil(t, c.Get("a"))
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// clusterHeader names the cluster of the coordinator replying, and of the
+// cluster a request is meant for.
+const clusterHeader = "X-Cluster-Id"
+
+// SetCluster makes the client send its requests to the coordinators of the
+// cluster id only, the --cluster-id of the nodes: coordinators of another
+// cluster refuse them, and replies not stamped with id are discarded with
+// an error. An empty id disables the check.
+func (c *RaftKVClient) SetCluster(id string) {
+	c.cluster = id
+}
+
+// checkCluster returns an error if resp is not stamped with the cluster of
+// the client, if set.
+func (c *RaftKVClient) checkCluster(resp *http.Response) error {
+	if c.cluster == "" {
+		return nil
+	}
+	if got := resp.Header.Get(clusterHeader); got != c.cluster {
+		return fmt.Errorf("%s replied for cluster %q, not %q", resp.Request.URL.Host, got, c.cluster)
+	}
+	return nil
+}
@@ -2,11 +2,16 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/raft-kv-store/client"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
 	flag "github.com/spf13/pflag"
 )
 
@@ -17,20 +22,522 @@ const (
 // Command line parameters
 var (
 	serverAddress string
+	hedgeAfter    time.Duration
+	readInfo      bool
+	dumpFormat    string
+	exportPrefix  string
+	exportPages   int
+	migrateSource string
+	migrateMove   bool
+	migrateRate   int
+	withSession   bool
+	priority      string
+	smartRouting  bool
+	standalone    []string
+	watchList     bool
+	watchEvents   []string
+	watchWhere    string
+	token         string
+	adminEndpoint string
+	clusterID     string
+	dryRun        bool
 )
 
 func init() {
 	flag.StringVarP(&serverAddress, "endpoint", "e", DefaultServerAddress, "Set the endpoint address")
+	flag.DurationVarP(&hedgeAfter, "hedge", "", 0, "Hedge reads to a second coordinator after this latency, disabled if 0")
+	flag.BoolVarP(&readInfo, "read-info", "", false, "Print which node served the gets, at which applied index and term, and consistency")
+	flag.StringVarP(&dumpFormat, "format", "", "jsonl", "Dump format of import and export, jsonl or csv")
+	flag.StringVarP(&exportPrefix, "prefix", "", "", "Export only the keys starting with this prefix")
+	flag.IntVarP(&exportPages, "page-size", "", 0, "Export in pages of this many keys, all at once if 0")
+	flag.StringVarP(&migrateSource, "source", "", "", "Migrate from the coordinator of another cluster at this address")
+	flag.BoolVarP(&migrateMove, "move", "", false, "Delete the source keys once migrated")
+	flag.IntVarP(&migrateRate, "rate", "", 0, "Migrate at most this many keys per second, unlimited if 0")
+	flag.BoolVarP(&withSession, "session", "", false, "Apply the retried writes at most once, within a session")
+	flag.StringVarP(&priority, "priority", "", "normal", "Priority of the writes: low, normal or high")
+	flag.BoolVarP(&smartRouting, "smart-routing", "", false, "Send gets, sets and deletes to the shard leaders directly")
+	flag.BoolVarP(&watchList, "list", "", false, "Print the keys watched before their changes")
+	flag.StringSliceVarP(&watchEvents, "events", "", nil, "Watch only these commands: set, del or evict")
+	flag.StringVarP(&watchWhere, "where", "", "", "Watch only the sets of JSON values with field=value, the value in JSON")
+	flag.StringSliceVarP(&standalone, "standalone", "", nil, "Talk to these store nodes of a single-shard deployment without coordinator")
+	flag.StringVarP(&token, "token", "", os.Getenv("RAFTKV_TOKEN"), "Bearer token of the requests, $RAFTKV_TOKEN if not set")
+	flag.StringVarP(&clusterID, "cluster-id", "", os.Getenv("RAFTKV_CLUSTER_ID"),
+		"Only talk to the coordinators of this cluster, $RAFTKV_CLUSTER_ID if not set")
+	flag.BoolVarP(&dryRun, "dry-run", "", false, "Print the steps of a membership change without applying them")
+	flag.StringVarP(&adminEndpoint, "admin-endpoint", "", "", "Address of the management endpoints, if the coordinators serve them apart")
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] import [file]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] export [file]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] migrate <from> <to>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] shards\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] replicas <shard> <n>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] members <shard> <promote,...> <remove,...>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] membership <request.json|->\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] topology [<topology.json>|-|clear]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] compact [shard]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] keyprofile [start [duration] [sample] [depth]|stop]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] watch [prefix]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] roles\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] role <name> [prefix:verb,... ...|-]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] bind <subject> <role,...|->\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] transforms [<prefix> <name[=config],...|->]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] ttl [<prefix> <default|-> [max]|<prefix> -]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] undelete <key>\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
 	flag.Parse()
-	c := client.NewRaftKVClient(serverAddress, 2 * time.Second)
+	if flag.Arg(0) == "import" {
+		os.Exit(runImport(flag.Arg(1)))
+	}
+	if flag.Arg(0) == "export" {
+		os.Exit(runExport(flag.Arg(1)))
+	}
+	if flag.Arg(0) == "migrate" {
+		os.Exit(runMigrate(flag.Arg(1), flag.Arg(2)))
+	}
+	if flag.Arg(0) == "watch" {
+		os.Exit(runWatch(flag.Arg(1)))
+	}
+	if flag.Arg(0) == "roles" || flag.Arg(0) == "role" || flag.Arg(0) == "bind" {
+		os.Exit(runRoles())
+	}
+	if flag.Arg(0) == "transforms" {
+		os.Exit(runTransforms())
+	}
+	if flag.Arg(0) == "ttl" {
+		os.Exit(runTTL())
+	}
+	if flag.Arg(0) == "undelete" {
+		os.Exit(runUndelete(flag.Arg(1)))
+	}
+	if flag.Arg(0) == "shards" || flag.Arg(0) == "replicas" || flag.Arg(0) == "members" || flag.Arg(0) == "membership" || flag.Arg(0) == "topology" || flag.Arg(0) == "compact" || flag.Arg(0) == "keyprofile" {
+		os.Exit(runShards())
+	}
+	c := newClient(2 * time.Second)
+	c.EnableReadHedging(hedgeAfter)
+	if readInfo {
+		c.ShowReadInfo()
+	}
+	p, err := common.ParsePriority(priority)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c.SetPriority(p)
+	if len(standalone) > 0 {
+		if err := c.EnableStandalone(standalone); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	} else if smartRouting {
+		if err := c.EnableSmartRouting(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if withSession {
+		if err := c.OpenSession(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 	c.Run()
 	signal.Notify(c.Terminate, os.Interrupt)
 	<-c.Terminate
 }
+
+// newClient returns a client of --endpoint and --admin-endpoint
+// authenticated with --token, of the cluster --cluster-id.
+func newClient(timeout time.Duration) *client.RaftKVClient {
+	c := client.NewRaftKVClient(serverAddress, timeout)
+	c.SetToken(token)
+	c.SetCluster(clusterID)
+	if adminEndpoint != "" {
+		c.SetAdminEndpoint(adminEndpoint)
+	}
+	return c
+}
+
+// runImport bulk loads the dump file, or stdin if file is empty.
+func runImport(file string) int {
+	in := os.Stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+	// imports take as long as the dump is large
+	c := newClient(0)
+	n, err := c.Import(in, dumpFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Imported %d keys\n", n)
+	return 0
+}
+
+// runExport writes a consistent dump of the keys starting with --prefix to
+// file, or stdout if file is empty.
+func runExport(file string) int {
+	out := os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+	c := newClient(0)
+	var err error
+	if exportPages > 0 {
+		err = c.ExportPages(out, exportPrefix, dumpFormat, exportPages)
+	} else {
+		err = c.Export(out, exportPrefix, dumpFormat)
+	}
+	if common.IsCompacted(err) {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "the changes since the export started are not kept anymore, export again")
+		return 1
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// runWatch prints the changes of the keys starting with prefix selected by
+// --events and --where until interrupted, or all their changes after the keys
+// themselves with --list.
+func runWatch(prefix string) int {
+	c := newClient(0)
+	var w *client.Watcher
+	var err error
+	if watchList {
+		var entries []*raftpb.KVEntry
+		if entries, w, err = c.ListAndWatch(prefix); err == nil {
+			err = printEntries(entries)
+		}
+	} else {
+		filter := &raftpb.WatchFilter{Methods: watchEvents}
+		if watchWhere != "" {
+			kv := strings.SplitN(watchWhere, "=", 2)
+			if len(kv) != 2 {
+				flag.Usage()
+				return 2
+			}
+			filter.Field, filter.Equals = kv[0], kv[1]
+		}
+		w, err = c.WatchFiltered(prefix, filter, nil)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		w.Stop()
+	}()
+	for ev := range w.Events {
+		if ev.Err != nil {
+			fmt.Fprintf(os.Stderr, "shard %d: %s\n", ev.Shard, ev.Err)
+			return 1
+		}
+		cmd := ev.Command
+		switch {
+		case cmd.Method != common.SET:
+			fmt.Printf("shard %d revision %d: %s %s\n", ev.Shard, cmd.Revision, cmd.Method, cmd.Key)
+		case cmd.Codec != "":
+			fmt.Printf("shard %d revision %d: %s %s (%d bytes of %s)\n", ev.Shard, cmd.Revision, cmd.Method, cmd.Key, len(cmd.Blob), cmd.Codec)
+		default:
+			fmt.Printf("shard %d revision %d: %s %s %d\n", ev.Shard, cmd.Revision, cmd.Method, cmd.Key, cmd.Value)
+		}
+	}
+	return 0
+}
+
+// printEntries writes entries to stdout in --format.
+func printEntries(entries []*raftpb.KVEntry) error {
+	d, err := common.NewDumpWriter(os.Stdout, dumpFormat)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := d.Write(e); err != nil {
+			return err
+		}
+	}
+	return d.Flush()
+}
+
+// runMigrate copies, or moves, the keys starting with from to keys starting
+// with to.
+func runMigrate(from, to string) int {
+	if flag.NArg() != 3 {
+		flag.Usage()
+		return 2
+	}
+	c := newClient(0)
+	n, err := c.Migrate(from, to, migrateSource, migrateMove, migrateRate)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Migrated %d keys\n", n)
+	return 0
+}
+
+// runRoles prints the access policy, after setting the rules of a role for
+// the role command or the roles of a subject for the bind command. A role
+// without rules, or a subject, are deleted with -.
+func runRoles() int {
+	c := newClient(0)
+	var res string
+	var err error
+	switch flag.Arg(0) {
+	case "role":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			return 2
+		}
+		if flag.Arg(2) == "-" {
+			res, err = c.DeleteRole(flag.Arg(1))
+			break
+		}
+		var rules []common.Rule
+		for _, arg := range flag.Args()[2:] {
+			i := strings.LastIndex(arg, ":")
+			if i < 0 {
+				flag.Usage()
+				return 2
+			}
+			rules = append(rules, common.Rule{Prefix: arg[:i], Verbs: strings.Split(arg[i+1:], ",")})
+		}
+		res, err = c.SetRole(flag.Arg(1), rules)
+	case "bind":
+		if flag.NArg() != 3 {
+			flag.Usage()
+			return 2
+		}
+		if flag.Arg(2) == "-" {
+			res, err = c.Unbind(flag.Arg(1))
+		} else {
+			res, err = c.Bind(flag.Arg(1), strings.Split(flag.Arg(2), ","))
+		}
+	default:
+		res, err = c.Roles()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(res)
+	return 0
+}
+
+// runTransforms prints the transforms of the values by prefix, after setting
+// those of a prefix, or deleting them with -.
+func runTransforms() int {
+	c := newClient(0)
+	var res string
+	var err error
+	switch flag.NArg() {
+	case 1:
+		res, err = c.Transforms()
+	case 3:
+		if flag.Arg(2) == "-" {
+			res, err = c.DeleteTransforms(flag.Arg(1))
+			break
+		}
+		var specs []common.TransformSpec
+		for _, arg := range strings.Split(flag.Arg(2), ",") {
+			parts := strings.SplitN(arg, "=", 2)
+			spec := common.TransformSpec{Name: parts[0]}
+			if len(parts) == 2 {
+				spec.Config = parts[1]
+			}
+			specs = append(specs, spec)
+		}
+		res, err = c.SetTransforms(flag.Arg(1), specs)
+	default:
+		flag.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(res)
+	return 0
+}
+
+// runTTL prints the ttl policies of the keys by prefix, after setting the
+// default and max ttl of a prefix, - for none, or deleting its policy with -.
+func runTTL() int {
+	c := newClient(0)
+	var res string
+	var err error
+	switch {
+	case flag.NArg() == 1:
+		res, err = c.TTLPolicies()
+	case flag.NArg() == 3 && flag.Arg(2) == "-":
+		res, err = c.DeleteTTLPolicy(flag.Arg(1))
+	case flag.NArg() == 3 || flag.NArg() == 4:
+		p := common.TTLPolicy{Max: flag.Arg(3)}
+		if flag.Arg(2) != "-" {
+			p.Default = flag.Arg(2)
+		}
+		res, err = c.SetTTLPolicy(flag.Arg(1), p)
+	default:
+		flag.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(res)
+	return 0
+}
+
+// runUndelete restores key, soft deleted, from its tombstone.
+func runUndelete(key string) int {
+	if flag.NArg() != 2 {
+		flag.Usage()
+		return 2
+	}
+	if err := newClient(0).Undelete(key); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("OK")
+	return 0
+}
+
+// runShards prints the replication of the shards, after setting the
+// replication factor of a shard for the replicas command, or the members of
+// a shard after changing them for the members command. The membership
+// command prints the report of the json request of the file, or stdin if
+// "-", the topology command the topology and its drift, after replacing it
+// with the file or clearing it, the compact command prints the nodes
+// compacted, and the keyprofile command the key profile of the coordinator,
+// after starting or stopping it.
+func runShards() int {
+	c := newClient(0)
+	var res string
+	var err error
+	if flag.Arg(0) == "members" {
+		if flag.NArg() != 4 {
+			flag.Usage()
+			return 2
+		}
+		shard, err1 := strconv.Atoi(flag.Arg(1))
+		if err1 != nil {
+			flag.Usage()
+			return 2
+		}
+		split := func(v string) []string {
+			if v == "" || v == "-" {
+				return nil
+			}
+			return strings.Split(v, ",")
+		}
+		res, err = c.ChangeMembers(shard, split(flag.Arg(2)), split(flag.Arg(3)))
+	} else if flag.Arg(0) == "membership" {
+		if flag.NArg() != 2 {
+			flag.Usage()
+			return 2
+		}
+		var request []byte
+		if flag.Arg(1) == "-" {
+			request, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			request, err = ioutil.ReadFile(flag.Arg(1))
+		}
+		if err == nil {
+			res, err = c.ChangeMembership(request, dryRun)
+		}
+	} else if flag.Arg(0) == "topology" {
+		var topology []byte
+		switch flag.Arg(1) {
+		case "", "clear":
+		case "-":
+			topology, err = ioutil.ReadAll(os.Stdin)
+		default:
+			topology, err = ioutil.ReadFile(flag.Arg(1))
+		}
+		if err == nil {
+			res, err = c.Topology(topology, flag.Arg(1) == "clear")
+		}
+	} else if flag.Arg(0) == "keyprofile" {
+		switch flag.Arg(1) {
+		case "":
+			res, err = c.KeyProfile(false)
+		case "stop":
+			res, err = c.KeyProfile(true)
+		case "start":
+			window, rate, depth := time.Minute, 0.1, 1
+			var err1 error
+			if flag.NArg() > 2 {
+				window, err1 = time.ParseDuration(flag.Arg(2))
+			}
+			if flag.NArg() > 3 && err1 == nil {
+				rate, err1 = strconv.ParseFloat(flag.Arg(3), 64)
+			}
+			if flag.NArg() > 4 && err1 == nil {
+				depth, err1 = strconv.Atoi(flag.Arg(4))
+			}
+			if err1 != nil {
+				flag.Usage()
+				return 2
+			}
+			res, err = c.StartKeyProfile(window, rate, depth)
+		default:
+			flag.Usage()
+			return 2
+		}
+	} else if flag.Arg(0) == "compact" {
+		shard := -1
+		if flag.NArg() > 1 {
+			n, err1 := strconv.Atoi(flag.Arg(1))
+			if err1 != nil {
+				flag.Usage()
+				return 2
+			}
+			shard = n
+		}
+		res, err = c.Compact(shard)
+	} else if flag.Arg(0) == "replicas" {
+		if flag.NArg() != 3 {
+			flag.Usage()
+			return 2
+		}
+		shard, err1 := strconv.Atoi(flag.Arg(1))
+		n, err2 := strconv.Atoi(flag.Arg(2))
+		if err1 != nil || err2 != nil {
+			flag.Usage()
+			return 2
+		}
+		res, err = c.SetReplicas(shard, n)
+	} else {
+		res, err = c.Shards()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println(res)
+	return 0
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Codec encodes typed values stored with SetFrom and decodes them in GetInto.
+// The name of the codec is stored alongside the value so that any client
+// which registered the same codec can read it back.
+type Codec interface {
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// codecHeader carries the codec of a value in GET responses.
+const codecHeader = "X-Codec"
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(jsonCodec{})
+	RegisterCodec(protoCodec{})
+}
+
+// RegisterCodec makes c available to every client under c.Name(), replacing
+// any codec registered under the same name. Codecs that pull in a third party
+// dependency, such as msgpack, are registered by the application.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.Name()] = c
+}
+
+func lookupCodec(name string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("codec %q is not registered", name)
+	}
+	return c, nil
+}
+
+// jsonCodec is the default codec.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string { return "json" }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// protoCodec encodes protobuf messages.
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "protobuf" }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// SetCodec selects the registered codec used by SetFrom. It defaults to json.
+func (c *RaftKVClient) SetCodec(name string) error {
+	if _, err := lookupCodec(name); err != nil {
+		return err
+	}
+	c.codec = name
+	return nil
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Compare runs the compare transaction ct, whose keys must belong to a
+// single shard: its success commands are applied if every comparison holds,
+// its failure commands otherwise, atomically with the comparisons. It reports
+// whether the comparisons held, and returns the results of the gets of the
+// branch applied.
+func (c *RaftKVClient) Compare(ct *raftpb.CompareTxn) (bool, []*raftpb.Command, error) {
+	if c.priority != common.PriorityNormal {
+		common.SetPriority(ct.Success, c.priority)
+		common.SetPriority(ct.Failure, c.priority)
+	}
+	b, err := proto.Marshal(ct)
+	if err != nil {
+		return false, nil, err
+	}
+	resp, body, err := c.txnRequest(http.MethodPost, "compare", b)
+	if err != nil {
+		return false, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, errors.New(string(body))
+	}
+	res := &raftpb.RPCResponse{}
+	if err := proto.Unmarshal(body, res); err != nil {
+		return false, nil, err
+	}
+	return res.Value == 1, res.Commands, nil
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"github.com/raft-kv-store/common"
+)
+
+// nextCursorHeader carries the cursor of the next page of an export.
+const nextCursorHeader = "X-Next-Cursor"
+
+// Export writes the keys starting with prefix to w, in jsonl or csv format.
+// The export is a consistent cut of the cluster taken by the coordinator
+// leader.
+func (c *RaftKVClient) Export(w io.Writer, prefix, format string) error {
+	resp, err := c.exportRequest(prefix, format, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, err = c.exportRequest(prefix, format, nil); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return errors.New(string(body))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// ExportPages writes the keys starting with prefix to w like Export, reading
+// them in pages of pageSize keys. The pages read every shard at the same
+// revision, so they make a consistent cut without the coordinator leader
+// holding the whole export. If a shard does not keep the changes since then
+// anymore, the export fails with common.ErrCompacted and must start over.
+func (c *RaftKVClient) ExportPages(w io.Writer, prefix, format string, pageSize int) error {
+	cursor := ""
+	for {
+		next, err := c.exportPage(w, prefix, format, cursor, pageSize)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// exportPage writes the page of the export from cursor to w, and returns the
+// cursor of the next page, empty after the last one.
+func (c *RaftKVClient) exportPage(w io.Writer, prefix, format, cursor string, pageSize int) (string, error) {
+	q := url.Values{"limit": {strconv.Itoa(pageSize)}}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	resp, err := c.exportRequest(prefix, format, q)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, err = c.exportRequest(prefix, format, q); err != nil {
+			return "", err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusGone {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("%w: %s", common.ErrCompacted, body)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", errors.New(string(body))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return "", err
+	}
+	return resp.Header.Get(nextCursorHeader), nil
+}
+
+func (c *RaftKVClient) exportRequest(prefix, format string, page url.Values) (*http.Response, error) {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "export")
+	q := url.Values{"prefix": {prefix}, "format": {format}}
+	for k, v := range page {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// GetSet sets key to value and returns the value it had before, read and
+// replaced atomically by the shard. ok is false if the key did not exist.
+func (c *RaftKVClient) GetSet(key string, value int64) (old int64, ok bool, err error) {
+	cmd := &raftpb.Command{Method: common.SET, Key: key, Value: value, Priority: c.priority}
+	cmd.Session, cmd.Seq = c.nextSeq()
+	reqBody, err := proto.Marshal(cmd)
+	if err != nil {
+		return 0, false, err
+	}
+	return c.returnOld(http.MethodPost, key, reqBody)
+}
+
+// GetDel deletes key and returns the value it had before, like GetSet.
+func (c *RaftKVClient) GetDel(key string) (old int64, ok bool, err error) {
+	c.nextSeq()
+	return c.returnOld(http.MethodDelete, key, nil)
+}
+
+// returnOld sends a write of key asking for the value it replaces, following
+// a redirection to the leader once.
+func (c *RaftKVClient) returnOld(method, key string, data []byte) (int64, bool, error) {
+	resp, body, err := c.returnOldRequest(method, key, data)
+	if err == nil && resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		resp, body, err = c.returnOldRequest(method, key, data)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusNoContent:
+		return 0, false, nil
+	case http.StatusOK:
+		if res, err := decodeReply(resp, body); err != nil {
+			return 0, true, err
+		} else if res != nil {
+			if res.Codec != "" {
+				return 0, true, errors.New("the previous value is a blob")
+			}
+			return res.Value, true, nil
+		}
+		if resp.Header.Get(codecHeader) != "" {
+			return 0, true, errors.New("the previous value is a blob")
+		}
+		i := strings.LastIndex(string(body), "Value=")
+		if i < 0 {
+			return 0, true, errors.New(string(body))
+		}
+		old, err := parseInt64(string(body)[i+len("Value="):])
+		return old, true, err
+	}
+	return 0, false, replyError(resp, body)
+}
+
+func (c *RaftKVClient) returnOldRequest(method, key string, data []byte) (*http.Response, []byte, error) {
+	u, err := c.parseServerAddr(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(method, u+"?return=old", bytes.NewBuffer(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	c.setSessionHeaders(req)
+	c.setPriorityHeader(req)
+	c.setAcceptHeader(req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, errorText(resp, body), err
+}
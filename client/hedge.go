@@ -0,0 +1,84 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// EnableReadHedging makes Get send a second request to another coordinator when
+// the active one has not answered within after, taking whichever response
+// arrives first. This cuts tail latency when one node is GC-pausing or
+// overloaded. A zero duration disables hedging.
+func (c *RaftKVClient) EnableReadHedging(after time.Duration) {
+	c.hedgeAfter = after
+}
+
+// hedgeTarget returns a healthy coordinator other than the active one.
+func (c *RaftKVClient) hedgeTarget() string {
+	for _, addr := range staticCoordServers {
+		if addr != c.serverAddr && c.health.healthy(addr) {
+			return addr
+		}
+	}
+	return ""
+}
+
+type hedgeResult struct {
+	resp *http.Response
+	err  error
+}
+
+// hedgedGet sends a GET for key to the active coordinator and, if it has not
+// answered within c.hedgeAfter (or failed), to a second one as well. The first
+// response received wins; the body of a late response is discarded.
+func (c *RaftKVClient) hedgedGet(key string) (*http.Response, error) {
+	results := make(chan hedgeResult, 2)
+	send := func(addr string) {
+		resp, err := c.newRequestTo(addr, http.MethodGet, key, nil)
+		results <- hedgeResult{resp: resp, err: err}
+	}
+
+	go send(c.serverAddr)
+	inflight := 1
+	hedged := false
+	hedge := func() {
+		if hedged {
+			return
+		}
+		hedged = true
+		if target := c.hedgeTarget(); target != "" {
+			go send(target)
+			inflight++
+		}
+	}
+
+	timer := time.NewTimer(c.hedgeAfter)
+	defer timer.Stop()
+
+	var err error
+	for inflight > 0 {
+		select {
+		case <-timer.C:
+			hedge()
+		case r := <-results:
+			inflight--
+			if r.err == nil {
+				go discardHedged(results, inflight)
+				return r.resp, nil
+			}
+			err = r.err
+			// do not wait for the timer if the active coordinator failed
+			hedge()
+		}
+	}
+	return nil, err
+}
+
+// discardHedged closes the bodies of the n responses that lost the race.
+func discardHedged(results <-chan hedgeResult, n int) {
+	for i := 0; i < n; i++ {
+		if r := <-results; r.err == nil {
+			r.resp.Body.Close()
+		}
+	}
+}
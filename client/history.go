@@ -0,0 +1,74 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// GetRevision returns the response body of a GET for the value key had at
+// revision rev, and the codec of the value, empty for numerical values.
+// Revisions older than the retention of the shard are compacted.
+func (c *RaftKVClient) GetRevision(key string, rev int64) ([]byte, string, error) {
+	u, err := c.parseServerAddr(key)
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?rev=%d", u, rev), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", errors.New(string(body))
+	}
+	return body, resp.Header.Get(codecHeader), nil
+}
+
+// History returns up to limit past revisions of key, newest first. Each
+// revision is returned as the SET or DEL command that produced it, or EVICT
+// for keys evicted by the cache mode, with its Revision set. A limit of 0
+// returns all the retained revisions.
+func (c *RaftKVClient) History(key string, limit int) ([]*raftpb.Command, error) {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "history", key)
+	u.RawQuery = fmt.Sprintf("limit=%d", limit)
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	cmds := &raftpb.RaftCommand{}
+	if err = proto.Unmarshal(body, cmds); err != nil {
+		return nil, err
+	}
+	return cmds.Commands, nil
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Import streams the dump r, in jsonl or csv format, to the coordinator which
+// installs it on the shards as snapshots. It returns the number of imported
+// keys. Imports can take long, use a client without timeout.
+func (c *RaftKVClient) Import(r io.Reader, format string) (int, error) {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return 0, err
+	}
+	u.Path = path.Join(u.Path, "import")
+	u.RawQuery = url.Values{"format": {format}}.Encode()
+	req, err := http.NewRequest(http.MethodPost, u.String(), r)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.New(string(body))
+	}
+	return strconv.Atoi(strings.TrimPrefix(string(body), "Imported="))
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Txn is an interactive transaction. Its writes are buffered by the
+// coordinator leader, and its gets see them, until Commit applies them
+// atomically.
+type Txn struct {
+	c  *RaftKVClient
+	id string
+}
+
+// Begin begins an interactive transaction on the coordinator leader.
+func (c *RaftKVClient) Begin() (*Txn, error) {
+	resp, body, err := c.txnRequest(http.MethodPost, "txn", nil)
+	if err == nil && resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		resp, body, err = c.txnRequest(http.MethodPost, "txn", nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	return &Txn{c: c, id: string(body)}, nil
+}
+
+// ID returns the id of the transaction.
+func (t *Txn) ID() string {
+	return t.id
+}
+
+// Get returns the value of key in the transaction.
+func (t *Txn) Get(key string) (int64, error) {
+	res, err := t.Do(&raftpb.Command{Method: common.GET, Key: key})
+	if err != nil {
+		return 0, err
+	}
+	return res.Commands[0].Value, nil
+}
+
+// Set buffers the write of value to key.
+func (t *Txn) Set(key string, value int64) error {
+	_, err := t.Do(&raftpb.Command{Method: common.SET, Key: key, Value: value})
+	return err
+}
+
+// Delete buffers the deletion of key.
+func (t *Txn) Delete(key string) error {
+	_, err := t.Do(&raftpb.Command{Method: common.DEL, Key: key})
+	return err
+}
+
+// Do runs cmds in the transaction, in a single round trip, and returns the
+// results of their gets.
+func (t *Txn) Do(cmds ...*raftpb.Command) (*raftpb.RaftCommand, error) {
+	if t.c.priority != common.PriorityNormal {
+		common.SetPriority(cmds, t.c.priority)
+	}
+	b, err := proto.Marshal(&raftpb.RaftCommand{Commands: cmds})
+	if err != nil {
+		return nil, err
+	}
+	return t.call(http.MethodPost, path.Join("txn", t.id), b)
+}
+
+// Commit applies the writes of the transaction atomically. It fails if a
+// key the transaction read and then set was written since.
+func (t *Txn) Commit() (*raftpb.RaftCommand, error) {
+	return t.call(http.MethodPost, path.Join("txn", t.id, "commit"), nil)
+}
+
+// Abort drops the transaction and its writes.
+func (t *Txn) Abort() error {
+	_, err := t.call(http.MethodDelete, path.Join("txn", t.id), nil)
+	return err
+}
+
+func (t *Txn) call(method, p string, data []byte) (*raftpb.RaftCommand, error) {
+	resp, body, err := t.c.txnRequest(method, p, data)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	res := &raftpb.RaftCommand{}
+	if err := proto.Unmarshal(body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *RaftKVClient) txnRequest(method, p string, data []byte) (*http.Response, []byte, error) {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Path = path.Join(u.Path, p)
+	req, err := http.NewRequest(method, u.String(), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
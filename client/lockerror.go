@@ -0,0 +1,36 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/raft-kv-store/common"
+)
+
+// Headers describing the key lock a single read or write failed on.
+const (
+	conflictKeyHeader  = "X-Conflict-Key"
+	conflictTxidHeader = "X-Conflict-Txid"
+	retryBackoffHeader = "X-Retry-Backoff"
+	lockNodeHeader     = "X-Lock-Node"
+	lockHeldForHeader  = "X-Lock-Held-For"
+)
+
+// replyError returns the error of a request failed with body: a
+// *common.LockError if the headers of resp describe the key lock it failed
+// on, the text of body otherwise.
+func replyError(resp *http.Response, body []byte) error {
+	h := resp.Header
+	if resp.StatusCode != http.StatusConflict || h.Get(lockNodeHeader) == "" {
+		return errors.New(string(body))
+	}
+	lock := &common.LockError{
+		Key:    h.Get(conflictKeyHeader),
+		Holder: h.Get(conflictTxidHeader),
+		Node:   h.Get(lockNodeHeader),
+	}
+	lock.HeldFor, _ = time.ParseDuration(h.Get(lockHeldForHeader))
+	lock.RetryAfter, _ = time.ParseDuration(h.Get(retryBackoffHeader))
+	return lock
+}
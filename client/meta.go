@@ -0,0 +1,67 @@
+package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Metadata returns the create and mod revisions, version and size of key.
+func (c *RaftKVClient) Metadata(key string) (*raftpb.KeyMeta, error) {
+	u, err := c.parseServerAddr(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, u+"?metadata=true", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	return parseMetaHeaders(resp.Header)
+}
+
+func parseMetaHeaders(h http.Header) (*raftpb.KeyMeta, error) {
+	var vals [4]int64
+	for i, name := range []string{"X-Create-Revision", "X-Mod-Revision", "X-Version", "X-Size"} {
+		v, err := parseInt64(h.Get(name))
+		if err != nil {
+			return nil, errors.New("missing metadata in response, is the coordinator up to date?")
+		}
+		vals[i] = v
+	}
+	return &raftpb.KeyMeta{
+		CreateRevision: vals[0],
+		ModRevision:    vals[1],
+		Version:        vals[2],
+		Size:           vals[3],
+	}, nil
+}
+
+// SetIfModRevision sets key to value only if the key was last modified at
+// revision rev, as returned by Metadata. Read-modify-write cycles built on it
+// fail instead of overwriting a concurrent update.
+func (c *RaftKVClient) SetIfModRevision(key string, value, rev int64) error {
+	if rev == 0 {
+		return errors.New("a mod revision is required")
+	}
+	return c.setCommand(&raftpb.Command{
+		Method: common.SET,
+		Key:    key,
+		Value:  value,
+		Cond:   &raftpb.Cond{Key: key, ModRevision: rev},
+	})
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Migrate asks the coordinator leader to copy the keys starting with from to
+// keys starting with to, reading them from the coordinator at source when it
+// is not empty. With move, the source keys are deleted after the cutover.
+// rate limits the keys written per second, 0 for unlimited. It returns the
+// number of migrated keys. Migrations can take long, use a client without
+// timeout.
+func (c *RaftKVClient) Migrate(from, to, source string, move bool, rate int) (int, error) {
+	q := url.Values{
+		"from":   {from},
+		"to":     {to},
+		"source": {source},
+		"move":   {strconv.FormatBool(move)},
+		"rate":   {strconv.Itoa(rate)},
+	}
+	resp, body, err := c.migrateRequest(q)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.migrateRequest(q); err != nil {
+			return 0, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.New(string(body))
+	}
+	return strconv.Atoi(strings.TrimPrefix(string(body), "Migrated="))
+}
+
+func (c *RaftKVClient) migrateRequest(q url.Values) (*http.Response, []byte, error) {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Path = path.Join(u.Path, "migrate")
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PoolConfig configures connection reuse towards the coordinator endpoints and
+// how unhealthy endpoints are ejected from the rotation.
+type PoolConfig struct {
+	// MaxIdleConnsPerHost is the number of idle keep-alive connections kept per endpoint.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes pooled connections that stay idle for longer.
+	IdleConnTimeout time.Duration
+	// KeepAlive is the TCP keep-alive period of pooled connections.
+	KeepAlive time.Duration
+	// EjectAfter is the number of consecutive failures before an endpoint is ejected.
+	EjectAfter int
+	// EjectDuration is how long an ejected endpoint is skipped when retrying.
+	EjectDuration time.Duration
+}
+
+// DefaultPoolConfig is used by NewRaftKVClient.
+var DefaultPoolConfig = PoolConfig{
+	MaxIdleConnsPerHost: 64,
+	IdleConnTimeout:     90 * time.Second,
+	KeepAlive:           30 * time.Second,
+	EjectAfter:          3,
+	EjectDuration:       10 * time.Second,
+}
+
+// newTransport returns a keep-alive transport sized by cfg. The default
+// transport only keeps two idle connections per host, so high-QPS callers
+// end up re-dialing the coordinator for most requests.
+func newTransport(cfg PoolConfig, timeout time.Duration) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: cfg.KeepAlive,
+		}).DialContext,
+		MaxIdleConns:        cfg.MaxIdleConnsPerHost * len(staticCoordServers),
+		MaxIdleConnsPerHost: cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:     cfg.IdleConnTimeout,
+	}
+}
+
+// endpointHealth tracks consecutive failures per endpoint and ejects the
+// endpoints that keep failing for a while.
+type endpointHealth struct {
+	mu           sync.Mutex
+	cfg          PoolConfig
+	failures     map[string]int
+	ejectedUntil map[string]time.Time
+}
+
+func newEndpointHealth(cfg PoolConfig) *endpointHealth {
+	return &endpointHealth{
+		cfg:          cfg,
+		failures:     make(map[string]int),
+		ejectedUntil: make(map[string]time.Time),
+	}
+}
+
+func (h *endpointHealth) success(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.failures, addr)
+	delete(h.ejectedUntil, addr)
+}
+
+func (h *endpointHealth) failure(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures[addr]++
+	if h.cfg.EjectAfter > 0 && h.failures[addr] >= h.cfg.EjectAfter {
+		h.ejectedUntil[addr] = time.Now().Add(h.cfg.EjectDuration)
+		h.failures[addr] = 0
+	}
+}
+
+// healthy reports whether addr is currently part of the rotation.
+func (h *endpointHealth) healthy(addr string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	until, ok := h.ejectedUntil[addr]
+	if !ok {
+		return true
+	}
+	if time.Now().After(until) {
+		delete(h.ejectedUntil, addr)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/raft-kv-store/common"
+)
+
+// priorityHeader carries the priority of DELETE requests.
+const priorityHeader = "X-Priority"
+
+// SetPriority sets the priority of the following writes and transactions of
+// the client. Under contention, shard leaders throttle low priority writes
+// first and propose high priority ones ahead of the others.
+func (c *RaftKVClient) SetPriority(p int32) {
+	c.priority = p
+}
+
+// setPriorityHeader sets the priority of a DELETE request, other writes carry
+// it in their commands.
+func (c *RaftKVClient) setPriorityHeader(req *http.Request) {
+	if c.priority != common.PriorityNormal && req.Method == http.MethodDelete {
+		req.Header.Set(priorityHeader, common.PriorityName(c.priority))
+	}
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/raft-kv-store/common"
+)
+
+// Roles returns the roles of the access policy and their bindings, as json.
+func (c *RaftKVClient) Roles() (string, error) {
+	return c.policyRequest(http.MethodGet, nil, nil)
+}
+
+// SetRole sets the rules of the role name, and returns the policy as json.
+func (c *RaftKVClient) SetRole(name string, rules []common.Rule) (string, error) {
+	if rules == nil {
+		rules = []common.Rule{}
+	}
+	return c.policyRequest(http.MethodPut, url.Values{"role": {name}}, rules)
+}
+
+// DeleteRole deletes the role name, and returns the policy as json.
+func (c *RaftKVClient) DeleteRole(name string) (string, error) {
+	return c.policyRequest(http.MethodDelete, url.Values{"role": {name}}, nil)
+}
+
+// Bind binds roles to subject, an identity name or group:<name>, and returns
+// the policy as json.
+func (c *RaftKVClient) Bind(subject string, roles []string) (string, error) {
+	if roles == nil {
+		roles = []string{}
+	}
+	return c.policyRequest(http.MethodPut, url.Values{"subject": {subject}}, roles)
+}
+
+// Unbind unbinds the roles of subject, and returns the policy as json.
+func (c *RaftKVClient) Unbind(subject string) (string, error) {
+	return c.policyRequest(http.MethodDelete, url.Values{"subject": {subject}}, nil)
+}
+
+// policyRequest sends a request to the roles endpoint, with the json of v as
+// body if not nil, to the leader if it changes the policy.
+func (c *RaftKVClient) policyRequest(method string, q url.Values, v interface{}) (string, error) {
+	return c.leaderAdminRequest(method, "admin/roles", q, v)
+}
+
+// leaderAdminRequest sends a request to the admin endpoint path, with the
+// json of v as body if not nil, to the leader if it changes the state of the
+// coordinators, and returns the body of the reply.
+func (c *RaftKVClient) leaderAdminRequest(method, path string, q url.Values, v interface{}) (string, error) {
+	var data []byte
+	if v != nil {
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return "", err
+		}
+	}
+	resp, body, err := c.adminRequestWithBody(method, path, q, data)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.adminRequestWithBody(method, path, q, data); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Headers telling how a GET was served.
+const (
+	servedByHeader     = "X-Served-By"
+	shardHeader        = "X-Shard"
+	appliedIndexHeader = "X-Applied-Index"
+	appliedTermHeader  = "X-Applied-Term"
+	consistencyHeader  = "X-Consistency"
+)
+
+// LastRead returns how the last read of the client was served: by which node
+// of which shard, in which raft state, at which applied index and term, and
+// with which consistency check. It is nil before the first read. Comparing
+// the applied index of a stale value with the revision of the write it
+// misses tells whether the read lagged or the write came later.
+func (c *RaftKVClient) LastRead() *raftpb.ReadInfo {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+	return c.lastRead
+}
+
+// ShowReadInfo has the gets of the interactive client print how they were
+// served.
+func (c *RaftKVClient) ShowReadInfo() {
+	c.showReads = true
+}
+
+// FormatReadInfo describes how a read was served in one line.
+func FormatReadInfo(info *raftpb.ReadInfo) string {
+	return fmt.Sprintf("served by %s (%s) of shard %d at applied index %d, term %d, consistency %s",
+		info.Node, info.State, info.Shard, info.AppliedIndex, info.AppliedTerm, info.Consistency)
+}
+
+// observeRead records info as the annotation of the last read.
+func (c *RaftKVClient) observeRead(info *raftpb.ReadInfo) {
+	if info == nil {
+		return
+	}
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+	c.lastRead = info
+}
+
+// readInfoOf parses the read annotation of the headers h, nil if there is
+// none.
+func readInfoOf(h http.Header) *raftpb.ReadInfo {
+	served := h.Get(servedByHeader)
+	if served == "" {
+		return nil
+	}
+	info := &raftpb.ReadInfo{Node: served, Consistency: h.Get(consistencyHeader)}
+	// the node is followed by its raft state in parentheses
+	if i := strings.LastIndex(served, " ("); i >= 0 && strings.HasSuffix(served, ")") {
+		info.Node, info.State = served[:i], served[i+2:len(served)-1]
+	}
+	info.Shard, _ = strconv.ParseInt(h.Get(shardHeader), 10, 64)
+	info.AppliedIndex, _ = strconv.ParseInt(h.Get(appliedIndexHeader), 10, 64)
+	info.AppliedTerm, _ = strconv.ParseInt(h.Get(appliedTermHeader), 10, 64)
+	return info
+}
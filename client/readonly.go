@@ -0,0 +1,66 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ReadOnlyTransaction reads keys at a consistent point across their shards.
+// Unlike Transaction, nothing is replicated and no lock is taken, so the
+// reads neither slow down nor abort the concurrent writes.
+func (c *RaftKVClient) ReadOnlyTransaction(keys ...string) (*raftpb.RaftCommand, error) {
+	cmds := &raftpb.RaftCommand{IsTxn: true}
+	for _, key := range keys {
+		cmds.Commands = append(cmds.Commands, &raftpb.Command{Method: common.GET, Key: key})
+	}
+	reqBody, err := proto.Marshal(cmds)
+	if err != nil {
+		return nil, err
+	}
+	resp, body, err := c.readOnlyRequest(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.readOnlyRequest(reqBody); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	res := &raftpb.RaftCommand{}
+	if err := proto.Unmarshal(body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (c *RaftKVClient) readOnlyRequest(data []byte) (*http.Response, []byte, error) {
+	u, err := url.Parse(c.serverAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Path = path.Join(u.Path, "transaction")
+	u.RawQuery = url.Values{"readonly": {"true"}}.Encode()
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewBuffer(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
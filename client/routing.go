@@ -0,0 +1,187 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// shardMap is the routing table of the shards served by the coordinator at
+// /shardmap.
+type shardMap struct {
+	Shards  int                `json:"shards"`
+	Peers   map[int64][]string `json:"peers"`
+	Leaders map[int64]string   `json:"leaders"`
+}
+
+// shardRouting caches the shard map of a client sending single-key
+// operations to the shard leaders directly.
+type shardRouting struct {
+	mu sync.Mutex
+	m  *shardMap
+	// nodes are the store nodes of the single shard in coordinator-less
+	// mode, nil otherwise
+	nodes []string
+}
+
+// errMisrouted is returned for operations the shard node sent to cannot
+// serve, or when the shard has no known leader.
+var errMisrouted = errors.New("no known leader for the shard of the key")
+
+// EnableSmartRouting has the client send gets, sets and deletes straight to
+// the leader of the shard of their key, skipping the coordinator hop. The
+// shard map is fetched from the coordinator and cached until a shard node
+// cannot serve an operation, after which it is fetched again. Operations go
+// through the coordinator while a shard has no known leader. Direct writes
+// are not counted against the tenant quotas nor audited by the coordinator.
+func (c *RaftKVClient) EnableSmartRouting() error {
+	c.routing = &shardRouting{}
+	_, err := c.shardMap()
+	return err
+}
+
+// shardMap returns the cached shard map, fetched from the coordinator if
+// there is none.
+func (c *RaftKVClient) shardMap() (*shardMap, error) {
+	c.routing.mu.Lock()
+	defer c.routing.mu.Unlock()
+	if c.routing.m != nil {
+		return c.routing.m, nil
+	}
+	if c.routing.nodes != nil {
+		c.routing.m = standaloneShardMap(c.routing.nodes)
+		return c.routing.m, nil
+	}
+	m, err := c.fetchShardMap()
+	if err != nil {
+		return nil, err
+	}
+	c.routing.m = m
+	return m, nil
+}
+
+// fetchShardMap fetches the shard map from the coordinator.
+func (c *RaftKVClient) fetchShardMap() (*shardMap, error) {
+	resp, body, err := c.adminRequest(http.MethodGet, "shardmap", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New(string(body))
+	}
+	m := &shardMap{}
+	if err := json.Unmarshal(body, m); err != nil {
+		return nil, err
+	}
+	if m.Shards == 0 {
+		return nil, errors.New("no shard in the shard map")
+	}
+	return m, nil
+}
+
+func (c *RaftKVClient) invalidateShardMap() {
+	c.routing.mu.Lock()
+	c.routing.m = nil
+	c.routing.mu.Unlock()
+}
+
+// misrouted reports whether err, flattened by rpc, comes from a shard node
+// which is not the leader of its shard anymore.
+func misrouted(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, raft.ErrNotLeader.Error()) ||
+		strings.Contains(msg, raft.ErrLeadershipLost.Error()) ||
+		strings.Contains(msg, "node is a witness")
+}
+
+// unsigned reports whether err, flattened by rpc, comes from a shard node
+// refusing a write for not being signed with the cluster secret, which only
+// the coordinators hold.
+func unsigned(err error) bool {
+	return strings.Contains(err.Error(), common.ErrBadSignature.Error())
+}
+
+// direct sends cmd to the leader of the shard of its key. It returns false
+// if smart routing is disabled or the shard leader is unknown or unable to
+// serve cmd, in which case cmd is to be sent through the coordinator. In
+// coordinator-less mode, it always returns true. GETs see the revision
+// token of the client, and the revision of the reply is recorded in it.
+func (c *RaftKVClient) direct(cmd *raftpb.Command) (*raftpb.RPCResponse, bool, error) {
+	if c.routing == nil {
+		return nil, false, nil
+	}
+	m, err := c.shardMap()
+	if err != nil {
+		return nil, false, nil
+	}
+	shard := common.SimpleHash(cmd.Key, m.Shards)
+	if cmd.Method == common.GET {
+		cmd.MinRevision = c.minRevision(shard)
+	}
+	args := &raftpb.RaftCommand{Commands: []*raftpb.Command{cmd}, LockReply: true}
+	res, err := c.callShardLeader(cmd.Key, "Cohort.ProcessCommands", args)
+	if err == errMisrouted && c.routing.nodes != nil {
+		// without coordinator, the new leader is looked up right away
+		res, err = c.callShardLeader(cmd.Key, "Cohort.ProcessCommands", args)
+	}
+	if (err == errMisrouted || err != nil && unsigned(err)) && c.routing.nodes == nil {
+		return nil, false, nil
+	}
+	if err == nil {
+		if lock := common.ReplyLock(res); lock != nil {
+			return res, true, lock
+		}
+		c.observeRevision(shard, res.Revision)
+		if res.Read != nil {
+			res.Read.Shard = shard
+			c.observeRead(res.Read)
+		}
+	}
+	return res, true, err
+}
+
+// callShardLeader calls the rpc method of the leader of the shard of key. It
+// returns errMisrouted, after dropping the cached shard map, if the leader is
+// unknown, unreachable or not the leader anymore.
+func (c *RaftKVClient) callShardLeader(key, method string, args interface{}) (*raftpb.RPCResponse, error) {
+	m, err := c.shardMap()
+	if err != nil {
+		return nil, errMisrouted
+	}
+	addr, ok := m.Leaders[common.SimpleHash(key, m.Shards)]
+	if !ok {
+		c.invalidateShardMap()
+		return nil, errMisrouted
+	}
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		c.invalidateShardMap()
+		return nil, errMisrouted
+	}
+	defer client.Close()
+	var response raftpb.RPCResponse
+	call := client.Go(method, args, &response, nil)
+	var timeout <-chan time.Time
+	if c.client.Timeout > 0 {
+		timeout = time.After(c.client.Timeout)
+	}
+	select {
+	case <-call.Done:
+	case <-timeout:
+		// the outcome of a write is unknown, retrying it is up to the caller
+		return nil, errors.New("timed out waiting for the shard leader")
+	}
+	if call.Error != nil && misrouted(call.Error) {
+		c.invalidateShardMap()
+		return nil, errMisrouted
+	}
+	return &response, call.Error
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Headers identifying the DELETE requests of a session.
+const (
+	sessionHeader = "X-Session"
+	seqHeader     = "X-Seq"
+)
+
+// OpenSession opens a session for the following writes of the client. A
+// write retried by the client, after a timeout or a redirection to the
+// leader, is then applied at most once, even across restarts of the shards.
+func (c *RaftKVClient) OpenSession() error {
+	resp, body, err := c.adminRequest(http.MethodPost, "session", nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(string(body))
+	}
+	c.session, c.seq = string(body), 0
+	return nil
+}
+
+// CloseSession closes the session of the client, whose writes are no longer
+// deduplicated.
+func (c *RaftKVClient) CloseSession() error {
+	if c.session == "" {
+		return nil
+	}
+	resp, body, err := c.adminRequest(http.MethodDelete, "session/"+c.session, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(string(body))
+	}
+	c.session, c.seq = "", 0
+	return nil
+}
+
+// nextSeq returns the session and the sequence number of a new write, no
+// session if none is open.
+func (c *RaftKVClient) nextSeq() (string, int64) {
+	if c.session == "" {
+		return "", 0
+	}
+	c.seq++
+	return c.session, c.seq
+}
+
+// setSessionHeaders identifies a DELETE request as the current write of the
+// session of the client.
+func (c *RaftKVClient) setSessionHeaders(req *http.Request) {
+	if c.session != "" && req.Method == http.MethodDelete {
+		req.Header.Set(sessionHeader, c.session)
+		req.Header.Set(seqHeader, strconv.FormatInt(c.seq, 10))
+	}
+}
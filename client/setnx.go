@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// SetIfAbsent sets key to value only if the key does not exist, and reports
+// whether it did. Only one of concurrent callers for the same key gets true,
+// which makes it a simple lock or a guard for one-time initializations.
+func (c *RaftKVClient) SetIfAbsent(key string, value int64) (bool, error) {
+	cmd := &raftpb.Command{Method: common.SET, Key: key, Value: value, Priority: c.priority}
+	cmd.Session, cmd.Seq = c.nextSeq()
+	reqBody, err := proto.Marshal(cmd)
+	if err != nil {
+		return false, err
+	}
+	resp, body, err := c.setIfAbsentRequest(key, reqBody)
+	if err == nil && resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		resp, body, err = c.setIfAbsentRequest(key, reqBody)
+	}
+	if err != nil {
+		return false, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusPreconditionFailed:
+		return false, nil
+	}
+	return false, replyError(resp, body)
+}
+
+func (c *RaftKVClient) setIfAbsentRequest(key string, data []byte) (*http.Response, []byte, error) {
+	u, err := c.parseServerAddr(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, u+"?if=absent", bytes.NewBuffer(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shards returns the replication factor and the raft members of every shard,
+// as json.
+func (c *RaftKVClient) Shards() (string, error) {
+	resp, body, err := c.adminRequest(http.MethodGet, "admin/shards", nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// Nodes returns the store nodes with their liveness and metadata, as json.
+func (c *RaftKVClient) Nodes() (string, error) {
+	resp, body, err := c.adminRequest(http.MethodGet, "admin/nodes", nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// Compact snapshots the raft groups of the nodes of shard now, of every shard
+// if shard is negative, and returns the nodes compacted as json.
+func (c *RaftKVClient) Compact(shard int) (string, error) {
+	var q url.Values
+	if shard >= 0 {
+		q = url.Values{"shard": {strconv.Itoa(shard)}}
+	}
+	resp, body, err := c.adminRequest(http.MethodPost, "admin/compact", q)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// SetReplicas sets the replication factor of shard to replicas, 0 to stop
+// enforcing it, and returns the members of its raft groups as json.
+func (c *RaftKVClient) SetReplicas(shard, replicas int) (string, error) {
+	q := url.Values{
+		"shard":    {strconv.Itoa(shard)},
+		"replicas": {strconv.Itoa(replicas)},
+	}
+	resp, body, err := c.adminRequest(http.MethodPost, "admin/shards", q)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.adminRequest(http.MethodPost, "admin/shards", q); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// ChangeMembers promotes the non-voters promote of shard and then removes its
+// members remove, by store node id, and returns the members of its raft
+// groups as json.
+func (c *RaftKVClient) ChangeMembers(shard int, promote, remove []string) (string, error) {
+	q := url.Values{
+		"shard":   {strconv.Itoa(shard)},
+		"promote": {strings.Join(promote, ",")},
+		"remove":  {strings.Join(remove, ",")},
+	}
+	resp, body, err := c.adminRequest(http.MethodPost, "admin/members", q)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.adminRequest(http.MethodPost, "admin/members", q); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// ChangeMembership sends the json membership request to the coordinator
+// leader, applied or only planned with dryRun, and returns the report as
+// json. The request can be repeated: the steps already in place are skipped.
+func (c *RaftKVClient) ChangeMembership(request []byte, dryRun bool) (string, error) {
+	var q url.Values
+	if dryRun {
+		q = url.Values{"dry_run": {"true"}}
+	}
+	resp, body, err := c.adminRequestWithBody(http.MethodPost, "admin/membership", q, request)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.adminRequestWithBody(http.MethodPost, "admin/membership", q, request); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// Topology returns the desired topology of the cluster and the drift of the
+// shards from it, as json. A non-empty json topology replaces it first, and
+// clear clears it.
+func (c *RaftKVClient) Topology(topology []byte, clear bool) (string, error) {
+	method := http.MethodGet
+	if clear {
+		method = http.MethodDelete
+	} else if len(topology) > 0 {
+		method = http.MethodPut
+	}
+	resp, body, err := c.adminRequestWithBody(method, "admin/topology", nil, topology)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusMisdirectedRequest {
+		c.serverAddr = staticIPLeaderMapping[string(body)]
+		if resp, body, err = c.adminRequestWithBody(method, "admin/topology", nil, topology); err != nil {
+			return "", err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+// KeyProfile returns the load of the key prefixes sampled by the key profile
+// of the coordinator, running or last, as json, after stopping it if stop.
+func (c *RaftKVClient) KeyProfile(stop bool) (string, error) {
+	method := http.MethodGet
+	if stop {
+		method = http.MethodDelete
+	}
+	return c.keyProfile(method, nil)
+}
+
+// StartKeyProfile starts a key profile on the coordinator, sampling the
+// fraction rate of its requests by the first depth segments of their keys for
+// window, and returns it as json.
+func (c *RaftKVClient) StartKeyProfile(window time.Duration, rate float64, depth int) (string, error) {
+	q := url.Values{}
+	q.Set("duration", window.String())
+	q.Set("sample", strconv.FormatFloat(rate, 'g', -1, 64))
+	q.Set("depth", strconv.Itoa(depth))
+	return c.keyProfile(http.MethodPost, q)
+}
+
+func (c *RaftKVClient) keyProfile(method string, q url.Values) (string, error) {
+	resp, body, err := c.adminRequest(method, "admin/keyprofile", q)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New(string(body))
+	}
+	return string(body), nil
+}
+
+func (c *RaftKVClient) adminRequest(method, p string, q url.Values) (*http.Response, []byte, error) {
+	return c.adminRequestWithBody(method, p, q, nil)
+}
+
+// SetAdminEndpoint sends the requests to the management endpoints to the
+// coordinator at addr, for coordinators serving them on a separate
+// listener.
+func (c *RaftKVClient) SetAdminEndpoint(addr string) {
+	c.adminAddr = addURLScheme(addr)
+}
+
+func (c *RaftKVClient) adminRequestWithBody(method, p string, q url.Values, data []byte) (*http.Response, []byte, error) {
+	addr := c.serverAddr
+	if c.adminAddr != "" && strings.HasPrefix(p, "admin/") {
+		addr = c.adminAddr
+	}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	u.Path = path.Join(u.Path, p)
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp, body, err
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+
+	"github.com/fatih/color"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// EnableStandalone has the client talk directly to the store nodes of a
+// single-shard deployment, started without coordinator: the leader is looked
+// up among nodes, by rpc address, and serves the gets, sets, deletes and
+// transactions of the client. Transactions commit in one phase and hold only
+// sets and deletes. Sessions, history, imports, exports and the other
+// operations served by the coordinator are not available.
+func (c *RaftKVClient) EnableStandalone(nodes []string) error {
+	if len(nodes) == 0 {
+		return errors.New("no store node given")
+	}
+	c.routing = &shardRouting{nodes: nodes}
+	m, _ := c.shardMap()
+	if _, ok := m.Leaders[0]; !ok {
+		c.invalidateShardMap()
+		return fmt.Errorf("no leader among %v", nodes)
+	}
+	return nil
+}
+
+// standaloneShardMap returns the shard map of the single shard served by
+// nodes, with its leader if one of them is.
+func standaloneShardMap(nodes []string) *shardMap {
+	m := &shardMap{
+		Shards:  1,
+		Peers:   map[int64][]string{0: nodes},
+		Leaders: make(map[int64]string),
+	}
+	for _, addr := range nodes {
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			continue
+		}
+		var response raftpb.RPCResponse
+		err = client.Call("Cohort.ProcessCommands", &raftpb.RaftCommand{Commands: []*raftpb.Command{{Method: common.LEADER}}}, &response)
+		client.Close()
+		if err == nil && response.Addr != "" {
+			m.Leaders[0] = addr
+			break
+		}
+	}
+	return m
+}
+
+// standaloneTransaction commits cmds in one phase on the single shard of a
+// coordinator-less deployment.
+func (c *RaftKVClient) standaloneTransaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand, error) {
+	for _, cmd := range cmds.Commands {
+		if cmd.Method != common.SET && cmd.Method != common.DEL {
+			return nil, fmt.Errorf("%s in a transaction without coordinator", cmd.Method)
+		}
+	}
+	ops := &raftpb.ShardOps{
+		Txid:         xid.New().String(),
+		MasterKey:    cmds.Commands[0].Key,
+		Phase:        common.OnePhase,
+		Cmds:         cmds,
+		OutcomeReply: true,
+	}
+	res, err := c.callShardLeader(ops.MasterKey, "Cohort.ProcessTransactionMessages", ops)
+	if err == errMisrouted {
+		res, err = c.callShardLeader(ops.MasterKey, "Cohort.ProcessTransactionMessages", ops)
+	}
+	if err == nil && res.Phase == common.OutcomeUnknown {
+		return nil, fmt.Errorf("transaction %s: %w", ops.Txid, common.ErrOutcomeUnknown)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s aborted: %s", ops.Txid, err)
+	}
+	color.HiGreen("OK")
+	return &raftpb.RaftCommand{}, nil
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/raft-kv-store/common"
+)
+
+// Transforms returns the transforms of the blob values by key prefix, as
+// json.
+func (c *RaftKVClient) Transforms() (string, error) {
+	return c.leaderAdminRequest(http.MethodGet, "admin/transforms", nil, nil)
+}
+
+// SetTransforms sets the transforms applied in turn to the blob values of the
+// keys starting with prefix, and returns the transforms as json.
+func (c *RaftKVClient) SetTransforms(prefix string, specs []common.TransformSpec) (string, error) {
+	if specs == nil {
+		specs = []common.TransformSpec{}
+	}
+	return c.leaderAdminRequest(http.MethodPut, "admin/transforms", url.Values{"prefix": {prefix}}, specs)
+}
+
+// DeleteTransforms deletes the transforms of prefix, and returns the
+// transforms as json.
+func (c *RaftKVClient) DeleteTransforms(prefix string) (string, error) {
+	return c.leaderAdminRequest(http.MethodDelete, "admin/transforms", url.Values{"prefix": {prefix}}, nil)
+}
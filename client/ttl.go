@@ -0,0 +1,48 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// SetWithTTL sets key to value, to be deleted once ttl, rounded to the
+// millisecond, has passed. A ttl of 0 leaves the key to the ttl policy of its
+// prefix, if any. Writes routed straight to the shards skip the ttl policies.
+func (c *RaftKVClient) SetWithTTL(key string, value int64, ttl time.Duration) error {
+	return c.setCommand(&raftpb.Command{
+		Method: common.SET,
+		Key:    key,
+		Value:  value,
+		Ttl:    int64(ttl / time.Millisecond),
+	})
+}
+
+// expiresOf returns the expiry time of a write with a ttl of ttl
+// milliseconds, 0 for none.
+func expiresOf(ttl int64) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return time.Now().Add(time.Duration(ttl) * time.Millisecond).UnixNano()
+}
+
+// TTLPolicies returns the ttl policies of the keys by prefix, as json.
+func (c *RaftKVClient) TTLPolicies() (string, error) {
+	return c.leaderAdminRequest(http.MethodGet, "admin/ttl", nil, nil)
+}
+
+// SetTTLPolicy sets the ttl policy of the keys starting with prefix, and
+// returns the policies as json.
+func (c *RaftKVClient) SetTTLPolicy(prefix string, p common.TTLPolicy) (string, error) {
+	return c.leaderAdminRequest(http.MethodPut, "admin/ttl", url.Values{"prefix": {prefix}}, p)
+}
+
+// DeleteTTLPolicy deletes the ttl policy of prefix, and returns the policies
+// as json.
+func (c *RaftKVClient) DeleteTTLPolicy(prefix string) (string, error) {
+	return c.leaderAdminRequest(http.MethodDelete, "admin/ttl", url.Values{"prefix": {prefix}}, nil)
+}
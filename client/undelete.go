@@ -0,0 +1,13 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Undelete restores key, soft deleted, from its tombstone. It fails if the
+// key has no tombstone or exists again.
+func (c *RaftKVClient) Undelete(key string) error {
+	_, err := c.leaderAdminRequest(http.MethodPost, "admin/undelete", url.Values{"key": {key}}, nil)
+	return err
+}
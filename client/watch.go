@@ -0,0 +1,318 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+const (
+	// watchPollWait is how long the polls of a watch wait for changes.
+	watchPollWait = 10 * time.Second
+	// watchRetry is the delay before polling another node after an error.
+	watchRetry = 500 * time.Millisecond
+)
+
+// WatchEvent is a change of a key: the SET, DEL or EVICT command that made
+// it, with its revision in the shard of the key. Err is set instead on the
+// last event of a watch ended by an error.
+type WatchEvent struct {
+	Shard   int64
+	Command *raftpb.Command
+	Err     error
+}
+
+// Watcher streams the changes of the keys starting with a prefix on Events,
+// closed once the watch ends.
+type Watcher struct {
+	Events <-chan WatchEvent
+	stop   chan struct{}
+	once   sync.Once
+
+	mu sync.Mutex
+	// compacted is the last compact revision of every shard
+	compacted map[int64]int64
+}
+
+// CompactRevision returns the revision of shard after which its node polled
+// last keeps the changes, -1 if unknown. A watch resumed from an older
+// revision ends with common.ErrCompacted, the keys must be listed again.
+func (w *Watcher) CompactRevision(shard int64) int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if rev, ok := w.compacted[shard]; ok {
+		return rev
+	}
+	return -1
+}
+
+// Stop ends the watch.
+func (w *Watcher) Stop() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+// Watch streams the changes of the keys starting with prefix from now on,
+// polling a store node of every shard. The changes of a shard come in the
+// order of their revisions, the raft indexes of the shard, which are not
+// comparable across shards. When a node fails, or the watcher falls too far
+// behind, the watcher polls another node of the shard, which catches it up
+// from the changes it keeps; the watch ends with common.ErrCompacted if
+// the node does not keep all the changes missed.
+func (c *RaftKVClient) Watch(prefix string) (*Watcher, error) {
+	return c.WatchFrom(prefix, nil)
+}
+
+// WatchFrom is Watch, resuming the watch of every shard in revisions after
+// its revision there. The watch ends with common.ErrCompacted if a node does
+// not keep the changes of the shard after that revision anymore.
+func (c *RaftKVClient) WatchFrom(prefix string, revisions map[int64]int64) (*Watcher, error) {
+	return c.WatchFiltered(prefix, nil, revisions)
+}
+
+// WatchFiltered is WatchFrom, streaming only the changes selected by filter,
+// if not nil. The nodes filter the changes, so that the others are not sent
+// to the client: filter.Methods selects the commands, and filter.Field the
+// SETs of JSON values whose field, a dot separated path such as
+// "job.status", equals the JSON value filter.Equals, such as "\"done\"".
+func (c *RaftKVClient) WatchFiltered(prefix string, filter *raftpb.WatchFilter, revisions map[int64]int64) (*Watcher, error) {
+	m, err := c.watchShardMap()
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan WatchEvent)
+	w := newWatcher(events)
+	var watches []shardWatch
+	for shard := int64(0); shard < int64(m.Shards); shard++ {
+		nodes := watchNodes(m, shard)
+		if len(nodes) == 0 {
+			continue
+		}
+		req := newWatchRequest(prefix, revisions[shard])
+		req.Filter = filter
+		watches = append(watches, shardWatch{shard: shard, nodes: nodes, req: req})
+	}
+	w.start(events, watches)
+	return w, nil
+}
+
+// ListAndWatch returns the keys starting with prefix, in key order, and
+// streams their changes from there on like Watch. Every shard lists its keys
+// and registers the watch at a single revision, so the changes streamed are
+// exactly those made after the listing: a cache loaded with the entries and
+// updated with the events stays consistent with every shard. Unlike Export,
+// the listings of the shards are not a consistent cut of the cluster.
+func (c *RaftKVClient) ListAndWatch(prefix string) ([]*raftpb.KVEntry, *Watcher, error) {
+	m, err := c.watchShardMap()
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make(chan WatchEvent)
+	w := newWatcher(events)
+	var entries []*raftpb.KVEntry
+	var watches []shardWatch
+	for shard := int64(0); shard < int64(m.Shards); shard++ {
+		nodes := watchNodes(m, shard)
+		if len(nodes) == 0 {
+			continue
+		}
+		sw, list, err := listShard(shard, nodes, prefix)
+		if err != nil {
+			for _, sw := range watches {
+				pollWatch(sw.nodes[0], &raftpb.WatchRequest{Id: sw.req.Id, Cancel: true})
+			}
+			return nil, nil, fmt.Errorf("shard %d: %s", shard, err)
+		}
+		w.compacted[shard] = sw.compacted
+		entries = append(entries, list...)
+		watches = append(watches, sw)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	w.start(events, watches)
+	return entries, w, nil
+}
+
+// shardWatch is the watch of a shard: the nodes to poll, the first polled
+// first, the next poll and the changes received before the watch started.
+type shardWatch struct {
+	shard     int64
+	nodes     []string
+	req       *raftpb.WatchRequest
+	backlog   []*raftpb.Command
+	compacted int64
+}
+
+// listShard lists the keys of shard starting with prefix from the first of
+// nodes that replies, registering the watch of their changes there.
+func listShard(shard int64, nodes []string, prefix string) (shardWatch, []*raftpb.KVEntry, error) {
+	req := newWatchRequest(prefix, 0)
+	list := &raftpb.WatchRequest{Id: req.Id, Prefix: prefix, List: true}
+	var err error
+	for i, addr := range nodes {
+		var res *raftpb.RPCResponse
+		if res, err = pollWatch(addr, list); err != nil {
+			continue
+		}
+		req.After = res.Value
+		// the node registered is polled first
+		polled := append([]string{addr}, nodes[:i]...)
+		polled = append(polled, nodes[i+1:]...)
+		return shardWatch{shard: shard, nodes: polled, req: req, backlog: res.Commands, compacted: res.CompactRevision}, res.Entries, nil
+	}
+	return shardWatch{}, nil, err
+}
+
+// watchShardMap returns the shard map, cached if routing.
+func (c *RaftKVClient) watchShardMap() (*shardMap, error) {
+	if c.routing != nil {
+		return c.shardMap()
+	}
+	return c.fetchShardMap()
+}
+
+// watchNodes returns the nodes of shard to poll, the leader first.
+func watchNodes(m *shardMap, shard int64) []string {
+	var nodes []string
+	if leader, ok := m.Leaders[shard]; ok {
+		nodes = append(nodes, leader)
+	}
+	for _, addr := range m.Peers[shard] {
+		if addr != m.Leaders[shard] {
+			nodes = append(nodes, addr)
+		}
+	}
+	return nodes
+}
+
+func newWatcher(events <-chan WatchEvent) *Watcher {
+	return &Watcher{Events: events, stop: make(chan struct{}), compacted: make(map[int64]int64)}
+}
+
+func newWatchRequest(prefix string, after int64) *raftpb.WatchRequest {
+	return &raftpb.WatchRequest{Id: xid.New().String(), Prefix: prefix, After: after, Wait: int64(watchPollWait / time.Millisecond)}
+}
+
+// start runs the watches of the shards, closing events once they all end.
+func (w *Watcher) start(events chan<- WatchEvent, watches []shardWatch) {
+	var wg sync.WaitGroup
+	for _, sw := range watches {
+		wg.Add(1)
+		go func(sw shardWatch) {
+			defer wg.Done()
+			w.watchShard(events, sw)
+		}(sw)
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+}
+
+// watchShard sends the backlog of sw, then polls the nodes of its shard for
+// the changes of the keys after revision sw.req.After, or from now on if 0,
+// until the watch is stopped or ends with an error.
+func (w *Watcher) watchShard(events chan<- WatchEvent, sw shardWatch) {
+	shard, nodes, req := sw.shard, sw.nodes, sw.req
+	for _, cmd := range sw.backlog {
+		if !w.send(events, WatchEvent{Shard: shard, Command: cmd}) {
+			pollWatch(nodes[0], &raftpb.WatchRequest{Id: req.Id, Cancel: true})
+			return
+		}
+		req.After = cmd.Revision
+	}
+	var n int
+	// compacted is set once the watcher was cancelled as too slow
+	var compacted bool
+	for {
+		addr := nodes[n%len(nodes)]
+		res, err := w.poll(addr, req)
+		if err == errWatchStopped {
+			pollWatch(addr, &raftpb.WatchRequest{Id: req.Id, Cancel: true})
+			return
+		}
+		if err != nil {
+			if common.IsCompacted(err) {
+				if compacted {
+					// the node does not keep the changes missed either
+					w.send(events, WatchEvent{Shard: shard, Err: err})
+					return
+				}
+				compacted = true
+			} else {
+				n++
+			}
+			select {
+			case <-w.stop:
+				return
+			case <-time.After(watchRetry):
+			}
+			continue
+		}
+		compacted = false
+		w.mu.Lock()
+		w.compacted[shard] = res.CompactRevision
+		w.mu.Unlock()
+		if req.After == 0 {
+			req.After = res.Value
+		}
+		for _, cmd := range res.Commands {
+			if !w.send(events, WatchEvent{Shard: shard, Command: cmd}) {
+				return
+			}
+			req.After = cmd.Revision
+		}
+	}
+}
+
+// errWatchStopped is returned by the polls of a stopped watch.
+var errWatchStopped = errors.New("watch stopped")
+
+// poll polls addr for req, until the watch is stopped.
+func (w *Watcher) poll(addr string, req *raftpb.WatchRequest) (*raftpb.RPCResponse, error) {
+	type result struct {
+		res *raftpb.RPCResponse
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := pollWatch(addr, req)
+		done <- result{res, err}
+	}()
+	select {
+	case r := <-done:
+		return r.res, r.err
+	case <-time.After(2 * watchPollWait):
+		return nil, errors.New("timed out polling " + addr)
+	case <-w.stop:
+		return nil, errWatchStopped
+	}
+}
+
+// send sends ev, and returns false if the watch is stopped first.
+func (w *Watcher) send(events chan<- WatchEvent, ev WatchEvent) bool {
+	select {
+	case events <- ev:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
+
+func pollWatch(addr string, req *raftpb.WatchRequest) (*raftpb.RPCResponse, error) {
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	var response raftpb.RPCResponse
+	if err := client.Call("Cohort.Watch", req, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// protobufContentType is the media type of the protobuf replies of the
+// coordinators.
+const protobufContentType = "application/protobuf"
+
+// EnableProtobuf makes the reads, and the writes returning the value they
+// replace, ask the coordinators for protobuf replies rather than text, which
+// are cheaper to build and parse.
+func (c *RaftKVClient) EnableProtobuf() {
+	c.protobuf = true
+}
+
+// setAcceptHeader asks for a protobuf reply to req if enabled.
+func (c *RaftKVClient) setAcceptHeader(req *http.Request) {
+	if c.protobuf {
+		req.Header.Set("Accept", protobufContentType)
+	}
+}
+
+// decodeReply returns the RPCResponse of a protobuf reply, nil if resp is
+// not one.
+func decodeReply(resp *http.Response, body []byte) (*raftpb.RPCResponse, error) {
+	if resp.Header.Get("Content-Type") != protobufContentType {
+		return nil, nil
+	}
+	res := &raftpb.RPCResponse{}
+	if err := proto.Unmarshal(body, res); err != nil {
+		return nil, fmt.Errorf("failed to parse the reply: %s", err)
+	}
+	return res, nil
+}
+
+// valueOf returns the value of key in res, as the body of a text GET reply,
+// and its codec, empty for numerical values.
+func valueOf(key string, res *raftpb.RPCResponse) ([]byte, string) {
+	if res.Codec != "" {
+		return res.Blob, res.Codec
+	}
+	return []byte(fmt.Sprintf("Key=%s, Value=%d", key, res.Value)), ""
+}
+
+// errorText returns the text of a failed request replied with body: the
+// message of a protobuf Error, or the leader of a redirection, as in text
+// replies.
+func errorText(resp *http.Response, body []byte) []byte {
+	if resp.StatusCode < http.StatusBadRequest || resp.Header.Get("Content-Type") != protobufContentType {
+		return body
+	}
+	e := &raftpb.Error{}
+	if err := proto.Unmarshal(body, e); err != nil {
+		return body
+	}
+	if e.Leader != "" {
+		return []byte(e.Leader)
+	}
+	return []byte(e.Message)
+}
@@ -0,0 +1,23 @@
+"""Reference Python client of raft-kv-store."""
+
+from .client import (
+    DEFAULT_ENDPOINTS,
+    DEFAULT_LEADERS,
+    Client,
+    Conflict,
+    Error,
+    NotLeader,
+    Unavailable,
+    Value,
+)
+
+__all__ = [
+    "DEFAULT_ENDPOINTS",
+    "DEFAULT_LEADERS",
+    "Client",
+    "Conflict",
+    "Error",
+    "NotLeader",
+    "Unavailable",
+    "Value",
+]
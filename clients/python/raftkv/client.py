@@ -0,0 +1,200 @@
+"""Client of the HTTP API of the coordinators, with protobuf bodies.
+
+The messages are generated from raftpb/raft.proto by `make proto-python`.
+Like the Go client, the client sends its requests to an active coordinator,
+moves to another one when it is unreachable, follows the redirections of the
+writes to the coordinator leader, and keeps the revision token of its writes
+and reads so that its reads see every write it saw before.
+"""
+
+import collections
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+
+from . import raft_pb2
+
+PROTOBUF = "application/protobuf"
+
+# The coordinators of the docker cluster, as in the Go client.
+DEFAULT_ENDPOINTS = [
+    "http://node0:17000",
+    "http://node1:17000",
+    "http://node2:17000",
+]
+
+# The HTTP endpoints of the coordinators by raft address, the leader named by
+# the redirections.
+DEFAULT_LEADERS = {
+    "10.10.10.2:18000": "http://node0:17000",
+    "10.10.10.3:18000": "http://node1:17000",
+    "10.10.10.4:18000": "http://node2:17000",
+}
+
+# Value is a value read: value for numbers, or codec and blob for encoded
+# values, with the key metadata, the revision the read was served at, and
+# how it was served, a raft_pb2.ReadInfo.
+Value = collections.namedtuple("Value", "key value codec blob meta revision read")
+
+
+class Error(Exception):
+    """A failed request, from its raft_pb2.Error reply."""
+
+    def __init__(self, reply):
+        super().__init__("%d: %s" % (reply.code, reply.message))
+        self.code = reply.code
+        self.message = reply.message
+        self.reply = reply
+
+
+class NotLeader(Error):
+    """A request only the coordinator leader serves, sent to a follower."""
+
+    @property
+    def leader(self):
+        return self.reply.leader
+
+
+class Conflict(Error):
+    """A write conflicting with a transaction, worth retrying after
+    retry_backoff seconds."""
+
+    @property
+    def retry_backoff(self):
+        return self.reply.retry_backoff_ms / 1000.0
+
+
+class Unavailable(Error):
+    """A request rejected by an overloaded shard or an exceeded quota, or
+    a read of a revision not applied in time, worth retrying later."""
+
+
+def _error(code, body):
+    reply = raft_pb2.Error()
+    try:
+        reply.ParseFromString(body)
+    except Exception:
+        reply.message = body.decode("utf-8", "replace")
+    reply.code = code
+    if code == 421:
+        return NotLeader(reply)
+    if code == 409:
+        return Conflict(reply)
+    if code in (429, 503):
+        return Unavailable(reply)
+    return Error(reply)
+
+
+class Client:
+    """Client of a raft-kv-store cluster.
+
+    endpoints are the HTTP addresses of the coordinators, the first one
+    active, and leaders maps the raft addresses of the coordinators to their
+    endpoints. Requests failing to reach the active coordinator, or rejected
+    as unavailable, are retried up to retries times, backing off from
+    backoff seconds.
+    """
+
+    def __init__(self, endpoints=None, leaders=None, timeout=2.0, retries=3,
+                 backoff=0.05, token=None):
+        self.endpoints = list(endpoints or DEFAULT_ENDPOINTS)
+        self.leaders = dict(DEFAULT_LEADERS if leaders is None else leaders)
+        self.active = self.endpoints[0]
+        self.timeout = timeout
+        self.retries = retries
+        self.backoff = backoff
+        self.token = token
+        # the latest revision seen of every shard
+        self.revisions = {}
+        # how the last read was served
+        self.last_read = None
+
+    def get(self, key):
+        """Returns the Value of key."""
+        query = {}
+        token = self.revision_token()
+        if token:
+            query["min_revision"] = token
+        reply = raft_pb2.RPCResponse()
+        reply.ParseFromString(self._request("GET", "/key/" + key, query=query))
+        if reply.HasField("read"):
+            self.last_read = reply.read
+        return Value(key, reply.value, reply.codec, reply.blob,
+                     reply.meta, reply.revision, self.last_read)
+
+    def set(self, key, value):
+        """Sets key to the number value."""
+        self._request("POST", "/key/" + key,
+                      body=raft_pb2.Command(method="set", key=key, value=value))
+
+    def set_blob(self, key, codec, blob):
+        """Sets key to blob, a value encoded with codec."""
+        self._request("POST", "/key/" + key,
+                      body=raft_pb2.Command(method="set", key=key, codec=codec, blob=blob))
+
+    def delete(self, key):
+        """Deletes key."""
+        self._request("DELETE", "/key/" + key)
+
+    def revision_token(self):
+        """Returns the revision token of the client, the latest revision
+        seen of every shard, in its text form."""
+        return ",".join("%d:%d" % (shard, rev) for shard, rev in sorted(self.revisions.items()))
+
+    def observe_revisions(self, token):
+        """Merges token, the revision_token of another client, into the
+        token of the client."""
+        for pair in filter(None, token.split(",")):
+            shard, rev = pair.split(":")
+            shard, rev = int(shard), int(rev)
+            if rev > self.revisions.get(shard, 0):
+                self.revisions[shard] = rev
+
+    def _request(self, method, path, body=None, query=None):
+        data = body.SerializeToString() if body is not None else None
+        attempt = 0
+        while True:
+            try:
+                return self._send(self.active, method, path, data, query)
+            except NotLeader as e:
+                # the redirection is followed once, then the endpoints tried
+                leader = self.leaders.get(e.leader)
+                if leader is None or leader == self.active or attempt >= self.retries:
+                    raise
+                self.active = leader
+            except Unavailable:
+                if attempt >= self.retries:
+                    raise
+                time.sleep(self.backoff * (2 ** attempt))
+            except urllib.error.URLError:
+                if attempt >= self.retries:
+                    raise
+                self._next_endpoint()
+            attempt += 1
+
+    def _next_endpoint(self):
+        i = self.endpoints.index(self.active) if self.active in self.endpoints else -1
+        self.active = self.endpoints[(i + 1) % len(self.endpoints)]
+
+    def _send(self, endpoint, method, path, data, query):
+        url = endpoint + urllib.parse.quote(path)
+        if query:
+            url += "?" + urllib.parse.urlencode(query)
+        req = urllib.request.Request(url, data=data, method=method)
+        req.add_header("Accept", PROTOBUF)
+        if data is not None:
+            req.add_header("Content-Type", PROTOBUF)
+        if self.token:
+            req.add_header("Authorization", "Bearer " + self.token)
+        try:
+            with urllib.request.urlopen(req, timeout=self.timeout) as resp:
+                self._observe(resp.headers)
+                return resp.read()
+        except urllib.error.HTTPError as e:
+            raise _error(e.code, e.read())
+
+    def _observe(self, headers):
+        token = headers.get("X-Revision")
+        if token:
+            self.observe_revisions(token)
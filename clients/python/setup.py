@@ -0,0 +1,9 @@
+from setuptools import setup
+
+setup(
+    name="raftkv",
+    version="0.1",
+    description="Reference Python client of raft-kv-store",
+    packages=["raftkv"],
+    install_requires=["protobuf>=3.12"],
+)
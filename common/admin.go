@@ -0,0 +1,104 @@
+package common
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"expvar"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	rpprof "runtime/pprof"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AdminServer serves the debug endpoints of a node on a separate port. Every
+// request must carry the admin token as a bearer token.
+type AdminServer struct {
+	addr  string
+	token string
+	mux   *http.ServeMux
+	log   *log.Entry
+}
+
+// NewAdminServer returns an admin server for addr with the pprof, expvar and
+// goroutine dump endpoints. Node specific endpoints are added with Handle.
+func NewAdminServer(logger *log.Logger, addr, token string) *AdminServer {
+	a := &AdminServer{
+		addr:  addr,
+		token: token,
+		mux:   http.NewServeMux(),
+		log:   logger.WithField("component", "admin"),
+	}
+	a.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	a.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	a.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	a.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	a.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	a.mux.Handle("/debug/vars", expvar.Handler())
+	a.mux.HandleFunc("/debug/goroutines", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		rpprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+	return a
+}
+
+// Handle registers handler for pattern.
+func (a *AdminServer) Handle(pattern string, handler http.Handler) {
+	a.mux.Handle(pattern, handler)
+}
+
+// HandleJSON registers for pattern a handler writing the result of fn as json.
+func (a *AdminServer) HandleJSON(pattern string, fn func() interface{}) {
+	a.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		b, err := json.MarshalIndent(fn(), "", "  ")
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	})
+}
+
+// ServeHTTP checks the admin token before serving r.
+func (a *AdminServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	a.mux.ServeHTTP(w, r)
+}
+
+// Start starts serving in the background, to the AdminAllow addresses only
+// and over TLS with the management certificate if set.
+func (a *AdminServer) Start() {
+	if a.token == "" {
+		a.log.Fatal("an admin token is required to serve the admin endpoints")
+	}
+	ln, err := net.Listen("tcp", a.addr)
+	if err != nil {
+		a.log.Fatalf("failed to start admin service: %s", err)
+	}
+	if ln, err = AllowListener(ln, AdminAllow, a.log); err != nil {
+		a.log.Fatalf("failed to start admin service: %s", err)
+	}
+	tlsConfig, err := ManagementTLSConfig()
+	if err != nil {
+		a.log.Fatalf("failed to configure TLS: %s", err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	go func() {
+		if err := http.Serve(ln, a); err != nil {
+			a.log.Fatalf("admin serve error: %s", err)
+		}
+	}()
+	a.log.Infof("admin endpoints listening on %s", a.addr)
+}
@@ -0,0 +1,102 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Allowlists of the listeners of a node, as IP addresses or CIDR ranges,
+// allowing every address if empty.
+var (
+	// ClientAllow guards the service listener: the HTTP API of coordinators
+	// and the rpc listener of store nodes.
+	ClientAllow []string
+	// PeerAllow guards the raft listeners.
+	PeerAllow []string
+	// AdminAllow guards the management and debug listeners.
+	AdminAllow []string
+)
+
+// Allowlist is a set of IP ranges.
+type Allowlist []*net.IPNet
+
+// ParseAllowlist parses the IP addresses and CIDR ranges of specs.
+func ParseAllowlist(specs []string) (Allowlist, error) {
+	var a Allowlist
+	for _, spec := range specs {
+		if !strings.Contains(spec, "/") {
+			ip := net.ParseIP(spec)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", spec)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			a = append(a, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(spec)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, n)
+	}
+	return a, nil
+}
+
+// Allows reports whether the host of addr is in a, always if a is empty.
+func (a Allowlist) Allows(addr net.Addr) bool {
+	if len(a) == 0 {
+		return true
+	}
+	host := addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range a {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowListener closes the connections of the addresses not allowed.
+type allowListener struct {
+	net.Listener
+	allow Allowlist
+	log   *log.Entry
+}
+
+// AllowListener returns ln accepting the connections of the addresses of
+// specs only, ln itself if specs is empty.
+func AllowListener(ln net.Listener, specs []string, logger *log.Entry) (net.Listener, error) {
+	allow, err := ParseAllowlist(specs)
+	if err != nil || len(allow) == 0 {
+		return ln, err
+	}
+	return &allowListener{Listener: ln, allow: allow, log: logger}, nil
+}
+
+// Accept waits for the next connection of an allowed address.
+func (l *allowListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if l.allow.Allows(conn.RemoteAddr()) {
+			return conn, nil
+		}
+		l.log.Warnf("rejecting connection of %s to %s, not allowed", conn.RemoteAddr(), l.Addr())
+		conn.Close()
+	}
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"net"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowlist(t *testing.T) {
+	_, err := ParseAllowlist([]string{"10.0.0.300"})
+	assert.NotNil(t, err)
+	a, err := ParseAllowlist([]string{"10.1.0.0/16", "192.168.1.7", "::1"})
+	assert.Nil(t, err)
+	assert.True(t, a.Allows(&net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 80}))
+	assert.True(t, a.Allows(&net.TCPAddr{IP: net.ParseIP("192.168.1.7"), Port: 80}))
+	assert.True(t, a.Allows(&net.TCPAddr{IP: net.ParseIP("::1"), Port: 80}))
+	assert.False(t, a.Allows(&net.TCPAddr{IP: net.ParseIP("192.168.1.8"), Port: 80}))
+	assert.True(t, Allowlist(nil).Allows(&net.TCPAddr{IP: net.ParseIP("192.168.1.8")}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	allowed, err := AllowListener(ln, []string{"10.0.0.0/8"}, log.NewEntry(log.New()))
+	assert.Nil(t, err)
+	defer allowed.Close()
+	go func() {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err == nil {
+			// closed by the listener
+			b := make([]byte, 1)
+			conn.Read(b)
+			conn.Close()
+		}
+		allowed.Close()
+	}()
+	_, err = allowed.Accept()
+	assert.NotNil(t, err)
+}
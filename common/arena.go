@@ -0,0 +1,166 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"github.com/subchen/go-trylock/v2"
+)
+
+// ArenaValues has the key-value maps of the store nodes allocate their
+// entries in slabs of ArenaSlabValues, and copy the bytes of the blob values
+// up to ArenaMaxBlob bytes in slabs of ArenaSlabBytes, instead of one by one.
+// Millions of tiny values then make a few thousand objects for the garbage
+// collector instead of millions, at the cost of the slots of the deleted
+// entries, which are not reused: a slab is freed once all its entries are.
+var ArenaValues bool
+
+const (
+	// ArenaSlabValues is the number of entries of a slab.
+	ArenaSlabValues = 4096
+	// ArenaSlabBytes is the size of a slab of blob bytes.
+	ArenaSlabBytes = 256 << 10
+	// ArenaMaxBlob is the size of the largest blob copied in the slabs.
+	ArenaMaxBlob = 256
+)
+
+// Arena metrics of the store nodes.
+const (
+	ArenaSlabsMetric       = "raftkv_arena_slabs"
+	ArenaUsedMetric        = "raftkv_arena_used"
+	ArenaLiveMetric        = "raftkv_arena_live_values"
+	ArenaUtilizationMetric = "raftkv_arena_utilization_ratio"
+)
+
+// Arena allocates the entries of a Cmap in slabs. A nil Arena allocates them
+// one by one.
+type Arena struct {
+	mu     sync.Mutex
+	values []Value
+	bytes  []byte
+	stats  ArenaStats
+}
+
+// ArenaStats is the utilization of an arena: the slabs allocated, the
+// entries and bytes handed out of them, and the entries still in the map.
+// The slabs of blob bytes are not tracked further than their bytes handed
+// out, the values they hold being replaced in place.
+type ArenaStats struct {
+	ValueSlabs int64 `json:"value_slabs"`
+	Values     int64 `json:"values"`
+	LiveValues int64 `json:"live_values"`
+	ByteSlabs  int64 `json:"byte_slabs"`
+	Bytes      int64 `json:"bytes"`
+	// Utilization is the fraction of the entries of the slabs still in the
+	// map, 0 if none was allocated.
+	Utilization float64 `json:"utilization"`
+}
+
+// NewArena returns an arena if ArenaValues is set, nil otherwise.
+func NewArena() *Arena {
+	if !ArenaValues {
+		return nil
+	}
+	return &Arena{}
+}
+
+// newValue returns a new entry of key k holding v, temporary if temp.
+func (a *Arena) newValue(k string, v interface{}, temp bool) *Value {
+	if a == nil {
+		if temp {
+			return TempNewValue(k, v)
+		}
+		return NewValue(k, v)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.values) == 0 {
+		a.values = make([]Value, ArenaSlabValues)
+		a.stats.ValueSlabs++
+	}
+	value := &a.values[0]
+	a.values = a.values[1:]
+	a.stats.Values++
+	a.stats.LiveValues++
+	*value = Value{k: k, V: a.copyBlob(v), mu: trylock.New(), temp: temp}
+	if !temp {
+		value.lastAccess = time.Now().UnixNano()
+	}
+	return value
+}
+
+// value returns v, to replace the value of an entry, its bytes copied in a
+// slab if it is a small blob.
+func (a *Arena) value(v interface{}) interface{} {
+	if a == nil {
+		return v
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.copyBlob(v)
+}
+
+// copyBlob returns v, its bytes copied in a slab if it is a small blob, with a
+// locked.
+func (a *Arena) copyBlob(v interface{}) interface{} {
+	b, ok := v.(*Blob)
+	if !ok || len(b.Data) == 0 || len(b.Data) > ArenaMaxBlob {
+		return v
+	}
+	if len(a.bytes) < len(b.Data) {
+		a.bytes = make([]byte, ArenaSlabBytes)
+		a.stats.ByteSlabs++
+	}
+	n := len(b.Data)
+	data := a.bytes[:n:n]
+	a.bytes = a.bytes[n:]
+	copy(data, b.Data)
+	a.stats.Bytes += int64(n)
+	return &Blob{Codec: b.Codec, Data: data}
+}
+
+// free counts an entry removed from the map.
+func (a *Arena) free() {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stats.LiveValues--
+}
+
+// Stats returns the utilization of a, zero if nil.
+func (a *Arena) Stats() ArenaStats {
+	if a == nil {
+		return ArenaStats{}
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	stats := a.stats
+	if stats.ValueSlabs > 0 {
+		stats.Utilization = float64(stats.LiveValues) / float64(stats.ValueSlabs*ArenaSlabValues)
+	}
+	return stats
+}
+
+// RegisterArenaMetrics registers the arena metrics in m.
+func RegisterArenaMetrics(m *Metrics) {
+	m.Register(ArenaSlabsMetric, GaugeMetric, "Slabs allocated by the arena of the key-value map, by kind.")
+	m.Register(ArenaUsedMetric, GaugeMetric, "Entries and blob bytes handed out of the arena slabs, by kind.")
+	m.Register(ArenaLiveMetric, GaugeMetric, "Entries of the arena slabs still in the key-value map.")
+	m.Register(ArenaUtilizationMetric, GaugeMetric, "Fraction of the entries of the arena slabs still in the key-value map.")
+}
+
+// SetMetrics sets the arena metrics of a in m, none if nil.
+func (a *Arena) SetMetrics(m *Metrics) {
+	if a == nil {
+		return
+	}
+	stats := a.Stats()
+	m.Set(ArenaSlabsMetric, float64(stats.ValueSlabs), "kind", "values")
+	m.Set(ArenaSlabsMetric, float64(stats.ByteSlabs), "kind", "bytes")
+	m.Set(ArenaUsedMetric, float64(stats.Values), "kind", "values")
+	m.Set(ArenaUsedMetric, float64(stats.Bytes), "kind", "bytes")
+	m.Set(ArenaLiveMetric, float64(stats.LiveValues))
+	m.Set(ArenaUtilizationMetric, stats.Utilization)
+}
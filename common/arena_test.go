@@ -0,0 +1,53 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArena(t *testing.T) {
+	assert.Nil(t, NewArena())
+	assert.Equal(t, ArenaStats{}, NewCmap(log.New(), 0).Arena().Stats())
+
+	defer func() { ArenaValues = false }()
+	ArenaValues = true
+	m := NewCmap(log.New(), 0)
+
+	data := []byte("tiny")
+	assert.Nil(t, m.Set("a", int64(1)))
+	assert.Nil(t, m.Set("b", &Blob{Codec: "raw", Data: data}))
+	data[0] = 'T'
+	v, ok, err := m.Get("b")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	// the blob was copied in a slab
+	assert.Equal(t, []byte("tiny"), v.(*Blob).Data)
+
+	assert.Nil(t, m.TryLocks([]*raftpb.Command{{Method: SET, Key: "c", Value: 3}}, "tx1"))
+	m.AbortWithLocks([]*raftpb.Command{{Method: SET, Key: "c", Value: 3}}, "tx1")
+	m.Write([]*raftpb.Command{{Method: SET, Key: "a", Value: 2}, {Method: DEL, Key: "b"}}, 1)
+	assert.Nil(t, m.Del("a"))
+	v, ok, _ = m.Get("a")
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Set("d", int64(4)))
+	stats := m.Arena().Stats()
+	assert.Equal(t, int64(1), stats.ValueSlabs)
+	assert.Equal(t, int64(5), stats.Values)
+	assert.Equal(t, int64(1), stats.LiveValues)
+	assert.Equal(t, int64(1), stats.ByteSlabs)
+	assert.Equal(t, int64(4), stats.Bytes)
+	assert.Equal(t, 1/float64(ArenaSlabValues), stats.Utilization)
+
+	metrics := NewMetrics()
+	RegisterArenaMetrics(metrics)
+	m.Arena().SetMetrics(metrics)
+	var b strings.Builder
+	assert.Nil(t, metrics.Write(&b))
+	assert.Contains(t, b.String(), `raftkv_arena_used{kind="values"} 5`)
+	assert.Contains(t, b.String(), "raftkv_arena_live_values 1")
+}
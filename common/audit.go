@@ -0,0 +1,135 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is a line of the audit log.
+type AuditRecord struct {
+	Time   time.Time `json:"time"`
+	Who    string    `json:"who"`
+	Op     string    `json:"op"`
+	Key    string    `json:"key,omitempty"`
+	Txid   string    `json:"txid,omitempty"`
+	Status int       `json:"status"`
+}
+
+// AuditLog appends records, one json object per line, to its sinks. A nil
+// AuditLog records nothing.
+type AuditLog struct {
+	mu     sync.Mutex
+	sinks  []io.Writer
+	writes bool
+}
+
+// NewAuditLog returns an audit log of administrative operations, and of
+// every write if writes is set, to sinks.
+func NewAuditLog(writes bool, sinks ...io.Writer) *AuditLog {
+	return &AuditLog{sinks: sinks, writes: writes}
+}
+
+// Enabled reports whether operations of the given kind are recorded.
+func (a *AuditLog) Enabled(write bool) bool {
+	return a != nil && (!write || a.writes)
+}
+
+// Record appends rec to every sink. It returns the first error of the sinks,
+// the record is still appended to the others.
+func (a *AuditLog) Record(rec *AuditRecord) error {
+	if a == nil {
+		return nil
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var first error
+	for _, s := range a.sinks {
+		if _, err := s.Write(b); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// RotatingFile is a file that is rotated once it would grow over a maximum
+// size. Rotated files are suffixed with .1, the most recent, up to the number
+// of kept backups.
+type RotatingFile struct {
+	path    string
+	maxSize int64
+	backups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingFile opens, or creates, the file at path for appending.
+func NewRotatingFile(path string, maxSize int64, backups int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p does not fit.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	if f.backups > 0 {
+		for i := f.backups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		}
+		if err := os.Rename(f.path, f.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(f.path); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,53 @@
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog(t *testing.T) {
+	var nilLog *AuditLog
+	assert.False(t, nilLog.Enabled(false))
+	assert.Nil(t, nilLog.Record(&AuditRecord{Op: "join"}))
+
+	var buf bytes.Buffer
+	a := NewAuditLog(false, &buf)
+	assert.True(t, a.Enabled(false))
+	assert.False(t, a.Enabled(true))
+	assert.Nil(t, a.Record(&AuditRecord{Who: "alice", Op: "import", Status: 200}))
+	var rec AuditRecord
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &rec))
+	assert.Equal(t, "alice", rec.Who)
+	assert.Equal(t, 200, rec.Status)
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	f, err := NewRotatingFile(path, 10, 2)
+	assert.Nil(t, err)
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := f.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, f.Close())
+
+	read := func(p string) string {
+		b, _ := ioutil.ReadFile(p)
+		return string(b)
+	}
+	assert.Equal(t, "dddddddd\n", read(path))
+	assert.Equal(t, "cccccccc\n", read(path+".1"))
+	assert.Equal(t, "bbbbbbbb\n", read(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
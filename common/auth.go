@@ -0,0 +1,218 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Authentication of the HTTP API, disabled unless AuthBackends are set.
+var (
+	// AuthBackends authenticate the requests, tried in order: token:<file>
+	// for static bearer tokens, jwt:<jwks url> for JWTs signed by the keys
+	// of a JWKS, and mtls for client certificates.
+	AuthBackends []string
+	// JWTIssuer and JWTAudience, when set, are required in the iss and aud
+	// claims of the JWTs.
+	JWTIssuer   string
+	JWTAudience string
+	// HTTPTLSCert and HTTPTLSKey serve the HTTP API over TLS, in clear if
+	// not set.
+	HTTPTLSCert string
+	HTTPTLSKey  string
+	// HTTPTLSClientCA verifies the client certificates, when presented.
+	HTTPTLSClientCA string
+	// JoinToken is the bearer token of the joins of the coordinators, sent
+	// if set.
+	JoinToken string
+	// ManagementAddress, when set, serves the management endpoints of the
+	// HTTP API on a listener of their own instead of the client one, over
+	// TLS with ManagementTLSCert and ManagementTLSKey if set.
+	ManagementAddress     string
+	ManagementTLSCert     string
+	ManagementTLSKey      string
+	ManagementTLSClientCA string
+)
+
+// ErrUnauthenticated is returned for the requests without valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Identity is the authenticated author of a request.
+type Identity struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator authenticates the requests of the HTTP API.
+type Authenticator interface {
+	// Authenticate returns the identity of r, nil without error if r does
+	// not carry credentials of this authenticator.
+	Authenticate(r *http.Request) (*Identity, error)
+}
+
+// Authenticators tries its authenticators in order, and fails with
+// ErrUnauthenticated if none identifies the request.
+type Authenticators []Authenticator
+
+// Authenticate returns the identity from the first authenticator that
+// identifies r.
+func (a Authenticators) Authenticate(r *http.Request) (*Identity, error) {
+	for _, auth := range a {
+		id, err := auth.Authenticate(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnauthenticated, err)
+		}
+		if id != nil {
+			return id, nil
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+// NewAuthenticator returns the authenticators of specs, as AuthBackends, nil
+// if there is none.
+func NewAuthenticator(specs []string) (Authenticator, error) {
+	var auths Authenticators
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, ":", 2)
+		switch {
+		case kv[0] == "token" && len(kv) == 2:
+			auth, err := NewTokenAuth(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			auths = append(auths, auth)
+		case kv[0] == "jwt" && len(kv) == 2:
+			auths = append(auths, NewJWTAuth(kv[1], JWTIssuer, JWTAudience))
+		case spec == "mtls":
+			if HTTPTLSClientCA == "" && ManagementTLSClientCA == "" {
+				return nil, errors.New("a client CA is required to authenticate client certificates")
+			}
+			auths = append(auths, MTLSAuth{})
+		default:
+			return nil, fmt.Errorf("unknown authentication backend %q", spec)
+		}
+	}
+	if len(auths) == 0 {
+		return nil, nil
+	}
+	return auths, nil
+}
+
+type identityKey struct{}
+
+// WithIdentity returns ctx carrying id.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// IdentityFrom returns the identity carried by ctx, nil if none.
+func IdentityFrom(ctx context.Context) *Identity {
+	id, _ := ctx.Value(identityKey{}).(*Identity)
+	return id
+}
+
+// bearerToken returns the bearer token of r, empty if none.
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+}
+
+// TokenAuth authenticates static bearer tokens.
+type TokenAuth struct {
+	tokens map[string]*Identity
+}
+
+// NewTokenAuth returns the authenticator of the tokens of file, one per line
+// as: token name [group...]. Empty lines and lines starting with # are
+// skipped.
+func NewTokenAuth(file string) (*TokenAuth, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a := &TokenAuth{tokens: make(map[string]*Identity)}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected a token and a name", file, n)
+		}
+		a.tokens[fields[0]] = &Identity{Name: fields[1], Groups: fields[2:]}
+	}
+	return a, scanner.Err()
+}
+
+// Authenticate returns the identity of the bearer token of r, nil if r has
+// no token or an unknown one, possibly for another authenticator.
+func (a *TokenAuth) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	return a.tokens[token], nil
+}
+
+// MTLSAuth authenticates the verified client certificates: their common name
+// is the name of the identity, and their organizational units its groups.
+type MTLSAuth struct{}
+
+// Authenticate returns the identity of the client certificate of r, nil if
+// none was verified.
+func (MTLSAuth) Authenticate(r *http.Request) (*Identity, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, errors.New("client certificate without common name")
+	}
+	return &Identity{Name: cert.Subject.CommonName, Groups: cert.Subject.OrganizationalUnit}, nil
+}
+
+// HTTPTLSConfig returns the TLS configuration of the HTTP API, nil if it is
+// served in clear. Client certificates are verified when presented, if
+// HTTPTLSClientCA is set.
+func HTTPTLSConfig() (*tls.Config, error) {
+	return serverTLSConfig(HTTPTLSCert, HTTPTLSKey, HTTPTLSClientCA)
+}
+
+// ManagementTLSConfig is HTTPTLSConfig for the management listener.
+func ManagementTLSConfig() (*tls.Config, error) {
+	return serverTLSConfig(ManagementTLSCert, ManagementTLSKey, ManagementTLSClientCA)
+}
+
+func serverTLSConfig(certRef, keyRef, caRef string) (*tls.Config, error) {
+	if certRef == "" && keyRef == "" {
+		if caRef != "" {
+			return nil, errors.New("a TLS certificate and key are required with a client CA")
+		}
+		return nil, nil
+	}
+	source, err := newCertSource(certRef, keyRef)
+	if err != nil {
+		return nil, err
+	}
+	config := source.tlsConfig()
+	if caRef != "" {
+		pool, err := fetchCertPool(caRef)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/key/a", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestTokenAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "tokens")
+	assert.Nil(t, ioutil.WriteFile(file, []byte("# ops\ns3cret alice admins ops\n\nt0ken bob\n"), 0600))
+
+	auth, err := NewAuthenticator([]string{"token:" + file})
+	assert.Nil(t, err)
+	id, err := auth.Authenticate(bearer("s3cret"))
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{Name: "alice", Groups: []string{"admins", "ops"}}, id)
+	id, err = auth.Authenticate(bearer("t0ken"))
+	assert.Nil(t, err)
+	assert.Equal(t, "bob", id.Name)
+	_, err = auth.Authenticate(bearer("wrong"))
+	assert.Equal(t, ErrUnauthenticated, err)
+	_, err = auth.Authenticate(bearer(""))
+	assert.Equal(t, ErrUnauthenticated, err)
+
+	_, err = NewAuthenticator([]string{"ldap"})
+	assert.NotNil(t, err)
+	auth, err = NewAuthenticator(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, auth)
+}
+
+func TestJWTAuth(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "EC", "kid": "k1", "crv": "P-256", "x": b64(key.X.Bytes()), "y": b64(key.Y.Bytes())},
+		}})
+	}))
+	defer jwks.Close()
+	sign := func(kid string, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": "ES256", "kid": kid})
+		payload, _ := json.Marshal(claims)
+		signed := b64(header) + "." + b64(payload)
+		hash := sha256.Sum256([]byte(signed))
+		r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+		assert.Nil(t, err)
+		sig := make([]byte, 64)
+		rb, sb := r.Bytes(), s.Bytes()
+		copy(sig[32-len(rb):32], rb)
+		copy(sig[64-len(sb):], sb)
+		return signed + "." + b64(sig)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+
+	auth := Authenticators{NewJWTAuth(jwks.URL, "https://idp", "raftkv")}
+	id, err := auth.Authenticate(bearer(sign("k1", map[string]interface{}{
+		"sub": "alice", "iss": "https://idp", "aud": []string{"raftkv"}, "exp": exp, "groups": []string{"ops"}})))
+	assert.Nil(t, err)
+	assert.Equal(t, &Identity{Name: "alice", Groups: []string{"ops"}}, id)
+
+	for _, claims := range []map[string]interface{}{
+		{"sub": "alice", "iss": "https://idp", "aud": "raftkv", "exp": time.Now().Add(-time.Minute).Unix()},
+		{"sub": "alice", "iss": "https://other", "aud": "raftkv", "exp": exp},
+		{"sub": "alice", "iss": "https://idp", "aud": "other", "exp": exp},
+	} {
+		_, err = auth.Authenticate(bearer(sign("k1", claims)))
+		assert.True(t, errors.Is(err, ErrUnauthenticated), "%v", claims)
+	}
+	_, err = auth.Authenticate(bearer(sign("k2", map[string]interface{}{"sub": "alice", "exp": exp})))
+	assert.True(t, errors.Is(err, ErrUnauthenticated))
+	// a payload signed for another subject
+	token := strings.Split(sign("k1", map[string]interface{}{"sub": "alice", "iss": "https://idp", "aud": "raftkv", "exp": exp}), ".")
+	other := strings.Split(sign("k1", map[string]interface{}{"sub": "mallory", "iss": "https://idp", "aud": "raftkv", "exp": exp}), ".")
+	_, err = auth.Authenticate(bearer(token[0] + "." + other[1] + "." + token[2]))
+	assert.True(t, errors.Is(err, ErrUnauthenticated))
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// SnapshotBandwidth limits the bytes per second of the snapshots sent to
+// followers, shared by concurrent sends. Unlimited if 0.
+var SnapshotBandwidth int64
+
+// Bandwidth paces reads to a number of bytes per second, in bursts of up to
+// a second worth of bytes.
+type Bandwidth struct {
+	rate int64
+
+	mu     sync.Mutex
+	tokens int64
+	last   time.Time
+}
+
+// NewBandwidth returns a limit of rate bytes per second.
+func NewBandwidth(rate int64) *Bandwidth {
+	return &Bandwidth{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// wait blocks until n bytes can be transferred.
+func (b *Bandwidth) wait(n int) {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += int64(now.Sub(b.last).Seconds() * float64(b.rate))
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	b.tokens -= int64(n)
+	debt := b.tokens
+	b.mu.Unlock()
+	if debt < 0 {
+		time.Sleep(time.Duration(-debt) * time.Second / time.Duration(b.rate))
+	}
+}
+
+// Reader returns r read at the pace of b.
+func (b *Bandwidth) Reader(r io.ReadCloser) io.ReadCloser {
+	return &limitedReader{ReadCloser: r, bw: b}
+}
+
+type limitedReader struct {
+	io.ReadCloser
+	bw *Bandwidth
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// bound the burst of a single read
+	if len(p) > 32<<10 {
+		p = p[:32<<10]
+	}
+	n, err := l.ReadCloser.Read(p)
+	if n > 0 {
+		l.bw.wait(n)
+	}
+	return n, err
+}
+
+// throttledSnapshotStore limits the bandwidth of the snapshots it opens once
+// started. Raft opens snapshots to restore them at startup, before it is
+// started, and to send them to followers.
+type throttledSnapshotStore struct {
+	raft.SnapshotStore
+	bw      *Bandwidth
+	started int32
+}
+
+func (s *throttledSnapshotStore) start() {
+	atomic.StoreInt32(&s.started, 1)
+}
+
+func (s *throttledSnapshotStore) Open(id string) (*raft.SnapshotMeta, io.ReadCloser, error) {
+	meta, rc, err := s.SnapshotStore.Open(id)
+	if err == nil && atomic.LoadInt32(&s.started) == 1 {
+		rc = s.bw.Reader(rc)
+	}
+	return meta, rc, err
+}
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBandwidth(t *testing.T) {
+	data := make([]byte, 3000)
+	bw := NewBandwidth(10000)
+	start := time.Now()
+	b, err := ioutil.ReadAll(bw.Reader(ioutil.NopCloser(bytes.NewReader(data))))
+	assert.Nil(t, err)
+	assert.Equal(t, data, b)
+	// the first second worth of bytes is a burst
+	assert.True(t, time.Since(start) < 100*time.Millisecond)
+
+	start = time.Now()
+	ioutil.ReadAll(bw.Reader(ioutil.NopCloser(bytes.NewReader(make([]byte, 9000)))))
+	assert.True(t, time.Since(start) >= 150*time.Millisecond)
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/raft"
+)
+
+// Cluster is the id of the cluster of the node, set alike on all its nodes.
+// It names the raft groups bootstrapped, opens the raft connections and is
+// stamped on the HTTP responses, so that nodes and clients of other
+// clusters are refused. Nothing is checked if empty.
+var Cluster string
+
+// ClusterIDFile is the file of a raft directory holding the id of the
+// cluster of its raft group.
+const ClusterIDFile = "cluster-id"
+
+// ClusterID returns the cluster of the raft group whose state is kept in
+// dir, empty if the node has not bootstrapped or joined one yet.
+func ClusterID(dir string) (string, error) {
+	if InMemory {
+		return "", nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, ClusterIDFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return strings.TrimSpace(string(b)), err
+}
+
+// SetClusterID records that the state kept in dir is of the cluster id,
+// refusing another cluster than the one recorded: the state would corrupt
+// it.
+func SetClusterID(dir, id string) error {
+	if InMemory || id == "" {
+		return nil
+	}
+	stored, err := ClusterID(dir)
+	if err != nil {
+		return err
+	}
+	if stored == id {
+		return nil
+	}
+	if stored != "" {
+		return fmt.Errorf("%s has the state of cluster %s, not %s: wipe it to join this cluster", dir, stored, id)
+	}
+	path := filepath.Join(dir, ClusterIDFile)
+	if err := ioutil.WriteFile(path+".tmp", []byte(id+"\n"), 0600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// NewClusterID returns Cluster if set, a random cluster id otherwise.
+func NewClusterID() (string, error) {
+	if Cluster != "" {
+		return Cluster, nil
+	}
+	id, err := NewNodeUUID()
+	return strings.TrimPrefix(id, "node-"), err
+}
+
+// CheckJoin refuses the node id joining the raft group ra, of the cluster
+// clusterID, with the state of the cluster joining, empty if it has none.
+// State of another cluster would corrupt the group, and so would the state
+// of a node removed from it: the node recorded the cluster when joining it,
+// so a node knowing the cluster is not a member only once removed. Only the
+// leader checks, the join failing on the other nodes anyway.
+func CheckJoin(ra *raft.Raft, clusterID, id, joining string) error {
+	if clusterID == "" || joining == "" || ra.State() != raft.Leader {
+		return nil
+	}
+	if joining != clusterID {
+		return fmt.Errorf("node %s has the state of cluster %s, not %s: wipe its data directory to join it", id, joining, clusterID)
+	}
+	future := ra.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return err
+	}
+	for _, srv := range future.Configuration().Servers {
+		if srv.ID == raft.ServerID(id) {
+			return nil
+		}
+	}
+	return fmt.Errorf("node %s was removed from cluster %s: wipe its data directory to join it again", id, clusterID)
+}
+
+// JoinVerified reports whether the join of a node with the state of the
+// cluster joining, empty if none, proves its identity to the cluster
+// clusterID: the node is authenticated, by a join signed with ClusterSecret
+// or by its credentials, and has the state of the cluster once it has an id.
+func JoinVerified(clusterID, joining string, authenticated bool) bool {
+	return authenticated && joining == clusterID
+}
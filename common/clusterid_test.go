@@ -0,0 +1,46 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClusterID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cluster")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	id, err := ClusterID(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, "", id)
+
+	a, err := NewClusterID()
+	assert.Nil(t, err)
+	b, err := NewClusterID()
+	assert.Nil(t, err)
+	assert.NotEqual(t, a, b)
+
+	assert.Nil(t, SetClusterID(dir, a))
+	assert.Nil(t, SetClusterID(dir, a))
+	assert.Nil(t, SetClusterID(dir, ""))
+	id, err = ClusterID(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, a, id)
+
+	// the state of a cluster is never handed to another one
+	assert.NotNil(t, SetClusterID(dir, b))
+	id, err = ClusterID(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, a, id)
+}
+
+func TestJoinVerified(t *testing.T) {
+	assert.True(t, JoinVerified("c1", "c1", true))
+	assert.False(t, JoinVerified("c1", "c1", false))
+	// a node without the state of the cluster proves nothing
+	assert.False(t, JoinVerified("c1", "", true))
+	assert.True(t, JoinVerified("", "", true))
+}
@@ -4,7 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"hash/fnv"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/raft-kv-store/raftpb"
@@ -15,18 +18,60 @@ import (
 const maxTryOutMicroSeconds = 50000
 
 type Value struct {
+	// lastAccess and hits track the reads and writes of the key for the
+	// cache eviction, updated atomically.
+	lastAccess int64
+	hits       int64
+
 	k    string // For debug purpose
 	V    interface{}
+	Meta KeyMeta
 	mu   trylock.TryLocker
 	temp bool
 	txid string
+	// lockedAt is when txid locked the key, in nanoseconds.
+	lockedAt int64
+}
+
+// KeyMeta tracks the revisions at which a key was created and last modified,
+// and the number of writes since it was created.
+type KeyMeta struct {
+	CreateRevision int64
+	ModRevision    int64
+	Version        int64
+}
+
+// touch records a write at revision rev. Writes without a revision (rev 0)
+// are not tracked.
+func (v *Value) touch(rev int64) {
+	if rev == 0 {
+		return
+	}
+	if v.Meta.Version == 0 {
+		v.Meta.CreateRevision = rev
+	}
+	v.Meta.ModRevision = rev
+	v.Meta.Version++
+}
+
+// condHolds reports whether value satisfies cond. A condition on the mod
+// revision takes precedence over a condition on the value.
+func condHolds(cond *raftpb.Cond, value *Value) bool {
+	if cond == nil {
+		return true
+	}
+	if cond.ModRevision != 0 {
+		return value.Meta.ModRevision == cond.ModRevision
+	}
+	return value.V == cond.Value
 }
 
 func NewValue(k string, v interface{}) *Value {
 	return &Value{
-		k:  k,
-		V:  v,
-		mu: trylock.New(),
+		lastAccess: time.Now().UnixNano(),
+		k:          k,
+		V:          v,
+		mu:         trylock.New(),
 	}
 }
 
@@ -40,63 +85,203 @@ func TempNewValue(k string, v interface{}) *Value {
 }
 
 type Cmap struct {
+	// stateHash is the hash of the committed keys, see StateHash, first to
+	// be aligned for the atomic operations.
+	stateHash uint64
+
 	Map     map[string]*Value
 	mu      trylock.TryLocker
-	timeout time.Duration
+	timeout *LockTimeout
 	log     *log.Entry
+	// slow records long lock waits
+	slow *SlowLog
+	// stats counts the lock timeouts and waits
+	stats *LockStats
+	// node is the store node of the map, reported in lock errors
+	node string
+	// keyStats are the statistics of the committed keys
+	keyStats *KeyStats
+	// arena allocates the entries, one by one if nil
+	arena *Arena
+	// views are the open views, see View
+	viewMu sync.Mutex
+	views  []*CmapView
+	// shared is set once Map is shared with a view: the next write adding
+	// or removing a key copies it first
+	shared int32
 }
 
 func NewCmap(logger *log.Logger, t time.Duration) *Cmap {
 	l := logger.WithField("component", "cmap")
 	return &Cmap{
-		Map:     make(map[string]*Value),
-		mu:      trylock.New(),
-		timeout: t,
-		log:     l,
+		Map:      make(map[string]*Value),
+		mu:       trylock.New(),
+		timeout:  NewLockTimeout(t),
+		log:      l,
+		keyStats: NewKeyStats(),
+		arena:    NewArena(),
 	}
 }
 
 func NewCmapFromMap(logger *log.Logger, m map[string]interface{}, t time.Duration) *Cmap {
 	l := logger.WithField("component", "cmap")
 	res := &Cmap{
-		Map:     make(map[string]*Value),
-		mu:      trylock.New(),
-		timeout: t,
-		log:     l,
+		Map:      make(map[string]*Value),
+		mu:       trylock.New(),
+		timeout:  NewLockTimeout(t),
+		log:      l,
+		keyStats: NewKeyStats(),
+		arena:    NewArena(),
 	}
 	for k, v := range m {
-		res.Map[k] = NewValue(k, v)
+		res.Map[k] = res.arena.newValue(k, v, false)
+		res.keyStats.count(k, v, 1)
+		res.hashValue(res.Map[k])
 	}
 	return res
 }
 
-func (c *Cmap) Snapshot() map[string]interface{} {
-	res := make(map[string]interface{})
-	c.mu.RLock()
+// SetSlowLog records the lock waits over SlowLockThreshold in slow.
+func (c *Cmap) SetSlowLog(slow *SlowLog) {
+	c.slow = slow
+}
+
+// Arena returns the arena allocating the entries of c, nil if they are
+// allocated one by one.
+func (c *Cmap) Arena() *Arena {
+	return c.arena
+}
+
+// SetNode sets the store node reported in the lock errors of c.
+func (c *Cmap) SetNode(id string) {
+	c.node = id
+}
+
+// SetLockStats counts the lock timeouts and waits in stats.
+func (c *Cmap) SetLockStats(stats *LockStats) {
+	c.stats = stats
+}
+
+// errGlobalLock counts a timeout of the global lock and returns its error.
+func (c *Cmap) errGlobalLock() error {
+	c.stats.globalTimeout()
+	return errors.New("map is locked globally")
+}
+
+// errKeyLock counts a timeout of the lock of k, held in value, and returns
+// its error, with the map locked.
+func (c *Cmap) errKeyLock(k string, value *Value) error {
+	c.stats.keyTimeout()
+	return c.lockError(k, value)
+}
+
+// SnapshotPrefix returns the committed keys starting with prefix, with their
+// metadata, as of a single point in time. It fails on keys locked for longer
+// than timeout, such as the keys of a pending transaction.
+func (c *Cmap) SnapshotPrefix(prefix string, timeout time.Duration) ([]*raftpb.KVEntry, error) {
+	if global := c.mu.RTryLockTimeout(timeout); !global {
+		return nil, c.errGlobalLock()
+	}
 	defer c.mu.RUnlock()
+	var res []*raftpb.KVEntry
 	for k, v := range c.Map {
-		v.mu.RLock()
-		res[k] = v.V
+		// new keys of pending transactions are not committed yet
+		if v.temp || !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if local := v.mu.RTryLockTimeout(timeout); !local {
+			return nil, c.errKeyLock(k, v)
+		}
+		res = append(res, NewEntry(k, v.V, v.Meta))
 		v.mu.RUnlock()
 	}
+	return res, nil
+}
+
+// Usage returns the number of committed keys and value bytes per namespace.
+// The size of keys locked for longer than timeout is not counted.
+func (c *Cmap) Usage(timeout time.Duration) []*raftpb.NamespaceUsage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	usage := make(map[string]*raftpb.NamespaceUsage)
+	for k, v := range c.Map {
+		if v.temp {
+			continue
+		}
+		ns := Namespace(k)
+		u, ok := usage[ns]
+		if !ok {
+			u = &raftpb.NamespaceUsage{Namespace: ns}
+			usage[ns] = u
+		}
+		u.Keys++
+		if v.mu.RTryLockTimeout(timeout) {
+			u.Bytes += ValueSize(v.V)
+			v.mu.RUnlock()
+		}
+	}
+	res := make([]*raftpb.NamespaceUsage, 0, len(usage))
+	for _, u := range usage {
+		res = append(res, u)
+	}
+	return res
+}
+
+// SnapshotMeta returns a copy of the metadata of every key, see View.
+func (c *Cmap) SnapshotMeta() map[string]KeyMeta {
+	view := c.View()
+	defer view.Close()
+	_, meta := view.Read()
+	return meta
+}
+
+// RestoreMeta sets the metadata of the keys present in meta. It must only be
+// used while the map is not shared yet, as when restoring a snapshot.
+func (c *Cmap) RestoreMeta(meta map[string]KeyMeta) {
+	for k, m := range meta {
+		if v, ok := c.Map[k]; ok {
+			c.hashValue(v)
+			v.Meta = m
+			c.hashValue(v)
+		}
+	}
+}
+
+// Snapshot returns a copy of the value of every key, see View.
+func (c *Cmap) Snapshot() map[string]interface{} {
+	view := c.View()
+	defer view.Close()
+	res, _ := view.Read()
 	return res
 }
 
 func (c *Cmap) Get(k string) (val interface{}, ok bool, err error) {
-	if global := c.mu.RTryLockTimeout(c.timeout); !global {
-		return val, ok, errors.New("map is locked globally")
+	val, _, ok, err = c.GetWithMeta(k)
+	return val, ok, err
+}
+
+// GetWithMeta returns the value of k along with its metadata.
+func (c *Cmap) GetWithMeta(k string) (val interface{}, meta KeyMeta, ok bool, err error) {
+	start, timeout := time.Now(), c.timeout.Get()
+	if global := c.mu.RTryLockTimeout(timeout); !global {
+		c.timeout.Observe(timeout)
+		return val, meta, ok, c.errGlobalLock()
 	}
 	value, ok := c.Map[k]
 	if !ok {
 		c.mu.RUnlock() // unlock globally asap
-		return val, ok, nil
-	} else if local := value.mu.RTryLockTimeout(c.timeout); !local {
+		return val, meta, ok, nil
+	} else if local := value.mu.RTryLockTimeout(timeout); !local {
+		err := c.errKeyLock(k, value)
 		c.mu.RUnlock() // unlock globally asap
-		return val, ok, fmt.Errorf("map is locked on Key=%s", k)
+		c.timeout.Observe(time.Since(start))
+		return val, meta, ok, err
 	}
 	c.mu.RUnlock()
 	defer value.mu.RUnlock()
-	return value.V, ok, nil
+	c.timeout.Observe(time.Since(start))
+	value.accessed()
+	return value.V, value.Meta, ok, nil
 }
 
 // MGet is multiple get
@@ -105,7 +290,7 @@ func (c *Cmap) MGet(ops []*raftpb.Command, txid string) (map[string]interface{},
 	timeout := txTimeout(txid)
 	res := make(map[string]interface{})
 	if global := c.mu.RTryLockTimeout(timeout); !global {
-		return res, errors.New("map is locked globally")
+		return res, c.errGlobalLock()
 	}
 	defer c.mu.RUnlock()
 	for _, op := range ops {
@@ -116,7 +301,7 @@ func (c *Cmap) MGet(ops []*raftpb.Command, txid string) (map[string]interface{},
 		if !ok {
 			return nil, fmt.Errorf("Key=%s does not exist", op.Key)
 		} else if local := value.mu.RTryLockTimeout(timeout); !local {
-			return nil, fmt.Errorf("map is locked on Key=%s", op.Key)
+			return nil, c.errKeyLock(op.Key, value)
 		}
 		res[op.Key] = value.V
 		value.mu.RUnlock()
@@ -124,26 +309,131 @@ func (c *Cmap) MGet(ops []*raftpb.Command, txid string) (map[string]interface{},
 	return res, nil
 }
 
+// SnapshotGet returns the committed values of keys without waiting for the
+// transactions holding their locks, whose ids it returns. Keys created by a
+// transaction not committed yet do not exist.
+func (c *Cmap) SnapshotGet(keys []string, timeout time.Duration) (map[string]interface{}, []string, error) {
+	if global := c.mu.RTryLockTimeout(timeout); !global {
+		return nil, nil, c.errGlobalLock()
+	}
+	defer c.mu.RUnlock()
+	res := make(map[string]interface{}, len(keys))
+	var txids []string
+	for _, k := range keys {
+		value, ok := c.Map[k]
+		if !ok || value.temp {
+			return nil, nil, fmt.Errorf("Key=%s does not exist", k)
+		}
+		if value.txid != "" {
+			// the value only changes once the global lock is released
+			res[k] = value.V
+			txids = append(txids, value.txid)
+			continue
+		}
+		if local := value.mu.RTryLockTimeout(timeout); !local {
+			return nil, nil, c.errKeyLock(k, value)
+		}
+		res[k] = value.V
+		value.mu.RUnlock()
+	}
+	return res, txids, nil
+}
+
+// Peek returns the committed value of k with its metadata, like GetWithMeta,
+// but also reads the keys locked by transactions, which the leader locks
+// before proposing a compare transaction. Pending new keys do not exist.
+func (c *Cmap) Peek(k string, timeout time.Duration) (val interface{}, meta KeyMeta, ok bool, err error) {
+	if global := c.mu.RTryLockTimeout(timeout); !global {
+		return nil, meta, false, c.errGlobalLock()
+	}
+	defer c.mu.RUnlock()
+	value, ok := c.Map[k]
+	if !ok || value.temp {
+		return nil, meta, false, nil
+	}
+	if value.txid != "" {
+		// the value only changes once the global lock is released
+		return value.V, value.Meta, true, nil
+	}
+	if local := value.mu.RTryLockTimeout(timeout); !local {
+		return nil, meta, false, c.errKeyLock(k, value)
+	}
+	defer value.mu.RUnlock()
+	return value.V, value.Meta, true, nil
+}
+
+// Locks returns the keys locked by transactions, oldest lock first, to trace
+// lock errors back to the transaction holding the key.
+func (c *Cmap) Locks(timeout time.Duration) ([]*raftpb.KeyLock, error) {
+	if global := c.mu.RTryLockTimeout(timeout); !global {
+		return nil, c.errGlobalLock()
+	}
+	var locks []*raftpb.KeyLock
+	now := time.Now().UnixNano()
+	for k, value := range c.Map {
+		// txid is set and cleared under the global lock
+		if value.txid != "" {
+			locks = append(locks, &raftpb.KeyLock{Key: k, Txid: value.txid, Since: value.lockedAt, Age: now - value.lockedAt})
+		}
+	}
+	c.mu.RUnlock()
+	sort.Slice(locks, func(i, j int) bool {
+		if locks[i].Since != locks[j].Since {
+			return locks[i].Since < locks[j].Since
+		}
+		return locks[i].Key < locks[j].Key
+	})
+	return locks, nil
+}
+
 func (c *Cmap) benchmarkSet(k string, v, v0 interface{}, t time.Duration) error {
-	if global := c.mu.TryLockTimeout(c.timeout); !global {
-		return errors.New("map is locked globally")
+	var check func(*Value) bool
+	if v0 != nil {
+		check = func(value *Value) bool { return value.V == v0 }
+	}
+	return c.set(k, v, check, 0, t)
+}
+
+// set writes v at revision rev if check, when given, holds on the current value
+// of k. New keys are created without checking.
+func (c *Cmap) set(k string, v interface{}, check func(*Value) bool, rev int64, t time.Duration) error {
+	start, timeout := time.Now(), c.timeout.Get()
+	if global := c.mu.TryLockTimeout(timeout); !global {
+		c.timeout.Observe(timeout)
+		return c.errGlobalLock()
 	}
 	value, ok := c.Map[k]
 	if !ok {
-		c.Map[k] = NewValue(k, v)
+		value = c.arena.newValue(k, v, false)
+		value.touch(rev)
+		c.writable()[k] = value
+		c.keyStats.added(k, v)
+		c.hashValue(value)
 		c.mu.Unlock() // unlock globally asap
 		return nil
-	} else if local := value.mu.TryLockTimeout(c.timeout); !local {
+	} else if local := value.mu.TryLockTimeout(timeout); !local {
+		err := c.errKeyLock(k, value)
 		c.mu.Unlock() // unlock globally asap
-		return fmt.Errorf("map is locked on Key=%s", k)
+		c.timeout.Observe(time.Since(start))
+		return err
 	}
 	c.mu.Unlock()
 	defer value.mu.Unlock()
+	c.slow.Observe(SlowLockWait, k, "", start)
+	c.stats.observeWait(start)
+	c.timeout.Observe(time.Since(start))
 	time.Sleep(t)
-	if v0 != nil && value.V != v0 {
+	if check != nil && !check(value) {
 		return fmt.Errorf("condition not satisfied on Key=%s", k)
 	}
-	value.V = v
+	if !value.temp {
+		c.keyStats.replaced(k, value.V, v)
+	}
+	c.change(value, func() {
+		value.V = c.arena.value(v)
+		value.touch(rev)
+	})
+	value.accessed()
 	return nil
 }
 
@@ -155,19 +445,79 @@ func (c *Cmap) SetCond(k string, v, v0 interface{}) error {
 	return c.benchmarkSet(k, v, v0, 0)
 }
 
+// SetRev sets k to v at revision rev, provided cond holds. A condition on the
+// mod revision fails for keys that do not exist.
+func (c *Cmap) SetRev(k string, v interface{}, cond *raftpb.Cond, rev int64) error {
+	if cond != nil && cond.ModRevision != 0 {
+		if _, meta, ok, err := c.GetWithMeta(k); err != nil {
+			return err
+		} else if !ok || meta.ModRevision != cond.ModRevision {
+			return fmt.Errorf("condition not satisfied on Key=%s", k)
+		}
+	}
+	var check func(*Value) bool
+	if cond != nil {
+		check = func(value *Value) bool { return condHolds(cond, value) }
+	}
+	return c.set(k, v, check, rev, 0)
+}
+
+// SetIfAbsent sets k to v at revision rev if k does not exist, and reports
+// whether it did.
+func (c *Cmap) SetIfAbsent(k string, v interface{}, rev int64) (bool, error) {
+	timeout := c.timeout.Get()
+	if global := c.mu.TryLockTimeout(timeout); !global {
+		c.timeout.Observe(timeout)
+		return false, c.errGlobalLock()
+	}
+	value, ok := c.Map[k]
+	switch {
+	case ok && !value.temp:
+		c.mu.Unlock()
+		return false, nil
+	case !ok:
+		value = c.arena.newValue(k, v, false)
+		value.touch(rev)
+		c.writable()[k] = value
+		c.keyStats.added(k, v)
+		c.hashValue(value)
+		c.mu.Unlock()
+		return true, nil
+	}
+	c.mu.Unlock()
+	// pending new key of a transaction, only known to the leader: written
+	// like any set, so that all the replicas agree
+	if err := c.set(k, v, nil, rev, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (c *Cmap) Del(k string) error {
-	if global := c.mu.TryLockTimeout(c.timeout); !global {
-		return errors.New("map is locked globally")
+	start, timeout := time.Now(), c.timeout.Get()
+	if global := c.mu.TryLockTimeout(timeout); !global {
+		c.timeout.Observe(timeout)
+		return c.errGlobalLock()
 	}
 	value, ok := c.Map[k]
 	if !ok {
 		c.mu.Unlock() // unlock globally asap
 		return nil
-	} else if local := value.mu.TryLockTimeout(c.timeout); !local { // Not to del if the key is locked by other op
+	} else if local := value.mu.TryLockTimeout(timeout); !local { // Not to del if the key is locked by other op
+		err := c.errKeyLock(k, value)
 		c.mu.Unlock() // unlock globally asap
-		return fmt.Errorf("map is locked on Key=%s", k)
+		c.timeout.Observe(time.Since(start))
+		return err
 	}
-	delete(c.Map, k)
+	c.slow.Observe(SlowLockWait, k, "", start)
+	c.stats.observeWait(start)
+	c.timeout.Observe(time.Since(start))
+	delete(c.writable(), k)
+	c.arena.free()
+	if !value.temp {
+		c.keyStats.removed(k, value.V)
+	}
+	c.hashValue(value)
 	c.mu.Unlock()
 	return nil
 }
@@ -177,12 +527,14 @@ func (c *Cmap) TryLocks(ops []*raftpb.Command, txid string) error {
 	if len(ops) == 0 {
 		return errors.New("no key given")
 	}
+	start := time.Now()
 	if global := c.mu.TryLockTimeout(timeout); !global {
-		return errors.New("map is locked globally")
+		return c.errGlobalLock()
 	}
 	// locked is used to revert lock if any trylock fails
 	var locked []*Value
-	var revert, cond bool
+	var revert bool
+	var conflict *Conflict
 	// tmpMap is the local temp map for new value initialization
 	tmpMap := make(map[string]*Value)
 	for _, op := range ops {
@@ -191,19 +543,22 @@ func (c *Cmap) TryLocks(ops []*raftpb.Command, txid string) error {
 		if !ok {
 			// Handle new values
 			// Put temp flag to delete if abort
-			value = TempNewValue(k, nil)
+			value = c.arena.newValue(k, nil, true)
 			tmpMap[k] = value
 		}
 		// trylock on each value including new init
 		if local := value.mu.TryLockTimeout(timeout); !local {
 			revert = true
+			c.stats.keyTimeout()
+			// the holder is set under the global lock
+			conflict = newConflict(k, ConflictLocked, value.txid)
 			break
 		} else {
 			locked = append(locked, value)
 			// revert all locks if condition fails
-			if op.Method == SET && op.Cond != nil && op.Cond.Value != value.V {
+			if op.Method == SET && !condHolds(op.Cond, value) {
 				revert = true
-				cond = true
+				conflict = newConflict(k, ConflictCondition, "")
 				break
 			}
 		}
@@ -212,7 +567,15 @@ func (c *Cmap) TryLocks(ops []*raftpb.Command, txid string) error {
 	if len(tmpMap) > 0 && !revert {
 		for k, v := range tmpMap {
 			c.log.Infof("try lock for new key %s", k)
-			c.Map[k] = v
+			c.writable()[k] = v
+		}
+	}
+	//Assign txid if success, before readers of the txid see the keys
+	if !revert {
+		now := time.Now().UnixNano()
+		for _, value := range locked {
+			value.txid = txid
+			value.lockedAt = now
 		}
 	}
 	c.mu.Unlock()
@@ -221,19 +584,28 @@ func (c *Cmap) TryLocks(ops []*raftpb.Command, txid string) error {
 		for _, value := range locked {
 			value.mu.Unlock()
 		}
-		if cond {
-			return errors.New("set condition fails")
-		}
-		return errors.New("map is locked locally")
+		return conflict
 	}
-	//Assign txid if success
+	c.slow.Observe(SlowLockWait, ops[0].Key, txid, start)
+	c.stats.observeWait(start)
 	for _, value := range locked {
-		value.txid = txid
-		c.log.Infof("LOCKED for key %s in %s", value.k, value.txid)
+		c.log.Infof("LOCKED for key %s in %s", value.k, txid)
 	}
 	return nil
 }
 
+// HoldsLocks reports whether the keys of ops are all locked by txid.
+func (c *Cmap) HoldsLocks(ops []*raftpb.Command, txid string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, op := range ops {
+		if val, ok := c.Map[op.Key]; !ok || val.txid != txid {
+			return false
+		}
+	}
+	return len(ops) > 0
+}
+
 func (c *Cmap) WriteWithLocks(ops []*raftpb.Command) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -244,33 +616,72 @@ func (c *Cmap) WriteWithLocks(ops []*raftpb.Command) {
 			if !ok {
 				c.log.Fatalf("%s does not exist", op.Key)
 			}
-			val.V = op.Value
-			// unset temp flag for committed keys
-			val.temp = false
+			if val.temp {
+				c.keyStats.added(op.Key, CommandValue(op))
+			} else {
+				c.keyStats.replaced(op.Key, val.V, CommandValue(op))
+			}
+			c.change(val, func() {
+				val.V = c.arena.value(CommandValue(op))
+				// unset temp flag for committed keys
+				val.temp = false
+			})
+			c.stats.observeHold(val)
+			val.txid = ""
 			val.mu.Unlock()
 		case DEL:
-			delete(c.Map, op.Key)
+			c.delete(op.Key)
 		default:
 			c.log.Fatalf("Unknown op: %s", op.Method)
 		}
 	}
 }
 
-func (c *Cmap) Write(ops []*raftpb.Command) {
+// Write applies the committed ops of a transaction at revision rev.
+func (c *Cmap) Write(ops []*raftpb.Command, rev int64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for _, op := range ops {
 		switch op.Method {
 		case SET:
-			c.Map[op.Key] = NewValue(op.Key, op.Value)
+			value := c.arena.newValue(op.Key, CommandValue(op), false)
+			old, ok := c.Map[op.Key]
+			if ok {
+				value.Meta = old.Meta
+				value.hits = atomic.LoadInt64(&old.hits)
+				c.arena.free()
+			}
+			if ok && !old.temp {
+				c.keyStats.replaced(op.Key, old.V, value.V)
+			} else {
+				c.keyStats.added(op.Key, value.V)
+			}
+			value.touch(rev)
+			if ok {
+				c.hashValue(old)
+			}
+			c.writable()[op.Key] = value
+			c.hashValue(value)
 		case DEL:
-			delete(c.Map, op.Key)
+			c.delete(op.Key)
 		default:
 			c.log.Fatalf("Unknown op: %s", op.Method)
 		}
 	}
 }
 
+// delete deletes k, with the map locked.
+func (c *Cmap) delete(k string) {
+	if value, ok := c.Map[k]; ok {
+		delete(c.writable(), k)
+		c.arena.free()
+		if !value.temp {
+			c.keyStats.removed(k, value.V)
+		}
+		c.hashValue(value)
+	}
+}
+
 func (c *Cmap) AbortWithLocks(ops []*raftpb.Command, txid string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -285,9 +696,12 @@ func (c *Cmap) AbortWithLocks(ops []*raftpb.Command, txid string) {
 		}
 		if val.temp {
 			// delete key is temp when aborting
-			delete(c.Map, op.Key)
+			delete(c.writable(), op.Key)
+			c.arena.free()
 		}
 		//val.mu.TryLockTimeout(LongTimeOut)
+		c.stats.observeHold(val)
+		val.txid = ""
 		val.mu.Unlock()
 		c.log.Infof("txid %s UNLOCK when trying to abort %v", txid, ops)
 	}
@@ -0,0 +1,315 @@
+// Package cmap provides a concurrent map of string keys, whose locking gives
+// up after a timeout or when a context is done instead of blocking forever.
+//
+// It is the general purpose counterpart of common.Cmap, which adds the key
+// locks and revisions of the shard transactions.
+package cmap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/subchen/go-trylock/v2"
+)
+
+// ErrLocked is returned when the map could not be locked in time.
+var ErrLocked = errors.New("map is locked")
+
+// Map is a concurrent map of string keys. The zero value is not usable, see
+// New.
+type Map struct {
+	mu trylock.TryLocker
+	t  *table
+	// timeout bounds the lock waits of the methods without a context,
+	// unbounded if 0.
+	timeout time.Duration
+}
+
+// table holds the content of maps. Once shared by a clone or a snapshot, it is
+// never changed again: the next write to each map sharing it copies it first.
+type table struct {
+	m      map[string]interface{}
+	shared int32
+}
+
+// share marks t as shared, with the map locked.
+func (t *table) share() *table {
+	atomic.StoreInt32(&t.shared, 1)
+	return t
+}
+
+// New returns an empty map whose methods without a context wait for the lock
+// up to timeout, or as long as needed if timeout is 0.
+func New(timeout time.Duration) *Map {
+	return &Map{
+		mu:      trylock.New(),
+		t:       &table{m: make(map[string]interface{})},
+		timeout: timeout,
+	}
+}
+
+// FromMap returns a map holding a copy of m.
+func FromMap(m map[string]interface{}, timeout time.Duration) *Map {
+	res := New(timeout)
+	for k, v := range m {
+		res.t.m[k] = v
+	}
+	return res
+}
+
+// writable returns the content of the map to be changed, copied first if
+// shared, with the map write locked.
+func (m *Map) writable() map[string]interface{} {
+	if atomic.LoadInt32(&m.t.shared) == 1 {
+		c := make(map[string]interface{}, len(m.t.m))
+		for k, v := range m.t.m {
+			c[k] = v
+		}
+		m.t = &table{m: c}
+	}
+	return m.t.m
+}
+
+// context returns the context bounding the lock waits of the methods without
+// one.
+func (m *Map) context() (context.Context, context.CancelFunc) {
+	if m.timeout == 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), m.timeout)
+}
+
+func (m *Map) lock(ctx context.Context) error {
+	if !m.mu.TryLock(ctx) {
+		return fmt.Errorf("%w: %s", ErrLocked, ctx.Err())
+	}
+	return nil
+}
+
+func (m *Map) rlock(ctx context.Context) error {
+	if !m.mu.RTryLock(ctx) {
+		return fmt.Errorf("%w: %s", ErrLocked, ctx.Err())
+	}
+	return nil
+}
+
+// Get returns the value of k and whether it is present.
+func (m *Map) Get(k string) (interface{}, bool, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	return m.GetContext(ctx, k)
+}
+
+// GetContext is Get, waiting for the lock until ctx is done.
+func (m *Map) GetContext(ctx context.Context, k string) (interface{}, bool, error) {
+	if err := m.rlock(ctx); err != nil {
+		return nil, false, err
+	}
+	defer m.mu.RUnlock()
+	v, ok := m.t.m[k]
+	return v, ok, nil
+}
+
+// Set sets the value of k.
+func (m *Map) Set(k string, v interface{}) error {
+	ctx, cancel := m.context()
+	defer cancel()
+	return m.SetContext(ctx, k, v)
+}
+
+// SetContext is Set, waiting for the lock until ctx is done.
+func (m *Map) SetContext(ctx context.Context, k string, v interface{}) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+	m.writable()[k] = v
+	return nil
+}
+
+// SetIfAbsent sets the value of k if k is not present, and reports whether it
+// did.
+func (m *Map) SetIfAbsent(k string, v interface{}) (bool, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	return m.UpdateContext(ctx, k, func(_ interface{}, ok bool) (interface{}, bool) {
+		return v, !ok
+	})
+}
+
+// UpdateContext sets the value of k to the value returned by fn, given the
+// current value of k and whether it is present, unless fn returns false. It
+// reports whether the value was set. fn is called with the map locked and
+// must not use the map.
+func (m *Map) UpdateContext(ctx context.Context, k string, fn func(v interface{}, ok bool) (interface{}, bool)) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+	old, ok := m.t.m[k]
+	v, set := fn(old, ok)
+	if set {
+		m.writable()[k] = v
+	}
+	return set, nil
+}
+
+// Delete removes k, and reports whether it was present.
+func (m *Map) Delete(k string) (bool, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	return m.DeleteContext(ctx, k)
+}
+
+// DeleteContext is Delete, waiting for the lock until ctx is done.
+func (m *Map) DeleteContext(ctx context.Context, k string) (bool, error) {
+	if err := m.lock(ctx); err != nil {
+		return false, err
+	}
+	defer m.mu.Unlock()
+	_, ok := m.t.m[k]
+	if ok {
+		delete(m.writable(), k)
+	}
+	return ok, nil
+}
+
+// Len returns the number of keys, or -1 if the map could not be locked in
+// time.
+func (m *Map) Len() int {
+	ctx, cancel := m.context()
+	defer cancel()
+	if err := m.rlock(ctx); err != nil {
+		return -1
+	}
+	defer m.mu.RUnlock()
+	return len(m.t.m)
+}
+
+// Keys returns the keys, sorted.
+func (m *Map) Keys() ([]string, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	if err := m.rlock(ctx); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(m.t.m))
+	for k := range m.t.m {
+		keys = append(keys, k)
+	}
+	m.mu.RUnlock()
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Snapshot returns a copy of the content of the map.
+func (m *Map) Snapshot() (map[string]interface{}, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	if err := m.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.RUnlock()
+	res := make(map[string]interface{}, len(m.t.m))
+	for k, v := range m.t.m {
+		res[k] = v
+	}
+	return res, nil
+}
+
+// Clone returns a copy of the map, in constant time: the map and the copy
+// share their content until either is written, which copies it then.
+func (m *Map) Clone() (*Map, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	if err := m.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.RUnlock()
+	return &Map{mu: trylock.New(), t: m.t.share(), timeout: m.timeout}, nil
+}
+
+// SnapshotIter returns the content of the map at this point in time, in
+// constant time, as Clone, without a lock of its own.
+func (m *Map) SnapshotIter() (*View, error) {
+	ctx, cancel := m.context()
+	defer cancel()
+	if err := m.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer m.mu.RUnlock()
+	return &View{m: m.t.share().m}, nil
+}
+
+// View is the content of a map at a point in time, see SnapshotIter. It is
+// safe for concurrent use.
+type View struct {
+	m map[string]interface{}
+}
+
+// Get returns the value of k and whether it is present.
+func (v *View) Get(k string) (interface{}, bool) {
+	val, ok := v.m[k]
+	return val, ok
+}
+
+// Len returns the number of keys.
+func (v *View) Len() int {
+	return len(v.m)
+}
+
+// Range calls fn for every key and value, in no particular order, until fn
+// returns false.
+func (v *View) Range(fn func(k string, v interface{}) bool) {
+	for k, val := range v.m {
+		if !fn(k, val) {
+			return
+		}
+	}
+}
+
+// Range calls fn for every key and value of a snapshot of the map, by key,
+// until fn returns false. fn may use the map: the changes made after the
+// snapshot are not seen.
+func (m *Map) Range(fn func(k string, v interface{}) bool) error {
+	view, err := m.SnapshotIter()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, view.Len())
+	view.Range(func(k string, _ interface{}) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn(k, view.m[k]) {
+			break
+		}
+	}
+	return nil
+}
+
+// ForEach calls fn for every key and value, in no particular order, with the
+// map read locked, so without a copy but blocking the writes meanwhile. It
+// stops at the first error of fn, returned, or once ctx is done, returning
+// ctx.Err(). fn must not change the map.
+func (m *Map) ForEach(ctx context.Context, fn func(k string, v interface{}) error) error {
+	if err := m.rlock(ctx); err != nil {
+		return err
+	}
+	defer m.mu.RUnlock()
+	for k, v := range m.t.m {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package cmap
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	m := FromMap(map[string]interface{}{"a": 1}, time.Second)
+	v, ok, err := m.Get("a")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	_, ok, err = m.Get("b")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	assert.Nil(t, m.Set("b", 2))
+	set, err := m.SetIfAbsent("b", 3)
+	assert.Nil(t, err)
+	assert.False(t, set)
+	set, err = m.SetIfAbsent("c", 3)
+	assert.Nil(t, err)
+	assert.True(t, set)
+	assert.Equal(t, 3, m.Len())
+
+	keys, err := m.Keys()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+
+	deleted, err := m.Delete("a")
+	assert.Nil(t, err)
+	assert.True(t, deleted)
+	deleted, err = m.Delete("a")
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+
+	snapshot, err := m.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"b": 2, "c": 3}, snapshot)
+}
+
+func TestMapRange(t *testing.T) {
+	m := New(time.Second)
+	for i := 0; i < 5; i++ {
+		m.Set(strconv.Itoa(i), i)
+	}
+	var seen []string
+	// the map may be changed while ranging, unseen
+	assert.Nil(t, m.Range(func(k string, v interface{}) bool {
+		seen = append(seen, k)
+		assert.Nil(t, m.Set("new"+k, v))
+		return k != "2"
+	}))
+	assert.Equal(t, []string{"0", "1", "2"}, seen)
+	assert.Equal(t, 8, m.Len())
+
+	sum := 0
+	assert.Nil(t, m.ForEach(context.Background(), func(k string, v interface{}) error {
+		sum += v.(int)
+		return nil
+	}))
+	assert.Equal(t, 10+0+1+2, sum)
+
+	stop := errors.New("stop")
+	calls := 0
+	assert.Equal(t, stop, m.ForEach(context.Background(), func(string, interface{}) error {
+		calls++
+		return stop
+	}))
+	assert.Equal(t, 1, calls)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls = 0
+	assert.Equal(t, context.Canceled, m.ForEach(ctx, func(string, interface{}) error {
+		calls++
+		cancel()
+		return nil
+	}))
+	assert.Equal(t, 1, calls)
+}
+
+func TestMapLocking(t *testing.T) {
+	m := New(10 * time.Millisecond)
+	m.Set("a", 1)
+
+	// a writer waits for ForEach, up to its timeout
+	release := make(chan struct{})
+	ranging := make(chan struct{})
+	go m.ForEach(context.Background(), func(string, interface{}) error {
+		close(ranging)
+		<-release
+		return nil
+	})
+	<-ranging
+	err := m.Set("a", 2)
+	assert.True(t, errors.Is(err, ErrLocked), err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = m.DeleteContext(ctx, "a")
+	assert.True(t, errors.Is(err, ErrLocked), err)
+	// readers are not blocked
+	v, _, err := m.Get("a")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, v)
+
+	done := make(chan error)
+	go func() { done <- m.SetContext(context.Background(), "a", 3) }()
+	close(release)
+	assert.Nil(t, <-done)
+
+	// a read waits for UpdateContext
+	updating := make(chan struct{})
+	release = make(chan struct{})
+	go m.UpdateContext(context.Background(), "a", func(v interface{}, ok bool) (interface{}, bool) {
+		close(updating)
+		<-release
+		return v.(int) + 1, true
+	})
+	<-updating
+	_, _, err = m.Get("a")
+	assert.True(t, errors.Is(err, ErrLocked), err)
+	assert.Equal(t, -1, m.Len())
+	_, err = m.Keys()
+	assert.NotNil(t, err)
+	close(release)
+	v, _, err = m.GetContext(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, v)
+}
+
+// TestMapConcurrent is meant to be run with the race detector.
+func TestMapConcurrent(t *testing.T) {
+	m := New(0)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				k := strconv.Itoa(i % 20)
+				switch (w + i) % 6 {
+				case 0:
+					m.Set(k, i)
+				case 1:
+					m.Get(k)
+				case 2:
+					m.Delete(k)
+				case 3:
+					m.UpdateContext(context.Background(), "counter", func(v interface{}, ok bool) (interface{}, bool) {
+						if !ok {
+							return 1, true
+						}
+						return v.(int) + 1, true
+					})
+				case 4:
+					m.Range(func(k string, v interface{}) bool {
+						m.Get(k)
+						return true
+					})
+				case 5:
+					m.ForEach(context.Background(), func(string, interface{}) error { return nil })
+					m.Keys()
+					m.Len()
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// every update was counted
+	v, ok, err := m.Get("counter")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	updates := 0
+	for w := 0; w < 8; w++ {
+		for i := 0; i < 200; i++ {
+			if (w+i)%6 == 3 {
+				updates++
+			}
+		}
+	}
+	assert.Equal(t, updates, v)
+}
+
+func TestMapClone(t *testing.T) {
+	m := FromMap(map[string]interface{}{"a": 1, "b": 2}, time.Second)
+	clone, err := m.Clone()
+	assert.Nil(t, err)
+	view, err := m.SnapshotIter()
+	assert.Nil(t, err)
+
+	// the writes to either map are not seen by the others
+	assert.Nil(t, m.Set("a", 10))
+	m.Delete("b")
+	assert.Nil(t, clone.Set("c", 3))
+
+	v, _, _ := clone.Get("a")
+	assert.Equal(t, 1, v)
+	_, ok, _ := clone.Get("b")
+	assert.True(t, ok)
+	_, ok, _ = m.Get("c")
+	assert.False(t, ok)
+	snapshot, _ := m.Snapshot()
+	assert.Equal(t, map[string]interface{}{"a": 10}, snapshot)
+
+	assert.Equal(t, 2, view.Len())
+	v, ok = view.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	seen := map[string]interface{}{}
+	view.Range(func(k string, v interface{}) bool {
+		seen[k] = v
+		return true
+	})
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": 2}, seen)
+}
+
+// TestMapCloneConcurrent is meant to be run with the race detector.
+func TestMapCloneConcurrent(t *testing.T) {
+	m := New(0)
+	for i := 0; i < 100; i++ {
+		m.Set(strconv.Itoa(i), i)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				m.Set(strconv.Itoa(i), -i)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				clone, _ := m.Clone()
+				clone.Set("clone", i)
+				view, _ := m.SnapshotIter()
+				n := 0
+				view.Range(func(string, interface{}) bool {
+					n++
+					return true
+				})
+				assert.Equal(t, 100, n)
+			}
+		}()
+	}
+	wg.Wait()
+	_, ok, _ := m.Get("clone")
+	assert.False(t, ok)
+}
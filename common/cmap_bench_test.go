@@ -114,3 +114,15 @@ func BenchmarkNaiveMapMultiGetSetBlock(b *testing.B) {
 		<-finished
 	}
 }
+
+// BenchmarkCmapView opens and closes views of a large map, in constant time.
+func BenchmarkCmapView(b *testing.B) {
+	m := NewCmap(log.New(), 0)
+	for i := 0; i < 100000; i++ {
+		m.Set(strconv.Itoa(i), "value")
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.View().Close()
+	}
+}
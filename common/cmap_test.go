@@ -1,12 +1,12 @@
 package common
 
 import (
-	"errors"
-	"fmt"
 	"github.com/raft-kv-store/raftpb"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestCmap_TryLocks(t *testing.T) {
@@ -32,7 +32,7 @@ func TestCmap_TryLocks(t *testing.T) {
 	}
 	for k, expected := range map[string]interface{}{"c": nil, "d": nil} {
 		_, ok, err := m1.Get(k)
-		expectedErr := fmt.Errorf("map is locked on Key=%s", k)
+		expectedErr := &LockError{Key: k, RetryAfter: ConflictBackoff}
 		assert.Truef(t, err.Error() == expectedErr.Error(), "Expected %s, but got %s for key %s", expectedErr.Error(), err.Error(), k)
 		assert.Truef(t, ok, "Value should exist for key %s", k)
 		m1.Map[k].mu.Unlock()
@@ -63,7 +63,7 @@ func TestCmap_TryLocks(t *testing.T) {
 	}
 	for k, expected := range map[string]interface{}{"b": int64(2)} {
 		actual, ok, err := m2.Get(k)
-		expectedErr := fmt.Errorf("map is locked on Key=%s", k)
+		expectedErr := &LockError{Key: k, RetryAfter: ConflictBackoff}
 		assert.Truef(t, err.Error() == expectedErr.Error(), "Expected %s, but got %s for key %s", expectedErr.Error(), err.Error(), k)
 		assert.Truef(t, ok, "Value should exist for key %s", k)
 		m2.Map[k].mu.Unlock()
@@ -72,7 +72,7 @@ func TestCmap_TryLocks(t *testing.T) {
 	}
 	for k, expected := range map[string]interface{}{"c": nil, "d": nil} {
 		_, ok, err := m2.Get(k)
-		expectedErr := fmt.Errorf("map is locked on Key=%s", k)
+		expectedErr := &LockError{Key: k, RetryAfter: ConflictBackoff}
 		assert.Truef(t, err.Error() == expectedErr.Error(), "Expected %s, but got %s for key %s", expectedErr.Error(), err.Error(), k)
 		assert.Truef(t, ok, "Value should exist for key %s", k)
 		m2.Map[k].mu.Unlock()
@@ -102,7 +102,7 @@ func TestCmap_TryLocks(t *testing.T) {
 
 	for k, expected := range map[string]interface{}{"a": 1, "d": 5} {
 		actual, ok, err := m3.Get(k)
-		expectedErr := fmt.Errorf("map is locked on Key=%s", k)
+		expectedErr := &LockError{Key: k, RetryAfter: ConflictBackoff}
 		assert.Truef(t, err.Error() == expectedErr.Error(), "Expected %s, but got %s for key %s", expectedErr.Error(), err.Error(), k)
 		assert.Truef(t, ok, "Value should exist for key %s", k)
 		m3.Map[k].mu.Unlock()
@@ -144,7 +144,7 @@ func TestCmap_TryLocks(t *testing.T) {
 
 	for k, expected := range map[string]interface{}{"a": 1, "d": 5} {
 		actual, ok, err := m4.Get(k)
-		expectedErr := fmt.Errorf("map is locked on Key=%s", k)
+		expectedErr := &LockError{Key: k, RetryAfter: ConflictBackoff}
 		assert.Truef(t, err.Error() == expectedErr.Error(), "Expected %s, but got %s for key %s", expectedErr.Error(), err.Error(), k)
 		assert.Truef(t, ok, "Value should exist for key %s", k)
 		m4.Map[k].mu.Unlock()
@@ -177,7 +177,7 @@ func TestCmap_TryLocks(t *testing.T) {
 
 	assert.True(t, m5.mu.TryLockTimeout(0), "Cmap should not be globally locked")
 	m5.mu.Unlock()
-	expectErr := errors.New("set condition fails")
+	expectErr := &Conflict{Key: "a", Reason: ConflictCondition}
 	assert.Truef(t, err5 != nil, "should return err %s", expectErr.Error())
 	assert.Truef(t, err5.Error() == expectErr.Error(), "expected err %s, but got %s", expectErr.Error(), err5.Error())
 
@@ -207,6 +207,66 @@ func TestCmap_WriteWithLocks(t *testing.T) {
 	}
 }
 
+func TestCmap_HoldsLocks(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	op1 := []*raftpb.Command{
+		{Method: SET, Key: "a", Value: 3},
+		{Method: DEL, Key: "b"},
+	}
+	assert.False(t, m1.HoldsLocks(op1, "tx1"))
+	assert.Nil(t, m1.TryLocks(op1, "tx1"))
+	assert.True(t, m1.HoldsLocks(op1, "tx1"))
+	assert.False(t, m1.HoldsLocks(op1, "tx2"))
+
+	m1.AbortWithLocks(op1, "tx1")
+	assert.False(t, m1.HoldsLocks(op1, "tx1"))
+	assert.Nil(t, m1.TryLocks(op1, "tx1"))
+	m1.Write(op1, 1)
+	assert.False(t, m1.HoldsLocks(op1, "tx1"))
+}
+
+func TestCmap_SnapshotGet(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	op1 := []*raftpb.Command{{Method: SET, Key: "a", Value: 3}}
+	assert.Nil(t, m1.TryLocks(op1, "tx1"))
+	m1.WriteWithLocks(op1)
+
+	op2 := []*raftpb.Command{
+		{Method: SET, Key: "a", Value: 4},
+		{Method: SET, Key: "b", Value: 5},
+	}
+	assert.Nil(t, m1.TryLocks(op2, "tx2"))
+	_, _, err := m1.SnapshotGet([]string{"a", "b"}, 10*time.Millisecond)
+	assert.NotNil(t, err)
+	res, txids, err := m1.SnapshotGet([]string{"a"}, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), res["a"])
+	assert.Equal(t, []string{"tx2"}, txids)
+
+	m1.WriteWithLocks(op2)
+	res, txids, err = m1.SnapshotGet([]string{"a", "b"}, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"a": int64(4), "b": int64(5)}, res)
+	assert.Empty(t, txids)
+}
+
+func TestCmap_SetIfAbsent(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	ok, err := m1.SetIfAbsent("a", int64(1), 1)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = m1.SetIfAbsent("a", int64(2), 2)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	v, _, _ := m1.Get("a")
+	assert.Equal(t, int64(1), v)
+
+	assert.Nil(t, m1.Del("a"))
+	ok, err = m1.SetIfAbsent("a", int64(3), 3)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
+
 func TestCmap_MGet(t *testing.T) {
 	m1 := NewCmap(log.New(), 0)
 	op1 := []*raftpb.Command{
@@ -238,3 +298,143 @@ func TestCmap_SET(t *testing.T) {
 		assert.Equalf(t, expected, actual, "Expected %d, but got %d for key %s", expected, actual, k)
 	}
 }
+
+func TestCmap_Snapshot(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m1.Set("a", 1)
+	assert.Equal(t, map[string]interface{}{"a": 1}, m1.Snapshot())
+	// the map is not left read locked
+	assert.True(t, m1.mu.TryLockTimeout(0), "Cmap should not be globally locked")
+	m1.mu.Unlock()
+}
+
+func TestCmap_SnapshotLockedKey(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m1.Set("a", 1)
+	ops := []*raftpb.Command{{Method: SET, Key: "a", Value: 2}}
+	assert.Nil(t, m1.TryLocks(ops, "tx1"))
+	// read through a view, without waiting for the transaction
+	assert.Equal(t, map[string]interface{}{"a": 1}, m1.Snapshot())
+	assert.Equal(t, int64(0), m1.SnapshotMeta()["a"].Version)
+	m1.WriteWithLocks(ops)
+	assert.Equal(t, map[string]interface{}{"a": int64(2)}, m1.Snapshot())
+	assert.Empty(t, m1.views, "the views are closed")
+}
+
+func TestCmap_Meta(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	assert.Nil(t, m1.SetRev("a", int64(1), nil, 10))
+	assert.Nil(t, m1.SetRev("a", int64(2), nil, 12))
+	_, meta, ok, err := m1.GetWithMeta("a")
+	assert.True(t, ok && err == nil)
+	assert.Equal(t, KeyMeta{CreateRevision: 10, ModRevision: 12, Version: 2}, meta)
+
+	// optimistic concurrency on the mod revision
+	assert.NotNil(t, m1.SetRev("a", int64(3), &raftpb.Cond{Key: "a", ModRevision: 10}, 13))
+	assert.Nil(t, m1.SetRev("a", int64(3), &raftpb.Cond{Key: "a", ModRevision: 12}, 13))
+	assert.NotNil(t, m1.SetRev("b", int64(3), &raftpb.Cond{Key: "b", ModRevision: 12}, 14))
+
+	// transactions keep the metadata of the keys they overwrite
+	m1.Write([]*raftpb.Command{{Method: SET, Key: "a", Value: 4}}, 15)
+	_, meta, _, _ = m1.GetWithMeta("a")
+	assert.Equal(t, KeyMeta{CreateRevision: 10, ModRevision: 15, Version: 4}, meta)
+}
+
+func TestCmap_Locks(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m1.Set("a", int64(1))
+	assert.Nil(t, m1.TryLocks([]*raftpb.Command{{Method: SET, Key: "a", Value: 2}}, "tx1"))
+	assert.Nil(t, m1.TryLocks([]*raftpb.Command{{Method: SET, Key: "b", Value: 3}}, "tx2"))
+
+	locks, err := m1.Locks(10 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Len(t, locks, 2)
+	assert.Equal(t, "a", locks[0].Key)
+	assert.Equal(t, "tx1", locks[0].Txid)
+	assert.Equal(t, "b", locks[1].Key)
+	assert.Equal(t, "tx2", locks[1].Txid)
+	assert.True(t, locks[0].Age >= locks[1].Age)
+
+	m1.AbortWithLocks([]*raftpb.Command{{Method: SET, Key: "a"}}, "tx1")
+	locks, err = m1.Locks(10 * time.Millisecond)
+	assert.Nil(t, err)
+	assert.Len(t, locks, 1)
+	assert.Equal(t, "b", locks[0].Key)
+}
+
+func TestCmap_View(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m1.SetRev("a", int64(1), nil, 1)
+	m1.SetRev("b", int64(2), nil, 2)
+	m1.SetRev("c", int64(3), nil, 3)
+
+	view := m1.View()
+	defer view.Close()
+	// changed in place, replaced, deleted and created after the view
+	m1.SetRev("a", int64(10), nil, 4)
+	m1.Write([]*raftpb.Command{{Method: SET, Key: "b", Value: 20}}, 5)
+	m1.Del("c")
+	m1.SetRev("d", int64(4), nil, 6)
+
+	values, meta := view.Read()
+	assert.Equal(t, map[string]interface{}{"a": int64(1), "b": int64(2), "c": int64(3)}, values)
+	assert.Equal(t, int64(1), meta["a"].ModRevision)
+	assert.Equal(t, int64(1), meta["a"].Version)
+
+	v, _, _ := m1.Get("a")
+	assert.Equal(t, int64(10), v)
+	view.Close()
+	m1.SetRev("b", int64(30), nil, 7)
+	assert.Len(t, m1.views, 0)
+}
+
+func TestCmap_ViewCopyOnWrite(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m1.SetRev("a", int64(1), nil, 1)
+	m1.SetRev("b", int64(2), nil, 2)
+
+	// the views share the keys of the map until a key is added or removed
+	view := m1.View()
+	defer view.Close()
+	other := m1.View()
+	defer other.Close()
+	assert.Equal(t, reflect.ValueOf(m1.Map).Pointer(), reflect.ValueOf(view.values).Pointer())
+	assert.Equal(t, reflect.ValueOf(m1.Map).Pointer(), reflect.ValueOf(other.values).Pointer())
+	m1.SetRev("a", int64(10), nil, 3)
+	assert.Equal(t, reflect.ValueOf(m1.Map).Pointer(), reflect.ValueOf(view.values).Pointer(), "changed in place")
+
+	m1.SetRev("c", int64(3), nil, 4)
+	assert.NotEqual(t, reflect.ValueOf(m1.Map).Pointer(), reflect.ValueOf(view.values).Pointer())
+	copied := reflect.ValueOf(m1.Map).Pointer()
+	m1.Del("b")
+	assert.Equal(t, copied, reflect.ValueOf(m1.Map).Pointer(), "copied once")
+
+	values, _ := view.Read()
+	assert.Equal(t, map[string]interface{}{"a": int64(1), "b": int64(2)}, values)
+	values, _ = other.Read()
+	assert.Equal(t, map[string]interface{}{"a": int64(1), "b": int64(2)}, values)
+	values, _ = m1.View().Read()
+	assert.Equal(t, map[string]interface{}{"a": int64(10), "c": int64(3)}, values)
+}
+
+func TestCmap_ViewPreparedTransaction(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m1.SetRev("a", int64(1), nil, 1)
+	ops := []*raftpb.Command{{Method: SET, Key: "a", Value: 2}, {Method: SET, Key: "b", Value: 3}}
+	assert.Nil(t, m1.TryLocks(ops, "tx1"))
+
+	// snapshotted while prepared, and committed before the view is read
+	view := m1.View()
+	defer view.Close()
+	hash := m1.StateHash()
+	m1.WriteWithLocks(ops)
+
+	values, meta := view.Read()
+	assert.Equal(t, map[string]interface{}{"a": int64(1)}, values)
+	assert.Equal(t, hash, StateHashOf(values, meta))
+
+	// restored as a follower installing the snapshot
+	restored := NewCmapFromMap(log.New(), values, 0)
+	restored.RestoreMeta(meta)
+	assert.Equal(t, hash, restored.StateHash())
+}
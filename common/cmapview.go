@@ -0,0 +1,100 @@
+package common
+
+import "sync/atomic"
+
+// CmapView is the content of a Cmap at a point in time, read while the map
+// keeps changing, as when a snapshot is persisted. Every copy of the map is
+// read through one. Opening it takes constant time: the view shares the keys
+// of the map, which the next write adding or removing a key copies first. The
+// values changed in place afterwards are saved first, the values replaced or
+// deleted are kept by the view. The new keys of pending transactions are not
+// committed, so not in the view.
+type CmapView struct {
+	c      *Cmap
+	values map[string]*Value
+	// saved are the contents of the values changed in place since the view
+	// was opened, guarded by c.viewMu
+	saved map[*Value]savedValue
+}
+
+type savedValue struct {
+	v    interface{}
+	meta KeyMeta
+	temp bool
+}
+
+// View opens a view of the map, to be closed once read.
+func (c *Cmap) View() *CmapView {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.viewMu.Lock()
+	defer c.viewMu.Unlock()
+	atomic.StoreInt32(&c.shared, 1)
+	view := &CmapView{c: c, values: c.Map, saved: make(map[*Value]savedValue)}
+	c.views = append(c.views, view)
+	return view
+}
+
+// writable returns Map to be changed, with the map locked, copied first if
+// shared with a view.
+func (c *Cmap) writable() map[string]*Value {
+	if atomic.LoadInt32(&c.shared) == 1 {
+		m := make(map[string]*Value, len(c.Map))
+		for k, v := range c.Map {
+			m[k] = v
+		}
+		c.Map = m
+		atomic.StoreInt32(&c.shared, 0)
+	}
+	return c.Map
+}
+
+// change runs fn, which changes value in place, after saving the content of
+// value for the open views holding it, and updates the state hash.
+func (c *Cmap) change(value *Value, fn func()) {
+	c.viewMu.Lock()
+	defer c.viewMu.Unlock()
+	for _, view := range c.views {
+		if view.values[value.k] != value {
+			continue
+		}
+		if _, ok := view.saved[value]; !ok {
+			view.saved[value] = savedValue{v: value.V, meta: value.Meta, temp: value.temp}
+		}
+	}
+	c.hashValue(value)
+	fn()
+	c.hashValue(value)
+}
+
+// Read returns the values and the metadata of the keys as of the opening of
+// the view.
+func (v *CmapView) Read() (map[string]interface{}, map[string]KeyMeta) {
+	res := make(map[string]interface{}, len(v.values))
+	meta := make(map[string]KeyMeta, len(v.values))
+	for k, value := range v.values {
+		v.c.viewMu.Lock()
+		s, ok := v.saved[value]
+		if !ok {
+			s = savedValue{v: value.V, meta: value.Meta, temp: value.temp}
+		}
+		v.c.viewMu.Unlock()
+		if !s.temp {
+			res[k], meta[k] = s.v, s.meta
+		}
+	}
+	return res, meta
+}
+
+// Close stops saving the values changed for the view. It can be called more
+// than once.
+func (v *CmapView) Close() {
+	v.c.viewMu.Lock()
+	defer v.c.viewMu.Unlock()
+	for i, view := range v.c.views {
+		if view == v {
+			v.c.views = append(v.c.views[:i], v.c.views[i+1:]...)
+			return
+		}
+	}
+}
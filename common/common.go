@@ -2,6 +2,7 @@ package common
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"math/rand"
 	"net"
@@ -9,23 +10,44 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/hashicorp/raft"
 	raftboltdb "github.com/hashicorp/raft-boltdb"
 )
 
 const (
-	GET      = "get"
-	SET      = "set"
-	DEL      = "del"
-	LEADER   = "leader"
-	EXIT     = "exit"
-	TXN      = "txn"
-	ADD      = "add"
-	SUB      = "sub"
-	ENDTXN   = "end"
-	TRANSFER = "xfer"
+	GET       = "get"
+	SET       = "set"
+	DEL       = "del"
+	LEADER    = "leader"
+	EXIT      = "exit"
+	TXN       = "txn"
+	ADD       = "add"
+	SUB       = "sub"
+	ENDTXN    = "end"
+	TRANSFER  = "xfer"
+	HISTORY   = "history"
+	REPLICAS  = "replicas"
+	OPEN      = "open"
+	CLOSE     = "close"
+	EXPIRE    = "expire"
+	BATCH     = "batch"
+	EVICT     = "evict"
+	GETSET    = "getset"
+	GETDEL    = "getdel"
+	SETNX     = "setnx"
+	COMPARE   = "compare"
+	POLICY    = "policy"
+	HASH      = "hash"
+	MERKLE    = "merkle"
+	BULK      = "bulk"
+	TOPOLOGY  = "topology"
+	TRANSFORM = "transform"
+	TTL       = "ttl"
+	UNDELETE  = "undelete"
 
 	Prepare = "Prepare"
 	Commit  = "Commit"
@@ -37,20 +59,55 @@ const (
 	NotPrepared = "NotPrepared"
 	Invalid     = "Invalid"
 	Abort       = "Abort"
+	// OnePhase commits a transaction on its only shard without preparing it.
+	OnePhase = "OnePhase"
 
 	RetainSnapshotCount = 2
 	RaftTimeout         = 10 * time.Second
 	NodeIDLen           = 5
 
 	MagicDiff      = 20000
-	RaftPVBaseDir  = "/pv"
 	LockContention = 1 * time.Microsecond // This can be change to test concurrentMap performance
 
 )
 
 var (
+	// InMemory keeps the raft logs and snapshots in memory, for development
+	// clusters whose state is lost on exit.
+	InMemory bool
+	// RaftPVBaseDir is the directory holding the raft directories of the
+	// nodes.
+	RaftPVBaseDir = "/pv"
+	// RaftLogPreallocMB is the size in MB the raft log file is mapped at when
+	// opened, so that it grows up to that size without being remapped while
+	// appending, which blocks the writes. Not preallocated if 0.
+	RaftLogPreallocMB int
+	// SnapshotThreshold and SnapshotInterval override the raft profile if not 0.
 	SnapshotThreshold int
 	SnapshotInterval  int
+	// Witness makes a store node vote in its raft groups without keeping the
+	// keys, to break ties between two data replicas.
+	Witness bool
+	// Nonvoter makes a store node join its raft groups without a vote, to
+	// be promoted once it has caught up.
+	Nonvoter bool
+	// SeedFrom are the rpc addresses of replicas of the shard a new store
+	// node fetches its initial snapshot from.
+	SeedFrom []string
+	// MaxClockSkew is the clock offset between a replica and its leader above
+	// which the leader lease is not trusted for reads, disabled if 0.
+	MaxClockSkew time.Duration
+	// TxnParallelism bounds the shards a coordinator sends the messages of a
+	// transaction to at the same time.
+	TxnParallelism = 16
+	// TxnBatchSize is the most committed transactions a shard leader packs
+	// in a raft entry, waiting up to TxnBatchDelay for them. Transactions are
+	// not batched if not above 1.
+	TxnBatchSize  = 1
+	TxnBatchDelay = 2 * time.Millisecond
+	// TxnIdleTimeout drops the interactive transactions without requests
+	// for this long, never if 0.
+	TxnIdleTimeout = time.Minute
 )
 
 // RandNodeID returns a random node id
@@ -64,49 +121,96 @@ func RandNodeID(n int) string {
 	return string(b)
 }
 
+// raftClosers are the transport and stores ShutdownRaft closes, by raft
+// instance.
+var (
+	raftClosersMu sync.Mutex
+	raftClosers   = make(map[*raft.Raft][]io.Closer)
+)
+
 // SetupRaft initialises raft and returns a raft instance. If enableSingle is set, and there are no existing peers,
 // then this node becomes the first node, and therefore leader, of the cluster.
 func SetupRaft(fsm raft.FSM, id, raftAddress, raftDir string, enableSingle bool) (*raft.Raft, error) {
 	config := raft.DefaultConfig()
 	// Override defaults with configured values
-	config.SnapshotThreshold = uint64(SnapshotThreshold)
-	config.SnapshotInterval = time.Duration(SnapshotInterval) * time.Second
+	timeout, err := applyRaftProfile(config, RaftProfile)
+	if err != nil {
+		return nil, err
+	}
 	config.LocalID = raft.ServerID(id)
 	config.LogLevel = "INFO"
 
 	// Setup Raft communication.
 	var TCPAddress *net.TCPAddr
-	var transport *raft.NetworkTransport
-	var err error
+	var transport raft.Transport
 	var snapshots *raft.FileSnapshotStore
 	if TCPAddress, err = net.ResolveTCPAddr("tcp", raftAddress); err != nil {
 		log.Fatalf("failed to resolve TCP address %s: %s", raftAddress, err)
 	}
-	if transport, err = raft.NewTCPTransport(raftAddress, TCPAddress, 3, 10*time.Second, os.Stderr); err != nil {
-		log.Fatalf("failed to make TCP transport on %s: %s", raftAddress, err.Error())
+	if transport, err = newRaftTransport(raftAddress, TCPAddress, timeout); err != nil {
+		log.Fatalf("failed to make raft transport on %s: %s", raftAddress, err.Error())
+	}
+
+	if InMemory {
+		// nothing survives the process
+		return setupRaftInMemory(config, fsm, transport, enableSingle)
 	}
 
 	// Create the snapshot store. This allows the Raft to truncate the log.
-	if snapshots, err = raft.NewFileSnapshotStore(raftDir, RetainSnapshotCount, os.Stderr); err != nil {
-		log.Fatalf("failed to create snapshot store at %s: %s", raftDir, err.Error())
+	snapDir := SnapshotDirOf(raftDir)
+	if err := moveSnapshots(raftDir, snapDir); err != nil {
+		return nil, err
+	}
+	if snapshots, err = raft.NewFileSnapshotStore(snapDir, RetainSnapshotCount, os.Stderr); err != nil {
+		log.Fatalf("failed to create snapshot store at %s: %s", snapDir, err.Error())
+	}
+
+	var snapshotStore raft.SnapshotStore = snapshots
+	if SnapshotWriteBandwidth > 0 {
+		snapshotStore = newPacedSnapshotStore(snapshots)
+	}
+	var throttled *throttledSnapshotStore
+	if SnapshotBandwidth > 0 {
+		throttled = &throttledSnapshotStore{SnapshotStore: snapshotStore, bw: NewBandwidth(SnapshotBandwidth)}
+		snapshotStore = throttled
 	}
 
 	// Create the log store and stable store.
 	var logStore raft.LogStore
 	var stableStore raft.StableStore
 
-	boltDB, err := raftboltdb.NewBoltStore(filepath.Join(raftDir, "raft.db"))
+	boltDB, err := raftboltdb.New(raftboltdb.Options{
+		Path:        filepath.Join(raftDir, "raft.db"),
+		BoltOptions: &bolt.Options{InitialMmapSize: RaftLogPreallocMB << 20},
+	})
 	if err != nil {
 		log.Fatalf("failed to create new bolt store: %s", err)
 	}
 	logStore = boltDB
 	stableStore = boltDB
+	var logCache *LogCache
+	if RaftLogCacheMB > 0 {
+		logCache = NewLogCache(int64(RaftLogCacheMB)<<20, boltDB)
+		logStore = logCache
+	}
 
 	// Instantiate the Raft systems.
-	ra, err := raft.NewRaft(config, fsm, logStore, stableStore, snapshots, transport)
+	ra, err := raft.NewRaft(config, fsm, logStore, stableStore, snapshotStore, transport)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new raft: %s", err)
 	}
+	closers := []io.Closer{boltDB}
+	if t, ok := transport.(raft.WithClose); ok {
+		closers = append(closers, t)
+	}
+	raftClosersMu.Lock()
+	raftClosers[ra] = closers
+	raftClosersMu.Unlock()
+	registerRaftStorage(ra, raftStorage{logs: logStore, snapshots: snapshotStore, logFile: filepath.Join(raftDir, "raft.db"), cache: logCache})
+	// the local snapshot is restored, the snapshots opened from now on are sent
+	if throttled != nil {
+		throttled.start()
+	}
 	if enableSingle {
 		configuration := raft.Configuration{
 			Servers: []raft.Server{
@@ -116,12 +220,61 @@ func SetupRaft(fsm raft.FSM, id, raftAddress, raftDir string, enableSingle bool)
 				},
 			},
 		}
-		ra.BootstrapCluster(configuration)
+		if ra.BootstrapCluster(configuration).Error() == nil {
+			// a new cluster, named by the node bootstrapping it
+			id, err := NewClusterID()
+			if err != nil {
+				return nil, err
+			}
+			if err := SetClusterID(raftDir, id); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	return ra, nil
 }
 
+// setupRaftInMemory sets raft up like SetupRaft, keeping its log, stable
+// store and snapshots in memory.
+func setupRaftInMemory(config *raft.Config, fsm raft.FSM, transport raft.Transport, enableSingle bool) (*raft.Raft, error) {
+	store := raft.NewInmemStore()
+	snapshots := raft.NewInmemSnapshotStore()
+	ra, err := raft.NewRaft(config, fsm, store, store, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new raft: %s", err)
+	}
+	if t, ok := transport.(raft.WithClose); ok {
+		raftClosersMu.Lock()
+		raftClosers[ra] = []io.Closer{t}
+		raftClosersMu.Unlock()
+	}
+	registerRaftStorage(ra, raftStorage{logs: store, snapshots: snapshots})
+	if enableSingle {
+		ra.BootstrapCluster(raft.Configuration{
+			Servers: []raft.Server{{ID: config.LocalID, Address: transport.LocalAddr()}},
+		})
+	}
+	return ra, nil
+}
+
+// ShutdownRaft shuts ra down and closes its transport and log store, so that
+// its address and directory can be reused in the same process.
+func ShutdownRaft(ra *raft.Raft) error {
+	err := ra.Shutdown().Error()
+	raftClosersMu.Lock()
+	closers := raftClosers[ra]
+	delete(raftClosers, ra)
+	delete(raftStorages, ra)
+	raftClosersMu.Unlock()
+	for _, c := range closers {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
 // GetDerivedAddress derives a new IP:Port from a given
 // address.
 func GetDerivedAddress(address string) string {
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Targets of a comparison.
+const (
+	CompareValue          = "value"
+	CompareVersion        = "version"
+	CompareModRevision    = "mod_revision"
+	CompareCreateRevision = "create_revision"
+)
+
+// ValidateCompare returns an error if cmp has an unknown target or result.
+func ValidateCompare(cmp *raftpb.Compare) error {
+	switch cmp.Target {
+	case CompareValue, CompareVersion, CompareModRevision, CompareCreateRevision:
+	default:
+		return fmt.Errorf("invalid compare target %q", cmp.Target)
+	}
+	switch cmp.Result {
+	case "=", "!=", "<", ">":
+	default:
+		return fmt.Errorf("invalid compare result %q", cmp.Result)
+	}
+	return nil
+}
+
+// CompareHolds reports whether cmp holds for the value v and metadata meta of
+// its key, ok being false if the key does not exist. The value and metadata
+// of a missing key are 0. Blob values are only different from every int64.
+func CompareHolds(cmp *raftpb.Compare, v interface{}, meta KeyMeta, ok bool) bool {
+	var actual int64
+	switch cmp.Target {
+	case CompareValue:
+		if ok {
+			i, isInt := v.(int64)
+			if !isInt {
+				return cmp.Result == "!="
+			}
+			actual = i
+		}
+	case CompareVersion:
+		actual = meta.Version
+	case CompareModRevision:
+		actual = meta.ModRevision
+	case CompareCreateRevision:
+		actual = meta.CreateRevision
+	}
+	switch cmp.Result {
+	case "=":
+		return actual == cmp.Value
+	case "!=":
+		return actual != cmp.Value
+	case "<":
+		return actual < cmp.Value
+	case ">":
+		return actual > cmp.Value
+	}
+	return false
+}
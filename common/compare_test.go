@@ -0,0 +1,58 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareHolds(t *testing.T) {
+	meta := KeyMeta{Version: 2, ModRevision: 7, CreateRevision: 5}
+	for _, c := range []struct {
+		cmp  *raftpb.Compare
+		v    interface{}
+		ok   bool
+		want bool
+	}{
+		{&raftpb.Compare{Target: CompareValue, Result: "=", Value: 3}, int64(3), true, true},
+		{&raftpb.Compare{Target: CompareValue, Result: ">", Value: 3}, int64(3), true, false},
+		{&raftpb.Compare{Target: CompareValue, Result: "!=", Value: 3}, []byte("3"), true, true},
+		{&raftpb.Compare{Target: CompareValue, Result: "=", Value: 3}, []byte("3"), true, false},
+		{&raftpb.Compare{Target: CompareVersion, Result: "=", Value: 2}, int64(0), true, true},
+		{&raftpb.Compare{Target: CompareModRevision, Result: "<", Value: 8}, int64(0), true, true},
+		{&raftpb.Compare{Target: CompareCreateRevision, Result: ">", Value: 5}, int64(0), true, false},
+		// missing keys compare as 0
+		{&raftpb.Compare{Target: CompareCreateRevision, Result: "=", Value: 0}, nil, false, true},
+		{&raftpb.Compare{Target: CompareValue, Result: "=", Value: 0}, nil, false, true},
+	} {
+		m := meta
+		if !c.ok {
+			m = KeyMeta{}
+		}
+		assert.Equal(t, c.want, CompareHolds(c.cmp, c.v, m, c.ok), "%v", c.cmp)
+	}
+}
+
+func TestValidateCompare(t *testing.T) {
+	assert.Nil(t, ValidateCompare(&raftpb.Compare{Target: CompareVersion, Result: "="}))
+	assert.NotNil(t, ValidateCompare(&raftpb.Compare{Target: "lease", Result: "="}))
+	assert.NotNil(t, ValidateCompare(&raftpb.Compare{Target: CompareValue, Result: ">="}))
+}
+
+func TestCmap_Peek(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	m.Set("a", int64(1))
+	assert.Nil(t, m.TryLocks([]*raftpb.Command{{Method: GET, Key: "a"}, {Method: GET, Key: "b"}}, "tx1"))
+
+	// keys locked by transactions are read, pending new keys do not exist
+	v, _, ok, err := m.Peek("a", time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), v)
+	_, _, ok, err = m.Peek("b", time.Millisecond)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
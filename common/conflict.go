@@ -0,0 +1,72 @@
+package common
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"time"
+)
+
+// ConflictBackoff is the base of the backoff suggested to the transactions
+// aborted because a key was locked. The suggestion adds up to as much jitter,
+// so that conflicting transactions do not retry in lockstep.
+var ConflictBackoff = 20 * time.Millisecond
+
+// Reasons of a conflict.
+const (
+	// ConflictLocked is a key locked by another transaction or write.
+	ConflictLocked = "locked"
+	// ConflictCondition is a set whose condition does not hold.
+	ConflictCondition = "condition"
+)
+
+// Conflict is the error of a transaction aborted by the key it conflicted on.
+type Conflict struct {
+	Key    string
+	Reason string
+	// Holder is the transaction holding the lock on Key, if known.
+	Holder string
+	// Backoff is how long the transaction should wait before being retried.
+	// Failed conditions are retried right away, once the keys are read again.
+	Backoff time.Duration
+}
+
+func newConflict(key, reason, holder string) *Conflict {
+	c := &Conflict{Key: key, Reason: reason, Holder: holder}
+	if reason == ConflictLocked && ConflictBackoff > 0 {
+		c.Backoff = ConflictBackoff + time.Duration(rand.Int63n(int64(ConflictBackoff)))
+	}
+	return c
+}
+
+func (c *Conflict) Error() string {
+	what := "set condition fails"
+	if c.Reason == ConflictLocked {
+		what = "locked"
+		if c.Holder != "" {
+			what += " by " + c.Holder
+		}
+	}
+	return fmt.Sprintf("conflict on Key=%s: %s, retry after %s", c.Key, what, c.Backoff)
+}
+
+var conflictRegexp = regexp.MustCompile(`conflict on Key=(.*?): (locked(?: by (\S+))?|set condition fails), retry after (\S+)`)
+
+// ParseConflict returns the conflict of err, possibly flattened by rpc or
+// wrapped in the message of an aborted transaction, nil if err is not a
+// conflict.
+func ParseConflict(err error) *Conflict {
+	if err == nil {
+		return nil
+	}
+	m := conflictRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	c := &Conflict{Key: m[1], Reason: ConflictCondition, Holder: m[3]}
+	if m[2] != "set condition fails" {
+		c.Reason = ConflictLocked
+	}
+	c.Backoff, _ = time.ParseDuration(m[4])
+	return c
+}
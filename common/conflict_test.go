@@ -0,0 +1,40 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflict_Locked(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	m.Set("a", int64(1))
+	assert.Nil(t, m.TryLocks([]*raftpb.Command{{Method: SET, Key: "a", Value: 2}}, "tx1"))
+
+	err := m.TryLocks([]*raftpb.Command{{Method: DEL, Key: "a"}}, "tx2")
+	conflict, ok := err.(*Conflict)
+	assert.True(t, ok)
+	assert.Equal(t, "a", conflict.Key)
+	assert.Equal(t, ConflictLocked, conflict.Reason)
+	assert.Equal(t, "tx1", conflict.Holder)
+	assert.True(t, conflict.Backoff >= ConflictBackoff && conflict.Backoff < 2*ConflictBackoff)
+}
+
+func TestParseConflict(t *testing.T) {
+	for _, c := range []*Conflict{
+		{Key: "ns/a:b", Reason: ConflictLocked, Holder: "tx1", Backoff: 25 * time.Millisecond},
+		{Key: "a", Reason: ConflictLocked},
+		{Key: "a", Reason: ConflictCondition},
+	} {
+		// as returned by rpc and by the coordinator
+		err := fmt.Errorf("transaction tx2 aborted: %s", c)
+		assert.Equal(t, c, ParseConflict(err))
+	}
+	assert.Nil(t, ParseConflict(errors.New("map is locked globally")))
+	assert.Nil(t, ParseConflict(nil))
+}
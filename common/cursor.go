@@ -0,0 +1,39 @@
+package common
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// ExportCursor is where the next page of a paginated export starts: the
+// revision of every shard as of the first page, which all the pages read,
+// and the last key of the previous page. The pages of a cursor fail with
+// ErrCompacted once a shard does not keep the changes since its revision,
+// and the export must start over.
+type ExportCursor struct {
+	Revisions RevisionToken
+	After     string
+}
+
+// String returns the text form of the cursor, opaque to clients.
+func (c *ExportCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Revisions.String() + "\n" + c.After))
+}
+
+// ParseExportCursor parses the text form of a cursor.
+func ParseExportCursor(s string) (*ExportCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	i := strings.Index(string(b), "\n")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	revs, err := ParseRevisionToken(string(b[:i]))
+	if err != nil || len(revs) == 0 {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	return &ExportCursor{Revisions: revs, After: string(b[i+1:])}, nil
+}
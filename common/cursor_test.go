@@ -0,0 +1,19 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportCursor(t *testing.T) {
+	c := &ExportCursor{Revisions: RevisionToken{0: 12, 1: 7}, After: "app/a\nb"}
+	parsed, err := ParseExportCursor(c.String())
+	assert.Nil(t, err)
+	assert.Equal(t, c, parsed)
+
+	for _, s := range []string{"", "not base64!", "bm8gbmV3bGluZQ"} {
+		_, err := ParseExportCursor(s)
+		assert.Error(t, err, s)
+	}
+}
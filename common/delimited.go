@@ -0,0 +1,49 @@
+package common
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MaxMessageSize bounds the size of the messages of a stream.
+const MaxMessageSize = 64 << 20
+
+// WriteMessage writes m to w prefixed by its size as a uvarint, the framing
+// of the streams of protobuf messages.
+func WriteMessage(w io.Writer, m proto.Message) error {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	var size [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(size[:], uint64(len(b)))
+	if _, err := w.Write(size[:n]); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// ReadMessage reads into m the next message written by WriteMessage to r. It
+// returns io.EOF at the end of the stream, and io.ErrUnexpectedEOF at the end
+// of a truncated one.
+func ReadMessage(r *bufio.Reader, m proto.Message) error {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	if size > MaxMessageSize {
+		return fmt.Errorf("message of %d bytes, more than %d", size, MaxMessageSize)
+	}
+	b := make([]byte, size)
+	if _, err := io.ReadFull(r, b); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	return proto.Unmarshal(b, m)
+}
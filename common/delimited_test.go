@@ -0,0 +1,34 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessages(t *testing.T) {
+	var buf bytes.Buffer
+	cmds := []*raftpb.Command{{Method: SET, Key: "a", Value: 1}, {}, {Method: DEL, Key: "b"}}
+	for _, cmd := range cmds {
+		assert.Nil(t, WriteMessage(&buf, cmd))
+	}
+	b := buf.Bytes()
+
+	r := bufio.NewReader(bytes.NewReader(b))
+	for _, cmd := range cmds {
+		read := &raftpb.Command{}
+		assert.Nil(t, ReadMessage(r, read))
+		assert.True(t, proto.Equal(cmd, read))
+	}
+	assert.Equal(t, io.EOF, ReadMessage(r, &raftpb.Command{}))
+
+	r = bufio.NewReader(bytes.NewReader(b[:len(b)-1]))
+	assert.Nil(t, ReadMessage(r, &raftpb.Command{}))
+	assert.Nil(t, ReadMessage(r, &raftpb.Command{}))
+	assert.Equal(t, io.ErrUnexpectedEOF, ReadMessage(r, &raftpb.Command{}))
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// The FSMs must be deterministic: replicas applying the same entries must
+// reach the same state. Time comes from the entries, as Command.Time set by
+// the leader, never from the clock of the replica, and nothing applied may
+// depend on randomness or on the iteration order of a map.
+//
+// Builds with the determinism tag check it: every replica of the process
+// hashes its keys after each entry it applies, and the first replica to
+// apply an entry after a given log records its hash. A replica reaching
+// another state after the same log panics with the entry and both hashes.
+// This is meant for tests running several replicas of a group in a process,
+// as go test -tags determinism: hashing the keys after every entry is slow.
+// The store tests of the tag replay a log into several FSMs, one of them
+// applying it later than the others, so that the states depending on the
+// clock, on the iteration order of a map or on randomness diverge.
+
+// ApplyCheck checks the entries applied by a replica, in the determinism
+// builds.
+type ApplyCheck struct {
+	node string
+	// chain is the hash of the entries applied, identifying the log of
+	// the replica
+	chain uint64
+}
+
+// NewApplyCheck returns the check of the entries applied by node.
+func NewApplyCheck(node string) *ApplyCheck {
+	return &ApplyCheck{node: node}
+}
+
+// chained returns the hash of the entries applied once data is applied at
+// index after the entries hashed to chain.
+func chained(chain uint64, index uint64, data []byte) uint64 {
+	h := fnv.New64a()
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:], chain)
+	binary.BigEndian.PutUint64(b[8:], index)
+	h.Write(b[:])
+	h.Write(data)
+	return h.Sum64()
+}
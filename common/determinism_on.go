@@ -0,0 +1,67 @@
+//go:build determinism
+// +build determinism
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// DeterminismChecks is set in the builds with the determinism tag.
+const DeterminismChecks = true
+
+// appliedStatesSize is the number of entries whose state is kept to be
+// compared.
+const appliedStatesSize = 1 << 16
+
+type appliedState struct {
+	node  string
+	hash  uint64
+	entry string
+}
+
+// appliedStates are the states reached by the first replica of the process
+// applying an entry after a given log, by the hash of the log.
+var appliedStates = struct {
+	sync.Mutex
+	states map[uint64]appliedState
+	order  []uint64
+}{states: make(map[uint64]appliedState)}
+
+// restores tells apart the logs of the replicas restored from a snapshot.
+var restores uint64
+
+// Apply marks the entry data at index as being applied, until the function
+// returned is called with the hash of the state after it. It panics if
+// another replica of the process reached another state after the same
+// entries.
+func (a *ApplyCheck) Apply(index uint64, data []byte, describe func() string) func(state func() uint64) {
+	a.chain = chained(a.chain, index, data)
+	chain := a.chain
+	return func(state func() uint64) {
+		reached := appliedState{node: a.node, hash: state(), entry: describe()}
+		appliedStates.Lock()
+		first, ok := appliedStates.states[chain]
+		if !ok {
+			appliedStates.states[chain] = reached
+			appliedStates.order = append(appliedStates.order, chain)
+			if len(appliedStates.order) > appliedStatesSize {
+				delete(appliedStates.states, appliedStates.order[0])
+				appliedStates.order = appliedStates.order[1:]
+			}
+		}
+		appliedStates.Unlock()
+		if ok && first.hash != reached.hash {
+			panic(fmt.Sprintf("non-deterministic apply of the entry at index %d: %s reached state %016x, %s reached %016x\nentry on %s: %s\nentry on %s: %s",
+				index, first.node, first.hash, reached.node, reached.hash, first.node, first.entry, reached.node, reached.entry))
+		}
+	}
+}
+
+// Reset forgets the entries applied, once the state is replaced by a
+// snapshot. The replica is not compared to the others anymore.
+func (a *ApplyCheck) Reset() {
+	a.chain = chained(0, atomic.AddUint64(&restores, 1), []byte(a.node))
+}
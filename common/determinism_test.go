@@ -0,0 +1,36 @@
+//go:build determinism
+// +build determinism
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyCheck(t *testing.T) {
+	a := NewApplyCheck("a")
+	b := NewApplyCheck("b")
+	describe := func() string { return "set k" }
+	state := func(h uint64) func() uint64 { return func() uint64 { return h } }
+
+	// the same state after the same entries
+	a.Apply(1, []byte("set k"), describe)(state(1))
+	b.Apply(1, []byte("set k"), describe)(state(1))
+
+	// another state after the same entries
+	a.Apply(2, []byte("set k"), describe)(state(2))
+	checked := b.Apply(2, []byte("set k"), describe)
+	assert.Panics(t, func() { checked(state(3)) })
+
+	// other entries are not compared
+	c := NewApplyCheck("c")
+	c.Apply(1, []byte("set j"), describe)(state(4))
+
+	// nor replicas restored from a snapshot
+	a.Reset()
+	a.Apply(3, []byte("set k"), describe)(state(5))
+	b.Reset()
+	b.Apply(3, []byte("set k"), describe)(state(6))
+}
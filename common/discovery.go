@@ -0,0 +1,138 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Discovery providers, given as <provider>:<argument> to Discover.
+const (
+	// DiscoveryStatic lists the addresses of the nodes, comma separated.
+	DiscoveryStatic = "static"
+	// DiscoverySRV looks the nodes up in the DNS SRV records of a name.
+	DiscoverySRV = "srv"
+	// DiscoveryExec runs a command printing the addresses of the nodes, such
+	// as a cloud CLI listing the instances with a tag.
+	DiscoveryExec = "exec"
+)
+
+var (
+	// DiscoveryTimeout is how long a node waits for the nodes it discovers.
+	DiscoveryTimeout = 5 * time.Minute
+	// discoveryInterval is how often the nodes are discovered again.
+	discoveryInterval = time.Second
+	// JoinTimeout is how long a node retries joining a group whose seed is
+	// not ready yet.
+	JoinTimeout = time.Minute
+)
+
+// Discover returns the addresses of the nodes of a raft group found by the
+// provider of spec.
+func Discover(spec string) ([]string, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("invalid discovery %q, expecting <provider>:<argument>", spec)
+	}
+	var addrs []string
+	switch parts[0] {
+	case DiscoveryStatic:
+		addrs = strings.Split(parts[1], ",")
+	case DiscoverySRV:
+		_, records, err := net.LookupSRV("", "", parts[1])
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			addrs = append(addrs, net.JoinHostPort(strings.TrimSuffix(r.Target, "."), fmt.Sprint(r.Port)))
+		}
+	case DiscoveryExec:
+		out, err := exec.Command("sh", "-c", parts[1]).Output()
+		if err != nil {
+			return nil, fmt.Errorf("discovery command failed: %s", err)
+		}
+		addrs = strings.Fields(string(out))
+	default:
+		return nil, fmt.Errorf("unknown discovery provider %q", parts[0])
+	}
+	var res []string
+	for _, addr := range addrs {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			res = append(res, addr)
+		}
+	}
+	return res, nil
+}
+
+// DiscoverJoin decides how the node reachable at self starts: it waits until
+// expect nodes of its group, itself included, are discovered by spec and
+// accept connections, and returns the address of the node to join, the
+// lowest of them, or an empty address if self is the lowest and bootstraps
+// the group. Waiting for the expected size keeps nodes starting together
+// from bootstrapping several groups.
+func DiscoverJoin(spec, self string, expect int) (string, error) {
+	deadline := time.Now().Add(DiscoveryTimeout)
+	for {
+		addrs, err := Discover(spec)
+		if err != nil {
+			return "", err
+		}
+		up := []string{self}
+		for _, addr := range addrs {
+			if addr != self && reachable(addr) {
+				up = append(up, addr)
+			}
+		}
+		if len(up) >= expect {
+			sort.Strings(up)
+			if up[0] == self {
+				return "", nil
+			}
+			return up[0], nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("%d of %d expected nodes found after %s", len(up), expect, DiscoveryTimeout)
+		}
+		time.Sleep(discoveryInterval)
+	}
+}
+
+func reachable(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, discoveryInterval)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// RetryJoin calls join until it succeeds, for up to JoinTimeout, since the
+// node bootstrapping a group discovered at the same time may not lead it yet.
+func RetryJoin(join func() error) error {
+	deadline := time.Now().Add(JoinTimeout)
+	for {
+		err := join()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(discoveryInterval)
+	}
+}
+
+// RetryJoinAny calls join with each of the comma separated addresses addrs
+// in turn until one succeeds, retrying for up to JoinTimeout: only the
+// leader of a group accepts members, and the nodes given may not know it.
+func RetryJoinAny(addrs string, join func(addr string) error) error {
+	return RetryJoin(func() error {
+		var err error
+		for _, addr := range strings.Split(addrs, ",") {
+			if err = join(strings.TrimSpace(addr)); err == nil {
+				return nil
+			}
+		}
+		return err
+	})
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscover(t *testing.T) {
+	addrs, err := Discover("static:a:1, b:2,,")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a:1", "b:2"}, addrs)
+
+	addrs, err = Discover("exec:echo a:1 b:2")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a:1", "b:2"}, addrs)
+
+	_, err = Discover("consul:x")
+	assert.NotNil(t, err)
+	_, err = Discover("static")
+	assert.NotNil(t, err)
+}
+
+func TestDiscoverJoin(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+	peer := ln.Addr().String()
+
+	// the lowest address bootstraps, the others join it
+	join, err := DiscoverJoin("static:"+peer, "127.0.0.2:1", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, peer, join)
+	join, err = DiscoverJoin("static:"+peer, "0.0.0.0:1", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, "", join)
+
+	// unreachable nodes do not count
+	defer func(d time.Duration) { DiscoveryTimeout = d }(DiscoveryTimeout)
+	DiscoveryTimeout = 0
+	_, err = DiscoverJoin("static:"+peer+",127.0.0.1:1", "127.0.0.2:1", 3)
+	assert.NotNil(t, err)
+}
+
+func TestRetryJoinAny(t *testing.T) {
+	var tried []string
+	err := RetryJoinAny("a:1, b:2,c:3", func(addr string) error {
+		tried = append(tried, addr)
+		if addr != "b:2" {
+			return errors.New("not the leader")
+		}
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a:1", "b:2"}, tried)
+
+	defer func(d time.Duration) { JoinTimeout = d }(JoinTimeout)
+	JoinTimeout = 0
+	err = RetryJoinAny("a:1", func(addr string) error { return errors.New("not the leader") })
+	assert.EqualError(t, err, "not the leader")
+}
@@ -0,0 +1,155 @@
+package common
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Dump formats understood by bulk import and export.
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// DumpRecord is the JSONL form of a key. Blob values carry their codec.
+// Exports include the metadata of the key, imports ignore it.
+type DumpRecord struct {
+	Key   string          `json:"key"`
+	Value int64           `json:"value,omitempty"`
+	Codec string          `json:"codec,omitempty"`
+	Blob  []byte          `json:"blob,omitempty"`
+	Meta  *raftpb.KeyMeta `json:"meta,omitempty"`
+}
+
+// DumpReader reads keys from a dump. CSV rows are either key,value for
+// numerical values or key,codec,base64 data for blobs.
+type DumpReader struct {
+	format string
+	lines  *bufio.Scanner
+	rows   *csv.Reader
+	line   int
+}
+
+// NewDumpReader returns a reader of the dump r in the given format.
+func NewDumpReader(r io.Reader, format string) (*DumpReader, error) {
+	d := &DumpReader{format: format}
+	switch format {
+	case FormatJSONL:
+		d.lines = bufio.NewScanner(r)
+		d.lines.Buffer(make([]byte, 64*1024), 64<<20)
+	case FormatCSV:
+		d.rows = csv.NewReader(r)
+		d.rows.FieldsPerRecord = -1
+	default:
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+	return d, nil
+}
+
+// Next returns the next key of the dump, or io.EOF at the end.
+func (d *DumpReader) Next() (*raftpb.KVEntry, error) {
+	d.line++
+	if d.format == FormatCSV {
+		row, err := d.rows.Read()
+		if err != nil {
+			return nil, err
+		}
+		return d.parseRow(row)
+	}
+	for d.lines.Scan() {
+		if len(d.lines.Bytes()) == 0 {
+			d.line++
+			continue
+		}
+		var rec DumpRecord
+		if err := json.Unmarshal(d.lines.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: %s", d.line, err)
+		}
+		if rec.Key == "" {
+			return nil, fmt.Errorf("line %d: key is missing", d.line)
+		}
+		return &raftpb.KVEntry{Key: rec.Key, Value: rec.Value, Codec: rec.Codec, Blob: rec.Blob}, nil
+	}
+	if err := d.lines.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (d *DumpReader) parseRow(row []string) (*raftpb.KVEntry, error) {
+	if len(row) == 0 || row[0] == "" {
+		return nil, fmt.Errorf("line %d: key is missing", d.line)
+	}
+	switch len(row) {
+	case 2:
+		v, err := strconv.ParseInt(row[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", d.line, err)
+		}
+		return &raftpb.KVEntry{Key: row[0], Value: v}, nil
+	case 3:
+		data, err := base64.StdEncoding.DecodeString(row[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", d.line, err)
+		}
+		return &raftpb.KVEntry{Key: row[0], Codec: row[1], Blob: data}, nil
+	}
+	return nil, fmt.Errorf("line %d: expected key,value or key,codec,data", d.line)
+}
+
+// DumpWriter writes keys in a format DumpReader reads back. CSV rows do not
+// carry metadata.
+type DumpWriter struct {
+	format string
+	w      *bufio.Writer
+	rows   *csv.Writer
+}
+
+// NewDumpWriter returns a writer of a dump in the given format to w.
+func NewDumpWriter(w io.Writer, format string) (*DumpWriter, error) {
+	d := &DumpWriter{format: format, w: bufio.NewWriter(w)}
+	switch format {
+	case FormatJSONL:
+	case FormatCSV:
+		d.rows = csv.NewWriter(d.w)
+	default:
+		return nil, fmt.Errorf("unknown dump format %q", format)
+	}
+	return d, nil
+}
+
+// Write appends e to the dump.
+func (d *DumpWriter) Write(e *raftpb.KVEntry) error {
+	if d.format == FormatCSV {
+		if e.Codec != "" {
+			return d.rows.Write([]string{e.Key, e.Codec, base64.StdEncoding.EncodeToString(e.Blob)})
+		}
+		return d.rows.Write([]string{e.Key, strconv.FormatInt(e.Value, 10)})
+	}
+	b, err := json.Marshal(&DumpRecord{Key: e.Key, Value: e.Value, Codec: e.Codec, Blob: e.Blob, Meta: e.Meta})
+	if err != nil {
+		return err
+	}
+	if _, err := d.w.Write(b); err != nil {
+		return err
+	}
+	return d.w.WriteByte('\n')
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (d *DumpWriter) Flush() error {
+	if d.rows != nil {
+		d.rows.Flush()
+		if err := d.rows.Error(); err != nil {
+			return err
+		}
+	}
+	return d.w.Flush()
+}
@@ -0,0 +1,64 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpReader(t *testing.T) {
+	d, err := NewDumpReader(strings.NewReader("a,1\nb,json,e30=\n"), FormatCSV)
+	assert.Nil(t, err)
+	e, err := d.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), e.Value)
+	e, err = d.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "json", e.Codec)
+	assert.Equal(t, []byte("{}"), e.Blob)
+	_, err = d.Next()
+	assert.Equal(t, io.EOF, err)
+
+	d, _ = NewDumpReader(strings.NewReader(`{"key":"a","value":2}`+"\n\n"+`{"value":3}`), FormatJSONL)
+	e, err = d.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, "a", e.Key)
+	_, err = d.Next()
+	assert.EqualError(t, err, "line 3: key is missing")
+
+	_, err = NewDumpReader(strings.NewReader(""), "xml")
+	assert.Error(t, err)
+}
+
+func TestDumpWriter(t *testing.T) {
+	entries := []*raftpb.KVEntry{
+		{Key: "a", Value: 1, Meta: &raftpb.KeyMeta{ModRevision: 3, Version: 1}},
+		{Key: "b", Codec: "json", Blob: []byte("{}")},
+	}
+	for _, format := range []string{FormatJSONL, FormatCSV} {
+		var buf bytes.Buffer
+		w, err := NewDumpWriter(&buf, format)
+		assert.Nil(t, err)
+		for _, e := range entries {
+			assert.Nil(t, w.Write(e))
+		}
+		assert.Nil(t, w.Flush())
+
+		d, err := NewDumpReader(&buf, format)
+		assert.Nil(t, err)
+		for _, want := range entries {
+			e, err := d.Next()
+			assert.Nil(t, err)
+			assert.Equal(t, want.Key, e.Key)
+			assert.Equal(t, want.Value, e.Value)
+			assert.Equal(t, want.Codec, e.Codec)
+			assert.Equal(t, want.Blob, e.Blob)
+		}
+		_, err = d.Next()
+		assert.Equal(t, io.EOF, err)
+	}
+}
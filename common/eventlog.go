@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// WatchHistorySize is the number of recent changes a store node keeps for the
+// watchers to start from, or catch up from, an older revision.
+var WatchHistorySize = 10000
+
+// EventLog keeps the last changes of the keys, the SET, DEL and EVICT
+// commands that made them, in revision order.
+type EventLog struct {
+	mu     sync.RWMutex
+	limit  int
+	events []*raftpb.Command
+	// compacted is the revision after which every change is kept, -1 until
+	// the log starts
+	compacted int64
+}
+
+// NewEventLog returns a log keeping the last limit changes.
+func NewEventLog(limit int) *EventLog {
+	return &EventLog{limit: limit, compacted: -1}
+}
+
+// Start sets the revision the log starts after, if not set yet.
+func (l *EventLog) Start(rev int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.compacted < 0 {
+		l.compacted = rev
+	}
+}
+
+// Reset drops the changes logged, starting over from the next Start.
+func (l *EventLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = nil
+	l.compacted = -1
+}
+
+// Append appends events, of revisions higher than those already logged.
+func (l *EventLog) Append(events ...*raftpb.Command) {
+	if l.limit <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, events...)
+	// trimmed by a quarter of the limit at a time, copying the retained
+	// events off the old array
+	if len(l.events) > l.limit+l.limit/4 {
+		drop := len(l.events) - l.limit
+		l.compacted = l.events[drop-1].Revision
+		l.events = append([]*raftpb.Command(nil), l.events[drop:]...)
+	}
+}
+
+// CompactRevision returns the revision after which every change is kept, -1
+// if none is.
+func (l *EventLog) CompactRevision() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.limit <= 0 {
+		return -1
+	}
+	return l.compacted
+}
+
+// Since returns the changes after rev of the keys starting with prefix, or
+// ErrCompacted if some of them are not kept anymore.
+func (l *EventLog) Since(prefix string, rev int64) ([]*raftpb.Command, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.limit <= 0 || l.compacted < 0 || rev < l.compacted {
+		return nil, fmt.Errorf("%w: changes are kept after revision %d only", ErrCompacted, l.compacted)
+	}
+	i := sort.Search(len(l.events), func(i int) bool { return l.events[i].Revision > rev })
+	var res []*raftpb.Command
+	for _, ev := range l.events[i:] {
+		if strings.HasPrefix(ev.Key, prefix) {
+			res = append(res, ev)
+		}
+	}
+	return res, nil
+}
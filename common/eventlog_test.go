@@ -0,0 +1,42 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventLog(t *testing.T) {
+	l := NewEventLog(4)
+	_, err := l.Since("", 0)
+	assert.True(t, IsCompacted(err))
+
+	l.Start(1)
+	l.Start(5)
+	assert.Equal(t, int64(1), l.CompactRevision())
+	l.Append(&raftpb.Command{Method: SET, Key: "a/1", Revision: 2})
+	l.Append(&raftpb.Command{Method: SET, Key: "a/2", Revision: 3}, &raftpb.Command{Method: SET, Key: "b", Revision: 3})
+	l.Append(&raftpb.Command{Method: DEL, Key: "a/1", Revision: 4})
+
+	events, err := l.Since("a/", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []*raftpb.Command{{Method: SET, Key: "a/2", Revision: 3}, {Method: DEL, Key: "a/1", Revision: 4}}, events)
+
+	// beyond a quarter over the limit, the oldest are compacted
+	l.Append(&raftpb.Command{Method: SET, Key: "a/3", Revision: 5})
+	l.Append(&raftpb.Command{Method: SET, Key: "a/4", Revision: 6})
+	assert.Equal(t, int64(3), l.CompactRevision())
+	_, err = l.Since("a/", 2)
+	assert.True(t, IsCompacted(err))
+	events, err = l.Since("a/", 3)
+	assert.Nil(t, err)
+	assert.Len(t, events, 3)
+
+	l.Reset()
+	assert.Equal(t, int64(-1), l.CompactRevision())
+	l.Start(9)
+	events, err = l.Since("", 9)
+	assert.Nil(t, err)
+	assert.Empty(t, events)
+}
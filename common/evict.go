@@ -0,0 +1,120 @@
+package common
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Eviction policies of the cache mode.
+const (
+	// CacheLRU evicts the least recently read or written keys first.
+	CacheLRU = "lru"
+	// CacheLFU evicts the least frequently read or written keys first.
+	CacheLFU = "lfu"
+)
+
+// Cache mode, where shard leaders evict the coldest keys once the keys and
+// values of the shard exceed CacheBytes, checked every EvictInterval.
+// Disabled if CacheBytes is 0.
+var (
+	CacheBytes    int64
+	CachePolicy   = CacheLRU
+	EvictInterval = time.Second
+)
+
+// accessed records a read or write of v.
+func (v *Value) accessed() {
+	atomic.StoreInt64(&v.lastAccess, time.Now().UnixNano())
+	atomic.AddInt64(&v.hits, 1)
+}
+
+// entrySize is the size of key k and its value v counted against the cache
+// budget.
+func entrySize(k string, v interface{}) int64 {
+	return int64(len(k)) + ValueSize(v)
+}
+
+// EvictionCandidates returns the coldest keys to evict, according to policy,
+// for the keys and values of c to fit in budget bytes, nil if they already
+// do. Keys locked, by a transaction or for longer than timeout, are kept.
+// The candidates are EVICT commands carrying the mod revision of their key,
+// so that keys written since are not evicted.
+func (c *Cmap) EvictionCandidates(budget int64, policy string, timeout time.Duration) []*raftpb.Command {
+	type candidate struct {
+		key              string
+		rev              int64
+		size             int64
+		lastAccess, hits int64
+	}
+	c.mu.RLock()
+	var total int64
+	var candidates []candidate
+	for k, v := range c.Map {
+		if v.temp {
+			continue
+		}
+		if v.txid != "" {
+			// the value only changes once the global lock is released
+			total += entrySize(k, v.V)
+			continue
+		}
+		if !v.mu.RTryLockTimeout(timeout) {
+			continue
+		}
+		size := entrySize(k, v.V)
+		total += size
+		candidates = append(candidates, candidate{
+			key:        k,
+			rev:        v.Meta.ModRevision,
+			size:       size,
+			lastAccess: atomic.LoadInt64(&v.lastAccess),
+			hits:       atomic.LoadInt64(&v.hits),
+		})
+		v.mu.RUnlock()
+	}
+	c.mu.RUnlock()
+	if total <= budget {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if policy == CacheLFU && a.hits != b.hits {
+			return a.hits < b.hits
+		}
+		if a.lastAccess != b.lastAccess {
+			return a.lastAccess < b.lastAccess
+		}
+		return a.key < b.key
+	})
+	var cmds []*raftpb.Command
+	for _, cand := range candidates {
+		if total <= budget {
+			break
+		}
+		cmds = append(cmds, &raftpb.Command{Method: EVICT, Key: cand.key, Revision: cand.rev})
+		total -= cand.size
+	}
+	return cmds
+}
+
+// Evict deletes the keys of the EVICT commands cmds not written since they
+// were selected, and returns them. Pending keys of transactions, only known
+// to the leader, are kept, every other key is deleted whether it is locked or
+// not, so that all the replicas evict the same keys.
+func (c *Cmap) Evict(cmds []*raftpb.Command) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var evicted []string
+	for _, cmd := range cmds {
+		v, ok := c.Map[cmd.Key]
+		if !ok || v.temp || v.Meta.ModRevision != cmd.Revision {
+			continue
+		}
+		c.delete(cmd.Key)
+		evicted = append(evicted, cmd.Key)
+	}
+	return evicted
+}
@@ -0,0 +1,58 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func evictedKeys(cmds []*raftpb.Command) []string {
+	var keys []string
+	for _, cmd := range cmds {
+		keys = append(keys, cmd.Key)
+	}
+	return keys
+}
+
+func TestCmap_EvictionCandidates(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	// every entry takes 9 bytes
+	for i, k := range []string{"a", "b", "c"} {
+		assert.Nil(t, m.SetRev(k, int64(i), nil, int64(i+1)))
+		time.Sleep(time.Millisecond)
+	}
+	assert.Nil(t, m.EvictionCandidates(27, CacheLRU, 0))
+
+	// a is read last, b twice
+	m.Get("b")
+	m.Get("b")
+	time.Sleep(time.Millisecond)
+	m.Get("a")
+	assert.Equal(t, []string{"c"}, evictedKeys(m.EvictionCandidates(18, CacheLRU, 0)))
+	assert.Equal(t, []string{"c", "b"}, evictedKeys(m.EvictionCandidates(9, CacheLRU, 0)))
+	assert.Equal(t, []string{"c", "a"}, evictedKeys(m.EvictionCandidates(9, CacheLFU, 0)))
+
+	// keys locked by a transaction are kept
+	assert.Nil(t, m.TryLocks([]*raftpb.Command{{Method: SET, Key: "c", Value: 5}}, "tx1"))
+	assert.Equal(t, []string{"b"}, evictedKeys(m.EvictionCandidates(18, CacheLRU, 0)))
+}
+
+func TestCmap_Evict(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	assert.Nil(t, m.SetRev("a", int64(1), nil, 1))
+	assert.Nil(t, m.SetRev("b", int64(2), nil, 2))
+	cmds := m.EvictionCandidates(0, CacheLRU, 0)
+	assert.Len(t, cmds, 2)
+
+	// b is written again before the eviction is applied
+	assert.Nil(t, m.SetRev("b", int64(3), nil, 3))
+	assert.Equal(t, []string{"a"}, m.Evict(cmds))
+	_, ok, _ := m.Get("a")
+	assert.False(t, ok)
+	v, ok, _ := m.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), v)
+}
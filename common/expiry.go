@@ -0,0 +1,146 @@
+package common
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// ExpiryIndex orders ids by time, such as their last activity, to find those
+// before a time in O(expired) work rather than by scanning every id. The ids
+// of the same time are ordered by id, so that replicas expire them in the
+// same order. It is not safe for concurrent use.
+type ExpiryIndex struct {
+	items expiryHeap
+	byID  map[string]*expiryItem
+}
+
+type expiryItem struct {
+	id    string
+	at    int64
+	index int
+}
+
+// expiryHeap is a min-heap of items by time then id.
+type expiryHeap []*expiryItem
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool {
+	if h[i].at != h[j].at {
+		return h[i].at < h[j].at
+	}
+	return h[i].id < h[j].id
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *expiryHeap) Push(x interface{}) {
+	item := x.(*expiryItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return item
+}
+
+// NewExpiryIndex returns an empty index.
+func NewExpiryIndex() *ExpiryIndex {
+	return &ExpiryIndex{byID: make(map[string]*expiryItem)}
+}
+
+// Set sets the time of id to at, adding id if it is not indexed.
+func (x *ExpiryIndex) Set(id string, at int64) {
+	if item, ok := x.byID[id]; ok {
+		item.at = at
+		heap.Fix(&x.items, item.index)
+		return
+	}
+	item := &expiryItem{id: id, at: at}
+	x.byID[id] = item
+	heap.Push(&x.items, item)
+}
+
+// Remove removes id, if indexed.
+func (x *ExpiryIndex) Remove(id string) {
+	if item, ok := x.byID[id]; ok {
+		heap.Remove(&x.items, item.index)
+		delete(x.byID, id)
+	}
+}
+
+// Next returns the earliest time of the ids, false if there are none.
+func (x *ExpiryIndex) Next() (int64, bool) {
+	if len(x.items) == 0 {
+		return 0, false
+	}
+	return x.items[0].at, true
+}
+
+// Expire removes the ids whose time is before t, and returns them, earliest
+// first.
+func (x *ExpiryIndex) Expire(t int64) []string {
+	var res []string
+	for len(x.items) > 0 && x.items[0].at < t {
+		item := heap.Pop(&x.items).(*expiryItem)
+		delete(x.byID, item.id)
+		res = append(res, item.id)
+	}
+	return res
+}
+
+// Before returns up to limit of the ids whose time is before t, earliest
+// first, without removing them.
+func (x *ExpiryIndex) Before(t int64, limit int) []string {
+	var found []*expiryItem
+	// the children of an item are not before it in the heap
+	pending := []int{0}
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if i >= len(x.items) || x.items[i].at >= t {
+			continue
+		}
+		found = append(found, x.items[i])
+		pending = append(pending, 2*i+1, 2*i+2)
+	}
+	sort.Slice(found, func(i, j int) bool { return x.items.Less(found[i].index, found[j].index) })
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	res := make([]string, len(found))
+	for i, item := range found {
+		res[i] = item.id
+	}
+	return res
+}
+
+// At returns the time of id, false if it is not indexed.
+func (x *ExpiryIndex) At(id string) (int64, bool) {
+	item, ok := x.byID[id]
+	if !ok {
+		return 0, false
+	}
+	return item.at, true
+}
+
+// Times returns the time of every id.
+func (x *ExpiryIndex) Times() map[string]int64 {
+	times := make(map[string]int64, len(x.byID))
+	for id, item := range x.byID {
+		times[id] = item.at
+	}
+	return times
+}
+
+// Len returns the number of ids.
+func (x *ExpiryIndex) Len() int {
+	return len(x.items)
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiryIndex(t *testing.T) {
+	x := NewExpiryIndex()
+	_, ok := x.Next()
+	assert.False(t, ok)
+
+	x.Set("c", 3)
+	x.Set("b", 2)
+	x.Set("a", 2)
+	x.Set("d", 5)
+	next, ok := x.Next()
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), next)
+
+	// moved and removed ids
+	x.Set("b", 4)
+	x.Remove("c")
+	x.Remove("missing")
+	assert.Equal(t, 3, x.Len())
+
+	assert.Nil(t, x.Expire(2))
+	assert.Equal(t, []string{"a", "b"}, x.Expire(5))
+	assert.Equal(t, 1, x.Len())
+
+	// ids of the same time expire by id
+	x.Set("f", 5)
+	x.Set("e", 5)
+	assert.Equal(t, []string{"d", "e", "f"}, x.Expire(6))
+	assert.Equal(t, 0, x.Len())
+}
+
+func TestExpiryIndexBefore(t *testing.T) {
+	x := NewExpiryIndex()
+	for i, id := range []string{"g", "f", "e", "d", "c", "b", "a"} {
+		x.Set(id, int64(i%3))
+	}
+	assert.Equal(t, []string{"a", "d", "g", "c", "f"}, x.Before(2, 10))
+	assert.Equal(t, []string{"a", "d"}, x.Before(2, 2))
+	assert.Empty(t, x.Before(0, 10))
+	// the ids are left indexed
+	assert.Equal(t, 7, x.Len())
+
+	at, ok := x.At("b")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), at)
+	_, ok = x.At("missing")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), x.Times()["f"])
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"runtime"
+	"runtime/debug"
+	"time"
+)
+
+// MemoryHeap is the heap of a store node, accounted against HeapLimit.
+const MemoryHeap = "heap"
+
+// Garbage collector settings of the nodes.
+var (
+	// GCPercent is the GOGC of the node, the GOGC environment variable or
+	// its default if 0.
+	GCPercent int
+	// HeapLimit is the soft memory limit of the node in bytes, disabled if 0.
+	// On Go 1.19 and later, the garbage collector runs more often as the heap
+	// approaches it. Store nodes reject writes with ErrOverloaded once their
+	// heap is over HeapWatermark of it.
+	HeapLimit int64
+	// HeapWatermark is the fraction of HeapLimit over which the writes are
+	// rejected.
+	HeapWatermark = 0.9
+	// HeapInterval is how often the heap of store nodes is measured.
+	HeapInterval = time.Second
+)
+
+// TuneGC applies GCPercent and HeapLimit to the runtime.
+func TuneGC() {
+	if GCPercent != 0 {
+		debug.SetGCPercent(GCPercent)
+	}
+	if HeapLimit > 0 {
+		setMemoryLimit(HeapLimit)
+	}
+}
+
+// HeapBudget returns the heap size over which the writes are rejected, 0 if
+// unlimited.
+func HeapBudget() int64 {
+	return int64(float64(HeapLimit) * HeapWatermark)
+}
+
+// HeapInUse returns the bytes of the live and not yet collected heap objects.
+func HeapInUse() int64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.HeapAlloc)
+}
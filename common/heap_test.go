@@ -0,0 +1,35 @@
+package common
+
+import (
+	"errors"
+	"math"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeapWatermark(t *testing.T) {
+	defer func(limit int64, percent int) {
+		HeapLimit, GCPercent = limit, percent
+		debug.SetGCPercent(100)
+		setMemoryLimit(math.MaxInt64)
+	}(HeapLimit, GCPercent)
+
+	HeapLimit = 0
+	assert.Equal(t, int64(0), HeapBudget())
+	assert.True(t, HeapInUse() > 0)
+
+	HeapLimit, GCPercent = 1000, 50
+	TuneGC()
+	assert.Equal(t, 50, debug.SetGCPercent(100))
+	assert.Equal(t, int64(900), HeapBudget())
+
+	a := NewMemoryAccount(map[string]int64{MemoryHeap: HeapBudget()})
+	a.Set(MemoryHeap, 950)
+	err := a.Check(MemoryHeap, PriorityNormal)
+	assert.True(t, errors.Is(err, ErrOverloaded))
+	assert.Nil(t, a.Check(MemoryHeap, PriorityHigh))
+	a.Set(MemoryHeap, 800)
+	assert.Nil(t, a.Check(MemoryHeap, PriorityNormal))
+}
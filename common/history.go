@@ -0,0 +1,93 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+)
+
+// HistoryRetention is the number of past revisions kept per key.
+var HistoryRetention int
+
+// Revision is a past value of a key. Deleted revisions have no value, and
+// Evicted ones are deletions by the cache eviction rather than by a client.
+type Revision struct {
+	Rev     int64
+	Value   interface{}
+	Deleted bool
+	Evicted bool
+	// Version is the version of the key at Rev, 1 when the key was created.
+	Version int64
+}
+
+// History keeps the last revisions of every key in memory. Older revisions
+// are compacted away, as is the whole history when a snapshot is restored.
+type History struct {
+	mu    sync.RWMutex
+	limit int
+	keys  map[string][]Revision
+}
+
+// NewHistory returns a history keeping limit revisions per key.
+func NewHistory(limit int) *History {
+	return &History{limit: limit, keys: make(map[string][]Revision)}
+}
+
+// Record appends r to the history of key.
+func (h *History) Record(key string, r Revision) {
+	if h.limit <= 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	revs := append(h.keys[key], r)
+	if len(revs) > h.limit {
+		revs = revs[len(revs)-h.limit:]
+	}
+	h.keys[key] = revs
+}
+
+// At returns the revision of key that was current at rev.
+func (h *History) At(key string, rev int64) (Revision, error) {
+	r, ok, err := h.Lookup(key, rev)
+	if err != nil {
+		return Revision{}, fmt.Errorf("revision %d of Key=%s has been compacted", rev, key)
+	}
+	if !ok {
+		return Revision{}, fmt.Errorf("Key=%s does not exist at revision %d", key, rev)
+	}
+	return r, nil
+}
+
+// Lookup returns the revision of key that was current at rev, false if the
+// key did not exist then, or ErrCompacted if that revision is not retained.
+func (h *History) Lookup(key string, rev int64) (Revision, bool, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	revs := h.keys[key]
+	for i := len(revs) - 1; i >= 0; i-- {
+		if revs[i].Rev <= rev {
+			return revs[i], !revs[i].Deleted, nil
+		}
+	}
+	if len(revs) > 0 && revs[0].Version == 1 {
+		// the whole history is known, the key was created after rev
+		return Revision{}, false, nil
+	}
+	return Revision{}, false, fmt.Errorf("%w: revision %d of Key=%s", ErrCompacted, rev, key)
+}
+
+// List returns up to limit revisions of key, newest first. A limit of 0
+// returns all the retained revisions.
+func (h *History) List(key string, limit int) []Revision {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	revs := h.keys[key]
+	if limit <= 0 || limit > len(revs) {
+		limit = len(revs)
+	}
+	res := make([]Revision, 0, limit)
+	for i := len(revs) - 1; i >= len(revs)-limit; i-- {
+		res = append(res, revs[i])
+	}
+	return res
+}
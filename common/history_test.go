@@ -0,0 +1,35 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	h := NewHistory(3)
+	h.Record("a", Revision{Rev: 2, Value: int64(1), Version: 1})
+	h.Record("a", Revision{Rev: 5, Value: int64(2), Version: 2})
+
+	r, err := h.At("a", 4)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), r.Value)
+	_, err = h.At("a", 1)
+	assert.EqualError(t, err, "Key=a does not exist at revision 1")
+
+	h.Record("a", Revision{Rev: 7, Deleted: true})
+	h.Record("a", Revision{Rev: 9, Value: int64(3), Version: 1})
+	_, err = h.At("a", 8)
+	assert.EqualError(t, err, "Key=a does not exist at revision 8")
+	_, err = h.At("a", 3)
+	assert.EqualError(t, err, "revision 3 of Key=a has been compacted")
+	_, _, err = h.Lookup("a", 3)
+	assert.True(t, IsCompacted(err))
+	_, ok, err := h.Lookup("a", 8)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	revs := h.List("a", 2)
+	assert.Equal(t, []int64{9, 7}, []int64{revs[0].Rev, revs[1].Rev})
+	assert.Len(t, h.List("a", 0), 3)
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NodeIDFile is the file of a raft directory holding the id of its node.
+const NodeIDFile = "node-id"
+
+// NewNodeUUID returns a random node id built on a version 4 UUID.
+func NewNodeUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("node-%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// NodeIdentity returns the id of the node keeping its raft state in dir, so
+// that the id outlives the address of the node: id if set, else the id
+// stored in dir, generated and stored the first time. An id other than the
+// one stored is refused, as the state in dir belongs to another node.
+func NodeIdentity(dir, id string) (string, error) {
+	if InMemory {
+		if id == "" {
+			return NewNodeUUID()
+		}
+		return id, nil
+	}
+	path := filepath.Join(dir, NodeIDFile)
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		stored := strings.TrimSpace(string(b))
+		if id != "" && id != stored {
+			return "", fmt.Errorf("%s belongs to node %s, not %s", dir, stored, id)
+		}
+		return stored, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	if id == "" {
+		if id, err = NewNodeUUID(); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	// written aside first, a partial id is never read back
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(id+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return id, os.Rename(tmp, path)
+}
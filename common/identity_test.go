@@ -0,0 +1,38 @@
+package common
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "identity")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	// generated once, then read back
+	id, err := NodeIdentity(filepath.Join(dir, "a"), "")
+	assert.Nil(t, err)
+	assert.True(t, regexp.MustCompile(`^node-[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id), id)
+	again, err := NodeIdentity(filepath.Join(dir, "a"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, id, again)
+	again, err = NodeIdentity(filepath.Join(dir, "a"), id)
+	assert.Nil(t, err)
+	assert.Equal(t, id, again)
+
+	// an id given is stored, and another one refused
+	id, err = NodeIdentity(filepath.Join(dir, "b"), "node-b")
+	assert.Nil(t, err)
+	assert.Equal(t, "node-b", id)
+	_, err = NodeIdentity(filepath.Join(dir, "b"), "node-c")
+	assert.NotNil(t, err)
+	id, err = NodeIdentity(filepath.Join(dir, "b"), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "node-b", id)
+}
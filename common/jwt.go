@@ -0,0 +1,249 @@
+package common
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWKSRefreshInterval bounds how often the JWKS is fetched again for a key
+// id it does not hold, as when the keys are rotated.
+var JWKSRefreshInterval = time.Minute
+
+// JWTAuth authenticates the bearer tokens that are JWTs signed with RS256 or
+// ES256 by a key of a JWKS, as issued by OIDC providers. The sub claim is
+// the name of the identity, and the groups claim its groups.
+type JWTAuth struct {
+	jwksURL  string
+	issuer   string
+	audience string
+	client   *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWTAuth returns the authenticator of the JWTs signed by the keys at
+// jwksURL, with the iss and aud claims issuer and audience if not empty.
+func NewJWTAuth(jwksURL, issuer, audience string) *JWTAuth {
+	return &JWTAuth{jwksURL: jwksURL, issuer: issuer, audience: audience, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	Groups    []string        `json:"groups"`
+}
+
+// Authenticate returns the identity of the JWT bearer token of r, nil if r
+// has no token or one that is not a JWT.
+func (a *JWTAuth) Authenticate(r *http.Request) (*Identity, error) {
+	token := bearerToken(r)
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT signature encoding: %s", err)
+	}
+	key, err := a.key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWT(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, err
+	}
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := a.validate(&claims, time.Now()); err != nil {
+		return nil, err
+	}
+	return &Identity{Name: claims.Subject, Groups: claims.Groups}, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("invalid JWT encoding: %s", err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("invalid JWT: %s", err)
+	}
+	return nil
+}
+
+// verifyJWT verifies the signature sig of signed by key with alg.
+func verifyJWT(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	hash := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("JWT key is not an RSA key")
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hash[:], sig); err != nil {
+			return errors.New("invalid JWT signature")
+		}
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(sig) != 64 {
+			return errors.New("JWT key is not a P-256 key")
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, hash[:], r, s) {
+			return errors.New("invalid JWT signature")
+		}
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", alg)
+	}
+	return nil
+}
+
+// validate checks the registered claims of c at now.
+func (a *JWTAuth) validate(c *jwtClaims, now time.Time) error {
+	t := float64(now.Unix())
+	switch {
+	case c.Subject == "":
+		return errors.New("JWT without subject")
+	case c.ExpiresAt == nil || t >= *c.ExpiresAt:
+		return errors.New("JWT expired")
+	case c.NotBefore != nil && t < *c.NotBefore:
+		return errors.New("JWT not valid yet")
+	case a.issuer != "" && c.Issuer != a.issuer:
+		return fmt.Errorf("JWT issued by %q", c.Issuer)
+	}
+	if a.audience == "" {
+		return nil
+	}
+	// aud is a string or an array of strings
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil && one == a.audience {
+		return nil
+	}
+	var many []string
+	if json.Unmarshal(c.Audience, &many) == nil {
+		for _, aud := range many {
+			if aud == a.audience {
+				return nil
+			}
+		}
+	}
+	return errors.New("JWT not intended for this audience")
+}
+
+// key returns the key kid of the JWKS, fetched again if unknown.
+func (a *JWTAuth) key(kid string) (crypto.PublicKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(a.fetched) < JWKSRefreshInterval {
+		return nil, fmt.Errorf("unknown JWT key %q", kid)
+	}
+	a.fetched = time.Now()
+	keys, err := a.fetchKeys()
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch the JWKS: %s", err)
+	}
+	a.keys = keys
+	if key, ok := a.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown JWT key %q", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetchKeys fetches the RSA and P-256 keys of the JWKS, by id.
+func (a *JWTAuth) fetchKeys() (map[string]crypto.PublicKey, error) {
+	resp, err := a.client.Get(a.jwksURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS replied %d", resp.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k *jwk) publicKey() (crypto.PublicKey, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch {
+	case k.Kty == "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case k.Kty == "EC" && k.Crv == "P-256":
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
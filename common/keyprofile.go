@@ -0,0 +1,208 @@
+package common
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxProfilePrefixes bounds the prefixes tracked by a key profile; the
+// samples of the prefixes seen after are counted under OtherPrefix.
+const MaxProfilePrefixes = 10000
+
+// OtherPrefix stands for the prefixes over MaxProfilePrefixes in key profiles.
+const OtherPrefix = "*"
+
+// PrefixLoad is the load of the keys of a prefix in a key profile. The counts
+// are of the sampled requests, the rates are estimated from them.
+type PrefixLoad struct {
+	Prefix              string  `json:"prefix"`
+	Reads               int64   `json:"reads"`
+	Writes              int64   `json:"writes"`
+	ReadBytes           int64   `json:"read_bytes"`
+	WriteBytes          int64   `json:"write_bytes"`
+	ReadsPerSecond      float64 `json:"reads_per_second"`
+	WritesPerSecond     float64 `json:"writes_per_second"`
+	ReadBytesPerSecond  float64 `json:"read_bytes_per_second"`
+	WriteBytesPerSecond float64 `json:"write_bytes_per_second"`
+}
+
+// KeyProfileReport is the load of the key prefixes measured by a key profile,
+// the busiest prefixes first.
+type KeyProfileReport struct {
+	Running    bool          `json:"running"`
+	Started    time.Time     `json:"started,omitempty"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Window     time.Duration `json:"window"`
+	SampleRate float64       `json:"sample_rate"`
+	Depth      int           `json:"depth"`
+	Prefixes   []*PrefixLoad `json:"prefixes"`
+}
+
+// KeyProfile samples the reads and writes of the keys by prefix, over a
+// window started at runtime, to size the namespaces of a cluster before
+// splitting them into shards. It costs an atomic load per request when not
+// running.
+type KeyProfile struct {
+	running int32
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	rate     float64
+	depth    int
+	started  time.Time
+	stopped  time.Time
+	window   time.Duration
+	prefixes map[string]*PrefixLoad
+}
+
+// NewKeyProfile returns a key profile, not running.
+func NewKeyProfile() *KeyProfile {
+	return &KeyProfile{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Start starts a new profile sampling the fraction rate of the requests, by
+// the first depth segments of their keys, for window. The previous profile is
+// discarded.
+func (p *KeyProfile) Start(window time.Duration, rate float64, depth int) error {
+	if window <= 0 {
+		return fmt.Errorf("invalid profile duration %s", window)
+	}
+	if rate <= 0 || rate > 1 {
+		return fmt.Errorf("invalid sample rate %g, expected a fraction in (0, 1]", rate)
+	}
+	if depth < 1 {
+		return fmt.Errorf("invalid prefix depth %d", depth)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rate, p.depth, p.window = rate, depth, window
+	p.started, p.stopped = time.Now(), time.Time{}
+	p.prefixes = make(map[string]*PrefixLoad)
+	atomic.StoreInt32(&p.running, 1)
+	return nil
+}
+
+// Stop ends the running profile, whose report is kept.
+func (p *KeyProfile) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stop(time.Now())
+}
+
+// stop ends the running profile at t, with p locked.
+func (p *KeyProfile) stop(t time.Time) {
+	if atomic.LoadInt32(&p.running) == 1 {
+		atomic.StoreInt32(&p.running, 0)
+		p.stopped = t
+	}
+}
+
+// Read records a read of key returning n bytes.
+func (p *KeyProfile) Read(key string, n int64) {
+	p.record(key, n, false)
+}
+
+// Write records a write of n bytes to key.
+func (p *KeyProfile) Write(key string, n int64) {
+	p.record(key, n, true)
+}
+
+func (p *KeyProfile) record(key string, n int64, write bool) {
+	if p == nil || atomic.LoadInt32(&p.running) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if now := time.Now(); now.Sub(p.started) >= p.window {
+		p.stop(p.started.Add(p.window))
+		return
+	}
+	if p.rate < 1 && p.rand.Float64() >= p.rate {
+		return
+	}
+	prefix := KeyPrefix(key, p.depth)
+	l, ok := p.prefixes[prefix]
+	if !ok {
+		if len(p.prefixes) >= MaxProfilePrefixes {
+			prefix = OtherPrefix
+		}
+		if l, ok = p.prefixes[prefix]; !ok {
+			l = &PrefixLoad{Prefix: prefix}
+			p.prefixes[prefix] = l
+		}
+	}
+	if write {
+		l.Writes++
+		l.WriteBytes += n
+	} else {
+		l.Reads++
+		l.ReadBytes += n
+	}
+}
+
+// Report returns the load measured by the running profile, or by the last one.
+func (p *KeyProfile) Report() *KeyProfileReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if atomic.LoadInt32(&p.running) == 1 && now.Sub(p.started) >= p.window {
+		p.stop(p.started.Add(p.window))
+	}
+	report := &KeyProfileReport{
+		Running:    atomic.LoadInt32(&p.running) == 1,
+		Started:    p.started,
+		Window:     p.window,
+		SampleRate: p.rate,
+		Depth:      p.depth,
+		Prefixes:   []*PrefixLoad{},
+	}
+	if p.started.IsZero() {
+		return report
+	}
+	end := p.stopped
+	if report.Running {
+		end = now
+	}
+	report.Elapsed = end.Sub(p.started)
+	// the rates of the requests, estimated from the samples
+	scale := 1 / p.rate
+	if seconds := report.Elapsed.Seconds(); seconds > 0 {
+		scale /= seconds
+	}
+	for _, l := range p.prefixes {
+		load := *l
+		load.ReadsPerSecond = float64(l.Reads) * scale
+		load.WritesPerSecond = float64(l.Writes) * scale
+		load.ReadBytesPerSecond = float64(l.ReadBytes) * scale
+		load.WriteBytesPerSecond = float64(l.WriteBytes) * scale
+		report.Prefixes = append(report.Prefixes, &load)
+	}
+	sort.Slice(report.Prefixes, func(i, j int) bool {
+		a, b := report.Prefixes[i], report.Prefixes[j]
+		if a.Reads+a.Writes != b.Reads+b.Writes {
+			return a.Reads+a.Writes > b.Reads+b.Writes
+		}
+		return a.Prefix < b.Prefix
+	})
+	return report
+}
+
+// KeyPrefix returns the first depth segments of key, separated by
+// NamespaceSeparator, with their trailing separator. The last segment of a key
+// is its name, not part of its prefix: keys without a separator have an empty
+// prefix.
+func KeyPrefix(key string, depth int) string {
+	segments := strings.Split(key, NamespaceSeparator)
+	if n := len(segments) - 1; depth > n {
+		depth = n
+	}
+	if depth <= 0 {
+		return ""
+	}
+	return strings.Join(segments[:depth], NamespaceSeparator) + NamespaceSeparator
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	assert.Equal(t, "", KeyPrefix("key", 1))
+	assert.Equal(t, "users/", KeyPrefix("users/42", 1))
+	assert.Equal(t, "users/", KeyPrefix("users/42", 2))
+	assert.Equal(t, "users/eu/", KeyPrefix("users/eu/42", 2))
+	assert.Equal(t, "users/", KeyPrefix("users/eu/42", 1))
+}
+
+func TestKeyProfile(t *testing.T) {
+	p := NewKeyProfile()
+	// not running, nothing is recorded
+	p.Write("users/1", 10)
+	assert.Empty(t, p.Report().Prefixes)
+	assert.False(t, p.Report().Running)
+
+	assert.Error(t, p.Start(0, 1, 1))
+	assert.Error(t, p.Start(time.Minute, 0, 1))
+	assert.Error(t, p.Start(time.Minute, 1.5, 1))
+	assert.Error(t, p.Start(time.Minute, 1, 0))
+
+	assert.NoError(t, p.Start(time.Minute, 1, 1))
+	p.Write("users/1", 10)
+	p.Write("users/2", 20)
+	p.Read("users/1", 10)
+	p.Read("orders/1", 5)
+	p.Read("key", 3)
+	p.Stop()
+	p.Write("users/3", 10)
+
+	report := p.Report()
+	assert.False(t, report.Running)
+	assert.Equal(t, 1, report.Depth)
+	if assert.Len(t, report.Prefixes, 3) {
+		users := report.Prefixes[0]
+		assert.Equal(t, "users/", users.Prefix)
+		assert.Equal(t, int64(1), users.Reads)
+		assert.Equal(t, int64(2), users.Writes)
+		assert.Equal(t, int64(30), users.WriteBytes)
+		assert.True(t, users.WritesPerSecond > users.ReadsPerSecond)
+		assert.Equal(t, "", report.Prefixes[1].Prefix)
+		assert.Equal(t, "orders/", report.Prefixes[2].Prefix)
+	}
+
+	// the profile stops at the end of its window
+	assert.NoError(t, p.Start(10*time.Millisecond, 1, 1))
+	time.Sleep(20 * time.Millisecond)
+	p.Write("users/1", 10)
+	report = p.Report()
+	assert.False(t, report.Running)
+	assert.Empty(t, report.Prefixes)
+	assert.Equal(t, 10*time.Millisecond, report.Elapsed)
+}
@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SystemPrefix is reserved for the internal metadata, such as the access
+// policy. Clients cannot write keys under it.
+const SystemPrefix = "__system/"
+
+// Key naming rules of the client writes.
+var (
+	// KeyCharset are the characters allowed in the keys written by clients,
+	// as the content of a regexp character class such as a-zA-Z0-9_./-, any
+	// if empty.
+	KeyCharset string
+	// KeyMaxDepth is the most segments, separated by NamespaceSeparator, of
+	// the keys written by clients, unlimited if 0.
+	KeyMaxDepth int
+
+	keyCharset *regexp.Regexp
+)
+
+// Errors wrapped by the KeyErrors of the keys clients cannot write.
+var (
+	ErrInvalidKey  = errors.New("invalid key")
+	ErrReservedKey = errors.New("reserved key")
+)
+
+// KeyError is the error of a key clients cannot write.
+type KeyError struct {
+	Key string
+	// Err is ErrInvalidKey or ErrReservedKey.
+	Err    error
+	Reason string
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Err, e.Key, e.Reason)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// CompileKeyCharset compiles KeyCharset, once set.
+func CompileKeyCharset() error {
+	if KeyCharset == "" {
+		keyCharset = nil
+		return nil
+	}
+	re, err := regexp.Compile("^[" + KeyCharset + "]*$")
+	if err != nil {
+		return fmt.Errorf("invalid key charset %q: %s", KeyCharset, err)
+	}
+	keyCharset = re
+	return nil
+}
+
+// ValidateKey returns a KeyError if clients cannot write key with method:
+// keys under SystemPrefix cannot be written, and the keys set must follow
+// KeyCharset and KeyMaxDepth. Keys set before the rules changed can still be
+// deleted.
+func ValidateKey(method, key string) error {
+	if strings.HasPrefix(key, SystemPrefix) {
+		return &KeyError{Key: key, Err: ErrReservedKey, Reason: "the " + SystemPrefix + " prefix is reserved"}
+	}
+	if method == DEL || method == GETDEL {
+		return nil
+	}
+	if keyCharset != nil && !keyCharset.MatchString(key) {
+		return &KeyError{Key: key, Err: ErrInvalidKey, Reason: fmt.Sprintf("characters out of [%s]", KeyCharset)}
+	}
+	if KeyMaxDepth > 0 {
+		if depth := strings.Count(key, NamespaceSeparator) + 1; depth > KeyMaxDepth {
+			return &KeyError{Key: key, Err: ErrInvalidKey, Reason: fmt.Sprintf("%d segments, more than %d", depth, KeyMaxDepth)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateKey(t *testing.T) {
+	defer func() {
+		KeyCharset, KeyMaxDepth = "", 0
+		CompileKeyCharset()
+	}()
+	assert.Nil(t, ValidateKey(SET, "any key/at/all:depths"))
+	assert.True(t, errors.Is(ValidateKey(SET, SystemPrefix+"a"), ErrReservedKey))
+	assert.True(t, errors.Is(ValidateKey(DEL, SystemPrefix+"a"), ErrReservedKey))
+
+	KeyCharset = `\`
+	assert.NotNil(t, CompileKeyCharset())
+	KeyCharset, KeyMaxDepth = "a-z/", 2
+	assert.Nil(t, CompileKeyCharset())
+	assert.Nil(t, ValidateKey(SET, "app/key"))
+
+	err := ValidateKey(SET, "app/Key")
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+	var keyErr *KeyError
+	if assert.True(t, errors.As(err, &keyErr)) {
+		assert.Equal(t, "app/Key", keyErr.Key)
+	}
+	assert.True(t, errors.Is(ValidateKey(GETSET, "app/a/b"), ErrInvalidKey))
+
+	// keys set before the rules changed can be deleted
+	assert.Nil(t, ValidateKey(DEL, "app/a/B"))
+}
@@ -0,0 +1,122 @@
+package common
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ValueSizeBounds are the upper bounds, in bytes, of the buckets of the value
+// size histograms, the last bucket holding the larger values.
+var ValueSizeBounds = []int64{64, 256, 1 << 10, 4 << 10, 16 << 10, 64 << 10, 256 << 10, 1 << 20}
+
+// HotPrefixes is the number of namespaces, with the most writes, in the
+// statistics of the keys.
+const HotPrefixes = 10
+
+// KeyStats are the statistics of the committed keys of a Cmap, kept up to date
+// by its writes rather than computed by scanning the keys.
+type KeyStats struct {
+	mu    sync.Mutex
+	keys  int64
+	bytes int64
+	sizes []int64
+	// prefixes are the statistics of the namespaces holding keys
+	prefixes map[string]*raftpb.NamespaceUsage
+}
+
+// NewKeyStats returns the statistics of no keys.
+func NewKeyStats() *KeyStats {
+	return &KeyStats{
+		sizes:    make([]int64, len(ValueSizeBounds)+1),
+		prefixes: make(map[string]*raftpb.NamespaceUsage),
+	}
+}
+
+// sizeBucket returns the histogram bucket of the values of size n.
+func sizeBucket(n int64) int {
+	return sort.Search(len(ValueSizeBounds), func(i int) bool { return ValueSizeBounds[i] >= n })
+}
+
+// count adds key k with value v to the statistics, or removes it if n is -1,
+// with s locked.
+func (s *KeyStats) count(k string, v interface{}, n int64) {
+	s.keys += n
+	s.bytes += n * entrySize(k, v)
+	s.sizes[sizeBucket(ValueSize(v))] += n
+	ns := Namespace(k)
+	u, ok := s.prefixes[ns]
+	if !ok {
+		u = &raftpb.NamespaceUsage{Namespace: ns}
+		s.prefixes[ns] = u
+	}
+	u.Keys += n
+	u.Bytes += n * entrySize(k, v)
+}
+
+// added counts the write of k, a new key with value v.
+func (s *KeyStats) added(k string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count(k, v, 1)
+	s.prefixes[Namespace(k)].Writes++
+}
+
+// replaced counts the write of v to k, whose value was old.
+func (s *KeyStats) replaced(k string, old, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count(k, old, -1)
+	s.count(k, v, 1)
+	s.prefixes[Namespace(k)].Writes++
+}
+
+// removed counts the deletion of k, whose value was v.
+func (s *KeyStats) removed(k string, v interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count(k, v, -1)
+	ns := Namespace(k)
+	if u := s.prefixes[ns]; u.Keys == 0 {
+		delete(s.prefixes, ns)
+	} else {
+		u.Writes++
+	}
+}
+
+// Stats returns the statistics, with the HotPrefixes namespaces with the
+// most writes since the keys were loaded.
+func (s *KeyStats) Stats() *raftpb.ShardStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := &raftpb.ShardStats{
+		Keys:       s.keys,
+		Bytes:      s.bytes,
+		ValueSizes: append([]int64(nil), s.sizes...),
+	}
+	for _, u := range s.prefixes {
+		res.Prefixes = append(res.Prefixes, &raftpb.NamespaceUsage{
+			Namespace: u.Namespace,
+			Keys:      u.Keys,
+			Bytes:     u.Bytes,
+			Writes:    u.Writes,
+		})
+	}
+	sort.Slice(res.Prefixes, func(i, j int) bool {
+		a, b := res.Prefixes[i], res.Prefixes[j]
+		if a.Writes != b.Writes {
+			return a.Writes > b.Writes
+		}
+		return a.Namespace < b.Namespace
+	})
+	if len(res.Prefixes) > HotPrefixes {
+		res.Prefixes = res.Prefixes[:HotPrefixes]
+	}
+	return res
+}
+
+// KeyStats returns the statistics of the committed keys of the map.
+func (c *Cmap) KeyStats() *raftpb.ShardStats {
+	return c.keyStats.Stats()
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyStats(t *testing.T) {
+	m := NewCmapFromMap(log.New(), map[string]interface{}{"a/1": int64(1)}, 0)
+	m.SetRev("a/2", &Blob{Codec: "raw", Data: make([]byte, 100)}, nil, 1)
+	m.SetRev("a/2", int64(2), nil, 2)
+	m.SetRev("b/1", int64(3), nil, 3)
+
+	// pending new keys of transactions only count once committed
+	ops := []*raftpb.Command{{Method: SET, Key: "c/1", Value: 4}}
+	assert.Nil(t, m.TryLocks(ops, ""))
+	assert.Equal(t, int64(3), m.KeyStats().Keys)
+	m.WriteWithLocks(ops)
+
+	m.Write([]*raftpb.Command{{Method: DEL, Key: "b/1"}}, 4)
+	m.Del("missing")
+
+	stats := m.KeyStats()
+	assert.Equal(t, int64(3), stats.Keys)
+	assert.Equal(t, int64(len("a/1")+len("a/2")+len("c/1")+3*8), stats.Bytes)
+	assert.Equal(t, int64(3), stats.ValueSizes[0])
+	assert.Equal(t, len(ValueSizeBounds)+1, len(stats.ValueSizes))
+	// the namespaces without keys are dropped, the others by writes
+	if assert.Equal(t, 2, len(stats.Prefixes)) {
+		assert.Equal(t, &raftpb.NamespaceUsage{Namespace: "a", Keys: 2, Bytes: 22, Writes: 2}, stats.Prefixes[0])
+		assert.Equal(t, "c", stats.Prefixes[1].Namespace)
+	}
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// StatefulSet is the identity of a pod of a Kubernetes StatefulSet, derived
+// from its host name <set>-<ordinal>. It survives the pod being rescheduled,
+// its IP address does not.
+type StatefulSet struct {
+	Name    string
+	Ordinal int
+	// Service is the headless service governing the set, giving every pod
+	// the DNS name <set>-<ordinal>.<service>.
+	Service string
+}
+
+// ParseStatefulSet returns the identity of the pod of the StatefulSet
+// governed by service with the host name hostname.
+func ParseStatefulSet(hostname, service string) (*StatefulSet, error) {
+	i := strings.LastIndex(hostname, "-")
+	if i <= 0 {
+		return nil, fmt.Errorf("host name %q is not <statefulset>-<ordinal>", hostname)
+	}
+	ordinal, err := strconv.Atoi(hostname[i+1:])
+	if err != nil || ordinal < 0 {
+		return nil, fmt.Errorf("host name %q is not <statefulset>-<ordinal>", hostname)
+	}
+	return &StatefulSet{Name: hostname[:i], Ordinal: ordinal, Service: service}, nil
+}
+
+// ID returns the node id of the pod, its host name.
+func (s *StatefulSet) ID() string {
+	return fmt.Sprintf("%s-%d", s.Name, s.Ordinal)
+}
+
+// Host returns the DNS name of the pod of ordinal.
+func (s *StatefulSet) Host(ordinal int) string {
+	return fmt.Sprintf("%s-%d.%s", s.Name, ordinal, s.Service)
+}
+
+// Peers returns the addresses at port of the other pods of the set of
+// replicas pods.
+func (s *StatefulSet) Peers(replicas int, port string) []string {
+	var peers []string
+	for i := 0; i < replicas; i++ {
+		if i != s.Ordinal {
+			peers = append(peers, net.JoinHostPort(s.Host(i), port))
+		}
+	}
+	return peers
+}
+
+// Join returns the comma separated addresses of the pods to join, tried in
+// turn, or an empty address if the pod bootstraps the raft group: the first
+// pod does when no other pod of the set accepts connections. The others,
+// and the first one once the group runs, join it again whenever they start,
+// which updates their address if they were rescheduled.
+func (s *StatefulSet) Join(replicas int, port string) string {
+	peers := s.Peers(replicas, port)
+	if s.Ordinal == 0 {
+		up := false
+		for _, addr := range peers {
+			if reachable(addr) {
+				up = true
+				break
+			}
+		}
+		if !up {
+			return ""
+		}
+	}
+	return strings.Join(peers, ",")
+}
+
+// PodAddress returns addr with the IP address of the pod for a missing or
+// unspecified host, raft advertising the address its peers dial.
+func PodAddress(addr, hostname string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(host); host != "" && (ip == nil || !ip.IsUnspecified()) {
+		return addr, nil
+	}
+	ips, err := net.LookupIP(hostname)
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if !ip.IsLoopback() {
+			return net.JoinHostPort(ip.String(), port), nil
+		}
+	}
+	return "", fmt.Errorf("no address of %s to advertise", hostname)
+}
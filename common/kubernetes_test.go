@@ -0,0 +1,42 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatefulSet(t *testing.T) {
+	s, err := ParseStatefulSet("raft-kv-store-2", "raftkv")
+	assert.Nil(t, err)
+	assert.Equal(t, &StatefulSet{Name: "raft-kv-store", Ordinal: 2, Service: "raftkv"}, s)
+	assert.Equal(t, "raft-kv-store-2", s.ID())
+	assert.Equal(t, "raft-kv-store-0.raftkv", s.Host(0))
+	assert.Equal(t, []string{"raft-kv-store-0.raftkv:17000", "raft-kv-store-1.raftkv:17000"}, s.Peers(3, "17000"))
+
+	for _, h := range []string{"store", "store-a", "-1"} {
+		_, err := ParseStatefulSet(h, "raftkv")
+		assert.NotNil(t, err, h)
+	}
+}
+
+func TestStatefulSetJoin(t *testing.T) {
+	// the first pod bootstraps the group unless another pod is up
+	first := &StatefulSet{Name: "store", Ordinal: 0, Service: "invalid"}
+	assert.Equal(t, "", first.Join(2, "1"))
+	second := &StatefulSet{Name: "store", Ordinal: 1, Service: "raftkv"}
+	assert.Equal(t, "store-0.raftkv:1,store-2.raftkv:1", second.Join(3, "1"))
+}
+
+func TestPodAddress(t *testing.T) {
+	addr, err := PodAddress("10.0.0.1:12000", "localhost")
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1:12000", addr)
+
+	// loopback addresses are not advertised
+	_, err = PodAddress(":12000", "127.0.0.1")
+	assert.NotNil(t, err)
+	addr, err = PodAddress("0.0.0.0:12000", "192.0.2.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "192.0.2.1:12000", addr)
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// LockError is the error of a read or write of a key whose lock is held for
+// longer than the lock timeout, describing the holder of the lock, if known,
+// and when to try again. It crosses rpc in the lock of the reply, see
+// LockProto; its message is for display only.
+type LockError struct {
+	Key string
+	// Holder is the transaction holding the lock, empty for a single write.
+	Holder string
+	// Node is the store node the lock is held on.
+	Node string
+	// HeldFor is how long Holder has held the lock.
+	HeldFor time.Duration
+	// RetryAfter is the estimated wait until the lock is released.
+	RetryAfter time.Duration
+}
+
+func (e *LockError) Error() string {
+	var details []string
+	if e.Holder != "" {
+		details = append(details, "held by txn "+e.Holder)
+	}
+	if e.Node != "" {
+		details = append(details, "on node "+e.Node)
+	}
+	if e.HeldFor > 0 {
+		details = append(details, "for "+e.HeldFor.String())
+	}
+	if e.RetryAfter > 0 {
+		details = append(details, "retry after "+e.RetryAfter.String())
+	}
+	if len(details) == 0 {
+		return fmt.Sprintf("map is locked on Key=%s", e.Key)
+	}
+	return fmt.Sprintf("map is locked on Key=%s (%s)", e.Key, strings.Join(details, " "))
+}
+
+// LockProto returns e as the lock of an rpc reply.
+func LockProto(e *LockError) *raftpb.LockError {
+	return &raftpb.LockError{
+		Key:          e.Key,
+		Holder:       e.Holder,
+		Node:         e.Node,
+		HeldForMs:    int64(e.HeldFor / time.Millisecond),
+		RetryAfterMs: int64(e.RetryAfter / time.Millisecond),
+	}
+}
+
+// ReplyLock returns the lock error replied in reply, nil if there is none.
+func ReplyLock(reply *raftpb.RPCResponse) *LockError {
+	l := reply.GetLock()
+	if l == nil {
+		return nil
+	}
+	return &LockError{
+		Key:        l.Key,
+		Holder:     l.Holder,
+		Node:       l.Node,
+		HeldFor:    time.Duration(l.HeldForMs) * time.Millisecond,
+		RetryAfter: time.Duration(l.RetryAfterMs) * time.Millisecond,
+	}
+}
+
+// lockError returns the error of a lock of k held in value for longer than
+// the lock timeout, with the map locked.
+func (c *Cmap) lockError(k string, value *Value) *LockError {
+	e := &LockError{Key: k, Node: c.node, RetryAfter: ConflictBackoff}
+	if value.txid == "" {
+		return e
+	}
+	e.Holder = value.txid
+	if value.lockedAt > 0 {
+		e.HeldFor = time.Duration(time.Now().UnixNano() - value.lockedAt).Round(time.Millisecond)
+	}
+	// transactions usually release their locks after the average hold
+	if left := c.stats.averageHold() - e.HeldFor; left > e.RetryAfter {
+		e.RetryAfter = left.Round(time.Millisecond)
+	}
+	return e
+}
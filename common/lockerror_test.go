@@ -0,0 +1,54 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockError_Holder(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	m.SetNode("node0")
+	m.Set("a", int64(1))
+	assert.Nil(t, m.TryLocks([]*raftpb.Command{{Method: SET, Key: "a", Value: 2}}, "tx1"))
+
+	_, _, err := m.Get("a")
+	lock, ok := err.(*LockError)
+	assert.True(t, ok)
+	assert.Equal(t, "a", lock.Key)
+	assert.Equal(t, "tx1", lock.Holder)
+	assert.Equal(t, "node0", lock.Node)
+	assert.Equal(t, ConflictBackoff, lock.RetryAfter)
+
+	// the lock is expected to be released after the average hold
+	m.SetLockStats(&LockStats{holds: 1, holdNanos: int64(time.Second)})
+	_, _, err = m.Get("a")
+	lock = err.(*LockError)
+	assert.True(t, lock.RetryAfter > ConflictBackoff && lock.RetryAfter <= time.Second-lock.HeldFor)
+}
+
+func TestReplyLock(t *testing.T) {
+	for _, e := range []*LockError{
+		{Key: "ns/a b", Holder: "tx1", Node: "node0", HeldFor: 1200 * time.Millisecond, RetryAfter: 300 * time.Millisecond},
+		{Key: "a", Node: "node0", RetryAfter: ConflictBackoff},
+		{Key: "a"},
+	} {
+		// as replied over rpc
+		b, err := proto.Marshal(&raftpb.RPCResponse{Status: -1, Lock: LockProto(e)})
+		assert.Nil(t, err)
+		reply := &raftpb.RPCResponse{}
+		assert.Nil(t, proto.Unmarshal(b, reply))
+		assert.Equal(t, e, ReplyLock(reply))
+
+		var lock *LockError
+		assert.True(t, errors.As(fmt.Errorf("Unable to set: %w", ReplyLock(reply)), &lock))
+		assert.Equal(t, e, lock)
+	}
+	assert.Nil(t, ReplyLock(&raftpb.RPCResponse{}))
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Lock contention metrics of the key-value maps.
+const (
+	LockTimeoutsMetric = "raftkv_cmap_lock_timeouts_total"
+	LockWaitsMetric    = "raftkv_cmap_lock_waits_total"
+	LockWaitTimeMetric = "raftkv_cmap_lock_wait_seconds_total"
+	LockAvgWaitMetric  = "raftkv_cmap_lock_wait_average_seconds"
+	LockHeldMetric     = "raftkv_cmap_key_locks_held"
+	LockTimeoutMetric  = "raftkv_cmap_lock_timeout_seconds"
+)
+
+const (
+	lockScopeGlobal = "global"
+	lockScopeKey    = "key"
+	// heldLocksTimeout bounds the wait to count the held key locks.
+	heldLocksTimeout = 100 * time.Millisecond
+)
+
+// LockStats counts the lock timeouts and waits of a Cmap, to tune
+// LockContention. It outlives the maps replaced by snapshot restores. A nil
+// LockStats counts nothing.
+type LockStats struct {
+	globalTimeouts int64
+	keyTimeouts    int64
+	waits          int64
+	waitNanos      int64
+	holds          int64
+	holdNanos      int64
+}
+
+// LockStatsSnapshot is the content of LockStats at a point in time.
+type LockStatsSnapshot struct {
+	GlobalTimeouts int64         `json:"global_timeouts"`
+	KeyTimeouts    int64         `json:"key_timeouts"`
+	Waits          int64         `json:"waits"`
+	AverageWait    time.Duration `json:"average_wait"`
+	// AverageHold is how long transactions hold their key locks, on average.
+	AverageHold time.Duration `json:"average_hold"`
+	// HeldLocks is the number of keys locked by pending transactions, -1
+	// if unknown.
+	HeldLocks int `json:"held_locks"`
+	// Timeout is the current lock timeout of single key reads and writes.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// NewLockStats returns zeroed lock stats.
+func NewLockStats() *LockStats {
+	return &LockStats{}
+}
+
+func (s *LockStats) globalTimeout() {
+	if s != nil {
+		atomic.AddInt64(&s.globalTimeouts, 1)
+	}
+}
+
+func (s *LockStats) keyTimeout() {
+	if s != nil {
+		atomic.AddInt64(&s.keyTimeouts, 1)
+	}
+}
+
+// observeWait records a lock acquired after waiting since start.
+func (s *LockStats) observeWait(start time.Time) {
+	if s != nil {
+		atomic.AddInt64(&s.waits, 1)
+		atomic.AddInt64(&s.waitNanos, int64(time.Since(start)))
+	}
+}
+
+// observeHold records the release of the transaction lock of value.
+func (s *LockStats) observeHold(value *Value) {
+	if s != nil && value.txid != "" && value.lockedAt > 0 {
+		atomic.AddInt64(&s.holds, 1)
+		atomic.AddInt64(&s.holdNanos, time.Now().UnixNano()-value.lockedAt)
+	}
+}
+
+// averageHold returns how long transactions hold their key locks, on
+// average, 0 if unknown.
+func (s *LockStats) averageHold() time.Duration {
+	if s == nil {
+		return 0
+	}
+	holds := atomic.LoadInt64(&s.holds)
+	if holds == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&s.holdNanos) / holds)
+}
+
+// Snapshot returns the stats, with the number of key locks held in c.
+func (s *LockStats) Snapshot(c *Cmap) LockStatsSnapshot {
+	snap := LockStatsSnapshot{
+		GlobalTimeouts: atomic.LoadInt64(&s.globalTimeouts),
+		KeyTimeouts:    atomic.LoadInt64(&s.keyTimeouts),
+		Waits:          atomic.LoadInt64(&s.waits),
+		AverageHold:    s.averageHold(),
+		HeldLocks:      -1,
+		Timeout:        c.timeout.Get(),
+	}
+	if snap.Waits > 0 {
+		snap.AverageWait = time.Duration(atomic.LoadInt64(&s.waitNanos) / snap.Waits)
+	}
+	if locks, err := c.Locks(heldLocksTimeout); err == nil {
+		snap.HeldLocks = len(locks)
+	}
+	return snap
+}
+
+// RegisterLockMetrics registers the lock contention metrics in m.
+func RegisterLockMetrics(m *Metrics) {
+	m.Register(LockTimeoutsMetric, CounterMetric, "Lock attempts of the key-value map that timed out, by scope.")
+	m.Register(LockWaitsMetric, CounterMetric, "Key locks acquired by writes and transactions.")
+	m.Register(LockWaitTimeMetric, CounterMetric, "Time spent waiting for the key locks acquired.")
+	m.Register(LockAvgWaitMetric, GaugeMetric, "Average wait of the key locks acquired.")
+	m.Register(LockHeldMetric, GaugeMetric, "Keys locked by pending transactions.")
+	m.Register(LockTimeoutMetric, GaugeMetric, "Lock timeout of single key reads and writes.")
+}
+
+// SetMetrics sets the lock contention metrics of the map c in m.
+func (s *LockStats) SetMetrics(m *Metrics, c *Cmap) {
+	snap := s.Snapshot(c)
+	m.Set(LockTimeoutsMetric, float64(snap.GlobalTimeouts), "scope", lockScopeGlobal)
+	m.Set(LockTimeoutsMetric, float64(snap.KeyTimeouts), "scope", lockScopeKey)
+	m.Set(LockWaitsMetric, float64(snap.Waits))
+	m.Set(LockWaitTimeMetric, float64(atomic.LoadInt64(&s.waitNanos))/float64(time.Second))
+	m.Set(LockAvgWaitMetric, snap.AverageWait.Seconds())
+	m.Set(LockTimeoutMetric, snap.Timeout.Seconds())
+	if snap.HeldLocks >= 0 {
+		m.Set(LockHeldMetric, float64(snap.HeldLocks))
+	}
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockStats(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	stats := NewLockStats()
+	m.SetLockStats(stats)
+
+	assert.Nil(t, m.Set("a", 1))
+	assert.Nil(t, m.TryLocks([]*raftpb.Command{{Method: SET, Key: "a", Value: 2}}, "tx1"))
+	// the key is held by tx1
+	assert.NotNil(t, m.Set("a", 3))
+	assert.NotNil(t, m.TryLocks([]*raftpb.Command{{Method: SET, Key: "a", Value: 4}}, "tx2"))
+	m.mu.Lock()
+	_, _, err := m.Get("b")
+	m.mu.Unlock()
+	assert.NotNil(t, err)
+
+	snap := stats.Snapshot(m)
+	assert.Equal(t, int64(1), snap.GlobalTimeouts)
+	assert.Equal(t, int64(2), snap.KeyTimeouts)
+	assert.Equal(t, int64(1), snap.Waits)
+	assert.Equal(t, 1, snap.HeldLocks)
+
+	metrics := NewMetrics()
+	RegisterLockMetrics(metrics)
+	stats.SetMetrics(metrics, m)
+	var b strings.Builder
+	assert.Nil(t, metrics.Write(&b))
+	assert.Contains(t, b.String(), `raftkv_cmap_lock_timeouts_total{scope="key"} 2`)
+	assert.Contains(t, b.String(), "raftkv_cmap_key_locks_held 1")
+
+	// a map without stats counts nothing
+	assert.NotNil(t, NewCmap(log.New(), 0).errGlobalLock())
+}
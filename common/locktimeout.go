@@ -0,0 +1,95 @@
+package common
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// MaxLockTimeout caps the lock timeout of the single key reads and writes of
+// the key-value maps, which otherwise adapts to their recent lock waits. The
+// timeout stays at its floor if 0.
+var MaxLockTimeout = 10 * time.Millisecond
+
+const (
+	// lockWaitSamples is the number of recent lock waits the timeout is
+	// derived from.
+	lockWaitSamples = 512
+	// lockTimeoutUpdate is the number of waits between two updates of the
+	// timeout.
+	lockTimeoutUpdate = 32
+	// lockWaitPercentile is the percentile of the recent waits the timeout
+	// leaves room for, twice over.
+	lockWaitPercentile = 0.99
+)
+
+// LockTimeout is the lock timeout of the single key reads and writes of a
+// Cmap: twice the 99th percentile of its recent lock waits, between a floor
+// and MaxLockTimeout. A wait that timed out counts as long as the timeout, so
+// the timeout doubles while more than 1% of the waits time out, riding out
+// spikes of contention, and comes back down once they pass. Transactions
+// keep their own timeouts, spread by transaction id, which break the
+// deadlocks between them. The waits are recorded without a lock, and the
+// percentile is computed in the background, off the path of the waits.
+type LockTimeout struct {
+	// current is the timeout, in nanoseconds, read atomically.
+	current int64
+	// observed is the number of waits observed, the last lockWaitSamples
+	// of which are in waits, in nanoseconds, read and written atomically.
+	observed uint64
+	waits    [lockWaitSamples]int64
+	floor    time.Duration
+	// updating is set while the timeout is updated.
+	updating int32
+}
+
+// NewLockTimeout returns a lock timeout starting at floor.
+func NewLockTimeout(floor time.Duration) *LockTimeout {
+	return &LockTimeout{floor: floor, current: int64(floor)}
+}
+
+// Get returns the current timeout.
+func (t *LockTimeout) Get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.current))
+}
+
+// Observe records a lock acquired after waiting d, or a wait of d that
+// timed out. Every lockTimeoutUpdate waits, the timeout is updated in the
+// background unless an update is still running.
+func (t *LockTimeout) Observe(d time.Duration) {
+	if MaxLockTimeout <= t.floor {
+		return
+	}
+	n := atomic.AddUint64(&t.observed, 1)
+	atomic.StoreInt64(&t.waits[(n-1)%lockWaitSamples], int64(d))
+	if n%lockTimeoutUpdate == 0 && atomic.CompareAndSwapInt32(&t.updating, 0, 1) {
+		go func() {
+			t.update()
+			atomic.StoreInt32(&t.updating, 0)
+		}()
+	}
+}
+
+// update sets the timeout from the recent waits.
+func (t *LockTimeout) update() {
+	n := atomic.LoadUint64(&t.observed)
+	if n == 0 {
+		return
+	}
+	if n > lockWaitSamples {
+		n = lockWaitSamples
+	}
+	waits := make([]int64, n)
+	for i := range waits {
+		waits[i] = atomic.LoadInt64(&t.waits[i])
+	}
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	timeout := 2 * time.Duration(waits[int(float64(len(waits)-1)*lockWaitPercentile)])
+	if timeout < t.floor {
+		timeout = t.floor
+	}
+	if timeout > MaxLockTimeout {
+		timeout = MaxLockTimeout
+	}
+	atomic.StoreInt64(&t.current, int64(timeout))
+}
@@ -0,0 +1,103 @@
+package common
+
+import (
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// settle waits for the update of lt in the background, if any, and updates
+// it with the last waits observed.
+func settle(lt *LockTimeout) {
+	for !atomic.CompareAndSwapInt32(&lt.updating, 0, 1) {
+		runtime.Gosched()
+	}
+	lt.update()
+	atomic.StoreInt32(&lt.updating, 0)
+}
+
+func TestLockTimeout(t *testing.T) {
+	lt := NewLockTimeout(time.Microsecond)
+	assert.Equal(t, time.Microsecond, lt.Get())
+
+	// a spike of timeouts doubles the timeout up to the cap
+	for i := 0; i < 20; i++ {
+		for j := 0; j < lockTimeoutUpdate; j++ {
+			lt.Observe(lt.Get())
+		}
+		settle(lt)
+	}
+	assert.Equal(t, MaxLockTimeout, lt.Get())
+
+	// it comes back down to the floor once the waits are short again
+	for i := 0; i < lockWaitSamples; i++ {
+		lt.Observe(0)
+	}
+	settle(lt)
+	assert.Equal(t, time.Microsecond, lt.Get())
+
+	// a few slow waits are below the percentile
+	for i := 0; i < lockWaitSamples; i++ {
+		if i%100 == 0 {
+			lt.Observe(time.Millisecond)
+		} else {
+			lt.Observe(100 * time.Microsecond)
+		}
+	}
+	settle(lt)
+	assert.Equal(t, 200*time.Microsecond, lt.Get())
+
+	defer func(max time.Duration) { MaxLockTimeout = max }(MaxLockTimeout)
+	MaxLockTimeout = 0
+	fixed := NewLockTimeout(time.Microsecond)
+	for i := 0; i < lockWaitSamples; i++ {
+		fixed.Observe(time.Second)
+	}
+	assert.Equal(t, time.Microsecond, fixed.Get())
+}
+
+func TestLockTimeoutBackground(t *testing.T) {
+	lt := NewLockTimeout(time.Microsecond)
+	for i := 0; i < lockTimeoutUpdate; i++ {
+		lt.Observe(time.Millisecond)
+	}
+	// updated by the waits themselves, without settle
+	deadline := time.Now().Add(time.Second)
+	for lt.Get() != 2*time.Millisecond && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 2*time.Millisecond, lt.Get())
+}
+
+// BenchmarkLockTimeoutObserve observes waits from every processor at once,
+// as the single key reads and writes of a busy map do.
+func BenchmarkLockTimeoutObserve(b *testing.B) {
+	lt := NewLockTimeout(time.Microsecond)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			lt.Observe(time.Microsecond)
+		}
+	})
+}
+
+// BenchmarkCmapParallelGetSet reads and writes distinct keys of a map from
+// every processor at once, each operation observing its lock wait.
+func BenchmarkCmapParallelGetSet(b *testing.B) {
+	m := NewCmap(log.New(), time.Microsecond)
+	var worker int64
+	b.RunParallel(func(pb *testing.PB) {
+		k := strconv.FormatInt(atomic.AddInt64(&worker, 1), 10)
+		for i := 0; pb.Next(); i++ {
+			if i%2 == 0 {
+				m.Set(k, i)
+			} else {
+				m.Get(k)
+			}
+		}
+	})
+}
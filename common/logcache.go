@@ -0,0 +1,134 @@
+package common
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// RaftLogCacheMB is the size in MB of the cache of the recently appended
+// entries of each raft log, which serves the entries sent to the followers
+// catching up without reading the log file. No cache if 0.
+var RaftLogCacheMB int
+
+// logEntryOverhead is the size counted for an entry besides its data.
+const logEntryOverhead = 64
+
+// Log cache metrics, by raft group.
+const (
+	RaftLogCacheHitsMetric   = "raftkv_raft_log_cache_hits_total"
+	RaftLogCacheMissesMetric = "raftkv_raft_log_cache_misses_total"
+	RaftLogCacheSizeMetric   = "raftkv_raft_log_cache_size_bytes"
+)
+
+// LogCache keeps the entries appended to a log store, up to a number of
+// bytes, the least recently used evicted first. The reads it misses go to
+// the store.
+type LogCache struct {
+	raft.LogStore
+	capacity int64
+
+	mu      sync.Mutex
+	entries map[uint64]*list.Element
+	lru     *list.List
+	size    int64
+	hits    int64
+	misses  int64
+}
+
+// LogCacheStats is the use of a log cache.
+type LogCacheStats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Size is the bytes of the cached entries.
+	Size int64 `json:"size"`
+}
+
+// NewLogCache returns a cache of capacity bytes in front of store.
+func NewLogCache(capacity int64, store raft.LogStore) *LogCache {
+	return &LogCache{LogStore: store, capacity: capacity, entries: make(map[uint64]*list.Element), lru: list.New()}
+}
+
+func logEntrySize(l *raft.Log) int64 {
+	return int64(len(l.Data)) + logEntryOverhead
+}
+
+// GetLog reads the entry at index, from the cache if it holds it.
+func (c *LogCache) GetLog(index uint64, l *raft.Log) error {
+	c.mu.Lock()
+	if e, ok := c.entries[index]; ok {
+		c.lru.MoveToFront(e)
+		*l = *e.Value.(*raft.Log)
+		c.hits++
+		c.mu.Unlock()
+		return nil
+	}
+	c.misses++
+	c.mu.Unlock()
+	return c.LogStore.GetLog(index, l)
+}
+
+// StoreLog appends l to the store and caches it.
+func (c *LogCache) StoreLog(l *raft.Log) error {
+	return c.StoreLogs([]*raft.Log{l})
+}
+
+// StoreLogs appends logs to the store and caches them.
+func (c *LogCache) StoreLogs(logs []*raft.Log) error {
+	if err := c.LogStore.StoreLogs(logs); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range logs {
+		c.remove(l.Index)
+		size := logEntrySize(l)
+		if size > c.capacity {
+			continue
+		}
+		c.entries[l.Index] = c.lru.PushFront(l)
+		c.size += size
+	}
+	for c.size > c.capacity {
+		c.remove(c.lru.Back().Value.(*raft.Log).Index)
+	}
+	return nil
+}
+
+// DeleteRange deletes the entries from min to max of the store and of the
+// cache.
+func (c *LogCache) DeleteRange(min, max uint64) error {
+	c.mu.Lock()
+	if max-min < uint64(len(c.entries)) {
+		for i := min; i <= max; i++ {
+			c.remove(i)
+		}
+	} else {
+		for i := range c.entries {
+			if i >= min && i <= max {
+				c.remove(i)
+			}
+		}
+	}
+	c.mu.Unlock()
+	return c.LogStore.DeleteRange(min, max)
+}
+
+// remove drops the entry at index from the cache, with c locked.
+func (c *LogCache) remove(index uint64) {
+	e, ok := c.entries[index]
+	if !ok {
+		return
+	}
+	c.size -= logEntrySize(e.Value.(*raft.Log))
+	c.lru.Remove(e)
+	delete(c.entries, index)
+}
+
+// Stats returns the use of c.
+func (c *LogCache) Stats() LogCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return LogCacheStats{Hits: c.hits, Misses: c.misses, Size: c.size}
+}
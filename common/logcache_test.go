@@ -0,0 +1,77 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingLogStore counts the reads reaching the store.
+type countingLogStore struct {
+	logs  map[uint64]*raft.Log
+	reads int
+}
+
+func (s *countingLogStore) FirstIndex() (uint64, error) { return 0, nil }
+func (s *countingLogStore) LastIndex() (uint64, error)  { return 0, nil }
+
+func (s *countingLogStore) GetLog(index uint64, l *raft.Log) error {
+	s.reads++
+	stored, ok := s.logs[index]
+	if !ok {
+		return raft.ErrLogNotFound
+	}
+	*l = *stored
+	return nil
+}
+
+func (s *countingLogStore) StoreLog(l *raft.Log) error { return s.StoreLogs([]*raft.Log{l}) }
+
+func (s *countingLogStore) StoreLogs(logs []*raft.Log) error {
+	for _, l := range logs {
+		s.logs[l.Index] = l
+	}
+	return nil
+}
+
+func (s *countingLogStore) DeleteRange(min, max uint64) error {
+	for i := min; i <= max; i++ {
+		delete(s.logs, i)
+	}
+	return nil
+}
+
+func TestLogCache(t *testing.T) {
+	store := &countingLogStore{logs: make(map[uint64]*raft.Log)}
+	entry := logEntryOverhead + 10
+	cache := NewLogCache(int64(3*entry), store)
+	for i := uint64(1); i <= 4; i++ {
+		assert.Nil(t, cache.StoreLog(&raft.Log{Index: i, Data: make([]byte, 10)}))
+	}
+
+	// the first entry is evicted
+	var l raft.Log
+	assert.Nil(t, cache.GetLog(4, &l))
+	assert.Equal(t, uint64(4), l.Index)
+	assert.Equal(t, 0, store.reads)
+	assert.Nil(t, cache.GetLog(1, &l))
+	assert.Equal(t, uint64(1), l.Index)
+	assert.Equal(t, 1, store.reads)
+	assert.Equal(t, LogCacheStats{Hits: 1, Misses: 1, Size: int64(3 * entry)}, cache.Stats())
+
+	// the least recently read is evicted next
+	assert.Nil(t, cache.GetLog(2, &l))
+	assert.Nil(t, cache.StoreLog(&raft.Log{Index: 5, Data: make([]byte, 10)}))
+	assert.Nil(t, cache.GetLog(3, &l))
+	assert.Equal(t, 2, store.reads)
+
+	// deleted entries are not served
+	assert.Nil(t, cache.DeleteRange(1, 4))
+	assert.Equal(t, raft.ErrLogNotFound, cache.GetLog(2, &l))
+	assert.Equal(t, int64(entry), cache.Stats().Size)
+
+	// entries over the capacity are not cached
+	assert.Nil(t, cache.StoreLog(&raft.Log{Index: 6, Data: make([]byte, 4*entry)}))
+	assert.Equal(t, int64(entry), cache.Stats().Size)
+}
@@ -0,0 +1,36 @@
+package common
+
+import "time"
+
+// States of the store nodes, as seen by the coordinator.
+const (
+	NodeAlive   = "alive"
+	NodeSuspect = "suspect"
+	NodeDead    = "dead"
+)
+
+var (
+	// Zone is the failure domain of the node, such as its availability zone.
+	Zone string
+	// Capacity is the relative capacity of the node, in arbitrary units.
+	Capacity int64 = 1
+	// MemberProbeInterval is how often the coordinator probes the store
+	// nodes.
+	MemberProbeInterval = time.Second
+	// SuspectAfter and DeadAfter are how long after its last probe answered
+	// a store node is suspected, and then considered dead.
+	SuspectAfter = 3 * time.Second
+	DeadAfter    = 10 * time.Second
+)
+
+// NodeState returns the state of a node last reached at lastSeen, zero if
+// never, at now.
+func NodeState(lastSeen, now time.Time) string {
+	switch {
+	case lastSeen.IsZero() || now.Sub(lastSeen) >= DeadAfter:
+		return NodeDead
+	case now.Sub(lastSeen) >= SuspectAfter:
+		return NodeSuspect
+	}
+	return NodeAlive
+}
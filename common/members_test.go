@@ -0,0 +1,16 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeState(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, NodeAlive, NodeState(now.Add(-time.Second), now))
+	assert.Equal(t, NodeSuspect, NodeState(now.Add(-SuspectAfter), now))
+	assert.Equal(t, NodeDead, NodeState(now.Add(-DeadAfter), now))
+	assert.Equal(t, NodeDead, NodeState(time.Time{}, now))
+}
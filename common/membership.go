@@ -0,0 +1,164 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Steps of the member changes of the raft groups.
+const (
+	MemberAddVoter    = "add-voter"
+	MemberAddNonvoter = "add-nonvoter"
+	MemberPromote     = "promote"
+	MemberRemove      = "remove"
+	MemberTransfer    = "transfer"
+	MemberMove        = "move"
+)
+
+// PlanMembers returns the steps of a member change of the raft group of
+// servers, led by self: joining joins, promoting the non-voters promote,
+// removing the members remove and then transferring the leadership to
+// transfer, by server id. Joining a member at a new address moves it there,
+// keeping its vote. The whole change is checked before any step, and
+// the steps already in place are left out, so that the plan of a change
+// applied already is empty. hashicorp/raft changes one server at a time;
+// promoting before removing keeps at least as many voters throughout.
+func PlanMembers(servers []raft.Server, self string, joins []raft.Server, promote, remove []string, transfer string) ([]*raftpb.MemberAction, error) {
+	members := make(map[raft.ServerID]raft.Server)
+	for _, srv := range servers {
+		members[srv.ID] = srv
+	}
+	var plan []*raftpb.MemberAction
+	step := func(op string, srv raft.Server) {
+		plan = append(plan, &raftpb.MemberAction{Op: op, Id: string(srv.ID), Address: string(srv.Address)})
+	}
+	for _, j := range joins {
+		for _, srv := range members {
+			if srv.Address == j.Address && srv.ID != j.ID {
+				return nil, fmt.Errorf("%s is the address of %s, remove it first", j.Address, srv.ID)
+			}
+		}
+		if srv, ok := members[j.ID]; ok {
+			if srv.Address != j.Address {
+				srv.Address = j.Address
+				step(MemberMove, srv)
+				members[srv.ID] = srv
+			}
+			if j.Suffrage == raft.Voter && srv.Suffrage != raft.Voter {
+				promote = append([]string{string(j.ID)}, promote...)
+			}
+			continue
+		}
+		if j.Suffrage == raft.Voter {
+			step(MemberAddVoter, j)
+		} else {
+			step(MemberAddNonvoter, j)
+		}
+		members[j.ID] = j
+	}
+	for _, id := range promote {
+		srv, ok := members[raft.ServerID(id)]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a member, join it with --nonvoter first", id)
+		}
+		if srv.Suffrage != raft.Voter {
+			step(MemberPromote, srv)
+			srv.Suffrage = raft.Voter
+			members[srv.ID] = srv
+		}
+	}
+	for _, id := range remove {
+		if id == self {
+			return nil, fmt.Errorf("%s is the leader, transfer the leadership first", id)
+		}
+		if srv, ok := members[raft.ServerID(id)]; ok {
+			step(MemberRemove, srv)
+			delete(members, srv.ID)
+		}
+	}
+	if transfer != "" && transfer != self {
+		srv, ok := members[raft.ServerID(transfer)]
+		if !ok || srv.Suffrage != raft.Voter {
+			return nil, fmt.Errorf("%s is not a voter, the leadership cannot be transferred to it", transfer)
+		}
+		step(MemberTransfer, srv)
+	}
+	return plan, nil
+}
+
+// ApplyMembers applies the steps of plan to ra, in order.
+func ApplyMembers(ra *raft.Raft, plan []*raftpb.MemberAction) error {
+	for _, a := range plan {
+		id, addr := raft.ServerID(a.Id), raft.ServerAddress(a.Address)
+		var err error
+		switch a.Op {
+		case MemberAddVoter, MemberPromote:
+			err = ra.AddVoter(id, addr, 0, 0).Error()
+		case MemberAddNonvoter:
+			err = ra.AddNonvoter(id, addr, 0, 0).Error()
+		case MemberRemove:
+			err = ra.RemoveServer(id, 0, 0).Error()
+		case MemberTransfer:
+			err = ra.LeadershipTransferToServer(id, addr).Error()
+		case MemberMove:
+			// member changes are made by administrators
+			_, err = MoveMember(ra, a.Id, a.Address, true)
+		default:
+			err = fmt.Errorf("unknown member change %q", a.Op)
+		}
+		if err != nil {
+			return fmt.Errorf("%s %s: %s", a.Op, a.Id, err)
+		}
+	}
+	return nil
+}
+
+// ErrUnverifiedMove is returned for the joins of a member at a new address
+// that do not prove the identity of the node.
+var ErrUnverifiedMove = errors.New("unverified move of a member")
+
+// MoveMember updates the address of the member id of ra, led by this node,
+// to addr, keeping its vote, for a node restarted elsewhere under the same
+// identity. It reports whether it did: not if id is not a member, is at addr
+// already or another member is at addr. verified reports whether the node
+// proved its identity, by a join signed with ClusterSecret or authenticated;
+// the address of a member is not changed otherwise, and ErrUnverifiedMove is
+// returned.
+func MoveMember(ra *raft.Raft, id, addr string, verified bool) (bool, error) {
+	future := ra.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return false, err
+	}
+	var member *raft.Server
+	var taken bool
+	for _, srv := range future.Configuration().Servers {
+		if srv.Address == raft.ServerAddress(addr) {
+			taken = true
+		}
+		if srv.ID == raft.ServerID(id) {
+			s := srv
+			member = &s
+		}
+	}
+	if member == nil || member.Address == raft.ServerAddress(addr) {
+		return false, nil
+	}
+	if !verified {
+		return false, fmt.Errorf("%w: node %s is a member at %s, refusing to move it to %s without a signed or authenticated join",
+			ErrUnverifiedMove, id, member.Address, addr)
+	}
+	if taken {
+		return false, nil
+	}
+	// adding a member again replaces its address
+	var err error
+	if member.Suffrage == raft.Voter {
+		err = ra.AddVoter(member.ID, raft.ServerAddress(addr), 0, 0).Error()
+	} else {
+		err = ra.AddNonvoter(member.ID, raft.ServerAddress(addr), 0, 0).Error()
+	}
+	return err == nil, err
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanMembers(t *testing.T) {
+	servers := []raft.Server{
+		{ID: "a", Address: "a:1", Suffrage: raft.Voter},
+		{ID: "b", Address: "b:1", Suffrage: raft.Voter},
+		{ID: "c", Address: "c:1", Suffrage: raft.Nonvoter},
+	}
+	joins := []raft.Server{{ID: "d", Address: "d:1", Suffrage: raft.Nonvoter}}
+	plan, err := PlanMembers(servers, "a", joins, []string{"c", "d"}, []string{"b", "x"}, "c")
+	assert.Nil(t, err)
+	assert.Equal(t, []*raftpb.MemberAction{
+		{Op: MemberAddNonvoter, Id: "d", Address: "d:1"},
+		{Op: MemberPromote, Id: "c", Address: "c:1"},
+		{Op: MemberPromote, Id: "d", Address: "d:1"},
+		{Op: MemberRemove, Id: "b", Address: "b:1"},
+		{Op: MemberTransfer, Id: "c", Address: "c:1"},
+	}, plan)
+
+	// once applied, the same change plans nothing
+	applied := []raft.Server{
+		{ID: "a", Address: "a:1", Suffrage: raft.Voter},
+		{ID: "c", Address: "c:1", Suffrage: raft.Voter},
+		{ID: "d", Address: "d:1", Suffrage: raft.Voter},
+	}
+	plan, err = PlanMembers(applied, "c", joins, []string{"c", "d"}, []string{"b", "x"}, "c")
+	assert.Nil(t, err)
+	assert.Empty(t, plan)
+
+	// joined as a voter, a non-voter is promoted
+	plan, err = PlanMembers(servers, "a", []raft.Server{{ID: "c", Address: "c:1", Suffrage: raft.Voter}}, nil, nil, "")
+	assert.Nil(t, err)
+	assert.Equal(t, []*raftpb.MemberAction{{Op: MemberPromote, Id: "c", Address: "c:1"}}, plan)
+
+	// joined at a new address, a member is moved there
+	plan, err = PlanMembers(servers, "a", []raft.Server{{ID: "c", Address: "c:2", Suffrage: raft.Voter}}, nil, nil, "c")
+	assert.Nil(t, err)
+	assert.Equal(t, []*raftpb.MemberAction{
+		{Op: MemberMove, Id: "c", Address: "c:2"},
+		{Op: MemberPromote, Id: "c", Address: "c:2"},
+		{Op: MemberTransfer, Id: "c", Address: "c:2"},
+	}, plan)
+
+	_, err = PlanMembers(servers, "a", []raft.Server{{ID: "e", Address: "c:1"}}, nil, nil, "")
+	assert.EqualError(t, err, "c:1 is the address of c, remove it first")
+	_, err = PlanMembers(servers, "a", nil, []string{"x"}, nil, "")
+	assert.EqualError(t, err, "x is not a member, join it with --nonvoter first")
+	_, err = PlanMembers(servers, "a", nil, nil, []string{"a"}, "")
+	assert.EqualError(t, err, "a is the leader, transfer the leadership first")
+	_, err = PlanMembers(servers, "a", nil, nil, nil, "c")
+	assert.EqualError(t, err, "c is not a voter, the leadership cannot be transferred to it")
+}
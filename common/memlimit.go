@@ -0,0 +1,10 @@
+//go:build go1.19
+// +build go1.19
+
+package common
+
+import "runtime/debug"
+
+func setMemoryLimit(n int64) {
+	debug.SetMemoryLimit(n)
+}
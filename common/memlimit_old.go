@@ -0,0 +1,8 @@
+//go:build !go1.19
+// +build !go1.19
+
+package common
+
+// setMemoryLimit does nothing before Go 1.19, which has no soft memory limit:
+// the writes are still shed at the heap watermark.
+func setMemoryLimit(n int64) {}
@@ -0,0 +1,152 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Memory accounted on a store node, by subsystem.
+const (
+	// MemoryStore is the keys and values of the shard.
+	MemoryStore = "store"
+	// MemoryProposals is the commands proposed and not applied yet.
+	MemoryProposals = "proposals"
+)
+
+// Soft memory budgets of a store node in bytes, above which writes are
+// rejected with ErrOverloaded rather than risking the OOM killer. Disabled if
+// 0.
+var (
+	StoreMemoryBudget    int64
+	ProposalMemoryBudget int64
+	// MemoryInterval is how often the size of the keys and values is
+	// recomputed.
+	MemoryInterval = 5 * time.Second
+)
+
+// Memory metrics, by subsystem.
+const (
+	MemoryMetric       = "raftkv_memory_bytes"
+	MemoryBudgetMetric = "raftkv_memory_budget_bytes"
+)
+
+// MemoryAccount tracks the approximate bytes used by subsystems against their
+// budgets.
+type MemoryAccount struct {
+	mu      sync.Mutex
+	used    map[string]int64
+	budgets map[string]int64
+}
+
+// NewMemoryAccount returns an account of the subsystems of budgets, a budget
+// of 0 being unlimited.
+func NewMemoryAccount(budgets map[string]int64) *MemoryAccount {
+	used := make(map[string]int64)
+	for sub := range budgets {
+		used[sub] = 0
+	}
+	return &MemoryAccount{used: used, budgets: budgets}
+}
+
+// Set sets the bytes used by sub to n.
+func (a *MemoryAccount) Set(sub string, n int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.used[sub] = n
+}
+
+// Used returns the bytes used by sub.
+func (a *MemoryAccount) Used(sub string) int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.used[sub]
+}
+
+// Check returns ErrOverloaded if sub is over its budget for a request of the
+// given priority. High priority requests are never rejected, and low
+// priority ones are rejected at half the budget.
+func (a *MemoryAccount) Check(sub string, priority int32) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.check(sub, 0, priority)
+}
+
+// Reserve adds n bytes to sub, unless this puts it over its budget, and
+// returns the function removing them.
+func (a *MemoryAccount) Reserve(sub string, n int64, priority int32) (func(), error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.check(sub, n, priority); err != nil {
+		return nil, err
+	}
+	a.used[sub] += n
+	return func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.used[sub] -= n
+	}, nil
+}
+
+func (a *MemoryAccount) check(sub string, n int64, priority int32) error {
+	budget := a.budgets[sub]
+	switch {
+	case budget <= 0 || priority > PriorityNormal:
+		return nil
+	case priority < PriorityNormal:
+		budget /= 2
+	}
+	if a.used[sub]+n > budget {
+		return fmt.Errorf("%w: memory budget of %s exceeded, %d of %d bytes used", ErrOverloaded, sub, a.used[sub], budget)
+	}
+	return nil
+}
+
+// RegisterMemoryMetrics registers the memory metrics in m.
+func RegisterMemoryMetrics(m *Metrics) {
+	m.Register(MemoryMetric, GaugeMetric, "Approximate bytes used by the subsystem.")
+	m.Register(MemoryBudgetMetric, GaugeMetric, "Soft memory budget of the subsystem in bytes, 0 if unlimited.")
+}
+
+// SetMetrics sets the memory metrics of the subsystems in m.
+func (a *MemoryAccount) SetMetrics(m *Metrics) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for sub, n := range a.used {
+		m.Set(MemoryMetric, float64(n), "subsystem", sub)
+		m.Set(MemoryBudgetMetric, float64(a.budgets[sub]), "subsystem", sub)
+	}
+}
+
+// CommandsSize returns the approximate bytes of the keys and values of cmds.
+func CommandsSize(cmds []*raftpb.Command) int64 {
+	var n int64
+	for _, cmd := range cmds {
+		n += entrySize(cmd.Key, CommandValue(cmd))
+	}
+	return n
+}
+
+// Bytes returns the approximate bytes of the keys and values of c, counted
+// like the cache budget. Keys locked for longer than timeout are not
+// counted.
+func (c *Cmap) Bytes(timeout time.Duration) int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var total int64
+	for k, v := range c.Map {
+		if v.txid != "" {
+			// the value only changes once the global lock is released
+			total += entrySize(k, v.V)
+			continue
+		}
+		if !v.mu.RTryLockTimeout(timeout) {
+			continue
+		}
+		total += entrySize(k, v.V)
+		v.mu.RUnlock()
+	}
+	return total
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryAccount(t *testing.T) {
+	a := NewMemoryAccount(map[string]int64{MemoryStore: 100, MemoryProposals: 0})
+	a.Set(MemoryStore, 60)
+	assert.Nil(t, a.Check(MemoryStore, PriorityNormal))
+	assert.True(t, IsOverloaded(a.Check(MemoryStore, PriorityLow)))
+
+	a.Set(MemoryStore, 120)
+	assert.True(t, IsOverloaded(a.Check(MemoryStore, PriorityNormal)))
+	assert.Nil(t, a.Check(MemoryStore, PriorityHigh))
+
+	// unlimited
+	release, err := a.Reserve(MemoryProposals, 1<<30, PriorityNormal)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1<<30), a.Used(MemoryProposals))
+	release()
+	assert.Equal(t, int64(0), a.Used(MemoryProposals))
+}
+
+func TestMemoryAccount_Reserve(t *testing.T) {
+	a := NewMemoryAccount(map[string]int64{MemoryProposals: 100})
+	release, err := a.Reserve(MemoryProposals, 80, PriorityNormal)
+	assert.Nil(t, err)
+	_, err = a.Reserve(MemoryProposals, 30, PriorityNormal)
+	assert.True(t, IsOverloaded(err))
+	release()
+	_, err = a.Reserve(MemoryProposals, 30, PriorityNormal)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,151 @@
+package common
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// MerkleBuckets is the number of leaves of the Merkle trees of the keys, each
+// hashing the keys of a bucket.
+const MerkleBuckets = 256
+
+// MerkleTree is a binary hash tree of the keys of a replica, stored as a heap:
+// the children of node i are 2i+1 and 2i+2, and the leaf of bucket b is node
+// MerkleBuckets-1+b. Two replicas holding the same keys have the same tree,
+// and the buckets they hold differently are found by walking the nodes that
+// differ from the root.
+type MerkleTree [2*MerkleBuckets - 1]uint64
+
+// MerkleBucket returns the bucket of key k.
+func MerkleBucket(k string) int {
+	h := fnv.New32a()
+	h.Write([]byte(k))
+	return int(h.Sum32() % MerkleBuckets)
+}
+
+// MerkleTree returns the Merkle tree of the committed keys of the map. Like
+// StateHash, it must be called from the goroutine applying the raft entries
+// only.
+func (c *Cmap) MerkleTree() *MerkleTree {
+	t := &MerkleTree{}
+	c.mu.RLock()
+	for k, v := range c.Map {
+		if v.temp {
+			continue
+		}
+		t[MerkleBuckets-1+MerkleBucket(k)] ^= entryHash(k, v.V, v.Meta)
+	}
+	c.mu.RUnlock()
+	for i := MerkleBuckets - 2; i >= 0; i-- {
+		h := fnv.New64a()
+		var b [16]byte
+		binary.BigEndian.PutUint64(b[:], t[2*i+1])
+		binary.BigEndian.PutUint64(b[8:], t[2*i+2])
+		h.Write(b[:])
+		t[i] = h.Sum64()
+	}
+	return t
+}
+
+// Diff walks the nodes of t and of another tree, read with remote, from the
+// root down the nodes that differ, and returns the buckets of the leaves that
+// differ.
+func (t *MerkleTree) Diff(remote func(nodes []int32) ([]uint64, error)) ([]int32, error) {
+	var buckets []int32
+	level := []int32{0}
+	for len(level) > 0 {
+		hashes, err := remote(level)
+		if err != nil {
+			return nil, err
+		}
+		var next []int32
+		for i, node := range level {
+			if i >= len(hashes) || hashes[i] == t[node] {
+				continue
+			}
+			if node >= MerkleBuckets-1 {
+				buckets = append(buckets, node-(MerkleBuckets-1))
+				continue
+			}
+			next = append(next, 2*node+1, 2*node+2)
+		}
+		level = next
+	}
+	return buckets, nil
+}
+
+// Hashes returns the hashes of nodes, 0 for the nodes out of the tree.
+func (t *MerkleTree) Hashes(nodes []int32) []uint64 {
+	res := make([]uint64, len(nodes))
+	for i, node := range nodes {
+		if node >= 0 && int(node) < len(t) {
+			res[i] = t[node]
+		}
+	}
+	return res
+}
+
+// ReadBuckets returns the committed keys of the view in buckets as of the
+// opening of the view.
+func (v *CmapView) ReadBuckets(buckets []int32) []*raftpb.KVEntry {
+	in := make(map[int]bool, len(buckets))
+	for _, b := range buckets {
+		in[int(b)] = true
+	}
+	var res []*raftpb.KVEntry
+	for k, value := range v.values {
+		if value.temp || !in[MerkleBucket(k)] {
+			continue
+		}
+		v.c.viewMu.Lock()
+		if s, ok := v.saved[value]; ok {
+			res = append(res, NewEntry(k, s.v, s.meta))
+		} else {
+			res = append(res, NewEntry(k, value.V, value.Meta))
+		}
+		v.c.viewMu.Unlock()
+	}
+	return res
+}
+
+// RepairKey replaces key k, held as local when the Merkle trees were built,
+// with source, the key of another replica then, or deletes it if source is
+// nil. It leaves the key and reports false if it was written since, or is
+// locked by a transaction.
+func (c *Cmap) RepairKey(k string, local, source *raftpb.KVEntry) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.Map[k]
+	switch {
+	case local == nil && ok,
+		local != nil && !ok,
+		ok && (value.temp || value.Meta.ModRevision != EntryMeta(local).ModRevision):
+		return false
+	}
+	if ok {
+		if !value.mu.TryLockTimeout(0) {
+			return false
+		}
+		defer value.mu.Unlock()
+	}
+	if source == nil {
+		c.delete(k)
+		return ok
+	}
+	if !ok {
+		value = c.arena.newValue(k, EntryValue(source), false)
+		value.Meta = EntryMeta(source)
+		c.writable()[k] = value
+		c.keyStats.added(k, value.V)
+		c.hashValue(value)
+		return true
+	}
+	c.keyStats.replaced(k, value.V, EntryValue(source))
+	c.change(value, func() {
+		value.V = c.arena.value(EntryValue(source))
+		value.Meta = EntryMeta(source)
+	})
+	return true
+}
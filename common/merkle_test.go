@@ -0,0 +1,71 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerkleTree(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m2 := NewCmap(log.New(), 0)
+	for i := 0; i < 1000; i++ {
+		k := "k" + strconv.Itoa(i)
+		m1.SetRev(k, int64(i), nil, int64(i+1))
+		m2.SetRev(k, int64(i), nil, int64(i+1))
+	}
+	t1, t2 := m1.MerkleTree(), m2.MerkleTree()
+	assert.Equal(t, t1, t2)
+
+	m2.SetRev("k10", int64(-1), nil, 2000)
+	m2.Del("k20")
+	m2.SetRev("extra", int64(1), nil, 2001)
+	t2 = m2.MerkleTree()
+	assert.NotEqual(t, t1[0], t2[0])
+
+	walked := 0
+	buckets, err := t1.Diff(func(nodes []int32) ([]uint64, error) {
+		walked += len(nodes)
+		return t2.Hashes(nodes), nil
+	})
+	assert.Nil(t, err)
+	expected := map[int32]bool{}
+	for _, k := range []string{"k10", "k20", "extra"} {
+		expected[int32(MerkleBucket(k))] = true
+	}
+	assert.Len(t, buckets, len(expected))
+	for _, b := range buckets {
+		assert.True(t, expected[b], b)
+	}
+	// only the paths to the buckets that differ are walked
+	assert.True(t, walked <= 1+2*8*len(expected), walked)
+}
+
+func TestRepairKey(t *testing.T) {
+	m := NewCmap(log.New(), 0)
+	m.SetRev("a", int64(1), nil, 1)
+	m.SetRev("b", int64(2), nil, 2)
+	m.SetRev("c", int64(3), nil, 3)
+	view := m.View()
+	local := map[string]*raftpb.KVEntry{}
+	for _, e := range view.ReadBuckets([]int32{int32(MerkleBucket("a")), int32(MerkleBucket("b")), int32(MerkleBucket("c"))}) {
+		local[e.Key] = e
+	}
+	view.Close()
+
+	// written since the trees were built
+	m.SetRev("c", int64(4), nil, 4)
+
+	source := NewEntry("a", int64(10), KeyMeta{CreateRevision: 1, ModRevision: 1, Version: 1})
+	assert.True(t, m.RepairKey("a", local["a"], source))
+	assert.True(t, m.RepairKey("b", local["b"], nil))
+	assert.False(t, m.RepairKey("c", local["c"], nil))
+	assert.True(t, m.RepairKey("d", nil, NewEntry("d", int64(5), KeyMeta{CreateRevision: 2, ModRevision: 2, Version: 1})))
+
+	assert.Equal(t, map[string]interface{}{"a": int64(10), "c": int64(4), "d": int64(5)}, m.Snapshot())
+	_, meta, _, _ := m.GetWithMeta("d")
+	assert.Equal(t, int64(2), meta.ModRevision)
+}
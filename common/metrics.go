@@ -0,0 +1,117 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Metric types of the Prometheus text format.
+const (
+	CounterMetric = "counter"
+	GaugeMetric   = "gauge"
+)
+
+// Metrics is a set of labelled counters and gauges written in the Prometheus
+// text exposition format.
+type Metrics struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+type metricFamily struct {
+	help   string
+	typ    string
+	values map[string]float64 // by formatted labels
+}
+
+// NewMetrics returns an empty set of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{families: make(map[string]*metricFamily)}
+}
+
+// Register declares the metric name of type typ.
+func (m *Metrics) Register(name, typ, help string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.families[name] = &metricFamily{help: help, typ: typ, values: make(map[string]float64)}
+}
+
+// Add adds v to the metric name with the given label name and value pairs.
+func (m *Metrics) Add(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.family(name).values[formatLabels(labels)] += v
+}
+
+// Set sets the metric name with the given label name and value pairs to v.
+func (m *Metrics) Set(name string, v float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.family(name).values[formatLabels(labels)] = v
+}
+
+// Reset drops every value of the metric name, for gauges recomputed as a
+// whole.
+func (m *Metrics) Reset(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.family(name).values = make(map[string]float64)
+}
+
+func (m *Metrics) family(name string) *metricFamily {
+	f, ok := m.families[name]
+	if !ok {
+		panic(fmt.Sprintf("metric %s is not registered", name))
+	}
+	return f
+}
+
+// Write writes the metrics to w, sorted by name and labels.
+func (m *Metrics) Write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.families))
+	for name := range m.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		f := m.families[name]
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ)
+		labels := make([]string, 0, len(f.values))
+		for l := range f.values {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			fmt.Fprintf(&b, "%s%s %v\n", name, l, f.values[l])
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// formatLabels formats label name and value pairs as {name="value",...}.
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	if len(labels)%2 != 0 {
+		panic("labels are not name and value pairs")
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=%q", labels[i], labels[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	m.Register("requests_total", CounterMetric, "Requests.")
+	m.Register("keys", GaugeMetric, "Keys.")
+	m.Add("requests_total", 1, "tenant", "b", "op", "get")
+	m.Add("requests_total", 2, "tenant", "a", "op", "get")
+	m.Add("requests_total", 1, "tenant", "a", "op", "get")
+	m.Set("keys", 7)
+	m.Set("keys", 5)
+
+	var b strings.Builder
+	assert.Nil(t, m.Write(&b))
+	assert.Equal(t, `# HELP keys Keys.
+# TYPE keys gauge
+keys 5
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{tenant="a",op="get"} 3
+requests_total{tenant="b",op="get"} 1
+`, b.String())
+
+	m.Reset("requests_total")
+	b.Reset()
+	m.Write(&b)
+	assert.False(t, strings.Contains(b.String(), "tenant"))
+}
+
+func TestNamespace(t *testing.T) {
+	assert.Equal(t, "app", Namespace("app/users/1"))
+	assert.Equal(t, "", Namespace("users"))
+	assert.Equal(t, "", Namespace("/users"))
+}
@@ -0,0 +1,15 @@
+package common
+
+import "strings"
+
+// NamespaceSeparator ends the namespace, or tenant, part of a key.
+const NamespaceSeparator = "/"
+
+// Namespace returns the namespace of key, the part before the first
+// separator, or "" for keys without one.
+func Namespace(key string) string {
+	if i := strings.Index(key, NamespaceSeparator); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
@@ -0,0 +1,13 @@
+package common
+
+import "errors"
+
+// ErrOutcomeUnknown is returned for the transactions which may or may not
+// have been committed, such as those whose raft entry was appended by a
+// leader which lost its leadership before knowing if it was committed. The
+// client reads the keys again to find out.
+var ErrOutcomeUnknown = errors.New("transaction outcome unknown")
+
+// OutcomeUnknown is the phase replied by the shard leaders for the one-phase
+// commits of unknown outcome, to the callers setting ShardOps.OutcomeReply.
+const OutcomeUnknown = "OutcomeUnknown"
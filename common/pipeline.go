@@ -0,0 +1,149 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// Append pipelining settings, per follower.
+var (
+	// MaxInflight bounds the append requests sent to a follower and not yet
+	// acknowledged, the transport limit of 128 if 0.
+	MaxInflight int
+	// MaxInflightBytes bounds the bytes of the log entries sent to a follower
+	// and not yet acknowledged, unlimited if 0.
+	MaxInflightBytes int64
+)
+
+// inflightWindow bounds the append requests in flight to a follower. A
+// request larger than maxBytes is still sent once nothing else is in flight.
+type inflightWindow struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	maxMsgs  int
+	maxBytes int64
+	msgs     int
+	bytes    int64
+	closed   bool
+}
+
+func newInflightWindow(maxMsgs int, maxBytes int64) *inflightWindow {
+	w := &inflightWindow{maxMsgs: maxMsgs, maxBytes: maxBytes}
+	w.cond = sync.NewCond(&w.mu)
+	return w
+}
+
+// acquire waits until a request of n bytes fits in the window, and returns
+// false if the window is closed meanwhile.
+func (w *inflightWindow) acquire(n int64) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for !w.closed && w.msgs > 0 &&
+		((w.maxMsgs > 0 && w.msgs >= w.maxMsgs) || (w.maxBytes > 0 && w.bytes+n > w.maxBytes)) {
+		w.cond.Wait()
+	}
+	if w.closed {
+		return false
+	}
+	w.msgs++
+	w.bytes += n
+	return true
+}
+
+func (w *inflightWindow) release(n int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.msgs--
+	w.bytes -= n
+	w.cond.Broadcast()
+}
+
+func (w *inflightWindow) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	w.cond.Broadcast()
+}
+
+func entriesSize(req *raft.AppendEntriesRequest) int64 {
+	var n int64
+	for _, entry := range req.Entries {
+		n += int64(len(entry.Data))
+	}
+	return n
+}
+
+// windowedTransport bounds the append requests pipelined to every follower.
+type windowedTransport struct {
+	*raft.NetworkTransport
+	maxMsgs  int
+	maxBytes int64
+}
+
+// AppendEntriesPipeline opens a pipeline to the follower target, whose
+// requests wait for room in its window.
+func (t *windowedTransport) AppendEntriesPipeline(id raft.ServerID, target raft.ServerAddress) (raft.AppendPipeline, error) {
+	pipeline, err := t.NetworkTransport.AppendEntriesPipeline(id, target)
+	if err != nil {
+		return nil, err
+	}
+	p := &windowedPipeline{
+		AppendPipeline: pipeline,
+		window:         newInflightWindow(t.maxMsgs, t.maxBytes),
+		consumer:       make(chan raft.AppendFuture),
+		done:           make(chan struct{}),
+	}
+	go p.forward()
+	return p, nil
+}
+
+type windowedPipeline struct {
+	raft.AppendPipeline
+	window    *inflightWindow
+	consumer  chan raft.AppendFuture
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func (p *windowedPipeline) AppendEntries(args *raft.AppendEntriesRequest, resp *raft.AppendEntriesResponse) (raft.AppendFuture, error) {
+	n := entriesSize(args)
+	if !p.window.acquire(n) {
+		return nil, raft.ErrPipelineShutdown
+	}
+	future, err := p.AppendPipeline.AppendEntries(args, resp)
+	if err != nil {
+		p.window.release(n)
+	}
+	return future, err
+}
+
+// forward releases the window of the acknowledged requests before raft
+// consumes them.
+func (p *windowedPipeline) forward() {
+	for {
+		select {
+		case future := <-p.AppendPipeline.Consumer():
+			p.window.release(entriesSize(future.Request()))
+			select {
+			case p.consumer <- future:
+			case <-p.done:
+				return
+			}
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *windowedPipeline) Consumer() <-chan raft.AppendFuture {
+	return p.consumer
+}
+
+func (p *windowedPipeline) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.window.close()
+	})
+	return p.AppendPipeline.Close()
+}
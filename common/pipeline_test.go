@@ -0,0 +1,45 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertBlocked(t *testing.T, acquired chan bool) {
+	select {
+	case <-acquired:
+		t.Fatal("acquired beyond the window")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestInflightWindow(t *testing.T) {
+	acquired := make(chan bool)
+
+	w := newInflightWindow(2, 0)
+	assert.True(t, w.acquire(40))
+	assert.True(t, w.acquire(40))
+	go func() { acquired <- w.acquire(10) }()
+	assertBlocked(t, acquired)
+	w.release(40)
+	assert.True(t, <-acquired)
+
+	w = newInflightWindow(0, 100)
+	assert.True(t, w.acquire(60))
+	assert.True(t, w.acquire(30))
+	go func() { acquired <- w.acquire(20) }()
+	assertBlocked(t, acquired)
+	w.release(30)
+	assert.True(t, <-acquired)
+	w.release(60)
+	w.release(20)
+
+	// a request larger than the window goes alone
+	assert.True(t, w.acquire(500))
+	go func() { acquired <- w.acquire(1) }()
+	assertBlocked(t, acquired)
+	w.close()
+	assert.False(t, <-acquired)
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Priorities of writes. Bulk writes, like migrations, yield to the others
+// under contention, and admin writes, like sessions and migration markers, go
+// first.
+const (
+	PriorityLow    int32 = -1
+	PriorityNormal int32 = 0
+	PriorityHigh   int32 = 1
+)
+
+// MaxProposals bounds the writes and transaction prepares a shard leader
+// proposes at the same time, the others waiting by priority, unbounded if 0.
+var MaxProposals int
+
+// ParsePriority parses the name of a priority, normal if empty.
+func ParsePriority(s string) (int32, error) {
+	switch s {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	return 0, fmt.Errorf("invalid priority %q, expected low, normal or high", s)
+}
+
+// PriorityName returns the name of priority p.
+func PriorityName(p int32) string {
+	switch {
+	case p < PriorityNormal:
+		return "low"
+	case p > PriorityNormal:
+		return "high"
+	}
+	return "normal"
+}
+
+// Priority returns the highest priority of cmds.
+func Priority(cmds []*raftpb.Command) int32 {
+	if len(cmds) == 0 {
+		return PriorityNormal
+	}
+	p := cmds[0].Priority
+	for _, cmd := range cmds[1:] {
+		if cmd.Priority > p {
+			p = cmd.Priority
+		}
+	}
+	return p
+}
+
+// SetPriority sets the priority of cmds to p.
+func SetPriority(cmds []*raftpb.Command, p int32) {
+	for _, cmd := range cmds {
+		cmd.Priority = p
+	}
+}
+
+// Client returns the client of cmds, the first one set.
+func Client(cmds []*raftpb.Command) string {
+	for _, cmd := range cmds {
+		if cmd.Client != "" {
+			return cmd.Client
+		}
+	}
+	return ""
+}
+
+// PriorityGate admits a bounded number of holders at the same time. Waiters
+// are admitted by priority, then in turn by client: the clients waiting at a
+// priority are served one holder each in arrival order, so that a client
+// queueing many writes, like a bulk writer, does not delay the writes of the
+// others behind all of its own. A nil gate admits everyone.
+type PriorityGate struct {
+	mu      sync.Mutex
+	free    int
+	waiters map[int32]*fairQueue
+}
+
+// fairQueue holds the waiters of a priority by client, and the clients with
+// waiters in the order they are served.
+type fairQueue struct {
+	clients []string
+	waiters map[string][]chan struct{}
+}
+
+// NewPriorityGate returns a gate admitting n holders at the same time, nil if
+// n is not positive.
+func NewPriorityGate(n int) *PriorityGate {
+	if n <= 0 {
+		return nil
+	}
+	return &PriorityGate{free: n, waiters: make(map[int32]*fairQueue)}
+}
+
+// Acquire waits until g admits a holder of priority p sent by client, and
+// returns the function releasing it.
+func (g *PriorityGate) Acquire(p int32, client string) func() {
+	if g == nil {
+		return func() {}
+	}
+	p = clampPriority(p)
+	g.mu.Lock()
+	if g.free > 0 {
+		g.free--
+		g.mu.Unlock()
+		return g.release
+	}
+	q := g.waiters[p]
+	if q == nil {
+		q = &fairQueue{waiters: make(map[string][]chan struct{})}
+		g.waiters[p] = q
+	}
+	if len(q.waiters[client]) == 0 {
+		q.clients = append(q.clients, client)
+	}
+	ready := make(chan struct{})
+	q.waiters[client] = append(q.waiters[client], ready)
+	g.mu.Unlock()
+	<-ready
+	return g.release
+}
+
+// release hands the slot of a holder to the next client waiting at the
+// highest priority, if any, which goes to the back of the line if it has
+// more waiters.
+func (g *PriorityGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for p := PriorityHigh; p >= PriorityLow; p-- {
+		q := g.waiters[p]
+		if q == nil || len(q.clients) == 0 {
+			continue
+		}
+		client := q.clients[0]
+		q.clients = q.clients[1:]
+		w := q.waiters[client]
+		if len(w) > 1 {
+			q.waiters[client] = w[1:]
+			q.clients = append(q.clients, client)
+		} else {
+			delete(q.waiters, client)
+		}
+		close(w[0])
+		return
+	}
+	g.free++
+}
+
+func clampPriority(p int32) int32 {
+	if p < PriorityLow {
+		return PriorityLow
+	}
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+	return p
+}
@@ -0,0 +1,77 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriority(t *testing.T) {
+	assert.Equal(t, PriorityNormal, Priority(nil))
+	assert.Equal(t, PriorityLow, Priority([]*raftpb.Command{{Priority: PriorityLow}}))
+	assert.Equal(t, PriorityHigh, Priority([]*raftpb.Command{{Priority: PriorityLow}, {Priority: PriorityHigh}}))
+
+	for _, name := range []string{"low", "normal", "high"} {
+		p, err := ParsePriority(name)
+		assert.Nil(t, err)
+		assert.Equal(t, name, PriorityName(p))
+	}
+	_, err := ParsePriority("urgent")
+	assert.NotNil(t, err)
+}
+
+func TestPriorityGate(t *testing.T) {
+	assert.Nil(t, NewPriorityGate(0))
+	var nilGate *PriorityGate
+	nilGate.Acquire(PriorityLow, "")()
+
+	g := NewPriorityGate(1)
+	release := g.Acquire(PriorityNormal, "")
+
+	admitted := make(chan int32, 3)
+	for _, p := range []int32{PriorityLow, PriorityNormal, PriorityHigh} {
+		go func(p int32) {
+			r := g.Acquire(p, "")
+			admitted <- p
+			r()
+		}(p)
+		// waiters queue in this order
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-admitted:
+		t.Fatal("admitted beyond the bound")
+	default:
+	}
+	release()
+	assert.Equal(t, PriorityHigh, <-admitted)
+	assert.Equal(t, PriorityNormal, <-admitted)
+	assert.Equal(t, PriorityLow, <-admitted)
+}
+
+func TestPriorityGateFairness(t *testing.T) {
+	assert.Equal(t, "", Client(nil))
+	assert.Equal(t, "bob", Client([]*raftpb.Command{{}, {Client: "bob"}, {Client: "carol"}}))
+
+	g := NewPriorityGate(1)
+	release := g.Acquire(PriorityNormal, "bulk")
+
+	admitted := make(chan string, 4)
+	for _, client := range []string{"bulk", "bulk", "bulk", "web"} {
+		go func(client string) {
+			r := g.Acquire(PriorityNormal, client)
+			admitted <- client
+			r()
+		}(client)
+		// waiters queue in this order
+		time.Sleep(10 * time.Millisecond)
+	}
+	release()
+	// web waits behind a single write of bulk, not all of them
+	assert.Equal(t, "bulk", <-admitted)
+	assert.Equal(t, "web", <-admitted)
+	assert.Equal(t, "bulk", <-admitted)
+	assert.Equal(t, "bulk", <-admitted)
+}
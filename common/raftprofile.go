@@ -0,0 +1,188 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// RaftProfile names the raft tuning profile of the node, one of RaftProfiles.
+var RaftProfile = "default"
+
+// Read modes of the shard leaders.
+const (
+	// ReadLease serves reads locally while the leader lease holds, and with a
+	// read index otherwise.
+	ReadLease = "lease"
+	// ReadIndex confirms the leadership with a quorum before every read.
+	ReadIndex = "readindex"
+	// ReadLocal serves reads locally without any check, they may be stale
+	// right after a partition.
+	ReadLocal = "local"
+)
+
+// ReadMode is the read mode of the shard leaders.
+var ReadMode = ReadLease
+
+// ReadVerifySample is the fraction of the reads the coordinators verify
+// against another replica of the shard, never if 0.
+var ReadVerifySample float64
+
+// Consistency levels of the reads, set by namespace over the read mode.
+const (
+	// ReadLinearizable confirms the leadership with a quorum before every
+	// read.
+	ReadLinearizable = "linearizable"
+	// ReadLeaderLocal has the shard leader serve reads without any check.
+	ReadLeaderLocal = "leader-local"
+	// ReadStale has any replica of the shard serve reads, which lag behind
+	// the leader by the entries it has not applied yet.
+	ReadStale = "stale"
+)
+
+// Acknowledgement levels of the writes, set by namespace.
+const (
+	// AckQuorum acknowledges the writes once committed by a quorum and
+	// applied by the leader.
+	AckQuorum = "quorum"
+	// AckLeader acknowledges the unconditional writes without session once
+	// proposed by the leader. They are lost if the leader fails before they
+	// commit.
+	AckLeader = "leader"
+)
+
+// ValidateConsistency returns an error if read is not a read consistency
+// level or ack not an acknowledgement level, empty values being valid.
+func ValidateConsistency(read, ack string) error {
+	switch read {
+	case "", ReadLinearizable, ReadLeaderLocal, ReadStale:
+	default:
+		return fmt.Errorf("unknown read consistency %q, expecting %s, %s or %s", read, ReadLinearizable, ReadLeaderLocal, ReadStale)
+	}
+	switch ack {
+	case "", AckQuorum, AckLeader:
+	default:
+		return fmt.Errorf("unknown write acknowledgement %q, expecting %s or %s", ack, AckQuorum, AckLeader)
+	}
+	return nil
+}
+
+// raftProfile sets the raft timings, batching and log compaction coherently
+// for a kind of network.
+type raftProfile struct {
+	heartbeat   time.Duration
+	election    time.Duration
+	leaderLease time.Duration
+	commit      time.Duration
+	// maxAppendEntries bounds the entries sent in a single append request
+	maxAppendEntries  int
+	snapshotInterval  time.Duration
+	snapshotThreshold uint64
+	// trailingLogs are kept after a snapshot, so that lagging followers catch
+	// up from the log rather than with a snapshot
+	trailingLogs     uint64
+	transportTimeout time.Duration
+}
+
+var raftProfiles = map[string]raftProfile{
+	// default keeps the hashicorp/raft defaults and the historical snapshot
+	// settings.
+	"default": {
+		heartbeat:         time.Second,
+		election:          time.Second,
+		leaderLease:       500 * time.Millisecond,
+		commit:            50 * time.Millisecond,
+		maxAppendEntries:  64,
+		snapshotInterval:  180 * time.Second,
+		snapshotThreshold: 5,
+		trailingLogs:      10240,
+		transportTimeout:  10 * time.Second,
+	},
+	// lan detects failures fast on a low latency network.
+	"lan": {
+		heartbeat:         500 * time.Millisecond,
+		election:          500 * time.Millisecond,
+		leaderLease:       250 * time.Millisecond,
+		commit:            20 * time.Millisecond,
+		maxAppendEntries:  128,
+		snapshotInterval:  120 * time.Second,
+		snapshotThreshold: 8192,
+		trailingLogs:      10240,
+		transportTimeout:  5 * time.Second,
+	},
+	// wan tolerates round trips of hundreds of milliseconds, sends larger
+	// batches and keeps more log to avoid sending snapshots across regions.
+	"wan": {
+		heartbeat:         3 * time.Second,
+		election:          3 * time.Second,
+		leaderLease:       1500 * time.Millisecond,
+		commit:            100 * time.Millisecond,
+		maxAppendEntries:  256,
+		snapshotInterval:  300 * time.Second,
+		snapshotThreshold: 16384,
+		trailingLogs:      65536,
+		transportTimeout:  30 * time.Second,
+	},
+	// flaky avoids elections on transient packet loss, sends smaller batches
+	// that are cheaper to retry and keeps the most log.
+	"flaky": {
+		heartbeat:         2 * time.Second,
+		election:          5 * time.Second,
+		leaderLease:       time.Second,
+		commit:            50 * time.Millisecond,
+		maxAppendEntries:  32,
+		snapshotInterval:  180 * time.Second,
+		snapshotThreshold: 8192,
+		trailingLogs:      131072,
+		transportTimeout:  20 * time.Second,
+	},
+}
+
+// RaftProfiles returns the names of the raft tuning profiles.
+func RaftProfiles() []string {
+	var names []string
+	for name := range raftProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyRaftProfile sets config from the profile name and returns the timeout
+// of the transport. SnapshotInterval and SnapshotThreshold, when set,
+// override the profile.
+func applyRaftProfile(config *raft.Config, name string) (time.Duration, error) {
+	p, ok := raftProfiles[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown raft profile %q, expected one of %v", name, RaftProfiles())
+	}
+	config.HeartbeatTimeout = p.heartbeat
+	config.ElectionTimeout = p.election
+	config.LeaderLeaseTimeout = p.leaderLease
+	config.CommitTimeout = p.commit
+	config.MaxAppendEntries = p.maxAppendEntries
+	config.SnapshotInterval = p.snapshotInterval
+	config.SnapshotThreshold = p.snapshotThreshold
+	config.TrailingLogs = p.trailingLogs
+	if SnapshotInterval > 0 {
+		config.SnapshotInterval = time.Duration(SnapshotInterval) * time.Second
+	}
+	if SnapshotThreshold > 0 {
+		config.SnapshotThreshold = uint64(SnapshotThreshold)
+	}
+	return p.transportTimeout, nil
+}
+
+// LeaseDuration returns how long after confirming its leadership with a
+// quorum a leader may serve reads locally, 0 if never. Followers do not vote
+// for another candidate before a heartbeat timeout without hearing from the
+// leader, which the clock skew allowed between replicas shortens.
+func LeaseDuration() time.Duration {
+	p, ok := raftProfiles[RaftProfile]
+	if !ok || p.heartbeat <= MaxClockSkew {
+		return 0
+	}
+	return p.heartbeat - MaxClockSkew
+}
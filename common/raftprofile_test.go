@@ -0,0 +1,54 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRaftProfiles(t *testing.T) {
+	defer func(i, th int) { SnapshotInterval, SnapshotThreshold = i, th }(SnapshotInterval, SnapshotThreshold)
+	SnapshotInterval, SnapshotThreshold = 0, 0
+
+	assert.Equal(t, []string{"default", "flaky", "lan", "wan"}, RaftProfiles())
+	for _, name := range RaftProfiles() {
+		config := raft.DefaultConfig()
+		timeout, err := applyRaftProfile(config, name)
+		assert.Nil(t, err)
+		// constraints checked by raft.ValidateConfig
+		assert.True(t, config.LeaderLeaseTimeout <= config.HeartbeatTimeout, name)
+		assert.True(t, config.ElectionTimeout >= config.HeartbeatTimeout, name)
+		assert.True(t, config.CommitTimeout >= 5*time.Millisecond, name)
+		assert.True(t, config.MaxAppendEntries > 0 && config.MaxAppendEntries <= 1024, name)
+		assert.True(t, timeout > config.HeartbeatTimeout, name)
+	}
+
+	config := raft.DefaultConfig()
+	applyRaftProfile(config, "default")
+	assert.Equal(t, 180*time.Second, config.SnapshotInterval)
+	assert.Equal(t, uint64(5), config.SnapshotThreshold)
+
+	SnapshotInterval, SnapshotThreshold = 60, 100
+	applyRaftProfile(config, "wan")
+	assert.Equal(t, 60*time.Second, config.SnapshotInterval)
+	assert.Equal(t, uint64(100), config.SnapshotThreshold)
+	assert.Equal(t, 3*time.Second, config.HeartbeatTimeout)
+
+	_, err := applyRaftProfile(config, "satellite")
+	assert.NotNil(t, err)
+}
+
+func TestLeaseDuration(t *testing.T) {
+	defer func(p string, s time.Duration) { RaftProfile, MaxClockSkew = p, s }(RaftProfile, MaxClockSkew)
+
+	RaftProfile, MaxClockSkew = "default", 200*time.Millisecond
+	assert.Equal(t, 800*time.Millisecond, LeaseDuration())
+	RaftProfile = "wan"
+	assert.Equal(t, 2800*time.Millisecond, LeaseDuration())
+	MaxClockSkew = 5 * time.Second
+	assert.Equal(t, time.Duration(0), LeaseDuration())
+	RaftProfile = "unknown"
+	assert.Equal(t, time.Duration(0), LeaseDuration())
+}
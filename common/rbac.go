@@ -0,0 +1,216 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Access verbs of the roles. Admin covers the administrative operations of
+// the cluster, regardless of the keys.
+const (
+	VerbRead  = "read"
+	VerbWrite = "write"
+	VerbAdmin = "admin"
+	// VerbAll grants every verb.
+	VerbAll = "*"
+)
+
+// System keys of the access policy, replicated by the coordinators: the
+// roles under RolePrefix by name, and the roles bound to a subject, an
+// identity name or group:<name>, under BindingPrefix.
+const (
+	RolePrefix    = SystemPrefix + "rbac/roles/"
+	BindingPrefix = SystemPrefix + "rbac/bindings/"
+	// GroupSubject prefixes the subjects that are groups of identities.
+	GroupSubject = "group:"
+)
+
+var (
+	// RBAC enforces the access policy on the authenticated requests.
+	RBAC bool
+	// RBACAdmins are the subjects granted every verb on every key whatever
+	// the policy, to bootstrap it.
+	RBACAdmins []string
+)
+
+// Rule grants verbs on the keys starting with Prefix.
+type Rule struct {
+	Prefix string   `json:"prefix"`
+	Verbs  []string `json:"verbs"`
+}
+
+// Role is a named set of rules.
+type Role struct {
+	Name  string `json:"name"`
+	Rules []Rule `json:"rules"`
+}
+
+// Binding binds roles to a subject.
+type Binding struct {
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// PolicyView lists the roles and bindings of a policy.
+type PolicyView struct {
+	Roles    []Role    `json:"roles"`
+	Bindings []Binding `json:"bindings"`
+}
+
+// Policy is the access policy: the roles and their bindings, stored as
+// system keys with JSON values.
+type Policy struct {
+	mu       sync.RWMutex
+	roles    map[string]Role
+	bindings map[string][]string
+}
+
+// NewPolicy returns a policy granting nothing.
+func NewPolicy() *Policy {
+	return &Policy{roles: make(map[string]Role), bindings: make(map[string][]string)}
+}
+
+// ValidatePolicyKey checks that value, empty for a deletion, is a valid value
+// of the system key key.
+func ValidatePolicyKey(key string, value []byte) error {
+	switch {
+	case strings.HasPrefix(key, RolePrefix) && len(key) > len(RolePrefix):
+		if len(value) == 0 {
+			return nil
+		}
+		var rules []Rule
+		if err := json.Unmarshal(value, &rules); err != nil {
+			return fmt.Errorf("invalid rules of role %s: %s", key[len(RolePrefix):], err)
+		}
+		for _, r := range rules {
+			for _, v := range r.Verbs {
+				if v != VerbRead && v != VerbWrite && v != VerbAdmin && v != VerbAll {
+					return fmt.Errorf("unknown verb %q", v)
+				}
+			}
+		}
+	case strings.HasPrefix(key, BindingPrefix) && len(key) > len(BindingPrefix):
+		if len(value) == 0 {
+			return nil
+		}
+		var roles []string
+		if err := json.Unmarshal(value, &roles); err != nil {
+			return fmt.Errorf("invalid roles of subject %s: %s", key[len(BindingPrefix):], err)
+		}
+	default:
+		return fmt.Errorf("invalid policy key %q", key)
+	}
+	return nil
+}
+
+// Set sets the system key key to value, deleting it if value is empty.
+func (p *Policy) Set(key string, value []byte) error {
+	if err := ValidatePolicyKey(key, value); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if strings.HasPrefix(key, RolePrefix) {
+		name := key[len(RolePrefix):]
+		if len(value) == 0 {
+			delete(p.roles, name)
+			return nil
+		}
+		role := Role{Name: name}
+		json.Unmarshal(value, &role.Rules)
+		p.roles[name] = role
+		return nil
+	}
+	subject := key[len(BindingPrefix):]
+	if len(value) == 0 {
+		delete(p.bindings, subject)
+		return nil
+	}
+	var roles []string
+	json.Unmarshal(value, &roles)
+	p.bindings[subject] = roles
+	return nil
+}
+
+// Keys returns the system keys of p, for snapshots.
+func (p *Policy) Keys() map[string][]byte {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := make(map[string][]byte, len(p.roles)+len(p.bindings))
+	for name, role := range p.roles {
+		keys[RolePrefix+name], _ = json.Marshal(role.Rules)
+	}
+	for subject, roles := range p.bindings {
+		keys[BindingPrefix+subject], _ = json.Marshal(roles)
+	}
+	return keys
+}
+
+// Restore replaces the roles and bindings of p with those of keys.
+func (p *Policy) Restore(keys map[string][]byte) {
+	restored := NewPolicy()
+	for k, v := range keys {
+		restored.Set(k, v)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roles, p.bindings = restored.roles, restored.bindings
+}
+
+// View returns the roles and bindings of p, sorted by name and subject.
+func (p *Policy) View() *PolicyView {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	v := &PolicyView{Roles: []Role{}, Bindings: []Binding{}}
+	for _, role := range p.roles {
+		v.Roles = append(v.Roles, role)
+	}
+	for subject, roles := range p.bindings {
+		v.Bindings = append(v.Bindings, Binding{Subject: subject, Roles: roles})
+	}
+	sort.Slice(v.Roles, func(i, j int) bool { return v.Roles[i].Name < v.Roles[j].Name })
+	sort.Slice(v.Bindings, func(i, j int) bool { return v.Bindings[i].Subject < v.Bindings[j].Subject })
+	return v
+}
+
+// subjects returns the subjects of id: its name and its groups.
+func subjects(id *Identity) []string {
+	s := []string{id.Name}
+	for _, g := range id.Groups {
+		s = append(s, GroupSubject+g)
+	}
+	return s
+}
+
+// Allowed reports whether id is granted verb on key, or on every key
+// starting with key for the operations on prefixes, by RBACAdmins or by a
+// rule of a role bound to one of its subjects.
+func (p *Policy) Allowed(id *Identity, verb, key string) bool {
+	for _, s := range subjects(id) {
+		for _, admin := range RBACAdmins {
+			if s == admin {
+				return true
+			}
+		}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, s := range subjects(id) {
+		for _, name := range p.bindings[s] {
+			for _, rule := range p.roles[name].Rules {
+				if !strings.HasPrefix(key, rule.Prefix) {
+					continue
+				}
+				for _, v := range rule.Verbs {
+					if v == verb || v == VerbAll {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
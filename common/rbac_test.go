@@ -0,0 +1,40 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicy(t *testing.T) {
+	p := NewPolicy()
+	alice := &Identity{Name: "alice", Groups: []string{"ops"}}
+	assert.False(t, p.Allowed(alice, VerbRead, "app/a"))
+
+	assert.NotNil(t, p.Set(RolePrefix+"bad", []byte(`[{"prefix":"app/","verbs":["delete"]}]`)))
+	assert.NotNil(t, p.Set(SystemPrefix+"other", []byte(`[]`)))
+	assert.Nil(t, p.Set(RolePrefix+"app-reader", []byte(`[{"prefix":"app/","verbs":["read"]}]`)))
+	assert.Nil(t, p.Set(RolePrefix+"root", []byte(`[{"prefix":"","verbs":["*"]}]`)))
+	assert.Nil(t, p.Set(BindingPrefix+"group:ops", []byte(`["app-reader"]`)))
+
+	assert.True(t, p.Allowed(alice, VerbRead, "app/a"))
+	assert.True(t, p.Allowed(alice, VerbRead, "app/"))
+	assert.False(t, p.Allowed(alice, VerbRead, "ap"))
+	assert.False(t, p.Allowed(alice, VerbWrite, "app/a"))
+	assert.False(t, p.Allowed(&Identity{Name: "bob"}, VerbRead, "app/a"))
+
+	// snapshots restore the same policy
+	restored := NewPolicy()
+	restored.Restore(p.Keys())
+	assert.Equal(t, p.View(), restored.View())
+
+	assert.Nil(t, p.Set(BindingPrefix+"alice", []byte(`["root"]`)))
+	assert.True(t, p.Allowed(alice, VerbAdmin, ""))
+	assert.Nil(t, p.Set(BindingPrefix+"alice", nil))
+	assert.False(t, p.Allowed(alice, VerbAdmin, ""))
+	assert.Len(t, p.View().Bindings, 1)
+
+	RBACAdmins = []string{"group:ops"}
+	defer func() { RBACAdmins = nil }()
+	assert.True(t, p.Allowed(alice, VerbAdmin, ""))
+}
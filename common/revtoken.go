@@ -0,0 +1,72 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrRevisionNotApplied is returned by the reads of a revision the shard node
+// serving them has not applied in time.
+var ErrRevisionNotApplied = errors.New("revision not applied")
+
+// RevisionToken is the latest revision seen of every shard, by shard id, as
+// returned by the writes and reads. A read given the token of a client sees
+// every write the client saw before, whichever node serves it. Its text form
+// is shard:revision pairs separated by commas.
+type RevisionToken map[int64]int64
+
+// ParseRevisionToken parses the text form of a revision token.
+func ParseRevisionToken(s string) (RevisionToken, error) {
+	t := RevisionToken{}
+	if s == "" {
+		return t, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.Index(pair, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("invalid revision token %q, expecting shard:revision pairs", s)
+		}
+		shard, err := strconv.ParseInt(pair[:i], 10, 64)
+		if err != nil || shard < 0 {
+			return nil, fmt.Errorf("invalid shard %q in revision token", pair[:i])
+		}
+		rev, err := strconv.ParseInt(pair[i+1:], 10, 64)
+		if err != nil || rev < 0 {
+			return nil, fmt.Errorf("invalid revision %q in revision token", pair[i+1:])
+		}
+		t.Observe(shard, rev)
+	}
+	return t, nil
+}
+
+// Observe records that revision rev of shard was seen. It does nothing on a
+// nil token.
+func (t RevisionToken) Observe(shard, rev int64) {
+	if t != nil && rev > t[shard] {
+		t[shard] = rev
+	}
+}
+
+// Merge records the revisions of o.
+func (t RevisionToken) Merge(o RevisionToken) {
+	for shard, rev := range o {
+		t.Observe(shard, rev)
+	}
+}
+
+// String returns the text form of t, by shard.
+func (t RevisionToken) String() string {
+	shards := make([]int64, 0, len(t))
+	for shard := range t {
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i] < shards[j] })
+	pairs := make([]string, len(shards))
+	for i, shard := range shards {
+		pairs[i] = fmt.Sprintf("%d:%d", shard, t[shard])
+	}
+	return strings.Join(pairs, ",")
+}
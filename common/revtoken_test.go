@@ -0,0 +1,31 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevisionToken(t *testing.T) {
+	token, err := ParseRevisionToken("2:40,0:15,2:30")
+	assert.Nil(t, err)
+	assert.Equal(t, RevisionToken{0: 15, 2: 40}, token)
+	assert.Equal(t, "0:15,2:40", token.String())
+
+	token.Merge(RevisionToken{0: 10, 1: 5})
+	assert.Equal(t, "0:15,1:5,2:40", token.String())
+
+	empty, err := ParseRevisionToken("")
+	assert.Nil(t, err)
+	assert.Equal(t, "", empty.String())
+
+	for _, s := range []string{"15", "a:1", "1:b", "-1:2", "1:2,"} {
+		_, err := ParseRevisionToken(s)
+		assert.NotNil(t, err, s)
+	}
+
+	// a nil token records nothing
+	var none RevisionToken
+	none.Observe(0, 1)
+	assert.Nil(t, none)
+}
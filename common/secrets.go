@@ -0,0 +1,173 @@
+package common
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The TLS certificates, keys and CAs are secret references: a file path,
+// env:<variable>, or vault:<path>#<field> for a field of a secret of the KV
+// engine of Vault, read with VaultToken at VaultAddress.
+var (
+	VaultAddress = os.Getenv("VAULT_ADDR")
+	VaultToken   = os.Getenv("VAULT_TOKEN")
+	// SecretRefresh is how often the certificates and keys are fetched
+	// again, to follow their rotation, never if 0.
+	SecretRefresh = 10 * time.Minute
+	// vaultTimeout bounds the requests to Vault.
+	vaultTimeout = 10 * time.Second
+)
+
+// FetchSecret returns the secret referenced by ref.
+func FetchSecret(ref string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(ref, "env:"):
+		name := strings.TrimPrefix(ref, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("secret %s: $%s is not set", ref, name)
+		}
+		return []byte(v), nil
+	case strings.HasPrefix(ref, "vault:"):
+		return fetchVaultSecret(strings.TrimPrefix(ref, "vault:"))
+	default:
+		return ioutil.ReadFile(ref)
+	}
+}
+
+// fetchVaultSecret returns the field of the secret at path, path#field, of a
+// KV engine of version 1 or 2, the path of the latter including data/.
+func fetchVaultSecret(ref string) ([]byte, error) {
+	i := strings.LastIndex(ref, "#")
+	if i < 0 {
+		return nil, fmt.Errorf("secret vault:%s: no field, expecting vault:<path>#<field>", ref)
+	}
+	path, field := strings.Trim(ref[:i], "/"), ref[i+1:]
+	if VaultAddress == "" {
+		return nil, fmt.Errorf("secret vault:%s: no Vault address", ref)
+	}
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(VaultAddress, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", VaultToken)
+	resp, err := (&http.Client{Timeout: vaultTimeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("secret vault:%s: %s", ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("secret vault:%s: %s", ref, resp.Status)
+	}
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("secret vault:%s: %s", ref, err)
+	}
+	data := body.Data
+	// version 2 nests the fields with the metadata of the secret
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = nested
+		}
+	}
+	v, ok := data[field].(string)
+	if !ok {
+		return nil, fmt.Errorf("secret vault:%s: no field %s", ref, field)
+	}
+	return []byte(v), nil
+}
+
+// certSource holds a certificate and its key, fetched again every
+// SecretRefresh.
+type certSource struct {
+	certRef, keyRef string
+	mu              sync.RWMutex
+	cert            *tls.Certificate
+}
+
+// newCertSource fetches the certificate and key referenced by certRef and
+// keyRef, and keeps them up to date.
+func newCertSource(certRef, keyRef string) (*certSource, error) {
+	s := &certSource{certRef: certRef, keyRef: keyRef}
+	if err := s.fetch(); err != nil {
+		return nil, err
+	}
+	if SecretRefresh > 0 {
+		go s.refresh()
+	}
+	return s, nil
+}
+
+func (s *certSource) fetch() error {
+	certPEM, err := FetchSecret(s.certRef)
+	if err != nil {
+		return err
+	}
+	keyPEM, err := FetchSecret(s.keyRef)
+	if err != nil {
+		return err
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("certificate %s: %s", s.certRef, err)
+	}
+	s.mu.Lock()
+	s.cert = &cert
+	s.mu.Unlock()
+	return nil
+}
+
+// refresh fetches the certificate again every SecretRefresh, keeping the
+// previous one when it fails.
+func (s *certSource) refresh() {
+	for range time.Tick(SecretRefresh) {
+		if err := s.fetch(); err != nil {
+			log.Warnf("unable to refresh the certificate %s, keeping the current one: %s", s.certRef, err)
+		}
+	}
+}
+
+func (s *certSource) certificate() *tls.Certificate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cert
+}
+
+// tlsConfig returns a TLS configuration presenting the current certificate,
+// as a server and as a client.
+func (s *certSource) tlsConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.certificate(), nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.certificate(), nil
+		},
+		MinVersion: tls.VersionTLS12,
+	}
+}
+
+// fetchCertPool returns the pool of the certificates referenced by ref.
+func fetchCertPool(ref string) (*x509.CertPool, error) {
+	pem, err := FetchSecret(ref)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", ref)
+	}
+	return pool, nil
+}
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// selfSigned returns the PEM certificate and key of a new self-signed
+// certificate for name.
+func selfSigned(t *testing.T, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestFetchSecret(t *testing.T) {
+	os.Setenv("RAFTKV_TEST_SECRET", "from env")
+	defer os.Unsetenv("RAFTKV_TEST_SECRET")
+	v, err := FetchSecret("env:RAFTKV_TEST_SECRET")
+	assert.Nil(t, err)
+	assert.Equal(t, "from env", string(v))
+	_, err = FetchSecret("env:RAFTKV_TEST_UNSET")
+	assert.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "secrets")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("from file"), 0600))
+	v, err = FetchSecret(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "from file", string(v))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/raftkv":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"key": "v1"}})
+		case "/v1/secret/data/raftkv":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"data":     map[string]interface{}{"key": "v2"},
+				"metadata": map[string]interface{}{"version": 3},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	VaultAddress, VaultToken = srv.URL, "vault-token"
+	defer func() { VaultAddress, VaultToken = "", "" }()
+
+	v, err = FetchSecret("vault:kv/raftkv#key")
+	assert.Nil(t, err)
+	assert.Equal(t, "v1", string(v))
+	v, err = FetchSecret("vault:secret/data/raftkv#key")
+	assert.Nil(t, err)
+	assert.Equal(t, "v2", string(v))
+	_, err = FetchSecret("vault:secret/data/raftkv#missing")
+	assert.NotNil(t, err)
+	_, err = FetchSecret("vault:secret/data/raftkv")
+	assert.NotNil(t, err)
+	_, err = FetchSecret("vault:secret/data/other#key")
+	assert.NotNil(t, err)
+}
+
+func TestCertSource(t *testing.T) {
+	var mu sync.Mutex
+	cert, key := selfSigned(t, "first")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"cert": cert, "key": key}})
+	}))
+	defer srv.Close()
+	VaultAddress = srv.URL
+	defer func() { VaultAddress = "" }()
+	refresh := SecretRefresh
+	SecretRefresh = 0
+	defer func() { SecretRefresh = refresh }()
+
+	source, err := newCertSource("vault:kv/tls#cert", "vault:kv/tls#key")
+	assert.Nil(t, err)
+	config := source.tlsConfig()
+	first, err := config.GetCertificate(nil)
+	assert.Nil(t, err)
+
+	// the rotated certificate is presented once fetched
+	mu.Lock()
+	cert, key = selfSigned(t, "second")
+	mu.Unlock()
+	assert.Nil(t, source.fetch())
+	second, err := config.GetCertificate(nil)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	// a failed fetch keeps the current certificate
+	mu.Lock()
+	key = "not a key"
+	mu.Unlock()
+	assert.NotNil(t, source.fetch())
+	current, err := config.GetClientCertificate(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, second, current)
+}
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// SessionTimeout is how long a client session is kept without writes, for
+// ever if 0.
+var SessionTimeout = 10 * time.Minute
+
+// ErrUnknownSession is returned for writes of sessions never opened on the
+// shard, closed or expired.
+var ErrUnknownSession = errors.New("unknown or expired session")
+
+// IsUnknownSession reports whether err, possibly flattened by rpc, is
+// ErrUnknownSession.
+func IsUnknownSession(err error) bool {
+	return err != nil && strings.Contains(err.Error(), ErrUnknownSession.Error())
+}
@@ -0,0 +1,226 @@
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/raftpb"
+)
+
+var (
+	// ClusterSecret signs the transaction messages and the writes of the
+	// coordinators to the shard leaders, their other mutating requests to
+	// the store nodes, and the joins of the nodes, which are rejected
+	// unsigned or badly signed. The messages are neither signed nor checked
+	// if empty.
+	ClusterSecret string
+	// SignatureMaxAge is how far from the clock of a shard leader the
+	// signature of a message may be, clock skew included, before it is
+	// rejected as replayed.
+	SignatureMaxAge = time.Minute
+)
+
+// ErrBadSignature is returned for the signed messages without a valid
+// signature.
+var ErrBadSignature = errors.New("bad signature")
+
+// seenSignatures holds the messages verified, which are refused if received
+// again while their signature is recent enough to be accepted.
+var seenSignatures = newSignatureCache()
+
+// signatureCache remembers the signed messages, by key, until their
+// signature is too old to be accepted anyway.
+type signatureCache struct {
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	swept time.Time
+}
+
+func newSignatureCache() *signatureCache {
+	return &signatureCache{seen: make(map[string]time.Time)}
+}
+
+// replayed records the message key signed at signedAt, and reports whether
+// it was already recorded.
+func (c *signatureCache) replayed(key string, signedAt int64) bool {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.swept) > SignatureMaxAge {
+		for k, expiry := range c.seen {
+			if now.After(expiry) {
+				delete(c.seen, k)
+			}
+		}
+		c.swept = now
+	}
+	if _, ok := c.seen[key]; ok {
+		return true
+	}
+	c.seen[key] = time.Unix(0, signedAt).Add(SignatureMaxAge)
+	return false
+}
+
+// SignShardOps returns a copy of ops signed with secret, ops if secret is
+// empty.
+func SignShardOps(secret string, ops *raftpb.ShardOps) *raftpb.ShardOps {
+	if secret == "" {
+		return ops
+	}
+	signed := *ops
+	signed.SignedAt = time.Now().UnixNano()
+	signed.Signature = shardOpsMAC(secret, &signed)
+	return &signed
+}
+
+// VerifyShardOps checks the signature of ops with secret, if not empty, and
+// clears it, not to be replicated with ops.
+func VerifyShardOps(secret string, ops *raftpb.ShardOps) error {
+	if secret == "" {
+		return nil
+	}
+	if len(ops.Signature) == 0 {
+		return fmt.Errorf("%w: unsigned message for txid %s", ErrBadSignature, ops.Txid)
+	}
+	if !hmac.Equal(ops.Signature, shardOpsMAC(secret, ops)) {
+		return fmt.Errorf("%w: txid %s", ErrBadSignature, ops.Txid)
+	}
+	age := time.Since(time.Unix(0, ops.SignedAt))
+	if age > SignatureMaxAge || age < -SignatureMaxAge {
+		return fmt.Errorf("%w: txid %s signed %s ago", ErrBadSignature, ops.Txid, age)
+	}
+	if seenSignatures.replayed(fmt.Sprintf("ops/%s/%s/%d", ops.Txid, ops.MasterKey, ops.SignedAt), ops.SignedAt) {
+		return fmt.Errorf("%w: txid %s replayed", ErrBadSignature, ops.Txid)
+	}
+	ops.Signature, ops.SignedAt = nil, 0
+	return nil
+}
+
+// SignJoin returns a copy of msg signed with secret, msg if secret is empty.
+func SignJoin(secret string, msg *raftpb.JoinMsg) *raftpb.JoinMsg {
+	if secret == "" {
+		return msg
+	}
+	signed := *msg
+	signed.SignedAt = time.Now().UnixNano()
+	signed.Signature = joinMAC(secret, &signed)
+	return &signed
+}
+
+// VerifyJoin checks the signature of msg with secret, if not empty.
+func VerifyJoin(secret string, msg *raftpb.JoinMsg) error {
+	if secret == "" {
+		return nil
+	}
+	if len(msg.Signature) == 0 {
+		return fmt.Errorf("%w: unsigned join of node %s", ErrBadSignature, msg.ID)
+	}
+	if !hmac.Equal(msg.Signature, joinMAC(secret, msg)) {
+		return fmt.Errorf("%w: join of node %s", ErrBadSignature, msg.ID)
+	}
+	age := time.Since(time.Unix(0, msg.SignedAt))
+	if age > SignatureMaxAge || age < -SignatureMaxAge {
+		return fmt.Errorf("%w: join of node %s signed %s ago", ErrBadSignature, msg.ID, age)
+	}
+	if seenSignatures.replayed(fmt.Sprintf("join/%s/%s/%d", msg.ID, msg.TYPE, msg.SignedAt), msg.SignedAt) {
+		return fmt.Errorf("%w: join of node %s replayed", ErrBadSignature, msg.ID)
+	}
+	return nil
+}
+
+// SignRequest returns a copy of req, the request of a mutating rpc method of
+// the store nodes, signed with secret for method, req if secret is empty. req
+// is a pointer to a message with Signature and SignedAt fields.
+func SignRequest(secret, method string, req proto.Message) proto.Message {
+	if secret == "" {
+		return req
+	}
+	v := reflect.New(reflect.TypeOf(req).Elem())
+	v.Elem().Set(reflect.ValueOf(req).Elem())
+	signed := v.Interface().(proto.Message)
+	signedAt := time.Now().UnixNano()
+	v.Elem().FieldByName("SignedAt").SetInt(signedAt)
+	v.Elem().FieldByName("Signature").SetBytes(requestMAC(secret, method, signed))
+	return signed
+}
+
+// VerifyRequest checks the signature of req for method with secret, if not
+// empty, and clears it, not to be replicated with req.
+func VerifyRequest(secret, method string, req proto.Message) error {
+	if secret == "" {
+		return nil
+	}
+	v := reflect.ValueOf(req).Elem()
+	signature := v.FieldByName("Signature").Bytes()
+	signedAt := v.FieldByName("SignedAt").Int()
+	if len(signature) == 0 {
+		return fmt.Errorf("%w: unsigned %s", ErrBadSignature, method)
+	}
+	if !hmac.Equal(signature, requestMAC(secret, method, req)) {
+		return fmt.Errorf("%w: %s", ErrBadSignature, method)
+	}
+	age := time.Since(time.Unix(0, signedAt))
+	if age > SignatureMaxAge || age < -SignatureMaxAge {
+		return fmt.Errorf("%w: %s signed %s ago", ErrBadSignature, method, age)
+	}
+	if seenSignatures.replayed(fmt.Sprintf("%s/%x", method, signature), signedAt) {
+		return fmt.Errorf("%w: %s replayed", ErrBadSignature, method)
+	}
+	v.FieldByName("Signature").SetBytes(nil)
+	v.FieldByName("SignedAt").SetInt(0)
+	return nil
+}
+
+// requestMAC returns the HMAC-SHA256 of req without its signature, for
+// method: a request signed for a method is refused by the others.
+func requestMAC(secret, method string, req proto.Message) []byte {
+	v := reflect.New(reflect.TypeOf(req).Elem())
+	v.Elem().Set(reflect.ValueOf(req).Elem())
+	v.Elem().FieldByName("Signature").SetBytes(nil)
+	return messageMAC(secret+"/"+method, v.Interface().(proto.Message))
+}
+
+// joinMAC returns the HMAC-SHA256 of msg without its signature.
+func joinMAC(secret string, msg *raftpb.JoinMsg) []byte {
+	unsigned := *msg
+	unsigned.Signature = nil
+	return messageMAC(secret, &unsigned)
+}
+
+// shardOpsMAC returns the HMAC-SHA256 of ops without its signature.
+func shardOpsMAC(secret string, ops *raftpb.ShardOps) []byte {
+	unsigned := *ops
+	unsigned.Signature = nil
+	return messageMAC(secret, &unsigned)
+}
+
+// messageMAC returns the HMAC-SHA256 of the deterministic encoding of m, as
+// received through net/rpc: gob drops the empty messages on the way, which
+// the encoding would otherwise tell apart from the missing ones.
+func messageMAC(secret string, m proto.Message) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(m); err != nil {
+		// the messages are all encodable, this one is never verified
+		return nil
+	}
+	received := reflect.New(reflect.TypeOf(m).Elem()).Interface().(proto.Message)
+	if err := gob.NewDecoder(&b).Decode(received); err != nil {
+		return nil
+	}
+	var encoded proto.Buffer
+	encoded.SetDeterministic(true)
+	if err := encoded.Marshal(received); err != nil {
+		return nil
+	}
+	mac.Write(encoded.Bytes())
+	return mac.Sum(nil)
+}
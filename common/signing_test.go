@@ -0,0 +1,154 @@
+package common
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+// sendOps returns ops as received by net/rpc.
+func sendOps(t *testing.T, ops *raftpb.ShardOps) *raftpb.ShardOps {
+	var buf bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(ops))
+	var received raftpb.ShardOps
+	assert.Nil(t, gob.NewDecoder(&buf).Decode(&received))
+	return &received
+}
+
+func TestSignShardOps(t *testing.T) {
+	ops := &raftpb.ShardOps{
+		Txid:  "tx1",
+		Phase: Commit,
+		Cmds: &raftpb.RaftCommand{Commands: []*raftpb.Command{
+			{Method: SET, Key: "a", Value: 1, Cond: &raftpb.Cond{}},
+			{Method: DEL, Key: "b"},
+		}},
+	}
+	signed := SignShardOps("secret", ops)
+	assert.Nil(t, ops.Signature, "the original is not signed")
+
+	received := sendOps(t, signed)
+	assert.Nil(t, VerifyShardOps("secret", received))
+	assert.Nil(t, received.Signature, "the signature is cleared once checked")
+
+	assert.True(t, errors.Is(VerifyShardOps("other", sendOps(t, signed)), ErrBadSignature))
+	assert.True(t, errors.Is(VerifyShardOps("secret", sendOps(t, ops)), ErrBadSignature))
+
+	tampered := sendOps(t, signed)
+	tampered.Phase = Abort
+	assert.True(t, errors.Is(VerifyShardOps("secret", tampered), ErrBadSignature))
+	tampered = sendOps(t, signed)
+	tampered.Cmds.Commands[0].Value = 2
+	assert.True(t, errors.Is(VerifyShardOps("secret", tampered), ErrBadSignature))
+	// every field is signed, not only those the shard leaders act on
+	tampered = sendOps(t, signed)
+	tampered.Cmds.Commands[1].Expires = 1
+	assert.True(t, errors.Is(VerifyShardOps("secret", tampered), ErrBadSignature))
+	tampered = sendOps(t, signed)
+	tampered.Cmds.Commands[1].Cond = &raftpb.Cond{Value: 1}
+	assert.True(t, errors.Is(VerifyShardOps("secret", tampered), ErrBadSignature))
+
+	stale := *ops
+	stale.SignedAt = time.Now().Add(-2 * SignatureMaxAge).UnixNano()
+	stale.Signature = shardOpsMAC("secret", &stale)
+	assert.True(t, errors.Is(VerifyShardOps("secret", &stale), ErrBadSignature))
+
+	// without a secret, nothing is signed nor checked
+	assert.Equal(t, ops, SignShardOps("", ops))
+	assert.Nil(t, VerifyShardOps("", sendOps(t, ops)))
+}
+
+func TestVerifyReplayed(t *testing.T) {
+	ops := &raftpb.ShardOps{Txid: "tx2", MasterKey: "a", Phase: Commit}
+	signed := SignShardOps("secret", ops)
+	assert.Nil(t, VerifyShardOps("secret", sendOps(t, signed)))
+	assert.True(t, errors.Is(VerifyShardOps("secret", sendOps(t, signed)), ErrBadSignature))
+	// signed again, as the coordinators do on every attempt
+	assert.Nil(t, VerifyShardOps("secret", sendOps(t, SignShardOps("secret", ops))))
+
+	msg := SignJoin("secret", &raftpb.JoinMsg{RaftAddress: "10.0.0.1:16000", ID: "node2"})
+	assert.Nil(t, VerifyJoin("secret", msg))
+	assert.True(t, errors.Is(VerifyJoin("secret", msg), ErrBadSignature))
+}
+
+// sendRequest returns req as received by net/rpc into received.
+func sendRequest(t *testing.T, req, received interface{}) {
+	var buf bytes.Buffer
+	assert.Nil(t, gob.NewEncoder(&buf).Encode(req))
+	assert.Nil(t, gob.NewDecoder(&buf).Decode(received))
+}
+
+func TestSignRequest(t *testing.T) {
+	cmd := &raftpb.RaftCommand{Commands: []*raftpb.Command{{Method: SET, Key: "a", Value: 1}}, LockReply: true}
+	signed := SignRequest("secret", "Cohort.ProcessCommands", cmd).(*raftpb.RaftCommand)
+	assert.Nil(t, cmd.Signature, "the original is not signed")
+
+	var received raftpb.RaftCommand
+	sendRequest(t, signed, &received)
+	assert.Nil(t, VerifyRequest("secret", "Cohort.ProcessCommands", &received))
+	assert.Nil(t, received.Signature, "the signature is cleared once checked")
+	assert.Zero(t, received.SignedAt)
+	received = raftpb.RaftCommand{}
+	sendRequest(t, signed, &received)
+	assert.True(t, errors.Is(VerifyRequest("secret", "Cohort.ProcessCommands", &received), ErrBadSignature), "replayed")
+
+	signed = SignRequest("secret", "Cohort.ProcessCommands", cmd).(*raftpb.RaftCommand)
+	received = raftpb.RaftCommand{}
+	sendRequest(t, signed, &received)
+	assert.True(t, errors.Is(VerifyRequest("secret", "Cohort.Bulk", &received), ErrBadSignature), "signed for another method")
+	received = raftpb.RaftCommand{}
+	sendRequest(t, signed, &received)
+	received.Commands[0].Value = 2
+	assert.True(t, errors.Is(VerifyRequest("secret", "Cohort.ProcessCommands", &received), ErrBadSignature))
+	received = raftpb.RaftCommand{}
+	sendRequest(t, cmd, &received)
+	assert.True(t, errors.Is(VerifyRequest("secret", "Cohort.ProcessCommands", &received), ErrBadSignature), "unsigned")
+
+	change := &raftpb.MemberChange{Remove: []string{"node3"}}
+	var receivedChange raftpb.MemberChange
+	sendRequest(t, SignRequest("secret", "Cohort.ChangeMembers", change), &receivedChange)
+	assert.True(t, errors.Is(VerifyRequest("other", "Cohort.ChangeMembers", &receivedChange), ErrBadSignature))
+	receivedChange = raftpb.MemberChange{}
+	sendRequest(t, SignRequest("secret", "Cohort.ChangeMembers", change), &receivedChange)
+	assert.Nil(t, VerifyRequest("secret", "Cohort.ChangeMembers", &receivedChange))
+
+	// without a secret, nothing is signed nor checked
+	assert.Equal(t, cmd, SignRequest("", "Cohort.ProcessCommands", cmd))
+	assert.Nil(t, VerifyRequest("", "Cohort.ProcessCommands", &raftpb.RaftCommand{}))
+}
+
+func TestSignatureCacheExpiry(t *testing.T) {
+	c := newSignatureCache()
+	old := time.Now().Add(-2 * SignatureMaxAge).UnixNano()
+	assert.False(t, c.replayed("a", old))
+	assert.False(t, c.replayed("b", time.Now().UnixNano()))
+	c.swept = time.Time{}
+	assert.False(t, c.replayed("c", time.Now().UnixNano()))
+	assert.Len(t, c.seen, 2, "expired signatures are dropped")
+}
+
+func TestSignJoin(t *testing.T) {
+	msg := &raftpb.JoinMsg{RaftAddress: "10.0.0.1:16000", ID: "node1", Version: ProtocolVersion, ClusterID: "c1"}
+	signed := SignJoin("secret", msg)
+	assert.Nil(t, msg.Signature, "the original is not signed")
+	assert.Nil(t, VerifyJoin("secret", signed))
+
+	assert.True(t, errors.Is(VerifyJoin("other", signed), ErrBadSignature))
+	assert.True(t, errors.Is(VerifyJoin("secret", msg), ErrBadSignature))
+	tampered := *signed
+	tampered.RaftAddress = "10.0.0.2:16000"
+	assert.True(t, errors.Is(VerifyJoin("secret", &tampered), ErrBadSignature))
+
+	stale := *msg
+	stale.SignedAt = time.Now().Add(-2 * SignatureMaxAge).UnixNano()
+	stale.Signature = joinMAC("secret", &stale)
+	assert.True(t, errors.Is(VerifyJoin("secret", &stale), ErrBadSignature))
+
+	assert.Equal(t, msg, SignJoin("", msg))
+	assert.Nil(t, VerifyJoin("", msg))
+}
@@ -0,0 +1,96 @@
+package common
+
+import (
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Kinds of slow operations.
+const (
+	SlowLockWait = "lock"
+	SlowCommit   = "commit"
+	SlowApply    = "apply"
+)
+
+// Thresholds above which operations are recorded in the slow log, disabled
+// if 0.
+var (
+	SlowLockThreshold   time.Duration
+	SlowCommitThreshold time.Duration
+	SlowApplyThreshold  time.Duration
+	SlowLogSize         int
+)
+
+// SlowLog keeps the most recent slow operations in a ring buffer. A nil
+// SlowLog records nothing.
+type SlowLog struct {
+	node string
+
+	mu   sync.Mutex
+	ops  []*raftpb.SlowOp
+	next int
+}
+
+// NewSlowLog returns a slow log of node keeping up to size operations.
+func NewSlowLog(node string, size int) *SlowLog {
+	return &SlowLog{node: node, ops: make([]*raftpb.SlowOp, 0, size)}
+}
+
+// threshold returns the threshold of the given kind of operations.
+func threshold(kind string) time.Duration {
+	switch kind {
+	case SlowLockWait:
+		return SlowLockThreshold
+	case SlowCommit:
+		return SlowCommitThreshold
+	case SlowApply:
+		return SlowApplyThreshold
+	}
+	return 0
+}
+
+// Observe records an operation of the given kind on key, or on the keys of
+// transaction txid, that started at start if it is over the threshold of its
+// kind.
+func (s *SlowLog) Observe(kind, key, txid string, start time.Time) {
+	if s == nil || cap(s.ops) == 0 {
+		return
+	}
+	t := threshold(kind)
+	d := time.Since(start)
+	if t <= 0 || d < t {
+		return
+	}
+	op := &raftpb.SlowOp{
+		Time:     start.UnixNano(),
+		Kind:     kind,
+		Key:      key,
+		Txid:     txid,
+		Duration: int64(d),
+		Node:     s.node,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.ops) < cap(s.ops) {
+		s.ops = append(s.ops, op)
+	} else {
+		s.ops[s.next] = op
+	}
+	s.next = (s.next + 1) % cap(s.ops)
+}
+
+// List returns the recorded operations, most recent first.
+func (s *SlowLog) List() []*raftpb.SlowOp {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]*raftpb.SlowOp, 0, len(s.ops))
+	for i := 1; i <= len(s.ops); i++ {
+		res = append(res, s.ops[(s.next-i+len(s.ops))%len(s.ops)])
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowLog(t *testing.T) {
+	defer func(t0 time.Duration) { SlowApplyThreshold = t0 }(SlowApplyThreshold)
+	SlowApplyThreshold = time.Millisecond
+
+	var nilLog *SlowLog
+	nilLog.Observe(SlowApply, "a", "", time.Now().Add(-time.Second))
+	assert.Nil(t, nilLog.List())
+
+	s := NewSlowLog("n1", 2)
+	s.Observe(SlowApply, "fast", "", time.Now())
+	s.Observe(SlowLockWait, "disabled", "", time.Now().Add(-time.Second))
+	assert.Len(t, s.List(), 0)
+
+	for _, k := range []string{"a", "b", "c"} {
+		s.Observe(SlowApply, k, "", time.Now().Add(-time.Second))
+	}
+	ops := s.List()
+	assert.Len(t, ops, 2)
+	assert.Equal(t, "c", ops[0].Key)
+	assert.Equal(t, "b", ops[1].Key)
+	assert.Equal(t, "n1", ops[0].Node)
+	assert.True(t, ops[0].Duration >= int64(time.Second))
+}
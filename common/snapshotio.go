@@ -0,0 +1,145 @@
+package common
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/raft"
+)
+
+// SnapshotDir holds the snapshots of the raft groups of the node apart from
+// their logs, on another disk for instance, in the raft directories if empty.
+var SnapshotDir string
+
+// SnapshotWriteBandwidth limits the bytes per second written to disk by the
+// snapshots of all the raft groups of the node, which a single goroutine
+// writes and syncs in turn so that they leave the disk to the log writes and
+// their syncs. Written as they come if 0.
+var SnapshotWriteBandwidth int64
+
+// snapshotChunk bounds the bytes of a single write of a snapshot, so that
+// the writes of several snapshots interleave.
+const snapshotChunk = 32 << 10
+
+// SnapshotDirOf returns the directory of the snapshots of the raft group of
+// raftDir.
+func SnapshotDirOf(raftDir string) string {
+	if SnapshotDir == "" {
+		return raftDir
+	}
+	if rel, err := filepath.Rel(RaftPVBaseDir, raftDir); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.Join(SnapshotDir, rel)
+	}
+	return filepath.Join(SnapshotDir, filepath.Clean("/"+raftDir))
+}
+
+// moveSnapshots moves the snapshots of the raft directory raftDir to dir, if
+// it has any and dir has none yet, as raft truncates its log up to them.
+func moveSnapshots(raftDir, dir string) error {
+	from, to := filepath.Join(raftDir, "snapshots"), filepath.Join(dir, "snapshots")
+	if from == to {
+		return nil
+	}
+	old, err := ioutil.ReadDir(from)
+	if err != nil || len(old) == 0 {
+		return nil
+	}
+	if current, err := ioutil.ReadDir(to); err == nil && len(current) > 0 {
+		return nil
+	}
+	os.Remove(to)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(from, to); err != nil {
+		return fmt.Errorf("unable to move the snapshots of %s to %s, move them by hand: %s", raftDir, dir, err)
+	}
+	return nil
+}
+
+// snapshotWrite is a write, or with p nil the close, of a snapshot.
+type snapshotWrite struct {
+	sink raft.SnapshotSink
+	p    []byte
+	done chan error
+}
+
+var (
+	snapshotWritesOnce sync.Once
+	snapshotWrites     chan snapshotWrite
+)
+
+// writeSnapshots writes, and closes, the snapshots of the node in turn, at
+// SnapshotWriteBandwidth if bw is not nil.
+func writeSnapshots(bw *Bandwidth) {
+	for w := range snapshotWrites {
+		if w.p == nil {
+			w.done <- w.sink.Close()
+			continue
+		}
+		n, err := w.sink.Write(w.p)
+		if bw != nil {
+			bw.wait(n)
+		}
+		w.done <- err
+	}
+}
+
+// pacedSnapshotStore has the snapshots it creates written by the snapshot
+// writer of the node.
+type pacedSnapshotStore struct {
+	raft.SnapshotStore
+}
+
+func newPacedSnapshotStore(s raft.SnapshotStore) *pacedSnapshotStore {
+	snapshotWritesOnce.Do(func() {
+		var bw *Bandwidth
+		if SnapshotWriteBandwidth > 0 {
+			bw = NewBandwidth(SnapshotWriteBandwidth)
+		}
+		snapshotWrites = make(chan snapshotWrite)
+		go writeSnapshots(bw)
+	})
+	return &pacedSnapshotStore{SnapshotStore: s}
+}
+
+func (s *pacedSnapshotStore) Create(version raft.SnapshotVersion, index, term uint64, configuration raft.Configuration,
+	configurationIndex uint64, trans raft.Transport) (raft.SnapshotSink, error) {
+	sink, err := s.SnapshotStore.Create(version, index, term, configuration, configurationIndex, trans)
+	if err != nil {
+		return nil, err
+	}
+	return &pacedSnapshotSink{SnapshotSink: sink, done: make(chan error, 1)}, nil
+}
+
+// pacedSnapshotSink hands its writes and its close to the snapshot writer.
+type pacedSnapshotSink struct {
+	raft.SnapshotSink
+	done chan error
+}
+
+func (s *pacedSnapshotSink) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > snapshotChunk {
+			chunk = chunk[:snapshotChunk]
+		}
+		snapshotWrites <- snapshotWrite{sink: s.SnapshotSink, p: chunk, done: s.done}
+		if err := <-s.done; err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+func (s *pacedSnapshotSink) Close() error {
+	snapshotWrites <- snapshotWrite{sink: s.SnapshotSink, done: s.done}
+	return <-s.done
+}
@@ -0,0 +1,69 @@
+package common
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotDirOf(t *testing.T) {
+	defer func(dir, base string) { SnapshotDir, RaftPVBaseDir = dir, base }(SnapshotDir, RaftPVBaseDir)
+	SnapshotDir, RaftPVBaseDir = "", "/pv"
+	assert.Equal(t, "/pv/node-1", SnapshotDirOf("/pv/node-1"))
+
+	SnapshotDir = "/snap"
+	assert.Equal(t, "/snap/node-1", SnapshotDirOf("/pv/node-1"))
+	assert.Equal(t, "/snap/node-1/coords", SnapshotDirOf("/pv/node-1/coords"))
+	assert.Equal(t, "/snap/cohort/pv/node-1", SnapshotDirOf("cohort/pv/node-1"))
+}
+
+func TestMoveSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	raftDir, snapDir := filepath.Join(dir, "raft"), filepath.Join(dir, "snap")
+
+	// nothing to move
+	assert.Nil(t, moveSnapshots(raftDir, snapDir))
+	_, err = os.Stat(snapDir)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(raftDir, "snapshots", "1-10-1"), 0755))
+	assert.Nil(t, moveSnapshots(raftDir, snapDir))
+	_, err = os.Stat(filepath.Join(snapDir, "snapshots", "1-10-1"))
+	assert.Nil(t, err)
+	_, err = os.Stat(filepath.Join(raftDir, "snapshots"))
+	assert.True(t, os.IsNotExist(err))
+
+	// the snapshots already moved are kept
+	assert.Nil(t, os.MkdirAll(filepath.Join(raftDir, "snapshots", "1-5-1"), 0755))
+	assert.Nil(t, moveSnapshots(raftDir, snapDir))
+	_, err = os.Stat(filepath.Join(snapDir, "snapshots", "1-5-1"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+type bufferSink struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (s *bufferSink) ID() string    { return "test" }
+func (s *bufferSink) Cancel() error { return nil }
+func (s *bufferSink) Close() error  { s.closed = true; return nil }
+
+func TestPacedSnapshotSink(t *testing.T) {
+	newPacedSnapshotStore(nil)
+	sink := &bufferSink{}
+	paced := &pacedSnapshotSink{SnapshotSink: sink, done: make(chan error, 1)}
+	data := bytes.Repeat([]byte("x"), 3*snapshotChunk+1)
+	n, err := paced.Write(data)
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Nil(t, paced.Close())
+	assert.True(t, sink.closed)
+	assert.Equal(t, data, sink.Bytes())
+}
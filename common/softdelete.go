@@ -0,0 +1,37 @@
+package common
+
+import (
+	"strings"
+	"time"
+)
+
+// TombstonePrefix is the prefix of the tombstones of the keys soft deleted:
+// the shard of a key keeps its last value under TombstonePrefix+key, to
+// undelete it, until its tombstone expires.
+const TombstonePrefix = SystemPrefix + "deleted/"
+
+// Soft delete settings of the coordinators.
+var (
+	// SoftDeleteRetention is how long the tombstones of the keys deleted
+	// are kept, the deletes being for good if 0.
+	SoftDeleteRetention time.Duration
+	// SoftDeletePrefixes are the prefixes of the keys soft deleted, all if
+	// empty.
+	SoftDeletePrefixes []string
+)
+
+// SoftDeleted reports whether the deletes of key keep a tombstone.
+func SoftDeleted(key string) bool {
+	if SoftDeleteRetention <= 0 || strings.HasPrefix(key, SystemPrefix) {
+		return false
+	}
+	if len(SoftDeletePrefixes) == 0 {
+		return true
+	}
+	for _, prefix := range SoftDeletePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDeleted(t *testing.T) {
+	defer func(retention time.Duration, prefixes []string) {
+		SoftDeleteRetention, SoftDeletePrefixes = retention, prefixes
+	}(SoftDeleteRetention, SoftDeletePrefixes)
+
+	SoftDeleteRetention, SoftDeletePrefixes = 0, nil
+	assert.False(t, SoftDeleted("config/a"))
+
+	SoftDeleteRetention = time.Hour
+	assert.True(t, SoftDeleted("config/a"))
+	// tombstones and the other system keys are deleted for good
+	assert.False(t, SoftDeleted(TombstonePrefix+"config/a"))
+
+	SoftDeletePrefixes = []string{"config/", "flags/"}
+	assert.True(t, SoftDeleted("flags/a"))
+	assert.False(t, SoftDeleted("users/a"))
+}
@@ -0,0 +1,158 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+var (
+	// StateHashInterval is how many raft entries the shard replicas apply
+	// between two hashes of their keys, never if 0.
+	StateHashInterval int64
+	// StateHashHistory is how many hashes the replicas keep.
+	StateHashHistory = 16
+)
+
+// entryHash returns the hash of key k with value v and metadata meta.
+func entryHash(k string, v interface{}, meta KeyMeta) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(k))
+	h.Write([]byte{0})
+	switch val := v.(type) {
+	case *Blob:
+		fmt.Fprintf(h, "%s\x00", val.Codec)
+		h.Write(val.Data)
+	default:
+		fmt.Fprintf(h, "%T\x00%v", v, v)
+	}
+	var b [24]byte
+	binary.BigEndian.PutUint64(b[:], uint64(meta.CreateRevision))
+	binary.BigEndian.PutUint64(b[8:], uint64(meta.ModRevision))
+	binary.BigEndian.PutUint64(b[16:], uint64(meta.Version))
+	h.Write(b[:])
+	return h.Sum64()
+}
+
+// StateHashOf returns the hash of the keys of m with their metadata: the
+// combination of the hashes of every key, whatever their order.
+func StateHashOf(m map[string]interface{}, meta map[string]KeyMeta) uint64 {
+	var sum uint64
+	for k, v := range m {
+		sum ^= entryHash(k, v, meta[k])
+	}
+	return sum
+}
+
+// StateHash returns the hash of the committed keys of the map, as
+// StateHashOf, kept up to date by the writes. It must be called from the
+// goroutine applying the raft entries only, to see all of them applied.
+func (c *Cmap) StateHash() uint64 {
+	return atomic.LoadUint64(&c.stateHash)
+}
+
+// hashValue adds value to the state hash of the map, or removes it if added
+// already, the hash combining the hashes of the keys with XOR. New keys of
+// pending transactions are only known to the leader, so not hashed.
+func (c *Cmap) hashValue(value *Value) {
+	if value.temp {
+		return
+	}
+	h := entryHash(value.k, value.V, value.Meta)
+	for {
+		old := atomic.LoadUint64(&c.stateHash)
+		if atomic.CompareAndSwapUint64(&c.stateHash, old, old^h) {
+			return
+		}
+	}
+}
+
+// StateHashes keeps the most recent hashes of the keys of a replica, by
+// raft index.
+type StateHashes struct {
+	node string
+
+	mu     sync.Mutex
+	hashes []*raftpb.StateHash
+}
+
+// NewStateHashes returns an empty history of the hashes of node.
+func NewStateHashes(node string) *StateHashes {
+	return &StateHashes{node: node}
+}
+
+// Record records hash, the hash of the keys once index is applied. The
+// hashes after index, of a log since replaced, are dropped.
+func (s *StateHashes) Record(index int64, hash uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := sort.Search(len(s.hashes), func(i int) bool { return s.hashes[i].Index >= index })
+	s.hashes = append(s.hashes[:i], &raftpb.StateHash{Index: index, Hash: hash, Node: s.node})
+	if len(s.hashes) > StateHashHistory {
+		s.hashes = s.hashes[len(s.hashes)-StateHashHistory:]
+	}
+}
+
+// List returns the hashes, oldest first.
+func (s *StateHashes) List() []*raftpb.StateHash {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]*raftpb.StateHash, len(s.hashes))
+	copy(res, s.hashes)
+	return res
+}
+
+// Reset drops every hash, once the keys are replaced by a snapshot.
+func (s *StateHashes) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hashes = nil
+}
+
+// StateDivergence is a raft index at which the replicas of a shard hashed
+// their keys differently.
+type StateDivergence struct {
+	Shard  int64             `json:"shard"`
+	Index  int64             `json:"index"`
+	Hashes map[string]string `json:"hashes"`
+}
+
+// CompareStateHashes returns the indexes at which the hashes of the replicas
+// of shard differ, out of the indexes hashed by more than one of them, and
+// the number of those indexes.
+func CompareStateHashes(shard int64, hashes []*raftpb.StateHash) ([]*StateDivergence, int) {
+	byIndex := make(map[int64][]*raftpb.StateHash)
+	for _, h := range hashes {
+		byIndex[h.Index] = append(byIndex[h.Index], h)
+	}
+	indexes := make([]int64, 0, len(byIndex))
+	for index := range byIndex {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+	var res []*StateDivergence
+	compared := 0
+	for _, index := range indexes {
+		hs := byIndex[index]
+		if len(hs) < 2 {
+			continue
+		}
+		compared++
+		for _, h := range hs[1:] {
+			if h.Hash != hs[0].Hash {
+				d := &StateDivergence{Shard: shard, Index: index, Hashes: make(map[string]string)}
+				for _, h := range hs {
+					d.Hashes[h.Node] = fmt.Sprintf("%016x", h.Hash)
+				}
+				res = append(res, d)
+				break
+			}
+		}
+	}
+	return res, compared
+}
@@ -0,0 +1,118 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateHash(t *testing.T) {
+	m1 := NewCmap(log.New(), 0)
+	m2 := NewCmap(log.New(), 0)
+	assert.Equal(t, m1.StateHash(), m2.StateHash())
+
+	// the order of the writes does not matter
+	m1.SetRev("a", int64(1), nil, 1)
+	m1.SetRev("b", &Blob{Codec: "json", Data: []byte("{}")}, nil, 2)
+	m2.SetRev("b", &Blob{Codec: "json", Data: []byte("{}")}, nil, 2)
+	m2.SetRev("a", int64(1), nil, 1)
+	assert.Equal(t, m1.StateHash(), m2.StateHash())
+	assert.Equal(t, m1.StateHash(), StateHashOf(m1.Snapshot(), m1.SnapshotMeta()))
+
+	// values and metadata both count
+	m2.SetRev("a", int64(1), nil, 3)
+	assert.NotEqual(t, m1.StateHash(), m2.StateHash())
+	m1.SetRev("a", int64(2), nil, 3)
+	assert.NotEqual(t, m1.StateHash(), m2.StateHash())
+
+	// pending new keys of transactions do not count
+	before := m1.StateHash()
+	assert.Nil(t, m1.TryLocks([]*raftpb.Command{{Method: SET, Key: "c", Value: 3}}, "tx"))
+	assert.Equal(t, before, m1.StateHash())
+}
+
+// scanStateHash returns the state hash of m computed from all its keys.
+func scanStateHash(m *Cmap) uint64 {
+	var sum uint64
+	for k, v := range m.Map {
+		if !v.temp {
+			sum ^= entryHash(k, v.V, v.Meta)
+		}
+	}
+	return sum
+}
+
+func TestStateHashRolling(t *testing.T) {
+	m := NewCmapFromMap(log.New(), map[string]interface{}{"a": int64(1), "b": int64(2)}, 0)
+	m.RestoreMeta(map[string]KeyMeta{"a": {CreateRevision: 1, ModRevision: 1, Version: 1}})
+	assert.Equal(t, scanStateHash(m), m.StateHash())
+
+	steps := []func(){
+		func() { m.SetRev("c", int64(3), nil, 2) },
+		func() { m.SetRev("a", int64(4), nil, 3) },
+		func() { m.SetIfAbsent("d", int64(5), 4) },
+		func() { m.Del("b") },
+		func() { m.Write([]*raftpb.Command{{Method: SET, Key: "a", Value: 6}, {Method: DEL, Key: "c"}}, 5) },
+		func() {
+			ops := []*raftpb.Command{{Method: SET, Key: "e", Value: 7}, {Method: SET, Key: "d", Value: 8}}
+			m.TryLocks(ops, "tx1")
+		},
+		func() {
+			m.WriteWithLocks([]*raftpb.Command{{Method: SET, Key: "e", Value: 7}, {Method: SET, Key: "d", Value: 8}})
+		},
+		func() {
+			m.TryLocks([]*raftpb.Command{{Method: SET, Key: "f", Value: 9}}, "tx2")
+			m.AbortWithLocks([]*raftpb.Command{{Method: SET, Key: "f", Value: 9}}, "tx2")
+		},
+		func() { m.Evict([]*raftpb.Command{{Method: EVICT, Key: "a", Revision: 5}}) },
+		func() {
+			local := NewEntry("d", m.Map["d"].V, m.Map["d"].Meta)
+			m.RepairKey("d", local, NewEntry("d", int64(10), KeyMeta{CreateRevision: 1, ModRevision: 9, Version: 3}))
+			m.RepairKey("g", nil, NewEntry("g", int64(11), KeyMeta{CreateRevision: 9, ModRevision: 9, Version: 1}))
+		},
+	}
+	for i, step := range steps {
+		step()
+		assert.Equal(t, scanStateHash(m), m.StateHash(), "step %d", i)
+	}
+	assert.NotEqual(t, uint64(0), m.StateHash())
+}
+
+func TestStateHashes(t *testing.T) {
+	history := StateHashHistory
+	StateHashHistory = 3
+	defer func() { StateHashHistory = history }()
+
+	s := NewStateHashes("n1")
+	for i := int64(1); i <= 4; i++ {
+		s.Record(i*10, uint64(i))
+	}
+	list := s.List()
+	assert.Len(t, list, 3)
+	assert.Equal(t, int64(20), list[0].Index)
+	assert.Equal(t, "n1", list[0].Node)
+
+	// a hash at an index already hashed replaces the later ones
+	s.Record(30, 7)
+	list = s.List()
+	assert.Len(t, list, 2)
+	assert.Equal(t, uint64(7), list[1].Hash)
+
+	s.Reset()
+	assert.Empty(t, s.List())
+}
+
+func TestCompareStateHashes(t *testing.T) {
+	hashes := []*raftpb.StateHash{
+		{Index: 10, Hash: 1, Node: "a"}, {Index: 10, Hash: 1, Node: "b"},
+		{Index: 20, Hash: 2, Node: "a"}, {Index: 20, Hash: 3, Node: "b"}, {Index: 20, Hash: 2, Node: "c"},
+		{Index: 30, Hash: 4, Node: "a"},
+	}
+	divergences, compared := CompareStateHashes(1, hashes)
+	assert.Equal(t, 2, compared)
+	assert.Len(t, divergences, 1)
+	assert.Equal(t, int64(20), divergences[0].Index)
+	assert.Equal(t, map[string]string{"a": "0000000000000002", "b": "0000000000000003", "c": "0000000000000002"}, divergences[0].Hashes)
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// Storage metrics, by raft group.
+const (
+	RaftLogFirstIndexMetric = "raftkv_raft_log_first_index"
+	RaftLogLastIndexMetric  = "raftkv_raft_log_last_index"
+	RaftLogSizeMetric       = "raftkv_raft_log_size_bytes"
+	RaftSnapshotSizeMetric  = "raftkv_raft_snapshot_size_bytes"
+	RaftSnapshotAgeMetric   = "raftkv_raft_snapshot_age_seconds"
+	RaftSnapshotIndexMetric = "raftkv_raft_snapshot_index"
+)
+
+// raftStorage is the log and snapshot stores of a raft instance.
+type raftStorage struct {
+	logs      raft.LogStore
+	snapshots raft.SnapshotStore
+	// logFile is the file of the log store, empty in memory
+	logFile string
+	// cache is the cache of the log, nil if none
+	cache *LogCache
+}
+
+// raftStorages are the stores of the raft instances, by instance, guarded by
+// raftClosersMu.
+var raftStorages = make(map[*raft.Raft]raftStorage)
+
+func registerRaftStorage(ra *raft.Raft, s raftStorage) {
+	raftClosersMu.Lock()
+	defer raftClosersMu.Unlock()
+	raftStorages[ra] = s
+}
+
+// RaftStorageStats describes the log and the latest snapshot of a raft
+// instance.
+type RaftStorageStats struct {
+	FirstIndex uint64 `json:"first_index"`
+	LastIndex  uint64 `json:"last_index"`
+	// LogSize is the size of the log file in bytes, 0 in memory.
+	LogSize       int64     `json:"log_size"`
+	SnapshotIndex uint64    `json:"snapshot_index"`
+	SnapshotSize  int64     `json:"snapshot_size"`
+	SnapshotTime  time.Time `json:"snapshot_time,omitempty"`
+	// LogCache is the use of the cache of the log, nil without one.
+	LogCache *LogCacheStats `json:"log_cache,omitempty"`
+}
+
+// RaftStorage returns the storage stats of ra, set up by SetupRaft.
+func RaftStorage(ra *raft.Raft) (RaftStorageStats, error) {
+	var stats RaftStorageStats
+	raftClosersMu.Lock()
+	s, ok := raftStorages[ra]
+	raftClosersMu.Unlock()
+	if !ok {
+		return stats, raft.ErrRaftShutdown
+	}
+	var err error
+	if stats.FirstIndex, err = s.logs.FirstIndex(); err != nil {
+		return stats, err
+	}
+	if stats.LastIndex, err = s.logs.LastIndex(); err != nil {
+		return stats, err
+	}
+	if s.logFile != "" {
+		if fi, err := os.Stat(s.logFile); err == nil {
+			stats.LogSize = fi.Size()
+		}
+	}
+	if s.cache != nil {
+		cache := s.cache.Stats()
+		stats.LogCache = &cache
+	}
+	snapshots, err := s.snapshots.List()
+	if err != nil {
+		return stats, err
+	}
+	// the most recent first
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Index > snapshots[j].Index })
+	if len(snapshots) > 0 {
+		stats.SnapshotIndex = snapshots[0].Index
+		stats.SnapshotSize = snapshots[0].Size
+		stats.SnapshotTime = SnapshotTime(snapshots[0].ID)
+	}
+	return stats, nil
+}
+
+// SnapshotTime returns the time a snapshot was taken from its id, made of its
+// term, index and time in milliseconds, or the zero time if id has another
+// form.
+func SnapshotTime(id string) time.Time {
+	parts := strings.Split(id, "-")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	msec, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, msec*int64(time.Millisecond))
+}
+
+// CompactRaft snapshots ra now, truncating its log, rather than when the
+// snapshot threshold or interval is reached.
+func CompactRaft(ra *raft.Raft) error {
+	if err := ra.Snapshot().Error(); err != nil && err != raft.ErrNothingNewToSnapshot {
+		return err
+	}
+	return nil
+}
+
+// RegisterRaftStorageMetrics registers the storage metrics in m.
+func RegisterRaftStorageMetrics(m *Metrics) {
+	m.Register(RaftLogFirstIndexMetric, GaugeMetric, "First index of the raft log.")
+	m.Register(RaftLogLastIndexMetric, GaugeMetric, "Last index of the raft log.")
+	m.Register(RaftLogSizeMetric, GaugeMetric, "Size of the raft log file in bytes.")
+	m.Register(RaftSnapshotIndexMetric, GaugeMetric, "Last index included in the latest raft snapshot.")
+	m.Register(RaftSnapshotSizeMetric, GaugeMetric, "Size of the latest raft snapshot in bytes.")
+	m.Register(RaftSnapshotAgeMetric, GaugeMetric, "Seconds since the latest raft snapshot was taken.")
+	m.Register(RaftLogCacheHitsMetric, CounterMetric, "Reads of raft log entries served by the log cache.")
+	m.Register(RaftLogCacheMissesMetric, CounterMetric, "Reads of raft log entries missed by the log cache.")
+	m.Register(RaftLogCacheSizeMetric, GaugeMetric, "Bytes of the raft log entries in the log cache.")
+}
+
+// SetRaftStorageMetrics sets the storage metrics of the raft group in m from
+// stats at now.
+func SetRaftStorageMetrics(m *Metrics, group string, stats RaftStorageStats, now time.Time) {
+	m.Set(RaftLogFirstIndexMetric, float64(stats.FirstIndex), "group", group)
+	m.Set(RaftLogLastIndexMetric, float64(stats.LastIndex), "group", group)
+	m.Set(RaftLogSizeMetric, float64(stats.LogSize), "group", group)
+	m.Set(RaftSnapshotIndexMetric, float64(stats.SnapshotIndex), "group", group)
+	m.Set(RaftSnapshotSizeMetric, float64(stats.SnapshotSize), "group", group)
+	if !stats.SnapshotTime.IsZero() {
+		m.Set(RaftSnapshotAgeMetric, now.Sub(stats.SnapshotTime).Seconds(), "group", group)
+	}
+	if stats.LogCache != nil {
+		m.Set(RaftLogCacheHitsMetric, float64(stats.LogCache.Hits), "group", group)
+		m.Set(RaftLogCacheMissesMetric, float64(stats.LogCache.Misses), "group", group)
+		m.Set(RaftLogCacheSizeMetric, float64(stats.LogCache.Size), "group", group)
+	}
+}
@@ -0,0 +1,34 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotTime(t *testing.T) {
+	at := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	assert.True(t, SnapshotTime("3-1024-1588334400000").Equal(at))
+	assert.True(t, SnapshotTime("inmem").IsZero())
+	assert.True(t, SnapshotTime("3-1024-x").IsZero())
+}
+
+func TestSetRaftStorageMetrics(t *testing.T) {
+	m := NewMetrics()
+	RegisterRaftStorageMetrics(m)
+	now := time.Now()
+	SetRaftStorageMetrics(m, "store", RaftStorageStats{FirstIndex: 10, LastIndex: 20}, now)
+	var b bytes.Buffer
+	m.Write(&b)
+	assert.True(t, strings.Contains(b.String(), `raftkv_raft_log_first_index{group="store"} 10`))
+	// no snapshot yet
+	assert.False(t, strings.Contains(b.String(), `raftkv_raft_snapshot_age_seconds{`))
+
+	SetRaftStorageMetrics(m, "store", RaftStorageStats{SnapshotTime: now.Add(-time.Minute)}, now)
+	b.Reset()
+	m.Write(&b)
+	assert.True(t, strings.Contains(b.String(), `raftkv_raft_snapshot_age_seconds{group="store"} 60`))
+}
@@ -0,0 +1,2 @@
+
+RaftAddressIDTYPE (0:	Signature
\ No newline at end of file
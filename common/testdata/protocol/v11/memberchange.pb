@@ -0,0 +1,2 @@
+
+promoteremove
\ No newline at end of file
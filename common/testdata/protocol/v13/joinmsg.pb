@@ -0,0 +1,2 @@
+
+RaftAddressIDTYPE (0:	SignatureB	ClusterID
\ No newline at end of file
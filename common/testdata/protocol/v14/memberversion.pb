@@ -0,0 +1,2 @@
+
+id
\ No newline at end of file
@@ -0,0 +1 @@
+"source*	signature0
\ No newline at end of file
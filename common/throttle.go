@@ -0,0 +1,57 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Proposal throttling, by the number of proposed entries the followers needed
+// for a quorum have not acknowledged yet. Disabled if 0.
+var (
+	// ThrottleLag is the lag from which new proposals are delayed.
+	ThrottleLag int64
+	// RejectLag is the lag from which new proposals are rejected.
+	RejectLag int64
+	// MaxThrottleDelay is the delay of proposals right below RejectLag.
+	MaxThrottleDelay = 100 * time.Millisecond
+)
+
+// ErrOverloaded is returned for proposals rejected because followers lag.
+var ErrOverloaded = errors.New("shard is overloaded")
+
+// IsOverloaded reports whether err, possibly flattened by rpc, is
+// ErrOverloaded.
+func IsOverloaded(err error) bool {
+	return err != nil && strings.Contains(err.Error(), ErrOverloaded.Error())
+}
+
+// ThrottleDelay returns how long to delay a new proposal when lag entries are
+// not acknowledged yet, or ErrOverloaded if it must be rejected. The delay
+// grows linearly from 0 at ThrottleLag to MaxThrottleDelay at RejectLag.
+func ThrottleDelay(lag int64) (time.Duration, error) {
+	return ThrottleDelayAt(lag, PriorityNormal)
+}
+
+// ThrottleDelayAt is ThrottleDelay for a proposal of the given priority. High
+// priority proposals are never throttled, and low priority ones are throttled
+// and rejected at half the lags.
+func ThrottleDelayAt(lag int64, priority int32) (time.Duration, error) {
+	switch {
+	case priority > PriorityNormal:
+		return 0, nil
+	case priority < PriorityNormal:
+		lag *= 2
+	}
+	if RejectLag > 0 && lag >= RejectLag {
+		return 0, fmt.Errorf("%w: followers lag %d entries", ErrOverloaded, lag)
+	}
+	if ThrottleLag <= 0 || lag < ThrottleLag {
+		return 0, nil
+	}
+	if RejectLag <= ThrottleLag {
+		return MaxThrottleDelay, nil
+	}
+	return time.Duration(int64(MaxThrottleDelay) * (lag - ThrottleLag) / (RejectLag - ThrottleLag)), nil
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleDelay(t *testing.T) {
+	defer func(t0, r0 int64, d0 time.Duration) {
+		ThrottleLag, RejectLag, MaxThrottleDelay = t0, r0, d0
+	}(ThrottleLag, RejectLag, MaxThrottleDelay)
+
+	ThrottleLag, RejectLag = 0, 0
+	d, err := ThrottleDelay(1 << 20)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), d)
+
+	ThrottleLag, RejectLag, MaxThrottleDelay = 100, 200, 100*time.Millisecond
+	d, _ = ThrottleDelay(99)
+	assert.Equal(t, time.Duration(0), d)
+	d, _ = ThrottleDelay(150)
+	assert.Equal(t, 50*time.Millisecond, d)
+	_, err = ThrottleDelay(200)
+	assert.True(t, IsOverloaded(err))
+	assert.True(t, IsOverloaded(errors.New(err.Error())))
+
+	RejectLag = 0
+	d, err = ThrottleDelay(1000)
+	assert.Nil(t, err)
+	assert.Equal(t, MaxThrottleDelay, d)
+}
+
+func TestThrottleDelayAt(t *testing.T) {
+	defer func(t0, r0 int64, d0 time.Duration) {
+		ThrottleLag, RejectLag, MaxThrottleDelay = t0, r0, d0
+	}(ThrottleLag, RejectLag, MaxThrottleDelay)
+
+	ThrottleLag, RejectLag, MaxThrottleDelay = 100, 200, 100*time.Millisecond
+	d, err := ThrottleDelayAt(1000, PriorityHigh)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), d)
+	d, _ = ThrottleDelayAt(75, PriorityLow)
+	assert.Equal(t, 50*time.Millisecond, d)
+	_, err = ThrottleDelayAt(100, PriorityLow)
+	assert.True(t, IsOverloaded(err))
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	// DeltaTombstones is the most deleted keys remembered to send the
+	// replicas behind a delta rather than a full snapshot.
+	DeltaTombstones = 100000
+	// DeltaMaxKeys is the most keys set and deleted a delta is sent for, a
+	// full snapshot being cheaper to send beyond.
+	DeltaMaxKeys = 100000
+)
+
+// Tombstones remembers the keys deleted after a raft index, by the index of
+// their deletion. The oldest half is dropped when there are more than limit.
+type Tombstones struct {
+	mu    sync.Mutex
+	limit int
+	// since is the index after which every deletion is known, -1 if unknown
+	since int64
+	keys  map[string]int64
+}
+
+// NewTombstones returns empty tombstones not knowing any deletion yet.
+func NewTombstones(limit int) *Tombstones {
+	return &Tombstones{limit: limit, since: -1, keys: make(map[string]int64)}
+}
+
+// Start sets the index after which the deletions are known, if not set yet.
+func (t *Tombstones) Start(since int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.since < 0 {
+		t.since = since
+	}
+}
+
+// Delete records the deletion of key at rev.
+func (t *Tombstones) Delete(key string, rev int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys[key] = rev
+	if len(t.keys) <= t.limit {
+		return
+	}
+	revs := make([]int64, 0, len(t.keys))
+	for _, r := range t.keys {
+		revs = append(revs, r)
+	}
+	sort.Slice(revs, func(i, j int) bool { return revs[i] < revs[j] })
+	t.since = revs[len(revs)/2]
+	for k, r := range t.keys {
+		if r <= t.since {
+			delete(t.keys, k)
+		}
+	}
+}
+
+// Since returns the keys deleted after rev, and false if some of them are
+// not known anymore.
+func (t *Tombstones) Since(rev int64) ([]string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.since < 0 || rev < t.since {
+		return nil, false
+	}
+	var keys []string
+	for k, r := range t.keys {
+		if r > rev {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, true
+}
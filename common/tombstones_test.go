@@ -0,0 +1,36 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTombstones(t *testing.T) {
+	ts := NewTombstones(4)
+	_, ok := ts.Since(0)
+	assert.False(t, ok)
+
+	ts.Start(10)
+	ts.Start(20)
+	ts.Delete("a", 11)
+	ts.Delete("b", 12)
+	ts.Delete("c", 13)
+
+	keys, ok := ts.Since(10)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"a", "b", "c"}, keys)
+	keys, _ = ts.Since(12)
+	assert.Equal(t, []string{"c"}, keys)
+	_, ok = ts.Since(9)
+	assert.False(t, ok)
+
+	// over the limit, the oldest half is forgotten
+	ts.Delete("d", 14)
+	ts.Delete("e", 15)
+	_, ok = ts.Since(12)
+	assert.False(t, ok)
+	keys, ok = ts.Since(13)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"d", "e"}, keys)
+}
@@ -0,0 +1,108 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Topology is the desired layout of the cluster: the store nodes of every
+// shard, where they run and how many of them vote. The coordinator leader
+// reconciles the shards toward it.
+type Topology struct {
+	Shards []*ShardTopology `json:"shards"`
+}
+
+// ShardTopology is the desired layout of a shard.
+type ShardTopology struct {
+	Shard int64 `json:"shard"`
+	// Replicas is the replication factor of the shard, left as is if 0.
+	Replicas int32           `json:"replicas,omitempty"`
+	Nodes    []*TopologyNode `json:"nodes"`
+}
+
+// TopologyNode is a store node of a shard.
+type TopologyNode struct {
+	ID string `json:"id"`
+	// Address and CohortAddress are the raft addresses of the node in the
+	// store and cohort raft groups.
+	Address       string `json:"address"`
+	CohortAddress string `json:"cohort_address"`
+	// Zone is the failure domain the node is expected to report, not checked
+	// if empty.
+	Zone string `json:"zone,omitempty"`
+}
+
+// ParseTopology decodes and checks the json topology b.
+func ParseTopology(b []byte) (*Topology, error) {
+	var t Topology
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("invalid topology: %s", err)
+	}
+	shards := make(map[int64]bool)
+	for _, s := range t.Shards {
+		if s == nil {
+			return nil, fmt.Errorf("invalid topology: null shard")
+		}
+		if shards[s.Shard] {
+			return nil, fmt.Errorf("shard %d is listed twice", s.Shard)
+		}
+		shards[s.Shard] = true
+		if len(s.Nodes) == 0 {
+			return nil, fmt.Errorf("shard %d has no nodes", s.Shard)
+		}
+		if s.Replicas < 0 || int(s.Replicas) > len(s.Nodes) {
+			return nil, fmt.Errorf("shard %d cannot have %d replicas out of %d nodes", s.Shard, s.Replicas, len(s.Nodes))
+		}
+		ids := make(map[string]bool)
+		for _, n := range s.Nodes {
+			if n == nil || n.ID == "" || n.Address == "" || n.CohortAddress == "" {
+				return nil, fmt.Errorf("shard %d: every node needs an id, an address and a cohort address", s.Shard)
+			}
+			if ids[n.ID] {
+				return nil, fmt.Errorf("shard %d: node %s is listed twice", s.Shard, n.ID)
+			}
+			ids[n.ID] = true
+		}
+	}
+	return &t, nil
+}
+
+// Drift compares the shard with its members, by store node id, the zones
+// reported by the nodes and its replication factor. It returns the
+// differences found, the nodes to join and the members to remove; the
+// members are only removed once every node of the shard has joined, so that
+// the shard never shrinks on the way.
+func (s *ShardTopology) Drift(members []string, zones map[string]string, replicas int32) (drift []string, join []*TopologyNode, remove []string) {
+	current := make(map[string]bool)
+	for _, id := range members {
+		current[id] = true
+	}
+	wanted := make(map[string]bool)
+	for _, n := range s.Nodes {
+		wanted[n.ID] = true
+		if !current[n.ID] {
+			drift = append(drift, fmt.Sprintf("%s is not a member", n.ID))
+			join = append(join, n)
+		} else if zone, ok := zones[n.ID]; ok && n.Zone != "" && zone != n.Zone {
+			drift = append(drift, fmt.Sprintf("%s is in zone %q, not %q", n.ID, zone, n.Zone))
+		}
+	}
+	var extra []string
+	for _, id := range members {
+		if !wanted[id] {
+			extra = append(extra, id)
+		}
+	}
+	sort.Strings(extra)
+	for _, id := range extra {
+		drift = append(drift, fmt.Sprintf("%s is not in the topology", id))
+	}
+	if len(join) == 0 {
+		remove = extra
+	}
+	if s.Replicas > 0 && s.Replicas != replicas {
+		drift = append(drift, fmt.Sprintf("%d replicas, not %d", replicas, s.Replicas))
+	}
+	return drift, join, remove
+}
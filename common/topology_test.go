@@ -0,0 +1,48 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTopology(t *testing.T) {
+	top, err := ParseTopology([]byte(`{"shards": [{"shard": 0, "replicas": 1, "nodes": [
+		{"id": "node-a", "address": "a:17000", "cohort_address": "a:18000", "zone": "z1"}]}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "z1", top.Shards[0].Nodes[0].Zone)
+
+	for _, b := range []string{
+		`{"shards": [{"shard": 0, "nodes": []}]}`,
+		`{"shards": [{"shard": 0, "replicas": 2, "nodes": [{"id": "a", "address": "a", "cohort_address": "a"}]}]}`,
+		`{"shards": [{"shard": 0, "nodes": [{"id": "a", "address": "a"}]}]}`,
+		`{"shards": [{"shard": 0, "nodes": [{"id": "a", "address": "a", "cohort_address": "a"}, {"id": "a", "address": "b", "cohort_address": "b"}]}]}`,
+		`{"shards": [{"shard": 0, "nodes": [{"id": "a", "address": "a", "cohort_address": "a"}]}, {"shard": 0, "nodes": [{"id": "b", "address": "b", "cohort_address": "b"}]}]}`,
+		`not json`,
+	} {
+		_, err := ParseTopology([]byte(b))
+		assert.Error(t, err, b)
+	}
+}
+
+func TestTopologyDrift(t *testing.T) {
+	s := &ShardTopology{Shard: 0, Replicas: 3, Nodes: []*TopologyNode{
+		{ID: "node-a", Zone: "z1"}, {ID: "node-b", Zone: "z2"}, {ID: "node-d"},
+	}}
+
+	// the extra member is kept until the missing node has joined
+	drift, join, remove := s.Drift([]string{"node-a", "node-b", "node-c"}, map[string]string{"node-a": "z1", "node-b": "z3"}, 3)
+	assert.Equal(t, []string{`node-b is in zone "z3", not "z2"`, "node-d is not a member", "node-c is not in the topology"}, drift)
+	assert.Equal(t, []*TopologyNode{s.Nodes[2]}, join)
+	assert.Empty(t, remove)
+
+	drift, join, remove = s.Drift([]string{"node-a", "node-b", "node-c", "node-d"}, nil, 5)
+	assert.Equal(t, []string{"node-c is not in the topology", "5 replicas, not 3"}, drift)
+	assert.Empty(t, join)
+	assert.Equal(t, []string{"node-c"}, remove)
+
+	drift, join, remove = s.Drift([]string{"node-d", "node-b", "node-a"}, nil, 3)
+	assert.Empty(t, drift)
+	assert.Empty(t, join)
+	assert.Empty(t, remove)
+}
@@ -0,0 +1,409 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TransformPrefix is the prefix of the system keys of the value transforms,
+// replicated by the coordinators: the transforms of the blob values of the
+// keys starting with a prefix under TransformPrefix+prefix.
+const TransformPrefix = SystemPrefix + "transforms/"
+
+// Built in value transformers.
+const (
+	// GzipTransform compresses the values.
+	GzipTransform = "gzip"
+	// EnvelopeTransform encrypts each value with AES-GCM under a key of its
+	// own, stored with the value encrypted by the key referenced by the
+	// config of the transform, a secret reference of a 16, 24 or 32 bytes
+	// key in base64.
+	EnvelopeTransform = "envelope"
+	// RedactTransform stores the values as they are and reads them as the
+	// config of the transform, "REDACTED" if empty.
+	RedactTransform = "redact"
+)
+
+// transformMagic starts the values stored transformed, followed by the
+// length of the json of their transforms, the json and the transformed value.
+var transformMagic = []byte("\x00rkvt")
+
+// ValueTransformer transforms the blob values of keys as they are written,
+// and back as they are read.
+type ValueTransformer interface {
+	Encode(key string, data []byte) ([]byte, error)
+	Decode(key string, data []byte) ([]byte, error)
+}
+
+// TransformerFactory returns the transformer of a transform of config.
+type TransformerFactory func(config string) (ValueTransformer, error)
+
+var (
+	transformersMu sync.RWMutex
+	transformers   = map[string]TransformerFactory{}
+)
+
+func init() {
+	RegisterTransformer(GzipTransform, func(string) (ValueTransformer, error) { return gzipTransformer{}, nil })
+	RegisterTransformer(EnvelopeTransform, newEnvelopeTransformer)
+	RegisterTransformer(RedactTransform, func(config string) (ValueTransformer, error) {
+		if config == "" {
+			config = "REDACTED"
+		}
+		return redactTransformer(config), nil
+	})
+}
+
+// RegisterTransformer makes the transforms called name available to the
+// coordinators, replacing any registered under the same name. Transforms
+// pulling in a third party dependency, such as a KMS client, are registered
+// by the application building the coordinator.
+func RegisterTransformer(name string, f TransformerFactory) {
+	transformersMu.Lock()
+	defer transformersMu.Unlock()
+	transformers[name] = f
+}
+
+func lookupTransformer(name string) (TransformerFactory, bool) {
+	transformersMu.RLock()
+	defer transformersMu.RUnlock()
+	f, ok := transformers[name]
+	return f, ok
+}
+
+// TransformSpec is a transform of the values and its config.
+type TransformSpec struct {
+	Name   string `json:"name"`
+	Config string `json:"config,omitempty"`
+}
+
+// TransformRule is the transforms applied in turn to the values of the keys
+// starting with Prefix.
+type TransformRule struct {
+	Prefix     string          `json:"prefix"`
+	Transforms []TransformSpec `json:"transforms"`
+}
+
+// ValidateTransformKey checks that value, empty for a deletion, is a valid
+// value of the system key key.
+func ValidateTransformKey(key string, value []byte) error {
+	if !strings.HasPrefix(key, TransformPrefix) || len(key) == len(TransformPrefix) {
+		return fmt.Errorf("invalid transform key %q", key)
+	}
+	if len(value) == 0 {
+		return nil
+	}
+	var specs []TransformSpec
+	if err := json.Unmarshal(value, &specs); err != nil {
+		return fmt.Errorf("invalid transforms of prefix %s: %s", key[len(TransformPrefix):], err)
+	}
+	for _, spec := range specs {
+		if _, ok := lookupTransformer(spec.Name); !ok {
+			return fmt.Errorf("unknown transform %q", spec.Name)
+		}
+	}
+	return nil
+}
+
+// transformChain is the transformers of a list of transforms.
+type transformChain struct {
+	transformers []ValueTransformer
+}
+
+// Transforms is the transforms of the values by key prefix, stored as system
+// keys with JSON values. The values written are transformed by the rule of
+// the longest prefix of their key, and store the transforms applied to them,
+// so that they are read back whatever the rules have become since.
+type Transforms struct {
+	mu    sync.RWMutex
+	rules map[string][]TransformSpec
+
+	chainsMu sync.Mutex
+	// chains are the transformers built, by json of their transforms
+	chains map[string]*transformChain
+}
+
+// NewTransforms returns transforms leaving every value as it is.
+func NewTransforms() *Transforms {
+	return &Transforms{rules: make(map[string][]TransformSpec), chains: make(map[string]*transformChain)}
+}
+
+// Set sets the system key key to value, deleting it if value is empty.
+func (t *Transforms) Set(key string, value []byte) error {
+	if err := ValidateTransformKey(key, value); err != nil {
+		return err
+	}
+	prefix := key[len(TransformPrefix):]
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var specs []TransformSpec
+	json.Unmarshal(value, &specs)
+	if len(specs) == 0 {
+		delete(t.rules, prefix)
+		return nil
+	}
+	t.rules[prefix] = specs
+	return nil
+}
+
+// Keys returns the system keys of t, for snapshots.
+func (t *Transforms) Keys() map[string][]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	keys := make(map[string][]byte, len(t.rules))
+	for prefix, specs := range t.rules {
+		keys[TransformPrefix+prefix], _ = json.Marshal(specs)
+	}
+	return keys
+}
+
+// Restore replaces the rules of t with those of keys.
+func (t *Transforms) Restore(keys map[string][]byte) {
+	restored := NewTransforms()
+	for k, v := range keys {
+		restored.Set(k, v)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rules = restored.rules
+}
+
+// Rules returns the rules of t, sorted by prefix.
+func (t *Transforms) Rules() []TransformRule {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rules := []TransformRule{}
+	for prefix, specs := range t.rules {
+		rules = append(rules, TransformRule{Prefix: prefix, Transforms: specs})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Prefix < rules[j].Prefix })
+	return rules
+}
+
+// rule returns the transforms of the longest prefix of key, nil if none.
+func (t *Transforms) rule(key string) []TransformSpec {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var longest string
+	var specs []TransformSpec
+	for prefix, s := range t.rules {
+		if strings.HasPrefix(key, prefix) && len(prefix) >= len(longest) {
+			longest, specs = prefix, s
+		}
+	}
+	return specs
+}
+
+// Applies reports whether the values of key are transformed.
+func (t *Transforms) Applies(key string) bool {
+	return len(t.rule(key)) > 0
+}
+
+// chain returns the transformers of the transforms of json header.
+func (t *Transforms) chain(header []byte) (*transformChain, error) {
+	t.chainsMu.Lock()
+	defer t.chainsMu.Unlock()
+	if c, ok := t.chains[string(header)]; ok {
+		return c, nil
+	}
+	var specs []TransformSpec
+	if err := json.Unmarshal(header, &specs); err != nil {
+		return nil, err
+	}
+	c := &transformChain{}
+	for _, spec := range specs {
+		f, ok := lookupTransformer(spec.Name)
+		if !ok {
+			return nil, fmt.Errorf("unknown transform %q", spec.Name)
+		}
+		tr, err := f(spec.Config)
+		if err != nil {
+			return nil, fmt.Errorf("transform %s: %s", spec.Name, err)
+		}
+		c.transformers = append(c.transformers, tr)
+	}
+	t.chains[string(header)] = c
+	return c, nil
+}
+
+// Encode returns data, the blob value written to key, transformed by the
+// rule of key, as it is if none.
+func (t *Transforms) Encode(key string, data []byte) ([]byte, error) {
+	specs := t.rule(key)
+	if len(specs) == 0 {
+		return data, nil
+	}
+	header, _ := json.Marshal(specs)
+	c, err := t.chain(header)
+	if err != nil {
+		return nil, err
+	}
+	for _, tr := range c.transformers {
+		if data, err = tr.Encode(key, data); err != nil {
+			return nil, err
+		}
+	}
+	var n [binary.MaxVarintLen64]byte
+	l := binary.PutUvarint(n[:], uint64(len(header)))
+	out := make([]byte, 0, len(transformMagic)+l+len(header)+len(data))
+	out = append(append(append(append(out, transformMagic...), n[:l]...), header...), data...)
+	return out, nil
+}
+
+// Decode returns data, the blob value read from key, transformed back by the
+// transforms it was written with, as it is if written without.
+func (t *Transforms) Decode(key string, data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, transformMagic) {
+		return data, nil
+	}
+	rest := data[len(transformMagic):]
+	l, n := binary.Uvarint(rest)
+	if n <= 0 || uint64(len(rest)-n) < l {
+		return nil, errors.New("truncated transformed value")
+	}
+	c, err := t.chain(rest[n : n+int(l)])
+	if err != nil {
+		return nil, err
+	}
+	data = rest[n+int(l):]
+	for i := len(c.transformers) - 1; i >= 0; i-- {
+		if data, err = c.transformers[i].Decode(key, data); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+type gzipTransformer struct{}
+
+func (gzipTransformer) Encode(key string, data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (gzipTransformer) Decode(key string, data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+type redactTransformer string
+
+func (redactTransformer) Encode(key string, data []byte) ([]byte, error) { return data, nil }
+
+func (r redactTransformer) Decode(key string, data []byte) ([]byte, error) {
+	return []byte(r), nil
+}
+
+// envelopeTransformer encrypts the values with keys of their own, encrypted
+// by the key encryption key kek.
+type envelopeTransformer struct {
+	kek cipher.AEAD
+}
+
+func newEnvelopeTransformer(config string) (ValueTransformer, error) {
+	if config == "" {
+		return nil, errors.New("no key reference")
+	}
+	key, err := FetchSecret(config)
+	if err != nil {
+		return nil, err
+	}
+	if key, err = base64.StdEncoding.DecodeString(string(bytes.TrimSpace(key))); err != nil {
+		return nil, fmt.Errorf("key %s is not in base64: %s", config, err)
+	}
+	kek, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeTransformer{kek: kek}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal appends the nonce and the encryption of data by aead to out.
+func seal(aead cipher.AEAD, out, data, ad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(append(out, nonce...), nonce, data, ad), nil
+}
+
+// open decrypts data sealed by aead, and returns the rest of data after
+// the first sealed n bytes of plaintext.
+func open(aead cipher.AEAD, data []byte, n int, ad []byte) ([]byte, []byte, error) {
+	size := aead.NonceSize() + n + aead.Overhead()
+	if n < 0 {
+		size = len(data)
+	}
+	if len(data) < size || size < aead.NonceSize()+aead.Overhead() {
+		return nil, nil, errors.New("truncated encrypted value")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():size], ad)
+	return plain, data[size:], err
+}
+
+// dataKeySize is the size of the keys the values are encrypted with.
+const dataKeySize = 32
+
+// Encode encrypts data with a new key, the key with the key encryption key,
+// and returns the encrypted key followed by the encrypted data. Both are
+// bound to key, so that a value cannot be moved to another key.
+func (e *envelopeTransformer) Encode(key string, data []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	out, err := seal(e.kek, nil, dataKey, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return seal(aead, out, data, []byte(key))
+}
+
+func (e *envelopeTransformer) Decode(key string, data []byte) ([]byte, error) {
+	dataKey, rest, err := open(e.kek, data, dataKeySize, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plain, _, err := open(aead, rest, -1, []byte(key))
+	return plain, err
+}
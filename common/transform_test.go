@@ -0,0 +1,105 @@
+package common
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setTransforms(t *testing.T, tr *Transforms, prefix string, specs ...TransformSpec) {
+	value, _ := json.Marshal(specs)
+	if len(specs) == 0 {
+		value = nil
+	}
+	assert.Nil(t, tr.Set(TransformPrefix+prefix, value))
+}
+
+func TestTransformRules(t *testing.T) {
+	tr := NewTransforms()
+	assert.NotNil(t, tr.Set(TransformPrefix+"secrets/", []byte(`[{"name":"rot13"}]`)))
+	assert.NotNil(t, tr.Set(TransformPrefix, []byte(`[{"name":"gzip"}]`)))
+	assert.NotNil(t, tr.Set(TransformPrefix+"secrets/", []byte(`{`)))
+
+	setTransforms(t, tr, "logs/", TransformSpec{Name: GzipTransform})
+	setTransforms(t, tr, "logs/audit/", TransformSpec{Name: RedactTransform})
+	assert.True(t, tr.Applies("logs/a"))
+	assert.False(t, tr.Applies("users/a"))
+	assert.Equal(t, []TransformSpec{{Name: RedactTransform}}, tr.rule("logs/audit/1"))
+	assert.Equal(t, []TransformSpec{{Name: GzipTransform}}, tr.rule("logs/app/1"))
+	assert.Equal(t, []TransformRule{
+		{Prefix: "logs/", Transforms: []TransformSpec{{Name: GzipTransform}}},
+		{Prefix: "logs/audit/", Transforms: []TransformSpec{{Name: RedactTransform}}},
+	}, tr.Rules())
+
+	restored := NewTransforms()
+	restored.Restore(tr.Keys())
+	assert.Equal(t, tr.Rules(), restored.Rules())
+
+	setTransforms(t, tr, "logs/audit/")
+	assert.Equal(t, []TransformSpec{{Name: GzipTransform}}, tr.rule("logs/audit/1"))
+}
+
+func TestTransformValues(t *testing.T) {
+	kek := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	os.Setenv("RAFTKV_TEST_KEK", kek)
+	defer os.Unsetenv("RAFTKV_TEST_KEK")
+
+	tr := NewTransforms()
+	setTransforms(t, tr, "secrets/", TransformSpec{Name: GzipTransform}, TransformSpec{Name: EnvelopeTransform, Config: "env:RAFTKV_TEST_KEK"})
+	setTransforms(t, tr, "audit/", TransformSpec{Name: RedactTransform, Config: "***"})
+	value := bytes.Repeat([]byte("secret value "), 100)
+
+	// values out of the rules are kept as they are
+	stored, err := tr.Encode("users/a", value)
+	assert.Nil(t, err)
+	assert.Equal(t, value, stored)
+	decoded, err := tr.Decode("users/a", stored)
+	assert.Nil(t, err)
+	assert.Equal(t, value, decoded)
+
+	stored, err = tr.Encode("secrets/a", value)
+	assert.Nil(t, err)
+	assert.False(t, bytes.Contains(stored, []byte("secret value")))
+	assert.True(t, len(stored) < len(value))
+	again, err := tr.Encode("secrets/a", value)
+	assert.Nil(t, err)
+	assert.NotEqual(t, stored, again)
+	decoded, err = tr.Decode("secrets/a", stored)
+	assert.Nil(t, err)
+	assert.Equal(t, value, decoded)
+
+	// an encrypted value is bound to its key
+	_, err = tr.Decode("secrets/b", stored)
+	assert.NotNil(t, err)
+
+	// the values are read back once their rule is deleted
+	setTransforms(t, tr, "secrets/")
+	decoded, err = tr.Decode("secrets/a", stored)
+	assert.Nil(t, err)
+	assert.Equal(t, value, decoded)
+
+	stored, err = tr.Encode("audit/a", value)
+	assert.Nil(t, err)
+	decoded, err = tr.Decode("audit/a", stored)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("***"), decoded)
+
+	_, err = tr.Decode("audit/a", stored[:len(transformMagic)+1])
+	assert.NotNil(t, err)
+}
+
+func TestEnvelopeTransformKey(t *testing.T) {
+	_, err := newEnvelopeTransformer("")
+	assert.NotNil(t, err)
+	os.Setenv("RAFTKV_TEST_KEK", "not base64!")
+	defer os.Unsetenv("RAFTKV_TEST_KEK")
+	_, err = newEnvelopeTransformer("env:RAFTKV_TEST_KEK")
+	assert.NotNil(t, err)
+	os.Setenv("RAFTKV_TEST_KEK", base64.StdEncoding.EncodeToString([]byte("short")))
+	_, err = newEnvelopeTransformer("env:RAFTKV_TEST_KEK")
+	assert.NotNil(t, err)
+}
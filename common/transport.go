@@ -0,0 +1,240 @@
+package common
+
+import (
+	"compress/flate"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/hashicorp/raft"
+	log "github.com/sirupsen/logrus"
+)
+
+// Raft transport settings.
+var (
+	// RaftTLSCert and RaftTLSKey are the certificate and key of the node for
+	// the raft connections, which are in clear if not set.
+	RaftTLSCert string
+	RaftTLSKey  string
+	// RaftTLSCA verifies the certificates of the peers, which must then
+	// present one, instead of the system roots.
+	RaftTLSCA string
+	// RaftCompress compresses the raft connections.
+	RaftCompress bool
+	// RaftMaxPool is the number of connections kept open to every peer.
+	RaftMaxPool = 3
+)
+
+// streamLayer carries raft connections over TLS and compression, opened by
+// a handshake naming the cluster if cluster is set.
+type streamLayer struct {
+	net.Listener
+	advertise net.Addr
+	tls       *tls.Config
+	compress  bool
+	cluster   string
+}
+
+// newStreamLayer listens on bindAddr for raft connections of the PeerAllow
+// addresses, secured by tlsConfig if not nil and compressed if compress.
+func newStreamLayer(bindAddr string, advertise net.Addr, tlsConfig *tls.Config, compress bool) (*streamLayer, error) {
+	ln, err := net.Listen("tcp", bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	if ln, err = AllowListener(ln, PeerAllow, log.WithField("component", "raft")); err != nil {
+		return nil, err
+	}
+	return &streamLayer{Listener: ln, advertise: advertise, tls: tlsConfig, compress: compress, cluster: Cluster}, nil
+}
+
+// Dial opens a connection to the peer at address.
+func (s *streamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if s.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", string(address), s.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", string(address))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.cluster != "" {
+		if err := writeClusterHandshake(conn, s.cluster); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if !s.compress {
+		return conn, nil
+	}
+	return newCompressedConn(conn), nil
+}
+
+// Accept waits for the next connection of a peer.
+func (s *streamLayer) Accept() (net.Conn, error) {
+	conn, err := s.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if s.tls != nil {
+		conn = tls.Server(conn, s.tls)
+	}
+	if s.cluster != "" {
+		conn = &clusterConn{Conn: conn, cluster: s.cluster}
+	}
+	if s.compress {
+		conn = newCompressedConn(conn)
+	}
+	return conn, nil
+}
+
+// Addr returns the address advertised to the peers.
+func (s *streamLayer) Addr() net.Addr {
+	if s.advertise != nil {
+		return s.advertise
+	}
+	return s.Listener.Addr()
+}
+
+// compressedConn compresses what is written to a connection and decompresses
+// what is read from it. Every write is flushed, raft buffers whole messages.
+type compressedConn struct {
+	net.Conn
+	r io.ReadCloser
+	w *flate.Writer
+}
+
+func newCompressedConn(conn net.Conn) *compressedConn {
+	w, _ := flate.NewWriter(conn, flate.BestSpeed)
+	return &compressedConn{Conn: conn, r: flate.NewReader(conn), w: w}
+}
+
+func (c *compressedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *compressedConn) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, c.w.Flush()
+}
+
+func (c *compressedConn) Close() error {
+	c.r.Close()
+	return c.Conn.Close()
+}
+
+// clusterMagic opens the handshake of the raft connections.
+const clusterMagic = "RAFTKV-CLUSTER"
+
+// writeClusterHandshake names the cluster of the node on conn.
+func writeClusterHandshake(conn net.Conn, cluster string) error {
+	if len(cluster) > 255 {
+		return fmt.Errorf("cluster id %q is too long", cluster)
+	}
+	b := append([]byte(clusterMagic), 0, byte(len(cluster)))
+	_, err := conn.Write(append(b, cluster...))
+	return err
+}
+
+// clusterConn is a raft connection accepted from a peer, which must name
+// the cluster of the node before anything else is read from it. The check
+// is done on the first read, not to block the accepting loop.
+type clusterConn struct {
+	net.Conn
+	cluster string
+	checked bool
+}
+
+func (c *clusterConn) Read(b []byte) (int, error) {
+	if !c.checked {
+		if err := c.check(); err != nil {
+			log.WithField("component", "raft").Warnf("refusing raft connection of %s: %s", c.RemoteAddr(), err)
+			c.Conn.Close()
+			return 0, err
+		}
+		c.checked = true
+	}
+	return c.Conn.Read(b)
+}
+
+func (c *clusterConn) check() error {
+	header := make([]byte, len(clusterMagic)+2)
+	if _, err := io.ReadFull(c.Conn, header); err != nil {
+		return err
+	}
+	if string(header[:len(clusterMagic)]) != clusterMagic || header[len(clusterMagic)] != 0 {
+		return errors.New("no cluster handshake, the peer has no cluster id")
+	}
+	peer := make([]byte, header[len(header)-1])
+	if _, err := io.ReadFull(c.Conn, peer); err != nil {
+		return err
+	}
+	if string(peer) != c.cluster {
+		return fmt.Errorf("the peer is in cluster %q, not %q", peer, c.cluster)
+	}
+	return nil
+}
+
+// raftTLSConfig returns the TLS configuration of the raft connections, nil if
+// they are in clear.
+func raftTLSConfig() (*tls.Config, error) {
+	if RaftTLSCert == "" && RaftTLSKey == "" {
+		if RaftTLSCA != "" {
+			return nil, errors.New("a raft TLS certificate and key are required with a CA")
+		}
+		return nil, nil
+	}
+	source, err := newCertSource(RaftTLSCert, RaftTLSKey)
+	if err != nil {
+		return nil, err
+	}
+	config := source.tlsConfig()
+	if RaftTLSCA != "" {
+		pool, err := fetchCertPool(RaftTLSCA)
+		if err != nil {
+			return nil, err
+		}
+		// peers are both clients and servers
+		config.RootCAs = pool
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// newRaftTransport returns the transport of the raft instance bound to
+// raftAddress, with TLS, compression and the PeerAllow allowlist if
+// configured, and the append requests pipelined to every follower bounded by
+// MaxInflight and MaxInflightBytes.
+func newRaftTransport(raftAddress string, advertise net.Addr, timeout time.Duration) (raft.Transport, error) {
+	tlsConfig, err := raftTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	var transport *raft.NetworkTransport
+	if tlsConfig == nil && !RaftCompress && len(PeerAllow) == 0 && Cluster == "" {
+		if transport, err = raft.NewTCPTransport(raftAddress, advertise, RaftMaxPool, timeout, os.Stderr); err != nil {
+			return nil, err
+		}
+	} else {
+		stream, err := newStreamLayer(raftAddress, advertise, tlsConfig, RaftCompress)
+		if err != nil {
+			return nil, err
+		}
+		transport = raft.NewNetworkTransport(stream, RaftMaxPool, timeout, os.Stderr)
+	}
+	if MaxInflight == 0 && MaxInflightBytes == 0 {
+		return transport, nil
+	}
+	return &windowedTransport{NetworkTransport: transport, maxMsgs: MaxInflight, maxBytes: MaxInflightBytes}, nil
+}
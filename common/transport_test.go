@@ -0,0 +1,76 @@
+package common
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressedConn(t *testing.T) {
+	a, b := net.Pipe()
+	client, server := newCompressedConn(a), newCompressedConn(b)
+	defer client.Close()
+	defer server.Close()
+
+	msg := bytes.Repeat([]byte("raft-kv-store "), 1000)
+	go func() {
+		// each write is flushed, so a message is readable without closing
+		client.Write(msg)
+	}()
+	got := make([]byte, len(msg))
+	_, err := io.ReadFull(server, got)
+	assert.Nil(t, err)
+	assert.Equal(t, msg, got)
+
+	go server.Write([]byte("ack"))
+	got = make([]byte, 3)
+	_, err = io.ReadFull(client, got)
+	assert.Nil(t, err)
+	assert.Equal(t, "ack", string(got))
+}
+
+func TestRaftTLSConfig(t *testing.T) {
+	config, err := raftTLSConfig()
+	assert.Nil(t, err)
+	assert.Nil(t, config)
+
+	RaftTLSCA = "ca.pem"
+	defer func() { RaftTLSCA = "" }()
+	_, err = raftTLSConfig()
+	assert.NotNil(t, err)
+}
+
+func TestClusterConn(t *testing.T) {
+	for _, tc := range []struct {
+		peer string
+		ok   bool
+	}{
+		{"prod", true},
+		{"staging", false},
+		{"", false},
+	} {
+		a, b := net.Pipe()
+		server := &clusterConn{Conn: b, cluster: "prod"}
+		go func() {
+			if tc.peer != "" {
+				writeClusterHandshake(a, tc.peer)
+			} else {
+				a.Write(bytes.Repeat([]byte{1}, 32))
+			}
+			a.Write([]byte("raft"))
+		}()
+		got := make([]byte, 4)
+		_, err := io.ReadFull(server, got)
+		if tc.ok {
+			assert.Nil(t, err)
+			assert.Equal(t, "raft", string(got))
+		} else {
+			assert.NotNil(t, err, tc.peer)
+		}
+		a.Close()
+		b.Close()
+	}
+}
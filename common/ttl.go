@@ -0,0 +1,170 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TTLPrefix is the prefix of the system keys of the ttl policies, replicated
+// by the coordinators: the policy of the keys starting with a prefix under
+// TTLPrefix+prefix.
+const TTLPrefix = SystemPrefix + "ttl/"
+
+// ExpiryInterval is how often the shard leaders delete the keys past their
+// expiry time, never if 0.
+var ExpiryInterval = time.Second
+
+// ErrInvalidTTL is returned for the writes whose ttl breaks the policy of
+// their key.
+var ErrInvalidTTL = errors.New("invalid ttl")
+
+// TTLPolicy is the time to live of the keys of a prefix, as durations such as
+// 24h: Default for the keys written without one, and Max, if set, bounding
+// the ttl of the keys, which get it if written without one and without
+// Default.
+type TTLPolicy struct {
+	Default string `json:"default,omitempty"`
+	Max     string `json:"max,omitempty"`
+}
+
+// durations returns the default and max ttl of p.
+func (p TTLPolicy) durations() (def, max time.Duration, err error) {
+	if p.Default != "" {
+		if def, err = time.ParseDuration(p.Default); err != nil || def <= 0 {
+			return 0, 0, fmt.Errorf("invalid default ttl %q", p.Default)
+		}
+	}
+	if p.Max != "" {
+		if max, err = time.ParseDuration(p.Max); err != nil || max <= 0 {
+			return 0, 0, fmt.Errorf("invalid max ttl %q", p.Max)
+		}
+	}
+	if max > 0 && def > max {
+		return 0, 0, fmt.Errorf("default ttl %s over the max ttl %s", def, max)
+	}
+	return def, max, nil
+}
+
+// TTLRule is the ttl policy of the keys starting with Prefix.
+type TTLRule struct {
+	Prefix string `json:"prefix"`
+	TTLPolicy
+}
+
+// ValidateTTLKey checks that value, empty for a deletion, is a valid value
+// of the system key key.
+func ValidateTTLKey(key string, value []byte) error {
+	if !strings.HasPrefix(key, TTLPrefix) || len(key) == len(TTLPrefix) {
+		return fmt.Errorf("invalid ttl key %q", key)
+	}
+	if len(value) == 0 {
+		return nil
+	}
+	var p TTLPolicy
+	if err := json.Unmarshal(value, &p); err != nil {
+		return fmt.Errorf("invalid ttl policy of prefix %s: %s", key[len(TTLPrefix):], err)
+	}
+	_, _, err := p.durations()
+	return err
+}
+
+// TTLPolicies is the ttl policies by key prefix, stored as system keys with
+// JSON values. The keys written get the policy of their longest prefix.
+type TTLPolicies struct {
+	mu       sync.RWMutex
+	policies map[string]TTLPolicy
+}
+
+// NewTTLPolicies returns policies leaving the keys without ttl.
+func NewTTLPolicies() *TTLPolicies {
+	return &TTLPolicies{policies: make(map[string]TTLPolicy)}
+}
+
+// Set sets the system key key to value, deleting it if value is empty.
+func (t *TTLPolicies) Set(key string, value []byte) error {
+	if err := ValidateTTLKey(key, value); err != nil {
+		return err
+	}
+	prefix := key[len(TTLPrefix):]
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(value) == 0 {
+		delete(t.policies, prefix)
+		return nil
+	}
+	var p TTLPolicy
+	json.Unmarshal(value, &p)
+	t.policies[prefix] = p
+	return nil
+}
+
+// Keys returns the system keys of t, for snapshots.
+func (t *TTLPolicies) Keys() map[string][]byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	keys := make(map[string][]byte, len(t.policies))
+	for prefix, p := range t.policies {
+		keys[TTLPrefix+prefix], _ = json.Marshal(p)
+	}
+	return keys
+}
+
+// Restore replaces the policies of t with those of keys.
+func (t *TTLPolicies) Restore(keys map[string][]byte) {
+	restored := NewTTLPolicies()
+	for k, v := range keys {
+		restored.Set(k, v)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policies = restored.policies
+}
+
+// Rules returns the policies of t, sorted by prefix.
+func (t *TTLPolicies) Rules() []TTLRule {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rules := []TTLRule{}
+	for prefix, p := range t.policies {
+		rules = append(rules, TTLRule{Prefix: prefix, TTLPolicy: p})
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Prefix < rules[j].Prefix })
+	return rules
+}
+
+// TTL returns the ttl of key written with ttl, 0 for none, by the policy of
+// its longest prefix, or an error wrapping ErrInvalidTTL if ttl is negative
+// or over the max ttl of the policy.
+func (t *TTLPolicies) TTL(key string, ttl time.Duration) (time.Duration, error) {
+	if ttl < 0 {
+		return 0, fmt.Errorf("%w: negative ttl %s", ErrInvalidTTL, ttl)
+	}
+	t.mu.RLock()
+	var longest string
+	var policy *TTLPolicy
+	for prefix, p := range t.policies {
+		if strings.HasPrefix(key, prefix) && len(prefix) >= len(longest) {
+			p := p
+			longest, policy = prefix, &p
+		}
+	}
+	t.mu.RUnlock()
+	if policy == nil {
+		return ttl, nil
+	}
+	def, max, _ := policy.durations()
+	switch {
+	case ttl == 0 && def > 0:
+		return def, nil
+	case ttl == 0:
+		return max, nil
+	case max > 0 && ttl > max:
+		return 0, fmt.Errorf("%w: ttl %s of %s over the max ttl %s of %s", ErrInvalidTTL, ttl, key, max, longest)
+	}
+	return ttl, nil
+}
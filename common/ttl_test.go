@@ -0,0 +1,60 @@
+package common
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLPolicies(t *testing.T) {
+	p := NewTTLPolicies()
+	assert.NotNil(t, p.Set(TTLPrefix, []byte(`{"default":"1h"}`)))
+	assert.NotNil(t, p.Set(TTLPrefix+"sessions/", []byte(`{"default":"soon"}`)))
+	assert.NotNil(t, p.Set(TTLPrefix+"sessions/", []byte(`{"default":"2h","max":"1h"}`)))
+	assert.NotNil(t, p.Set(TTLPrefix+"sessions/", []byte(`{`)))
+
+	assert.Nil(t, p.Set(TTLPrefix+"sessions/", []byte(`{"default":"24h","max":"72h"}`)))
+	assert.Nil(t, p.Set(TTLPrefix+"sessions/admin/", []byte(`{"max":"1h"}`)))
+
+	// keys without policy keep their ttl
+	ttl, err := p.TTL("users/a", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+	ttl, err = p.TTL("users/a", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Minute, ttl)
+
+	ttl, err = p.TTL("sessions/a", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 24*time.Hour, ttl)
+	ttl, err = p.TTL("sessions/a", 48*time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 48*time.Hour, ttl)
+	_, err = p.TTL("sessions/a", 96*time.Hour)
+	assert.True(t, errors.Is(err, ErrInvalidTTL))
+	_, err = p.TTL("users/a", -time.Second)
+	assert.True(t, errors.Is(err, ErrInvalidTTL))
+
+	// the longest prefix applies, its max without default
+	ttl, err = p.TTL("sessions/admin/a", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, ttl)
+	_, err = p.TTL("sessions/admin/a", 2*time.Hour)
+	assert.True(t, errors.Is(err, ErrInvalidTTL))
+
+	assert.Equal(t, []TTLRule{
+		{Prefix: "sessions/", TTLPolicy: TTLPolicy{Default: "24h", Max: "72h"}},
+		{Prefix: "sessions/admin/", TTLPolicy: TTLPolicy{Max: "1h"}},
+	}, p.Rules())
+
+	restored := NewTTLPolicies()
+	restored.Restore(p.Keys())
+	assert.Equal(t, p.Rules(), restored.Rules())
+
+	assert.Nil(t, p.Set(TTLPrefix+"sessions/admin/", nil))
+	ttl, err = p.TTL("sessions/admin/a", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 24*time.Hour, ttl)
+}
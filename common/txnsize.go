@@ -0,0 +1,83 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Limits of the transactions, unlimited if 0. Transactions over a limit are
+// rejected as a whole before any of their commands is sent to the shards.
+var (
+	// MaxTxnOps is the most commands of a transaction.
+	MaxTxnOps int
+	// MaxTxnBytes is the most bytes of the keys and values of the commands
+	// of a transaction, counted by CommandsSize.
+	MaxTxnBytes int64
+)
+
+// ErrTxnTooLarge is wrapped by the TxnSizeErrors of the transactions over
+// MaxTxnOps or MaxTxnBytes.
+var ErrTxnTooLarge = errors.New("transaction too large")
+
+// TxnSizeError is the error of a transaction over a size limit. It crosses
+// rpc and HTTP as its message, parsed back by ParseTxnSizeError.
+type TxnSizeError struct {
+	// Ops and Bytes are the size of the transaction, Bytes only counted if
+	// Ops is within MaxOps.
+	Ops   int
+	Bytes int64
+	// MaxOps or MaxBytes is the limit exceeded, the other 0.
+	MaxOps   int
+	MaxBytes int64
+}
+
+func (e *TxnSizeError) Error() string {
+	if e.MaxOps > 0 {
+		return fmt.Sprintf("%s: %d commands over the limit of %d", ErrTxnTooLarge, e.Ops, e.MaxOps)
+	}
+	return fmt.Sprintf("%s: %d bytes over the limit of %d", ErrTxnTooLarge, e.Bytes, e.MaxBytes)
+}
+
+func (e *TxnSizeError) Unwrap() error {
+	return ErrTxnTooLarge
+}
+
+// CheckTxnSize returns a TxnSizeError if the transaction of cmds is over
+// MaxTxnOps or MaxTxnBytes.
+func CheckTxnSize(cmds []*raftpb.Command) error {
+	if MaxTxnOps > 0 && len(cmds) > MaxTxnOps {
+		return &TxnSizeError{Ops: len(cmds), MaxOps: MaxTxnOps}
+	}
+	if MaxTxnBytes <= 0 {
+		return nil
+	}
+	if n := CommandsSize(cmds); n > MaxTxnBytes {
+		return &TxnSizeError{Ops: len(cmds), Bytes: n, MaxBytes: MaxTxnBytes}
+	}
+	return nil
+}
+
+var txnSizeRegexp = regexp.MustCompile(`transaction too large: (\d+) (commands|bytes) over the limit of (\d+)`)
+
+// ParseTxnSizeError returns the TxnSizeError of err, possibly flattened by
+// rpc or HTTP, nil if err is not one. Ops is unknown for the errors of the
+// byte limit.
+func ParseTxnSizeError(err error) *TxnSizeError {
+	if err == nil {
+		return nil
+	}
+	m := txnSizeRegexp.FindStringSubmatch(err.Error())
+	if m == nil {
+		return nil
+	}
+	size, _ := strconv.ParseInt(m[1], 10, 64)
+	limit, _ := strconv.ParseInt(m[3], 10, 64)
+	if m[2] == "commands" {
+		return &TxnSizeError{Ops: int(size), MaxOps: int(limit)}
+	}
+	return &TxnSizeError{Bytes: size, MaxBytes: limit}
+}
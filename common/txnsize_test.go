@@ -0,0 +1,49 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckTxnSize(t *testing.T) {
+	defer func(ops int, bytes int64) { MaxTxnOps, MaxTxnBytes = ops, bytes }(MaxTxnOps, MaxTxnBytes)
+	cmds := []*raftpb.Command{
+		{Method: SET, Key: "a", Value: 1},
+		{Method: SET, Key: "b", Codec: "json", Blob: make([]byte, 100)},
+		{Method: DEL, Key: "c"},
+	}
+
+	MaxTxnOps, MaxTxnBytes = 0, 0
+	assert.Nil(t, CheckTxnSize(cmds))
+
+	MaxTxnOps = 2
+	err := CheckTxnSize(cmds)
+	assert.True(t, errors.Is(err, ErrTxnTooLarge))
+	assert.Equal(t, &TxnSizeError{Ops: 3, MaxOps: 2}, err)
+	MaxTxnOps = 3
+	assert.Nil(t, CheckTxnSize(cmds))
+
+	MaxTxnBytes = 100
+	err = CheckTxnSize(cmds)
+	assert.True(t, errors.Is(err, ErrTxnTooLarge))
+	assert.Equal(t, CommandsSize(cmds), err.(*TxnSizeError).Bytes)
+	MaxTxnBytes = CommandsSize(cmds)
+	assert.Nil(t, CheckTxnSize(cmds))
+}
+
+func TestParseTxnSizeError(t *testing.T) {
+	for _, e := range []*TxnSizeError{
+		{Ops: 3, MaxOps: 2},
+		{Bytes: 200, MaxBytes: 100},
+	} {
+		// as returned by the coordinator over HTTP
+		err := fmt.Errorf("Unable to txn: %s", e)
+		assert.Equal(t, e, ParseTxnSizeError(err))
+	}
+	assert.Nil(t, ParseTxnSizeError(errors.New("quota exceeded")))
+	assert.Nil(t, ParseTxnSizeError(nil))
+}
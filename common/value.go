@@ -0,0 +1,95 @@
+package common
+
+import (
+	"github.com/raft-kv-store/raftpb"
+)
+
+// MaxCodecLen is the longest codec name a blob may carry.
+const MaxCodecLen = 255
+
+// Blob is an opaque value encoded by a client side codec. Values held by the
+// store are either int64 or *Blob; the codec name is kept next to the bytes so
+// that readers know how to decode them.
+type Blob struct {
+	Codec string
+	Data  []byte
+}
+
+// CommandValue returns the value carried by cmd. Commands with a codec carry
+// a blob, all the others an int64.
+func CommandValue(cmd *raftpb.Command) interface{} {
+	if cmd.Codec != "" {
+		return &Blob{Codec: cmd.Codec, Data: cmd.Blob}
+	}
+	return cmd.Value
+}
+
+// SetCommandValue stores v into the value fields of cmd.
+func SetCommandValue(cmd *raftpb.Command, v interface{}) {
+	switch val := v.(type) {
+	case int64:
+		cmd.Value = val
+	case *Blob:
+		cmd.Codec = val.Codec
+		cmd.Blob = val.Data
+	}
+}
+
+// SetResponseValue stores v into the value fields of reply.
+func SetResponseValue(reply *raftpb.RPCResponse, v interface{}) {
+	switch val := v.(type) {
+	case int64:
+		reply.Value = val
+	case *Blob:
+		reply.Codec = val.Codec
+		reply.Blob = val.Data
+	}
+}
+
+// ValueSize returns the size in bytes of v.
+func ValueSize(v interface{}) int64 {
+	if b, ok := v.(*Blob); ok {
+		return int64(len(b.Data))
+	}
+	return 8
+}
+
+// MetaProto returns meta in its wire form, for value v.
+func MetaProto(meta KeyMeta, v interface{}) *raftpb.KeyMeta {
+	return &raftpb.KeyMeta{
+		CreateRevision: meta.CreateRevision,
+		ModRevision:    meta.ModRevision,
+		Version:        meta.Version,
+		Size:           ValueSize(v),
+	}
+}
+
+// NewEntry returns key, its value v and metadata as a KVEntry.
+func NewEntry(key string, v interface{}, meta KeyMeta) *raftpb.KVEntry {
+	e := &raftpb.KVEntry{Key: key, Meta: MetaProto(meta, v)}
+	switch val := v.(type) {
+	case int64:
+		e.Value = val
+	case *Blob:
+		e.Codec = val.Codec
+		e.Blob = val.Data
+	}
+	return e
+}
+
+// EntryValue returns the value held by e.
+func EntryValue(e *raftpb.KVEntry) interface{} {
+	if e.Codec != "" {
+		return &Blob{Codec: e.Codec, Data: e.Blob}
+	}
+	return e.Value
+}
+
+// EntryMeta returns the metadata held by e.
+func EntryMeta(e *raftpb.KVEntry) KeyMeta {
+	return KeyMeta{
+		CreateRevision: e.Meta.GetCreateRevision(),
+		ModRevision:    e.Meta.GetModRevision(),
+		Version:        e.Meta.GetVersion(),
+	}
+}
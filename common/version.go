@@ -0,0 +1,153 @@
+package common
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// BaseProtocolVersion is the version spoken by nodes built before
+	// version negotiation existed. They do not announce a version.
+	BaseProtocolVersion int32 = 1
+
+	// ProtocolVersion is the version of the command encoding spoken by this
+	// build. Bump it whenever a new command type is added to the FSMs, and
+	// register the command in commandVersions, or a field to the messages
+	// the nodes exchange, and record its encoding with go test -update.
+	// Fields are never renumbered nor their numbers reused, see
+	// testdata/protocol.
+	ProtocolVersion int32 = 16
+
+	// CoordinatorProtocolVersion is the first version whose coordinators
+	// announce their version when they join and apply VERSION. The
+	// coordinators of older builds fail on any command they do not know,
+	// VERSION included.
+	CoordinatorProtocolVersion int32 = 13
+)
+
+// VERSION replicates the protocol version announced by a member through the
+// raft log of its group.
+const VERSION = "version"
+
+// commandVersions maps a command method to the minimum protocol version every
+// member of a raft group must speak before the command may be proposed.
+var commandVersions = map[string]int32{
+	GET:    BaseProtocolVersion,
+	SET:    BaseProtocolVersion,
+	DEL:    BaseProtocolVersion,
+	LEADER: BaseProtocolVersion,
+	// HISTORY is a read served by the cohort, it is never proposed.
+	HISTORY: BaseProtocolVersion,
+	// member versions replicated through raft
+	VERSION: 2,
+	// client sessions
+	OPEN:   3,
+	CLOSE:  3,
+	EXPIRE: 3,
+	// BATCH stands for the entries packing several transactions
+	BATCH: 4,
+	// OnePhase stands for the shard leaders committing the transactions of
+	// a single shard without preparing them, released along with batches
+	OnePhase: 4,
+	// cache mode
+	EVICT: 5,
+	// writes returning the previous value
+	GETSET: 6,
+	GETDEL: 6,
+	// put if absent
+	SETNX: 7,
+	// if, then, else transactions
+	COMPARE: 8,
+	// state hash verification
+	HASH: 9,
+	// anti-entropy repair
+	MERKLE: 10,
+	// BULK stands for the entries packing independent writes
+	BULK: 11,
+	// soft deletes, DELs with a tombstone expiry, and their undeletes
+	UNDELETE: 12,
+	// commands of the coordinators, proposed once every coordinator
+	// announces a version
+	REPLICAS:  CoordinatorProtocolVersion,
+	POLICY:    CoordinatorProtocolVersion,
+	TOPOLOGY:  CoordinatorProtocolVersion,
+	TRANSFORM: CoordinatorProtocolVersion,
+	TTL:       CoordinatorProtocolVersion,
+}
+
+// MinProtocolVersion returns the protocol version required to apply method.
+// Unknown methods require the version of this build.
+func MinProtocolVersion(method string) int32 {
+	if v, ok := commandVersions[method]; ok {
+		return v
+	}
+	return ProtocolVersion
+}
+
+// MemberVersions tracks the protocol version announced by each member of a
+// raft group, so that new command types are only proposed once every member
+// is able to decode them. The store nodes replicate the versions announced
+// to their leader through raft, see VERSION, so that the leaders elected
+// later know them.
+type MemberVersions struct {
+	mu       sync.RWMutex
+	versions map[string]int32
+}
+
+// NewMemberVersions returns an empty version table.
+func NewMemberVersions() *MemberVersions {
+	return &MemberVersions{versions: make(map[string]int32)}
+}
+
+// Set records the protocol version announced by member id.
+func (m *MemberVersions) Set(id string, version int32) {
+	if version < BaseProtocolVersion {
+		version = BaseProtocolVersion
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[id] = version
+}
+
+// Versions returns the protocol versions known, by member.
+func (m *MemberVersions) Versions() map[string]int32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	versions := make(map[string]int32, len(m.versions))
+	for id, v := range m.versions {
+		versions[id] = v
+	}
+	return versions
+}
+
+// ClusterVersion returns the highest protocol version supported by all of ids.
+// Members that never announced a version are assumed to be on the base version.
+func (m *MemberVersions) ClusterVersion(ids []string) int32 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	version := ProtocolVersion
+	for _, id := range ids {
+		v, ok := m.versions[id]
+		if !ok {
+			v = BaseProtocolVersion
+		}
+		if v < version {
+			version = v
+		}
+	}
+	return version
+}
+
+// Supports returns an error if method cannot be proposed to a group made of ids.
+// Methods unknown to this build are refused here, when proposed, rather than
+// reaching the FSMs of the members which would fail to apply them.
+func (m *MemberVersions) Supports(ids []string, method string) error {
+	required, ok := commandVersions[method]
+	if !ok {
+		return fmt.Errorf("unknown command %q", method)
+	}
+	if cluster := m.ClusterVersion(ids); cluster < required {
+		return fmt.Errorf("command %s requires protocol version %d, cluster is at %d", method, required, cluster)
+	}
+	return nil
+}
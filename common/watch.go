@@ -0,0 +1,169 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Watches are served by long polling: a watcher buffers the changes of its
+// keys between its polls, and is cancelled once it falls WatchBufferSize
+// events behind, rather than blocking the applies or dropping events.
+var (
+	WatchBufferSize = 1000
+	// WatchMaxWait bounds how long a poll waits for events.
+	WatchMaxWait = 30 * time.Second
+	// WatchIdleTimeout drops the watchers not polled for this long.
+	WatchIdleTimeout = time.Minute
+)
+
+// ErrCompacted is returned to the watchers whose events are not kept anymore.
+var ErrCompacted = errors.New("required revision has been compacted")
+
+// IsCompacted reports whether err, possibly flattened by rpc, is
+// ErrCompacted.
+func IsCompacted(err error) bool {
+	return err != nil && strings.Contains(err.Error(), ErrCompacted.Error())
+}
+
+// WatchHub holds the watchers of a store node, by id.
+type WatchHub struct {
+	mu       sync.Mutex
+	watchers map[string]*watcher
+}
+
+type watcher struct {
+	prefix string
+	filter *WatchFilter
+	events []*raftpb.Command
+	// notify is signaled when events are buffered or the watcher cancelled
+	notify   chan struct{}
+	err      error
+	lastPoll time.Time
+}
+
+// NewWatchHub returns a hub without watchers.
+func NewWatchHub() *WatchHub {
+	return &WatchHub{watchers: make(map[string]*watcher)}
+}
+
+// Register adds the watcher id of the changes selected by filter of the keys
+// starting with prefix, with those of backlog buffered, replacing any watcher
+// with the same id. The watchers idle for WatchIdleTimeout are dropped.
+func (h *WatchHub) Register(id, prefix string, filter *WatchFilter, backlog []*raftpb.Command) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	for wid, w := range h.watchers {
+		if now.Sub(w.lastPoll) > WatchIdleTimeout {
+			delete(h.watchers, wid)
+		}
+	}
+	w := &watcher{prefix: prefix, filter: filter, notify: make(chan struct{}, 1), lastPoll: now}
+	h.watchers[id] = w
+	var matching []*raftpb.Command
+	for _, ev := range backlog {
+		if filter.Match(ev) {
+			matching = append(matching, ev)
+		}
+	}
+	w.buffer(matching...)
+}
+
+// Registered reports whether the watcher id exists.
+func (h *WatchHub) Registered(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, ok := h.watchers[id]
+	return ok
+}
+
+// Publish buffers events for the watchers of their keys. It never blocks.
+func (h *WatchHub) Publish(events ...*raftpb.Command) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, w := range h.watchers {
+		var matching []*raftpb.Command
+		for _, ev := range events {
+			if strings.HasPrefix(ev.Key, w.prefix) && w.filter.Match(ev) {
+				matching = append(matching, ev)
+			}
+		}
+		w.buffer(matching...)
+	}
+}
+
+// buffer appends events to the buffer of w, cancelling w if this takes it
+// over WatchBufferSize events.
+func (w *watcher) buffer(events ...*raftpb.Command) {
+	if w.err != nil || len(events) == 0 {
+		return
+	}
+	if len(w.events)+len(events) > WatchBufferSize {
+		w.err = fmt.Errorf("%w: watcher fell more than %d events behind", ErrCompacted, WatchBufferSize)
+		w.events = nil
+	} else {
+		w.events = append(w.events, events...)
+	}
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Poll returns the events buffered for the watcher id, waiting up to wait
+// for some. A cancelled watcher is removed, and the reason returned.
+func (h *WatchHub) Poll(id string, wait time.Duration) ([]*raftpb.Command, error) {
+	timeout := time.After(wait)
+	for {
+		h.mu.Lock()
+		w, ok := h.watchers[id]
+		if !ok {
+			h.mu.Unlock()
+			return nil, fmt.Errorf("unknown watcher %s", id)
+		}
+		w.lastPoll = time.Now()
+		if w.err != nil {
+			delete(h.watchers, id)
+			h.mu.Unlock()
+			return nil, w.err
+		}
+		if len(w.events) > 0 {
+			events := w.events
+			w.events = nil
+			h.mu.Unlock()
+			return events, nil
+		}
+		h.mu.Unlock()
+		select {
+		case <-w.notify:
+		case <-timeout:
+			return nil, nil
+		}
+	}
+}
+
+// Cancel removes the watcher id.
+func (h *WatchHub) Cancel(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.watchers, id)
+}
+
+// CancelAll cancels every watcher with err.
+func (h *WatchHub) CancelAll(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, w := range h.watchers {
+		w.err = err
+		w.events = nil
+		select {
+		case w.notify <- struct{}{}:
+		default:
+		}
+	}
+}
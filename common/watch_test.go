@@ -0,0 +1,45 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchHub(t *testing.T) {
+	h := NewWatchHub()
+	h.Register("w1", "a/", nil, []*raftpb.Command{{Method: SET, Key: "a/1", Revision: 3}})
+	assert.True(t, h.Registered("w1"))
+
+	events, err := h.Poll("w1", time.Millisecond)
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+
+	go h.Publish(&raftpb.Command{Method: SET, Key: "b/1", Revision: 4}, &raftpb.Command{Method: DEL, Key: "a/2", Revision: 4})
+	events, err = h.Poll("w1", time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, []*raftpb.Command{{Method: DEL, Key: "a/2", Revision: 4}}, events)
+
+	events, err = h.Poll("w1", time.Millisecond)
+	assert.Nil(t, err)
+	assert.Len(t, events, 0)
+
+	h.Cancel("w1")
+	_, err = h.Poll("w1", time.Millisecond)
+	assert.NotNil(t, err)
+}
+
+func TestWatchHub_SlowWatcher(t *testing.T) {
+	defer func(n int) { WatchBufferSize = n }(WatchBufferSize)
+	WatchBufferSize = 2
+	h := NewWatchHub()
+	h.Register("w1", "", nil, nil)
+	h.Publish(&raftpb.Command{Key: "a", Revision: 1}, &raftpb.Command{Key: "b", Revision: 1})
+	h.Publish(&raftpb.Command{Key: "c", Revision: 2})
+
+	_, err := h.Poll("w1", time.Millisecond)
+	assert.True(t, IsCompacted(err))
+	assert.False(t, h.Registered("w1"))
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// WatchFilter selects the changes sent to a watcher on its node, so that
+// the watchers of a few changes are not sent the others.
+type WatchFilter struct {
+	methods map[string]bool
+	field   []string
+	equals  interface{}
+}
+
+// NewWatchFilter compiles f, nil if f selects every change.
+func NewWatchFilter(f *raftpb.WatchFilter) (*WatchFilter, error) {
+	if f == nil || (len(f.Methods) == 0 && f.Field == "") {
+		return nil, nil
+	}
+	wf := &WatchFilter{}
+	for _, m := range f.Methods {
+		m = strings.ToLower(m)
+		if m != SET && m != DEL && m != EVICT {
+			return nil, fmt.Errorf("cannot filter watch on command %q", m)
+		}
+		if wf.methods == nil {
+			wf.methods = make(map[string]bool)
+		}
+		wf.methods[m] = true
+	}
+	if f.Field != "" {
+		wf.field = strings.Split(f.Field, ".")
+		if err := json.Unmarshal([]byte(f.Equals), &wf.equals); err != nil {
+			return nil, fmt.Errorf("invalid JSON value of field %s: %s", f.Field, err)
+		}
+	}
+	return wf, nil
+}
+
+// Match reports whether the change ev is selected, always if f is nil.
+func (f *WatchFilter) Match(ev *raftpb.Command) bool {
+	if f == nil {
+		return true
+	}
+	if f.methods != nil && !f.methods[ev.Method] {
+		return false
+	}
+	if f.field == nil {
+		return true
+	}
+	if ev.Method != SET || ev.Codec != "json" {
+		return false
+	}
+	var v interface{}
+	if err := json.Unmarshal(ev.Blob, &v); err != nil {
+		return false
+	}
+	for _, name := range f.field {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = obj[name]; !ok {
+			return false
+		}
+	}
+	return reflect.DeepEqual(v, f.equals)
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/raft-kv-store/raftpb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchFilter(t *testing.T) {
+	f, err := NewWatchFilter(&raftpb.WatchFilter{})
+	assert.Nil(t, err)
+	assert.True(t, f.Match(&raftpb.Command{Method: DEL, Key: "a"}))
+
+	_, err = NewWatchFilter(&raftpb.WatchFilter{Methods: []string{"get"}})
+	assert.NotNil(t, err)
+	_, err = NewWatchFilter(&raftpb.WatchFilter{Field: "status", Equals: "done"})
+	assert.NotNil(t, err)
+
+	f, err = NewWatchFilter(&raftpb.WatchFilter{Methods: []string{"DEL", "evict"}})
+	assert.Nil(t, err)
+	assert.True(t, f.Match(&raftpb.Command{Method: DEL, Key: "a"}))
+	assert.False(t, f.Match(&raftpb.Command{Method: SET, Key: "a", Value: 1}))
+
+	f, err = NewWatchFilter(&raftpb.WatchFilter{Field: "job.status", Equals: `"done"`})
+	assert.Nil(t, err)
+	assert.True(t, f.Match(&raftpb.Command{Method: SET, Key: "a", Codec: "json", Blob: []byte(`{"job":{"status":"done"}}`)}))
+	assert.False(t, f.Match(&raftpb.Command{Method: SET, Key: "a", Codec: "json", Blob: []byte(`{"job":{"status":"running"}}`)}))
+	assert.False(t, f.Match(&raftpb.Command{Method: SET, Key: "a", Codec: "json", Blob: []byte(`{"status":"done"}`)}))
+	assert.False(t, f.Match(&raftpb.Command{Method: SET, Key: "a", Value: 1}))
+	assert.False(t, f.Match(&raftpb.Command{Method: DEL, Key: "a"}))
+
+	h := NewWatchHub()
+	h.Register("w1", "a/", f, []*raftpb.Command{
+		{Method: SET, Key: "a/1", Codec: "json", Blob: []byte(`{"job":{"status":"done"}}`), Revision: 2},
+		{Method: SET, Key: "a/2", Value: 1, Revision: 3},
+	})
+	h.Publish(&raftpb.Command{Method: DEL, Key: "a/1", Revision: 4})
+	events, err := h.Poll("w1", 0)
+	assert.Nil(t, err)
+	assert.Len(t, events, 1)
+	assert.Equal(t, int64(2), events[0].Revision)
+}
@@ -0,0 +1,226 @@
+package common
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+)
+
+// Webhooks are posted by the shard leaders, from a queue of WebhookQueueSize
+// events per endpoint, retried WebhookRetries times before being counted as
+// dead letters.
+var (
+	// Webhooks are the endpoints notified of the changes of the keys, as
+	// prefix=url.
+	Webhooks []string
+	// WebhookSecret signs the webhook events, unsigned if empty.
+	WebhookSecret    string
+	WebhookQueueSize = 1000
+	WebhookRetries   = 5
+	WebhookTimeout   = 5 * time.Second
+	// webhookBackoff is the delay before the first retry, doubled on every
+	// other.
+	webhookBackoff = 100 * time.Millisecond
+)
+
+// WebhookSignatureHeader holds the hex HMAC-SHA256 of the body of a webhook
+// event, keyed by WebhookSecret, as sha256=<hex>.
+const WebhookSignatureHeader = "X-Raftkv-Signature"
+
+// Webhook metrics, labelled by url.
+const (
+	WebhookDeliveredMetric   = "raftkv_webhook_delivered_total"
+	WebhookDeadLettersMetric = "raftkv_webhook_dead_letters_total"
+)
+
+// WebhookEvent is the JSON body of a webhook: a change of a key, with its
+// revision in its shard. Blob is base64 encoded.
+type WebhookEvent struct {
+	Shard    string `json:"shard"`
+	Revision int64  `json:"revision"`
+	Method   string `json:"method"`
+	Key      string `json:"key"`
+	Value    int64  `json:"value,omitempty"`
+	Codec    string `json:"codec,omitempty"`
+	Blob     []byte `json:"blob,omitempty"`
+}
+
+// SignWebhook returns the signature of body keyed by secret.
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type webhook struct {
+	prefix    string
+	url       string
+	queue     chan []byte
+	delivered int64
+	dead      int64
+}
+
+// WebhookSender posts the changes of the keys to the webhooks, in order per
+// endpoint, without ever blocking the writes: the events that do not fit in
+// the queue of an endpoint are dropped as dead letters.
+type WebhookSender struct {
+	shard  string
+	secret string
+	client *http.Client
+	log    *log.Entry
+
+	mu    sync.Mutex
+	hooks []*webhook
+	stop  chan struct{}
+}
+
+// NewWebhookSender returns a sender of the changes of shard to the webhooks
+// specs, as prefix=url, signed with secret. It returns nil without specs.
+func NewWebhookSender(logger *log.Logger, shard string, specs []string, secret string) (*WebhookSender, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	s := &WebhookSender{
+		shard:  shard,
+		secret: secret,
+		client: &http.Client{Timeout: WebhookTimeout},
+		log:    logger.WithField("component", "webhook"),
+		stop:   make(chan struct{}),
+	}
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 || !strings.HasPrefix(kv[1], "http") {
+			return nil, fmt.Errorf("invalid webhook %q, expected prefix=url", spec)
+		}
+		h := &webhook{prefix: kv[0], url: kv[1], queue: make(chan []byte, WebhookQueueSize)}
+		s.hooks = append(s.hooks, h)
+		go s.deliver(h)
+	}
+	return s, nil
+}
+
+// Publish queues events for the webhooks of their keys. It never blocks, and
+// does nothing on a nil sender.
+func (s *WebhookSender) Publish(events ...*raftpb.Command) {
+	if s == nil {
+		return
+	}
+	for _, ev := range events {
+		var body []byte
+		for _, h := range s.hooks {
+			if !strings.HasPrefix(ev.Key, h.prefix) {
+				continue
+			}
+			if body == nil {
+				var err error
+				body, err = json.Marshal(&WebhookEvent{Shard: s.shard, Revision: ev.Revision, Method: ev.Method,
+					Key: ev.Key, Value: ev.Value, Codec: ev.Codec, Blob: ev.Blob})
+				if err != nil {
+					s.log.Errorf("unable to encode the change of %s: %s", ev.Key, err)
+					break
+				}
+			}
+			select {
+			case h.queue <- body:
+			default:
+				s.log.Errorf("webhook %s queue is full, dropping the change of %s at revision %d", h.url, ev.Key, ev.Revision)
+				s.count(&h.dead)
+			}
+		}
+	}
+}
+
+// deliver posts the events queued for h until the sender is closed.
+func (s *WebhookSender) deliver(h *webhook) {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case body := <-h.queue:
+			if err := s.post(h.url, body); err != nil {
+				s.log.Errorf("webhook %s failed %d times, dropping event %s: %s", h.url, WebhookRetries+1, body, err)
+				s.count(&h.dead)
+			} else {
+				s.count(&h.delivered)
+			}
+		}
+	}
+}
+
+// post posts body to url, retrying WebhookRetries times with exponential
+// backoff until it is accepted with a 2xx status.
+func (s *WebhookSender) post(url string, body []byte) error {
+	var err error
+	backoff := webhookBackoff
+	for i := 0; i <= WebhookRetries; i++ {
+		if i > 0 {
+			select {
+			case <-s.stop:
+				return err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		var req *http.Request
+		if req, err = http.NewRequest(http.MethodPost, url, bytes.NewReader(body)); err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if s.secret != "" {
+			req.Header.Set(WebhookSignatureHeader, SignWebhook(s.secret, body))
+		}
+		var resp *http.Response
+		if resp, err = s.client.Do(req); err != nil {
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 == 2 {
+			return nil
+		}
+		err = fmt.Errorf("webhook replied %d", resp.StatusCode)
+	}
+	return err
+}
+
+func (s *WebhookSender) count(n *int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	*n++
+}
+
+// Close stops the deliveries, dropping the events queued.
+func (s *WebhookSender) Close() {
+	if s != nil {
+		close(s.stop)
+	}
+}
+
+// RegisterWebhookMetrics registers the webhook metrics in m.
+func RegisterWebhookMetrics(m *Metrics) {
+	m.Register(WebhookDeliveredMetric, CounterMetric, "Webhook events delivered.")
+	m.Register(WebhookDeadLettersMetric, CounterMetric, "Webhook events dropped after their retries, or with the queue full.")
+}
+
+// SetMetrics sets the webhook metrics of the endpoints in m. It does nothing
+// on a nil sender.
+func (s *WebhookSender) SetMetrics(m *Metrics) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, h := range s.hooks {
+		m.Set(WebhookDeliveredMetric, float64(h.delivered), "url", h.url)
+		m.Set(WebhookDeadLettersMetric, float64(h.dead), "url", h.url)
+	}
+}
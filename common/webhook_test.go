@@ -0,0 +1,66 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSender(t *testing.T) {
+	webhookBackoff = time.Millisecond
+	var mu sync.Mutex
+	var received []WebhookEvent
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// the first attempt fails, and is retried
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, SignWebhook("secret", body), r.Header.Get(WebhookSignatureHeader))
+		var ev WebhookEvent
+		assert.Nil(t, json.Unmarshal(body, &ev))
+		received = append(received, ev)
+	}))
+	defer srv.Close()
+
+	_, err := NewWebhookSender(log.New(), "s0", []string{srv.URL}, "secret")
+	assert.NotNil(t, err)
+	s, err := NewWebhookSender(log.New(), "s0", []string{"a/=" + srv.URL, "b/=http://127.0.0.1:1"}, "secret")
+	assert.Nil(t, err)
+	defer s.Close()
+	s.Publish(&raftpb.Command{Method: SET, Key: "a/1", Value: 7, Revision: 3},
+		&raftpb.Command{Method: DEL, Key: "c", Revision: 4},
+		&raftpb.Command{Method: DEL, Key: "b/1", Revision: 5})
+
+	var b strings.Builder
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		m := NewMetrics()
+		RegisterWebhookMetrics(m)
+		s.SetMetrics(m)
+		b.Reset()
+		m.Write(&b)
+		if strings.Contains(b.String(), `raftkv_webhook_delivered_total{url="`+srv.URL+`"} 1`) &&
+			strings.Contains(b.String(), `raftkv_webhook_dead_letters_total{url="http://127.0.0.1:1"} 1`) {
+			break
+		}
+	}
+	assert.Contains(t, b.String(), `raftkv_webhook_delivered_total{url="`+srv.URL+`"} 1`)
+	assert.Contains(t, b.String(), `raftkv_webhook_dead_letters_total{url="http://127.0.0.1:1"} 1`)
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []WebhookEvent{{Shard: "s0", Revision: 3, Method: SET, Key: "a/1", Value: 7}}, received)
+}
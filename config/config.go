@@ -3,20 +3,35 @@ package config
 import (
 	"encoding/json"
 	"io/ioutil"
+	"os"
 )
 
 const (
 	// ShardConfigFilePath is the file path of shard configuration
 	ShardConfigFilePath = "config/shard-config.json"
+	// QuotaConfigFilePath is the file path of tenant quotas
+	QuotaConfigFilePath = "config/quota-config.json"
+	// ConsistencyConfigFilePath is the file path of the consistency levels
+	// of the tenants
+	ConsistencyConfigFilePath = "config/consistency-config.json"
 )
 
 // ShardsConfig to read shards json file
 type ShardsConfig struct {
 	Shards [][]string `json:"shards"`
+	// Replicas is the replication factor of every shard, by index. Shards
+	// without one, or with 0, have every member joined as a voter.
+	Replicas []int32 `json:"replicas,omitempty"`
 }
 
+// Shards replaces the shard configuration file if set.
+var Shards *ShardsConfig
+
 // GetShards reads shard info from config file
 func GetShards() (*ShardsConfig, error) {
+	if Shards != nil {
+		return Shards, nil
+	}
 	config := &ShardsConfig{}
 	data, err := ioutil.ReadFile(ShardConfigFilePath)
 	if err != nil {
@@ -27,3 +42,50 @@ func GetShards() (*ShardsConfig, error) {
 	}
 	return config, nil
 }
+
+// Quota limits the usage of a tenant. Zero values are unlimited.
+type Quota struct {
+	Keys  int64   `json:"keys"`
+	Bytes int64   `json:"bytes"`
+	QPS   float64 `json:"qps"`
+}
+
+// GetQuotas reads the quotas by tenant from the quota file, if it exists.
+func GetQuotas() (map[string]Quota, error) {
+	quotas := make(map[string]Quota)
+	data, err := ioutil.ReadFile(QuotaConfigFilePath)
+	if os.IsNotExist(err) {
+		return quotas, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &quotas); err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// Consistency sets the default consistency of the reads and of the writes of
+// a tenant. Empty values keep the defaults of the nodes.
+type Consistency struct {
+	// Read is linearizable, leader-local or stale.
+	Read string `json:"read"`
+	// Write is quorum or leader.
+	Write string `json:"write"`
+}
+
+// GetConsistency reads the consistency levels by tenant from the
+// consistency file, if it exists.
+func GetConsistency() (map[string]Consistency, error) {
+	levels := make(map[string]Consistency)
+	data, err := ioutil.ReadFile(ConsistencyConfigFilePath)
+	if os.IsNotExist(err) {
+		return levels, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &levels); err != nil {
+		return nil, err
+	}
+	return levels, nil
+}
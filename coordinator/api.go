@@ -1,7 +1,9 @@
 package coordinator
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"net/rpc"
 	"time"
 
@@ -13,78 +15,202 @@ import (
 
 // TODO: Separate out the common code into a function
 
-// Get returns the value for the given key.
-func (c *Coordinator) Get(key string) (int64, error) {
+// Get returns the value for the given key. The value is either the int64
+// Value of the response or, when Codec is set, its Blob.
+func (c *Coordinator) Get(key string) (*raftpb.RPCResponse, error) {
+	return c.GetRevision(key, 0)
+}
+
+// GetRevision returns the value the given key had at revision rev, or its
+// current value when rev is 0.
+func (c *Coordinator) GetRevision(key string, rev int64) (*raftpb.RPCResponse, error) {
+	return c.GetRevisionAfter(key, rev, 0)
+}
 
-	c.log.Infof("Processing Get request %s", key)
+// GetRevisionAfter is GetRevision served by a shard node which has applied
+// revision minRev of the shard of key, waiting for it if needed. The
+// Revision of the response is the revision applied by the node.
+func (c *Coordinator) GetRevisionAfter(key string, rev, minRev int64) (*raftpb.RPCResponse, error) {
+
+	c.log.Infof("Processing Get request %s at revision %d", key, rev)
+	if err := c.admit([]*raftpb.Command{{Method: common.GET, Key: key}}); err != nil {
+		return nil, err
+	}
+	resp, err := c.getRevision(key, rev, minRev)
+	if err == nil {
+		err = c.decodeReply(key, resp)
+	}
+	if err == nil {
+		c.profileReads([]*raftpb.Command{{Key: key, Value: resp.Value, Blob: resp.Blob, Codec: resp.Codec}})
+	}
+	return resp, err
+}
+
+func (c *Coordinator) getRevision(key string, rev, minRev int64) (*raftpb.RPCResponse, error) {
 	var response raftpb.RPCResponse
 	cmd := &raftpb.RaftCommand{
 		Commands: []*raftpb.Command{
 			{
-				Method: common.GET,
-				Key:    key,
+				Method:      common.GET,
+				Key:         key,
+				Revision:    rev,
+				MinRevision: minRev,
+				Consistency: c.consistencyOf(key).Read,
 			},
 		},
 	}
 
 	// Figure out
-	addr, _, err := c.FindLeader(key)
+	var addr string
+	var shardID int64
+	var err error
+	if cmd.Commands[0].Consistency == common.ReadStale {
+		addr, shardID, err = c.findReplica(key)
+	} else {
+		addr, shardID, err = c.FindLeader(key)
+	}
 	if err != nil {
 		c.log.Println(err)
-		return 0, err
+		return nil, err
 	}
+	cmd.Commands[0].Verify = c.clockSkew.isSkewed(shardID)
 
 	client, err := rpc.DialHTTP("tcp", addr)
 	if err != nil {
 		c.log.Println(err)
-		return 0, err
+		return nil, err
 	}
 
-	err = client.Call("Cohort.ProcessCommands", cmd, &response)
+	err = processCommands(client, cmd, &response)
 	c.log.Infof(" Value of key: %s --> %d", key, response.Value)
+	if err == nil && response.Read != nil {
+		response.Read.Shard = shardID
+	}
+	if err == nil && rev == 0 {
+		c.maybeVerifyRead(key, addr, shardID, &response)
+	}
 
-	return response.Value, err
+	return &response, err
 
 }
 
-// Set sets the value for the given key.
-func (c *Coordinator) Set(key string, value int64) error {
+// History returns up to limit past revisions of the given key, newest first.
+// Deletions are returned as DEL commands.
+func (c *Coordinator) History(key string, limit int64) ([]*raftpb.Command, error) {
 
-	c.log.Infof("Processing Set request: Key=%s Value=%d", key, value)
+	c.log.Infof("Processing History request %s", key)
+	if err := c.admit([]*raftpb.Command{{Method: common.HISTORY, Key: key}}); err != nil {
+		return nil, err
+	}
 	var response raftpb.RPCResponse
 	cmd := &raftpb.RaftCommand{
 		Commands: []*raftpb.Command{
 			{
-				Method: common.SET,
+				Method: common.HISTORY,
 				Key:    key,
-				Value:  value,
+				Limit:  limit,
 			},
 		},
 	}
-	// Figure out
+
 	addr, _, err := c.FindLeader(key)
 	if err != nil {
-		return err
+		return nil, err
 	}
+
 	client, err := rpc.DialHTTP("tcp", addr)
 	if err != nil {
-		return fmt.Errorf("Unable to reach shard at :%s", addr)
+		return nil, err
 	}
 
-	return client.Call("Cohort.ProcessCommands", cmd, &response)
+	err = client.Call("Cohort.ProcessCommands", cmd, &response)
+	return response.Commands, err
+
+}
+
+// Set sets the value for the given key.
+func (c *Coordinator) Set(key string, value int64) error {
+	_, err := c.SetCommand(&raftpb.Command{Key: key, Value: value})
+	return err
+}
+
+// SetCommand sets the value carried by cmd, an int64 or a codec encoded blob,
+// for cmd.Key, provided the condition of cmd, if any, holds. It returns the
+// revision of the write.
+func (c *Coordinator) SetCommand(cmd *raftpb.Command) (int64, error) {
+
+	c.log.Infof("Processing Set request: Key=%s Value=%d Codec=%s", cmd.Key, cmd.Value, cmd.Codec)
+	if err := c.admit([]*raftpb.Command{{Method: common.SET, Key: cmd.Key, Value: cmd.Value, Blob: cmd.Blob, Codec: cmd.Codec}}); err != nil {
+		return 0, err
+	}
+	if err := c.expireCommands([]*raftpb.Command{cmd}); err != nil {
+		return 0, err
+	}
+	blob, err := c.encodeBlob(cmd)
+	if err != nil {
+		return 0, err
+	}
+	var response raftpb.RPCResponse
+	raftCmd := &raftpb.RaftCommand{
+		Commands: []*raftpb.Command{
+			{
+				Method:   common.SET,
+				Key:      cmd.Key,
+				Value:    cmd.Value,
+				Blob:     blob,
+				Codec:    cmd.Codec,
+				Cond:     cmd.Cond,
+				Expires:  cmd.Expires,
+				Session:  cmd.Session,
+				Seq:      cmd.Seq,
+				Priority: cmd.Priority,
+				Client:   cmd.Client,
+				Ack:      c.consistencyOf(cmd.Key).Write,
+			},
+		},
+	}
+	// Figure out
+	addr, _, err := c.FindLeader(cmd.Key)
+	if err != nil {
+		return 0, err
+	}
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to reach shard at :%s", addr)
+	}
+
+	err = processCommands(client, raftCmd, &response)
+	return response.Revision, err
 
 }
 
 // Delete deletes the given key.
 func (c *Coordinator) Delete(key string) error {
+	_, err := c.DeleteCommand(&raftpb.Command{Key: key})
+	return err
+}
+
+// DeleteCommand deletes cmd.Key, as a write of cmd.Session if set. It returns
+// the revision of the write.
+func (c *Coordinator) DeleteCommand(del *raftpb.Command) (int64, error) {
 
+	key := del.Key
 	c.log.Infof("Processing Delete request %s", key)
+	if err := c.admit([]*raftpb.Command{{Method: common.DEL, Key: key}}); err != nil {
+		return 0, err
+	}
 	var response raftpb.RPCResponse
 	cmd := &raftpb.RaftCommand{
 		Commands: []*raftpb.Command{
 			{
-				Method: common.DEL,
-				Key:    key,
+				Method:   common.DEL,
+				Key:      key,
+				Expires:  tombstoneExpiry(key),
+				Session:  del.Session,
+				Seq:      del.Seq,
+				Priority: del.Priority,
+				Client:   del.Client,
+				Ack:      c.consistencyOf(key).Write,
 			},
 		},
 	}
@@ -92,18 +218,147 @@ func (c *Coordinator) Delete(key string) error {
 	// Figure out
 	addr, _, err := c.FindLeader(key)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	client, err := rpc.DialHTTP("tcp", addr)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return client.Call("Cohort.ProcessCommands", cmd, &response)
+	err = processCommands(client, cmd, &response)
+	return response.Revision, err
 
 }
 
+// GetSetCommand sets cmd.Key like SetCommand and returns the value it had
+// before, read and replaced by the same raft entry. The Meta of the response
+// is nil if the key did not exist.
+func (c *Coordinator) GetSetCommand(cmd *raftpb.Command) (*raftpb.RPCResponse, error) {
+
+	c.log.Infof("Processing GetSet request: Key=%s Value=%d Codec=%s", cmd.Key, cmd.Value, cmd.Codec)
+	if err := c.admit([]*raftpb.Command{{Method: common.SET, Key: cmd.Key, Value: cmd.Value, Blob: cmd.Blob, Codec: cmd.Codec}}); err != nil {
+		return nil, err
+	}
+	if err := c.expireCommands([]*raftpb.Command{cmd}); err != nil {
+		return nil, err
+	}
+	blob, err := c.encodeBlob(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return c.proposeDecoded(&raftpb.Command{
+		Method:   common.GETSET,
+		Key:      cmd.Key,
+		Value:    cmd.Value,
+		Blob:     blob,
+		Codec:    cmd.Codec,
+		Cond:     cmd.Cond,
+		Expires:  cmd.Expires,
+		Session:  cmd.Session,
+		Seq:      cmd.Seq,
+		Priority: cmd.Priority,
+		Client:   cmd.Client,
+	})
+}
+
+// GetDelCommand deletes del.Key like DeleteCommand and returns the value it
+// had before, like GetSetCommand.
+func (c *Coordinator) GetDelCommand(del *raftpb.Command) (*raftpb.RPCResponse, error) {
+
+	c.log.Infof("Processing GetDel request %s", del.Key)
+	if err := c.admit([]*raftpb.Command{{Method: common.DEL, Key: del.Key}}); err != nil {
+		return nil, err
+	}
+	return c.proposeDecoded(&raftpb.Command{
+		Method:   common.GETDEL,
+		Key:      del.Key,
+		Expires:  tombstoneExpiry(del.Key),
+		Session:  del.Session,
+		Seq:      del.Seq,
+		Priority: del.Priority,
+		Client:   del.Client,
+	})
+}
+
+// SetIfAbsent sets cmd.Key to the value of cmd if the key does not exist,
+// and reports whether it did, with the revision of the write.
+func (c *Coordinator) SetIfAbsent(cmd *raftpb.Command) (bool, int64, error) {
+
+	c.log.Infof("Processing SetIfAbsent request: Key=%s Value=%d Codec=%s", cmd.Key, cmd.Value, cmd.Codec)
+	if err := c.admit([]*raftpb.Command{{Method: common.SET, Key: cmd.Key, Value: cmd.Value, Blob: cmd.Blob, Codec: cmd.Codec}}); err != nil {
+		return false, 0, err
+	}
+	if err := c.expireCommands([]*raftpb.Command{cmd}); err != nil {
+		return false, 0, err
+	}
+	blob, err := c.encodeBlob(cmd)
+	if err != nil {
+		return false, 0, err
+	}
+	resp, err := c.proposeCommand(&raftpb.Command{
+		Method:   common.SETNX,
+		Key:      cmd.Key,
+		Value:    cmd.Value,
+		Blob:     blob,
+		Codec:    cmd.Codec,
+		Expires:  cmd.Expires,
+		Session:  cmd.Session,
+		Seq:      cmd.Seq,
+		Priority: cmd.Priority,
+		Client:   cmd.Client,
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return resp.Value == 1, resp.Revision, nil
+}
+
+// proposeCommand has the leader of the shard of cmd.Key propose cmd, and
+// returns its reply.
+func (c *Coordinator) proposeCommand(cmd *raftpb.Command) (*raftpb.RPCResponse, error) {
+	addr, _, err := c.FindLeader(cmd.Key)
+	if err != nil {
+		return nil, err
+	}
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to reach shard at :%s", addr)
+	}
+	defer client.Close()
+	var response raftpb.RPCResponse
+	if err := processCommands(client, &raftpb.RaftCommand{Commands: []*raftpb.Command{cmd}}, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// processCommands has the store at the other end of client process cmd into
+// reply, and returns the key lock it fails on as a *common.LockError.
+func processCommands(client *rpc.Client, cmd *raftpb.RaftCommand, reply *raftpb.RPCResponse) error {
+	cmd.LockReply = true
+	if err := client.Call("Cohort.ProcessCommands", common.SignRequest(common.ClusterSecret, "Cohort.ProcessCommands", cmd), reply); err != nil {
+		return err
+	}
+	if lock := common.ReplyLock(reply); lock != nil {
+		return lock
+	}
+	return nil
+}
+
+// proposeDecoded is proposeCommand for the writes replying with the value
+// the key had before, transformed back.
+func (c *Coordinator) proposeDecoded(cmd *raftpb.Command) (*raftpb.RPCResponse, error) {
+	resp, err := c.proposeCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.decodeReply(cmd.Key, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func isReadOnly(ops []*raftpb.Command) bool {
 	for _, op := range ops {
 		if op.Method != common.GET {
@@ -115,43 +370,88 @@ func isReadOnly(ops []*raftpb.Command) bool {
 
 // Transaction atomically executes the transaction .
 func (c *Coordinator) Transaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand, error) {
+	return c.TransactionWithID(xid.New().String(), cmds)
+}
+
+// TransactionWithID atomically executes the transaction under the id txid.
+// Transactions aborted by a conflict fail with an error parsed by
+// common.ParseConflict, those over the size limits with a
+// common.TxnSizeError, without any of their commands applied.
+func (c *Coordinator) TransactionWithID(txid string, cmds *raftpb.RaftCommand) (*raftpb.RaftCommand, error) {
+	return c.TransactionWithRevisions(txid, cmds, nil)
+}
+
+// TransactionWithRevisions is TransactionWithID, recording in revs the
+// revision of the transaction on each shard which acknowledged its commit.
+func (c *Coordinator) TransactionWithRevisions(txid string, cmds *raftpb.RaftCommand, revs common.RevisionToken) (*raftpb.RaftCommand, error) {
+
+	c.log.Infof("Processing Transaction %s", txid)
+	if err := common.CheckTxnSize(cmds.Commands); err != nil {
+		return nil, err
+	}
+	if err := c.admit(cmds.Commands); err != nil {
+		return nil, err
+	}
+	if err := c.expireCommands(cmds.Commands); err != nil {
+		return nil, err
+	}
+	encoded, err := c.encodeCommands(cmds.Commands)
+	if err != nil {
+		return nil, err
+	}
+	cmds = &raftpb.RaftCommand{Commands: encoded, IsTxn: cmds.IsTxn}
+	res, err := c.transaction(txid, cmds, revs)
+	c.observeTxn(cmds.Commands, err)
+	if err == nil && res != nil {
+		err = c.decodeCommands(res.Commands)
+	}
+	if err == nil && res != nil {
+		c.profileReads(res.Commands)
+	}
+	return res, err
+}
 
-	c.log.Infof("Processing Transaction")
-	txid := xid.New().String()
+func (c *Coordinator) transaction(txid string, cmds *raftpb.RaftCommand, revs common.RevisionToken) (*raftpb.RaftCommand, error) {
+	c.cut.RLock()
+	defer c.cut.RUnlock()
 	gt := c.newGlobalTransaction(txid, cmds)
 	gt.StartTime = time.Now().UnixNano()
 	readOnly := isReadOnly(gt.Cmds.Commands)
 	numShards := len(gt.ShardToCommands)
 	resultCmds := &raftpb.RaftCommand{}
 
+	if numShards == 1 && !readOnly && c.supportsOnePhase(gt) {
+		return c.commitOnePhase(gt, revs)
+	}
+
 	c.log.Infof("Starting prepare phase for txid: [%s]", txid)
 	// Prepare Phase
-	// Send prepare messages to all the shards involved in transaction.
-	// This is a synchronous operation atm. It can be asynchronous
-	// TODO: go routine SendMessageToShard
+	// Send prepare messages to all the shards involved in transaction in
+	// parallel, the first failure aborts the transaction.
 	var prepareResponses int
-	var readOnlyErr error
+	var prepareErr error
 	if readOnly {
 		c.log.Infof("[txid %s] is read-only", txid)
 	}
 	for _, shardops := range gt.ShardToCommands {
 		shardops.ReadOnly = readOnly
-		cmds, err := c.SendMessageToShard(shardops)
-		if err == nil {
+	}
+	for id, reply := range c.sendToShards(gt.ShardToCommands, true) {
+		if reply.err == nil {
 			prepareResponses++
 		} else {
-			c.log.Infof("[txid %s] failed at %v with %s", txid, shardops, err.Error())
+			c.log.Infof("[txid %s] failed at %v with %s", txid, gt.ShardToCommands[id], reply.err.Error())
+			if prepareErr == nil || prepareErr == errNotSent {
+				prepareErr = reply.err
+			}
 		}
 		if readOnly {
-			resultCmds.Commands = append(resultCmds.Commands, cmds...)
-			if err != nil {
-				readOnlyErr = err
-			}
+			resultCmds.Commands = append(resultCmds.Commands, reply.cmds...)
 		}
 	}
 	if readOnly {
 		c.log.Infof("[txid: %s] read-only transaction, returning after prepare phase", txid)
-		return resultCmds, readOnlyErr
+		return resultCmds, prepareErr
 	}
 
 	c.log.Infof("[txid %s] Prepared sent", txid)
@@ -169,13 +469,14 @@ func (c *Coordinator) Transaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand
 
 		var err error
 		var abortMessages int
-		for id, shardops := range gt.ShardToCommands {
-			gt.ShardToCommands[id].Phase = common.Abort
+		for _, shardops := range gt.ShardToCommands {
 			shardops.Phase = common.Abort
-			// best effort
-			_, err = c.SendMessageToShard(shardops)
-			if err != nil {
-				c.log.Infof("[txid %s] failed at %v with %s", txid, shardops, err.Error())
+		}
+		// best effort
+		for id, reply := range c.sendToShards(gt.ShardToCommands, false) {
+			if reply.err != nil {
+				err = reply.err
+				c.log.Infof("[txid %s] failed at %v with %s", txid, gt.ShardToCommands[id], err.Error())
 			} else {
 				abortMessages++
 			}
@@ -189,6 +490,9 @@ func (c *Coordinator) Transaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand
 			}
 			c.log.Infof("[txid: %s] Aborted Successfully", txid)
 		}
+		if err == nil {
+			err = fmt.Errorf("transaction %s aborted, prepared %d of %d shards: %s", txid, prepareResponses, numShards, prepareErr)
+		}
 		return nil, err
 	}
 
@@ -219,20 +523,21 @@ func (c *Coordinator) Transaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand
 	// the transaction.
 
 	// Commit
-	var commitResponses int
-	for id, shardOps := range gt.ShardToCommands {
-		// Replicate via Raft
-
-		gt.ShardToCommands[id].Phase = common.Commit
+	// Replicate the decision via Raft once, then commit all the shards in
+	// parallel
+	for _, shardOps := range gt.ShardToCommands {
 		shardOps.Phase = common.Commit
-		gt.Phase = common.Commit
-		if err := c.Replicate(txid, common.SET, gt); err != nil {
-			c.log.Errorf("[txid: %s] failed to set commit state: %s", txid, err)
-			return nil, fmt.Errorf("failed to replicate state: %s", err)
-		}
-
-		if _, err := c.SendMessageToShard(shardOps); err == nil {
+	}
+	gt.Phase = common.Commit
+	if err := c.Replicate(txid, common.SET, gt); err != nil {
+		c.log.Errorf("[txid: %s] failed to set commit state: %s", txid, err)
+		return nil, fmt.Errorf("failed to replicate state: %s", err)
+	}
+	var commitResponses int
+	for id, reply := range c.sendToShards(gt.ShardToCommands, false) {
+		if reply.err == nil {
 			commitResponses++
+			revs.Observe(id, reply.rev)
 		}
 	}
 
@@ -256,6 +561,33 @@ func (c *Coordinator) Transaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand
 	return resultCmds, nil
 }
 
+// commitOnePhase commits gt, whose keys all belong to a single shard, on that
+// shard without two-phase commit: the shard locks the keys and applies the
+// transaction with a single raft entry. Nothing is recorded by the
+// coordinator, the shard either applies the whole transaction or nothing.
+// The revision of the transaction is recorded in revs. The outcome is
+// unknown, see common.ErrOutcomeUnknown, if the shard leader loses its
+// leadership or the connection to it once the transaction is sent.
+func (c *Coordinator) commitOnePhase(gt *raftpb.GlobalTransaction, revs common.RevisionToken) (*raftpb.RaftCommand, error) {
+	for id, shardOps := range gt.ShardToCommands {
+		c.log.Infof("[txid: %s] single shard, committing in one phase", gt.Txid)
+		shardOps.Phase = common.OnePhase
+		shardOps.OutcomeReply = true
+		resp, err := c.sendMessageToShard(shardOps)
+		if resp != nil && resp.Phase == common.OutcomeUnknown {
+			return nil, fmt.Errorf("transaction %s: %w", gt.Txid, common.ErrOutcomeUnknown)
+		}
+		if errors.Is(err, rpc.ErrShutdown) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("transaction %s: %w: %s", gt.Txid, common.ErrOutcomeUnknown, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transaction %s aborted: %s", gt.Txid, err)
+		}
+		revs.Observe(id, resp.Revision)
+	}
+	return &raftpb.RaftCommand{}, nil
+}
+
 // newGlobalTransaction creates a new transaction object and returns if a transaction is
 // read-only or not.
 func (c *Coordinator) newGlobalTransaction(txid string, cmds *raftpb.RaftCommand) *raftpb.GlobalTransaction {
@@ -289,11 +621,28 @@ func (c *Coordinator) newGlobalTransaction(txid string, cmds *raftpb.RaftCommand
 	return gt
 }
 
+// CheckJoin refuses the node id joining the coordinators with the state of
+// the cluster joining, empty if none, if of another cluster or of a removed
+// coordinator. It returns the cluster of the coordinators.
+func (c *Coordinator) CheckJoin(id, joining string) (string, error) {
+	clusterID, err := common.ClusterID(c.RaftDir)
+	if err != nil {
+		return "", err
+	}
+	return clusterID, common.CheckJoin(c.raft, clusterID, id, joining)
+}
+
 // Join joins a node, identified by nodeID and located at addr, to this store.
 // The node must be ready to respond to Raft communications at that address.
+// version is the protocol version announced by the node. The address of a
+// member is only changed if verified, see common.MoveMember.
 // TODO: Make an interface
-func (c *Coordinator) Join(nodeID, addr string) error {
-	c.log.Infof("received join request for remote node %s at %s", nodeID, addr)
+func (c *Coordinator) Join(nodeID, addr string, version int32, verified bool) error {
+	c.log.Infof("received join request for remote node %s at %s (protocol version %d)", nodeID, addr, version)
+	c.versions.Set(nodeID, version)
+	if err := c.replicateVersion(nodeID, version); err != nil {
+		c.log.Warnf("unable to replicate the protocol version of %s: %s", nodeID, err)
+	}
 
 	configFuture := c.raft.GetConfiguration()
 	if err := configFuture.Error(); err != nil {
@@ -301,6 +650,15 @@ func (c *Coordinator) Join(nodeID, addr string) error {
 		return err
 	}
 
+	moved, err := common.MoveMember(c.raft, nodeID, addr, verified)
+	if err != nil {
+		return fmt.Errorf("error moving existing node %s to %s: %w", nodeID, addr, err)
+	}
+	if moved {
+		c.log.Infof("node %s moved to %s", nodeID, addr)
+		return nil
+	}
+
 	for _, srv := range configFuture.Configuration().Servers {
 		// If a node already exists with either the joining node's ID or address,
 		// that node may need to be removed from the config first.
@@ -0,0 +1,133 @@
+package coordinator
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// bulkBatchSize is the most writes of a shard proposed in a single raft entry
+// by Bulk.
+const bulkBatchSize = 1000
+
+// bulkBatch is the writes of a shard waiting to be proposed, with their
+// indexes in the stream.
+type bulkBatch struct {
+	cmds    []*raftpb.Command
+	indexes []int64
+	keys    map[string]bool
+}
+
+// Bulk applies the writes read with next until it returns io.EOF, SET and
+// DEL commands, proposed by the leader of their shard in raft entries of up
+// to bulkBatchSize writes. The writes are independent: each succeeds or fails
+// on its own. emit is called with the results of the writes of each entry
+// once applied, in no particular order across shards, and with those of the
+// writes rejected before being proposed. It returns the number of writes
+// read, with the error of next or emit, if any.
+func (c *Coordinator) Bulk(next func() (*raftpb.Command, error), emit func([]*raftpb.WriteResult) error) (int64, error) {
+	batches := make(map[int64]*bulkBatch)
+	flush := func(shardID int64) error {
+		b := batches[shardID]
+		delete(batches, shardID)
+		return emit(c.proposeBulk(shardID, b))
+	}
+	var n int64
+	for ; ; n++ {
+		cmd, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+		err = c.checkBulkWrite(cmd)
+		if err == nil && cmd.Method == common.DEL {
+			cmd.Expires = tombstoneExpiry(cmd.Key)
+		} else if err == nil {
+			err = c.expireCommands([]*raftpb.Command{cmd})
+		}
+		var blob []byte
+		if err == nil {
+			blob, err = c.encodeBlob(cmd)
+		}
+		if err != nil {
+			if err := emit([]*raftpb.WriteResult{{Index: n, Key: cmd.Key, Error: err.Error()}}); err != nil {
+				return n, err
+			}
+			continue
+		}
+		shardID := c.GetShardID(cmd.Key)
+		b, ok := batches[shardID]
+		// the writes of an entry share its revision, a key is written once
+		if ok && b.keys[cmd.Key] {
+			if err := flush(shardID); err != nil {
+				return n, err
+			}
+			ok = false
+		}
+		if !ok {
+			b = &bulkBatch{keys: make(map[string]bool)}
+			batches[shardID] = b
+		}
+		b.cmds = append(b.cmds, &raftpb.Command{
+			Method:   cmd.Method,
+			Key:      cmd.Key,
+			Value:    cmd.Value,
+			Blob:     blob,
+			Codec:    cmd.Codec,
+			Cond:     cmd.Cond,
+			Expires:  cmd.Expires,
+			Priority: cmd.Priority,
+			Client:   cmd.Client,
+		})
+		b.indexes = append(b.indexes, n)
+		b.keys[cmd.Key] = true
+		if len(b.cmds) == bulkBatchSize {
+			if err := flush(shardID); err != nil {
+				return n, err
+			}
+		}
+	}
+	for shardID := range batches {
+		if err := flush(shardID); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// checkBulkWrite returns an error if cmd cannot be written by Bulk.
+func (c *Coordinator) checkBulkWrite(cmd *raftpb.Command) error {
+	if cmd.Method != common.SET && cmd.Method != common.DEL {
+		return fmt.Errorf("unexpected %q command in a bulk write", cmd.Method)
+	}
+	if len(cmd.Codec) > common.MaxCodecLen {
+		return fmt.Errorf("codec name longer than %d bytes", common.MaxCodecLen)
+	}
+	return c.admit([]*raftpb.Command{{Method: cmd.Method, Key: cmd.Key, Value: cmd.Value, Blob: cmd.Blob, Codec: cmd.Codec}})
+}
+
+// proposeBulk has the leader of shardID apply the writes of b, and returns
+// their results.
+func (c *Coordinator) proposeBulk(shardID int64, b *bulkBatch) []*raftpb.WriteResult {
+	var response raftpb.RPCResponse
+	addr, err := c.findShardLeader(shardID)
+	if err == nil {
+		err = callNode(addr, "Cohort.Bulk", common.SignRequest(common.ClusterSecret, "Cohort.Bulk", &raftpb.RaftCommand{Commands: b.cmds}), &response)
+	}
+	if err == nil && len(response.Results) != len(b.cmds) {
+		err = fmt.Errorf("%d results for %d writes", len(response.Results), len(b.cmds))
+	}
+	results := make([]*raftpb.WriteResult, len(b.cmds))
+	for i, cmd := range b.cmds {
+		if err != nil {
+			results[i] = &raftpb.WriteResult{Key: cmd.Key, Error: fmt.Sprintf("shard %d: %s", shardID, err)}
+		} else {
+			results[i] = response.Results[i]
+		}
+		results[i].Index = b.indexes[i]
+	}
+	return results
+}
@@ -0,0 +1,72 @@
+package coordinator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// clockSkewMetric is the estimated clock offset of a replica from the leader
+// of its raft group.
+const clockSkewMetric = "raftkv_clock_skew_seconds"
+
+// clockOffset estimates the offset of a remote clock from the local one,
+// given the remote time in nanoseconds read between sent and received. The
+// error of the estimate is at most half the round trip.
+func clockOffset(sent, received time.Time, remote int64) time.Duration {
+	return time.Unix(0, remote).Sub(sent.Add(received.Sub(sent) / 2))
+}
+
+// clockSkew keeps the shards whose replicas have a clock too far from the
+// clock of their leader. The leader of such a shard confirms its leadership
+// before serving reads rather than trusting its lease.
+type clockSkew struct {
+	metrics *common.Metrics
+	log     *log.Entry
+
+	mu     sync.Mutex
+	skewed map[int64]bool
+}
+
+func newClockSkew(m *common.Metrics, log *log.Entry) *clockSkew {
+	m.Register(clockSkewMetric, common.GaugeMetric, "Estimated offset of the clock of the replica from the clock of its leader.")
+	return &clockSkew{metrics: m, log: log, skewed: make(map[int64]bool)}
+}
+
+// observe records the clock offsets from the coordinator of the replicas of
+// shardID. Samples without a clock, from nodes predating it, are ignored.
+func (s *clockSkew) observe(shardID int64, group string, replicas map[string]*replicaSample) {
+	var leader *replicaSample
+	for _, r := range replicas {
+		if r.stats["state"] == "Leader" && r.clock {
+			leader = r
+		}
+	}
+	if leader == nil {
+		return
+	}
+	skewed := false
+	for node, r := range replicas {
+		if !r.clock {
+			continue
+		}
+		skew := r.offset - leader.offset
+		s.metrics.Set(clockSkewMetric, skew.Seconds(), "group", group, "node", node)
+		if common.MaxClockSkew > 0 && (skew > common.MaxClockSkew || skew < -common.MaxClockSkew) {
+			s.log.Warnf("clock of %s is %s off the clock of the leader of %s, leader reads are verified", node, skew, group)
+			skewed = true
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skewed[shardID] = skewed
+}
+
+// isSkewed returns whether reads of shardID must be verified by a quorum.
+func (s *clockSkew) isSkewed(shardID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.skewed[shardID]
+}
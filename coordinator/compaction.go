@@ -0,0 +1,59 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Compact snapshots the raft groups of every node of shardID now, of every
+// shard if shardID is negative, and those of the coordinator. It returns the
+// nodes compacted, with the first error.
+func (c *Coordinator) Compact(shardID int64) ([]string, error) {
+	var firstErr error
+	if err := common.CompactRaft(c.raft); err != nil {
+		firstErr = fmt.Errorf("%s: %s", c.ID, err)
+	}
+	nodes := []string{c.ID}
+	for id, peers := range c.ShardToPeers {
+		if shardID >= 0 && id != shardID {
+			continue
+		}
+		for _, addr := range peers {
+			err := c.compactNode(addr)
+			if err != nil {
+				c.log.Errorf("unable to compact %s: %s", addr, err)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %s", addr, err)
+				}
+				continue
+			}
+			nodes = append(nodes, addr)
+		}
+	}
+	return nodes, firstErr
+}
+
+func (c *Coordinator) compactNode(addr string) error {
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var response raftpb.RPCResponse
+	return client.Call("Cohort.Compact", common.SignRequest(common.ClusterSecret, "Cohort.Compact", &raftpb.Command{}), &response)
+}
+
+// refreshStorageMetrics sets the storage metrics of the raft group of the
+// coordinator.
+func (c *Coordinator) refreshStorageMetrics() {
+	stats, err := common.RaftStorage(c.raft)
+	if err != nil {
+		c.log.Errorf("unable to read the raft storage: %s", err)
+		return
+	}
+	common.SetRaftStorageMetrics(c.metrics, "coordinator", stats, time.Now())
+}
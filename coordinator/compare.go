@@ -0,0 +1,89 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ErrCrossShardCompare is returned for compare transactions whose keys
+// belong to more than one shard.
+var ErrCrossShardCompare = errors.New("the keys of a compare transaction must belong to a single shard")
+
+// CompareTxn evaluates the comparisons of ct atomically with its commands:
+// the success commands are applied if every comparison holds, the failure
+// commands otherwise. It reports whether the comparisons held, and returns
+// the results of the gets of the branch applied and the revision of the
+// transaction.
+func (c *Coordinator) CompareTxn(ct *raftpb.CompareTxn) (bool, []*raftpb.Command, int64, error) {
+	c.log.Infof("Processing compare transaction: %v", ct)
+	var key string
+	shard := int64(-1)
+	sameShard := func(k string) bool {
+		if shard == -1 {
+			key, shard = k, c.GetShardID(k)
+		}
+		return c.GetShardID(k) == shard
+	}
+	for _, cmp := range ct.Compare {
+		if err := common.ValidateCompare(cmp); err != nil {
+			return false, nil, 0, err
+		}
+		if !sameShard(cmp.Key) {
+			return false, nil, 0, ErrCrossShardCompare
+		}
+	}
+	var writes []*raftpb.Command
+	for _, cmd := range append(append([]*raftpb.Command{}, ct.Success...), ct.Failure...) {
+		switch cmd.Method {
+		case common.GET:
+		case common.SET, common.DEL:
+			writes = append(writes, cmd)
+		default:
+			return false, nil, 0, fmt.Errorf("unsupported compare command %s", cmd.Method)
+		}
+		if !sameShard(cmd.Key) {
+			return false, nil, 0, ErrCrossShardCompare
+		}
+	}
+	if shard == -1 {
+		return false, nil, 0, errors.New("no key given")
+	}
+	// only one branch is applied
+	for _, branch := range [][]*raftpb.Command{ct.Success, ct.Failure} {
+		if err := common.CheckTxnSize(branch); err != nil {
+			return false, nil, 0, err
+		}
+	}
+	if err := c.admit(writes); err != nil {
+		return false, nil, 0, err
+	}
+	if err := c.expireCommands(writes); err != nil {
+		return false, nil, 0, err
+	}
+	success, err := c.encodeCommands(ct.Success)
+	if err != nil {
+		return false, nil, 0, err
+	}
+	failure, err := c.encodeCommands(ct.Failure)
+	if err != nil {
+		return false, nil, 0, err
+	}
+	ct = &raftpb.CompareTxn{Compare: ct.Compare, Success: success, Failure: failure}
+	resp, err := c.proposeCommand(&raftpb.Command{
+		Method:   common.COMPARE,
+		Key:      key,
+		Compare:  ct,
+		Priority: common.Priority(writes),
+		Client:   common.Client(writes),
+	})
+	if err != nil {
+		return false, nil, 0, err
+	}
+	if err := c.decodeCommands(resp.Commands); err != nil {
+		return false, nil, 0, err
+	}
+	return resp.Value == 1, resp.Commands, resp.Revision, nil
+}
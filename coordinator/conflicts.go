@@ -0,0 +1,34 @@
+package coordinator
+
+import (
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Transaction conflict metrics, by tenant. The conflict rate of a tenant is
+// the ratio of the two.
+const (
+	txnTotalMetric     = "raftkv_txn_total"
+	txnConflictsMetric = "raftkv_txn_conflicts_total"
+)
+
+func registerConflictMetrics(m *common.Metrics) {
+	m.Register(txnTotalMetric, common.CounterMetric, "Transactions by tenant of their keys.")
+	m.Register(txnConflictsMetric, common.CounterMetric, "Transactions aborted by a conflict, by tenant of the conflicting key and reason.")
+}
+
+// observeTxn counts a transaction on cmds once for every tenant of its keys,
+// and its conflict if err is one.
+func (c *Coordinator) observeTxn(cmds []*raftpb.Command, err error) {
+	tenants := make(map[string]bool)
+	for _, cmd := range cmds {
+		ns := common.Namespace(cmd.Key)
+		if !tenants[ns] {
+			tenants[ns] = true
+			c.metrics.Add(txnTotalMetric, 1, "tenant", ns)
+		}
+	}
+	if conflict := common.ParseConflict(err); conflict != nil {
+		c.metrics.Add(txnConflictsMetric, 1, "tenant", common.Namespace(conflict.Key), "reason", conflict.Reason)
+	}
+}
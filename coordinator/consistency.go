@@ -0,0 +1,29 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/config"
+)
+
+// consistencyOf returns the consistency levels of the namespace of key.
+func (c *Coordinator) consistencyOf(key string) config.Consistency {
+	return c.consistency[common.Namespace(key)]
+}
+
+// findReplica returns the address of a reachable replica of the shard of key,
+// the replicas found alive first, for the stale reads.
+func (c *Coordinator) findReplica(key string) (string, int64, error) {
+	shardID := c.GetShardID(key)
+	for _, addr := range c.peers(shardID) {
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			continue
+		}
+		client.Close()
+		return addr, shardID, nil
+	}
+	return "", -1, fmt.Errorf("shard %d is not reachable", shardID)
+}
@@ -44,10 +44,57 @@ type Coordinator struct {
 	txMap map[string]*raftpb.GlobalTransaction
 	mu    sync.RWMutex
 
+	// cut is held for reading by transactions and recovery, and for writing
+	// by exports so that they observe no transaction half committed.
+	cut sync.RWMutex
+
+	// interactive holds the buffered writes of the interactive transactions
+	// served by this coordinator, by txid. They are not replicated.
+	interactiveMu sync.Mutex
+	interactive   map[string]*interactiveTxn
+
 	// ShardToPeers need to be populated based on a config.
 	// If time permits, these can be auto-discovered.
 	ShardToPeers map[int64][]string
 
+	// replicas is the replication factor of the shards, replicated with the
+	// coordinator state
+	placementMu sync.Mutex
+	replicas    map[int64]int32
+	// topology is the desired layout of the shards, nil if none, replicated
+	// with the coordinator state
+	topology *common.Topology
+	// drift is the outcome of the last reconciliation of every shard
+	drift map[int64]*ShardDrift
+
+	// policy is the access policy, replicated with the coordinator state
+	policy *common.Policy
+	// transforms are the transforms of the values, replicated with the
+	// coordinator state
+	transforms *common.Transforms
+	// ttls are the ttl policies of the keys, replicated with the coordinator
+	// state
+	ttls *common.TTLPolicies
+	// versions holds the protocol version announced by each coordinator,
+	// replicated with the coordinator state
+	versions *common.MemberVersions
+
+	metrics *common.Metrics
+	// tenants enforces the quotas of the namespaces and keeps their metrics.
+	tenants *tenants
+	// consistency is the consistency of the reads and writes, by namespace
+	consistency map[string]config.Consistency
+	// replication derives the replication health metrics of the shards
+	replication *replication
+	// members tracks the liveness and metadata of the store nodes
+	members *membership
+	// clockSkew tracks the shards whose leader lease cannot be trusted
+	clockSkew *clockSkew
+	// slow keeps the recent replications over the slow commit threshold
+	slow *common.SlowLog
+	// profile samples the keys read and written through this coordinator
+	profile *common.KeyProfile
+
 	Client   *rpc.Client
 	log      *log.Entry
 	failmode string
@@ -55,16 +102,16 @@ type Coordinator struct {
 
 // NewCoordinator initialises the new coordinator instance
 func NewCoordinator(logger *log.Logger, nodeID, raftDir, raftAddress string, enableSingle bool, failmode string) *Coordinator {
-	if nodeID == "" {
-		nodeID = "node-" + common.RandNodeID(common.NodeIDLen)
-	}
-
 	if raftDir == "" {
 		raftDir, _ = os.Hostname()
 	}
 
 	log := logger.WithField("component", "coordinator")
 	coordDir := filepath.Join(common.RaftPVBaseDir, raftDir, "coords")
+	nodeID, err := common.NodeIdentity(coordDir, nodeID)
+	if err != nil {
+		log.Fatal(err)
+	}
 	log.Infof("Preparing node-%s with persistent directory %s, raftAddress %s", nodeID, coordDir, raftAddress)
 	os.MkdirAll(coordDir, 0700)
 
@@ -73,17 +120,53 @@ func NewCoordinator(logger *log.Logger, nodeID, raftDir, raftAddress string, ena
 		log.Fatal(err)
 	}
 
+	quotas, err := config.GetQuotas()
+	if err != nil {
+		log.Fatal(err)
+	}
+	consistency, err := config.GetConsistency()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for ns, levels := range consistency {
+		if err := common.ValidateConsistency(levels.Read, levels.Write); err != nil {
+			log.Fatalf("tenant %q: %s", ns, err)
+		}
+	}
+
 	shardToPeers := make(map[int64][]string)
+	replicas := make(map[int64]int32)
 	for i, shard := range shardsInfo.Shards {
 		shardToPeers[int64(i)] = append(shardToPeers[int64(i)], shard...)
+		if i < len(shardsInfo.Replicas) && shardsInfo.Replicas[i] > 0 {
+			replicas[int64(i)] = shardsInfo.Replicas[i]
+		}
 	}
 
+	metrics := common.NewMetrics()
+	registerConflictMetrics(metrics)
+	registerReadVerifyMetrics(metrics)
+	common.RegisterRaftStorageMetrics(metrics)
 	c := &Coordinator{
 		ID:           nodeID,
 		RaftAddress:  raftAddress,
 		RaftDir:      coordDir,
 		ShardToPeers: shardToPeers,
+		replicas:     replicas,
+		policy:       common.NewPolicy(),
+		transforms:   common.NewTransforms(),
+		ttls:         common.NewTTLPolicies(),
+		versions:     common.NewMemberVersions(),
 		txMap:        make(map[string]*raftpb.GlobalTransaction),
+		interactive:  make(map[string]*interactiveTxn),
+		metrics:      metrics,
+		tenants:      newTenants(quotas, metrics),
+		consistency:  consistency,
+		replication:  newReplication(metrics),
+		members:      newMembership(metrics),
+		clockSkew:    newClockSkew(metrics, log),
+		slow:         common.NewSlowLog(nodeID, common.SlowLogSize),
+		profile:      common.NewKeyProfile(),
 		log:          log,
 		failmode:     failmode,
 	}
@@ -94,8 +177,14 @@ func NewCoordinator(logger *log.Logger, nodeID, raftDir, raftAddress string, ena
 	}
 
 	c.raft = ra
+	c.versions.Set(nodeID, common.ProtocolVersion)
 
 	go c.periodicRecovery()
+	go c.periodicUsage()
+	go c.periodicReplication()
+	go c.periodicPlacement()
+	go c.periodicMembers()
+	go c.expireInteractive()
 	log.Info("Starting coordniator")
 	return c
 }
@@ -106,8 +195,11 @@ func (c *Coordinator) periodicRecovery() {
 	for {
 
 		select {
-		case <-c.raft.LeaderCh():
+		case leader := <-c.raft.LeaderCh():
 			c.log.Infof("Leader Change")
+			if leader {
+				c.replicateOwnVersion()
+			}
 		case <-time.After(time.Duration(RecoveryInterval) * time.Second):
 		}
 
@@ -125,6 +217,8 @@ func (c *Coordinator) periodicRecovery() {
 // stop the world
 func (c *Coordinator) recoverTransactions() {
 
+	c.cut.RLock()
+	defer c.cut.RUnlock()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -192,9 +286,17 @@ func (c *Coordinator) Replicate(key, op string, gt *raftpb.GlobalTransaction) er
 		return err
 	}
 
+	start := time.Now()
 	f := c.raft.Apply(b, common.RaftTimeout)
+	err = f.Error()
+	c.slow.Observe(common.SlowCommit, "", key, start)
+	return err
+}
 
-	return f.Error()
+// Metrics returns the metrics of the coordinator.
+func (c *Coordinator) Metrics() *common.Metrics {
+	c.refreshStorageMetrics()
+	return c.metrics
 }
 
 // IsLeader return if coordinator is leader of cluster.
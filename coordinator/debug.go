@@ -0,0 +1,75 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+	"strconv"
+	"time"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ShardRaftStats is the raft progress of the replicas of a shard.
+type ShardRaftStats struct {
+	Leader string `json:"leader,omitempty"`
+	// Nodes are the raft stats of the replicas, by rpc address. The lag of a
+	// replica is the number of log entries the leader has and it has not
+	// applied yet.
+	Nodes map[string]map[string]string `json:"nodes"`
+}
+
+// RaftStats returns the raft stats of the coordinator and the progress of
+// every replica of every shard. Replicas that cannot be reached have an error
+// instead of stats.
+func (c *Coordinator) RaftStats() map[string]interface{} {
+	shards := make(map[string]*ShardRaftStats)
+	for shardID, peers := range c.ShardToPeers {
+		s := &ShardRaftStats{Nodes: make(map[string]map[string]string)}
+		for _, addr := range peers {
+			var stats map[string]string
+			if sample, err := nodeRaftStats(addr); err != nil {
+				stats = map[string]string{"error": err.Error()}
+			} else {
+				stats = sample.stats
+			}
+			if stats["state"] == "Leader" {
+				s.Leader = addr
+			}
+			s.Nodes[addr] = stats
+		}
+		if leader, ok := s.Nodes[s.Leader]; ok {
+			last, _ := strconv.ParseUint(leader["last_log_index"], 10, 64)
+			for _, stats := range s.Nodes {
+				if applied, err := strconv.ParseUint(stats["applied_index"], 10, 64); err == nil && applied <= last {
+					stats["lag"] = strconv.FormatUint(last-applied, 10)
+				}
+			}
+		}
+		shards[fmt.Sprint(shardID)] = s
+	}
+	return map[string]interface{}{
+		"coordinator": c.raft.Stats(),
+		"shards":      shards,
+	}
+}
+
+// nodeRaftStats samples the raft stats of the node at addr.
+func nodeRaftStats(addr string) (*replicaSample, error) {
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	var response raftpb.RPCResponse
+	sent := time.Now()
+	if err := client.Call("Cohort.RaftStats", &raftpb.Command{}, &response); err != nil {
+		return nil, err
+	}
+	received := time.Now()
+	s := &replicaSample{stats: response.Stats, rtt: received.Sub(sent)}
+	if response.Value != 0 {
+		s.offset = clockOffset(sent, received, response.Value)
+		s.clock = true
+	}
+	return s, nil
+}
@@ -0,0 +1,164 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+const (
+	// exportRetries is the number of attempts at a consistent cut.
+	exportRetries = 3
+	// exportRetryInterval lets pending transactions be recovered between attempts.
+	exportRetryInterval = 500 * time.Millisecond
+)
+
+// Export calls fn for every key starting with prefix, in key order, with the
+// value and metadata of the key. All the shards are read while no transaction
+// is running, so the export is a consistent cut of the cluster: it sees
+// either all or none of the writes of every transaction. Only the leader
+// runs transactions, so only the leader can export.
+func (c *Coordinator) Export(prefix string, fn func(*raftpb.KVEntry) error) error {
+	c.log.Infof("Processing Export request for prefix %q", prefix)
+	var entries []*raftpb.KVEntry
+	var err error
+	for i := 0; i < exportRetries; i++ {
+		if i > 0 {
+			time.Sleep(exportRetryInterval)
+		}
+		if entries, err = c.cutShards(prefix); err == nil {
+			break
+		}
+		c.log.Infof("export attempt %d failed: %s", i+1, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cutShards reads the keys starting with prefix from every shard while
+// transactions are held off.
+func (c *Coordinator) cutShards(prefix string) ([]*raftpb.KVEntry, error) {
+	c.cut.Lock()
+	defer c.cut.Unlock()
+
+	var entries []*raftpb.KVEntry
+	for shardID := range c.ShardToPeers {
+		addr, err := c.findShardLeader(shardID)
+		if err != nil {
+			return nil, err
+		}
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		var response raftpb.RPCResponse
+		err = client.Call("Cohort.Export", &raftpb.Command{Key: prefix}, &response)
+		client.Close()
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %s", shardID, err)
+		}
+		entries = append(entries, response.Entries...)
+	}
+	return entries, nil
+}
+
+// ExportPage calls fn for up to limit keys starting with prefix, in key
+// order, from cursor, or from the first key if nil, and returns the cursor of
+// the next page, nil after the last one. The first page is a consistent cut,
+// like Export, and the next pages read the shards at the same revisions, so
+// that all the pages of an export are the same cut. A page fails with
+// common.ErrCompacted once a shard does not keep the changes since its
+// revision, and the export must start over.
+func (c *Coordinator) ExportPage(prefix string, cursor *common.ExportCursor, limit int, fn func(*raftpb.KVEntry) error) (*common.ExportCursor, error) {
+	var entries []*raftpb.KVEntry
+	var err error
+	if cursor == nil {
+		for i := 0; i < exportRetries; i++ {
+			if i > 0 {
+				time.Sleep(exportRetryInterval)
+			}
+			cursor = &common.ExportCursor{Revisions: common.RevisionToken{}}
+			if entries, err = c.cutPage(prefix, cursor, limit); err == nil {
+				break
+			}
+			c.log.Infof("export attempt %d failed: %s", i+1, err)
+		}
+	} else {
+		if !strings.HasPrefix(cursor.After, prefix) {
+			return nil, fmt.Errorf("the cursor is not one of an export of prefix %q", prefix)
+		}
+		entries, err = c.readPage(prefix, cursor, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	more := len(entries) > limit
+	if more {
+		entries = entries[:limit]
+	}
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return nil, err
+		}
+	}
+	if !more {
+		return nil, nil
+	}
+	return &common.ExportCursor{Revisions: cursor.Revisions, After: entries[len(entries)-1].Key}, nil
+}
+
+// cutPage reads the first page of an export while transactions are held
+// off, recording the revision of every shard in cursor.
+func (c *Coordinator) cutPage(prefix string, cursor *common.ExportCursor, limit int) ([]*raftpb.KVEntry, error) {
+	c.cut.Lock()
+	defer c.cut.Unlock()
+	return c.readPage(prefix, cursor, limit)
+}
+
+// readPage reads the keys of the page after cursor from every shard, one
+// more than limit so that the last page is known. The shards missing from
+// the revisions of cursor are read at their last revision, recorded in
+// cursor.
+func (c *Coordinator) readPage(prefix string, cursor *common.ExportCursor, limit int) ([]*raftpb.KVEntry, error) {
+	first := len(cursor.Revisions) == 0
+	var entries []*raftpb.KVEntry
+	for shardID := range c.ShardToPeers {
+		rev, ok := cursor.Revisions[shardID]
+		if !ok && !first {
+			return nil, fmt.Errorf("%w: shard %d was added since the export started", common.ErrCompacted, shardID)
+		}
+		addr, err := c.findShardLeader(shardID)
+		if err != nil {
+			return nil, err
+		}
+		args := &raftpb.Command{Key: prefix, After: cursor.After, Revision: rev, Limit: int64(limit) + 1}
+		var response raftpb.RPCResponse
+		if err := callNode(addr, "Cohort.ExportPage", args, &response); err != nil {
+			if common.IsCompacted(err) {
+				return nil, fmt.Errorf("%w: shard %d does not keep the changes since revision %d, the export must start over", common.ErrCompacted, shardID, rev)
+			}
+			return nil, fmt.Errorf("shard %d: %s", shardID, err)
+		}
+		if first {
+			cursor.Revisions[shardID] = response.Value
+		}
+		entries = append(entries, response.Entries...)
+	}
+	return entries, nil
+}
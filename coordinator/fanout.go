@@ -0,0 +1,74 @@
+package coordinator
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// errNotSent is the outcome of the messages dropped after a shard failed.
+var errNotSent = errors.New("not sent, another shard failed")
+
+// shardReply is the reply of a shard to a transaction message.
+type shardReply struct {
+	cmds []*raftpb.Command
+	// txids hold locks on the keys of a snapshot read
+	txids []string
+	// rev is the revision of a commit
+	rev int64
+	err error
+}
+
+// sendToShards sends the transaction messages of the shards of shardToOps in
+// parallel, at most common.TxnParallelism at a time, and returns the replies
+// by shard. With failFast, the messages not sent yet once a shard fails are
+// dropped and reply errNotSent.
+func (c *Coordinator) sendToShards(shardToOps map[int64]*raftpb.ShardOps, failFast bool) map[int64]*shardReply {
+	return c.fanOut(shardToOps, failFast, func(ops *raftpb.ShardOps) *shardReply {
+		resp, err := c.sendMessageToShard(ops)
+		return &shardReply{cmds: resp.GetCommands(), rev: resp.GetRevision(), err: err}
+	})
+}
+
+// fanOut calls send for the shards of shardToOps like sendToShards.
+func (c *Coordinator) fanOut(shardToOps map[int64]*raftpb.ShardOps, failFast bool, send func(*raftpb.ShardOps) *shardReply) map[int64]*shardReply {
+	parallelism := common.TxnParallelism
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	sem := make(chan struct{}, parallelism)
+	failed := make(chan struct{})
+	var failOnce sync.Once
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	replies := make(map[int64]*shardReply, len(shardToOps))
+	for id, ops := range shardToOps {
+		wg.Add(1)
+		go func(id int64, ops *raftpb.ShardOps) {
+			defer wg.Done()
+			reply := &shardReply{err: errNotSent}
+			select {
+			case sem <- struct{}{}:
+				// a failure may be noticed along with a free slot
+				select {
+				case <-failed:
+				default:
+					reply = send(ops)
+				}
+				<-sem
+			case <-failed:
+			}
+			if reply.err != nil && reply.err != errNotSent && failFast {
+				failOnce.Do(func() { close(failed) })
+			}
+			mu.Lock()
+			replies[id] = reply
+			mu.Unlock()
+		}(id, ops)
+	}
+	wg.Wait()
+	return replies
+}
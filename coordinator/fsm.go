@@ -1,9 +1,11 @@
 package coordinator
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"strconv"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hashicorp/raft"
@@ -40,8 +42,40 @@ func (f *fsm) Apply(l *raft.Log) interface{} {
 		f.mu.Lock()
 		defer f.mu.Unlock()
 		delete(f.txMap, command.Key)
+	case common.REPLICAS:
+		shardID, err := strconv.ParseInt(command.Key, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.placementMu.Lock()
+		defer f.placementMu.Unlock()
+		f.replicas[shardID] = int32(command.Value)
+	case common.TOPOLOGY:
+		// validated before being proposed, cleared if empty
+		var t *common.Topology
+		if len(command.Blob) > 0 {
+			var err error
+			if t, err = common.ParseTopology(command.Blob); err != nil {
+				return err
+			}
+		}
+		f.placementMu.Lock()
+		defer f.placementMu.Unlock()
+		f.topology = t
+	case common.POLICY:
+		// validated before being proposed
+		return f.policy.Set(command.Key, command.Blob)
+	case common.TRANSFORM:
+		// validated before being proposed
+		return f.transforms.Set(command.Key, command.Blob)
+	case common.TTL:
+		// validated before being proposed
+		return f.ttls.Set(command.Key, command.Blob)
+	case common.VERSION:
+		f.applyVersion(command)
 	default:
-		panic(fmt.Sprintf("unrecognized command: %+v", command))
+		// refused when proposed, see checkProtocolVersion
+		return fmt.Errorf("unrecognized command %q", command.Method)
 	}
 	return nil
 }
@@ -60,6 +94,19 @@ func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
 		copier.Copy(gt, v)
 		o.Map[k] = gt
 	}
+	f.placementMu.Lock()
+	defer f.placementMu.Unlock()
+	o.Replicas = make(map[int64]int32)
+	for k, v := range f.replicas {
+		o.Replicas[k] = v
+	}
+	if f.topology != nil {
+		o.Topology, _ = json.Marshal(f.topology)
+	}
+	o.Policy = f.policy.Keys()
+	o.Transforms = f.transforms.Keys()
+	o.Ttls = f.ttls.Keys()
+	o.Versions = f.versions.Versions()
 	return &fsmSnapshot{txidMap: o}, nil
 }
 
@@ -72,13 +119,31 @@ func (f *fsm) Restore(rc io.ReadCloser) error {
 		f.log.Fatal(err)
 	}
 
-	var o *raftpb.TxidMap
-	//o := &raftpb.TxidMap{}
+	o := &raftpb.TxidMap{}
 	if err := proto.Unmarshal(b, o); err != nil {
 		return err
 	}
 
 	f.txMap = o.Map
+	if f.txMap == nil {
+		f.txMap = make(map[string]*raftpb.GlobalTransaction)
+	}
+	// replication factors set online override the configured ones
+	f.placementMu.Lock()
+	defer f.placementMu.Unlock()
+	for k, v := range o.Replicas {
+		f.replicas[k] = v
+	}
+	f.topology = nil
+	if len(o.Topology) > 0 {
+		if f.topology, err = common.ParseTopology(o.Topology); err != nil {
+			return err
+		}
+	}
+	f.policy.Restore(o.Policy)
+	f.transforms.Restore(o.Transforms)
+	f.ttls.Restore(o.Ttls)
+	f.restoreVersions(o.Versions)
 	return nil
 }
 
@@ -87,7 +152,15 @@ type fsmSnapshot struct {
 }
 
 func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
-	return nil
+	b, err := proto.Marshal(&f.txidMap)
+	if err == nil {
+		_, err = sink.Write(b)
+	}
+	if err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
 }
 
 func (f *fsmSnapshot) Release() {}
@@ -41,22 +41,38 @@ func (c *Coordinator) Leader(address string) (string, error) {
 func (c *Coordinator) FindLeader(key string) (string, int64, error) {
 
 	shardID := c.GetShardID(key)
-	// make rpc calls to get the leader
-	nodes := c.ShardToPeers[shardID]
+	addr, err := c.findShardLeader(shardID)
+	if err != nil {
+		return "", -1, err
+	}
+	return addr, shardID, nil
+}
+
+// findShardLeader returns the address of the leader of shard shardID.
+func (c *Coordinator) findShardLeader(shardID int64) (string, error) {
+	// make rpc calls to get the leader, the nodes found dead last
+	nodes := c.peers(shardID)
 
 	for _, nodeAddr := range nodes {
 		leader, err := c.Leader(nodeAddr)
 
 		if err == nil && leader != "" {
-			return nodeAddr, shardID, nil
+			return nodeAddr, nil
 		}
 	}
-	return "", -1, fmt.Errorf("shard %d is not reachable", shardID)
+	return "", fmt.Errorf("shard %d is not reachable", shardID)
 }
 
 // SendMessageToShard sends prepare message to a shard. The return value
 // indicates if the shard successfully performed the operation.
 func (c *Coordinator) SendMessageToShard(ops *raftpb.ShardOps) ([]*raftpb.Command, error) {
+	response, err := c.sendMessageToShard(ops)
+	return response.GetCommands(), err
+}
+
+// sendMessageToShard is SendMessageToShard returning the whole reply of the
+// shard, nil if it could not be reached.
+func (c *Coordinator) sendMessageToShard(ops *raftpb.ShardOps) (*raftpb.RPCResponse, error) {
 	var response raftpb.RPCResponse
 	// Figure out leader for the shard
 	addr, _, err := c.FindLeader(ops.MasterKey)
@@ -71,7 +87,7 @@ func (c *Coordinator) SendMessageToShard(ops *raftpb.ShardOps) ([]*raftpb.Comman
 		return nil, err
 	}
 
-	err = client.Call("Cohort.ProcessTransactionMessages", ops, &response)
+	err = client.Call("Cohort.ProcessTransactionMessages", common.SignShardOps(common.ClusterSecret, ops), &response)
 	if err != nil {
 		c.log.Error(err)
 		return nil, err
@@ -79,9 +95,9 @@ func (c *Coordinator) SendMessageToShard(ops *raftpb.ShardOps) ([]*raftpb.Comman
 
 	success := response.Phase == (common.Prepared) || response.Phase == (common.Committed) || response.Phase == (common.Aborted)
 	if success {
-		return response.Commands, nil
+		return &response, nil
 	}
-	return response.Commands, errors.New(response.Phase)
+	return &response, errors.New(response.Phase)
 }
 
 // RetryCommit ...
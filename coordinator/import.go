@@ -0,0 +1,103 @@
+package coordinator
+
+import (
+	"fmt"
+	"io"
+	"net/rpc"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// importChunkSize is the number of keys sent to a shard per ImportChunk call.
+const importChunkSize = 10000
+
+// Import bulk loads the dump r into the cluster. Keys are staged on the
+// leader of their shard in chunks, then every shard installs its keys at
+// once as a raft snapshot instead of proposing them one by one. Writes to the
+// shards are rejected while the import is staged. It returns the number of
+// imported keys.
+func (c *Coordinator) Import(r io.Reader, format string) (int, error) {
+	dump, err := common.NewDumpReader(r, format)
+	if err != nil {
+		return 0, err
+	}
+	id := xid.New().String()
+	c.log.Infof("Processing bulk import %s", id)
+
+	chunks := make(map[int64]*raftpb.ImportChunk)
+	leaders := make(map[int64]*rpc.Client)
+	defer func() {
+		for _, client := range leaders {
+			client.Close()
+		}
+	}()
+	abort := func(err error) (int, error) {
+		for shardID, client := range leaders {
+			var response raftpb.RPCResponse
+			if e := client.Call("Cohort.AbortImport", common.SignRequest(common.ClusterSecret, "Cohort.AbortImport", &raftpb.ImportChunk{Id: id}), &response); e != nil {
+				c.log.Errorf("[import %s] failed to abort on shard %d: %s", id, shardID, e)
+			}
+		}
+		return 0, err
+	}
+	send := func(shardID int64) error {
+		chunk := chunks[shardID]
+		var response raftpb.RPCResponse
+		if err := leaders[shardID].Call("Cohort.ImportChunk", common.SignRequest(common.ClusterSecret, "Cohort.ImportChunk", chunk), &response); err != nil {
+			return fmt.Errorf("shard %d: %s", shardID, err)
+		}
+		chunk.Entries = chunk.Entries[:0]
+		return nil
+	}
+
+	var n int
+	for {
+		e, err := dump.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return abort(err)
+		}
+		if len(e.Codec) > common.MaxCodecLen {
+			return abort(fmt.Errorf("codec of Key=%s is longer than %d bytes", e.Key, common.MaxCodecLen))
+		}
+		shardID := c.GetShardID(e.Key)
+		if _, ok := leaders[shardID]; !ok {
+			addr, _, err := c.FindLeader(e.Key)
+			if err != nil {
+				return abort(err)
+			}
+			client, err := rpc.DialHTTP("tcp", addr)
+			if err != nil {
+				return abort(err)
+			}
+			leaders[shardID] = client
+			chunks[shardID] = &raftpb.ImportChunk{Id: id}
+		}
+		chunks[shardID].Entries = append(chunks[shardID].Entries, e)
+		if len(chunks[shardID].Entries) == importChunkSize {
+			if err := send(shardID); err != nil {
+				return abort(err)
+			}
+		}
+		n++
+	}
+
+	for shardID := range chunks {
+		if err := send(shardID); err != nil {
+			return abort(err)
+		}
+	}
+	// Shards install independently: a failure here leaves the shards that
+	// already installed with their part of the dump.
+	for shardID, client := range leaders {
+		var response raftpb.RPCResponse
+		if err := client.Call("Cohort.InstallImport", common.SignRequest(common.ClusterSecret, "Cohort.InstallImport", &raftpb.ImportChunk{Id: id}), &response); err != nil {
+			return abort(fmt.Errorf("shard %d: %s", shardID, err))
+		}
+		c.log.Infof("[import %s] shard %d installed %d keys", id, shardID, response.Value)
+	}
+	return n, nil
+}
@@ -0,0 +1,203 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// ErrUnknownTransaction is returned for the interactive transactions this
+// coordinator does not serve: committed, aborted, expired, or begun on a
+// coordinator since replaced as leader.
+var ErrUnknownTransaction = errors.New("unknown transaction")
+
+// interactiveTxn is an interactive transaction, whose writes are buffered by
+// the coordinator until it commits.
+type interactiveTxn struct {
+	mu sync.Mutex
+	// writes are the last set or delete of every key written, in the order
+	// the keys were first written.
+	writes []*raftpb.Command
+	// written indexes writes by key.
+	written map[string]int
+	// read are the mod revisions of the existing keys read from the shards.
+	read       map[string]int64
+	lastActive time.Time
+}
+
+// BeginTransaction begins an interactive transaction and returns its id.
+// Its reads and writes are sent one request at a time by the client, and
+// its writes are buffered by the coordinator until the client commits.
+func (c *Coordinator) BeginTransaction() string {
+	txid := xid.New().String()
+	c.interactiveMu.Lock()
+	c.interactive[txid] = &interactiveTxn{
+		written:    make(map[string]int),
+		read:       make(map[string]int64),
+		lastActive: time.Now(),
+	}
+	c.interactiveMu.Unlock()
+	c.log.Infof("began interactive transaction %s", txid)
+	return txid
+}
+
+// TransactionCommands runs the gets, sets and deletes of cmds in the
+// interactive transaction txid, in order, and returns the results of the
+// gets. Gets see the writes buffered by the transaction, the committed values
+// otherwise.
+func (c *Coordinator) TransactionCommands(txid string, cmds *raftpb.RaftCommand) (*raftpb.RaftCommand, error) {
+	tx, err := c.interactiveTxn(txid)
+	if err != nil {
+		return nil, err
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	// nothing is buffered if the writes would make the commit too large
+	if err := tx.checkWrites(cmds.Commands); err != nil {
+		return nil, err
+	}
+	res := &raftpb.RaftCommand{}
+	for _, cmd := range cmds.Commands {
+		switch cmd.Method {
+		case common.GET:
+			get, err := c.interactiveGet(tx, cmd.Key)
+			if err != nil {
+				return nil, err
+			}
+			res.Commands = append(res.Commands, get)
+		case common.SET, common.DEL:
+			if i, ok := tx.written[cmd.Key]; ok {
+				tx.writes[i] = cmd
+				continue
+			}
+			tx.written[cmd.Key] = len(tx.writes)
+			tx.writes = append(tx.writes, cmd)
+		default:
+			return nil, fmt.Errorf("%s is not supported in transactions", cmd.Method)
+		}
+	}
+	tx.lastActive = time.Now()
+	return res, nil
+}
+
+// checkWrites returns a common.TxnSizeError if buffering the writes of cmds
+// would make tx over the size limits.
+func (tx *interactiveTxn) checkWrites(cmds []*raftpb.Command) error {
+	if common.MaxTxnOps <= 0 && common.MaxTxnBytes <= 0 {
+		return nil
+	}
+	writes := append([]*raftpb.Command{}, tx.writes...)
+	written := make(map[string]int, len(tx.written))
+	for k, i := range tx.written {
+		written[k] = i
+	}
+	for _, cmd := range cmds {
+		if cmd.Method != common.SET && cmd.Method != common.DEL {
+			continue
+		}
+		if i, ok := written[cmd.Key]; ok {
+			writes[i] = cmd
+			continue
+		}
+		written[cmd.Key] = len(writes)
+		writes = append(writes, cmd)
+	}
+	return common.CheckTxnSize(writes)
+}
+
+// interactiveGet reads key in tx, recording the revision read of keys not
+// written by tx.
+func (c *Coordinator) interactiveGet(tx *interactiveTxn, key string) (*raftpb.Command, error) {
+	if i, ok := tx.written[key]; ok {
+		if tx.writes[i].Method == common.DEL {
+			return nil, fmt.Errorf("Key=%s does not exist", key)
+		}
+		return &raftpb.Command{Method: common.GET, Key: key, Value: tx.writes[i].Value, Blob: tx.writes[i].Blob, Codec: tx.writes[i].Codec}, nil
+	}
+	resp, err := c.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Meta != nil {
+		tx.read[key] = resp.Meta.ModRevision
+	}
+	return &raftpb.Command{Method: common.GET, Key: key, Value: resp.Value, Blob: resp.Blob, Codec: resp.Codec}, nil
+}
+
+// CommitTransaction commits the buffered writes of the interactive
+// transaction txid atomically. The sets of keys the transaction read are
+// conditioned on the revision read, so that the commit fails if one of them
+// was written since.
+func (c *Coordinator) CommitTransaction(txid string) (*raftpb.RaftCommand, error) {
+	tx, err := c.removeInteractive(txid)
+	if err != nil {
+		return nil, err
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if len(tx.writes) == 0 {
+		return &raftpb.RaftCommand{}, nil
+	}
+	cmds := &raftpb.RaftCommand{IsTxn: true}
+	for _, cmd := range tx.writes {
+		if rev, ok := tx.read[cmd.Key]; ok && cmd.Method == common.SET && cmd.Cond == nil {
+			cmd.Cond = &raftpb.Cond{Key: cmd.Key, ModRevision: rev}
+		}
+		cmds.Commands = append(cmds.Commands, cmd)
+	}
+	return c.TransactionWithID(txid, cmds)
+}
+
+// AbortTransaction drops the interactive transaction txid and its writes.
+func (c *Coordinator) AbortTransaction(txid string) error {
+	_, err := c.removeInteractive(txid)
+	return err
+}
+
+func (c *Coordinator) interactiveTxn(txid string) (*interactiveTxn, error) {
+	c.interactiveMu.Lock()
+	defer c.interactiveMu.Unlock()
+	tx, ok := c.interactive[txid]
+	if !ok {
+		return nil, ErrUnknownTransaction
+	}
+	return tx, nil
+}
+
+func (c *Coordinator) removeInteractive(txid string) (*interactiveTxn, error) {
+	c.interactiveMu.Lock()
+	defer c.interactiveMu.Unlock()
+	tx, ok := c.interactive[txid]
+	if !ok {
+		return nil, ErrUnknownTransaction
+	}
+	delete(c.interactive, txid)
+	return tx, nil
+}
+
+// expireInteractive drops the interactive transactions idle for
+// common.TxnIdleTimeout.
+func (c *Coordinator) expireInteractive() {
+	if common.TxnIdleTimeout <= 0 {
+		return
+	}
+	for range time.Tick(common.TxnIdleTimeout / 4) {
+		cutoff := time.Now().Add(-common.TxnIdleTimeout)
+		c.interactiveMu.Lock()
+		for txid, tx := range c.interactive {
+			tx.mu.Lock()
+			idle := tx.lastActive.Before(cutoff)
+			tx.mu.Unlock()
+			if idle {
+				c.log.Infof("interactive transaction %s expired", txid)
+				delete(c.interactive, txid)
+			}
+		}
+		c.interactiveMu.Unlock()
+	}
+}
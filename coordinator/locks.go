@@ -0,0 +1,34 @@
+package coordinator
+
+import (
+	"net/rpc"
+	"sort"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Locks returns the keys locked by transactions on every reachable shard
+// node, oldest lock first, to trace lock errors back to the transaction
+// holding the key. Nodes that cannot be reached are skipped.
+func (c *Coordinator) Locks() []*raftpb.KeyLock {
+	var locks []*raftpb.KeyLock
+	for _, peers := range c.ShardToPeers {
+		for _, addr := range peers {
+			client, err := rpc.DialHTTP("tcp", addr)
+			if err != nil {
+				c.log.Infof("unable to reach %s for its locks: %s", addr, err)
+				continue
+			}
+			var response raftpb.RPCResponse
+			err = client.Call("Cohort.Locks", &raftpb.Command{}, &response)
+			client.Close()
+			if err != nil {
+				c.log.Infof("unable to get the locks of %s: %s", addr, err)
+				continue
+			}
+			locks = append(locks, response.Locks...)
+		}
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].Age > locks[j].Age })
+	return locks
+}
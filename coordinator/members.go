@@ -0,0 +1,159 @@
+package coordinator
+
+import (
+	"net/rpc"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// nodeUpMetric is 1 for the store nodes alive, by node address and zone.
+const nodeUpMetric = "raftkv_node_up"
+
+// member is what the coordinator knows of a store node.
+type member struct {
+	info     *raftpb.NodeInfo
+	lastSeen time.Time
+}
+
+// membership tracks the liveness and metadata of the store nodes, probed
+// every common.MemberProbeInterval, so that the coordinator stops waiting on
+// failed nodes within seconds.
+type membership struct {
+	metrics *common.Metrics
+
+	mu    sync.Mutex
+	nodes map[string]*member
+	// versions holds the protocol version reported by each store node, by
+	// address
+	versions *common.MemberVersions
+}
+
+func newMembership(m *common.Metrics) *membership {
+	m.Register(nodeUpMetric, common.GaugeMetric, "1 if the store node answers the liveness probes, 0 otherwise.")
+	return &membership{metrics: m, nodes: make(map[string]*member), versions: common.NewMemberVersions()}
+}
+
+// seen records the reply of addr to a probe, nil if it did not reply.
+func (ms *membership) seen(addr string, info *raftpb.NodeInfo, at time.Time) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	m, ok := ms.nodes[addr]
+	if !ok {
+		m = &member{info: &raftpb.NodeInfo{Addr: addr}}
+		ms.nodes[addr] = m
+	}
+	if info != nil {
+		m.info, m.lastSeen = info, at
+		m.info.Addr = addr
+		ms.versions.Set(addr, info.Version)
+	}
+	up := 0.0
+	if common.NodeState(m.lastSeen, at) == common.NodeAlive {
+		up = 1
+	}
+	ms.metrics.Set(nodeUpMetric, up, "node", addr, "zone", m.info.Zone)
+}
+
+// state returns the state of addr at now.
+func (ms *membership) state(addr string, now time.Time) string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	m, ok := ms.nodes[addr]
+	if !ok {
+		// not probed yet
+		return common.NodeAlive
+	}
+	return common.NodeState(m.lastSeen, now)
+}
+
+// list returns the store nodes probed, by address, with their state at now.
+func (ms *membership) list(now time.Time) []*raftpb.NodeInfo {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var nodes []*raftpb.NodeInfo
+	for _, m := range ms.nodes {
+		info := *m.info
+		info.State = common.NodeState(m.lastSeen, now)
+		if !m.lastSeen.IsZero() {
+			info.LastSeen = m.lastSeen.UnixNano()
+		}
+		nodes = append(nodes, &info)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Addr < nodes[j].Addr })
+	return nodes
+}
+
+// Members returns the store nodes with their liveness and metadata.
+func (c *Coordinator) Members() []*raftpb.NodeInfo {
+	return c.members.list(time.Now())
+}
+
+// peers returns the store nodes of shardID, alive first and dead last.
+func (c *Coordinator) peers(shardID int64) []string {
+	now := time.Now()
+	rank := map[string]int{common.NodeAlive: 0, common.NodeSuspect: 1, common.NodeDead: 2}
+	peers := append([]string(nil), c.ShardToPeers[shardID]...)
+	sort.SliceStable(peers, func(i, j int) bool {
+		return rank[c.members.state(peers[i], now)] < rank[c.members.state(peers[j], now)]
+	})
+	return peers
+}
+
+// supportsOnePhase reports whether every store node of the only shard of gt
+// commits transactions in one phase. Nodes not probed yet are assumed not
+// to, and gt is then prepared and committed in two phases.
+func (c *Coordinator) supportsOnePhase(gt *raftpb.GlobalTransaction) bool {
+	for id := range gt.ShardToCommands {
+		if err := c.members.versions.Supports(c.ShardToPeers[id], common.OnePhase); err != nil {
+			c.log.Infof("[txid: %s] committing in two phases: %s", gt.Txid, err)
+			return false
+		}
+	}
+	return true
+}
+
+// periodicMembers probes every store node.
+func (c *Coordinator) periodicMembers() {
+	for range time.Tick(common.MemberProbeInterval) {
+		for _, peers := range c.ShardToPeers {
+			for _, addr := range peers {
+				go c.probe(addr)
+			}
+		}
+	}
+}
+
+// probe asks addr for its metadata, a node not replying within the probe
+// interval missing the probe.
+func (c *Coordinator) probe(addr string) {
+	done := make(chan *raftpb.NodeInfo, 1)
+	go func() {
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			done <- nil
+			return
+		}
+		defer client.Close()
+		var response raftpb.RPCResponse
+		if err := client.Call("Cohort.Member", &raftpb.Command{}, &response); err != nil {
+			done <- nil
+			return
+		}
+		done <- response.Node
+	}()
+	select {
+	case info := <-done:
+		if info != nil && info.Cluster != common.Cluster {
+			// a node of another cluster counts as not replying
+			c.log.Errorf("store node %s is in cluster %q, not %q", addr, info.Cluster, common.Cluster)
+			info = nil
+		}
+		c.members.seen(addr, info, time.Now())
+	case <-time.After(common.MemberProbeInterval):
+		c.members.seen(addr, nil, time.Now())
+	}
+}
@@ -0,0 +1,198 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// MigrationMarker is appended to the destination prefix, and to the source
+// prefix of moves, to name the key written at cutover.
+const MigrationMarker = "_migrated"
+
+// migrateBatchSize is the number of keys written per transaction.
+const migrateBatchSize = 100
+
+// MigrateOptions describes a migration of the keys starting with From to keys
+// starting with To.
+type MigrateOptions struct {
+	From string
+	To   string
+	// Source is the HTTP address of the coordinator leader of a remote
+	// cluster to copy from, this cluster if empty.
+	Source string
+	// Move deletes the source keys after the cutover. Only keys of this
+	// cluster can be moved.
+	Move bool
+	// Rate limits the keys written per second, unlimited if 0.
+	Rate int
+}
+
+// Migration is the value of the cutover marker, encoded in json.
+type Migration struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Source string `json:"source,omitempty"`
+	Keys   int    `json:"keys"`
+	Moved  bool   `json:"moved"`
+	Time   int64  `json:"time"`
+}
+
+// Migrate copies the keys starting with opts.From, renamed to start with
+// opts.To, in throttled batches of transactions. Once all the keys are
+// copied, a single transaction writes the cutover marker To+MigrationMarker,
+// and From+MigrationMarker for moves, so that readers switch over at once.
+// Moves then delete the source keys. Writes to the source made during the
+// migration are not copied: writers should be stopped or watch the marker.
+// It returns the number of migrated keys.
+func (c *Coordinator) Migrate(opts MigrateOptions) (int, error) {
+	if opts.From == opts.To && opts.Source == "" {
+		return 0, fmt.Errorf("source and destination prefix are both %q", opts.From)
+	}
+	if opts.Move && opts.Source != "" {
+		return 0, fmt.Errorf("keys of a remote cluster can only be copied")
+	}
+	c.log.Infof("Processing Migrate request from %q to %q, source %q", opts.From, opts.To, opts.Source)
+
+	throttle := newThrottle(opts.Rate)
+	var keys []string
+	batch := &raftpb.RaftCommand{IsTxn: true}
+	flush := func() error {
+		if len(batch.Commands) == 0 {
+			return nil
+		}
+		throttle.wait(len(batch.Commands))
+		if _, err := c.Transaction(batch); err != nil {
+			return err
+		}
+		batch = &raftpb.RaftCommand{IsTxn: true}
+		return nil
+	}
+	copyEntry := func(e *raftpb.KVEntry) error {
+		if e.Key == opts.From+MigrationMarker {
+			return nil
+		}
+		cmd := &raftpb.Command{Method: common.SET, Key: opts.To + strings.TrimPrefix(e.Key, opts.From), Priority: common.PriorityLow}
+		common.SetCommandValue(cmd, common.EntryValue(e))
+		batch.Commands = append(batch.Commands, cmd)
+		keys = append(keys, e.Key)
+		if len(batch.Commands) == migrateBatchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	var err error
+	if opts.Source == "" {
+		err = c.Export(opts.From, copyEntry)
+	} else {
+		err = exportRemote(opts.Source, opts.From, copyEntry)
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("copied %d keys: %s", len(keys)-len(batch.Commands), err)
+	}
+
+	m := Migration{From: opts.From, To: opts.To, Source: opts.Source, Keys: len(keys), Moved: opts.Move, Time: time.Now().Unix()}
+	marker, err := json.Marshal(&m)
+	if err != nil {
+		return 0, err
+	}
+	cutover := &raftpb.RaftCommand{IsTxn: true}
+	cutover.Commands = append(cutover.Commands, &raftpb.Command{Method: common.SET, Key: opts.To + MigrationMarker, Blob: marker, Codec: "json"})
+	if opts.Move {
+		cutover.Commands = append(cutover.Commands, &raftpb.Command{Method: common.SET, Key: opts.From + MigrationMarker, Blob: marker, Codec: "json"})
+	}
+	// the marker goes ahead of the writes of the applications
+	common.SetPriority(cutover.Commands, common.PriorityHigh)
+	if _, err := c.Transaction(cutover); err != nil {
+		return 0, fmt.Errorf("copied %d keys, cutover failed: %s", len(keys), err)
+	}
+	c.log.Infof("Migrated %d keys from %q to %q", len(keys), opts.From, opts.To)
+
+	if opts.Move {
+		for _, key := range keys {
+			batch.Commands = append(batch.Commands, &raftpb.Command{Method: common.DEL, Key: key, Priority: common.PriorityLow})
+			if len(batch.Commands) == migrateBatchSize {
+				if err := flush(); err != nil {
+					return len(keys), fmt.Errorf("cutover done, deleting source keys failed: %s", err)
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return len(keys), fmt.Errorf("cutover done, deleting source keys failed: %s", err)
+		}
+	}
+	return len(keys), nil
+}
+
+// exportRemote calls fn for every key starting with prefix exported by the
+// coordinator at addr.
+func exportRemote(addr, prefix string, fn func(*raftpb.KVEntry) error) error {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
+	u.Path = path.Join(u.Path, "export")
+	u.RawQuery = url.Values{"prefix": {prefix}, "format": {common.FormatJSONL}}.Encode()
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusMisdirectedRequest {
+			return fmt.Errorf("%s is not the leader of the source cluster, leader is %s", addr, body)
+		}
+		return fmt.Errorf("unable to export from %s: %s", addr, body)
+	}
+	dump, err := common.NewDumpReader(resp.Body, common.FormatJSONL)
+	if err != nil {
+		return err
+	}
+	for {
+		e, err := dump.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+// throttle paces writes to a number of keys per second.
+type throttle struct {
+	rate  int
+	start time.Time
+	n     int
+}
+
+func newThrottle(rate int) *throttle {
+	return &throttle{rate: rate, start: time.Now()}
+}
+
+// wait blocks until n more keys can be written.
+func (t *throttle) wait(n int) {
+	if t.rate <= 0 {
+		return
+	}
+	t.n += n
+	next := t.start.Add(time.Duration(t.n) * time.Second / time.Duration(t.rate))
+	time.Sleep(time.Until(next))
+}
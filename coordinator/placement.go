@@ -0,0 +1,268 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// PlacementInterval is the interval at which the leader enforces the
+// replication factor of the shards.
+const PlacementInterval = 30 * time.Second
+
+// ShardPlacement is the replication factor of a shard and the members of its
+// raft groups.
+type ShardPlacement struct {
+	Shard int64 `json:"shard"`
+	// Replicas is the number of voters of the raft groups, not enforced if 0.
+	Replicas int32                  `json:"replicas"`
+	Groups   []*raftpb.GroupMembers `json:"groups"`
+}
+
+// SetReplicas sets the replication factor of shardID to n, 0 to stop
+// enforcing it, and enforces it right away.
+func (c *Coordinator) SetReplicas(shardID int64, n int32) (*ShardPlacement, error) {
+	if _, ok := c.ShardToPeers[shardID]; !ok {
+		return nil, fmt.Errorf("unknown shard %d", shardID)
+	}
+	if n < 0 {
+		return nil, fmt.Errorf("invalid replication factor %d", n)
+	}
+	if n%2 == 0 && n > 0 {
+		c.log.Warnf("shard %d set to %d replicas, which tolerate no more failures than %d", shardID, n, n-1)
+	}
+	if err := c.checkProtocolVersion(common.REPLICAS); err != nil {
+		return nil, err
+	}
+	cmd := &raftpb.RaftCommand{
+		Commands: []*raftpb.Command{
+			{
+				Method: common.REPLICAS,
+				Key:    strconv.FormatInt(shardID, 10),
+				Value:  int64(n),
+			},
+		},
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.raft.Apply(b, common.RaftTimeout).Error(); err != nil {
+		return nil, err
+	}
+	return c.place(shardID, n), nil
+}
+
+// replicasOf returns the replication factor of shardID.
+func (c *Coordinator) replicasOf(shardID int64) int32 {
+	c.placementMu.Lock()
+	defer c.placementMu.Unlock()
+	return c.replicas[shardID]
+}
+
+// place sets the number of voters of the raft groups of shardID to n on the
+// peers leading them, and returns their members. Peers that cannot be
+// reached are skipped.
+func (c *Coordinator) place(shardID int64, n int32) *ShardPlacement {
+	p := &ShardPlacement{Shard: shardID, Replicas: n}
+	p.Groups, _ = c.callGroupLeaders(shardID, "Cohort.SetReplication", &raftpb.Command{Value: int64(n)})
+	return p
+}
+
+// ChangeMembers promotes the non-voters promote and then removes the members
+// remove of the raft groups of shardID, by store node id, and returns the
+// members of the groups.
+func (c *Coordinator) ChangeMembers(shardID int64, promote, remove []string) (*ShardPlacement, error) {
+	if _, ok := c.ShardToPeers[shardID]; !ok {
+		return nil, fmt.Errorf("unknown shard %d", shardID)
+	}
+	change := &raftpb.MemberChange{Promote: promote, Remove: remove}
+	groups, err := c.callGroupLeaders(shardID, "Cohort.ChangeMembers", change)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) < 2 {
+		return nil, fmt.Errorf("changed %d of the 2 raft groups of shard %d, the other leader was not reached", len(groups), shardID)
+	}
+	return &ShardPlacement{Shard: shardID, Replicas: c.replicasOf(shardID), Groups: groups}, nil
+}
+
+// callGroupLeaders calls method with args on the peers of shardID, which
+// reply with the members of the raft groups they lead. The first error is
+// returned with the members of the groups of the peers that succeeded.
+func (c *Coordinator) callGroupLeaders(shardID int64, method string, args proto.Message) ([]*raftpb.GroupMembers, error) {
+	var groups []*raftpb.GroupMembers
+	var firstErr error
+	for _, addr := range c.ShardToPeers[shardID] {
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			c.log.Infof("unable to reach %s: %s", addr, err)
+			continue
+		}
+		var response raftpb.RPCResponse
+		err = client.Call(method, common.SignRequest(common.ClusterSecret, method, args), &response)
+		client.Close()
+		if err != nil {
+			c.log.Errorf("%s of shard %d failed on %s: %s", method, shardID, addr, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %s", addr, err)
+			}
+			continue
+		}
+		groups = append(groups, response.Members...)
+	}
+	return groups, firstErr
+}
+
+// Placement returns the replication factor and the members of the raft
+// groups of every shard.
+func (c *Coordinator) Placement() []*ShardPlacement {
+	var shards []int64
+	for shardID := range c.ShardToPeers {
+		shards = append(shards, shardID)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i] < shards[j] })
+	var res []*ShardPlacement
+	for _, shardID := range shards {
+		p := c.place(shardID, 0)
+		p.Replicas = c.replicasOf(shardID)
+		res = append(res, p)
+	}
+	return res
+}
+
+// periodicPlacement enforces the replication factor of the shards while
+// leader, so that nodes joining a shard over it become non-voters, and
+// reconciles them with the topology.
+func (c *Coordinator) periodicPlacement() {
+	for range time.Tick(PlacementInterval) {
+		if !c.IsLeader() {
+			continue
+		}
+		for shardID := range c.ShardToPeers {
+			if n := c.replicasOf(shardID); n > 0 {
+				c.place(shardID, n)
+			}
+		}
+		c.reconcile()
+	}
+}
+
+// MembershipRequest is a declarative member change of the raft groups of a
+// shard, or of the coordinators if Shard is nil. Store nodes are named by
+// their node id, coordinators by their raft id.
+type MembershipRequest struct {
+	Shard    *int64               `json:"shard,omitempty"`
+	Join     []*raftpb.MemberJoin `json:"join,omitempty"`
+	Promote  []string             `json:"promote,omitempty"`
+	Remove   []string             `json:"remove,omitempty"`
+	Transfer string               `json:"transfer,omitempty"`
+	// Replicas sets the replication factor of the shard if not nil.
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// MembershipReport is the outcome of a MembershipRequest: the members of the
+// raft groups with the steps taken, or only planned if DryRun. Changed is
+// false when the request was already in place, so that it can be repeated.
+type MembershipReport struct {
+	Shard    *int64                 `json:"shard,omitempty"`
+	DryRun   bool                   `json:"dry_run"`
+	Changed  bool                   `json:"changed"`
+	Replicas int32                  `json:"replicas"`
+	Groups   []*raftpb.GroupMembers `json:"groups"`
+}
+
+// ChangeMembership applies req, or only plans it with dryRun. The steps
+// already in place are skipped.
+func (c *Coordinator) ChangeMembership(req *MembershipRequest, dryRun bool) (*MembershipReport, error) {
+	if req.Shard == nil {
+		if req.Replicas != nil {
+			return nil, fmt.Errorf("the replication factor is set by shard")
+		}
+		return c.changeCoordinators(req, dryRun)
+	}
+	shardID := *req.Shard
+	if _, ok := c.ShardToPeers[shardID]; !ok {
+		return nil, fmt.Errorf("unknown shard %d", shardID)
+	}
+	if req.Replicas != nil && *req.Replicas < 0 {
+		return nil, fmt.Errorf("invalid replication factor %d", *req.Replicas)
+	}
+	change := &raftpb.MemberChange{
+		Join:     req.Join,
+		Promote:  req.Promote,
+		Remove:   req.Remove,
+		Transfer: req.Transfer,
+		DryRun:   dryRun,
+	}
+	groups, err := c.callGroupLeaders(shardID, "Cohort.ChangeMembers", change)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) < 2 {
+		return nil, fmt.Errorf("changed %d of the 2 raft groups of shard %d, the other leader was not reached", len(groups), shardID)
+	}
+	report := &MembershipReport{Shard: req.Shard, DryRun: dryRun, Replicas: c.replicasOf(shardID), Groups: groups}
+	for _, g := range groups {
+		if len(g.Actions) > 0 {
+			report.Changed = true
+		}
+	}
+	if req.Replicas != nil && *req.Replicas != report.Replicas {
+		report.Changed = true
+		report.Replicas = *req.Replicas
+		if !dryRun {
+			if _, err := c.SetReplicas(shardID, *req.Replicas); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// changeCoordinators applies req to the raft group of the coordinators, led
+// by this one.
+func (c *Coordinator) changeCoordinators(req *MembershipRequest, dryRun bool) (*MembershipReport, error) {
+	future := c.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	joins := make([]raft.Server, len(req.Join))
+	for i, j := range req.Join {
+		joins[i] = raft.Server{ID: raft.ServerID(j.Id), Address: raft.ServerAddress(j.Address), Suffrage: raft.Nonvoter}
+		if j.Voter {
+			joins[i].Suffrage = raft.Voter
+		}
+	}
+	plan, err := common.PlanMembers(future.Configuration().Servers, c.ID, joins, req.Promote, req.Remove, req.Transfer)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		for _, a := range plan {
+			c.log.Infof("coordinator member change: %s %s", a.Op, a.Id)
+		}
+		if err := common.ApplyMembers(c.raft, plan); err != nil {
+			return nil, err
+		}
+		if future = c.raft.GetConfiguration(); future.Error() != nil {
+			return nil, future.Error()
+		}
+	}
+	members := &raftpb.GroupMembers{Group: "coordinator", Actions: plan}
+	for _, srv := range future.Configuration().Servers {
+		if srv.Suffrage == raft.Voter {
+			members.Voters = append(members.Voters, string(srv.ID))
+		} else {
+			members.Nonvoters = append(members.Nonvoters, string(srv.ID))
+		}
+	}
+	return &MembershipReport{DryRun: dryRun, Changed: len(plan) > 0, Groups: []*raftpb.GroupMembers{members}}, nil
+}
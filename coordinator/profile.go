@@ -0,0 +1,20 @@
+package coordinator
+
+import (
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// KeyProfile returns the profile of the keys read and written through this
+// coordinator, started and reported on demand.
+func (c *Coordinator) KeyProfile() *common.KeyProfile {
+	return c.profile
+}
+
+// profileReads records in the key profile the reads of the keys and values
+// of cmds, the results of gets.
+func (c *Coordinator) profileReads(cmds []*raftpb.Command) {
+	for _, cmd := range cmds {
+		c.profile.Read(cmd.Key, common.CommandsSize([]*raftpb.Command{cmd}))
+	}
+}
@@ -0,0 +1,212 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/config"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// UsageRefreshInterval is the interval at which the leader collects the
+// usage of every tenant from the shards.
+const UsageRefreshInterval = 10 * time.Second
+
+// ErrQuotaExceeded is wrapped by the errors of operations rejected by the
+// quota of their tenant.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Per tenant metrics. Tenants are the namespaces of the keys.
+const (
+	tenantRequestsMetric = "raftkv_tenant_requests_total"
+	tenantRejectedMetric = "raftkv_tenant_rejected_total"
+	tenantKeysMetric     = "raftkv_tenant_keys"
+	tenantBytesMetric    = "raftkv_tenant_bytes"
+)
+
+// tenants enforces the quotas of the tenants and keeps their metrics. Key and
+// byte quotas are checked against the usage last collected from the shards,
+// so they are exceeded by at most the writes of a refresh interval. The QPS
+// quota is enforced by every coordinator for the requests it serves.
+type tenants struct {
+	quotas  map[string]config.Quota
+	metrics *common.Metrics
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	usage   map[string]*raftpb.NamespaceUsage
+}
+
+func newTenants(quotas map[string]config.Quota, m *common.Metrics) *tenants {
+	m.Register(tenantRequestsMetric, common.CounterMetric, "Requests by tenant and operation.")
+	m.Register(tenantRejectedMetric, common.CounterMetric, "Requests rejected by the quota of the tenant, by reason.")
+	m.Register(tenantKeysMetric, common.GaugeMetric, "Keys stored by tenant.")
+	m.Register(tenantBytesMetric, common.GaugeMetric, "Value bytes stored by tenant.")
+
+	t := &tenants{
+		quotas:  quotas,
+		metrics: m,
+		buckets: make(map[string]*tokenBucket),
+		usage:   make(map[string]*raftpb.NamespaceUsage),
+	}
+	for ns, q := range quotas {
+		if q.QPS > 0 {
+			t.buckets[ns] = newTokenBucket(q.QPS)
+		}
+	}
+	return t
+}
+
+// admit counts the operation op on key and checks it against the quota of
+// the namespace of key. size is the size of the value of writes, exists
+// reports whether key already exists and is only called at the key quota.
+func (t *tenants) admit(op, key string, size int64, exists func() bool) error {
+	ns := common.Namespace(key)
+	t.metrics.Add(tenantRequestsMetric, 1, "tenant", ns, "op", op)
+	q, ok := t.quotas[ns]
+	if !ok {
+		return nil
+	}
+
+	t.mu.Lock()
+	if b, ok := t.buckets[ns]; ok && !b.take() {
+		t.mu.Unlock()
+		return t.reject(ns, "qps")
+	}
+	var keys, bytes int64
+	if u, ok := t.usage[ns]; ok {
+		keys, bytes = u.Keys, u.Bytes
+	}
+	t.mu.Unlock()
+
+	if op != common.SET {
+		return nil
+	}
+	if q.Bytes > 0 && bytes+size > q.Bytes {
+		return t.reject(ns, "bytes")
+	}
+	if q.Keys > 0 && keys >= q.Keys && !exists() {
+		return t.reject(ns, "keys")
+	}
+	return nil
+}
+
+func (t *tenants) reject(ns, reason string) error {
+	t.metrics.Add(tenantRejectedMetric, 1, "tenant", ns, "reason", reason)
+	return fmt.Errorf("tenant %q: %s %w", ns, reason, ErrQuotaExceeded)
+}
+
+// setUsage replaces the usage of every tenant.
+func (t *tenants) setUsage(usage map[string]*raftpb.NamespaceUsage) {
+	t.mu.Lock()
+	t.usage = usage
+	t.mu.Unlock()
+
+	t.metrics.Reset(tenantKeysMetric)
+	t.metrics.Reset(tenantBytesMetric)
+	for ns, u := range usage {
+		t.metrics.Set(tenantKeysMetric, float64(u.Keys), "tenant", ns)
+		t.metrics.Set(tenantBytesMetric, float64(u.Bytes), "tenant", ns)
+	}
+}
+
+// tokenBucket allows rate operations per second, in bursts of up to a
+// second worth of operations.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: rate, last: time.Now()}
+}
+
+// take reports whether an operation is allowed now.
+func (b *tokenBucket) take() bool {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// admit checks the operations of cmds against the key naming rules and the
+// quotas of their tenants.
+func (c *Coordinator) admit(cmds []*raftpb.Command) error {
+	for _, cmd := range cmds {
+		if cmd.Method != common.GET && cmd.Method != common.HISTORY {
+			if err := common.ValidateKey(cmd.Method, cmd.Key); err != nil {
+				return err
+			}
+		}
+		exists := func() bool {
+			_, err := c.getRevision(cmd.Key, 0, 0)
+			// when in doubt, let the write through
+			return err == nil || !strings.Contains(err.Error(), "does not exist")
+		}
+		if err := c.tenants.admit(cmd.Method, cmd.Key, common.ValueSize(common.CommandValue(cmd)), exists); err != nil {
+			return err
+		}
+		if cmd.Method != common.GET && cmd.Method != common.HISTORY {
+			c.profile.Write(cmd.Key, common.CommandsSize([]*raftpb.Command{cmd}))
+		}
+	}
+	return nil
+}
+
+// periodicUsage collects the usage of the tenants while leader.
+func (c *Coordinator) periodicUsage() {
+	for range time.Tick(UsageRefreshInterval) {
+		if !c.IsLeader() {
+			continue
+		}
+		usage, err := c.collectUsage()
+		if err != nil {
+			c.log.Errorf("unable to collect tenant usage: %s", err)
+			continue
+		}
+		c.tenants.setUsage(usage)
+	}
+}
+
+// collectUsage sums the usage of every namespace over the shards.
+func (c *Coordinator) collectUsage() (map[string]*raftpb.NamespaceUsage, error) {
+	usage := make(map[string]*raftpb.NamespaceUsage)
+	for shardID := range c.ShardToPeers {
+		addr, err := c.findShardLeader(shardID)
+		if err != nil {
+			return nil, err
+		}
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		var response raftpb.RPCResponse
+		err = client.Call("Cohort.Usage", &raftpb.Command{}, &response)
+		client.Close()
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %s", shardID, err)
+		}
+		for _, u := range response.Usage {
+			if total, ok := usage[u.Namespace]; ok {
+				total.Keys += u.Keys
+				total.Bytes += u.Bytes
+			} else {
+				usage[u.Namespace] = u
+			}
+		}
+	}
+	return usage, nil
+}
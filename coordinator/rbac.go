@@ -0,0 +1,83 @@
+package coordinator
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Policy returns the roles and their bindings.
+func (c *Coordinator) Policy() *common.PolicyView {
+	return c.policy.View()
+}
+
+// Allowed reports whether id is granted verb on key by the access policy.
+func (c *Coordinator) Allowed(id *common.Identity, verb, key string) bool {
+	return c.policy.Allowed(id, verb, key)
+}
+
+// SetRole sets the rules of the role name, deleting it if rules is nil.
+func (c *Coordinator) SetRole(name string, rules []common.Rule) error {
+	var value []byte
+	if rules != nil {
+		var err error
+		if value, err = json.Marshal(rules); err != nil {
+			return err
+		}
+	}
+	return c.setPolicyKey(common.RolePrefix+name, value)
+}
+
+// Bind binds roles to subject, an identity name or group:<name>, unbinding
+// it if roles is nil.
+func (c *Coordinator) Bind(subject string, roles []string) error {
+	var value []byte
+	if roles != nil {
+		var err error
+		if value, err = json.Marshal(roles); err != nil {
+			return err
+		}
+	}
+	return c.setPolicyKey(common.BindingPrefix+subject, value)
+}
+
+// setPolicyKey replicates the system key of the policy key set to value, or
+// deleted if value is empty.
+func (c *Coordinator) setPolicyKey(key string, value []byte) error {
+	if err := common.ValidatePolicyKey(key, value); err != nil {
+		return err
+	}
+	c.log.Infof("setting policy key %s to %s", key, value)
+	return c.proposeSystemKey(common.POLICY, key, value)
+}
+
+// proposeSystemKey replicates the system key key set to value by a command
+// of method, and returns the error of its application.
+func (c *Coordinator) proposeSystemKey(method, key string, value []byte) error {
+	if err := c.checkProtocolVersion(method); err != nil {
+		return err
+	}
+	cmd := &raftpb.RaftCommand{
+		Commands: []*raftpb.Command{
+			{
+				Method: method,
+				Key:    key,
+				Blob:   value,
+			},
+		},
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	f := c.raft.Apply(b, common.RaftTimeout)
+	if err := f.Error(); err != nil {
+		return err
+	}
+	if err, ok := f.Response().(error); ok {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// snapshotReadRetry is how long a snapshot read waits before reading again
+// the shards not committed yet by a transaction decided to commit.
+const snapshotReadRetry = 10 * time.Millisecond
+
+// ReadOnlyTransaction reads the keys of cmds, which must all be gets, at a
+// consistent point across their shards without proposing anything to the
+// coordinator or the shards, and without taking locks.
+//
+// Shards return the committed values, with the transactions holding locks on
+// them. A transaction decided to commit may be committed on some shards only:
+// the shards it still locks are read again until it is, so that the reads
+// never observe half of it.
+func (c *Coordinator) ReadOnlyTransaction(cmds *raftpb.RaftCommand) (*raftpb.RaftCommand, error) {
+	if !isReadOnly(cmds.Commands) {
+		return nil, fmt.Errorf("read-only transactions only get keys")
+	}
+	if err := common.CheckTxnSize(cmds.Commands); err != nil {
+		return nil, err
+	}
+	txid := xid.New().String()
+	gt := c.newGlobalTransaction(txid, cmds)
+	pending := gt.ShardToCommands
+	replies := make(map[int64]*shardReply, len(pending))
+	deadline := time.Now().Add(common.RaftTimeout)
+	for len(pending) > 0 {
+		for id, reply := range c.fanOut(pending, true, c.snapshotRead) {
+			if reply.err != nil {
+				return nil, reply.err
+			}
+			replies[id] = reply
+		}
+		retry := make(map[int64]*raftpb.ShardOps)
+		for id := range pending {
+			for _, locker := range replies[id].txids {
+				if c.decidedToCommit(locker) {
+					retry[id] = pending[id]
+				}
+			}
+		}
+		if len(retry) > 0 && time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for transactions committing on the keys of %s", txid)
+		}
+		if len(retry) > 0 {
+			time.Sleep(snapshotReadRetry)
+		}
+		pending = retry
+	}
+	res := &raftpb.RaftCommand{}
+	for _, reply := range replies {
+		res.Commands = append(res.Commands, reply.cmds...)
+	}
+	if err := c.decodeCommands(res.Commands); err != nil {
+		return nil, err
+	}
+	c.profileReads(res.Commands)
+	return res, nil
+}
+
+// decidedToCommit reports whether the transaction txid is prepared on all its
+// shards, and thus committing.
+func (c *Coordinator) decidedToCommit(txid string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	gt, ok := c.txMap[txid]
+	if !ok {
+		// still preparing, or committing in one phase on a single shard
+		return false
+	}
+	switch gt.Phase {
+	case common.Prepared, common.Commit, common.Committed:
+		return true
+	}
+	return false
+}
+
+// snapshotRead reads the keys of ops on the leader of their shard.
+func (c *Coordinator) snapshotRead(ops *raftpb.ShardOps) *shardReply {
+	addr, _, err := c.FindLeader(ops.MasterKey)
+	if err != nil {
+		return &shardReply{err: err}
+	}
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return &shardReply{err: err}
+	}
+	defer client.Close()
+	var response raftpb.RPCResponse
+	if err := client.Call("Cohort.SnapshotRead", ops, &response); err != nil {
+		return &shardReply{err: err}
+	}
+	return &shardReply{cmds: response.Commands, txids: response.Txids}
+}
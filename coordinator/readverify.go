@@ -0,0 +1,71 @@
+package coordinator
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// readVerifyMetric counts the reads verified against another replica, by
+// result: match, mismatch, or inconclusive when the other replica could not
+// tell the value of the key at the revision read.
+const readVerifyMetric = "raftkv_read_verify_total"
+
+func registerReadVerifyMetrics(m *common.Metrics) {
+	m.Register(readVerifyMetric, common.CounterMetric, "Reads verified against another replica, by shard and result.")
+}
+
+// maybeVerifyRead verifies, for a sample of common.ReadVerifySample of the
+// reads, the read of key served by the replica at addr, response, against
+// another replica of its shard, in the background.
+func (c *Coordinator) maybeVerifyRead(key, addr string, shardID int64, response *raftpb.RPCResponse) {
+	if common.ReadVerifySample <= 0 || rand.Float64() >= common.ReadVerifySample || response.Meta == nil {
+		return
+	}
+	var other string
+	for _, peer := range c.peers(shardID) {
+		if peer != addr {
+			other = peer
+			break
+		}
+	}
+	if other == "" {
+		return
+	}
+	go c.verifyRead(key, addr, other, shardID, response)
+}
+
+// verifyRead reads key from the replica at other at the revision of the value
+// read from addr, response, and compares them. A mismatch is a replica that
+// applied the same log to a different value, and is logged as an error.
+func (c *Coordinator) verifyRead(key, addr, other string, shardID int64, response *raftpb.RPCResponse) {
+	rev := response.Meta.ModRevision
+	cmd := &raftpb.RaftCommand{Commands: []*raftpb.Command{{
+		Method:      common.GET,
+		Key:         key,
+		Revision:    rev,
+		MinRevision: rev,
+		Consistency: common.ReadStale,
+	}}}
+	var check raftpb.RPCResponse
+	err := callNode(other, "Cohort.ProcessCommands", cmd, &check)
+	result := "match"
+	switch {
+	case err != nil && strings.Contains(err.Error(), "does not exist"):
+		result = "mismatch"
+	case err != nil:
+		c.log.Infof("unable to verify the read of %s at revision %d on %s: %s", key, rev, other, err)
+		result = "inconclusive"
+	case check.Meta != nil && check.Meta.ModRevision != rev,
+		check.Value != response.Value || check.Codec != response.Codec || string(check.Blob) != string(response.Blob):
+		result = "mismatch"
+	}
+	c.metrics.Add(readVerifyMetric, 1, "shard", strconv.FormatInt(shardID, 10), "result", result)
+	if result == "mismatch" {
+		c.log.Errorf("replicas of shard %d diverge on %s at revision %d: %s read %d %q, %s read %d %q (%v)",
+			shardID, key, rev, addr, response.Value, response.Blob, other, check.Value, check.Blob, err)
+	}
+}
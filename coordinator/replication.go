@@ -0,0 +1,180 @@
+package coordinator
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/raft-kv-store/common"
+)
+
+// ReplicationSampleInterval is the interval at which the leader samples the
+// raft stats of every replica.
+const ReplicationSampleInterval = 5 * time.Second
+
+const (
+	// indexHistory is how long the last log index of the shard leaders is
+	// remembered, to convert the lag of replicas in entries to time.
+	indexHistory = 10 * time.Minute
+	// coordinatorGroup labels the raft group of the coordinators.
+	coordinatorGroup = "coordinator"
+)
+
+// Replication health metrics, labelled by raft group and replica.
+const (
+	replicaLagEntriesMetric = "raftkv_replica_lag_entries"
+	replicaLagSecondsMetric = "raftkv_replica_lag_seconds"
+	replicaContactMetric    = "raftkv_replica_last_contact_seconds"
+	replicaRTTMetric        = "raftkv_replica_rtt_seconds"
+	raftTermMetric          = "raftkv_raft_term"
+	raftElectionsMetric     = "raftkv_raft_elections_last_hour"
+)
+
+// indexSample is the last log index of a leader at a point in time.
+type indexSample struct {
+	at    time.Time
+	index uint64
+}
+
+// groupState is what replication remembers of a raft group between samples.
+type groupState struct {
+	term      uint64
+	elections []time.Time
+	indexes   []indexSample
+}
+
+// replication derives replication health metrics from successive samples of
+// the raft stats of the replicas.
+type replication struct {
+	metrics *common.Metrics
+
+	mu     sync.Mutex
+	groups map[string]*groupState
+}
+
+func newReplication(m *common.Metrics) *replication {
+	m.Register(replicaLagEntriesMetric, common.GaugeMetric, "Log entries the leader has and the replica has not applied.")
+	m.Register(replicaLagSecondsMetric, common.GaugeMetric, "Time since the leader had the first entry the replica has not applied.")
+	m.Register(replicaContactMetric, common.GaugeMetric, "Time since the replica last heard from its leader.")
+	m.Register(replicaRTTMetric, common.GaugeMetric, "Round trip time of a stats request from the coordinator to the replica.")
+	m.Register(raftTermMetric, common.GaugeMetric, "Current raft term of the group.")
+	m.Register(raftElectionsMetric, common.GaugeMetric, "Terms started in the last hour, each an election.")
+	return &replication{metrics: m, groups: make(map[string]*groupState)}
+}
+
+// replicaSample is the raft stats of a replica and how long they took to get.
+type replicaSample struct {
+	stats map[string]string
+	rtt   time.Duration
+	// offset is the estimated offset of the clock of the replica from the
+	// clock of the coordinator, if the replica sent its clock.
+	offset time.Duration
+	clock  bool
+}
+
+// observe records the samples of the replicas of group, taken at now.
+func (r *replication) observe(group string, replicas map[string]*replicaSample, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	g, ok := r.groups[group]
+	if !ok {
+		g = &groupState{}
+		r.groups[group] = g
+	}
+
+	var last uint64
+	for _, s := range replicas {
+		if s.stats["state"] == "Leader" {
+			last = statUint(s.stats, "last_log_index")
+		}
+		if term := statUint(s.stats, "term"); term > g.term {
+			// the first sample only sets the term
+			if g.term != 0 {
+				for i := g.term; i < term; i++ {
+					g.elections = append(g.elections, now)
+				}
+			}
+			g.term = term
+		}
+	}
+	for len(g.elections) > 0 && now.Sub(g.elections[0]) > time.Hour {
+		g.elections = g.elections[1:]
+	}
+	if last != 0 {
+		g.indexes = append(g.indexes, indexSample{at: now, index: last})
+	}
+	for len(g.indexes) > 0 && now.Sub(g.indexes[0].at) > indexHistory {
+		g.indexes = g.indexes[1:]
+	}
+
+	r.metrics.Set(raftTermMetric, float64(g.term), "group", group)
+	r.metrics.Set(raftElectionsMetric, float64(len(g.elections)), "group", group)
+	for node, s := range replicas {
+		r.metrics.Set(replicaRTTMetric, s.rtt.Seconds(), "group", group, "node", node)
+		if contact, err := time.ParseDuration(s.stats["last_contact"]); err == nil {
+			r.metrics.Set(replicaContactMetric, contact.Seconds(), "group", group, "node", node)
+		} else if s.stats["state"] == "Leader" {
+			r.metrics.Set(replicaContactMetric, 0, "group", group, "node", node)
+		}
+		if last == 0 {
+			continue
+		}
+		applied := statUint(s.stats, "applied_index")
+		var lag uint64
+		if applied < last {
+			lag = last - applied
+		}
+		r.metrics.Set(replicaLagEntriesMetric, float64(lag), "group", group, "node", node)
+		r.metrics.Set(replicaLagSecondsMetric, g.lagSince(applied, now).Seconds(), "group", group, "node", node)
+	}
+}
+
+// lagSince returns how long the leader has had entries past applied, as far
+// as the remembered samples tell.
+func (g *groupState) lagSince(applied uint64, now time.Time) time.Duration {
+	for _, s := range g.indexes {
+		if s.index > applied {
+			return now.Sub(s.at)
+		}
+	}
+	return 0
+}
+
+func statUint(stats map[string]string, name string) uint64 {
+	v, _ := strconv.ParseUint(stats[name], 10, 64)
+	return v
+}
+
+// periodicReplication samples the replication of the shards and of the
+// coordinators. Every coordinator tracks the clock skew of the shards, which
+// its reads depend on, and the leader exports the replication metrics.
+func (c *Coordinator) periodicReplication() {
+	for range time.Tick(ReplicationSampleInterval) {
+		leader := c.IsLeader()
+		now := time.Now()
+		for shardID, peers := range c.ShardToPeers {
+			replicas := make(map[string]*replicaSample)
+			for _, addr := range peers {
+				sample, err := nodeRaftStats(addr)
+				if err != nil {
+					c.log.Infof("unable to sample the raft stats of %s: %s", addr, err)
+					continue
+				}
+				replicas[addr] = sample
+			}
+			group := fmt.Sprintf("shard-%d", shardID)
+			c.clockSkew.observe(shardID, group, replicas)
+			if leader {
+				c.replication.observe(group, replicas, now)
+			}
+		}
+		if !leader {
+			continue
+		}
+		// followers of the coordinator group are only known through the leader
+		c.replication.observe(coordinatorGroup, map[string]*replicaSample{
+			c.RaftAddress: {stats: c.raft.Stats()},
+		}, now)
+	}
+}
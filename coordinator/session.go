@@ -0,0 +1,50 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// OpenSession opens a client session on every shard and returns its id. The
+// writes of a session carry increasing sequence numbers, and a write retried
+// with the same number is applied at most once.
+func (c *Coordinator) OpenSession() (string, error) {
+	id := xid.New().String()
+	if err := c.sessionCommand(common.OPEN, id); err != nil {
+		return "", err
+	}
+	c.log.Infof("opened session %s", id)
+	return id, nil
+}
+
+// CloseSession closes the client session id on every shard.
+func (c *Coordinator) CloseSession(id string) error {
+	return c.sessionCommand(common.CLOSE, id)
+}
+
+// sessionCommand proposes method for the session id to the leader of every
+// shard.
+func (c *Coordinator) sessionCommand(method, id string) error {
+	cmd := &raftpb.RaftCommand{Commands: []*raftpb.Command{{Method: method, Key: id, Priority: common.PriorityHigh}}}
+	for shardID := range c.ShardToPeers {
+		addr, err := c.findShardLeader(shardID)
+		if err != nil {
+			return err
+		}
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("Unable to reach shard at :%s", addr)
+		}
+		var response raftpb.RPCResponse
+		err = client.Call("Cohort.ProcessCommands", common.SignRequest(common.ClusterSecret, "Cohort.ProcessCommands", cmd), &response)
+		client.Close()
+		if err != nil {
+			return fmt.Errorf("%s session %s on shard %d: %s", method, id, shardID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package coordinator
+
+// ShardMap is the routing table of the shards handed to clients: a key
+// belongs to shard common.SimpleHash(key, Shards), whose store nodes are
+// Peers. Leaders is the rpc address of the leader of every reachable shard.
+type ShardMap struct {
+	Shards  int                `json:"shards"`
+	Peers   map[int64][]string `json:"peers"`
+	Leaders map[int64]string   `json:"leaders"`
+}
+
+// ShardMap returns the routing table of the shards, for clients sending
+// single-key operations to the shard leaders directly.
+func (c *Coordinator) ShardMap() *ShardMap {
+	m := &ShardMap{
+		Shards:  len(c.ShardToPeers),
+		Peers:   make(map[int64][]string),
+		Leaders: make(map[int64]string),
+	}
+	for shardID, peers := range c.ShardToPeers {
+		m.Peers[shardID] = peers
+		if addr, err := c.findShardLeader(shardID); err == nil {
+			m.Leaders[shardID] = addr
+		}
+	}
+	return m
+}
@@ -0,0 +1,34 @@
+package coordinator
+
+import (
+	"net/rpc"
+	"sort"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// SlowLog returns the recent slow operations of this coordinator and of every
+// reachable shard node, most recent first. Nodes that cannot be reached are
+// skipped.
+func (c *Coordinator) SlowLog() []*raftpb.SlowOp {
+	ops := c.slow.List()
+	for _, peers := range c.ShardToPeers {
+		for _, addr := range peers {
+			client, err := rpc.DialHTTP("tcp", addr)
+			if err != nil {
+				c.log.Infof("unable to reach %s for its slow log: %s", addr, err)
+				continue
+			}
+			var response raftpb.RPCResponse
+			err = client.Call("Cohort.SlowLog", &raftpb.Command{}, &response)
+			client.Close()
+			if err != nil {
+				c.log.Infof("unable to get the slow log of %s: %s", addr, err)
+				continue
+			}
+			ops = append(ops, response.SlowOps...)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].Time > ops[j].Time })
+	return ops
+}
@@ -0,0 +1,32 @@
+package coordinator
+
+import (
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// tombstoneExpiry returns the expiry time of the tombstone of key deleted
+// now, 0 if the key is deleted for good.
+func tombstoneExpiry(key string) int64 {
+	if !common.SoftDeleted(key) {
+		return 0
+	}
+	return time.Now().Add(common.SoftDeleteRetention).UnixNano()
+}
+
+// Undelete restores key, soft deleted, from its tombstone, and reports
+// whether it did, with the revision of the write. Keys whose tombstone
+// expired, and keys written again since, are not restored.
+func (c *Coordinator) Undelete(key string) (bool, int64, error) {
+	c.log.Infof("Processing Undelete request %s", key)
+	if err := c.admit([]*raftpb.Command{{Method: common.SET, Key: key}}); err != nil {
+		return false, 0, err
+	}
+	resp, err := c.proposeCommand(&raftpb.Command{Method: common.UNDELETE, Key: key})
+	if err != nil {
+		return false, 0, err
+	}
+	return resp.Value == 1, resp.Revision, nil
+}
@@ -0,0 +1,110 @@
+package coordinator
+
+import (
+	"fmt"
+	"net/rpc"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// StateReport is the comparison of the hashes of the keys of the replicas of
+// a shard.
+type StateReport struct {
+	Shard int64 `json:"shard"`
+	// Index is the index at which the replicas were asked to hash their keys.
+	Index int64 `json:"index"`
+	// Compared is the number of indexes hashed by more than one replica.
+	Compared    int                       `json:"compared"`
+	Divergences []*common.StateDivergence `json:"divergences,omitempty"`
+	// Unreachable are the replicas whose hashes could not be read.
+	Unreachable []string `json:"unreachable,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// VerifyState has the replicas of shardID, of every shard if shardID is
+// negative, hash their keys at the same index, and compares those hashes
+// along with the recent hashes of the replicas.
+func (c *Coordinator) VerifyState(shardID int64) []*StateReport {
+	var reports []*StateReport
+	for id, peers := range c.ShardToPeers {
+		if shardID >= 0 && id != shardID {
+			continue
+		}
+		reports = append(reports, c.verifyShardState(id, peers))
+	}
+	return reports
+}
+
+func (c *Coordinator) verifyShardState(shardID int64, peers []string) *StateReport {
+	report := &StateReport{Shard: shardID}
+	addr, err := c.findShardLeader(shardID)
+	if err == nil {
+		var response raftpb.RPCResponse
+		err = callNode(addr, "Cohort.HashState", &raftpb.Command{}, &response)
+		report.Index = response.Revision
+	}
+	if err != nil {
+		report.Error = fmt.Sprintf("unable to hash the keys of shard %d: %s", shardID, err)
+	}
+	var hashes []*raftpb.StateHash
+	for _, addr := range peers {
+		var response raftpb.RPCResponse
+		if err := callNode(addr, "Cohort.StateHashes", &raftpb.Command{Revision: report.Index}, &response); err != nil {
+			c.log.Infof("unable to get the state hashes of %s: %s", addr, err)
+			report.Unreachable = append(report.Unreachable, addr)
+			continue
+		}
+		hashes = append(hashes, response.StateHashes...)
+	}
+	report.Divergences, report.Compared = common.CompareStateHashes(shardID, hashes)
+	for _, d := range report.Divergences {
+		c.log.Errorf("replicas of shard %d diverge at index %d: %v", shardID, d.Index, d.Hashes)
+	}
+	return report
+}
+
+// callNode calls method of the node at addr.
+func callNode(addr, method string, args interface{}, reply interface{}) error {
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return client.Call(method, args, reply)
+}
+
+// RepairReport is the outcome of the repair of a replica.
+type RepairReport struct {
+	Shard int64  `json:"shard"`
+	Node  string `json:"node"`
+	// Source is the leader the keys were repaired from.
+	Source   string `json:"source"`
+	Index    int64  `json:"index"`
+	Repaired int64  `json:"repaired"`
+}
+
+// Repair has the replicas of shardID build the Merkle trees of their keys at
+// the same index, and the replica at addr replace the keys it holds
+// differently from the leader.
+func (c *Coordinator) Repair(shardID int64, addr string) (*RepairReport, error) {
+	leader, err := c.findShardLeader(shardID)
+	if err != nil {
+		return nil, err
+	}
+	if leader == addr {
+		return nil, fmt.Errorf("%s is the leader of shard %d, transfer its leadership first", addr, shardID)
+	}
+	var response raftpb.RPCResponse
+	if err := callNode(leader, "Cohort.BuildMerkle", &raftpb.Command{}, &response); err != nil {
+		return nil, err
+	}
+	report := &RepairReport{Shard: shardID, Node: addr, Source: leader, Index: response.Revision}
+	req := &raftpb.MerkleRequest{Index: report.Index, Source: leader}
+	if err := callNode(addr, "Cohort.Repair", common.SignRequest(common.ClusterSecret, "Cohort.Repair", req), &response); err != nil {
+		return nil, err
+	}
+	report.Repaired = response.Value
+	c.log.Infof("repaired %d keys of %s from %s", report.Repaired, addr, leader)
+	return report, nil
+}
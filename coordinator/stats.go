@@ -0,0 +1,69 @@
+package coordinator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ShardStats are the statistics of the keys of a shard, read from its
+// leader.
+type ShardStats struct {
+	Shard int64  `json:"shard"`
+	Node  string `json:"node,omitempty"`
+	Keys  int64  `json:"keys"`
+	// Bytes are the approximate bytes of the keys and values.
+	Bytes      int64         `json:"bytes"`
+	ValueSizes []*SizeBucket `json:"value_sizes,omitempty"`
+	// Prefixes are the namespaces with the most writes.
+	Prefixes []*raftpb.NamespaceUsage `json:"prefixes,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+}
+
+// SizeBucket counts the values of at most LE bytes, larger than those of the
+// previous bucket.
+type SizeBucket struct {
+	LE    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// Stats returns the statistics of the keys of shardID, of every shard if
+// shardID is negative, by shard.
+func (c *Coordinator) Stats(shardID int64) []*ShardStats {
+	var res []*ShardStats
+	for id := range c.ShardToPeers {
+		if shardID >= 0 && id != shardID {
+			continue
+		}
+		res = append(res, c.shardStats(id))
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Shard < res[j].Shard })
+	return res
+}
+
+func (c *Coordinator) shardStats(shardID int64) *ShardStats {
+	stats := &ShardStats{Shard: shardID}
+	addr, err := c.findShardLeader(shardID)
+	var response raftpb.RPCResponse
+	if err == nil {
+		stats.Node = addr
+		err = callNode(addr, "Cohort.Stats", &raftpb.Command{}, &response)
+	}
+	if err != nil {
+		stats.Error = fmt.Sprintf("unable to read the stats of shard %d: %s", shardID, err)
+		return stats
+	}
+	s := response.ShardStats
+	stats.Keys, stats.Bytes, stats.Prefixes = s.GetKeys(), s.GetBytes(), s.GetPrefixes()
+	for i, n := range s.GetValueSizes() {
+		le := "+Inf"
+		if i < len(common.ValueSizeBounds) {
+			le = strconv.FormatInt(common.ValueSizeBounds[i], 10)
+		}
+		stats.ValueSizes = append(stats.ValueSizes, &SizeBucket{LE: le, Count: n})
+	}
+	return stats
+}
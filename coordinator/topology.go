@@ -0,0 +1,207 @@
+package coordinator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ShardDrift is how a shard differs from the topology, as found by its last
+// reconciliation.
+type ShardDrift struct {
+	Shard int64    `json:"shard"`
+	Drift []string `json:"drift,omitempty"`
+	// Actions are the member changes made by the last reconciliation.
+	Actions []*raftpb.MemberAction `json:"actions,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+	Checked time.Time              `json:"checked"`
+}
+
+// TopologyStatus is the topology and the drift of the shards from it.
+type TopologyStatus struct {
+	Topology *common.Topology `json:"topology"`
+	Shards   []*ShardDrift    `json:"shards"`
+}
+
+// SetTopology replicates the json topology b, cleared if empty, and
+// reconciles the shards toward it right away.
+func (c *Coordinator) SetTopology(b []byte) (*TopologyStatus, error) {
+	if len(b) > 0 {
+		t, err := common.ParseTopology(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range t.Shards {
+			if _, ok := c.ShardToPeers[s.Shard]; !ok {
+				return nil, fmt.Errorf("unknown shard %d", s.Shard)
+			}
+		}
+	}
+	if err := c.checkProtocolVersion(common.TOPOLOGY); err != nil {
+		return nil, err
+	}
+	cmd := &raftpb.RaftCommand{
+		Commands: []*raftpb.Command{
+			{
+				Method: common.TOPOLOGY,
+				Blob:   b,
+			},
+		},
+	}
+	data, err := proto.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	f := c.raft.Apply(data, common.RaftTimeout)
+	if err := f.Error(); err != nil {
+		return nil, err
+	}
+	if err, ok := f.Response().(error); ok {
+		return nil, err
+	}
+	c.reconcile()
+	return c.Topology(), nil
+}
+
+// Topology returns the topology, nil if none, and the drift of the shards
+// found by the last reconciliation of this coordinator.
+func (c *Coordinator) Topology() *TopologyStatus {
+	c.placementMu.Lock()
+	defer c.placementMu.Unlock()
+	status := &TopologyStatus{Topology: c.topology}
+	if c.topology != nil {
+		for _, s := range c.topology.Shards {
+			if d, ok := c.drift[s.Shard]; ok {
+				status.Shards = append(status.Shards, d)
+			}
+		}
+	}
+	return status
+}
+
+// reconcile brings every shard of the topology a step closer to it, and
+// records their drift.
+func (c *Coordinator) reconcile() {
+	c.placementMu.Lock()
+	t := c.topology
+	c.placementMu.Unlock()
+	if t == nil {
+		return
+	}
+	drift := make(map[int64]*ShardDrift)
+	for _, s := range t.Shards {
+		drift[s.Shard] = c.reconcileShard(s)
+	}
+	c.placementMu.Lock()
+	c.drift = drift
+	c.placementMu.Unlock()
+}
+
+// reconcileShard joins the nodes of s missing from the raft groups of its
+// shard, removes the members not in s once they have all joined, and sets
+// its replication factor. The leadership is moved to a node of s before
+// removing members, as the leaders cannot remove themselves.
+func (c *Coordinator) reconcileShard(s *common.ShardTopology) *ShardDrift {
+	d := &ShardDrift{Shard: s.Shard, Checked: time.Now()}
+	groups := c.place(s.Shard, 0).Groups
+	if len(groups) < 2 {
+		d.Error = fmt.Sprintf("reached %d of the 2 raft group leaders", len(groups))
+		return d
+	}
+	zones := make(map[string]string)
+	for _, n := range c.Members() {
+		if n.Id != "" {
+			zones[n.Id] = n.Zone
+		}
+	}
+	replicas := c.replicasOf(s.Shard)
+	seen := make(map[string]bool)
+	joins := make(map[string]*common.TopologyNode)
+	removes := make(map[string]bool)
+	var transfer string
+	for _, g := range groups {
+		var members []string
+		voters := make(map[string]bool)
+		for _, id := range append(append([]string(nil), g.Voters...), g.Nonvoters...) {
+			members = append(members, strings.TrimPrefix(id, "c-"))
+		}
+		for _, id := range g.Voters {
+			voters[strings.TrimPrefix(id, "c-")] = true
+		}
+		drift, join, remove := s.Drift(members, zones, replicas)
+		for _, line := range drift {
+			if !seen[line] {
+				seen[line] = true
+				d.Drift = append(d.Drift, line)
+			}
+		}
+		for _, n := range join {
+			joins[n.ID] = n
+		}
+		for _, id := range remove {
+			removes[id] = true
+		}
+		if transfer == "" {
+			for _, n := range s.Nodes {
+				if voters[n.ID] {
+					transfer = n.ID
+					break
+				}
+			}
+		}
+	}
+	if s.Replicas > 0 {
+		replicas = s.Replicas
+	}
+	req := &MembershipRequest{Shard: &s.Shard}
+	for _, n := range s.Nodes {
+		if joins[n.ID] != nil {
+			// non-voters are promoted by the replication factor, if any
+			req.Join = append(req.Join, &raftpb.MemberJoin{Id: n.ID, Address: n.Address, CohortAddress: n.CohortAddress, Voter: replicas == 0})
+		}
+	}
+	for id := range removes {
+		req.Remove = append(req.Remove, id)
+	}
+	sort.Strings(req.Remove)
+	if s.Replicas > 0 && s.Replicas != c.replicasOf(s.Shard) {
+		req.Replicas = &s.Replicas
+	}
+	if len(req.Join) == 0 && len(req.Remove) == 0 && req.Replicas == nil {
+		return d
+	}
+	if len(req.Remove) > 0 {
+		if transfer == "" {
+			d.Error = "no voter of the topology to take over the leadership"
+			return d
+		}
+		report, err := c.ChangeMembership(&MembershipRequest{Shard: &s.Shard, Transfer: transfer}, false)
+		if err != nil {
+			d.Error = err.Error()
+			return d
+		}
+		d.Actions = append(d.Actions, actionsOf(report)...)
+	}
+	c.log.Infof("reconciling shard %d with the topology: %s", s.Shard, strings.Join(d.Drift, ", "))
+	report, err := c.ChangeMembership(req, false)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	d.Actions = append(d.Actions, actionsOf(report)...)
+	return d
+}
+
+// actionsOf returns the member changes of the groups of report.
+func actionsOf(report *MembershipReport) []*raftpb.MemberAction {
+	var actions []*raftpb.MemberAction
+	for _, g := range report.Groups {
+		actions = append(actions, g.Actions...)
+	}
+	return actions
+}
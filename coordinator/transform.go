@@ -0,0 +1,94 @@
+package coordinator
+
+import (
+	"encoding/json"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Transforms returns the rules of the value transforms, by prefix.
+func (c *Coordinator) Transforms() []common.TransformRule {
+	return c.transforms.Rules()
+}
+
+// SetTransforms sets the transforms of the blob values of the keys starting
+// with prefix, deleting them if specs is empty.
+func (c *Coordinator) SetTransforms(prefix string, specs []common.TransformSpec) error {
+	var value []byte
+	if len(specs) > 0 {
+		var err error
+		if value, err = json.Marshal(specs); err != nil {
+			return err
+		}
+	}
+	key := common.TransformPrefix + prefix
+	if err := common.ValidateTransformKey(key, value); err != nil {
+		return err
+	}
+	c.log.Infof("setting transform key %s to %s", key, value)
+	return c.proposeSystemKey(common.TRANSFORM, key, value)
+}
+
+// transformed reports whether cmd writes a blob value transformed by a rule.
+func (c *Coordinator) transformed(cmd *raftpb.Command) bool {
+	return cmd.Codec != "" && cmd.Method != common.DEL && cmd.Method != common.GET && c.transforms.Applies(cmd.Key)
+}
+
+// encodeBlob returns the blob value written by cmd, transformed by the rule
+// of its key.
+func (c *Coordinator) encodeBlob(cmd *raftpb.Command) ([]byte, error) {
+	if !c.transformed(cmd) {
+		return cmd.Blob, nil
+	}
+	return c.transforms.Encode(cmd.Key, cmd.Blob)
+}
+
+// encodeCommands returns cmds with the blob values they write transformed by
+// the rules of their keys, the commands transformed being copies.
+func (c *Coordinator) encodeCommands(cmds []*raftpb.Command) ([]*raftpb.Command, error) {
+	encoded := make([]*raftpb.Command, len(cmds))
+	for i, cmd := range cmds {
+		encoded[i] = cmd
+		if !c.transformed(cmd) {
+			continue
+		}
+		blob, err := c.transforms.Encode(cmd.Key, cmd.Blob)
+		if err != nil {
+			return nil, err
+		}
+		encoded[i] = proto.Clone(cmd).(*raftpb.Command)
+		encoded[i].Blob = blob
+	}
+	return encoded, nil
+}
+
+// decodeCommands transforms back the blob values of cmds, the results of
+// gets, in place.
+func (c *Coordinator) decodeCommands(cmds []*raftpb.Command) error {
+	for _, cmd := range cmds {
+		if cmd.Codec == "" {
+			continue
+		}
+		blob, err := c.transforms.Decode(cmd.Key, cmd.Blob)
+		if err != nil {
+			return err
+		}
+		cmd.Blob = blob
+	}
+	return nil
+}
+
+// decodeReply transforms back the blob value of key in resp, in place.
+func (c *Coordinator) decodeReply(key string, resp *raftpb.RPCResponse) error {
+	if resp == nil || resp.Codec == "" {
+		return nil
+	}
+	blob, err := c.transforms.Decode(key, resp.Blob)
+	if err != nil {
+		return err
+	}
+	resp.Blob = blob
+	return nil
+}
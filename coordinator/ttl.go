@@ -0,0 +1,55 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// TTLPolicies returns the ttl policies of the keys, by prefix.
+func (c *Coordinator) TTLPolicies() []common.TTLRule {
+	return c.ttls.Rules()
+}
+
+// SetTTLPolicy sets the ttl policy of the keys starting with prefix,
+// deleting it if p is nil.
+func (c *Coordinator) SetTTLPolicy(prefix string, p *common.TTLPolicy) error {
+	var value []byte
+	if p != nil {
+		var err error
+		if value, err = json.Marshal(p); err != nil {
+			return err
+		}
+	}
+	key := common.TTLPrefix + prefix
+	if err := common.ValidateTTLKey(key, value); err != nil {
+		return err
+	}
+	c.log.Infof("setting ttl key %s to %s", key, value)
+	return c.proposeSystemKey(common.TTL, key, value)
+}
+
+// expireCommands sets the expiry time of the keys set by cmds, in place,
+// from the ttl given by the client and the policy of the key. The clocks of
+// the coordinators and of the shard leaders deleting the keys are assumed
+// in sync.
+func (c *Coordinator) expireCommands(cmds []*raftpb.Command) error {
+	now := time.Now()
+	for _, cmd := range cmds {
+		switch cmd.Method {
+		case common.GET, common.DEL, common.GETDEL:
+			continue
+		}
+		ttl, err := c.ttls.TTL(cmd.Key, time.Duration(cmd.Ttl)*time.Millisecond)
+		if err != nil {
+			return err
+		}
+		cmd.Expires = 0
+		if ttl > 0 {
+			cmd.Expires = now.Add(ttl).UnixNano()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package coordinator
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// replicateVersion proposes the protocol version announced by coordinator id,
+// so that the coordinators, and so the leaders elected later, know it without
+// it joining them again. The versions stay in the memory of the leader while
+// a coordinator is too old to apply them.
+func (c *Coordinator) replicateVersion(id string, version int32) error {
+	if version < common.CoordinatorProtocolVersion {
+		return nil
+	}
+	ids, err := c.coordinatorIDs()
+	if err != nil {
+		return err
+	}
+	if c.versions.ClusterVersion(ids) < common.CoordinatorProtocolVersion {
+		return nil
+	}
+	cmd := &raftpb.RaftCommand{Commands: []*raftpb.Command{{Method: common.VERSION, Key: id, Value: int64(version)}}}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	return c.raft.Apply(b, common.RaftTimeout).Error()
+}
+
+// replicateOwnVersion has the coordinator replicate its protocol version
+// once it becomes the leader, as it may run a newer build than the one the
+// other coordinators last heard of.
+func (c *Coordinator) replicateOwnVersion() {
+	if err := c.replicateVersion(c.ID, common.ProtocolVersion); err != nil {
+		c.log.Warnf("unable to replicate the protocol version: %s", err)
+	}
+}
+
+// checkProtocolVersion returns an error if any coordinator is too old to
+// apply a command of method. Coordinators announce their version when they
+// (re)join, and the leader replicates it through raft. Coordinators it has
+// not heard of are treated as the base version until they rejoin.
+func (c *Coordinator) checkProtocolVersion(method string) error {
+	ids, err := c.coordinatorIDs()
+	if err != nil {
+		return err
+	}
+	return c.versions.Supports(ids, method)
+}
+
+// coordinatorIDs returns the ids of the coordinators.
+func (c *Coordinator) coordinatorIDs() ([]string, error) {
+	configFuture := c.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, srv := range configFuture.Configuration().Servers {
+		ids = append(ids, string(srv.ID))
+	}
+	return ids, nil
+}
+
+// applyVersion records the protocol version of a coordinator. The
+// coordinator keeps its own, that of its build.
+func (f *fsm) applyVersion(command *raftpb.Command) {
+	if command.Key != f.ID {
+		f.versions.Set(command.Key, int32(command.Value))
+	}
+}
+
+// restoreVersions records the protocol versions of the coordinators of a
+// snapshot.
+func (f *fsm) restoreVersions(versions map[string]int32) {
+	for id, version := range versions {
+		if id != f.ID {
+			f.versions.Set(id, version)
+		}
+	}
+}
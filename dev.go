@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/config"
+	"github.com/raft-kv-store/coordinator"
+	httpd "github.com/raft-kv-store/http"
+	"github.com/raft-kv-store/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// Addresses of the store node of the development cluster.
+const (
+	DevStoreListenAddress = "localhost:17001"
+	DevStoreRaftAddress   = "localhost:17101"
+)
+
+// devKeys is the example keyspace the development cluster is seeded with.
+var devKeys = map[string]int64{
+	"alice":    100,
+	"bob":      50,
+	"universe": 42,
+}
+
+// startDev starts a one-node development cluster in this process: a
+// coordinator serving the API on --listen, and a store node holding the only
+// shard, both bootstrapped at once with their raft state in memory. Once
+// they have a leader, the keys of devKeys are set. It returns the temporary
+// directory of the other files of the nodes, to remove on exit.
+func startDev(logger *log.Logger, l *log.Entry) string {
+	logger.SetLevel(log.DebugLevel)
+	dir, err := ioutil.TempDir("", "raftkv-dev")
+	if err != nil {
+		l.Fatalf("unable to create the directory of the development cluster: %s", err)
+	}
+	common.RaftPVBaseDir = dir
+	common.InMemory = true
+	config.Shards = &config.ShardsConfig{Shards: [][]string{{DevStoreListenAddress}}}
+
+	kv := store.NewStore(logger, "dev-store", "store", DevStoreRaftAddress, true, DevStoreListenAddress, "",
+		common.GetDerivedAddress(DevStoreRaftAddress), "")
+	c := coordinator.NewCoordinator(logger, "dev-coordinator", "coordinator", raftAddress, true, failmode)
+	h := httpd.NewService(logger, listenAddress, c, newAuditLog(l))
+	h.Start("")
+
+	deadline := time.Now().Add(common.RaftTimeout)
+	for !kv.Ready() || !c.IsLeader() {
+		if time.Now().After(deadline) {
+			l.Fatalf("the development cluster has no leader after %s", common.RaftTimeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for key, value := range devKeys {
+		if err := c.Set(key, value); err != nil {
+			l.Warnf("unable to seed %s: %s", key, err)
+		}
+	}
+	l.Infof("development cluster ready at http://%s, its data is lost on exit", listenAddress)
+	return dir
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/raft-kv-store/common"
+)
+
+// TxidHeader carries the id of the transaction in transaction responses.
+const TxidHeader = "X-Txid"
+
+// auditRecorder captures what the audit log needs from a response.
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	keys   []string
+}
+
+func (a *auditRecorder) WriteHeader(status int) {
+	if a.status == 0 {
+		a.status = status
+	}
+	a.ResponseWriter.WriteHeader(status)
+}
+
+func (a *auditRecorder) Write(b []byte) (int, error) {
+	if a.status == 0 {
+		a.status = http.StatusOK
+	}
+	return a.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered data of the response, for the streamed replies.
+func (a *auditRecorder) Flush() {
+	if f, ok := a.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// setAuditKeys records the keys of a request only known from its body.
+func setAuditKeys(w http.ResponseWriter, keys ...string) {
+	if a, ok := w.(*auditRecorder); ok {
+		a.keys = keys
+	}
+}
+
+// auditOp returns the operation of r for the audit log, the key or prefix
+// it applies to and whether it is a write rather than an administrative
+// operation. Reads are not audited and return an empty operation.
+func auditOp(r *http.Request) (op, key string, write bool) {
+	q := r.URL.Query()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/key"):
+		switch r.Method {
+		case http.MethodPost:
+			return common.SET, "", true
+		case http.MethodDelete:
+			return common.DEL, strings.TrimPrefix(r.URL.Path, "/key/"), true
+		}
+	case strings.HasPrefix(r.URL.Path, "/transaction") && q.Get("readonly") != "true":
+		return common.TXN, "", true
+	case strings.HasPrefix(r.URL.Path, "/txn/") && strings.HasSuffix(r.URL.Path, "/commit"):
+		return common.TXN, "", true
+	case r.URL.Path == "/compare":
+		return common.COMPARE, "", true
+	case r.URL.Path == "/join":
+		return "join", "", false
+	case r.URL.Path == "/import":
+		return "import", "", false
+	case r.URL.Path == "/bulk":
+		return "bulk", "", false
+	case r.URL.Path == "/export":
+		return "export", q.Get("prefix"), false
+	case r.URL.Path == "/migrate":
+		return "migrate", q.Get("from") + " -> " + q.Get("to"), false
+	case r.URL.Path == "/admin/shards" && r.Method == http.MethodPost:
+		return "replicas", "shard " + q.Get("shard") + " = " + q.Get("replicas"), false
+	case r.URL.Path == "/admin/roles" && r.Method != http.MethodGet:
+		return "roles", q.Get("role") + q.Get("subject"), false
+	case r.URL.Path == "/admin/transforms" && r.Method != http.MethodGet:
+		return "transforms", q.Get("prefix"), false
+	case r.URL.Path == "/admin/ttl" && r.Method != http.MethodGet:
+		return "ttl", q.Get("prefix"), false
+	case r.URL.Path == "/admin/undelete":
+		return common.UNDELETE, q.Get("key"), false
+	case r.URL.Path == "/admin/members":
+		return "members", "shard " + q.Get("shard") + " +" + q.Get("promote") + " -" + q.Get("remove"), false
+	case r.URL.Path == "/admin/membership" && q.Get("dry_run") != "true":
+		return "membership", "", false
+	case r.URL.Path == "/admin/topology" && r.Method != http.MethodGet:
+		return "topology", "", false
+	case r.URL.Path == "/admin/keyprofile" && r.Method != http.MethodGet:
+		return "keyprofile", q.Get("duration"), false
+	}
+	return "", "", false
+}
+
+// who identifies the author of r: its authenticated identity if any, the
+// basic auth user if any, the remote host otherwise.
+func who(r *http.Request) string {
+	if id := common.IdentityFrom(r.Context()); id != nil {
+		return id.Name
+	}
+	if user, _, ok := r.BasicAuth(); ok {
+		return user
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// serveAudited serves r with next and records it in the audit log when its
+// operation is audited.
+func (s *Service) serveAudited(w http.ResponseWriter, r *http.Request, next func(http.ResponseWriter, *http.Request)) {
+	op, key, write := auditOp(r)
+	if op == "" || !s.audit.Enabled(write) {
+		next(w, r)
+		return
+	}
+	rec := &auditRecorder{ResponseWriter: w}
+	next(rec, r)
+	if len(rec.keys) > 0 {
+		key = strings.Join(rec.keys, ",")
+	}
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	err := s.audit.Record(&common.AuditRecord{
+		Time:   time.Now().UTC(),
+		Who:    who(r),
+		Op:     op,
+		Key:    key,
+		Txid:   w.Header().Get(TxidHeader),
+		Status: rec.status,
+	})
+	if err != nil {
+		s.log.Errorf("unable to write audit record: %s", err)
+	}
+}
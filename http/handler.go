@@ -1,46 +1,395 @@
 package http
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/coordinator"
 	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
 )
 
+// CodecHeader carries the codec of a blob value in GET responses.
+const CodecHeader = "X-Codec"
+
+// SessionHeader and SeqHeader identify a DELETE of a client session, and
+// PriorityHeader sets its priority: low, normal or high. Writes with a body
+// carry them in the command instead.
+const (
+	SessionHeader  = "X-Session"
+	SeqHeader      = "X-Seq"
+	PriorityHeader = "X-Priority"
+)
+
+// Headers carrying the key metadata in GET responses with ?metadata=true.
+const (
+	CreateRevisionHeader = "X-Create-Revision"
+	ModRevisionHeader    = "X-Mod-Revision"
+	VersionHeader        = "X-Version"
+	SizeHeader           = "X-Size"
+)
+
+// Headers telling how a GET was served: by which node of which shard, at
+// which applied index and term, and with which consistency check.
+const (
+	ServedByHeader     = "X-Served-By"
+	ShardHeader        = "X-Shard"
+	AppliedIndexHeader = "X-Applied-Index"
+	AppliedTermHeader  = "X-Applied-Term"
+	ConsistencyHeader  = "X-Consistency"
+)
+
+// ClusterHeader carries the --cluster-id of the coordinator on every
+// response, and on requests the cluster they are meant for: requests for
+// another cluster are refused with 412 Precondition Failed.
+const ClusterHeader = "X-Cluster-Id"
+
+// RevisionHeader carries the revision token of the writes, the revisions
+// they were applied at by shard, and of the reads, the revision applied by
+// the shard node serving them. Reads given a token with ?min_revision= wait
+// until the node serving them has applied its revision of their shard.
+const RevisionHeader = "X-Revision"
+
+// ProtobufContentType is the media type of the protobuf bodies. The bodies
+// of the writes and transactions are always raftpb messages, and GET /key and
+// the writes with ?return=old reply with a raftpb.RPCResponse rather than
+// text to the requests accepting it. The failed requests accepting it reply
+// with a raftpb.Error.
+const ProtobufContentType = "application/protobuf"
+
+// acceptsProtobuf reports whether r accepts protobuf replies.
+func acceptsProtobuf(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), ProtobufContentType)
+}
+
+// writeProtobuf replies with status and m as body.
+func writeProtobuf(w http.ResponseWriter, status int, m proto.Message) {
+	b, err := proto.Marshal(m)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, fmt.Sprintf("Unable to marshal: %s", err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", ProtobufContentType)
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// protobufErrors replies to the failed requests of the clients accepting
+// protobuf with a raftpb.Error rather than text. The text of the failure is
+// kept until the handler returns, when finish replies.
+type protobufErrors struct {
+	http.ResponseWriter
+	status int
+	text   bytes.Buffer
+}
+
+func (p *protobufErrors) WriteHeader(status int) {
+	if p.status != 0 {
+		return
+	}
+	p.status = status
+	if status < http.StatusBadRequest {
+		p.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (p *protobufErrors) Write(b []byte) (int, error) {
+	if p.status == 0 {
+		p.WriteHeader(http.StatusOK)
+	}
+	if p.status >= http.StatusBadRequest {
+		return p.text.Write(b)
+	}
+	return p.ResponseWriter.Write(b)
+}
+
+// Flush sends the buffered data of the response, for the streamed replies.
+func (p *protobufErrors) Flush() {
+	if f, ok := p.ResponseWriter.(http.Flusher); ok && p.status < http.StatusBadRequest {
+		f.Flush()
+	}
+}
+
+// finish replies with the Error of a failed request.
+func (p *protobufErrors) finish() {
+	if p.status < http.StatusBadRequest {
+		return
+	}
+	h := p.Header()
+	e := &raftpb.Error{
+		Code:           int32(p.status),
+		Message:        strings.TrimSpace(p.text.String()),
+		ConflictKey:    h.Get(ConflictKeyHeader),
+		ConflictReason: h.Get(ConflictReasonHeader),
+		ConflictTxid:   h.Get(ConflictTxidHeader),
+		LockNode:       h.Get(LockNodeHeader),
+	}
+	if p.status == http.StatusMisdirectedRequest {
+		e.Leader = e.Message
+	}
+	if d, err := time.ParseDuration(h.Get(RetryBackoffHeader)); err == nil {
+		e.RetryBackoffMs = int64(d / time.Millisecond)
+	}
+	if d, err := time.ParseDuration(h.Get(LockHeldForHeader)); err == nil {
+		e.LockHeldForMs = int64(d / time.Millisecond)
+	}
+	writeProtobuf(p.ResponseWriter, p.status, e)
+}
+
+// valueReply returns the value, metadata and revision of resp, the reply of
+// a shard, as a protobuf reply.
+func valueReply(resp *raftpb.RPCResponse) *raftpb.RPCResponse {
+	return &raftpb.RPCResponse{
+		Value:    resp.Value,
+		Blob:     resp.Blob,
+		Codec:    resp.Codec,
+		Meta:     resp.Meta,
+		Revision: resp.Revision,
+		Read:     resp.Read,
+	}
+}
+
+// Headers describing the conflict of a transaction, or the key lock of a
+// single read or write, failed with 409.
+const (
+	ConflictKeyHeader    = "X-Conflict-Key"
+	ConflictReasonHeader = "X-Conflict-Reason"
+	ConflictTxidHeader   = "X-Conflict-Txid"
+	RetryBackoffHeader   = "X-Retry-Backoff"
+	LockNodeHeader       = "X-Lock-Node"
+	LockHeldForHeader    = "X-Lock-Held-For"
+)
+
+// setConflictHeaders describes the conflict or the lock error of err, if
+// any, in the headers of w.
+func setConflictHeaders(w http.ResponseWriter, err error) {
+	conflict := common.ParseConflict(err)
+	if conflict == nil {
+		setLockHeaders(w, err)
+		return
+	}
+	w.Header().Set(ConflictKeyHeader, conflict.Key)
+	w.Header().Set(ConflictReasonHeader, conflict.Reason)
+	if conflict.Holder != "" {
+		w.Header().Set(ConflictTxidHeader, conflict.Holder)
+	}
+	w.Header().Set(RetryBackoffHeader, conflict.Backoff.String())
+}
+
+// setLockHeaders describes the holder of the key lock of err, if err is a
+// lock error, in the headers of w.
+func setLockHeaders(w http.ResponseWriter, err error) {
+	var lock *common.LockError
+	if !errors.As(err, &lock) {
+		return
+	}
+	w.Header().Set(ConflictKeyHeader, lock.Key)
+	w.Header().Set(ConflictReasonHeader, common.ConflictLocked)
+	if lock.Holder != "" {
+		w.Header().Set(ConflictTxidHeader, lock.Holder)
+	}
+	if lock.Node != "" {
+		w.Header().Set(LockNodeHeader, lock.Node)
+	}
+	if lock.HeldFor > 0 {
+		w.Header().Set(LockHeldForHeader, lock.HeldFor.String())
+	}
+	if lock.RetryAfter > 0 {
+		w.Header().Set(RetryBackoffHeader, lock.RetryAfter.String())
+	}
+}
+
+// setMetaHeaders writes meta to the headers of w.
+func setMetaHeaders(w http.ResponseWriter, meta *raftpb.KeyMeta) {
+	w.Header().Set(CreateRevisionHeader, strconv.FormatInt(meta.GetCreateRevision(), 10))
+	w.Header().Set(ModRevisionHeader, strconv.FormatInt(meta.GetModRevision(), 10))
+	w.Header().Set(VersionHeader, strconv.FormatInt(meta.GetVersion(), 10))
+	w.Header().Set(SizeHeader, strconv.FormatInt(meta.GetSize(), 10))
+}
+
+// setReadHeaders writes how a read was served to the headers of w.
+func setReadHeaders(w http.ResponseWriter, info *raftpb.ReadInfo) {
+	if info == nil {
+		return
+	}
+	w.Header().Set(ServedByHeader, fmt.Sprintf("%s (%s)", info.Node, info.State))
+	w.Header().Set(ShardHeader, strconv.FormatInt(info.Shard, 10))
+	w.Header().Set(AppliedIndexHeader, strconv.FormatInt(info.AppliedIndex, 10))
+	w.Header().Set(AppliedTermHeader, strconv.FormatInt(info.AppliedTerm, 10))
+	w.Header().Set(ConsistencyHeader, info.Consistency)
+}
+
+// setRevisionHeader writes the revision rev of the shard of key to the
+// headers of w.
+func (s *Service) setRevisionHeader(w http.ResponseWriter, key string, rev int64) {
+	if rev > 0 {
+		w.Header().Set(RevisionHeader, common.RevisionToken{s.coordinator.GetShardID(key): rev}.String())
+	}
+}
+
+// minRevision returns the revision of the shard of key given by the
+// min_revision query parameter of r, a revision token or a revision of the
+// shard, 0 if not set.
+func (s *Service) minRevision(r *http.Request, key string) (int64, error) {
+	q := r.URL.Query().Get("min_revision")
+	if q == "" {
+		return 0, nil
+	}
+	if rev, err := strconv.ParseInt(q, 10, 64); err == nil && rev >= 0 {
+		return rev, nil
+	}
+	token, err := common.ParseRevisionToken(q)
+	if err != nil {
+		return 0, err
+	}
+	return token[s.coordinator.GetShardID(key)], nil
+}
+
+// clientOf returns who sent r, among whom the shard leaders share their
+// proposals fairly: its authenticated identity, else its host.
+func clientOf(r *http.Request) string {
+	if id := common.IdentityFrom(r.Context()); id != nil {
+		return id.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// setClient sets the client of cmds, replacing the one they may carry.
+func setClient(cmds []*raftpb.Command, client string) {
+	for _, cmd := range cmds {
+		cmd.Client = client
+	}
+}
+
+// errorStatus returns the status code of a request failed with err.
+func errorStatus(err error) int {
+	if errors.Is(err, coordinator.ErrQuotaExceeded) {
+		return http.StatusTooManyRequests
+	}
+	if errors.Is(err, common.ErrReservedKey) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, common.ErrInvalidKey) || errors.Is(err, common.ErrInvalidTTL) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, common.ErrTxnTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	if errors.Is(err, common.ErrOutcomeUnknown) {
+		return http.StatusGatewayTimeout
+	}
+	if strings.Contains(err.Error(), common.ErrRevisionNotApplied.Error()) {
+		return http.StatusServiceUnavailable
+	}
+	if common.IsOverloaded(err) {
+		return http.StatusServiceUnavailable
+	}
+	var lock *common.LockError
+	if common.ParseConflict(err) != nil || errors.As(err, &lock) {
+		return http.StatusConflict
+	}
+	if common.IsUnknownSession(err) || errors.Is(err, coordinator.ErrUnknownTransaction) || common.IsCompacted(err) {
+		return http.StatusGone
+	}
+	return http.StatusInternalServerError
+}
+
 func (s *Service) handleJoin(w http.ResponseWriter, r *http.Request) {
 
 	msg, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		s.log.Fatal("Error when reading join data", err)
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("failed to read join data: %s", err))
+		return
 	}
 
 	var joinMsg raftpb.JoinMsg
 	if err = proto.Unmarshal(msg, &joinMsg); err != nil {
-		s.log.Fatal("Error when unmarshal join data", err)
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("failed to parse join data: %s", err))
+		return
 	}
 
 	if joinMsg.RaftAddress == "" || joinMsg.ID == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if err := common.VerifyJoin(common.ClusterSecret, &joinMsg); err != nil {
+		s.log.Warnf("refusing join: %s", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, err.Error())
+		return
+	}
 
-	if err := s.coordinator.Join(joinMsg.ID, joinMsg.RaftAddress); err != nil {
+	clusterID, err := s.coordinator.CheckJoin(joinMsg.ID, joinMsg.ClusterID)
+	if err != nil {
+		s.log.Errorf("refusing join: %s", err)
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, err.Error())
+		return
+	}
+	// the join is signed, checked above, or authenticated
+	authenticated := common.ClusterSecret != "" || common.IdentityFrom(r.Context()) != nil
+	verified := common.JoinVerified(clusterID, joinMsg.ClusterID, authenticated)
+	if err := s.coordinator.Join(joinMsg.ID, joinMsg.RaftAddress, joinMsg.Version, verified); err != nil {
 		s.log.Error(err)
+		if errors.Is(err, common.ErrUnverifiedMove) {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, err.Error())
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
+	io.WriteString(w, clusterID)
+}
 
+// writeOldValue writes the value a key had before a write, as GET does with
+// its metadata headers, or 204 No Content if the key did not exist.
+func writeOldValue(w http.ResponseWriter, r *http.Request, key string, resp *raftpb.RPCResponse) {
+	if resp.Meta == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	setMetaHeaders(w, resp.Meta)
+	if acceptsProtobuf(r) {
+		writeProtobuf(w, http.StatusOK, valueReply(resp))
+		return
+	}
+	if resp.Codec != "" {
+		w.Header().Set(CodecHeader, resp.Codec)
+		w.WriteHeader(http.StatusOK)
+		w.Write(resp.Blob)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, fmt.Sprintf("Key=%s, Value=%d", key, resp.Value))
 }
 
 func (s *Service) handleKeyRequest(w http.ResponseWriter, r *http.Request) {
 
 	var msg string
-	if !s.coordinator.IsLeader() {
+	// Reads are served by any coordinator, they go straight to the shard leader
+	// and do not touch the coordinator state machine.
+	if r.Method != http.MethodGet && !s.coordinator.IsLeader() {
 		leader, err := s.coordinator.FindClusterLeader()
 		if err != nil {
 			msg = "No leader found"
@@ -54,6 +403,9 @@ func (s *Service) handleKeyRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// writes with ?return=old reply with the value the key had before
+	returnOld := r.URL.Query().Get("return") == "old"
+
 	getKey := func(path string) string {
 		parts := strings.SplitN(path, "/", 3)
 		if len(parts) != 3 {
@@ -68,13 +420,48 @@ func (s *Service) handleKeyRequest(w http.ResponseWriter, r *http.Request) {
 		if key == "" {
 			w.WriteHeader(http.StatusBadRequest)
 		}
-		val, err := s.coordinator.Get(key)
+		withMeta := r.URL.Query().Get("metadata") == "true"
+		var rev int64
+		if q := r.URL.Query().Get("rev"); q != "" {
+			var err error
+			if rev, err = strconv.ParseInt(q, 10, 64); err != nil || rev <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, fmt.Sprintf("invalid revision %s", q))
+				return
+			}
+		}
+		minRev, err := s.minRevision(r, key)
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, err.Error())
+			return
+		}
+		resp, err := s.coordinator.GetRevisionAfter(key, rev, minRev)
+		if err == nil && withMeta {
+			setMetaHeaders(w, resp.Meta)
+		}
+		if err == nil {
+			s.setRevisionHeader(w, key, resp.Revision)
+			setReadHeaders(w, resp.Read)
+		}
+		if err != nil {
+			setConflictHeaders(w, err)
+			w.WriteHeader(errorStatus(err))
 			msg = err.Error()
+		} else if acceptsProtobuf(r) {
+			writeProtobuf(w, http.StatusOK, valueReply(resp))
+		} else if resp.Codec != "" {
+			// blobs are returned as is, the codec tells the client how to decode them
+			w.Header().Set(CodecHeader, resp.Codec)
+			w.WriteHeader(http.StatusOK)
+			w.Write(resp.Blob)
 		} else {
 			w.WriteHeader(http.StatusOK)
-			msg = fmt.Sprintf("Key=%s, Value=%d", key, val)
+			msg = fmt.Sprintf("Key=%s, Value=%d", key, resp.Value)
+			if withMeta {
+				msg += fmt.Sprintf(", CreateRevision=%d, ModRevision=%d, Version=%d, Size=%d",
+					resp.Meta.GetCreateRevision(), resp.Meta.GetModRevision(), resp.Meta.GetVersion(), resp.Meta.GetSize())
+			}
 		}
 		io.WriteString(w, msg)
 
@@ -86,23 +473,72 @@ func (s *Service) handleKeyRequest(w http.ResponseWriter, r *http.Request) {
 		} else if err = proto.Unmarshal(m, cmd); err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			msg = fmt.Sprintf("failed to parse %v", r.Body)
-		} else if err := s.coordinator.Set(cmd.Key, cmd.Value); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+		} else if cmd.Client = clientOf(r); len(cmd.Codec) > common.MaxCodecLen {
+			w.WriteHeader(http.StatusBadRequest)
+			msg = fmt.Sprintf("codec name longer than %d bytes", common.MaxCodecLen)
+		} else if r.URL.Query().Get("if") == "absent" {
+			if ok, rev, err := s.coordinator.SetIfAbsent(cmd); err != nil {
+				setConflictHeaders(w, err)
+				w.WriteHeader(errorStatus(err))
+				msg = fmt.Sprintf("Unable to set: %s", err.Error())
+			} else if !ok {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				msg = fmt.Sprintf("Key=%s already exists", cmd.Key)
+			} else {
+				s.setRevisionHeader(w, cmd.Key, rev)
+				w.WriteHeader(http.StatusOK)
+			}
+		} else if returnOld {
+			if resp, err := s.coordinator.GetSetCommand(cmd); err != nil {
+				setConflictHeaders(w, err)
+				w.WriteHeader(errorStatus(err))
+				msg = fmt.Sprintf("Unable to set: %s", err.Error())
+			} else {
+				s.setRevisionHeader(w, cmd.Key, resp.Revision)
+				writeOldValue(w, r, cmd.Key, resp)
+			}
+		} else if rev, err := s.coordinator.SetCommand(cmd); err != nil {
+			setConflictHeaders(w, err)
+			w.WriteHeader(errorStatus(err))
 			msg = fmt.Sprintf("Unable to set: %s", err.Error())
 		} else {
+			s.setRevisionHeader(w, cmd.Key, rev)
 			w.WriteHeader(http.StatusOK)
 		}
+		setAuditKeys(w, cmd.Key)
 		io.WriteString(w, msg)
 
 	case http.MethodDelete:
-		key := getKey(r.URL.Path)
-		if key == "" {
+		cmd := &raftpb.Command{Key: getKey(r.URL.Path), Session: r.Header.Get(SessionHeader), Client: clientOf(r)}
+		var seqErr, priorityErr error
+		if cmd.Session != "" {
+			cmd.Seq, seqErr = strconv.ParseInt(r.Header.Get(SeqHeader), 10, 64)
+		}
+		cmd.Priority, priorityErr = common.ParsePriority(r.Header.Get(PriorityHeader))
+		if cmd.Key == "" {
 			w.WriteHeader(http.StatusBadRequest)
 			msg = "key is missing"
-		} else if err := s.coordinator.Delete(key); err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+		} else if seqErr != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			msg = fmt.Sprintf("invalid %s %q", SeqHeader, r.Header.Get(SeqHeader))
+		} else if priorityErr != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			msg = priorityErr.Error()
+		} else if returnOld {
+			if resp, err := s.coordinator.GetDelCommand(cmd); err != nil {
+				setConflictHeaders(w, err)
+				w.WriteHeader(errorStatus(err))
+				msg = err.Error()
+			} else {
+				s.setRevisionHeader(w, cmd.Key, resp.Revision)
+				writeOldValue(w, r, cmd.Key, resp)
+			}
+		} else if rev, err := s.coordinator.DeleteCommand(cmd); err != nil {
+			setConflictHeaders(w, err)
+			w.WriteHeader(errorStatus(err))
 			msg = err.Error()
 		} else {
+			s.setRevisionHeader(w, cmd.Key, rev)
 			w.WriteHeader(http.StatusOK)
 		}
 		io.WriteString(w, msg)
@@ -114,6 +550,283 @@ func (s *Service) handleKeyRequest(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleHistory returns the retained revisions of a key, newest first, as a
+// protobuf encoded RaftCommand. Like reads, it is served by any coordinator.
+func (s *Service) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/history/")
+	if key == "" || key == r.URL.Path {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "key is missing")
+		return
+	}
+	var limit int64
+	if q := r.URL.Query().Get("limit"); q != "" {
+		var err error
+		if limit, err = strconv.ParseInt(q, 10, 64); err != nil || limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid limit %s", q))
+			return
+		}
+	}
+
+	cmds, err := s.coordinator.History(key, limit)
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := proto.Marshal(&raftpb.RaftCommand{Commands: cmds})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, fmt.Sprintf("Unable to marshal: %s", err.Error()))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleImport bulk loads the dump in the request body, in the format given
+// by the format query parameter (jsonl or csv).
+func (s *Service) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = common.FormatJSONL
+	}
+	n, err := s.coordinator.Import(r.Body, format)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		msg := fmt.Sprintf("Unable to import: %s", err.Error())
+		s.log.Info(msg)
+		io.WriteString(w, msg)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, fmt.Sprintf("Imported=%d", n))
+}
+
+// handleBulk applies the writes of the request body, a stream of raftpb
+// Commands framed by common.WriteMessage, and replies with the stream of
+// their results, as raftpb.WriteResults framed the same way. HTTP/2 requests
+// get the results as the writes are applied, HTTP/1 ones once the request
+// body is read: the server cannot read it any more once the reply started. A
+// stream cut short, or that fails to parse, ends with a result without key
+// carrying the error.
+func (s *Service) handleBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	body := bufio.NewReader(r.Body)
+	client := clientOf(r)
+	next := func() (*raftpb.Command, error) {
+		cmd := &raftpb.Command{}
+		if err := common.ReadMessage(body, cmd); err != nil {
+			return cmd, err
+		}
+		cmd.Client = client
+		return cmd, nil
+	}
+	flusher, ok := w.(http.Flusher)
+	streamed := ok && r.ProtoMajor >= 2
+	var pending bytes.Buffer
+	out := io.Writer(&pending)
+	if streamed {
+		out = w
+		w.Header().Set("Content-Type", ProtobufContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+	emit := func(results []*raftpb.WriteResult) error {
+		for _, res := range results {
+			if err := common.WriteMessage(out, res); err != nil {
+				return err
+			}
+		}
+		if streamed {
+			flusher.Flush()
+		}
+		return nil
+	}
+	n, err := s.coordinator.Bulk(next, emit)
+	if err != nil {
+		s.log.Infof("bulk write stream ended after %d writes: %s", n, err)
+		emit([]*raftpb.WriteResult{{Index: n, Error: err.Error()}})
+	}
+	if !streamed {
+		w.Header().Set("Content-Type", ProtobufContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(pending.Bytes())
+	}
+}
+
+// handleMigrate copies, or moves with move=true, the keys starting with the
+// from query parameter to keys starting with to, reading them from the
+// coordinator at source if set. rate limits the keys written per second.
+func (s *Service) handleMigrate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	q := r.URL.Query()
+	opts := coordinator.MigrateOptions{
+		From:   q.Get("from"),
+		To:     q.Get("to"),
+		Source: q.Get("source"),
+		Move:   q.Get("move") == "true",
+	}
+	if rate := q.Get("rate"); rate != "" {
+		n, err := strconv.Atoi(rate)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid rate %q", rate))
+			return
+		}
+		opts.Rate = n
+	}
+	n, err := s.coordinator.Migrate(opts)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		msg := fmt.Sprintf("Unable to migrate: %s", err.Error())
+		s.log.Info(msg)
+		io.WriteString(w, msg)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, fmt.Sprintf("Migrated=%d", n))
+}
+
+// handleExport streams the keys starting with the prefix query parameter in
+// the format given by the format query parameter (jsonl or csv). Exports are
+// served by the leader, which holds transactions off while reading the shards.
+func (s *Service) handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = common.FormatJSONL
+	}
+	if q := r.URL.Query().Get("limit"); q != "" {
+		s.handleExportPage(w, r, format, q)
+		return
+	}
+	dump, err := common.NewDumpWriter(w, format)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+
+	started := false
+	err = s.coordinator.Export(r.URL.Query().Get("prefix"), func(e *raftpb.KVEntry) error {
+		if !started {
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		return dump.Write(e)
+	})
+	if err != nil && !started {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, fmt.Sprintf("Unable to export: %s", err.Error()))
+		return
+	} else if err != nil {
+		// the status is already sent, the client sees a truncated dump
+		s.log.Errorf("export interrupted: %s", err)
+		return
+	}
+	if err := dump.Flush(); err != nil {
+		s.log.Errorf("export interrupted: %s", err)
+	}
+}
+
+// NextCursorHeader carries the cursor of the next page of a paginated
+// export, missing after the last page.
+const NextCursorHeader = "X-Next-Cursor"
+
+// handleExportPage writes a page of at most limit keys of an export, from
+// the cursor query parameter or the first key, with the cursor of the next
+// page in the NextCursorHeader. Pages whose cursor is compacted fail with
+// 410 Gone: the export must start over.
+func (s *Service) handleExportPage(w http.ResponseWriter, r *http.Request, format, limit string) {
+	n, err := strconv.Atoi(limit)
+	if err != nil || n <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("invalid limit %s", limit))
+		return
+	}
+	var cursor *common.ExportCursor
+	if q := r.URL.Query().Get("cursor"); q != "" {
+		if cursor, err = common.ParseExportCursor(q); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, err.Error())
+			return
+		}
+	}
+	// the next cursor is known once the page is read
+	var page bytes.Buffer
+	dump, err := common.NewDumpWriter(&page, format)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	next, err := s.coordinator.ExportPage(r.URL.Query().Get("prefix"), cursor, n, dump.Write)
+	if err == nil {
+		err = dump.Flush()
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		io.WriteString(w, fmt.Sprintf("Unable to export: %s", err.Error()))
+		return
+	}
+	if next != nil {
+		w.Header().Set(NextCursorHeader, next.String())
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
+}
+
 // TODO: No raft leader api exposed in coordinator
 // // handleLeader mainly used for debugs.
 // func (s *Service) handleLeader(w http.ResponseWriter, r *http.Request) {
@@ -150,8 +863,9 @@ func (s *Service) handleTransaction(w http.ResponseWriter, r *http.Request) {
 	} else if err = proto.Unmarshal(m, cmds); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		msg = fmt.Sprintf("failed to parse %v", r.Body)
-	} else if resultCmds, err := s.coordinator.Transaction(cmds); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	} else if resultCmds, err := s.transaction(w, cmds, clientOf(r), r.URL.Query().Get("readonly") == "true"); err != nil {
+		setConflictHeaders(w, err)
+		w.WriteHeader(errorStatus(err))
 		msg = fmt.Sprintf("Unable to txn: %s", err.Error())
 	} else if respBody, err := proto.Marshal(resultCmds); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -166,6 +880,782 @@ func (s *Service) handleTransaction(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleSlowLog writes the recent slow operations of the cluster as json,
+// most recent first, up to the limit query parameter if set.
+func (s *Service) handleSlowLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ops := s.coordinator.SlowLog()
+	if q := r.URL.Query().Get("limit"); q != "" {
+		limit, err := strconv.Atoi(q)
+		if err != nil || limit < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid limit %s", q))
+			return
+		}
+		if limit < len(ops) {
+			ops = ops[:limit]
+		}
+	}
+	b, err := json.Marshal(ops)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleShardMap writes the routing table of the shards as json.
+func (s *Service) handleShardMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := json.Marshal(s.coordinator.ShardMap())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleCompact snapshots the raft groups of the nodes of the shard query
+// parameter, of every shard if not set, and writes the nodes compacted as
+// json.
+func (s *Service) handleCompact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	shardID := int64(-1)
+	if v := r.URL.Query().Get("shard"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if _, ok := s.coordinator.ShardToPeers[id]; err != nil || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid shard %q", v))
+			return
+		}
+		shardID = id
+	}
+	nodes, err := s.coordinator.Compact(shardID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(nodes)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleStats writes the statistics of the keys of the shard query
+// parameter, or of every shard, as json.
+func (s *Service) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	shardID := int64(-1)
+	if v := r.URL.Query().Get("shard"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if _, ok := s.coordinator.ShardToPeers[id]; err != nil || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid shard %q", v))
+			return
+		}
+		shardID = id
+	}
+	b, err := json.Marshal(s.coordinator.Stats(shardID))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleVerifyState has the replicas of the shard query parameter, or of
+// every shard, hash their keys at the same index, and writes the comparison
+// of their hashes as json, with 409 Conflict if any diverge.
+func (s *Service) handleVerifyState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	shardID := int64(-1)
+	if v := r.URL.Query().Get("shard"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if _, ok := s.coordinator.ShardToPeers[id]; err != nil || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid shard %q", v))
+			return
+		}
+		shardID = id
+	}
+	reports := s.coordinator.VerifyState(shardID)
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Shard < reports[j].Shard })
+	status := http.StatusOK
+	for _, report := range reports {
+		if len(report.Divergences) > 0 {
+			status = http.StatusConflict
+		}
+	}
+	b, err := json.Marshal(reports)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// handleRepair has the replica of the node query parameter, of the shard
+// query parameter, repair the keys it holds differently from its leader, and
+// writes the outcome as json.
+func (s *Service) handleRepair(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	v, node := r.URL.Query().Get("shard"), r.URL.Query().Get("node")
+	shardID, err := strconv.ParseInt(v, 10, 64)
+	peers, ok := s.coordinator.ShardToPeers[shardID]
+	if err != nil || !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("invalid shard %q", v))
+		return
+	}
+	found := false
+	for _, addr := range peers {
+		found = found || addr == node
+	}
+	if !found {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("%q is not a replica of shard %d", node, shardID))
+		return
+	}
+	report, err := s.coordinator.Repair(shardID, node)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleNodes writes the store nodes with their liveness and metadata as
+// json.
+func (s *Service) handleNodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := json.Marshal(s.coordinator.Members())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleLocks writes the keys locked by transactions on every shard node as
+// json, oldest lock first, optionally only those of the key query parameter.
+func (s *Service) handleLocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	locks := s.coordinator.Locks()
+	if key := r.URL.Query().Get("key"); key != "" {
+		var matching []*raftpb.KeyLock
+		for _, l := range locks {
+			if l.Key == key {
+				matching = append(matching, l)
+			}
+		}
+		locks = matching
+	}
+	b, err := json.Marshal(locks)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleShards writes the replication factor and the raft members of every
+// shard as json. POST sets the replication factor of the shard query
+// parameter to the replicas query parameter.
+func (s *Service) handleShards(w http.ResponseWriter, r *http.Request) {
+	var res interface{}
+	switch r.Method {
+	case http.MethodGet:
+		res = s.coordinator.Placement()
+	case http.MethodPost:
+		if !s.coordinator.IsLeader() {
+			leader, err := s.coordinator.FindClusterLeader()
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, "No leader found")
+			} else {
+				w.WriteHeader(http.StatusMisdirectedRequest)
+				io.WriteString(w, leader)
+			}
+			return
+		}
+		q := r.URL.Query()
+		shardID, err := strconv.ParseInt(q.Get("shard"), 10, 64)
+		if _, ok := s.coordinator.ShardToPeers[shardID]; err != nil || !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid shard %q", q.Get("shard")))
+			return
+		}
+		n, err := strconv.ParseInt(q.Get("replicas"), 10, 32)
+		if err != nil || n < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("invalid replicas %q", q.Get("replicas")))
+			return
+		}
+		p, err := s.coordinator.SetReplicas(shardID, int32(n))
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, err.Error())
+			return
+		}
+		res = p
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := json.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleMembers promotes the comma separated non-voters of the promote query
+// parameter and then removes the members of the remove query parameter from
+// the raft groups of the shard query parameter, and writes their members as
+// json.
+func (s *Service) handleMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	q := r.URL.Query()
+	shardID, err := strconv.ParseInt(q.Get("shard"), 10, 64)
+	if _, ok := s.coordinator.ShardToPeers[shardID]; err != nil || !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("invalid shard %q", q.Get("shard")))
+		return
+	}
+	split := func(v string) []string {
+		if v == "" {
+			return nil
+		}
+		return strings.Split(v, ",")
+	}
+	p, err := s.coordinator.ChangeMembers(shardID, split(q.Get("promote")), split(q.Get("remove")))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleMembership applies the json membership request of the body, or only
+// plans it with the dry_run query parameter, and writes the report as json.
+// Repeating a request is harmless: the steps already in place are skipped.
+func (s *Service) handleMembership(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	var req coordinator.MembershipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("invalid membership request: %s", err))
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	report, err := s.coordinator.ChangeMembership(&req, dryRun)
+	if err != nil {
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(report)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleKeyProfile writes the load of the key prefixes sampled by the key
+// profile of this coordinator as json: the running profile or the last one on
+// GET, a new one on POST, started for ?duration=, 1m by default, sampling the
+// fraction ?sample= of the requests, 0.1 by default, by the first ?depth=
+// segments of the keys, 1 by default, and the stopped one on DELETE.
+func (s *Service) handleKeyProfile(w http.ResponseWriter, r *http.Request) {
+	profile := s.coordinator.KeyProfile()
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		q := r.URL.Query()
+		window, rate, depth := time.Minute, 0.1, 1
+		if v := q.Get("duration"); v != "" {
+			window, err = time.ParseDuration(v)
+		}
+		if v := q.Get("sample"); v != "" && err == nil {
+			rate, err = strconv.ParseFloat(v, 64)
+		}
+		if v := q.Get("depth"); v != "" && err == nil {
+			depth, err = strconv.Atoi(v)
+		}
+		if err == nil {
+			err = profile.Start(window, rate, depth)
+		}
+	case http.MethodDelete:
+		profile.Stop()
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(profile.Report())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleTransforms serves /admin/transforms: GET lists the transforms of the
+// values by prefix, PUT ?prefix=<prefix> sets the transforms of a prefix from
+// the JSON body, and DELETE deletes them.
+func (s *Service) handleTransforms(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if r.Method != http.MethodGet && !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	var err error
+	switch {
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPut && prefix != "":
+		var specs []common.TransformSpec
+		if err = json.NewDecoder(r.Body).Decode(&specs); err == nil {
+			err = s.coordinator.SetTransforms(prefix, specs)
+		}
+	case r.Method == http.MethodDelete && prefix != "":
+		err = s.coordinator.SetTransforms(prefix, nil)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "expected GET, or PUT or DELETE with a prefix")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(s.coordinator.Transforms())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleTTL serves /admin/ttl: GET lists the ttl policies of the keys by
+// prefix, PUT ?prefix=<prefix> sets the policy of a prefix from the JSON body,
+// and DELETE deletes it.
+func (s *Service) handleTTL(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if r.Method != http.MethodGet && !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	var err error
+	switch {
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPut && prefix != "":
+		var p common.TTLPolicy
+		if err = json.NewDecoder(r.Body).Decode(&p); err == nil {
+			err = s.coordinator.SetTTLPolicy(prefix, &p)
+		}
+	case r.Method == http.MethodDelete && prefix != "":
+		err = s.coordinator.SetTTLPolicy(prefix, nil)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "expected GET, or PUT or DELETE with a prefix")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(s.coordinator.TTLPolicies())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleUndelete serves POST /admin/undelete?key=<key>, restoring a key soft
+// deleted from its tombstone, or replying 404 Not Found if it has none or
+// exists again.
+func (s *Service) handleUndelete(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if r.Method != http.MethodPost || key == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "expected POST with a key")
+		return
+	}
+	ok, rev, err := s.coordinator.Undelete(key)
+	if err != nil {
+		setConflictHeaders(w, err)
+		w.WriteHeader(errorStatus(err))
+		io.WriteString(w, fmt.Sprintf("Unable to undelete: %s", err.Error()))
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, fmt.Sprintf("No tombstone of Key=%s, or the key exists", key))
+		return
+	}
+	s.setRevisionHeader(w, key, rev)
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleTopology writes the topology and the drift of the shards from it as
+// json on GET, after replacing it with the json body on PUT or clearing it
+// on DELETE.
+func (s *Service) handleTopology(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	status := s.coordinator.Topology()
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var b []byte
+		if b, err = ioutil.ReadAll(r.Body); err == nil {
+			if len(bytes.TrimSpace(b)) == 0 {
+				err = fmt.Errorf("empty topology, DELETE it instead")
+			} else {
+				status, err = s.coordinator.SetTopology(b)
+			}
+		}
+	case http.MethodDelete:
+		status, err = s.coordinator.SetTopology(nil)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(status)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+// handleSession opens a client session on POST /session and writes its id,
+// and closes the session of the path on DELETE /session/<id>. Sessions live
+// on the shards, any coordinator serves them.
+func (s *Service) handleSession(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		id, err := s.coordinator.OpenSession()
+		if err != nil {
+			w.WriteHeader(errorStatus(err))
+			io.WriteString(w, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, id)
+	case http.MethodDelete:
+		id := strings.TrimPrefix(r.URL.Path, "/session/")
+		if id == "" || id == r.URL.Path {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "session is missing")
+			return
+		}
+		if err := s.coordinator.CloseSession(id); err != nil {
+			w.WriteHeader(errorStatus(err))
+			io.WriteString(w, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTxn serves the interactive transactions, buffered by the coordinator
+// leader: POST /txn begins one and writes its id, POST /txn/<id> runs the
+// commands of the body and returns the results of its gets, POST
+// /txn/<id>/commit commits it and DELETE /txn/<id> aborts it.
+func (s *Service) handleTxn(w http.ResponseWriter, r *http.Request) {
+	if !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/txn"), "/")
+	var res *raftpb.RaftCommand
+	var err error
+	switch {
+	case r.Method == http.MethodPost && id == "":
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, s.coordinator.BeginTransaction())
+		return
+	case r.Method == http.MethodPost && strings.HasSuffix(id, "/commit"):
+		id = strings.TrimSuffix(id, "/commit")
+		w.Header().Set(TxidHeader, id)
+		res, err = s.coordinator.CommitTransaction(id)
+	case r.Method == http.MethodPost:
+		cmds := &raftpb.RaftCommand{}
+		m, rerr := ioutil.ReadAll(r.Body)
+		if rerr == nil {
+			rerr = proto.Unmarshal(m, cmds)
+		}
+		if rerr != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, fmt.Sprintf("failed to parse commands: %s", rerr))
+			return
+		}
+		setClient(cmds.Commands, clientOf(r))
+		res, err = s.coordinator.TransactionCommands(id, cmds)
+	case r.Method == http.MethodDelete && id != "":
+		err = s.coordinator.AbortTransaction(id)
+		res = &raftpb.RaftCommand{}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		setConflictHeaders(w, err)
+		w.WriteHeader(errorStatus(err))
+		io.WriteString(w, err.Error())
+		return
+	}
+	respBody, err := proto.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
+}
+
+// handleCompare serves POST /compare, which runs the protobuf encoded
+// compare transaction of the body and replies with a protobuf encoded
+// RPCResponse: Value is 1 if the comparisons held, Commands are the results
+// of the gets of the branch applied, and Revision is its revision.
+func (s *Service) handleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ct := &raftpb.CompareTxn{}
+	m, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = proto.Unmarshal(m, ct)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("failed to parse compare transaction: %s", err))
+		return
+	}
+	setClient(ct.Success, clientOf(r))
+	setClient(ct.Failure, clientOf(r))
+	succeeded, gets, rev, err := s.coordinator.CompareTxn(ct)
+	if err != nil {
+		setConflictHeaders(w, err)
+		status := errorStatus(err)
+		if errors.Is(err, coordinator.ErrCrossShardCompare) {
+			status = http.StatusBadRequest
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, err.Error())
+		return
+	}
+	res := &raftpb.RPCResponse{Commands: gets, Revision: rev}
+	if succeeded {
+		res.Value = 1
+	}
+	if len(ct.Compare) > 0 {
+		s.setRevisionHeader(w, ct.Compare[0].Key, rev)
+	}
+	respBody, err := proto.Marshal(res)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(respBody)
+}
+
+// handleMetrics writes the metrics of the coordinator in the Prometheus text
+// format.
+func (s *Service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.coordinator.Metrics().Write(w); err != nil {
+		s.log.Errorf("unable to write metrics: %s", err)
+	}
+}
+
+// transaction runs cmds under a new transaction id, returned in the
+// TxidHeader of w with its revisions in the RevisionHeader.
+func (s *Service) transaction(w http.ResponseWriter, cmds *raftpb.RaftCommand, client string, readOnly bool) (*raftpb.RaftCommand, error) {
+	if readOnly {
+		return s.coordinator.ReadOnlyTransaction(cmds)
+	}
+	setClient(cmds.Commands, client)
+	txid := xid.New().String()
+	w.Header().Set(TxidHeader, txid)
+	keys := make([]string, len(cmds.Commands))
+	for i, cmd := range cmds.Commands {
+		keys[i] = cmd.Key
+	}
+	setAuditKeys(w, keys...)
+	revs := common.RevisionToken{}
+	res, err := s.coordinator.TransactionWithRevisions(txid, cmds, revs)
+	if len(revs) > 0 {
+		w.Header().Set(RevisionHeader, revs.String())
+	}
+	return res, err
+}
+
 // Addr returns the address on which the Service is listening
 func (s *Service) Addr() net.Addr {
 	return s.ln.Addr()
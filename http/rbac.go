@@ -0,0 +1,186 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// access is a verb on a key, or on the keys starting with it.
+type access struct {
+	verb string
+	key  string
+}
+
+// commandAccess returns the accesses of cmds: reads for gets, writes
+// otherwise.
+func commandAccess(cmds ...*raftpb.Command) []access {
+	var res []access
+	for _, cmd := range cmds {
+		verb := common.VerbWrite
+		if cmd.Method == common.GET {
+			verb = common.VerbRead
+		}
+		res = append(res, access{verb, cmd.Key})
+	}
+	return res
+}
+
+// readBody returns the body of r, which can then be read again.
+func readBody(r *http.Request) ([]byte, error) {
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
+
+// accessOf returns the accesses r requires, decoding its body for the keys
+// it holds. The requests without keys, such as the shard map or the
+// commits of interactive transactions whose commands were checked, only
+// require to be authenticated.
+func accessOf(r *http.Request) ([]access, error) {
+	q := r.URL.Query()
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/key/") && r.Method == http.MethodGet:
+		return []access{{common.VerbRead, strings.TrimPrefix(r.URL.Path, "/key/")}}, nil
+	case strings.HasPrefix(r.URL.Path, "/key/") && r.Method == http.MethodDelete:
+		return []access{{common.VerbWrite, strings.TrimPrefix(r.URL.Path, "/key/")}}, nil
+	case strings.HasPrefix(r.URL.Path, "/key") && r.Method == http.MethodPost:
+		b, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+		cmd := &raftpb.Command{}
+		if err := proto.Unmarshal(b, cmd); err != nil {
+			return nil, err
+		}
+		return []access{{common.VerbWrite, cmd.Key}}, nil
+	case strings.HasPrefix(r.URL.Path, "/history/"):
+		return []access{{common.VerbRead, strings.TrimPrefix(r.URL.Path, "/history/")}}, nil
+	case strings.HasPrefix(r.URL.Path, "/transaction"),
+		strings.HasPrefix(r.URL.Path, "/txn/") && r.Method == http.MethodPost && !strings.HasSuffix(r.URL.Path, "/commit"):
+		b, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+		cmds := &raftpb.RaftCommand{}
+		if err := proto.Unmarshal(b, cmds); err != nil {
+			return nil, err
+		}
+		return commandAccess(cmds.Commands...), nil
+	case r.URL.Path == "/compare":
+		b, err := readBody(r)
+		if err != nil {
+			return nil, err
+		}
+		ct := &raftpb.CompareTxn{}
+		if err := proto.Unmarshal(b, ct); err != nil {
+			return nil, err
+		}
+		res := commandAccess(append(ct.Success, ct.Failure...)...)
+		for _, cmp := range ct.Compare {
+			res = append(res, access{common.VerbRead, cmp.Key})
+		}
+		return res, nil
+	case r.URL.Path == "/export":
+		return []access{{common.VerbRead, q.Get("prefix")}}, nil
+	case r.URL.Path == "/import", r.URL.Path == "/bulk":
+		return []access{{common.VerbWrite, ""}}, nil
+	case r.URL.Path == "/migrate":
+		return []access{{common.VerbWrite, q.Get("from")}, {common.VerbWrite, q.Get("to")}}, nil
+	case strings.HasPrefix(r.URL.Path, "/admin/"), r.URL.Path == "/metrics", r.URL.Path == "/stats", r.URL.Path == "/join":
+		return []access{{common.VerbAdmin, ""}}, nil
+	}
+	return nil, nil
+}
+
+// authorize checks that id is granted the accesses of r by the access
+// policy, and replies 403 Forbidden otherwise.
+func (s *Service) authorize(w http.ResponseWriter, r *http.Request, id *common.Identity) bool {
+	accesses, err := accessOf(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, fmt.Sprintf("failed to parse request: %s", err))
+		return false
+	}
+	for _, a := range accesses {
+		if !s.coordinator.Allowed(id, a.verb, a.key) {
+			s.log.Infof("denying %s %s access to %q", id.Name, a.verb, a.key)
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, fmt.Sprintf("%s is not granted %s on %q", id.Name, a.verb, a.key))
+			return false
+		}
+	}
+	return true
+}
+
+// handleRoles serves /admin/roles: GET lists the roles and bindings, PUT
+// ?role=<name> sets the rules of a role from the JSON body, PUT
+// ?subject=<subject> binds the roles of the JSON body to a subject, and
+// DELETE deletes a role or unbinds a subject.
+func (s *Service) handleRoles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if r.Method != http.MethodGet && !s.coordinator.IsLeader() {
+		leader, err := s.coordinator.FindClusterLeader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "No leader found")
+		} else {
+			w.WriteHeader(http.StatusMisdirectedRequest)
+			io.WriteString(w, leader)
+		}
+		return
+	}
+	var err error
+	switch {
+	case r.Method == http.MethodGet:
+	case r.Method == http.MethodPut && q.Get("role") != "":
+		var rules []common.Rule
+		if err = json.NewDecoder(r.Body).Decode(&rules); err == nil {
+			if rules == nil {
+				rules = []common.Rule{}
+			}
+			err = s.coordinator.SetRole(q.Get("role"), rules)
+		}
+	case r.Method == http.MethodPut && q.Get("subject") != "":
+		var roles []string
+		if err = json.NewDecoder(r.Body).Decode(&roles); err == nil {
+			if roles == nil {
+				roles = []string{}
+			}
+			err = s.coordinator.Bind(q.Get("subject"), roles)
+		}
+	case r.Method == http.MethodDelete && q.Get("role") != "":
+		err = s.coordinator.SetRole(q.Get("role"), nil)
+	case r.Method == http.MethodDelete && q.Get("subject") != "":
+		err = s.coordinator.Bind(q.Get("subject"), nil)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, "expected GET, or PUT or DELETE with a role or subject")
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, err.Error())
+		return
+	}
+	b, err := json.Marshal(s.coordinator.Policy())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
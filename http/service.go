@@ -4,7 +4,10 @@ package http
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
@@ -12,6 +15,7 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/gogo/protobuf/proto"
+	"github.com/raft-kv-store/common"
 	"github.com/raft-kv-store/coordinator"
 	"github.com/raft-kv-store/raftpb"
 )
@@ -22,68 +26,263 @@ type Service struct {
 	ln          net.Listener
 	log         *log.Entry
 	coordinator *coordinator.Coordinator
+	audit       *common.AuditLog
+	// auth authenticates the requests, all accepted if nil
+	auth common.Authenticator
+	// mgmt is the listener of the management endpoints, if separate
+	mgmt net.Listener
 }
 
-// NewService returns an uninitialized HTTP service.
-func NewService(logger *log.Logger, addr string, coordinator *coordinator.Coordinator) *Service {
+// NewService returns an uninitialized HTTP service. Operations are recorded
+// in audit, if not nil.
+func NewService(logger *log.Logger, addr string, coordinator *coordinator.Coordinator, audit *common.AuditLog) *Service {
 
 	l := logger.WithField("component", "http")
 
 	return &Service{
 		addr:        addr,
 		coordinator: coordinator,
+		audit:       audit,
 		log:         l,
 	}
 }
 
-// Start starts the service.
-func (s *Service) Start(joinHTTPAddress string) {
-	server := http.Server{
-		Handler: s,
-	}
+// SetAuthenticator requires the requests to be authenticated by auth, and
+// authorized by the access policy if common.RBAC is set. The joins of the
+// other coordinators are authenticated by their signature instead, if
+// common.ClusterSecret is set.
+func (s *Service) SetAuthenticator(auth common.Authenticator) {
+	s.auth = auth
+}
 
-	ln, err := net.Listen("tcp", s.addr)
+// Start starts the service, over TLS if configured. The management
+// endpoints are served on a listener of their own if
+// common.ManagementAddress is set.
+func (s *Service) Start(joinHTTPAddress string) {
+	tlsConfig, err := common.HTTPTLSConfig()
 	if err != nil {
-		s.log.Fatalf("failed to start HTTP service: %s", err.Error())
+		s.log.Fatalf("failed to configure TLS: %s", err)
 	}
-	s.ln = ln
+	s.ln = s.listen(s.addr, tlsConfig, common.ClientAllow)
 
 	http.Handle("/", s)
 
 	go func() {
-		err := server.Serve(s.ln)
-		if err != nil {
+		server := http.Server{Handler: s}
+		if err := server.Serve(s.ln); err != nil {
 			s.log.Fatalf("HTTP serve error: %s", err)
 		}
 	}()
 
+	joinTLS := tlsConfig
+	if common.ManagementAddress != "" {
+		mgmtTLS, err := common.ManagementTLSConfig()
+		if err != nil {
+			s.log.Fatalf("failed to configure management TLS: %s", err)
+		}
+		// the coordinators joined serve their joins on their management
+		// listener too
+		joinTLS = mgmtTLS
+		s.mgmt = s.listen(common.ManagementAddress, mgmtTLS, common.AdminAllow)
+		go func() {
+			server := http.Server{Handler: managementHandler{s}}
+			if err := server.Serve(s.mgmt); err != nil {
+				s.log.Fatalf("HTTP management serve error: %s", err)
+			}
+		}()
+		s.log.Infof("management endpoints listening on %s", common.ManagementAddress)
+	}
+
 	if joinHTTPAddress != "" {
-		msg := &raftpb.JoinMsg{RaftAddress: s.coordinator.RaftAddress, ID: s.coordinator.ID}
-		b, err := proto.Marshal(msg)
+		scheme := "http"
+		if joinTLS != nil {
+			scheme = "https"
+		}
+		clusterID, err := common.ClusterID(s.coordinator.RaftDir)
 		if err != nil {
-			s.log.Fatalf("error when marshaling %+v", msg)
+			s.log.Fatal(err)
+		}
+		msg := &raftpb.JoinMsg{RaftAddress: s.coordinator.RaftAddress, ID: s.coordinator.ID, Version: common.ProtocolVersion, ClusterID: clusterID}
+		err = common.RetryJoinAny(joinHTTPAddress, func(addr string) error {
+			// signed again on every attempt, not to be too old
+			b, err := proto.Marshal(common.SignJoin(common.ClusterSecret, msg))
+			if err != nil {
+				return err
+			}
+			req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s://%s/join", scheme, addr), bytes.NewBuffer(b))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/protobuf")
+			if common.JoinToken != "" {
+				req.Header.Set("Authorization", "Bearer "+common.JoinToken)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			body, err := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return err
+			}
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("join replied %d: %s", resp.StatusCode, body)
+			}
+			clusterID = string(body)
+			return nil
+		})
+		if err == nil {
+			err = common.SetClusterID(s.coordinator.RaftDir, clusterID)
 		}
-		resp, err := http.Post(fmt.Sprintf("http://%s/join", joinHTTPAddress), "application/protobuf", bytes.NewBuffer(b))
 		if err != nil {
 			s.log.Fatalf("failed to join %s: %s", joinHTTPAddress, err)
 		}
-		defer resp.Body.Close()
 	}
 }
 
+// listen listens on addr, over TLS if tlsConfig is not nil, for the
+// addresses of allow.
+func (s *Service) listen(addr string, tlsConfig *tls.Config, allow []string) net.Listener {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		s.log.Fatalf("failed to start HTTP service: %s", err.Error())
+	}
+	if ln, err = common.AllowListener(ln, allow, s.log); err != nil {
+		s.log.Fatalf("failed to start HTTP service: %s", err)
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	return ln
+}
+
 // Close closes the service.
 func (s *Service) Close() {
 	s.ln.Close()
+	if s.mgmt != nil {
+		s.mgmt.Close()
+	}
 	return
 }
 
+// managementHandler serves the management endpoints of a service.
+type managementHandler struct {
+	s *Service
+}
+
+func (h managementHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.s.serve(w, r, true)
+}
+
+// isManagement reports whether path is a management endpoint. The joins of
+// the other coordinators are, not to be exposed to the clients.
+func isManagement(path string) bool {
+	return strings.HasPrefix(path, "/admin/") || path == "/metrics" || path == "/join"
+}
+
 // ServeHTTP allows Service to serve HTTP requests.
 func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.serve(w, r, false)
+}
+
+// serve serves r received on the management listener if management, on the
+// client one otherwise. With a separate management listener, each only
+// serves its own endpoints.
+func (s *Service) serve(w http.ResponseWriter, r *http.Request, management bool) {
 	s.log.Infof("Serving request for path: %s\n", r.URL.Path)
+	if acceptsProtobuf(r) {
+		p := &protobufErrors{ResponseWriter: w}
+		defer p.finish()
+		w = p
+	}
+	if common.Cluster != "" {
+		w.Header().Set(ClusterHeader, common.Cluster)
+	}
+	if cluster := r.Header.Get(ClusterHeader); cluster != "" && cluster != common.Cluster {
+		s.log.Infof("rejecting request for path %s of cluster %q", r.URL.Path, cluster)
+		w.WriteHeader(http.StatusPreconditionFailed)
+		io.WriteString(w, fmt.Sprintf("this is cluster %q, not %q", common.Cluster, cluster))
+		return
+	}
+	if (common.ManagementAddress != "" || management) && isManagement(r.URL.Path) != management {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if s.auth != nil && (r.URL.Path != "/join" || common.ClusterSecret == "") {
+		id, err := s.auth.Authenticate(r)
+		if err != nil {
+			s.log.Infof("rejecting request for path %s: %s", r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if common.RBAC && !s.authorize(w, r, id) {
+			return
+		}
+		r = r.WithContext(common.WithIdentity(r.Context(), id))
+	}
+	s.serveAudited(w, r, s.route)
+}
+
+// route dispatches r to the handler of its path.
+func (s *Service) route(w http.ResponseWriter, r *http.Request) {
 	if strings.HasPrefix(r.URL.Path, "/key") {
 		s.handleKeyRequest(w, r)
+	} else if strings.HasPrefix(r.URL.Path, "/history/") {
+		s.handleHistory(w, r)
 	} else if strings.HasPrefix(r.URL.Path, "/transaction") {
 		s.handleTransaction(w, r)
+	} else if r.URL.Path == "/export" {
+		s.handleExport(w, r)
+	} else if r.URL.Path == "/migrate" {
+		s.handleMigrate(w, r)
+	} else if r.URL.Path == "/import" {
+		s.handleImport(w, r)
+	} else if r.URL.Path == "/bulk" {
+		s.handleBulk(w, r)
+	} else if r.URL.Path == "/admin/slowlog" {
+		s.handleSlowLog(w, r)
+	} else if r.URL.Path == "/shardmap" {
+		s.handleShardMap(w, r)
+	} else if r.URL.Path == "/admin/locks" {
+		s.handleLocks(w, r)
+	} else if r.URL.Path == "/admin/shards" {
+		s.handleShards(w, r)
+	} else if r.URL.Path == "/stats" {
+		s.handleStats(w, r)
+	} else if r.URL.Path == "/admin/compact" {
+		s.handleCompact(w, r)
+	} else if r.URL.Path == "/admin/verify-state" {
+		s.handleVerifyState(w, r)
+	} else if r.URL.Path == "/admin/repair" {
+		s.handleRepair(w, r)
+	} else if r.URL.Path == "/admin/nodes" {
+		s.handleNodes(w, r)
+	} else if r.URL.Path == "/admin/members" {
+		s.handleMembers(w, r)
+	} else if r.URL.Path == "/admin/membership" {
+		s.handleMembership(w, r)
+	} else if r.URL.Path == "/admin/topology" {
+		s.handleTopology(w, r)
+	} else if r.URL.Path == "/admin/keyprofile" {
+		s.handleKeyProfile(w, r)
+	} else if r.URL.Path == "/admin/roles" {
+		s.handleRoles(w, r)
+	} else if r.URL.Path == "/admin/transforms" {
+		s.handleTransforms(w, r)
+	} else if r.URL.Path == "/admin/ttl" {
+		s.handleTTL(w, r)
+	} else if r.URL.Path == "/admin/undelete" {
+		s.handleUndelete(w, r)
+	} else if r.URL.Path == "/txn" || strings.HasPrefix(r.URL.Path, "/txn/") {
+		s.handleTxn(w, r)
+	} else if r.URL.Path == "/compare" {
+		s.handleCompare(w, r)
+	} else if r.URL.Path == "/session" || strings.HasPrefix(r.URL.Path, "/session/") {
+		s.handleSession(w, r)
+	} else if r.URL.Path == "/metrics" {
+		s.handleMetrics(w, r)
 	} else if r.URL.Path == "/join" {
 		s.handleJoin(w, r)
 	} else {
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"expvar"
 	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
 	"os"
 	"os/signal"
 	"path"
 	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
 	nested "github.com/antonfisher/nested-logrus-formatter"
 	"github.com/raft-kv-store/common"
@@ -37,6 +43,19 @@ var (
 	bucketName        string
 	failmode          string
 	isCoordinator     bool
+	auditFile         string
+	auditMaxSize      int64
+	auditBackups      int
+	auditSyslog       bool
+	auditWrites       bool
+	adminAddress      string
+	adminToken        string
+	devMode           bool
+	waitTimeout       time.Duration
+	discovery         string
+	bootstrapExpect   int
+	advertiseAddress  string
+	statefulSet       string
 )
 
 func init() {
@@ -45,26 +64,200 @@ func init() {
 
 	flag.StringVarP(&raftAddress, "raft", "r", DefaultRaftAddress, "Set the RAFT binding address")
 	flag.StringVarP(&cohortRaftAddress, "cohortRaft", "f", "", "Set the RAFT binding address")
-	flag.StringVarP(&joinHTTPAddress, "join", "j", "", "Set joining HTTP address, if any")
-	flag.StringVarP(&nodeID, "id", "i", "", "Node ID, randomly generated if not set")
+	flag.StringVarP(&joinHTTPAddress, "join", "j", "", "Set joining HTTP address, if any, or several comma separated tried in turn")
+	flag.StringVarP(&nodeID, "id", "i", "", "Node ID, generated and kept in the raft directory if not set")
 	flag.StringVarP(&raftDir, "dir", "d", "", "Raft directory, ./$(nodeID) if not set")
 	flag.StringVarP(&failmode, "fail", "t", "", "failure mode")
-	flag.IntVarP(&common.SnapshotInterval, "snapshotinterval", "", 180,
-		"Snapshot interval in seconds, 180 seconds if not set")
-	flag.IntVarP(&common.SnapshotThreshold, "snapshotthreshold", "", 5,
-		"snapshot threshold of log indices, 5 if not set")
+	flag.StringVarP(&common.RaftProfile, "raft-profile", "", "default",
+		"Raft tuning profile, one of "+strings.Join(common.RaftProfiles(), ", "))
+	flag.IntVarP(&common.SnapshotInterval, "snapshotinterval", "", 0,
+		"Snapshot interval in seconds, from the raft profile if not set")
+	flag.IntVarP(&common.SnapshotThreshold, "snapshotthreshold", "", 0,
+		"snapshot threshold of log indices, from the raft profile if not set")
+	flag.IntVarP(&common.RaftLogPreallocMB, "raft-log-prealloc", "", 0,
+		"Map the raft log file at this size in MB when opened, so that it grows without remapping")
+	flag.IntVarP(&common.RaftLogCacheMB, "raft-log-cache", "", 0,
+		"Cache this many MB of the recently appended raft log entries, no cache if 0")
+	flag.IntVarP(&common.HistoryRetention, "history", "", 10,
+		"number of past revisions kept per key for historical reads, 10 if not set")
+	flag.DurationVarP(&common.SlowLockThreshold, "slow-lock", "", 10*time.Millisecond,
+		"record lock waits longer than this in the slow log, disabled if 0")
+	flag.DurationVarP(&common.MaxLockTimeout, "max-lock-timeout", "", 10*time.Millisecond,
+		"cap the lock timeout of single key reads and writes, which adapts to the recent lock waits, fixed if 0")
+	flag.DurationVarP(&common.SlowCommitThreshold, "slow-commit", "", 100*time.Millisecond,
+		"record raft proposals taking longer than this to commit in the slow log, disabled if 0")
+	flag.DurationVarP(&common.SlowApplyThreshold, "slow-apply", "", 10*time.Millisecond,
+		"record raft log entries taking longer than this to apply in the slow log, disabled if 0")
+	flag.IntVarP(&common.SlowLogSize, "slowlog-size", "", 128, "number of slow operations kept per node")
+	flag.Int64VarP(&common.StateHashInterval, "state-hash-interval", "", 10000,
+		"hash the keys of the shard replicas every this many raft log entries, to detect their divergence, disabled if 0")
+	flag.Int64VarP(&common.ThrottleLag, "throttle-lag", "", 0,
+		"delay writes while this many log entries are not committed and applied, disabled if 0")
+	flag.Int64VarP(&common.RejectLag, "reject-lag", "", 0,
+		"reject writes while this many log entries are not committed and applied, disabled if 0")
+	flag.DurationVarP(&common.MaxThrottleDelay, "max-throttle-delay", "", 100*time.Millisecond,
+		"delay of writes right below the reject lag")
+	flag.DurationVarP(&common.MaxClockSkew, "max-clock-skew", "", 500*time.Millisecond,
+		"clock skew allowed between replicas, reads are verified by a quorum while a replica is further off")
+	flag.StringVarP(&common.ReadMode, "read-mode", "", common.ReadLease,
+		"Reads of shard leaders: lease, readindex to confirm the leadership before every read, or local")
+	flag.Float64VarP(&common.ReadVerifySample, "read-verify-sample", "", 0,
+		"fraction of the reads verified against another replica at the same revision, to detect diverging replicas, never if 0")
+	flag.DurationVarP(&common.SessionTimeout, "session-timeout", "", 10*time.Minute,
+		"Expire the client sessions without writes for this long, never if 0")
+	flag.StringVarP(&common.KeyCharset, "key-charset", "", "",
+		"Characters allowed in the keys written by clients, as a regexp character class such as a-zA-Z0-9_./-, any if not set")
+	flag.IntVarP(&common.KeyMaxDepth, "key-max-depth", "", 0,
+		"Most /-separated segments of the keys written by clients, unlimited if 0")
+	flag.DurationVarP(&common.SoftDeleteRetention, "soft-delete", "", 0,
+		"Keep the keys deleted as tombstones for this long, to undelete them, deleted for good if 0")
+	flag.StringSliceVarP(&common.SoftDeletePrefixes, "soft-delete-prefix", "", nil,
+		"Soft delete only the keys starting with these prefixes, all if not set")
+	flag.IntVarP(&common.TxnParallelism, "txn-parallelism", "", 16,
+		"Shards the coordinator prepares and commits a transaction on at the same time")
+	flag.IntVarP(&common.TxnBatchSize, "txn-batch", "", 1,
+		"Committed transactions a shard leader packs in a raft entry, not batched if 1")
+	flag.DurationVarP(&common.TxnBatchDelay, "txn-batch-delay", "", 2*time.Millisecond,
+		"How long a shard leader waits for transactions to fill a batch")
+	flag.Int64VarP(&common.CacheBytes, "cache-bytes", "", 0,
+		"Evict the coldest keys of a shard beyond this many bytes of keys and values, never if 0")
+	flag.StringVarP(&common.CachePolicy, "cache-policy", "", common.CacheLRU,
+		"Keys evicted first beyond --cache-bytes: lru for the least recently used, lfu for the least frequently used")
+	flag.DurationVarP(&common.EvictInterval, "evict-interval", "", time.Second,
+		"How often shard leaders check the size of their shard against --cache-bytes")
+	flag.DurationVarP(&common.ExpiryInterval, "expiry-interval", "", time.Second,
+		"How often shard leaders delete the keys past their ttl, never if 0")
+	flag.Int64VarP(&common.StoreMemoryBudget, "memory-store", "", 0,
+		"Reject the writes of a store node whose keys and values exceed this many bytes, unlimited if 0")
+	flag.Int64VarP(&common.ProposalMemoryBudget, "memory-proposals", "", 0,
+		"Reject the writes of a store node whose proposals in flight exceed this many bytes, unlimited if 0")
+	flag.BoolVarP(&common.ArenaValues, "arena", "", false,
+		"Allocate the keys of a store node in slabs, and copy small blob values in them, to cut the objects of millions of tiny values")
+	flag.IntVarP(&common.GCPercent, "gogc", "", 0,
+		"GOGC of the node, the GOGC environment variable or 100 if 0")
+	flag.Int64VarP(&common.HeapLimit, "heap-limit", "", 0,
+		"Soft memory limit of the node in bytes, store nodes rejecting writes once their heap is over --heap-watermark of it; unlimited if 0")
+	flag.Float64VarP(&common.HeapWatermark, "heap-watermark", "", 0.9,
+		"Fraction of --heap-limit over which store nodes reject writes")
+	flag.StringSliceVarP(&common.Webhooks, "webhook", "", nil,
+		"POST the changes of the keys starting with prefix to url as JSON events, as prefix=url, from the shard leaders")
+	flag.StringVarP(&common.Cluster, "cluster-id", "", "",
+		"Id of the cluster, the same on all its nodes, required of raft peers and clients; not checked if not set")
+	flag.StringVarP(&common.ClusterSecret, "cluster-secret", "", os.Getenv("RAFTKV_CLUSTER_SECRET"),
+		"Sign the transaction messages of the coordinators to the shard leaders, and the joins of the nodes, with this HMAC-SHA256 key, $RAFTKV_CLUSTER_SECRET if not set")
+	flag.StringVarP(&common.WebhookSecret, "webhook-secret", "", os.Getenv("RAFTKV_WEBHOOK_SECRET"),
+		"Sign the webhook events with this HMAC-SHA256 key, $RAFTKV_WEBHOOK_SECRET if not set")
+	flag.IntVarP(&common.MaxProposals, "max-proposals", "", 0,
+		"Writes and prepares a shard leader proposes at the same time, the others waiting by priority, unbounded if 0")
+	flag.DurationVarP(&common.ConflictBackoff, "conflict-backoff", "", 20*time.Millisecond,
+		"Base of the backoff suggested to transactions aborted on a locked key")
+	flag.DurationVarP(&common.TxnIdleTimeout, "txn-idle-timeout", "", time.Minute,
+		"Drop the interactive transactions without requests for this long, never if 0")
+	flag.IntVarP(&common.MaxTxnOps, "max-txn-ops", "", 0,
+		"Reject the transactions of more commands, unlimited if 0")
+	flag.Int64VarP(&common.MaxTxnBytes, "max-txn-bytes", "", 0,
+		"Reject the transactions whose keys and values take more bytes, unlimited if 0")
+	flag.StringSliceVarP(&common.SeedFrom, "seed-from", "", nil,
+		"Fetch the initial snapshot of a new store node, or the changes since the snapshot of a restarted one, from these replicas rpc addresses")
+	flag.Int64VarP(&common.SnapshotBandwidth, "snapshot-bandwidth", "", 0,
+		"bytes per second of the snapshots sent to followers, unlimited if 0")
+	flag.StringVarP(&common.SnapshotDir, "snapshot-dir", "", "",
+		"directory of the raft snapshots, apart from the raft logs, in the raft directories if not set")
+	flag.Int64VarP(&common.SnapshotWriteBandwidth, "snapshot-write-bandwidth", "", 0,
+		"bytes per second written to disk by the snapshots of the node, written in turn by a single writer, unlimited if 0")
+	flag.StringVarP(&common.VaultAddress, "vault-address", "", common.VaultAddress,
+		"Vault server of the vault:<path>#<field> TLS certificates and keys, $VAULT_ADDR if not set, read with $VAULT_TOKEN")
+	flag.DurationVarP(&common.SecretRefresh, "secret-refresh", "", common.SecretRefresh,
+		"Fetch the TLS certificates and keys again at this interval, never if 0")
+	flag.StringVarP(&common.RaftTLSCert, "raft-tls-cert", "", "", "TLS certificate of the raft connections, in clear if not set")
+	flag.StringVarP(&common.RaftTLSKey, "raft-tls-key", "", "", "TLS key of the raft connections")
+	flag.StringVarP(&common.RaftTLSCA, "raft-tls-ca", "", "",
+		"CA verifying the raft peers, which must then present a certificate signed by it")
+	flag.BoolVarP(&common.RaftCompress, "raft-compress", "", false, "Compress the raft connections")
+	flag.IntVarP(&common.RaftMaxPool, "raft-max-pool", "", 3, "raft connections kept open to every peer")
+	flag.IntVarP(&common.MaxInflight, "max-inflight", "", 0,
+		"append requests in flight to every follower, the transport limit of 128 if 0")
+	flag.Int64VarP(&common.MaxInflightBytes, "max-inflight-bytes", "", 0,
+		"bytes of log entries in flight to every follower, unlimited if 0")
 	flag.StringVarP(&bucketName, "bucketName/shard", "b", "", "Bucket name, randomly"+
 		"generated if not set")
 	flag.BoolVarP(&isCoordinator, "coordinator", "c", false, "Start as coordinator")
+	flag.StringVarP(&common.RaftPVBaseDir, "data-dir", "", common.RaftPVBaseDir, "Directory holding the raft directories")
+	flag.DurationVarP(&waitTimeout, "wait-timeout", "", 2*time.Minute, "How long wait-for-cluster waits")
+	flag.StringVarP(&discovery, "discovery", "", "",
+		"Find the nodes of the raft group to bootstrap or join instead of --join: static:<addr,...>, srv:<name> or exec:<command>")
+	flag.IntVarP(&bootstrapExpect, "bootstrap-expect", "", 3, "Number of nodes discovered before the group is bootstrapped")
+	flag.StringVarP(&advertiseAddress, "advertise", "", "",
+		"Address the other nodes discover this node at, the listen address with the host name if not set")
+	flag.StringVarP(&statefulSet, "statefulset", "", "",
+		"Derive the node id, raft addresses and nodes to join from the Kubernetes StatefulSet pod governed by this headless service, of --bootstrap-expect pods")
+	flag.DurationVarP(&common.DiscoveryTimeout, "discovery-timeout", "", common.DiscoveryTimeout,
+		"How long to wait for the expected nodes to be discovered")
+	flag.BoolVarP(&devMode, "dev", "", false,
+		"Start a one-node development cluster kept in memory, with example keys")
+	flag.BoolVarP(&common.Witness, "witness", "", false,
+		"Start as a witness that votes in the shard raft groups but stores no keys")
+	flag.BoolVarP(&common.Nonvoter, "nonvoter", "", false,
+		"Join the shard raft groups without a vote, to be promoted later")
+	flag.StringVarP(&common.Zone, "zone", "", "", "Zone of the node, reported to the coordinators")
+	flag.Int64VarP(&common.Capacity, "capacity", "", common.Capacity, "Relative capacity of the node, reported to the coordinators")
+	flag.StringVarP(&common.ManagementAddress, "management", "", "",
+		"Serve the /admin endpoints and /metrics of the coordinators on this address instead of --listen")
+	flag.StringVarP(&common.ManagementTLSCert, "management-tls-cert", "", "",
+		"TLS certificate of the management and debug endpoints, in clear if not set")
+	flag.StringVarP(&common.ManagementTLSKey, "management-tls-key", "", "", "TLS key of the management and debug endpoints")
+	flag.StringVarP(&common.ManagementTLSClientCA, "management-tls-client-ca", "", "",
+		"CA verifying the client certificates presented to the management endpoints")
+	flag.StringSliceVarP(&common.ClientAllow, "allow", "", nil,
+		"IP addresses or CIDR ranges allowed to connect to the HTTP API or the rpc listener, any if not set")
+	flag.StringSliceVarP(&common.PeerAllow, "peer-allow", "", nil,
+		"IP addresses or CIDR ranges allowed to connect to the raft listeners, any if not set")
+	flag.StringSliceVarP(&common.AdminAllow, "admin-allow", "", nil,
+		"IP addresses or CIDR ranges allowed to connect to the management and debug endpoints, any if not set")
+	flag.StringVarP(&adminAddress, "admin", "", "", "Serve the debug endpoints on this address, disabled if not set")
+	flag.StringVarP(&adminToken, "admin-token", "", os.Getenv("RAFTKV_ADMIN_TOKEN"),
+		"Bearer token required by the debug endpoints, $RAFTKV_ADMIN_TOKEN if not set")
+	flag.StringSliceVarP(&common.AuthBackends, "auth", "", nil,
+		"Authenticate the HTTP API with these backends, tried in order: token:<file> for static bearer tokens, jwt:<jwks url> for JWTs or mtls for client certificates")
+	flag.StringVarP(&common.JoinToken, "join-token", "", os.Getenv("RAFTKV_JOIN_TOKEN"),
+		"Bearer token of the join of this coordinator, with --auth on the coordinator joined, $RAFTKV_JOIN_TOKEN if not set")
+	flag.BoolVarP(&common.RBAC, "rbac", "", false, "Authorize the authenticated requests with the roles bound to the caller")
+	flag.StringSliceVarP(&common.RBACAdmins, "rbac-admins", "", nil,
+		"Subjects granted everything whatever the roles, as names or group:<name>, to bootstrap the roles")
+	flag.StringVarP(&common.JWTIssuer, "jwt-issuer", "", "", "Issuer required in the iss claim of the JWTs, any if not set")
+	flag.StringVarP(&common.JWTAudience, "jwt-audience", "", "", "Audience required in the aud claim of the JWTs, any if not set")
+	flag.StringVarP(&common.HTTPTLSCert, "tls-cert", "", "", "TLS certificate of the HTTP API of the coordinators, in clear if not set")
+	flag.StringVarP(&common.HTTPTLSKey, "tls-key", "", "", "TLS key of the HTTP API of the coordinators")
+	flag.StringVarP(&common.HTTPTLSClientCA, "tls-client-ca", "", "", "CA verifying the client certificates presented to the HTTP API")
+	flag.StringVarP(&auditFile, "audit-log", "", "", "Append the audit log of the coordinator to this file")
+	flag.Int64VarP(&auditMaxSize, "audit-max-size", "", 100, "Rotate the audit log file at this size in MB")
+	flag.IntVarP(&auditBackups, "audit-backups", "", 5, "Number of rotated audit log files kept")
+	flag.BoolVarP(&auditSyslog, "audit-syslog", "", false, "Send the audit log of the coordinator to syslog")
+	flag.BoolVarP(&auditWrites, "audit-writes", "", false, "Audit every write, not only administrative operations")
 
 	flag.Usage = func() {
 		log.Errorf("Usage: %s [options]\n", os.Args[0])
+		log.Errorf("       %s [options] wait-for-cluster <coordinator>\n", os.Args[0])
+		log.Errorf("Every option can also be set by its %sOPTION environment variable\n", envPrefix)
 		flag.PrintDefaults()
 	}
 }
 
 func main() {
 	flag.Parse()
+	if err := applyEnv(flag.CommandLine); err != nil {
+		log.Fatal(err)
+	}
+	if err := common.CompileKeyCharset(); err != nil {
+		log.Fatal(err)
+	}
+	if common.HeapWatermark <= 0 || common.HeapWatermark > 1 {
+		log.Fatalf("--heap-watermark must be in (0, 1], not %g", common.HeapWatermark)
+	}
+	common.TuneGC()
+	if flag.Arg(0) == "wait-for-cluster" {
+		os.Exit(waitForCluster(flag.Arg(1), waitTimeout))
+	}
 	logger := log.New()
 	logger.SetFormatter(&nested.Formatter{
 		HideKeys:    true,
@@ -79,10 +272,55 @@ func main() {
 	logger.SetReportCaller(true)
 	log := logger.WithField("component", "main")
 
-	if isCoordinator {
+	if !devMode {
+		if err := checkDataDir(common.RaftPVBaseDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if statefulSet != "" && !devMode {
+		if discovery != "" {
+			log.Fatal("--statefulset and --discovery are exclusive")
+		}
+		if err := bootstrapStatefulSet(log); err != nil {
+			log.Fatalf("statefulset bootstrap failed: %s", err)
+		}
+	}
+	if discovery != "" && joinHTTPAddress == "" && !devMode {
+		join, err := common.DiscoverJoin(discovery, advertised(), bootstrapExpect)
+		if err != nil {
+			log.Fatalf("discovery failed: %s", err)
+		}
+		if join == "" {
+			log.Infof("bootstrapping the raft group")
+		} else {
+			log.Infof("joining the raft group at %s", join)
+		}
+		joinHTTPAddress = join
+	}
+	var devDir string
+	var shutdown func() error
+	if devMode {
+		devDir = startDev(logger, log)
+	} else if isCoordinator {
 		c := coordinator.NewCoordinator(logger, nodeID, raftDir, raftAddress, joinHTTPAddress == "", failmode)
-		h := httpd.NewService(logger, listenAddress, c)
+		h := httpd.NewService(logger, listenAddress, c, newAuditLog(log))
+		auth, err := common.NewAuthenticator(common.AuthBackends)
+		if err != nil {
+			log.Fatalf("unable to setup authentication: %s", err)
+		}
+		if auth == nil && common.RBAC {
+			log.Fatal("--rbac requires --auth")
+		}
+		h.SetAuthenticator(auth)
 		h.Start(joinHTTPAddress)
+		if adminAddress != "" {
+			admin := common.NewAdminServer(logger, adminAddress, adminToken)
+			admin.HandleJSON("/debug/raft", func() interface{} { return c.RaftStats() })
+			admin.HandleJSON("/debug/slowlog", func() interface{} { return c.SlowLog() })
+			admin.HandleJSON("/debug/locks", func() interface{} { return c.Locks() })
+			admin.HandleJSON("/debug/nodes", func() interface{} { return c.Members() })
+			admin.Start()
+		}
 
 		log.Infof("coordinator started successfully")
 	} else {
@@ -93,11 +331,72 @@ func main() {
 		}
 		kv := store.NewStore(logger, nodeID, raftDir, raftAddress, joinHTTPAddress == "", listenAddress, bucketName, cohortRaftAddress, joinHTTPAddress)
 		kv.Start(joinHTTPAddress, nodeID)
+		shutdown = kv.Shutdown
+		if adminAddress != "" {
+			admin := common.NewAdminServer(logger, adminAddress, adminToken)
+			admin.HandleJSON("/debug/raft", func() interface{} { return kv.RaftStats() })
+			expvar.Publish("cmap_locks", expvar.Func(func() interface{} { return kv.LockStats() }))
+			admin.HandleJSON("/debug/storage", func() interface{} {
+				stats, err := kv.Storage()
+				if err != nil {
+					return err.Error()
+				}
+				return stats
+			})
+			admin.Handle("/metrics", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+				kv.Metrics().Write(w)
+			}))
+			admin.Handle("/debug/compact", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+				if err := kv.Compact(); err != nil {
+					w.WriteHeader(http.StatusInternalServerError)
+					io.WriteString(w, err.Error())
+				}
+			}))
+			admin.Start()
+		}
 	}
 
 	log.Info("raftd started successfully")
+	// as PID 1 in a container, signals without handler are ignored
 	terminate := make(chan os.Signal, 1)
-	signal.Notify(terminate, os.Interrupt)
-	<-terminate
-	log.Info("raftd exiting")
+	signal.Notify(terminate, os.Interrupt, syscall.SIGTERM)
+	sig := <-terminate
+	log.Infof("raftd exiting on %s", sig)
+	if shutdown != nil {
+		if err := shutdown(); err != nil {
+			log.Warnf("unable to shut down cleanly: %s", err)
+		}
+	}
+	if devDir != "" {
+		os.RemoveAll(devDir)
+	}
+}
+
+// newAuditLog returns the audit log configured by the command line, or nil
+// if there is none.
+func newAuditLog(log *log.Entry) *common.AuditLog {
+	var sinks []io.Writer
+	if auditFile != "" {
+		f, err := common.NewRotatingFile(auditFile, auditMaxSize<<20, auditBackups)
+		if err != nil {
+			log.Fatalf("unable to open audit log: %s", err)
+		}
+		sinks = append(sinks, f)
+	}
+	if auditSyslog {
+		w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, "raftkv-audit")
+		if err != nil {
+			log.Fatalf("unable to connect to syslog: %s", err)
+		}
+		sinks = append(sinks, w)
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return common.NewAuditLog(auditWrites, sinks...)
 }
@@ -0,0 +1,128 @@
+// Package raftkv embeds a store node in an application, which then runs a
+// replicated key-value store inside its own binary and uses it in process.
+// The nodes of a store are embedded in several instances of the application,
+// or some of them run as raftkv servers. Applications with several shards
+// run coordinators and use the client package instead.
+package raftkv
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/raft-kv-store/store"
+	log "github.com/sirupsen/logrus"
+)
+
+// Config is the configuration of an embedded node.
+type Config struct {
+	// ID is the id of the node, randomly generated if empty.
+	ID string
+	// BaseDir is the directory holding the raft directories of the nodes of
+	// the process, common.RaftPVBaseDir if empty.
+	BaseDir string
+	// Dir is the raft directory of the node under BaseDir, the host name if
+	// empty.
+	Dir string
+	// RaftAddress is the address of the raft group of the keys.
+	RaftAddress string
+	// CohortRaftAddress is the address of the raft group of the
+	// transactions, derived from RaftAddress if empty.
+	CohortRaftAddress string
+	// ListenAddress is the rpc address the other nodes and the clients reach
+	// the node at.
+	ListenAddress string
+	// Join is the rpc address of a node of the store to join. The node
+	// bootstraps a new store if empty.
+	Join string
+	// Logger is the logger of the node, the standard logger if nil.
+	Logger *log.Logger
+}
+
+// Node is a store node embedded in the application.
+type Node struct {
+	s *store.Store
+}
+
+// New starts a node configured by cfg. The node keeps running until Close.
+// Like a raftkv server, it exits the process if its raft groups cannot be
+// set up.
+func New(cfg Config) (*Node, error) {
+	if cfg.RaftAddress == "" || cfg.ListenAddress == "" {
+		return nil, errors.New("raft and listen addresses are required")
+	}
+	if cfg.CohortRaftAddress == "" {
+		cfg.CohortRaftAddress = common.GetDerivedAddress(cfg.RaftAddress)
+	}
+	if cfg.BaseDir != "" {
+		common.RaftPVBaseDir = cfg.BaseDir
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = log.StandardLogger()
+	}
+	s := store.NewStore(cfg.Logger, cfg.ID, cfg.Dir, cfg.RaftAddress, cfg.Join == "", cfg.ListenAddress, "", cfg.CohortRaftAddress, cfg.Join)
+	s.Start(cfg.Join, s.ID)
+	return &Node{s: s}, nil
+}
+
+// WaitLeader waits up to timeout until the store has a leader and the node
+// serves requests.
+func (n *Node) WaitLeader(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if n.s.Ready() {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no leader after %s", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// IsLeader reports whether the node leads the store. Writes are served by
+// the leader only.
+func (n *Node) IsLeader() bool {
+	return n.s.IsLeader()
+}
+
+// Get returns the value of key.
+func (n *Node) Get(key string) (int64, error) {
+	res, err := n.s.Do(&raftpb.Command{Method: common.GET, Key: key})
+	if err != nil {
+		return 0, err
+	}
+	return res.Value, nil
+}
+
+// Set sets key to value.
+func (n *Node) Set(key string, value int64) error {
+	_, err := n.s.Do(&raftpb.Command{Method: common.SET, Key: key, Value: value})
+	return err
+}
+
+// Delete deletes key.
+func (n *Node) Delete(key string) error {
+	_, err := n.s.Do(&raftpb.Command{Method: common.DEL, Key: key})
+	return err
+}
+
+// Do serves cmd like the shard leader serves the commands of coordinators:
+// gets, sets and deletes, blobs, conditional sets, history, compare
+// transactions and the other single-key commands.
+func (n *Node) Do(cmd *raftpb.Command) (*raftpb.RPCResponse, error) {
+	return n.s.Do(cmd)
+}
+
+// Transaction applies the sets and deletes cmds atomically.
+func (n *Node) Transaction(cmds ...*raftpb.Command) error {
+	return n.s.Transaction(cmds)
+}
+
+// Close stops the node. A node can be started again with the same
+// configuration in the same process.
+func (n *Node) Close() error {
+	return n.s.Shutdown()
+}
@@ -20,16 +20,72 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// Commands are stored in the raft logs and snapshots, and decoded by nodes of
+// older and newer versions during rolling upgrades and downgrades: never
+// renumber a field nor reuse the number of a removed one, reserve it instead.
+// Older nodes keep the fields they do not know and skip them when applying.
 type Command struct {
-	Method               string             `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
-	Key                  string             `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
-	Value                int64              `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
-	Gt                   *GlobalTransaction `protobuf:"bytes,4,opt,name=gt,proto3" json:"gt,omitempty"`
-	Cond                 *Cond              `protobuf:"bytes,5,opt,name=cond,proto3" json:"cond,omitempty"`
-	So                   *ShardOps          `protobuf:"bytes,6,opt,name=so,proto3" json:"so,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Method string             `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Key    string             `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value  int64              `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	Gt     *GlobalTransaction `protobuf:"bytes,4,opt,name=gt,proto3" json:"gt,omitempty"`
+	Cond   *Cond              `protobuf:"bytes,5,opt,name=cond,proto3" json:"cond,omitempty"`
+	So     *ShardOps          `protobuf:"bytes,6,opt,name=so,proto3" json:"so,omitempty"`
+	// blob carries an opaque value encoded with codec, used instead of value
+	// whenever codec is set.
+	Blob  []byte `protobuf:"bytes,7,opt,name=blob,proto3" json:"blob,omitempty"`
+	Codec string `protobuf:"bytes,8,opt,name=codec,proto3" json:"codec,omitempty"`
+	// revision selects a past value in reads, and is the revision of the
+	// entries returned by history queries.
+	Revision int64 `protobuf:"varint,9,opt,name=revision,proto3" json:"revision,omitempty"`
+	// limit caps the number of entries returned by history queries and
+	// export pages.
+	Limit int64 `protobuf:"varint,10,opt,name=limit,proto3" json:"limit,omitempty"`
+	// verify has the leader confirm its leadership with a quorum before a
+	// read, instead of relying on its lease.
+	Verify bool `protobuf:"varint,11,opt,name=verify,proto3" json:"verify,omitempty"`
+	// session and seq identify a write of a client session, applied at most
+	// once. seq increases with every write of the session.
+	Session string `protobuf:"bytes,12,opt,name=session,proto3" json:"session,omitempty"`
+	Seq     int64  `protobuf:"varint,13,opt,name=seq,proto3" json:"seq,omitempty"`
+	// time is the clock of the leader when it proposed the command, in unix
+	// nanoseconds, which keeps the sessions of the writes alive.
+	Time int64 `protobuf:"varint,14,opt,name=time,proto3" json:"time,omitempty"`
+	// priority orders the writes queued by a shard leader, and how early
+	// they are throttled: -1 for bulk writes, 0 by default, 1 for admin and
+	// urgent writes.
+	Priority int32 `protobuf:"varint,15,opt,name=priority,proto3" json:"priority,omitempty"`
+	// compare is the transaction of COMPARE commands.
+	Compare *CompareTxn `protobuf:"bytes,16,opt,name=compare,proto3" json:"compare,omitempty"`
+	// min_revision has the reads wait until the node serving them has
+	// applied this revision of its shard.
+	MinRevision int64 `protobuf:"varint,17,opt,name=min_revision,json=minRevision,proto3" json:"min_revision,omitempty"`
+	// consistency is the consistency of a read: linearizable, leader-local
+	// or stale, the read mode of the node if empty.
+	Consistency string `protobuf:"bytes,18,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	// ack is when a write is acknowledged: once applied with a quorum if
+	// empty or quorum, once proposed by the leader if leader.
+	Ack string `protobuf:"bytes,19,opt,name=ack,proto3" json:"ack,omitempty"`
+	// after is the last key of the previous export page, the page holding
+	// the keys after it.
+	After string `protobuf:"bytes,20,opt,name=after,proto3" json:"after,omitempty"`
+	// client is who sent the write, set by the coordinator: the shard
+	// leaders share their proposals fairly between the clients of a priority.
+	Client string `protobuf:"bytes,21,opt,name=client,proto3" json:"client,omitempty"`
+	// ttl is the time to live in milliseconds of the key written, given by
+	// the client; the coordinator turns it into expires.
+	Ttl int64 `protobuf:"varint,22,opt,name=ttl,proto3" json:"ttl,omitempty"`
+	// expires is the unix time in nanoseconds the key written expires at,
+	// never if 0.
+	Expires int64 `protobuf:"varint,23,opt,name=expires,proto3" json:"expires,omitempty"`
+	// signature authenticates the administrative commands of the
+	// coordinators to the store nodes, as ShardOps.signature. Added in
+	// protocol version 16.
+	Signature            []byte   `protobuf:"bytes,24,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedAt             int64    `protobuf:"varint,25,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Command) Reset()         { *m = Command{} }
@@ -99,513 +155,3311 @@ func (m *Command) GetSo() *ShardOps {
 	return nil
 }
 
-type Cond struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value                int64    `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
-}
-
-func (m *Cond) Reset()         { *m = Cond{} }
-func (m *Cond) String() string { return proto.CompactTextString(m) }
-func (*Cond) ProtoMessage()    {}
-func (*Cond) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{1}
-}
-
-func (m *Cond) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Cond.Unmarshal(m, b)
-}
-func (m *Cond) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Cond.Marshal(b, m, deterministic)
-}
-func (m *Cond) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Cond.Merge(m, src)
-}
-func (m *Cond) XXX_Size() int {
-	return xxx_messageInfo_Cond.Size(m)
-}
-func (m *Cond) XXX_DiscardUnknown() {
-	xxx_messageInfo_Cond.DiscardUnknown(m)
+func (m *Command) GetBlob() []byte {
+	if m != nil {
+		return m.Blob
+	}
+	return nil
 }
 
-var xxx_messageInfo_Cond proto.InternalMessageInfo
-
-func (m *Cond) GetKey() string {
+func (m *Command) GetCodec() string {
 	if m != nil {
-		return m.Key
+		return m.Codec
 	}
 	return ""
 }
 
-func (m *Cond) GetValue() int64 {
+func (m *Command) GetRevision() int64 {
 	if m != nil {
-		return m.Value
+		return m.Revision
 	}
 	return 0
 }
 
-// GlobalTransaction captures the info of entire transaction
-type GlobalTransaction struct {
-	Txid string       `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
-	Cmds *RaftCommand `protobuf:"bytes,2,opt,name=cmds,proto3" json:"cmds,omitempty"`
-	// Cohorts consists of leader's of all the shards.
-	// TODO: It can be used for transaction stats etc in the future.
-	Cohorts              []string            `protobuf:"bytes,3,rep,name=cohorts,proto3" json:"cohorts,omitempty"`
-	ShardToCommands      map[int64]*ShardOps `protobuf:"bytes,4,rep,name=shard_to_commands,json=shardToCommands,proto3" json:"shard_to_commands,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Phase                string              `protobuf:"bytes,5,opt,name=phase,proto3" json:"phase,omitempty"`
-	StartTime            int64               `protobuf:"varint,6,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
-}
-
-func (m *GlobalTransaction) Reset()         { *m = GlobalTransaction{} }
-func (m *GlobalTransaction) String() string { return proto.CompactTextString(m) }
-func (*GlobalTransaction) ProtoMessage()    {}
-func (*GlobalTransaction) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{2}
+func (m *Command) GetLimit() int64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
 }
 
-func (m *GlobalTransaction) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GlobalTransaction.Unmarshal(m, b)
-}
-func (m *GlobalTransaction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GlobalTransaction.Marshal(b, m, deterministic)
-}
-func (m *GlobalTransaction) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GlobalTransaction.Merge(m, src)
-}
-func (m *GlobalTransaction) XXX_Size() int {
-	return xxx_messageInfo_GlobalTransaction.Size(m)
-}
-func (m *GlobalTransaction) XXX_DiscardUnknown() {
-	xxx_messageInfo_GlobalTransaction.DiscardUnknown(m)
+func (m *Command) GetVerify() bool {
+	if m != nil {
+		return m.Verify
+	}
+	return false
 }
 
-var xxx_messageInfo_GlobalTransaction proto.InternalMessageInfo
-
-func (m *GlobalTransaction) GetTxid() string {
+func (m *Command) GetSession() string {
 	if m != nil {
-		return m.Txid
+		return m.Session
 	}
 	return ""
 }
 
-func (m *GlobalTransaction) GetCmds() *RaftCommand {
+func (m *Command) GetSeq() int64 {
 	if m != nil {
-		return m.Cmds
+		return m.Seq
 	}
-	return nil
+	return 0
 }
 
-func (m *GlobalTransaction) GetCohorts() []string {
+func (m *Command) GetTime() int64 {
 	if m != nil {
-		return m.Cohorts
+		return m.Time
 	}
-	return nil
+	return 0
 }
 
-func (m *GlobalTransaction) GetShardToCommands() map[int64]*ShardOps {
+func (m *Command) GetPriority() int32 {
 	if m != nil {
-		return m.ShardToCommands
+		return m.Priority
 	}
-	return nil
+	return 0
 }
 
-func (m *GlobalTransaction) GetPhase() string {
+func (m *Command) GetCompare() *CompareTxn {
 	if m != nil {
-		return m.Phase
+		return m.Compare
 	}
-	return ""
+	return nil
 }
 
-func (m *GlobalTransaction) GetStartTime() int64 {
+func (m *Command) GetMinRevision() int64 {
 	if m != nil {
-		return m.StartTime
+		return m.MinRevision
 	}
 	return 0
 }
 
-type TxidMap struct {
-	Map                  map[string]*GlobalTransaction `protobuf:"bytes,1,rep,name=map,proto3" json:"map,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}                      `json:"-"`
-	XXX_unrecognized     []byte                        `json:"-"`
-	XXX_sizecache        int32                         `json:"-"`
+func (m *Command) GetConsistency() string {
+	if m != nil {
+		return m.Consistency
+	}
+	return ""
 }
 
-func (m *TxidMap) Reset()         { *m = TxidMap{} }
-func (m *TxidMap) String() string { return proto.CompactTextString(m) }
-func (*TxidMap) ProtoMessage()    {}
-func (*TxidMap) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{3}
+func (m *Command) GetAck() string {
+	if m != nil {
+		return m.Ack
+	}
+	return ""
 }
 
-func (m *TxidMap) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_TxidMap.Unmarshal(m, b)
-}
-func (m *TxidMap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_TxidMap.Marshal(b, m, deterministic)
-}
-func (m *TxidMap) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_TxidMap.Merge(m, src)
-}
-func (m *TxidMap) XXX_Size() int {
-	return xxx_messageInfo_TxidMap.Size(m)
-}
-func (m *TxidMap) XXX_DiscardUnknown() {
-	xxx_messageInfo_TxidMap.DiscardUnknown(m)
+func (m *Command) GetAfter() string {
+	if m != nil {
+		return m.After
+	}
+	return ""
 }
 
-var xxx_messageInfo_TxidMap proto.InternalMessageInfo
-
-func (m *TxidMap) GetMap() map[string]*GlobalTransaction {
+func (m *Command) GetClient() string {
 	if m != nil {
-		return m.Map
+		return m.Client
 	}
-	return nil
+	return ""
 }
 
-type OpsMap struct {
-	Map                  map[string]*ShardOps `protobuf:"bytes,1,rep,name=map,proto3" json:"map,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+func (m *Command) GetTtl() int64 {
+	if m != nil {
+		return m.Ttl
+	}
+	return 0
 }
 
-func (m *OpsMap) Reset()         { *m = OpsMap{} }
-func (m *OpsMap) String() string { return proto.CompactTextString(m) }
-func (*OpsMap) ProtoMessage()    {}
-func (*OpsMap) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{4}
+func (m *Command) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
 }
 
-func (m *OpsMap) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_OpsMap.Unmarshal(m, b)
-}
-func (m *OpsMap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_OpsMap.Marshal(b, m, deterministic)
-}
-func (m *OpsMap) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_OpsMap.Merge(m, src)
-}
-func (m *OpsMap) XXX_Size() int {
-	return xxx_messageInfo_OpsMap.Size(m)
-}
-func (m *OpsMap) XXX_DiscardUnknown() {
-	xxx_messageInfo_OpsMap.DiscardUnknown(m)
+func (m *Command) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
 }
 
-var xxx_messageInfo_OpsMap proto.InternalMessageInfo
-
-func (m *OpsMap) GetMap() map[string]*ShardOps {
+func (m *Command) GetSignedAt() int64 {
 	if m != nil {
-		return m.Map
+		return m.SignedAt
 	}
-	return nil
+	return 0
 }
 
-type ShardOps struct {
-	Txid                 string       `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
-	MasterKey            string       `protobuf:"bytes,2,opt,name=master_key,json=masterKey,proto3" json:"master_key,omitempty"`
-	Cmds                 *RaftCommand `protobuf:"bytes,3,opt,name=cmds,proto3" json:"cmds,omitempty"`
-	Phase                string       `protobuf:"bytes,4,opt,name=phase,proto3" json:"phase,omitempty"`
-	ReadOnly             bool         `protobuf:"varint,5,opt,name=readOnly,proto3" json:"readOnly,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+// Compare compares the value, version, mod or create revision of a key,
+// those of a missing key being 0, with value: target result value.
+type Compare struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// target is value, version, mod_revision or create_revision.
+	Target string `protobuf:"bytes,2,opt,name=target,proto3" json:"target,omitempty"`
+	// result is =, !=, < or >.
+	Result               string   `protobuf:"bytes,3,opt,name=result,proto3" json:"result,omitempty"`
+	Value                int64    `protobuf:"varint,4,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *ShardOps) Reset()         { *m = ShardOps{} }
-func (m *ShardOps) String() string { return proto.CompactTextString(m) }
-func (*ShardOps) ProtoMessage()    {}
-func (*ShardOps) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{5}
+func (m *Compare) Reset()         { *m = Compare{} }
+func (m *Compare) String() string { return proto.CompactTextString(m) }
+func (*Compare) ProtoMessage()    {}
+func (*Compare) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{1}
 }
 
-func (m *ShardOps) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ShardOps.Unmarshal(m, b)
+func (m *Compare) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Compare.Unmarshal(m, b)
 }
-func (m *ShardOps) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ShardOps.Marshal(b, m, deterministic)
+func (m *Compare) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Compare.Marshal(b, m, deterministic)
 }
-func (m *ShardOps) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ShardOps.Merge(m, src)
+func (m *Compare) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Compare.Merge(m, src)
 }
-func (m *ShardOps) XXX_Size() int {
-	return xxx_messageInfo_ShardOps.Size(m)
+func (m *Compare) XXX_Size() int {
+	return xxx_messageInfo_Compare.Size(m)
 }
-func (m *ShardOps) XXX_DiscardUnknown() {
-	xxx_messageInfo_ShardOps.DiscardUnknown(m)
+func (m *Compare) XXX_DiscardUnknown() {
+	xxx_messageInfo_Compare.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ShardOps proto.InternalMessageInfo
+var xxx_messageInfo_Compare proto.InternalMessageInfo
 
-func (m *ShardOps) GetTxid() string {
+func (m *Compare) GetKey() string {
 	if m != nil {
-		return m.Txid
+		return m.Key
 	}
 	return ""
 }
 
-func (m *ShardOps) GetMasterKey() string {
+func (m *Compare) GetTarget() string {
 	if m != nil {
-		return m.MasterKey
+		return m.Target
 	}
 	return ""
 }
 
-func (m *ShardOps) GetCmds() *RaftCommand {
-	if m != nil {
-		return m.Cmds
-	}
-	return nil
-}
-
-func (m *ShardOps) GetPhase() string {
+func (m *Compare) GetResult() string {
 	if m != nil {
-		return m.Phase
+		return m.Result
 	}
 	return ""
 }
 
-func (m *ShardOps) GetReadOnly() bool {
+func (m *Compare) GetValue() int64 {
 	if m != nil {
-		return m.ReadOnly
+		return m.Value
 	}
-	return false
+	return 0
 }
 
-type RPCResponse struct {
-	Status               int32      `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
-	Value                int64      `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
-	Addr                 string     `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
-	Phase                string     `protobuf:"bytes,4,opt,name=phase,proto3" json:"phase,omitempty"`
-	Commands             []*Command `protobuf:"bytes,5,rep,name=commands,proto3" json:"commands,omitempty"`
+// CompareTxn applies the gets, sets and deletes of success if all the
+// comparisons hold, those of failure otherwise, atomically on a shard.
+type CompareTxn struct {
+	Compare              []*Compare `protobuf:"bytes,1,rep,name=compare,proto3" json:"compare,omitempty"`
+	Success              []*Command `protobuf:"bytes,2,rep,name=success,proto3" json:"success,omitempty"`
+	Failure              []*Command `protobuf:"bytes,3,rep,name=failure,proto3" json:"failure,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
 	XXX_unrecognized     []byte     `json:"-"`
 	XXX_sizecache        int32      `json:"-"`
 }
 
-func (m *RPCResponse) Reset()         { *m = RPCResponse{} }
-func (m *RPCResponse) String() string { return proto.CompactTextString(m) }
-func (*RPCResponse) ProtoMessage()    {}
-func (*RPCResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{6}
+func (m *CompareTxn) Reset()         { *m = CompareTxn{} }
+func (m *CompareTxn) String() string { return proto.CompactTextString(m) }
+func (*CompareTxn) ProtoMessage()    {}
+func (*CompareTxn) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{2}
 }
 
-func (m *RPCResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RPCResponse.Unmarshal(m, b)
+func (m *CompareTxn) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompareTxn.Unmarshal(m, b)
 }
-func (m *RPCResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RPCResponse.Marshal(b, m, deterministic)
+func (m *CompareTxn) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompareTxn.Marshal(b, m, deterministic)
 }
-func (m *RPCResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RPCResponse.Merge(m, src)
+func (m *CompareTxn) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompareTxn.Merge(m, src)
 }
-func (m *RPCResponse) XXX_Size() int {
-	return xxx_messageInfo_RPCResponse.Size(m)
+func (m *CompareTxn) XXX_Size() int {
+	return xxx_messageInfo_CompareTxn.Size(m)
 }
-func (m *RPCResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_RPCResponse.DiscardUnknown(m)
+func (m *CompareTxn) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompareTxn.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_RPCResponse proto.InternalMessageInfo
-
-func (m *RPCResponse) GetStatus() int32 {
-	if m != nil {
-		return m.Status
-	}
-	return 0
-}
+var xxx_messageInfo_CompareTxn proto.InternalMessageInfo
 
-func (m *RPCResponse) GetValue() int64 {
+func (m *CompareTxn) GetCompare() []*Compare {
 	if m != nil {
-		return m.Value
+		return m.Compare
 	}
-	return 0
+	return nil
 }
 
-func (m *RPCResponse) GetAddr() string {
+func (m *CompareTxn) GetSuccess() []*Command {
 	if m != nil {
-		return m.Addr
+		return m.Success
 	}
-	return ""
+	return nil
 }
 
-func (m *RPCResponse) GetPhase() string {
+func (m *CompareTxn) GetFailure() []*Command {
 	if m != nil {
-		return m.Phase
+		return m.Failure
 	}
-	return ""
+	return nil
+}
+
+type Cond struct {
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value int64  `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	// mod_revision, when set, is compared with the mod revision of the key
+	// instead of comparing value.
+	ModRevision          int64    `protobuf:"varint,3,opt,name=mod_revision,json=modRevision,proto3" json:"mod_revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Cond) Reset()         { *m = Cond{} }
+func (m *Cond) String() string { return proto.CompactTextString(m) }
+func (*Cond) ProtoMessage()    {}
+func (*Cond) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{3}
+}
+
+func (m *Cond) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Cond.Unmarshal(m, b)
+}
+func (m *Cond) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Cond.Marshal(b, m, deterministic)
+}
+func (m *Cond) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Cond.Merge(m, src)
+}
+func (m *Cond) XXX_Size() int {
+	return xxx_messageInfo_Cond.Size(m)
+}
+func (m *Cond) XXX_DiscardUnknown() {
+	xxx_messageInfo_Cond.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Cond proto.InternalMessageInfo
+
+func (m *Cond) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *Cond) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *Cond) GetModRevision() int64 {
+	if m != nil {
+		return m.ModRevision
+	}
+	return 0
+}
+
+// KeyMeta is the metadata kept for every key. Revisions are raft log indexes
+// of the shard the key belongs to.
+type KeyMeta struct {
+	CreateRevision int64 `protobuf:"varint,1,opt,name=create_revision,json=createRevision,proto3" json:"create_revision,omitempty"`
+	ModRevision    int64 `protobuf:"varint,2,opt,name=mod_revision,json=modRevision,proto3" json:"mod_revision,omitempty"`
+	// version counts the writes since the key was created.
+	Version              int64    `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	Size                 int64    `protobuf:"varint,4,opt,name=size,proto3" json:"size,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyMeta) Reset()         { *m = KeyMeta{} }
+func (m *KeyMeta) String() string { return proto.CompactTextString(m) }
+func (*KeyMeta) ProtoMessage()    {}
+func (*KeyMeta) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{4}
+}
+
+func (m *KeyMeta) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyMeta.Unmarshal(m, b)
+}
+func (m *KeyMeta) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyMeta.Marshal(b, m, deterministic)
+}
+func (m *KeyMeta) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyMeta.Merge(m, src)
+}
+func (m *KeyMeta) XXX_Size() int {
+	return xxx_messageInfo_KeyMeta.Size(m)
+}
+func (m *KeyMeta) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyMeta.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyMeta proto.InternalMessageInfo
+
+func (m *KeyMeta) GetCreateRevision() int64 {
+	if m != nil {
+		return m.CreateRevision
+	}
+	return 0
+}
+
+func (m *KeyMeta) GetModRevision() int64 {
+	if m != nil {
+		return m.ModRevision
+	}
+	return 0
+}
+
+func (m *KeyMeta) GetVersion() int64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *KeyMeta) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+// KVEntry is a key with its value and metadata, as found in snapshots,
+// imports and exports. blob and codec are set instead of value for codec
+// encoded values.
+type KVEntry struct {
+	Key   string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value int64    `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Blob  []byte   `protobuf:"bytes,3,opt,name=blob,proto3" json:"blob,omitempty"`
+	Codec string   `protobuf:"bytes,4,opt,name=codec,proto3" json:"codec,omitempty"`
+	Meta  *KeyMeta `protobuf:"bytes,5,opt,name=meta,proto3" json:"meta,omitempty"`
+	// member_version is set instead of key on the protocol versions of the
+	// members of snapshots.
+	MemberVersion *MemberVersion `protobuf:"bytes,6,opt,name=member_version,json=memberVersion,proto3" json:"member_version,omitempty"`
+	// session is set instead of key on the client sessions of snapshots.
+	Session *Session `protobuf:"bytes,7,opt,name=session,proto3" json:"session,omitempty"`
+	// state_hash is set instead of key on the trailer of snapshots, the hash
+	// of their keys.
+	StateHash *StateHash `protobuf:"bytes,8,opt,name=state_hash,json=stateHash,proto3" json:"state_hash,omitempty"`
+	// expires is the unix time in nanoseconds the key expires at, never if 0.
+	Expires              int64    `protobuf:"varint,9,opt,name=expires,proto3" json:"expires,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KVEntry) Reset()         { *m = KVEntry{} }
+func (m *KVEntry) String() string { return proto.CompactTextString(m) }
+func (*KVEntry) ProtoMessage()    {}
+func (*KVEntry) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{5}
+}
+
+func (m *KVEntry) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KVEntry.Unmarshal(m, b)
+}
+func (m *KVEntry) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KVEntry.Marshal(b, m, deterministic)
+}
+func (m *KVEntry) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KVEntry.Merge(m, src)
+}
+func (m *KVEntry) XXX_Size() int {
+	return xxx_messageInfo_KVEntry.Size(m)
+}
+func (m *KVEntry) XXX_DiscardUnknown() {
+	xxx_messageInfo_KVEntry.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KVEntry proto.InternalMessageInfo
+
+func (m *KVEntry) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KVEntry) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *KVEntry) GetBlob() []byte {
+	if m != nil {
+		return m.Blob
+	}
+	return nil
+}
+
+func (m *KVEntry) GetCodec() string {
+	if m != nil {
+		return m.Codec
+	}
+	return ""
+}
+
+func (m *KVEntry) GetMeta() *KeyMeta {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+func (m *KVEntry) GetMemberVersion() *MemberVersion {
+	if m != nil {
+		return m.MemberVersion
+	}
+	return nil
+}
+
+func (m *KVEntry) GetSession() *Session {
+	if m != nil {
+		return m.Session
+	}
+	return nil
+}
+
+func (m *KVEntry) GetStateHash() *StateHash {
+	if m != nil {
+		return m.StateHash
+	}
+	return nil
+}
+
+func (m *KVEntry) GetExpires() int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return 0
+}
+
+// MemberVersion is the protocol version announced by a member of a raft
+// group.
+type MemberVersion struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Version              int32    `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MemberVersion) Reset()         { *m = MemberVersion{} }
+func (m *MemberVersion) String() string { return proto.CompactTextString(m) }
+func (*MemberVersion) ProtoMessage()    {}
+func (*MemberVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{6}
+}
+
+func (m *MemberVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MemberVersion.Unmarshal(m, b)
+}
+func (m *MemberVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MemberVersion.Marshal(b, m, deterministic)
+}
+func (m *MemberVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MemberVersion.Merge(m, src)
+}
+func (m *MemberVersion) XXX_Size() int {
+	return xxx_messageInfo_MemberVersion.Size(m)
+}
+func (m *MemberVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_MemberVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MemberVersion proto.InternalMessageInfo
+
+func (m *MemberVersion) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *MemberVersion) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+// MerkleRequest reads the Merkle tree of the keys of a replica built at
+// index: the hashes of its nodes, or the entries of its leaves, the buckets
+// of keys. Repairs take source, the replica to repair from.
+type MerkleRequest struct {
+	Index   int64   `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Nodes   []int32 `protobuf:"varint,2,rep,packed,name=nodes,proto3" json:"nodes,omitempty"`
+	Buckets []int32 `protobuf:"varint,3,rep,packed,name=buckets,proto3" json:"buckets,omitempty"`
+	Source  string  `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	// signature authenticates the repairs of the coordinators, as
+	// ShardOps.signature. Added in protocol version 16.
+	Signature            []byte   `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedAt             int64    `protobuf:"varint,6,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MerkleRequest) Reset()         { *m = MerkleRequest{} }
+func (m *MerkleRequest) String() string { return proto.CompactTextString(m) }
+func (*MerkleRequest) ProtoMessage()    {}
+func (*MerkleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{7}
+}
+
+func (m *MerkleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleRequest.Unmarshal(m, b)
+}
+func (m *MerkleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleRequest.Marshal(b, m, deterministic)
+}
+func (m *MerkleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleRequest.Merge(m, src)
+}
+func (m *MerkleRequest) XXX_Size() int {
+	return xxx_messageInfo_MerkleRequest.Size(m)
+}
+func (m *MerkleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleRequest proto.InternalMessageInfo
+
+func (m *MerkleRequest) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *MerkleRequest) GetNodes() []int32 {
+	if m != nil {
+		return m.Nodes
+	}
+	return nil
+}
+
+func (m *MerkleRequest) GetBuckets() []int32 {
+	if m != nil {
+		return m.Buckets
+	}
+	return nil
+}
+
+func (m *MerkleRequest) GetSource() string {
+	if m != nil {
+		return m.Source
+	}
+	return ""
+}
+
+func (m *MerkleRequest) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *MerkleRequest) GetSignedAt() int64 {
+	if m != nil {
+		return m.SignedAt
+	}
+	return 0
+}
+
+// MerkleReply holds the hashes of the nodes or the entries of the buckets
+// of a MerkleRequest.
+type MerkleReply struct {
+	Index                int64      `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Hashes               []uint64   `protobuf:"varint,2,rep,packed,name=hashes,proto3" json:"hashes,omitempty"`
+	Entries              []*KVEntry `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *MerkleReply) Reset()         { *m = MerkleReply{} }
+func (m *MerkleReply) String() string { return proto.CompactTextString(m) }
+func (*MerkleReply) ProtoMessage()    {}
+func (*MerkleReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{8}
+}
+
+func (m *MerkleReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MerkleReply.Unmarshal(m, b)
+}
+func (m *MerkleReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MerkleReply.Marshal(b, m, deterministic)
+}
+func (m *MerkleReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MerkleReply.Merge(m, src)
+}
+func (m *MerkleReply) XXX_Size() int {
+	return xxx_messageInfo_MerkleReply.Size(m)
+}
+func (m *MerkleReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_MerkleReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MerkleReply proto.InternalMessageInfo
+
+func (m *MerkleReply) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *MerkleReply) GetHashes() []uint64 {
+	if m != nil {
+		return m.Hashes
+	}
+	return nil
+}
+
+func (m *MerkleReply) GetEntries() []*KVEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+// StateHash is the hash of the keys of a replica of a shard, with their
+// values and metadata, once the raft entry at index is applied.
+type StateHash struct {
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Hash                 uint64   `protobuf:"varint,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	Node                 string   `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StateHash) Reset()         { *m = StateHash{} }
+func (m *StateHash) String() string { return proto.CompactTextString(m) }
+func (*StateHash) ProtoMessage()    {}
+func (*StateHash) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{9}
+}
+
+func (m *StateHash) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StateHash.Unmarshal(m, b)
+}
+func (m *StateHash) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StateHash.Marshal(b, m, deterministic)
+}
+func (m *StateHash) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StateHash.Merge(m, src)
+}
+func (m *StateHash) XXX_Size() int {
+	return xxx_messageInfo_StateHash.Size(m)
+}
+func (m *StateHash) XXX_DiscardUnknown() {
+	xxx_messageInfo_StateHash.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StateHash proto.InternalMessageInfo
+
+func (m *StateHash) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *StateHash) GetHash() uint64 {
+	if m != nil {
+		return m.Hash
+	}
+	return 0
+}
+
+func (m *StateHash) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+// Session is the dedup state of a client session: the last write applied
+// and its outcome, replied again when the write is retried.
+type Session struct {
+	Id                   string       `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	LastSeq              int64        `protobuf:"varint,2,opt,name=last_seq,json=lastSeq,proto3" json:"last_seq,omitempty"`
+	LastActive           int64        `protobuf:"varint,3,opt,name=last_active,json=lastActive,proto3" json:"last_active,omitempty"`
+	Reply                *RPCResponse `protobuf:"bytes,4,opt,name=reply,proto3" json:"reply,omitempty"`
+	Error                string       `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Session) Reset()         { *m = Session{} }
+func (m *Session) String() string { return proto.CompactTextString(m) }
+func (*Session) ProtoMessage()    {}
+func (*Session) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{10}
+}
+
+func (m *Session) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Session.Unmarshal(m, b)
+}
+func (m *Session) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Session.Marshal(b, m, deterministic)
+}
+func (m *Session) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Session.Merge(m, src)
+}
+func (m *Session) XXX_Size() int {
+	return xxx_messageInfo_Session.Size(m)
+}
+func (m *Session) XXX_DiscardUnknown() {
+	xxx_messageInfo_Session.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Session proto.InternalMessageInfo
+
+func (m *Session) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Session) GetLastSeq() int64 {
+	if m != nil {
+		return m.LastSeq
+	}
+	return 0
+}
+
+func (m *Session) GetLastActive() int64 {
+	if m != nil {
+		return m.LastActive
+	}
+	return 0
+}
+
+func (m *Session) GetReply() *RPCResponse {
+	if m != nil {
+		return m.Reply
+	}
+	return nil
+}
+
+func (m *Session) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// ImportChunk is a batch of entries staged on a shard leader by a bulk import.
+type ImportChunk struct {
+	Id      string     `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Entries []*KVEntry `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	// signature authenticates the imports of the coordinators, as
+	// ShardOps.signature. Added in protocol version 16.
+	Signature            []byte   `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedAt             int64    `protobuf:"varint,4,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ImportChunk) Reset()         { *m = ImportChunk{} }
+func (m *ImportChunk) String() string { return proto.CompactTextString(m) }
+func (*ImportChunk) ProtoMessage()    {}
+func (*ImportChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{11}
+}
+
+func (m *ImportChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ImportChunk.Unmarshal(m, b)
+}
+func (m *ImportChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ImportChunk.Marshal(b, m, deterministic)
+}
+func (m *ImportChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ImportChunk.Merge(m, src)
+}
+func (m *ImportChunk) XXX_Size() int {
+	return xxx_messageInfo_ImportChunk.Size(m)
+}
+func (m *ImportChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_ImportChunk.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ImportChunk proto.InternalMessageInfo
+
+func (m *ImportChunk) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ImportChunk) GetEntries() []*KVEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *ImportChunk) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *ImportChunk) GetSignedAt() int64 {
+	if m != nil {
+		return m.SignedAt
+	}
+	return 0
+}
+
+// GlobalTransaction captures the info of entire transaction
+type GlobalTransaction struct {
+	Txid string       `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	Cmds *RaftCommand `protobuf:"bytes,2,opt,name=cmds,proto3" json:"cmds,omitempty"`
+	// Cohorts consists of leader's of all the shards.
+	// TODO: It can be used for transaction stats etc in the future.
+	Cohorts              []string            `protobuf:"bytes,3,rep,name=cohorts,proto3" json:"cohorts,omitempty"`
+	ShardToCommands      map[int64]*ShardOps `protobuf:"bytes,4,rep,name=shard_to_commands,json=shardToCommands,proto3" json:"shard_to_commands,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Phase                string              `protobuf:"bytes,5,opt,name=phase,proto3" json:"phase,omitempty"`
+	StartTime            int64               `protobuf:"varint,6,opt,name=start_time,json=startTime,proto3" json:"start_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *GlobalTransaction) Reset()         { *m = GlobalTransaction{} }
+func (m *GlobalTransaction) String() string { return proto.CompactTextString(m) }
+func (*GlobalTransaction) ProtoMessage()    {}
+func (*GlobalTransaction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{12}
+}
+
+func (m *GlobalTransaction) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GlobalTransaction.Unmarshal(m, b)
+}
+func (m *GlobalTransaction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GlobalTransaction.Marshal(b, m, deterministic)
+}
+func (m *GlobalTransaction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GlobalTransaction.Merge(m, src)
+}
+func (m *GlobalTransaction) XXX_Size() int {
+	return xxx_messageInfo_GlobalTransaction.Size(m)
+}
+func (m *GlobalTransaction) XXX_DiscardUnknown() {
+	xxx_messageInfo_GlobalTransaction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GlobalTransaction proto.InternalMessageInfo
+
+func (m *GlobalTransaction) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *GlobalTransaction) GetCmds() *RaftCommand {
+	if m != nil {
+		return m.Cmds
+	}
+	return nil
+}
+
+func (m *GlobalTransaction) GetCohorts() []string {
+	if m != nil {
+		return m.Cohorts
+	}
+	return nil
+}
+
+func (m *GlobalTransaction) GetShardToCommands() map[int64]*ShardOps {
+	if m != nil {
+		return m.ShardToCommands
+	}
+	return nil
+}
+
+func (m *GlobalTransaction) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
+}
+
+func (m *GlobalTransaction) GetStartTime() int64 {
+	if m != nil {
+		return m.StartTime
+	}
+	return 0
+}
+
+type TxidMap struct {
+	Map      map[string]*GlobalTransaction `protobuf:"bytes,1,rep,name=map,proto3" json:"map,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Replicas map[int64]int32               `protobuf:"bytes,2,rep,name=replicas,proto3" json:"replicas,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// policy holds the system keys of the access policy.
+	Policy map[string][]byte `protobuf:"bytes,3,rep,name=policy,proto3" json:"policy,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// topology is the json topology the shards are reconciled toward.
+	Topology []byte `protobuf:"bytes,4,opt,name=topology,proto3" json:"topology,omitempty"`
+	// transforms holds the system keys of the value transforms.
+	Transforms map[string][]byte `protobuf:"bytes,5,rep,name=transforms,proto3" json:"transforms,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// ttls holds the system keys of the ttl policies.
+	Ttls map[string][]byte `protobuf:"bytes,6,rep,name=ttls,proto3" json:"ttls,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// versions holds the protocol versions of the coordinators.
+	Versions             map[string]int32 `protobuf:"bytes,7,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *TxidMap) Reset()         { *m = TxidMap{} }
+func (m *TxidMap) String() string { return proto.CompactTextString(m) }
+func (*TxidMap) ProtoMessage()    {}
+func (*TxidMap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{13}
+}
+
+func (m *TxidMap) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxidMap.Unmarshal(m, b)
+}
+func (m *TxidMap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxidMap.Marshal(b, m, deterministic)
+}
+func (m *TxidMap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxidMap.Merge(m, src)
+}
+func (m *TxidMap) XXX_Size() int {
+	return xxx_messageInfo_TxidMap.Size(m)
+}
+func (m *TxidMap) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxidMap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxidMap proto.InternalMessageInfo
+
+func (m *TxidMap) GetMap() map[string]*GlobalTransaction {
+	if m != nil {
+		return m.Map
+	}
+	return nil
+}
+
+func (m *TxidMap) GetReplicas() map[int64]int32 {
+	if m != nil {
+		return m.Replicas
+	}
+	return nil
+}
+
+func (m *TxidMap) GetPolicy() map[string][]byte {
+	if m != nil {
+		return m.Policy
+	}
+	return nil
+}
+
+func (m *TxidMap) GetTopology() []byte {
+	if m != nil {
+		return m.Topology
+	}
+	return nil
+}
+
+func (m *TxidMap) GetTransforms() map[string][]byte {
+	if m != nil {
+		return m.Transforms
+	}
+	return nil
+}
+
+func (m *TxidMap) GetTtls() map[string][]byte {
+	if m != nil {
+		return m.Ttls
+	}
+	return nil
+}
+
+func (m *TxidMap) GetVersions() map[string]int32 {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+type OpsMap struct {
+	Map                  map[string]*ShardOps `protobuf:"bytes,1,rep,name=map,proto3" json:"map,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *OpsMap) Reset()         { *m = OpsMap{} }
+func (m *OpsMap) String() string { return proto.CompactTextString(m) }
+func (*OpsMap) ProtoMessage()    {}
+func (*OpsMap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{14}
+}
+
+func (m *OpsMap) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpsMap.Unmarshal(m, b)
+}
+func (m *OpsMap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpsMap.Marshal(b, m, deterministic)
+}
+func (m *OpsMap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpsMap.Merge(m, src)
+}
+func (m *OpsMap) XXX_Size() int {
+	return xxx_messageInfo_OpsMap.Size(m)
+}
+func (m *OpsMap) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpsMap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpsMap proto.InternalMessageInfo
+
+func (m *OpsMap) GetMap() map[string]*ShardOps {
+	if m != nil {
+		return m.Map
+	}
+	return nil
+}
+
+type ShardOps struct {
+	Txid      string       `protobuf:"bytes,1,opt,name=txid,proto3" json:"txid,omitempty"`
+	MasterKey string       `protobuf:"bytes,2,opt,name=master_key,json=masterKey,proto3" json:"master_key,omitempty"`
+	Cmds      *RaftCommand `protobuf:"bytes,3,opt,name=cmds,proto3" json:"cmds,omitempty"`
+	Phase     string       `protobuf:"bytes,4,opt,name=phase,proto3" json:"phase,omitempty"`
+	ReadOnly  bool         `protobuf:"varint,5,opt,name=readOnly,proto3" json:"readOnly,omitempty"`
+	// signature authenticates the transaction messages of the coordinators
+	// to the shard leaders, as the HMAC-SHA256 of the message keyed by the
+	// cluster secret, signed at signed_at in unix nanoseconds.
+	Signature []byte `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedAt  int64  `protobuf:"varint,7,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	// outcome_reply has the shard leader reply the one-phase commits of
+	// unknown outcome with phase OutcomeUnknown rather than an error, as
+	// net/rpc drops the reply of the failed calls. Added in protocol
+	// version 15.
+	OutcomeReply         bool     `protobuf:"varint,8,opt,name=outcome_reply,json=outcomeReply,proto3" json:"outcome_reply,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShardOps) Reset()         { *m = ShardOps{} }
+func (m *ShardOps) String() string { return proto.CompactTextString(m) }
+func (*ShardOps) ProtoMessage()    {}
+func (*ShardOps) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{15}
+}
+
+func (m *ShardOps) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShardOps.Unmarshal(m, b)
+}
+func (m *ShardOps) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShardOps.Marshal(b, m, deterministic)
+}
+func (m *ShardOps) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShardOps.Merge(m, src)
+}
+func (m *ShardOps) XXX_Size() int {
+	return xxx_messageInfo_ShardOps.Size(m)
+}
+func (m *ShardOps) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShardOps.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShardOps proto.InternalMessageInfo
+
+func (m *ShardOps) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *ShardOps) GetMasterKey() string {
+	if m != nil {
+		return m.MasterKey
+	}
+	return ""
+}
+
+func (m *ShardOps) GetCmds() *RaftCommand {
+	if m != nil {
+		return m.Cmds
+	}
+	return nil
+}
+
+func (m *ShardOps) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
+}
+
+func (m *ShardOps) GetReadOnly() bool {
+	if m != nil {
+		return m.ReadOnly
+	}
+	return false
+}
+
+func (m *ShardOps) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *ShardOps) GetSignedAt() int64 {
+	if m != nil {
+		return m.SignedAt
+	}
+	return 0
+}
+
+func (m *ShardOps) GetOutcomeReply() bool {
+	if m != nil {
+		return m.OutcomeReply
+	}
+	return false
+}
+
+type RPCResponse struct {
+	Status   int32             `protobuf:"varint,1,opt,name=status,proto3" json:"status,omitempty"`
+	Value    int64             `protobuf:"varint,2,opt,name=value,proto3" json:"value,omitempty"`
+	Addr     string            `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
+	Phase    string            `protobuf:"bytes,4,opt,name=phase,proto3" json:"phase,omitempty"`
+	Commands []*Command        `protobuf:"bytes,5,rep,name=commands,proto3" json:"commands,omitempty"`
+	Blob     []byte            `protobuf:"bytes,6,opt,name=blob,proto3" json:"blob,omitempty"`
+	Codec    string            `protobuf:"bytes,7,opt,name=codec,proto3" json:"codec,omitempty"`
+	Meta     *KeyMeta          `protobuf:"bytes,8,opt,name=meta,proto3" json:"meta,omitempty"`
+	Entries  []*KVEntry        `protobuf:"bytes,9,rep,name=entries,proto3" json:"entries,omitempty"`
+	Usage    []*NamespaceUsage `protobuf:"bytes,10,rep,name=usage,proto3" json:"usage,omitempty"`
+	SlowOps  []*SlowOp         `protobuf:"bytes,11,rep,name=slow_ops,json=slowOps,proto3" json:"slow_ops,omitempty"`
+	Stats    map[string]string `protobuf:"bytes,12,rep,name=stats,proto3" json:"stats,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Members  []*GroupMembers   `protobuf:"bytes,13,rep,name=members,proto3" json:"members,omitempty"`
+	// txids are the transactions holding locks on the keys of a snapshot read.
+	Txids []string   `protobuf:"bytes,14,rep,name=txids,proto3" json:"txids,omitempty"`
+	Locks []*KeyLock `protobuf:"bytes,15,rep,name=locks,proto3" json:"locks,omitempty"`
+	Node  *NodeInfo  `protobuf:"bytes,16,opt,name=node,proto3" json:"node,omitempty"`
+	// compact_revision is the revision after which the changes are kept for
+	// the watchers.
+	CompactRevision int64 `protobuf:"varint,17,opt,name=compact_revision,json=compactRevision,proto3" json:"compact_revision,omitempty"`
+	// revision is the revision of a write, the raft index of its entry, or
+	// the revision applied by the node serving a read.
+	Revision    int64          `protobuf:"varint,18,opt,name=revision,proto3" json:"revision,omitempty"`
+	StateHashes []*StateHash   `protobuf:"bytes,19,rep,name=state_hashes,json=stateHashes,proto3" json:"state_hashes,omitempty"`
+	ShardStats  *ShardStats    `protobuf:"bytes,20,opt,name=shard_stats,json=shardStats,proto3" json:"shard_stats,omitempty"`
+	Results     []*WriteResult `protobuf:"bytes,21,rep,name=results,proto3" json:"results,omitempty"`
+	Read        *ReadInfo      `protobuf:"bytes,22,opt,name=read,proto3" json:"read,omitempty"`
+	// cluster_id is the cluster of the raft group a node joined.
+	ClusterId string `protobuf:"bytes,23,opt,name=cluster_id,json=clusterId,proto3" json:"cluster_id,omitempty"`
+	// lock is the key lock a read or write failed on, replied with no error
+	// to the callers setting RaftCommand.lock_reply.
+	Lock                 *LockError `protobuf:"bytes,24,opt,name=lock,proto3" json:"lock,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *RPCResponse) Reset()         { *m = RPCResponse{} }
+func (m *RPCResponse) String() string { return proto.CompactTextString(m) }
+func (*RPCResponse) ProtoMessage()    {}
+func (*RPCResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{16}
+}
+
+func (m *RPCResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RPCResponse.Unmarshal(m, b)
+}
+func (m *RPCResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RPCResponse.Marshal(b, m, deterministic)
+}
+func (m *RPCResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RPCResponse.Merge(m, src)
+}
+func (m *RPCResponse) XXX_Size() int {
+	return xxx_messageInfo_RPCResponse.Size(m)
+}
+func (m *RPCResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RPCResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RPCResponse proto.InternalMessageInfo
+
+func (m *RPCResponse) GetStatus() int32 {
+	if m != nil {
+		return m.Status
+	}
+	return 0
+}
+
+func (m *RPCResponse) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+func (m *RPCResponse) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *RPCResponse) GetPhase() string {
+	if m != nil {
+		return m.Phase
+	}
+	return ""
 }
 
 func (m *RPCResponse) GetCommands() []*Command {
 	if m != nil {
-		return m.Commands
+		return m.Commands
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetBlob() []byte {
+	if m != nil {
+		return m.Blob
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetCodec() string {
+	if m != nil {
+		return m.Codec
+	}
+	return ""
+}
+
+func (m *RPCResponse) GetMeta() *KeyMeta {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetEntries() []*KVEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetUsage() []*NamespaceUsage {
+	if m != nil {
+		return m.Usage
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetSlowOps() []*SlowOp {
+	if m != nil {
+		return m.SlowOps
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetStats() map[string]string {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetMembers() []*GroupMembers {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetTxids() []string {
+	if m != nil {
+		return m.Txids
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetLocks() []*KeyLock {
+	if m != nil {
+		return m.Locks
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetNode() *NodeInfo {
+	if m != nil {
+		return m.Node
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetCompactRevision() int64 {
+	if m != nil {
+		return m.CompactRevision
+	}
+	return 0
+}
+
+func (m *RPCResponse) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *RPCResponse) GetStateHashes() []*StateHash {
+	if m != nil {
+		return m.StateHashes
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetShardStats() *ShardStats {
+	if m != nil {
+		return m.ShardStats
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetResults() []*WriteResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetRead() *ReadInfo {
+	if m != nil {
+		return m.Read
+	}
+	return nil
+}
+
+func (m *RPCResponse) GetClusterId() string {
+	if m != nil {
+		return m.ClusterId
+	}
+	return ""
+}
+
+func (m *RPCResponse) GetLock() *LockError {
+	if m != nil {
+		return m.Lock
+	}
+	return nil
+}
+
+// LockError is a key lock held for longer than the lock timeout, with its
+// holder, empty for a single write, and the estimated wait until it is
+// released, see common.LockError.
+type LockError struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Holder               string   `protobuf:"bytes,2,opt,name=holder,proto3" json:"holder,omitempty"`
+	Node                 string   `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`
+	HeldForMs            int64    `protobuf:"varint,4,opt,name=held_for_ms,json=heldForMs,proto3" json:"held_for_ms,omitempty"`
+	RetryAfterMs         int64    `protobuf:"varint,5,opt,name=retry_after_ms,json=retryAfterMs,proto3" json:"retry_after_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LockError) Reset()         { *m = LockError{} }
+func (m *LockError) String() string { return proto.CompactTextString(m) }
+func (*LockError) ProtoMessage()    {}
+func (*LockError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{17}
+}
+
+func (m *LockError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LockError.Unmarshal(m, b)
+}
+func (m *LockError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LockError.Marshal(b, m, deterministic)
+}
+func (m *LockError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LockError.Merge(m, src)
+}
+func (m *LockError) XXX_Size() int {
+	return xxx_messageInfo_LockError.Size(m)
+}
+func (m *LockError) XXX_DiscardUnknown() {
+	xxx_messageInfo_LockError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LockError proto.InternalMessageInfo
+
+func (m *LockError) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *LockError) GetHolder() string {
+	if m != nil {
+		return m.Holder
+	}
+	return ""
+}
+
+func (m *LockError) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *LockError) GetHeldForMs() int64 {
+	if m != nil {
+		return m.HeldForMs
+	}
+	return 0
+}
+
+func (m *LockError) GetRetryAfterMs() int64 {
+	if m != nil {
+		return m.RetryAfterMs
+	}
+	return 0
+}
+
+// Error is the reply of the failed requests of the clients accepting
+// protobuf replies. code is the HTTP status. leader is the raft address of
+// the coordinator leader, set on 421 Misdirected Request for the requests
+// only the leader serves. The conflict fields describe the conflicts of
+// transactions, failed with 409, and retry_backoff_ms how long to wait
+// before retrying them. The conflict fields, lock_node and lock_held_for_ms
+// also describe the key locks held past the lock timeout of single reads and
+// writes.
+type Error struct {
+	Code                 int32    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Leader               string   `protobuf:"bytes,3,opt,name=leader,proto3" json:"leader,omitempty"`
+	ConflictKey          string   `protobuf:"bytes,4,opt,name=conflict_key,json=conflictKey,proto3" json:"conflict_key,omitempty"`
+	ConflictReason       string   `protobuf:"bytes,5,opt,name=conflict_reason,json=conflictReason,proto3" json:"conflict_reason,omitempty"`
+	ConflictTxid         string   `protobuf:"bytes,6,opt,name=conflict_txid,json=conflictTxid,proto3" json:"conflict_txid,omitempty"`
+	RetryBackoffMs       int64    `protobuf:"varint,7,opt,name=retry_backoff_ms,json=retryBackoffMs,proto3" json:"retry_backoff_ms,omitempty"`
+	LockNode             string   `protobuf:"bytes,8,opt,name=lock_node,json=lockNode,proto3" json:"lock_node,omitempty"`
+	LockHeldForMs        int64    `protobuf:"varint,9,opt,name=lock_held_for_ms,json=lockHeldForMs,proto3" json:"lock_held_for_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Error) Reset()         { *m = Error{} }
+func (m *Error) String() string { return proto.CompactTextString(m) }
+func (*Error) ProtoMessage()    {}
+func (*Error) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{18}
+}
+
+func (m *Error) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Error.Unmarshal(m, b)
+}
+func (m *Error) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Error.Marshal(b, m, deterministic)
+}
+func (m *Error) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Error.Merge(m, src)
+}
+func (m *Error) XXX_Size() int {
+	return xxx_messageInfo_Error.Size(m)
+}
+func (m *Error) XXX_DiscardUnknown() {
+	xxx_messageInfo_Error.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Error proto.InternalMessageInfo
+
+func (m *Error) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *Error) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *Error) GetLeader() string {
+	if m != nil {
+		return m.Leader
+	}
+	return ""
+}
+
+func (m *Error) GetConflictKey() string {
+	if m != nil {
+		return m.ConflictKey
+	}
+	return ""
+}
+
+func (m *Error) GetConflictReason() string {
+	if m != nil {
+		return m.ConflictReason
+	}
+	return ""
+}
+
+func (m *Error) GetConflictTxid() string {
+	if m != nil {
+		return m.ConflictTxid
+	}
+	return ""
+}
+
+func (m *Error) GetRetryBackoffMs() int64 {
+	if m != nil {
+		return m.RetryBackoffMs
+	}
+	return 0
+}
+
+func (m *Error) GetLockNode() string {
+	if m != nil {
+		return m.LockNode
+	}
+	return ""
+}
+
+func (m *Error) GetLockHeldForMs() int64 {
+	if m != nil {
+		return m.LockHeldForMs
+	}
+	return 0
+}
+
+// ReadInfo tells how a read was served: by which node of which shard, in
+// which raft state, at which applied index and term, and with which
+// consistency check, linearizable, lease, local, leader-local or stale.
+type ReadInfo struct {
+	Node                 string   `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Shard                int64    `protobuf:"varint,2,opt,name=shard,proto3" json:"shard,omitempty"`
+	State                string   `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	AppliedIndex         int64    `protobuf:"varint,4,opt,name=applied_index,json=appliedIndex,proto3" json:"applied_index,omitempty"`
+	AppliedTerm          int64    `protobuf:"varint,5,opt,name=applied_term,json=appliedTerm,proto3" json:"applied_term,omitempty"`
+	Consistency          string   `protobuf:"bytes,6,opt,name=consistency,proto3" json:"consistency,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ReadInfo) Reset()         { *m = ReadInfo{} }
+func (m *ReadInfo) String() string { return proto.CompactTextString(m) }
+func (*ReadInfo) ProtoMessage()    {}
+func (*ReadInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{19}
+}
+
+func (m *ReadInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ReadInfo.Unmarshal(m, b)
+}
+func (m *ReadInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ReadInfo.Marshal(b, m, deterministic)
+}
+func (m *ReadInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ReadInfo.Merge(m, src)
+}
+func (m *ReadInfo) XXX_Size() int {
+	return xxx_messageInfo_ReadInfo.Size(m)
+}
+func (m *ReadInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_ReadInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ReadInfo proto.InternalMessageInfo
+
+func (m *ReadInfo) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *ReadInfo) GetShard() int64 {
+	if m != nil {
+		return m.Shard
+	}
+	return 0
+}
+
+func (m *ReadInfo) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *ReadInfo) GetAppliedIndex() int64 {
+	if m != nil {
+		return m.AppliedIndex
+	}
+	return 0
+}
+
+func (m *ReadInfo) GetAppliedTerm() int64 {
+	if m != nil {
+		return m.AppliedTerm
+	}
+	return 0
+}
+
+func (m *ReadInfo) GetConsistency() string {
+	if m != nil {
+		return m.Consistency
+	}
+	return ""
+}
+
+// NodeInfo is the liveness and metadata of a store node.
+type NodeInfo struct {
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addr string `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	Zone string `protobuf:"bytes,3,opt,name=zone,proto3" json:"zone,omitempty"`
+	// capacity is the relative capacity of the node, in arbitrary units.
+	Capacity int64 `protobuf:"varint,4,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Version  int32 `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	Witness  bool  `protobuf:"varint,6,opt,name=witness,proto3" json:"witness,omitempty"`
+	// state is alive, suspect or dead, as seen by the coordinator.
+	State string `protobuf:"bytes,7,opt,name=state,proto3" json:"state,omitempty"`
+	// last_seen is when the coordinator last reached the node, in
+	// nanoseconds.
+	LastSeen int64 `protobuf:"varint,8,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`
+	// cluster is the --cluster-id of the node.
+	Cluster              string   `protobuf:"bytes,9,opt,name=cluster,proto3" json:"cluster,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NodeInfo) Reset()         { *m = NodeInfo{} }
+func (m *NodeInfo) String() string { return proto.CompactTextString(m) }
+func (*NodeInfo) ProtoMessage()    {}
+func (*NodeInfo) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{20}
+}
+
+func (m *NodeInfo) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NodeInfo.Unmarshal(m, b)
+}
+func (m *NodeInfo) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NodeInfo.Marshal(b, m, deterministic)
+}
+func (m *NodeInfo) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NodeInfo.Merge(m, src)
+}
+func (m *NodeInfo) XXX_Size() int {
+	return xxx_messageInfo_NodeInfo.Size(m)
+}
+func (m *NodeInfo) XXX_DiscardUnknown() {
+	xxx_messageInfo_NodeInfo.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NodeInfo proto.InternalMessageInfo
+
+func (m *NodeInfo) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetZone() string {
+	if m != nil {
+		return m.Zone
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetCapacity() int64 {
+	if m != nil {
+		return m.Capacity
+	}
+	return 0
+}
+
+func (m *NodeInfo) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *NodeInfo) GetWitness() bool {
+	if m != nil {
+		return m.Witness
+	}
+	return false
+}
+
+func (m *NodeInfo) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetLastSeen() int64 {
+	if m != nil {
+		return m.LastSeen
+	}
+	return 0
+}
+
+func (m *NodeInfo) GetCluster() string {
+	if m != nil {
+		return m.Cluster
+	}
+	return ""
+}
+
+// KeyLock is a key locked by a transaction on the node, since the time in
+// nanoseconds. The age of the lock is measured by the node, whatever the
+// skew of its clock.
+type KeyLock struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Txid                 string   `protobuf:"bytes,2,opt,name=txid,proto3" json:"txid,omitempty"`
+	Since                int64    `protobuf:"varint,3,opt,name=since,proto3" json:"since,omitempty"`
+	Age                  int64    `protobuf:"varint,4,opt,name=age,proto3" json:"age,omitempty"`
+	Node                 string   `protobuf:"bytes,5,opt,name=node,proto3" json:"node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyLock) Reset()         { *m = KeyLock{} }
+func (m *KeyLock) String() string { return proto.CompactTextString(m) }
+func (*KeyLock) ProtoMessage()    {}
+func (*KeyLock) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{21}
+}
+
+func (m *KeyLock) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyLock.Unmarshal(m, b)
+}
+func (m *KeyLock) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyLock.Marshal(b, m, deterministic)
+}
+func (m *KeyLock) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyLock.Merge(m, src)
+}
+func (m *KeyLock) XXX_Size() int {
+	return xxx_messageInfo_KeyLock.Size(m)
+}
+func (m *KeyLock) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyLock.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyLock proto.InternalMessageInfo
+
+func (m *KeyLock) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KeyLock) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *KeyLock) GetSince() int64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *KeyLock) GetAge() int64 {
+	if m != nil {
+		return m.Age
+	}
+	return 0
+}
+
+func (m *KeyLock) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+type GroupMembers struct {
+	Group     string   `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Voters    []string `protobuf:"bytes,2,rep,name=voters,proto3" json:"voters,omitempty"`
+	Nonvoters []string `protobuf:"bytes,3,rep,name=nonvoters,proto3" json:"nonvoters,omitempty"`
+	// actions are the steps of the member change made, or planned.
+	Actions              []*MemberAction `protobuf:"bytes,4,rep,name=actions,proto3" json:"actions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *GroupMembers) Reset()         { *m = GroupMembers{} }
+func (m *GroupMembers) String() string { return proto.CompactTextString(m) }
+func (*GroupMembers) ProtoMessage()    {}
+func (*GroupMembers) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{22}
+}
+
+func (m *GroupMembers) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GroupMembers.Unmarshal(m, b)
+}
+func (m *GroupMembers) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GroupMembers.Marshal(b, m, deterministic)
+}
+func (m *GroupMembers) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GroupMembers.Merge(m, src)
+}
+func (m *GroupMembers) XXX_Size() int {
+	return xxx_messageInfo_GroupMembers.Size(m)
+}
+func (m *GroupMembers) XXX_DiscardUnknown() {
+	xxx_messageInfo_GroupMembers.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GroupMembers proto.InternalMessageInfo
+
+func (m *GroupMembers) GetGroup() string {
+	if m != nil {
+		return m.Group
+	}
+	return ""
+}
+
+func (m *GroupMembers) GetVoters() []string {
+	if m != nil {
+		return m.Voters
+	}
+	return nil
+}
+
+func (m *GroupMembers) GetNonvoters() []string {
+	if m != nil {
+		return m.Nonvoters
+	}
+	return nil
+}
+
+func (m *GroupMembers) GetActions() []*MemberAction {
+	if m != nil {
+		return m.Actions
+	}
+	return nil
+}
+
+type SlowOp struct {
+	Time                 int64    `protobuf:"varint,1,opt,name=time,proto3" json:"time,omitempty"`
+	Kind                 string   `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Key                  string   `protobuf:"bytes,3,opt,name=key,proto3" json:"key,omitempty"`
+	Txid                 string   `protobuf:"bytes,4,opt,name=txid,proto3" json:"txid,omitempty"`
+	Duration             int64    `protobuf:"varint,5,opt,name=duration,proto3" json:"duration,omitempty"`
+	Node                 string   `protobuf:"bytes,6,opt,name=node,proto3" json:"node,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SlowOp) Reset()         { *m = SlowOp{} }
+func (m *SlowOp) String() string { return proto.CompactTextString(m) }
+func (*SlowOp) ProtoMessage()    {}
+func (*SlowOp) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{23}
+}
+
+func (m *SlowOp) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SlowOp.Unmarshal(m, b)
+}
+func (m *SlowOp) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SlowOp.Marshal(b, m, deterministic)
+}
+func (m *SlowOp) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SlowOp.Merge(m, src)
+}
+func (m *SlowOp) XXX_Size() int {
+	return xxx_messageInfo_SlowOp.Size(m)
+}
+func (m *SlowOp) XXX_DiscardUnknown() {
+	xxx_messageInfo_SlowOp.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SlowOp proto.InternalMessageInfo
+
+func (m *SlowOp) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *SlowOp) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *SlowOp) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *SlowOp) GetTxid() string {
+	if m != nil {
+		return m.Txid
+	}
+	return ""
+}
+
+func (m *SlowOp) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *SlowOp) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+type NamespaceUsage struct {
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Keys      int64  `protobuf:"varint,2,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes     int64  `protobuf:"varint,3,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// writes counts the sets and deletes of the keys of the namespace.
+	Writes               int64    `protobuf:"varint,4,opt,name=writes,proto3" json:"writes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NamespaceUsage) Reset()         { *m = NamespaceUsage{} }
+func (m *NamespaceUsage) String() string { return proto.CompactTextString(m) }
+func (*NamespaceUsage) ProtoMessage()    {}
+func (*NamespaceUsage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{24}
+}
+
+func (m *NamespaceUsage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NamespaceUsage.Unmarshal(m, b)
+}
+func (m *NamespaceUsage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NamespaceUsage.Marshal(b, m, deterministic)
+}
+func (m *NamespaceUsage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NamespaceUsage.Merge(m, src)
+}
+func (m *NamespaceUsage) XXX_Size() int {
+	return xxx_messageInfo_NamespaceUsage.Size(m)
+}
+func (m *NamespaceUsage) XXX_DiscardUnknown() {
+	xxx_messageInfo_NamespaceUsage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NamespaceUsage proto.InternalMessageInfo
+
+func (m *NamespaceUsage) GetNamespace() string {
+	if m != nil {
+		return m.Namespace
+	}
+	return ""
+}
+
+func (m *NamespaceUsage) GetKeys() int64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *NamespaceUsage) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *NamespaceUsage) GetWrites() int64 {
+	if m != nil {
+		return m.Writes
+	}
+	return 0
+}
+
+// WriteResult is the outcome of a write of a bulk write stream.
+type WriteResult struct {
+	// index is the position of the write in the stream.
+	Index int64  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Key   string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// revision is the revision of the write, 0 if it failed.
+	Revision             int64    `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	Error                string   `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WriteResult) Reset()         { *m = WriteResult{} }
+func (m *WriteResult) String() string { return proto.CompactTextString(m) }
+func (*WriteResult) ProtoMessage()    {}
+func (*WriteResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{25}
+}
+
+func (m *WriteResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WriteResult.Unmarshal(m, b)
+}
+func (m *WriteResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WriteResult.Marshal(b, m, deterministic)
+}
+func (m *WriteResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WriteResult.Merge(m, src)
+}
+func (m *WriteResult) XXX_Size() int {
+	return xxx_messageInfo_WriteResult.Size(m)
+}
+func (m *WriteResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_WriteResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WriteResult proto.InternalMessageInfo
+
+func (m *WriteResult) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *WriteResult) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *WriteResult) GetRevision() int64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *WriteResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+// ShardStats are the statistics of the keys of a shard replica.
+type ShardStats struct {
+	Keys int64 `protobuf:"varint,1,opt,name=keys,proto3" json:"keys,omitempty"`
+	// bytes are the approximate bytes of the keys and values.
+	Bytes int64 `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	// value_sizes counts the values by size, in the buckets bounded by
+	// common.ValueSizeBounds, the last one holding the larger values.
+	ValueSizes []int64 `protobuf:"varint,3,rep,packed,name=value_sizes,json=valueSizes,proto3" json:"value_sizes,omitempty"`
+	// prefixes are the namespaces with the most writes.
+	Prefixes             []*NamespaceUsage `protobuf:"bytes,4,rep,name=prefixes,proto3" json:"prefixes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *ShardStats) Reset()         { *m = ShardStats{} }
+func (m *ShardStats) String() string { return proto.CompactTextString(m) }
+func (*ShardStats) ProtoMessage()    {}
+func (*ShardStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{26}
+}
+
+func (m *ShardStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShardStats.Unmarshal(m, b)
+}
+func (m *ShardStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShardStats.Marshal(b, m, deterministic)
+}
+func (m *ShardStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShardStats.Merge(m, src)
+}
+func (m *ShardStats) XXX_Size() int {
+	return xxx_messageInfo_ShardStats.Size(m)
+}
+func (m *ShardStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShardStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShardStats proto.InternalMessageInfo
+
+func (m *ShardStats) GetKeys() int64 {
+	if m != nil {
+		return m.Keys
+	}
+	return 0
+}
+
+func (m *ShardStats) GetBytes() int64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+func (m *ShardStats) GetValueSizes() []int64 {
+	if m != nil {
+		return m.ValueSizes
+	}
+	return nil
+}
+
+func (m *ShardStats) GetPrefixes() []*NamespaceUsage {
+	if m != nil {
+		return m.Prefixes
+	}
+	return nil
+}
+
+type RaftCommand struct {
+	Commands []*Command `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	// To ensure handled by ApplyTransaction
+	IsTxn bool `protobuf:"varint,2,opt,name=is_txn,json=isTxn,proto3" json:"is_txn,omitempty"`
+	// batch packs independent committed transactions in a single entry,
+	// applied in order, instead of commands.
+	Batch []*RaftCommand `protobuf:"bytes,3,rep,name=batch,proto3" json:"batch,omitempty"`
+	// lock_reply has the store reply the key locks a command fails on in
+	// RPCResponse.lock, as net/rpc drops the reply of the failed calls. It
+	// is cleared before the command is proposed. Added in protocol version
+	// 14, the stores of older versions fail with the message of the lock.
+	LockReply bool `protobuf:"varint,4,opt,name=lock_reply,json=lockReply,proto3" json:"lock_reply,omitempty"`
+	// signature authenticates the writes of the coordinators to the shard
+	// leaders, as ShardOps.signature. It is cleared before the command is
+	// proposed. Added in protocol version 16.
+	Signature            []byte   `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedAt             int64    `protobuf:"varint,6,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RaftCommand) Reset()         { *m = RaftCommand{} }
+func (m *RaftCommand) String() string { return proto.CompactTextString(m) }
+func (*RaftCommand) ProtoMessage()    {}
+func (*RaftCommand) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{27}
+}
+
+func (m *RaftCommand) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RaftCommand.Unmarshal(m, b)
+}
+func (m *RaftCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RaftCommand.Marshal(b, m, deterministic)
+}
+func (m *RaftCommand) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RaftCommand.Merge(m, src)
+}
+func (m *RaftCommand) XXX_Size() int {
+	return xxx_messageInfo_RaftCommand.Size(m)
+}
+func (m *RaftCommand) XXX_DiscardUnknown() {
+	xxx_messageInfo_RaftCommand.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RaftCommand proto.InternalMessageInfo
+
+func (m *RaftCommand) GetCommands() []*Command {
+	if m != nil {
+		return m.Commands
+	}
+	return nil
+}
+
+func (m *RaftCommand) GetIsTxn() bool {
+	if m != nil {
+		return m.IsTxn
+	}
+	return false
+}
+
+func (m *RaftCommand) GetBatch() []*RaftCommand {
+	if m != nil {
+		return m.Batch
+	}
+	return nil
+}
+
+func (m *RaftCommand) GetLockReply() bool {
+	if m != nil {
+		return m.LockReply
+	}
+	return false
+}
+
+func (m *RaftCommand) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *RaftCommand) GetSignedAt() int64 {
+	if m != nil {
+		return m.SignedAt
+	}
+	return 0
+}
+
+type JoinMsg struct {
+	RaftAddress string `protobuf:"bytes,1,opt,name=RaftAddress,proto3" json:"RaftAddress,omitempty"`
+	ID          string `protobuf:"bytes,2,opt,name=ID,proto3" json:"ID,omitempty"`
+	TYPE        string `protobuf:"bytes,3,opt,name=TYPE,proto3" json:"TYPE,omitempty"`
+	// Version is the protocol version spoken by the joining node.
+	Version int32 `protobuf:"varint,4,opt,name=Version,proto3" json:"Version,omitempty"`
+	// Nonvoter joins the node without a vote, to be promoted later.
+	Nonvoter bool `protobuf:"varint,5,opt,name=Nonvoter,proto3" json:"Nonvoter,omitempty"`
+	// SignedAt and Signature sign the join with the cluster secret, see
+	// common.SignJoin.
+	SignedAt  int64  `protobuf:"varint,6,opt,name=SignedAt,proto3" json:"SignedAt,omitempty"`
+	Signature []byte `protobuf:"bytes,7,opt,name=Signature,proto3" json:"Signature,omitempty"`
+	// ClusterID is the cluster of the state of the joining node, empty if
+	// it has none.
+	ClusterID            string   `protobuf:"bytes,8,opt,name=ClusterID,proto3" json:"ClusterID,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *JoinMsg) Reset()         { *m = JoinMsg{} }
+func (m *JoinMsg) String() string { return proto.CompactTextString(m) }
+func (*JoinMsg) ProtoMessage()    {}
+func (*JoinMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{28}
+}
+
+func (m *JoinMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_JoinMsg.Unmarshal(m, b)
+}
+func (m *JoinMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_JoinMsg.Marshal(b, m, deterministic)
+}
+func (m *JoinMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_JoinMsg.Merge(m, src)
+}
+func (m *JoinMsg) XXX_Size() int {
+	return xxx_messageInfo_JoinMsg.Size(m)
+}
+func (m *JoinMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_JoinMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_JoinMsg proto.InternalMessageInfo
+
+func (m *JoinMsg) GetRaftAddress() string {
+	if m != nil {
+		return m.RaftAddress
+	}
+	return ""
+}
+
+func (m *JoinMsg) GetID() string {
+	if m != nil {
+		return m.ID
+	}
+	return ""
+}
+
+func (m *JoinMsg) GetTYPE() string {
+	if m != nil {
+		return m.TYPE
+	}
+	return ""
+}
+
+func (m *JoinMsg) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *JoinMsg) GetNonvoter() bool {
+	if m != nil {
+		return m.Nonvoter
+	}
+	return false
+}
+
+func (m *JoinMsg) GetSignedAt() int64 {
+	if m != nil {
+		return m.SignedAt
+	}
+	return 0
+}
+
+func (m *JoinMsg) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *JoinMsg) GetClusterID() string {
+	if m != nil {
+		return m.ClusterID
+	}
+	return ""
+}
+
+// MemberChange joins members, promotes non-voters, removes members and then
+// transfers the leadership of the raft groups of a shard, or of the
+// coordinators, by node id. The parts already in place are skipped, so that
+// the same change can be sent again. dry_run only plans the change.
+type MemberChange struct {
+	Promote  []string      `protobuf:"bytes,1,rep,name=promote,proto3" json:"promote,omitempty"`
+	Remove   []string      `protobuf:"bytes,2,rep,name=remove,proto3" json:"remove,omitempty"`
+	Join     []*MemberJoin `protobuf:"bytes,3,rep,name=join,proto3" json:"join,omitempty"`
+	Transfer string        `protobuf:"bytes,4,opt,name=transfer,proto3" json:"transfer,omitempty"`
+	DryRun   bool          `protobuf:"varint,5,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	// signature authenticates the membership changes of the coordinators,
+	// as ShardOps.signature. Added in protocol version 16.
+	Signature            []byte   `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	SignedAt             int64    `protobuf:"varint,7,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MemberChange) Reset()         { *m = MemberChange{} }
+func (m *MemberChange) String() string { return proto.CompactTextString(m) }
+func (*MemberChange) ProtoMessage()    {}
+func (*MemberChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{29}
+}
+
+func (m *MemberChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MemberChange.Unmarshal(m, b)
+}
+func (m *MemberChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MemberChange.Marshal(b, m, deterministic)
+}
+func (m *MemberChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MemberChange.Merge(m, src)
+}
+func (m *MemberChange) XXX_Size() int {
+	return xxx_messageInfo_MemberChange.Size(m)
+}
+func (m *MemberChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_MemberChange.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MemberChange proto.InternalMessageInfo
+
+func (m *MemberChange) GetPromote() []string {
+	if m != nil {
+		return m.Promote
+	}
+	return nil
+}
+
+func (m *MemberChange) GetRemove() []string {
+	if m != nil {
+		return m.Remove
+	}
+	return nil
+}
+
+func (m *MemberChange) GetJoin() []*MemberJoin {
+	if m != nil {
+		return m.Join
+	}
+	return nil
+}
+
+func (m *MemberChange) GetTransfer() string {
+	if m != nil {
+		return m.Transfer
+	}
+	return ""
+}
+
+func (m *MemberChange) GetDryRun() bool {
+	if m != nil {
+		return m.DryRun
+	}
+	return false
+}
+
+func (m *MemberChange) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func (m *MemberChange) GetSignedAt() int64 {
+	if m != nil {
+		return m.SignedAt
+	}
+	return 0
+}
+
+// MemberJoin is a node joining raft groups: a coordinator, at address, or a
+// store node, at address in the store group and cohort_address in the
+// cohort group of its shard. It joins as a non-voter unless voter is set.
+type MemberJoin struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address              string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	CohortAddress        string   `protobuf:"bytes,3,opt,name=cohort_address,json=cohortAddress,proto3" json:"cohort_address,omitempty"`
+	Voter                bool     `protobuf:"varint,4,opt,name=voter,proto3" json:"voter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MemberJoin) Reset()         { *m = MemberJoin{} }
+func (m *MemberJoin) String() string { return proto.CompactTextString(m) }
+func (*MemberJoin) ProtoMessage()    {}
+func (*MemberJoin) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{30}
+}
+
+func (m *MemberJoin) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MemberJoin.Unmarshal(m, b)
+}
+func (m *MemberJoin) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MemberJoin.Marshal(b, m, deterministic)
+}
+func (m *MemberJoin) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MemberJoin.Merge(m, src)
+}
+func (m *MemberJoin) XXX_Size() int {
+	return xxx_messageInfo_MemberJoin.Size(m)
+}
+func (m *MemberJoin) XXX_DiscardUnknown() {
+	xxx_messageInfo_MemberJoin.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MemberJoin proto.InternalMessageInfo
+
+func (m *MemberJoin) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *MemberJoin) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *MemberJoin) GetCohortAddress() string {
+	if m != nil {
+		return m.CohortAddress
+	}
+	return ""
+}
+
+func (m *MemberJoin) GetVoter() bool {
+	if m != nil {
+		return m.Voter
+	}
+	return false
+}
+
+// MemberAction is a step of a member change of a raft group: add-voter,
+// add-nonvoter, promote, remove or transfer, of the member id at address.
+type MemberAction struct {
+	Op                   string   `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"`
+	Id                   string   `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Address              string   `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MemberAction) Reset()         { *m = MemberAction{} }
+func (m *MemberAction) String() string { return proto.CompactTextString(m) }
+func (*MemberAction) ProtoMessage()    {}
+func (*MemberAction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{31}
+}
+
+func (m *MemberAction) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MemberAction.Unmarshal(m, b)
+}
+func (m *MemberAction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MemberAction.Marshal(b, m, deterministic)
+}
+func (m *MemberAction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MemberAction.Merge(m, src)
+}
+func (m *MemberAction) XXX_Size() int {
+	return xxx_messageInfo_MemberAction.Size(m)
+}
+func (m *MemberAction) XXX_DiscardUnknown() {
+	xxx_messageInfo_MemberAction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MemberAction proto.InternalMessageInfo
+
+func (m *MemberAction) GetOp() string {
+	if m != nil {
+		return m.Op
 	}
-	return nil
+	return ""
 }
 
-type RaftCommand struct {
-	Commands []*Command `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
-	// To ensure handled by ApplyTransaction
-	IsTxn                bool     `protobuf:"varint,2,opt,name=is_txn,json=isTxn,proto3" json:"is_txn,omitempty"`
+func (m *MemberAction) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *MemberAction) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+// SnapshotChunk is a chunk of the latest raft snapshot of the store of a
+// node, fetched by a new replica to seed its store before joining. The first
+// request of a session has no id and gets the metadata of the snapshot only.
+type SnapshotChunk struct {
+	Session            string        `protobuf:"bytes,1,opt,name=session,proto3" json:"session,omitempty"`
+	Id                 string        `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Version            int32         `protobuf:"varint,3,opt,name=version,proto3" json:"version,omitempty"`
+	Index              uint64        `protobuf:"varint,4,opt,name=index,proto3" json:"index,omitempty"`
+	Term               uint64        `protobuf:"varint,5,opt,name=term,proto3" json:"term,omitempty"`
+	Configuration      []*RaftServer `protobuf:"bytes,6,rep,name=configuration,proto3" json:"configuration,omitempty"`
+	ConfigurationIndex uint64        `protobuf:"varint,7,opt,name=configuration_index,json=configurationIndex,proto3" json:"configuration_index,omitempty"`
+	Size               int64         `protobuf:"varint,8,opt,name=size,proto3" json:"size,omitempty"`
+	Offset             int64         `protobuf:"varint,9,opt,name=offset,proto3" json:"offset,omitempty"`
+	Data               []byte        `protobuf:"bytes,10,opt,name=data,proto3" json:"data,omitempty"`
+	// leader is set when the node is the leader of the store raft group.
+	Leader               bool     `protobuf:"varint,11,opt,name=leader,proto3" json:"leader,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *RaftCommand) Reset()         { *m = RaftCommand{} }
-func (m *RaftCommand) String() string { return proto.CompactTextString(m) }
-func (*RaftCommand) ProtoMessage()    {}
-func (*RaftCommand) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{7}
+func (m *SnapshotChunk) Reset()         { *m = SnapshotChunk{} }
+func (m *SnapshotChunk) String() string { return proto.CompactTextString(m) }
+func (*SnapshotChunk) ProtoMessage()    {}
+func (*SnapshotChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{32}
 }
 
-func (m *RaftCommand) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RaftCommand.Unmarshal(m, b)
+func (m *SnapshotChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotChunk.Unmarshal(m, b)
 }
-func (m *RaftCommand) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RaftCommand.Marshal(b, m, deterministic)
+func (m *SnapshotChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotChunk.Marshal(b, m, deterministic)
 }
-func (m *RaftCommand) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RaftCommand.Merge(m, src)
+func (m *SnapshotChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotChunk.Merge(m, src)
 }
-func (m *RaftCommand) XXX_Size() int {
-	return xxx_messageInfo_RaftCommand.Size(m)
+func (m *SnapshotChunk) XXX_Size() int {
+	return xxx_messageInfo_SnapshotChunk.Size(m)
 }
-func (m *RaftCommand) XXX_DiscardUnknown() {
-	xxx_messageInfo_RaftCommand.DiscardUnknown(m)
+func (m *SnapshotChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotChunk.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_RaftCommand proto.InternalMessageInfo
+var xxx_messageInfo_SnapshotChunk proto.InternalMessageInfo
 
-func (m *RaftCommand) GetCommands() []*Command {
+func (m *SnapshotChunk) GetSession() string {
 	if m != nil {
-		return m.Commands
+		return m.Session
+	}
+	return ""
+}
+
+func (m *SnapshotChunk) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *SnapshotChunk) GetVersion() int32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetConfiguration() []*RaftServer {
+	if m != nil {
+		return m.Configuration
 	}
 	return nil
 }
 
-func (m *RaftCommand) GetIsTxn() bool {
+func (m *SnapshotChunk) GetConfigurationIndex() uint64 {
 	if m != nil {
-		return m.IsTxn
+		return m.ConfigurationIndex
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetSize() int64 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *SnapshotChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *SnapshotChunk) GetLeader() bool {
+	if m != nil {
+		return m.Leader
 	}
 	return false
 }
 
-type JoinMsg struct {
-	RaftAddress          string   `protobuf:"bytes,1,opt,name=RaftAddress,proto3" json:"RaftAddress,omitempty"`
-	ID                   string   `protobuf:"bytes,2,opt,name=ID,proto3" json:"ID,omitempty"`
-	TYPE                 string   `protobuf:"bytes,3,opt,name=TYPE,proto3" json:"TYPE,omitempty"`
+type RaftServer struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Address              string   `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Suffrage             int32    `protobuf:"varint,3,opt,name=suffrage,proto3" json:"suffrage,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *JoinMsg) Reset()         { *m = JoinMsg{} }
-func (m *JoinMsg) String() string { return proto.CompactTextString(m) }
-func (*JoinMsg) ProtoMessage()    {}
-func (*JoinMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_f652ee94e728864d, []int{8}
+func (m *RaftServer) Reset()         { *m = RaftServer{} }
+func (m *RaftServer) String() string { return proto.CompactTextString(m) }
+func (*RaftServer) ProtoMessage()    {}
+func (*RaftServer) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{33}
 }
 
-func (m *JoinMsg) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_JoinMsg.Unmarshal(m, b)
+func (m *RaftServer) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RaftServer.Unmarshal(m, b)
 }
-func (m *JoinMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_JoinMsg.Marshal(b, m, deterministic)
+func (m *RaftServer) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RaftServer.Marshal(b, m, deterministic)
 }
-func (m *JoinMsg) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_JoinMsg.Merge(m, src)
+func (m *RaftServer) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RaftServer.Merge(m, src)
 }
-func (m *JoinMsg) XXX_Size() int {
-	return xxx_messageInfo_JoinMsg.Size(m)
+func (m *RaftServer) XXX_Size() int {
+	return xxx_messageInfo_RaftServer.Size(m)
 }
-func (m *JoinMsg) XXX_DiscardUnknown() {
-	xxx_messageInfo_JoinMsg.DiscardUnknown(m)
+func (m *RaftServer) XXX_DiscardUnknown() {
+	xxx_messageInfo_RaftServer.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_JoinMsg proto.InternalMessageInfo
+var xxx_messageInfo_RaftServer proto.InternalMessageInfo
 
-func (m *JoinMsg) GetRaftAddress() string {
+func (m *RaftServer) GetId() string {
 	if m != nil {
-		return m.RaftAddress
+		return m.Id
 	}
 	return ""
 }
 
-func (m *JoinMsg) GetID() string {
+func (m *RaftServer) GetAddress() string {
 	if m != nil {
-		return m.ID
+		return m.Address
 	}
 	return ""
 }
 
-func (m *JoinMsg) GetTYPE() string {
+func (m *RaftServer) GetSuffrage() int32 {
 	if m != nil {
-		return m.TYPE
+		return m.Suffrage
+	}
+	return 0
+}
+
+// WatchRequest polls the changes of the keys starting with prefix for the
+// watcher id, registered with the changes after revision after on its first
+// poll, or from then on if after is 0.
+type WatchRequest struct {
+	Id     string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Prefix string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	After  int64  `protobuf:"varint,3,opt,name=after,proto3" json:"after,omitempty"`
+	// wait is how long the poll waits for changes, in milliseconds.
+	Wait int64 `protobuf:"varint,4,opt,name=wait,proto3" json:"wait,omitempty"`
+	// cancel drops the watcher.
+	Cancel bool `protobuf:"varint,5,opt,name=cancel,proto3" json:"cancel,omitempty"`
+	// list replies to the registration with the keys starting with prefix,
+	// as of the revision watched from, instead of catching up after after.
+	List                 bool         `protobuf:"varint,6,opt,name=list,proto3" json:"list,omitempty"`
+	Filter               *WatchFilter `protobuf:"bytes,7,opt,name=filter,proto3" json:"filter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{34}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *WatchRequest) GetAfter() int64 {
+	if m != nil {
+		return m.After
+	}
+	return 0
+}
+
+func (m *WatchRequest) GetWait() int64 {
+	if m != nil {
+		return m.Wait
+	}
+	return 0
+}
+
+func (m *WatchRequest) GetCancel() bool {
+	if m != nil {
+		return m.Cancel
+	}
+	return false
+}
+
+func (m *WatchRequest) GetList() bool {
+	if m != nil {
+		return m.List
+	}
+	return false
+}
+
+func (m *WatchRequest) GetFilter() *WatchFilter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+// WatchFilter selects the changes sent to a watcher, on top of its prefix.
+type WatchFilter struct {
+	// methods are the SET, DEL and EVICT commands selected, all if empty.
+	Methods []string `protobuf:"bytes,1,rep,name=methods,proto3" json:"methods,omitempty"`
+	// field, when set, selects the SETs of JSON values whose field, a dot
+	// separated path, equals the JSON value equals.
+	Field                string   `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	Equals               string   `protobuf:"bytes,3,opt,name=equals,proto3" json:"equals,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchFilter) Reset()         { *m = WatchFilter{} }
+func (m *WatchFilter) String() string { return proto.CompactTextString(m) }
+func (*WatchFilter) ProtoMessage()    {}
+func (*WatchFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{35}
+}
+
+func (m *WatchFilter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchFilter.Unmarshal(m, b)
+}
+func (m *WatchFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchFilter.Marshal(b, m, deterministic)
+}
+func (m *WatchFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchFilter.Merge(m, src)
+}
+func (m *WatchFilter) XXX_Size() int {
+	return xxx_messageInfo_WatchFilter.Size(m)
+}
+func (m *WatchFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchFilter proto.InternalMessageInfo
+
+func (m *WatchFilter) GetMethods() []string {
+	if m != nil {
+		return m.Methods
+	}
+	return nil
+}
+
+func (m *WatchFilter) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *WatchFilter) GetEquals() string {
+	if m != nil {
+		return m.Equals
 	}
 	return ""
 }
 
+// SnapshotDelta brings a replica whose latest snapshot is at index since to
+// index, with the keys set and deleted in between, the client sessions and
+// the protocol versions of the members.
+type SnapshotDelta struct {
+	Since              uint64           `protobuf:"varint,1,opt,name=since,proto3" json:"since,omitempty"`
+	Index              uint64           `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Term               uint64           `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
+	Configuration      []*RaftServer    `protobuf:"bytes,4,rep,name=configuration,proto3" json:"configuration,omitempty"`
+	ConfigurationIndex uint64           `protobuf:"varint,5,opt,name=configuration_index,json=configurationIndex,proto3" json:"configuration_index,omitempty"`
+	Entries            []*KVEntry       `protobuf:"bytes,6,rep,name=entries,proto3" json:"entries,omitempty"`
+	Deleted            []string         `protobuf:"bytes,7,rep,name=deleted,proto3" json:"deleted,omitempty"`
+	Sessions           []*Session       `protobuf:"bytes,8,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	Versions           map[string]int32 `protobuf:"bytes,9,rep,name=versions,proto3" json:"versions,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// expires are the expiry times of the keys with one, by key.
+	Expires              map[string]int64 `protobuf:"bytes,10,rep,name=expires,proto3" json:"expires,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *SnapshotDelta) Reset()         { *m = SnapshotDelta{} }
+func (m *SnapshotDelta) String() string { return proto.CompactTextString(m) }
+func (*SnapshotDelta) ProtoMessage()    {}
+func (*SnapshotDelta) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f652ee94e728864d, []int{36}
+}
+
+func (m *SnapshotDelta) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SnapshotDelta.Unmarshal(m, b)
+}
+func (m *SnapshotDelta) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SnapshotDelta.Marshal(b, m, deterministic)
+}
+func (m *SnapshotDelta) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SnapshotDelta.Merge(m, src)
+}
+func (m *SnapshotDelta) XXX_Size() int {
+	return xxx_messageInfo_SnapshotDelta.Size(m)
+}
+func (m *SnapshotDelta) XXX_DiscardUnknown() {
+	xxx_messageInfo_SnapshotDelta.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SnapshotDelta proto.InternalMessageInfo
+
+func (m *SnapshotDelta) GetSince() uint64 {
+	if m != nil {
+		return m.Since
+	}
+	return 0
+}
+
+func (m *SnapshotDelta) GetIndex() uint64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *SnapshotDelta) GetTerm() uint64 {
+	if m != nil {
+		return m.Term
+	}
+	return 0
+}
+
+func (m *SnapshotDelta) GetConfiguration() []*RaftServer {
+	if m != nil {
+		return m.Configuration
+	}
+	return nil
+}
+
+func (m *SnapshotDelta) GetConfigurationIndex() uint64 {
+	if m != nil {
+		return m.ConfigurationIndex
+	}
+	return 0
+}
+
+func (m *SnapshotDelta) GetEntries() []*KVEntry {
+	if m != nil {
+		return m.Entries
+	}
+	return nil
+}
+
+func (m *SnapshotDelta) GetDeleted() []string {
+	if m != nil {
+		return m.Deleted
+	}
+	return nil
+}
+
+func (m *SnapshotDelta) GetSessions() []*Session {
+	if m != nil {
+		return m.Sessions
+	}
+	return nil
+}
+
+func (m *SnapshotDelta) GetVersions() map[string]int32 {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+func (m *SnapshotDelta) GetExpires() map[string]int64 {
+	if m != nil {
+		return m.Expires
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*Command)(nil), "raftpb.Command")
+	proto.RegisterType((*Compare)(nil), "raftpb.Compare")
+	proto.RegisterType((*CompareTxn)(nil), "raftpb.CompareTxn")
 	proto.RegisterType((*Cond)(nil), "raftpb.Cond")
+	proto.RegisterType((*KeyMeta)(nil), "raftpb.KeyMeta")
+	proto.RegisterType((*KVEntry)(nil), "raftpb.KVEntry")
+	proto.RegisterType((*MemberVersion)(nil), "raftpb.MemberVersion")
+	proto.RegisterType((*MerkleRequest)(nil), "raftpb.MerkleRequest")
+	proto.RegisterType((*MerkleReply)(nil), "raftpb.MerkleReply")
+	proto.RegisterType((*StateHash)(nil), "raftpb.StateHash")
+	proto.RegisterType((*Session)(nil), "raftpb.Session")
+	proto.RegisterType((*ImportChunk)(nil), "raftpb.ImportChunk")
 	proto.RegisterType((*GlobalTransaction)(nil), "raftpb.GlobalTransaction")
 	proto.RegisterMapType((map[int64]*ShardOps)(nil), "raftpb.GlobalTransaction.ShardToCommandsEntry")
 	proto.RegisterType((*TxidMap)(nil), "raftpb.TxidMap")
 	proto.RegisterMapType((map[string]*GlobalTransaction)(nil), "raftpb.TxidMap.MapEntry")
+	proto.RegisterMapType((map[string][]byte)(nil), "raftpb.TxidMap.PolicyEntry")
+	proto.RegisterMapType((map[int64]int32)(nil), "raftpb.TxidMap.ReplicasEntry")
+	proto.RegisterMapType((map[string][]byte)(nil), "raftpb.TxidMap.TransformsEntry")
+	proto.RegisterMapType((map[string][]byte)(nil), "raftpb.TxidMap.TtlsEntry")
+	proto.RegisterMapType((map[string]int32)(nil), "raftpb.TxidMap.VersionsEntry")
 	proto.RegisterType((*OpsMap)(nil), "raftpb.OpsMap")
 	proto.RegisterMapType((map[string]*ShardOps)(nil), "raftpb.OpsMap.MapEntry")
 	proto.RegisterType((*ShardOps)(nil), "raftpb.ShardOps")
 	proto.RegisterType((*RPCResponse)(nil), "raftpb.RPCResponse")
+	proto.RegisterMapType((map[string]string)(nil), "raftpb.RPCResponse.StatsEntry")
+	proto.RegisterType((*LockError)(nil), "raftpb.LockError")
+	proto.RegisterType((*Error)(nil), "raftpb.Error")
+	proto.RegisterType((*ReadInfo)(nil), "raftpb.ReadInfo")
+	proto.RegisterType((*NodeInfo)(nil), "raftpb.NodeInfo")
+	proto.RegisterType((*KeyLock)(nil), "raftpb.KeyLock")
+	proto.RegisterType((*GroupMembers)(nil), "raftpb.GroupMembers")
+	proto.RegisterType((*SlowOp)(nil), "raftpb.SlowOp")
+	proto.RegisterType((*NamespaceUsage)(nil), "raftpb.NamespaceUsage")
+	proto.RegisterType((*WriteResult)(nil), "raftpb.WriteResult")
+	proto.RegisterType((*ShardStats)(nil), "raftpb.ShardStats")
 	proto.RegisterType((*RaftCommand)(nil), "raftpb.RaftCommand")
 	proto.RegisterType((*JoinMsg)(nil), "raftpb.JoinMsg")
+	proto.RegisterType((*MemberChange)(nil), "raftpb.MemberChange")
+	proto.RegisterType((*MemberJoin)(nil), "raftpb.MemberJoin")
+	proto.RegisterType((*MemberAction)(nil), "raftpb.MemberAction")
+	proto.RegisterType((*SnapshotChunk)(nil), "raftpb.SnapshotChunk")
+	proto.RegisterType((*RaftServer)(nil), "raftpb.RaftServer")
+	proto.RegisterType((*WatchRequest)(nil), "raftpb.WatchRequest")
+	proto.RegisterType((*WatchFilter)(nil), "raftpb.WatchFilter")
+	proto.RegisterType((*SnapshotDelta)(nil), "raftpb.SnapshotDelta")
+	proto.RegisterMapType((map[string]int64)(nil), "raftpb.SnapshotDelta.ExpiresEntry")
+	proto.RegisterMapType((map[string]int32)(nil), "raftpb.SnapshotDelta.VersionsEntry")
 }
 
 func init() { proto.RegisterFile("raftpb/raft.proto", fileDescriptor_f652ee94e728864d) }
 
 var fileDescriptor_f652ee94e728864d = []byte{
-	// 608 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0x4f, 0x6f, 0xd3, 0x30,
-	0x14, 0x97, 0x93, 0x34, 0x4d, 0x5e, 0x11, 0xdb, 0xcc, 0x80, 0x30, 0x69, 0x52, 0x94, 0x03, 0x74,
-	0x20, 0x65, 0xd2, 0xb8, 0x20, 0x6e, 0xb0, 0x4d, 0x30, 0xd0, 0xd4, 0xcd, 0xe4, 0xc2, 0x2e, 0x95,
-	0x57, 0x7b, 0x6b, 0x44, 0x13, 0x47, 0xb1, 0x87, 0xda, 0x03, 0x77, 0xce, 0x9c, 0xf8, 0x28, 0x7c,
-	0x0d, 0xbe, 0x11, 0x8a, 0x9d, 0x74, 0x29, 0x64, 0x9b, 0x38, 0xc5, 0xef, 0x5f, 0xfc, 0xfb, 0xfd,
-	0xde, 0x7b, 0x86, 0x8d, 0x92, 0x5e, 0xa8, 0xe2, 0x7c, 0xb7, 0xfa, 0xc4, 0x45, 0x29, 0x94, 0xc0,
-	0xae, 0x71, 0x45, 0xbf, 0x10, 0xf4, 0xf7, 0x45, 0x96, 0xd1, 0x9c, 0xe1, 0x47, 0xe0, 0x66, 0x5c,
-	0x4d, 0x05, 0x0b, 0x50, 0x88, 0x86, 0x3e, 0xa9, 0x2d, 0xbc, 0x0e, 0xf6, 0x17, 0xbe, 0x08, 0x2c,
-	0xed, 0xac, 0x8e, 0x78, 0x13, 0x7a, 0x5f, 0xe9, 0xec, 0x8a, 0x07, 0x76, 0x88, 0x86, 0x36, 0x31,
-	0x06, 0xde, 0x01, 0xeb, 0x52, 0x05, 0x4e, 0x88, 0x86, 0x83, 0xbd, 0x27, 0xb1, 0xb9, 0x20, 0x7e,
-	0x37, 0x13, 0xe7, 0x74, 0x96, 0x94, 0x34, 0x97, 0x74, 0xa2, 0x52, 0x91, 0x13, 0xeb, 0x52, 0xe1,
-	0x10, 0x9c, 0x89, 0xc8, 0x59, 0xd0, 0xd3, 0xc9, 0xf7, 0x9a, 0xe4, 0x7d, 0x91, 0x33, 0xa2, 0x23,
-	0x38, 0x04, 0x4b, 0x8a, 0xc0, 0xd5, 0xf1, 0xf5, 0x26, 0xfe, 0x69, 0x4a, 0x4b, 0x36, 0x2a, 0x24,
-	0xb1, 0xa4, 0x88, 0x62, 0x70, 0xaa, 0xfc, 0x06, 0x1e, 0xea, 0x80, 0x67, 0xb5, 0xe0, 0x45, 0xbf,
-	0x2d, 0xd8, 0xf8, 0x07, 0x0d, 0xc6, 0xe0, 0xa8, 0x79, 0xda, 0x50, 0xd6, 0x67, 0xfc, 0x0c, 0x9c,
-	0x49, 0xc6, 0xa4, 0x2e, 0x1f, 0xec, 0x3d, 0x68, 0x6e, 0x27, 0xf4, 0x42, 0xd5, 0x5a, 0x11, 0x9d,
-	0x80, 0x03, 0xe8, 0x4f, 0xc4, 0x54, 0x94, 0x4a, 0x06, 0x76, 0x68, 0x0f, 0x7d, 0xd2, 0x98, 0xf8,
-	0x0c, 0x36, 0x64, 0x05, 0x76, 0xac, 0xc4, 0x78, 0x62, 0x6a, 0x64, 0xe0, 0x84, 0xf6, 0x70, 0xb0,
-	0x17, 0xdf, 0x28, 0x8d, 0xe1, 0x97, 0x88, 0xfa, 0x12, 0x79, 0x98, 0xab, 0x72, 0x41, 0xd6, 0xe4,
-	0xaa, 0xb7, 0xa2, 0x57, 0x4c, 0xa9, 0xe4, 0x5a, 0x3d, 0x9f, 0x18, 0x03, 0x6f, 0x03, 0x48, 0x45,
-	0x4b, 0x35, 0x56, 0x69, 0xc6, 0xb5, 0x70, 0x36, 0xf1, 0xb5, 0x27, 0x49, 0x33, 0xbe, 0x95, 0xc0,
-	0x66, 0xd7, 0xdf, 0xdb, 0xea, 0xd9, 0x46, 0xbd, 0xa7, 0x6d, 0xf5, 0xba, 0xc4, 0x37, 0xe1, 0xd7,
-	0xd6, 0x2b, 0x14, 0x7d, 0x47, 0xd0, 0x4f, 0xe6, 0x29, 0x3b, 0xa6, 0x05, 0x7e, 0x0e, 0x76, 0x46,
-	0x8b, 0x00, 0x69, 0x92, 0x41, 0x53, 0x55, 0x47, 0xe3, 0x63, 0x5a, 0x18, 0x3a, 0x55, 0xd2, 0xd6,
-	0x29, 0x78, 0x8d, 0xa3, 0xa3, 0x7f, 0xbb, 0xab, 0x08, 0x6e, 0x99, 0xa5, 0x16, 0x94, 0x6f, 0xe0,
-	0x8e, 0x0a, 0x59, 0x01, 0xd9, 0x69, 0x03, 0x79, 0xdc, 0x14, 0x9b, 0xe0, 0x5f, 0x38, 0xde, 0xdf,
-	0x8a, 0xe3, 0x7f, 0x94, 0xf8, 0x89, 0xc0, 0x6b, 0xfc, 0x9d, 0x43, 0xb5, 0x0d, 0x90, 0x51, 0xa9,
-	0x78, 0x39, 0xbe, 0x5e, 0x26, 0xdf, 0x78, 0x3e, 0xf2, 0xc5, 0x72, 0xe6, 0xec, 0xbb, 0x66, 0x6e,
-	0xd9, 0x7d, 0xa7, 0xdd, 0xfd, 0x2d, 0xf0, 0x4a, 0x4e, 0xd9, 0x28, 0x9f, 0x2d, 0xf4, 0x58, 0x78,
-	0x64, 0x69, 0x47, 0x3f, 0x10, 0x0c, 0xc8, 0xc9, 0x3e, 0xe1, 0xb2, 0x10, 0xb9, 0xe4, 0xd5, 0x9e,
-	0x4b, 0x45, 0xd5, 0x95, 0xd4, 0xf8, 0x7a, 0xa4, 0xb6, 0xba, 0xd7, 0xa6, 0xe2, 0x42, 0x19, 0x2b,
-	0x35, 0x30, 0x9f, 0xe8, 0xf3, 0x0d, 0x18, 0x5e, 0x80, 0xb7, 0x1c, 0xf5, 0x9e, 0x16, 0x7f, 0xed,
-	0x7a, 0xb1, 0x0d, 0x85, 0x65, 0x42, 0x74, 0x0a, 0x83, 0x16, 0xb7, 0x95, 0x5a, 0x74, 0x47, 0x2d,
-	0x7e, 0x08, 0x6e, 0x2a, 0xc7, 0x6a, 0x9e, 0x6b, 0xa4, 0x1e, 0xe9, 0xa5, 0x32, 0x99, 0xe7, 0xd1,
-	0x08, 0xfa, 0x1f, 0x44, 0x9a, 0x1f, 0xcb, 0x4b, 0x1c, 0x9a, 0xbf, 0xbf, 0x61, 0xac, 0xe4, 0x52,
-	0xd6, 0x7d, 0x68, 0xbb, 0xf0, 0x7d, 0xb0, 0x8e, 0x0e, 0xea, 0x36, 0x58, 0x47, 0x07, 0x15, 0xcd,
-	0xe4, 0xf3, 0xc9, 0x61, 0x43, 0xb3, 0x3a, 0xbf, 0xf5, 0xce, 0xea, 0x67, 0xf2, 0xdc, 0xd5, 0xaf,
-	0xe6, 0xcb, 0x3f, 0x01, 0x00, 0x00, 0xff, 0xff, 0x8b, 0x97, 0xe1, 0xd6, 0x4a, 0x05, 0x00, 0x00,
+	// 2956 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x3a, 0x4b, 0x6f, 0x1c, 0xc7,
+	0xd1, 0x98, 0x7d, 0x6f, 0xed, 0x92, 0x94, 0x5a, 0x0f, 0x8f, 0xe9, 0xcf, 0x36, 0xbf, 0x71, 0x6c,
+	0x53, 0xb1, 0x2d, 0x07, 0xb2, 0x81, 0xf8, 0x15, 0x18, 0xb2, 0x24, 0x47, 0x8c, 0x43, 0xcb, 0x6e,
+	0xd2, 0x36, 0xe2, 0xcb, 0x62, 0x38, 0xd3, 0x4b, 0x4e, 0x38, 0x3b, 0x3d, 0x9a, 0xee, 0x95, 0xb8,
+	0x06, 0x72, 0x0a, 0x90, 0x43, 0x82, 0x20, 0xb7, 0xfc, 0x80, 0x20, 0x87, 0x9c, 0x72, 0x8a, 0x0f,
+	0xb9, 0xe5, 0x2f, 0xe4, 0x12, 0xe4, 0x12, 0xe4, 0x0f, 0xe4, 0x07, 0xe4, 0x18, 0x54, 0x75, 0xf7,
+	0x3c, 0x96, 0x4b, 0xd2, 0x82, 0x4e, 0xec, 0xaa, 0xae, 0xee, 0xa9, 0x77, 0x55, 0xd7, 0x12, 0x2e,
+	0x17, 0xe1, 0x54, 0xe7, 0x07, 0x6f, 0xe2, 0x9f, 0x9b, 0x79, 0x21, 0xb5, 0x64, 0x3d, 0x83, 0x0a,
+	0xfe, 0xd0, 0x85, 0xfe, 0x1d, 0x39, 0x9b, 0x85, 0x59, 0xcc, 0xae, 0x43, 0x6f, 0x26, 0xf4, 0x91,
+	0x8c, 0x7d, 0x6f, 0xcb, 0xdb, 0x1e, 0x72, 0x0b, 0xb1, 0x4b, 0xd0, 0x3e, 0x16, 0x0b, 0xbf, 0x45,
+	0x48, 0x5c, 0xb2, 0xab, 0xd0, 0x7d, 0x14, 0xa6, 0x73, 0xe1, 0xb7, 0xb7, 0xbc, 0xed, 0x36, 0x37,
+	0x00, 0xbb, 0x01, 0xad, 0x43, 0xed, 0x77, 0xb6, 0xbc, 0xed, 0xd1, 0xad, 0x67, 0x6f, 0x9a, 0x0f,
+	0xdc, 0xfc, 0x71, 0x2a, 0x0f, 0xc2, 0x74, 0xbf, 0x08, 0x33, 0x15, 0x46, 0x3a, 0x91, 0x19, 0x6f,
+	0x1d, 0x6a, 0xb6, 0x05, 0x9d, 0x48, 0x66, 0xb1, 0xdf, 0x25, 0xe2, 0xb1, 0x23, 0xbe, 0x23, 0xb3,
+	0x98, 0xd3, 0x0e, 0xdb, 0x82, 0x96, 0x92, 0x7e, 0x8f, 0xf6, 0x2f, 0xb9, 0xfd, 0xbd, 0xa3, 0xb0,
+	0x88, 0x1f, 0xe4, 0x8a, 0xb7, 0x94, 0x64, 0x0c, 0x3a, 0x07, 0xa9, 0x3c, 0xf0, 0xfb, 0x5b, 0xde,
+	0xf6, 0x98, 0xd3, 0x1a, 0x19, 0x8b, 0x64, 0x2c, 0x22, 0x7f, 0x40, 0xcc, 0x1a, 0x80, 0x6d, 0xc2,
+	0xa0, 0x10, 0x8f, 0x12, 0x95, 0xc8, 0xcc, 0x1f, 0x12, 0xc7, 0x25, 0x8c, 0x27, 0xd2, 0x64, 0x96,
+	0x68, 0x1f, 0x8c, 0x28, 0x04, 0xa0, 0x2a, 0x1e, 0x89, 0x22, 0x99, 0x2e, 0xfc, 0xd1, 0x96, 0xb7,
+	0x3d, 0xe0, 0x16, 0x62, 0x3e, 0xf4, 0x95, 0x50, 0x74, 0xd1, 0x98, 0xbe, 0xe0, 0x40, 0x54, 0x92,
+	0x12, 0x0f, 0xfd, 0x35, 0xba, 0x05, 0x97, 0xc8, 0x9f, 0x4e, 0x66, 0xc2, 0x5f, 0x27, 0x14, 0xad,
+	0x91, 0x93, 0xbc, 0x48, 0x64, 0x91, 0xe8, 0x85, 0xbf, 0xb1, 0xe5, 0x6d, 0x77, 0x79, 0x09, 0xb3,
+	0xd7, 0xa1, 0x1f, 0xc9, 0x59, 0x1e, 0x16, 0xc2, 0xbf, 0x44, 0x62, 0xb3, 0x4a, 0x2d, 0x84, 0xde,
+	0x3f, 0xc9, 0xb8, 0x23, 0x61, 0xff, 0x0f, 0xe3, 0x59, 0x92, 0x4d, 0x4a, 0xb9, 0x2e, 0xd3, 0x57,
+	0x46, 0xb3, 0x24, 0xe3, 0x4e, 0xb4, 0x2d, 0x18, 0x45, 0x32, 0x53, 0x89, 0xd2, 0x22, 0x8b, 0x16,
+	0x3e, 0x23, 0x86, 0xeb, 0x28, 0x64, 0x3a, 0x8c, 0x8e, 0xfd, 0x2b, 0xc6, 0xb2, 0x61, 0x74, 0x8c,
+	0xea, 0x08, 0xa7, 0x5a, 0x14, 0xfe, 0x55, 0xa3, 0x40, 0x02, 0x50, 0x1d, 0x51, 0x9a, 0x88, 0x4c,
+	0xfb, 0xd7, 0x8c, 0x67, 0x18, 0x08, 0xcf, 0x6b, 0x9d, 0xfa, 0xd7, 0x8d, 0xd0, 0x5a, 0xa7, 0xa8,
+	0x20, 0x71, 0x92, 0x27, 0x85, 0x50, 0xfe, 0x33, 0x84, 0x75, 0x20, 0xfb, 0x3f, 0x18, 0xaa, 0xe4,
+	0x30, 0x0b, 0xf5, 0xbc, 0x10, 0xbe, 0x4f, 0x36, 0xab, 0x10, 0xec, 0x39, 0xb3, 0x2b, 0xe2, 0x49,
+	0xa8, 0xfd, 0x67, 0x8d, 0x8d, 0x0c, 0xe2, 0xb6, 0x0e, 0x42, 0xf2, 0x51, 0x12, 0xdb, 0xfa, 0xa2,
+	0x57, 0xf9, 0xe2, 0x75, 0xe8, 0xe9, 0xb0, 0x38, 0x14, 0xda, 0x3a, 0xa8, 0x85, 0x10, 0x5f, 0x08,
+	0x35, 0x4f, 0x35, 0x39, 0xe9, 0x90, 0x5b, 0xa8, 0xf2, 0xdd, 0x4e, 0xcd, 0x77, 0x83, 0xdf, 0x7a,
+	0x00, 0x95, 0x9a, 0xd9, 0x8d, 0xca, 0x16, 0xde, 0x56, 0x7b, 0x7b, 0x74, 0x6b, 0x63, 0xc9, 0x16,
+	0x95, 0x21, 0x6e, 0x40, 0x5f, 0xcd, 0xa3, 0x48, 0x28, 0xe5, 0xb7, 0x4e, 0x91, 0x62, 0x5c, 0x71,
+	0xb7, 0x8f, 0xa4, 0xd3, 0x30, 0x49, 0x51, 0x01, 0xed, 0x33, 0x48, 0xed, 0x7e, 0xf0, 0x39, 0x74,
+	0x30, 0x18, 0x56, 0xc8, 0x5b, 0xf2, 0xdf, 0xaa, 0xc7, 0x1e, 0xba, 0x83, 0x8c, 0x2b, 0x77, 0x68,
+	0x5b, 0x77, 0x90, 0xb1, 0x73, 0x87, 0xe0, 0x97, 0x1e, 0xf4, 0x3f, 0x11, 0x8b, 0x5d, 0xa1, 0x43,
+	0xf6, 0x2a, 0x6c, 0x44, 0x85, 0x08, 0xb5, 0xa8, 0x4e, 0x78, 0x74, 0x62, 0xdd, 0xa0, 0x4b, 0x1f,
+	0x5a, 0xbe, 0xb7, 0x75, 0xea, 0x5e, 0x34, 0xf9, 0x23, 0x51, 0xd4, 0xbe, 0xea, 0x40, 0x8c, 0x00,
+	0x95, 0x7c, 0xe3, 0x34, 0x4d, 0xeb, 0xe0, 0xdb, 0x16, 0xf4, 0x3f, 0xf9, 0xf2, 0x5e, 0xa6, 0x8b,
+	0xc5, 0x77, 0x16, 0xce, 0x45, 0x7a, 0x7b, 0x55, 0xa4, 0x77, 0xea, 0x91, 0xfe, 0x12, 0x74, 0x66,
+	0x42, 0x87, 0x36, 0xaf, 0x94, 0xea, 0xb5, 0x62, 0x73, 0xda, 0x64, 0x1f, 0xc0, 0xfa, 0x4c, 0xcc,
+	0x0e, 0x44, 0x31, 0x71, 0x7c, 0x9b, 0x34, 0x73, 0xcd, 0x91, 0xef, 0xd2, 0xee, 0x97, 0x66, 0x93,
+	0xaf, 0xcd, 0xea, 0x20, 0xd9, 0xdb, 0xa6, 0x80, 0x7e, 0xf3, 0x2b, 0x7b, 0x06, 0x5d, 0xe5, 0x84,
+	0x1f, 0x00, 0x28, 0x8d, 0x4a, 0x3e, 0x0a, 0xd5, 0x11, 0xa5, 0xa4, 0xd1, 0xad, 0xcb, 0x25, 0x35,
+	0xee, 0xdc, 0x0f, 0xd5, 0x11, 0x1f, 0x2a, 0xb7, 0xac, 0x87, 0xcf, 0xb0, 0x11, 0x3e, 0xc1, 0xbb,
+	0xb0, 0xd6, 0x60, 0x8b, 0xad, 0x43, 0x2b, 0x71, 0x99, 0xba, 0x95, 0xc4, 0x75, 0x33, 0xb4, 0x28,
+	0xb3, 0x38, 0x30, 0xf8, 0x93, 0x87, 0x67, 0x8b, 0xe3, 0x54, 0x70, 0xf1, 0x70, 0x2e, 0x14, 0xc5,
+	0x40, 0x92, 0xc5, 0xe2, 0xc4, 0x1a, 0xdd, 0x00, 0x88, 0xcd, 0x64, 0x2c, 0x8c, 0x1f, 0x77, 0xb9,
+	0x01, 0xf0, 0xde, 0x83, 0x79, 0x74, 0x2c, 0xb4, 0x22, 0xa7, 0xed, 0x72, 0x07, 0x62, 0x84, 0x29,
+	0x39, 0x2f, 0x22, 0x61, 0x6d, 0x60, 0xa1, 0x66, 0xa4, 0x77, 0xcf, 0x8d, 0xf4, 0xde, 0x52, 0xa4,
+	0x4f, 0x61, 0xe4, 0x38, 0xcd, 0xd3, 0xc5, 0x19, 0x7c, 0x5e, 0x87, 0x1e, 0x2a, 0xd4, 0x32, 0xda,
+	0xe1, 0x16, 0x42, 0xcb, 0x88, 0x4c, 0x17, 0x89, 0x50, 0xcb, 0xe1, 0x65, 0x1d, 0x8e, 0xbb, 0xfd,
+	0x60, 0x07, 0x86, 0xa5, 0xfe, 0xcf, 0xf8, 0x0a, 0x83, 0x0e, 0x99, 0x0d, 0x95, 0xd9, 0xe1, 0xb4,
+	0x46, 0x1c, 0x2a, 0xc5, 0x66, 0x14, 0x5a, 0x07, 0xbf, 0xf7, 0xa0, 0x6f, 0x2d, 0x7f, 0xca, 0x26,
+	0xcf, 0xc2, 0x20, 0x0d, 0x95, 0x9e, 0x60, 0x65, 0x30, 0x1e, 0xdd, 0x47, 0x78, 0x4f, 0x3c, 0x64,
+	0x2f, 0xc2, 0x88, 0xb6, 0xb0, 0x28, 0x3e, 0x72, 0x85, 0x14, 0x10, 0x75, 0x9b, 0x30, 0xec, 0x06,
+	0x74, 0x0b, 0x54, 0x82, 0x2d, 0xa8, 0x57, 0x9c, 0x2c, 0xfc, 0xb3, 0x3b, 0x5c, 0xa8, 0x5c, 0x66,
+	0x4a, 0x70, 0x43, 0x81, 0x02, 0x88, 0xa2, 0x90, 0x05, 0x29, 0x7b, 0xc8, 0x0d, 0x80, 0xf1, 0x3e,
+	0xda, 0x99, 0xe5, 0xb2, 0xd0, 0x77, 0x8e, 0xe6, 0xd9, 0xf1, 0x29, 0xe6, 0x6a, 0xea, 0x6a, 0x9d,
+	0xaf, 0xae, 0xa6, 0x45, 0xdb, 0xe7, 0x5a, 0xb4, 0xb3, 0x64, 0xd1, 0xbf, 0xb7, 0xe0, 0xf2, 0xa9,
+	0x1e, 0x80, 0x6a, 0xe3, 0x49, 0xc9, 0x0d, 0xad, 0xd9, 0xab, 0xd0, 0x89, 0x66, 0xb1, 0xf2, 0x5b,
+	0x4b, 0xf2, 0x86, 0x53, 0xed, 0xd2, 0x23, 0x11, 0xa0, 0x47, 0x46, 0xf2, 0x48, 0x16, 0xd6, 0x23,
+	0x87, 0xdc, 0x81, 0xec, 0x6b, 0xb8, 0xac, 0xb0, 0x45, 0x98, 0x68, 0x39, 0x89, 0xcc, 0x19, 0xe5,
+	0x77, 0x48, 0xb8, 0x9b, 0x67, 0x36, 0x24, 0xa6, 0xab, 0xd8, 0x97, 0xf6, 0x23, 0xca, 0xc8, 0xbe,
+	0xa1, 0x9a, 0x58, 0x54, 0x72, 0x7e, 0x14, 0x2a, 0xe1, 0x94, 0x4c, 0x00, 0x7b, 0x9e, 0x42, 0xbc,
+	0xd0, 0x13, 0x2a, 0xf5, 0xc6, 0x9d, 0x87, 0x84, 0xd9, 0x4f, 0x66, 0x62, 0x73, 0x1f, 0xae, 0xae,
+	0xba, 0xbd, 0x9e, 0xf9, 0xda, 0x26, 0xf3, 0xbd, 0x52, 0xcf, 0x7c, 0xab, 0x5a, 0x1e, 0xb3, 0xfd,
+	0x5e, 0xeb, 0x1d, 0x2f, 0xf8, 0x4f, 0x17, 0xfa, 0xfb, 0x27, 0x49, 0xbc, 0x1b, 0xe6, 0xec, 0xfb,
+	0xd0, 0x9e, 0x85, 0xb9, 0xad, 0x52, 0xbe, 0x3b, 0x65, 0x77, 0x6f, 0xee, 0x86, 0xb9, 0x11, 0x07,
+	0x89, 0xd8, 0xbb, 0xd8, 0x07, 0xe5, 0x69, 0x12, 0x85, 0xce, 0xe4, 0xcf, 0x2f, 0x1f, 0xe0, 0x76,
+	0xdf, 0x9c, 0x2a, 0xc9, 0xd9, 0x5b, 0xd0, 0xcb, 0x65, 0x9a, 0x44, 0x0b, 0x1b, 0x5a, 0xcf, 0x2d,
+	0x1f, 0xfc, 0x8c, 0x76, 0xcd, 0x31, 0x4b, 0x8a, 0xdd, 0x8e, 0x96, 0xb9, 0x4c, 0xe5, 0xa1, 0xf1,
+	0xe2, 0x31, 0x2f, 0x61, 0xf6, 0x21, 0x80, 0x46, 0x1b, 0x4c, 0x65, 0x31, 0x53, 0x7e, 0x97, 0x2e,
+	0x7d, 0x71, 0xf9, 0xd2, 0xfd, 0x92, 0xc2, 0x5c, 0x5c, 0x3b, 0xc2, 0xde, 0x80, 0x8e, 0xd6, 0xa9,
+	0xf2, 0x7b, 0x5b, 0xed, 0x7a, 0xbf, 0x59, 0x1e, 0xd5, 0xa9, 0x3d, 0x44, 0x64, 0x28, 0xbb, 0xcd,
+	0x87, 0xca, 0xef, 0xaf, 0x96, 0xdd, 0x66, 0x56, 0x27, 0xbb, 0x23, 0xdf, 0xfc, 0x1c, 0x06, 0x4e,
+	0x8f, 0x2b, 0x4a, 0xd6, 0x9b, 0x4d, 0xc3, 0x9d, 0xd3, 0xf8, 0x56, 0x16, 0xdc, 0x7c, 0x1f, 0xd6,
+	0x1a, 0x9a, 0x5e, 0xe1, 0x10, 0x8d, 0x52, 0xd8, 0xad, 0x1f, 0x7e, 0x17, 0x46, 0x35, 0x6d, 0x5f,
+	0x54, 0x45, 0xc7, 0xf5, 0xa3, 0x3f, 0x82, 0x8d, 0x25, 0x9d, 0x3e, 0xd1, 0xf1, 0x1f, 0xc2, 0xb0,
+	0xd4, 0xeb, 0x13, 0x1d, 0x7c, 0x1f, 0xd6, 0x1a, 0xda, 0xbd, 0xe8, 0x70, 0x5d, 0xde, 0xe0, 0x17,
+	0xd0, 0x7b, 0x90, 0x2b, 0x74, 0xf6, 0x1b, 0x75, 0x67, 0x7f, 0xc6, 0x69, 0xda, 0x6c, 0x36, 0x7d,
+	0x7d, 0xf3, 0xfe, 0xb9, 0x46, 0x7b, 0x92, 0x68, 0xfb, 0xaf, 0x07, 0x03, 0x87, 0x5f, 0x99, 0xb8,
+	0x9e, 0x07, 0x98, 0x85, 0x4a, 0x8b, 0x62, 0x52, 0x3d, 0x93, 0x86, 0x06, 0xf3, 0x89, 0x58, 0x94,
+	0x79, 0xad, 0x7d, 0x51, 0x5e, 0x2b, 0x33, 0x4c, 0xa7, 0x9e, 0x61, 0xe8, 0xf1, 0x12, 0xc6, 0x0f,
+	0xb2, 0x74, 0x41, 0xa9, 0x67, 0xc0, 0x4b, 0xb8, 0x99, 0x97, 0x7b, 0xe7, 0xe6, 0xe5, 0x7e, 0x33,
+	0x2f, 0xb3, 0x97, 0x60, 0x4d, 0xce, 0x75, 0x24, 0x67, 0x62, 0x62, 0xca, 0xcc, 0x80, 0xee, 0x1e,
+	0x5b, 0x24, 0xd5, 0xdf, 0xe0, 0xdb, 0x3e, 0x8c, 0x6a, 0xf5, 0x86, 0x2a, 0xbe, 0x0e, 0xf5, 0x5c,
+	0x91, 0xfc, 0x5d, 0x6e, 0xa1, 0xb3, 0xdb, 0xb6, 0x30, 0x8e, 0x0b, 0x57, 0x2d, 0x71, 0x7d, 0x86,
+	0x8c, 0xaf, 0xc1, 0xa0, 0x4c, 0xd7, 0xdd, 0xd5, 0x9d, 0x71, 0x49, 0x50, 0x76, 0x83, 0xbd, 0x55,
+	0xdd, 0x60, 0x7f, 0x55, 0x37, 0x38, 0x38, 0xaf, 0x1b, 0xac, 0x95, 0xc1, 0xe1, 0x05, 0x65, 0xf0,
+	0x75, 0xe8, 0xce, 0x55, 0x78, 0x28, 0x7c, 0x20, 0xc2, 0xeb, 0x8e, 0xf0, 0xd3, 0x70, 0x26, 0x54,
+	0x1e, 0x46, 0xe2, 0x0b, 0xdc, 0xe5, 0x86, 0x88, 0xdd, 0x80, 0x81, 0x4a, 0xe5, 0xe3, 0x89, 0xcc,
+	0x95, 0x3f, 0xa2, 0x03, 0xeb, 0xa5, 0x9b, 0xa5, 0xf2, 0xf1, 0x83, 0x9c, 0xf7, 0x15, 0xfd, 0x55,
+	0xec, 0x6d, 0xe8, 0xa2, 0x26, 0x95, 0x3f, 0x26, 0xba, 0x17, 0x56, 0xd4, 0x7a, 0xea, 0x17, 0x6d,
+	0x6a, 0x32, 0xc4, 0xec, 0x26, 0xf4, 0x4d, 0x6b, 0xaa, 0xfc, 0x35, 0x3a, 0x77, 0xb5, 0xcc, 0x3d,
+	0x85, 0x9c, 0xe7, 0xa6, 0x5d, 0x54, 0xdc, 0x11, 0xa1, 0x92, 0xd0, 0x5f, 0x95, 0xbf, 0x4e, 0x55,
+	0xd3, 0x00, 0xec, 0x65, 0xe8, 0xa6, 0x32, 0x3a, 0x56, 0xfe, 0xc6, 0x92, 0xf4, 0x62, 0xf1, 0x53,
+	0x19, 0x1d, 0x73, 0xb3, 0xcb, 0xbe, 0x67, 0x5b, 0x9f, 0x4b, 0xcd, 0x80, 0xf9, 0x54, 0xc6, 0x62,
+	0x27, 0x9b, 0x4a, 0xd3, 0x0c, 0xb1, 0x1b, 0x70, 0x89, 0xde, 0x45, 0x91, 0x5e, 0x7e, 0x99, 0x6e,
+	0x58, 0x7c, 0xf9, 0x6c, 0xa8, 0x3f, 0xca, 0xd9, 0xd2, 0xa3, 0xfc, 0x6d, 0x18, 0x57, 0x8d, 0xb3,
+	0x50, 0xfe, 0x95, 0xad, 0xf6, 0xea, 0xd6, 0x79, 0x54, 0xb6, 0xce, 0x02, 0x6b, 0xd4, 0xc8, 0x54,
+	0x7f, 0xa3, 0xcb, 0xab, 0xcd, 0x47, 0x34, 0x85, 0x30, 0x29, 0x91, 0x83, 0x2a, 0xd7, 0xec, 0x0d,
+	0xe8, 0x9b, 0x87, 0xa1, 0xf2, 0xaf, 0x6d, 0xb5, 0xeb, 0x01, 0xfa, 0x55, 0x91, 0x68, 0xc1, 0x69,
+	0x8f, 0x3b, 0x1a, 0x54, 0x03, 0x46, 0x9f, 0x7f, 0xbd, 0xa9, 0x06, 0x2e, 0xc2, 0xd8, 0xa8, 0x01,
+	0x77, 0x31, 0x23, 0x44, 0xe9, 0x9c, 0x52, 0x42, 0x12, 0xd3, 0x43, 0x78, 0xc8, 0x87, 0x16, 0xb3,
+	0x13, 0xb3, 0x97, 0xa1, 0x83, 0x4a, 0xa5, 0x57, 0x70, 0x4d, 0x2c, 0x54, 0xf7, 0x3d, 0x6c, 0xdd,
+	0x38, 0x6d, 0x6f, 0xbe, 0x03, 0x50, 0x19, 0xfd, 0xa2, 0x8c, 0x39, 0xac, 0xa7, 0xac, 0xdf, 0x79,
+	0x30, 0x2c, 0x6f, 0x5b, 0xfd, 0x66, 0x3e, 0x92, 0x69, 0x2c, 0x0a, 0xf7, 0x66, 0x36, 0xd0, 0xaa,
+	0xfe, 0x96, 0xbd, 0x00, 0xa3, 0x23, 0x91, 0xc6, 0x93, 0xa9, 0x2c, 0x26, 0x33, 0x65, 0xfb, 0xbb,
+	0x21, 0xa2, 0x3e, 0x96, 0xc5, 0x2e, 0x6a, 0x64, 0xbd, 0x10, 0xba, 0x58, 0x4c, 0x68, 0x54, 0x30,
+	0xa1, 0x62, 0x8e, 0x24, 0x63, 0xc2, 0xde, 0x46, 0xe4, 0xae, 0x0a, 0xfe, 0xdc, 0x82, 0xae, 0xe1,
+	0x86, 0xe1, 0xe8, 0x27, 0x16, 0x36, 0x83, 0xd0, 0x1a, 0x3b, 0xba, 0x99, 0x50, 0x14, 0x5a, 0x86,
+	0x21, 0x07, 0x22, 0xa7, 0xa9, 0x08, 0x91, 0x53, 0xfb, 0x8a, 0x37, 0x10, 0xbe, 0x4b, 0x23, 0x99,
+	0x4d, 0xd3, 0x24, 0xd2, 0x94, 0x75, 0x3b, 0xe5, 0x70, 0x83, 0x70, 0x26, 0xef, 0x6e, 0x94, 0x24,
+	0x85, 0x08, 0x95, 0xcc, 0x6c, 0xeb, 0xb6, 0xee, 0xd0, 0x9c, 0xb0, 0x98, 0x0a, 0x4b, 0x42, 0x4a,
+	0xee, 0x3d, 0x22, 0x2b, 0x3f, 0x80, 0xfd, 0x01, 0xdb, 0x86, 0x4b, 0x46, 0xcc, 0x83, 0x30, 0x3a,
+	0x96, 0xd3, 0x29, 0x0a, 0x6a, 0x72, 0xaa, 0x11, 0xff, 0x23, 0x83, 0xde, 0x55, 0x98, 0x76, 0xd1,
+	0x7c, 0x13, 0xd2, 0xa4, 0x99, 0x43, 0x0d, 0x10, 0x81, 0xa1, 0xc2, 0x5e, 0x85, 0x4b, 0xb4, 0x59,
+	0x57, 0xa9, 0x79, 0xe9, 0xad, 0x21, 0xfe, 0xbe, 0x53, 0x6b, 0xf0, 0x17, 0x0f, 0x06, 0xce, 0xab,
+	0x4a, 0xbb, 0x78, 0x35, 0xbb, 0x5c, 0x85, 0x2e, 0xb9, 0xb1, 0xcb, 0xb9, 0x04, 0x10, 0x16, 0x43,
+	0xc2, 0xaa, 0xcb, 0x00, 0x28, 0x61, 0x98, 0xe7, 0x69, 0x22, 0xe2, 0x89, 0x79, 0xe9, 0x18, 0x2b,
+	0x8e, 0x2d, 0x72, 0x07, 0x71, 0xa8, 0x52, 0x47, 0xa4, 0x45, 0x31, 0xb3, 0x66, 0x1c, 0x59, 0xdc,
+	0xbe, 0x28, 0x66, 0xcb, 0x13, 0xa5, 0xde, 0xa9, 0x89, 0x52, 0xf0, 0x6f, 0x0f, 0x06, 0x2e, 0x27,
+	0x9c, 0x7a, 0x71, 0xb8, 0x82, 0xd0, 0xaa, 0x15, 0x04, 0x06, 0x9d, 0x6f, 0x64, 0x56, 0xba, 0x1c,
+	0xae, 0x31, 0x35, 0x44, 0x61, 0x1e, 0x46, 0x38, 0x25, 0xb3, 0xef, 0x09, 0x07, 0xd7, 0x9f, 0xb9,
+	0xdd, 0xc6, 0x33, 0x17, 0x77, 0x1e, 0x27, 0x3a, 0x13, 0x4a, 0x11, 0x63, 0x03, 0xee, 0xc0, 0x4a,
+	0x29, 0xfd, 0xba, 0x52, 0xd0, 0x4e, 0xe6, 0x71, 0x26, 0x32, 0xb2, 0x53, 0x9b, 0x0f, 0xcc, 0xeb,
+	0x4c, 0xd0, 0x65, 0x36, 0x5e, 0xc9, 0x3c, 0x43, 0xee, 0xc0, 0xe0, 0x98, 0xa6, 0x28, 0x18, 0x5d,
+	0x2b, 0x02, 0xcb, 0xb5, 0x07, 0xad, 0x5a, 0x7b, 0x80, 0x5f, 0x4f, 0xb2, 0xa8, 0x1c, 0x96, 0x12,
+	0x80, 0x67, 0xd1, 0xdd, 0x8d, 0x78, 0xb8, 0x2c, 0x8d, 0xdc, 0xad, 0x3d, 0x2e, 0x7f, 0xed, 0xc1,
+	0xb8, 0x9e, 0xcc, 0xf1, 0xb2, 0x43, 0x84, 0xed, 0x47, 0x0d, 0x40, 0xe3, 0x4a, 0xa9, 0x45, 0x61,
+	0xda, 0xfa, 0x21, 0xb7, 0x10, 0xf6, 0x07, 0x99, 0xcc, 0xec, 0x96, 0x79, 0x2b, 0x55, 0x08, 0xac,
+	0x1f, 0xa6, 0x33, 0x75, 0x6f, 0xa4, 0xab, 0xcd, 0x01, 0xc8, 0x6d, 0xda, 0xe4, 0x8e, 0x28, 0xf8,
+	0x95, 0x07, 0x3d, 0x53, 0xb9, 0xca, 0xd9, 0xa6, 0x57, 0x9b, 0x6d, 0x32, 0xe8, 0x1c, 0x27, 0x59,
+	0x29, 0x3b, 0xae, 0x9d, 0x86, 0xda, 0xa7, 0x35, 0xd4, 0xa9, 0x69, 0x68, 0x13, 0x06, 0xf1, 0xbc,
+	0x08, 0xb5, 0x33, 0x6a, 0x9b, 0x97, 0x70, 0xa9, 0x95, 0x5e, 0x4d, 0x2b, 0x39, 0xac, 0x37, 0x4b,
+	0x2e, 0x09, 0xea, 0x30, 0x56, 0x35, 0x15, 0x82, 0x38, 0x13, 0x0b, 0x65, 0x23, 0x85, 0xd6, 0xa8,
+	0xc8, 0x83, 0x85, 0x16, 0xca, 0x59, 0x85, 0x00, 0x54, 0xe4, 0x63, 0x4c, 0xfb, 0x2e, 0xcf, 0x59,
+	0x28, 0x38, 0x84, 0x51, 0xad, 0x1c, 0x9c, 0x31, 0x31, 0x38, 0x3d, 0x27, 0xaf, 0xd7, 0xb8, 0xf6,
+	0xe9, 0xc1, 0xb3, 0x79, 0xb4, 0x77, 0xea, 0x8f, 0xf6, 0xdf, 0x78, 0x00, 0x55, 0xa5, 0x2a, 0x39,
+	0xf7, 0x56, 0x71, 0xde, 0xaa, 0x73, 0xfe, 0x22, 0x8c, 0x28, 0xff, 0x4f, 0x70, 0xca, 0x66, 0x8c,
+	0xdd, 0xe6, 0x40, 0xa8, 0x3d, 0xc4, 0xb0, 0x5b, 0x38, 0x7a, 0x16, 0xd3, 0xe4, 0x44, 0x38, 0x73,
+	0x9f, 0xd5, 0xbf, 0x94, 0x74, 0xc1, 0x3f, 0x3c, 0x18, 0xd5, 0xfa, 0xd4, 0x46, 0x9f, 0xe6, 0x5d,
+	0xd4, 0xa7, 0x5d, 0x83, 0x5e, 0xa2, 0x26, 0xfa, 0xc4, 0xcc, 0xa3, 0x06, 0xbc, 0x9b, 0x28, 0x33,
+	0x5a, 0xed, 0x1e, 0x84, 0x3a, 0x3a, 0xf2, 0xdb, 0xcd, 0x72, 0x5b, 0xfb, 0x0e, 0x37, 0x14, 0x58,
+	0x46, 0x29, 0x59, 0x56, 0x73, 0x90, 0x01, 0xa7, 0xdc, 0x6a, 0xa6, 0x43, 0x4f, 0x31, 0x67, 0xfa,
+	0x97, 0x07, 0xfd, 0x9f, 0xc8, 0x24, 0xdb, 0x55, 0x87, 0x98, 0xd4, 0xf0, 0xdb, 0xb7, 0xe3, 0xb8,
+	0x10, 0xca, 0xa8, 0x7a, 0xc8, 0xeb, 0x28, 0xcc, 0x63, 0x3b, 0x77, 0xad, 0x5d, 0x5b, 0x3b, 0x77,
+	0xd1, 0x2a, 0xfb, 0x3f, 0xfb, 0xec, 0x9e, 0xcb, 0x59, 0xb8, 0xc6, 0x84, 0x61, 0x5f, 0x38, 0xc4,
+	0x68, 0x97, 0x3b, 0x10, 0x9d, 0xe0, 0x53, 0x1b, 0x73, 0xae, 0x81, 0x77, 0x30, 0xee, 0xed, 0x59,
+	0x9e, 0x1c, 0x8f, 0x0e, 0x46, 0xf1, 0xf6, 0x4a, 0xf1, 0xcc, 0x8f, 0x1c, 0x15, 0x02, 0x77, 0xef,
+	0xd8, 0x86, 0xe2, 0xae, 0xad, 0x32, 0x15, 0x22, 0xf8, 0xa7, 0x07, 0x63, 0x13, 0xc4, 0x77, 0x8e,
+	0xc2, 0xec, 0x90, 0x2a, 0x6c, 0x5e, 0xc8, 0x99, 0xd4, 0x66, 0xa0, 0x3d, 0xe4, 0x0e, 0x34, 0x73,
+	0xf2, 0x99, 0x7c, 0x24, 0x5c, 0xee, 0x30, 0x10, 0x7b, 0x05, 0x3a, 0x3f, 0x97, 0x49, 0x66, 0xcd,
+	0xc4, 0x9a, 0xa9, 0x01, 0x75, 0xc7, 0x69, 0x1f, 0x45, 0x30, 0xaf, 0x72, 0xe1, 0x5c, 0xb9, 0x84,
+	0xd9, 0x33, 0xd0, 0x8f, 0x8b, 0xc5, 0xa4, 0x98, 0x67, 0x56, 0xf2, 0x5e, 0x5c, 0x2c, 0xf8, 0x3c,
+	0x7b, 0x8a, 0x87, 0x4b, 0xa0, 0x00, 0x2a, 0x1e, 0x56, 0x4d, 0x41, 0x43, 0x6b, 0x48, 0xdb, 0x49,
+	0x58, 0x90, 0xbd, 0x0c, 0xeb, 0x66, 0x4c, 0x34, 0x71, 0x04, 0xc6, 0x7c, 0x6b, 0x06, 0xeb, 0x6c,
+	0x8d, 0x4d, 0x15, 0x99, 0xca, 0xb8, 0x9b, 0x01, 0x82, 0xfb, 0x30, 0xae, 0xe7, 0x44, 0xfc, 0xac,
+	0x74, 0x39, 0xb8, 0x25, 0x73, 0xcb, 0x46, 0x6b, 0x15, 0x1b, 0xed, 0x06, 0x1b, 0xc1, 0xdf, 0x5a,
+	0xb0, 0xb6, 0x97, 0x85, 0xb9, 0x3a, 0x92, 0x76, 0x2e, 0x57, 0xfb, 0x4d, 0xc9, 0x6b, 0xfe, 0xa6,
+	0xb4, 0xe2, 0xd6, 0xfa, 0xa4, 0xbd, 0x56, 0xfb, 0xca, 0x84, 0xd4, 0xa1, 0x69, 0x65, 0x35, 0xc2,
+	0x2c, 0x2b, 0x79, 0x87, 0xd3, 0x9a, 0xbd, 0x63, 0x9a, 0x9d, 0xe4, 0xd0, 0x25, 0xdc, 0x5e, 0xd3,
+	0xbe, 0xe8, 0xf7, 0x7b, 0xa2, 0x78, 0x24, 0x0a, 0xde, 0x24, 0x64, 0x6f, 0xc2, 0x95, 0x06, 0xc2,
+	0xb6, 0x12, 0x7d, 0xba, 0x9c, 0x35, 0xb6, 0x76, 0xdc, 0xe7, 0x69, 0xfc, 0x3f, 0xa8, 0xc6, 0xff,
+	0xe8, 0x6d, 0x72, 0x3a, 0x55, 0x42, 0xdb, 0xae, 0xc7, 0x42, 0x48, 0x1b, 0x87, 0x3a, 0xa4, 0x5f,
+	0xe1, 0xc6, 0x9c, 0xd6, 0xb5, 0xde, 0xcf, 0xfe, 0x08, 0x67, 0xa0, 0x80, 0x03, 0x54, 0x5c, 0x3e,
+	0x81, 0x07, 0x6c, 0xc2, 0x40, 0xcd, 0xa7, 0xd3, 0x02, 0xeb, 0xae, 0xd1, 0x5f, 0x09, 0x07, 0x7f,
+	0xf5, 0x60, 0xfc, 0x15, 0x26, 0x1d, 0x37, 0x22, 0x5f, 0xbe, 0xf6, 0x3a, 0xf4, 0x4c, 0x5a, 0x74,
+	0x2d, 0xb3, 0x81, 0xaa, 0x1f, 0xcc, 0x6c, 0x1d, 0x21, 0x00, 0xc5, 0x79, 0x1c, 0x26, 0x6e, 0x1a,
+	0x4a, 0x6b, 0xbc, 0x21, 0x0a, 0xb3, 0x48, 0xa4, 0x2e, 0x16, 0x0c, 0x84, 0xb4, 0x69, 0xa2, 0xb4,
+	0x6d, 0x5a, 0x68, 0xcd, 0x5e, 0x83, 0xde, 0x34, 0x49, 0xf1, 0xda, 0x7e, 0x73, 0x6a, 0x40, 0x3c,
+	0x7e, 0x4c, 0x5b, 0xdc, 0x92, 0x04, 0x5f, 0xc0, 0xa8, 0x86, 0x36, 0xcd, 0x34, 0xfe, 0x70, 0xab,
+	0x5c, 0xa8, 0x5b, 0x10, 0x79, 0x9d, 0x26, 0x22, 0x75, 0x2e, 0x65, 0x00, 0xe4, 0x4b, 0x3c, 0x9c,
+	0x87, 0xa9, 0x73, 0x55, 0x0b, 0x05, 0x7f, 0xec, 0x54, 0x9e, 0x7a, 0x57, 0xa4, 0x3a, 0xac, 0x3a,
+	0x19, 0xcf, 0x78, 0x19, 0x01, 0x95, 0xef, 0xb5, 0x56, 0xf9, 0x5e, 0xfb, 0x3c, 0xdf, 0xeb, 0x3c,
+	0xa5, 0xef, 0x75, 0xcf, 0xf4, 0xbd, 0xda, 0xab, 0xbe, 0x77, 0xc1, 0xab, 0xde, 0x87, 0x7e, 0x2c,
+	0x52, 0xa1, 0x45, 0x4c, 0x83, 0xc1, 0x21, 0x77, 0x20, 0x96, 0x3b, 0x1b, 0x8a, 0xca, 0x1f, 0x34,
+	0x6f, 0x71, 0xbf, 0xf5, 0x94, 0x04, 0xec, 0xc3, 0xda, 0x80, 0xd1, 0x0c, 0x12, 0x5e, 0x2a, 0x89,
+	0xeb, 0x5a, 0x3c, 0x6b, 0xcc, 0xc8, 0x3e, 0xa8, 0x7e, 0xfb, 0x31, 0xf3, 0x85, 0x60, 0xf5, 0xf9,
+	0x7b, 0x86, 0xc8, 0x49, 0x61, 0xa0, 0xa7, 0x9a, 0xb0, 0x6d, 0xbe, 0x07, 0xe3, 0xfa, 0xad, 0xdf,
+	0xf5, 0x87, 0x39, 0x3c, 0xfb, 0xd1, 0xe0, 0x6b, 0xfb, 0xbf, 0x04, 0x07, 0x3d, 0xfa, 0xd7, 0x82,
+	0xb7, 0xfe, 0x37, 0x00, 0x30, 0x94, 0x71, 0xc5, 0x6f, 0x20, 0x00, 0x00,
 }
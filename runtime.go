@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/coordinator"
+	log "github.com/sirupsen/logrus"
+	flag "github.com/spf13/pflag"
+)
+
+// envPrefix prefixes the environment variables setting the options, as in
+// RAFTKV_LISTEN for --listen.
+const envPrefix = "RAFTKV_"
+
+// envName returns the environment variable of the option name.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// applyEnv sets the options not given on the command line from their
+// environment variable, if set.
+func applyEnv(fs *flag.FlagSet) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Changed || err != nil {
+			return
+		}
+		if v, ok := os.LookupEnv(envName(f.Name)); ok {
+			if serr := fs.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("invalid %s: %s", envName(f.Name), serr)
+			}
+		}
+	})
+	return err
+}
+
+// checkDataDir makes sure the data directory exists and is writable, to fail
+// at startup with a clear error rather than later in raft.
+func checkDataDir(dir string) error {
+	hint := fmt.Sprintf("mount a volume writable by uid %d or set --data-dir", os.Getuid())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("data directory %s cannot be created: %s; %s", dir, err, hint)
+	}
+	f, err := ioutil.TempFile(dir, ".probe")
+	if err != nil {
+		return fmt.Errorf("data directory %s is not writable: %s; %s", dir, err, hint)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// waitForCluster waits up to timeout until the coordinator at addr serves
+// requests and every shard has a leader, for container entrypoints starting
+// clients after the cluster. It returns the exit code.
+func waitForCluster(addr string, timeout time.Duration) int {
+	if addr == "" {
+		flag.Usage()
+		return 2
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(timeout)
+	for {
+		status := clusterStatus(client, addr)
+		if status == "" {
+			fmt.Println("cluster is ready")
+			return 0
+		}
+		if time.Now().After(deadline) {
+			fmt.Fprintf(os.Stderr, "cluster not ready after %s: %s\n", timeout, status)
+			return 1
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// clusterStatus returns why the cluster of the coordinator at addr is not
+// ready, or an empty string if it is.
+func clusterStatus(client *http.Client, addr string) string {
+	resp, err := client.Get(addr + "/shardmap")
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err.Error()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("coordinator replied %d: %s", resp.StatusCode, body)
+	}
+	var m coordinator.ShardMap
+	if err := json.Unmarshal(body, &m); err != nil {
+		return err.Error()
+	}
+	if m.Shards == 0 || len(m.Leaders) < m.Shards {
+		return fmt.Sprintf("%d of %d shards have a leader", len(m.Leaders), m.Shards)
+	}
+	return ""
+}
+
+// advertised returns the address the other nodes discover this node at.
+func advertised() string {
+	if advertiseAddress != "" {
+		return advertiseAddress
+	}
+	addr := joinAddress()
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	if host, err = os.Hostname(); err != nil {
+		return addr
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// joinAddress returns the address the other nodes join this node at: the
+// management address of the coordinators serving one, the HTTP or rpc
+// address otherwise.
+func joinAddress() string {
+	if isCoordinator && common.ManagementAddress != "" {
+		return common.ManagementAddress
+	}
+	return listenAddress
+}
+
+// bootstrapStatefulSet derives the node id, the raft addresses and the nodes
+// to join from the identity of the pod in its StatefulSet, for the options
+// not set. The pod joins the other pods whenever it starts, so that its
+// address is updated once it is rescheduled with a new IP address.
+func bootstrapStatefulSet(log *log.Entry) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	set, err := common.ParseStatefulSet(hostname, statefulSet)
+	if err != nil {
+		return err
+	}
+	if nodeID == "" {
+		nodeID = set.ID()
+	}
+	if raftAddress, err = common.PodAddress(raftAddress, hostname); err != nil {
+		return err
+	}
+	if cohortRaftAddress != "" {
+		if cohortRaftAddress, err = common.PodAddress(cohortRaftAddress, hostname); err != nil {
+			return err
+		}
+	}
+	if joinHTTPAddress == "" {
+		_, port, err := net.SplitHostPort(joinAddress())
+		if err != nil {
+			return err
+		}
+		joinHTTPAddress = set.Join(bootstrapExpect, port)
+	}
+	if joinHTTPAddress == "" {
+		log.Infof("pod %s bootstrapping the raft group at %s", nodeID, raftAddress)
+	} else {
+		log.Infof("pod %s at %s joining the raft group through %s", nodeID, raftAddress, joinHTTPAddress)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ackedOnProposal reports whether the write command is acknowledged once
+// proposed: the unconditional sets and deletes without session of the
+// namespaces acknowledged by the leader, whose outcome does not matter to
+// the client.
+func ackedOnProposal(command *raftpb.Command) bool {
+	return command.Ack == common.AckLeader && command.Cond == nil && command.Session == "" &&
+		(command.Method == common.SET || command.Method == common.DEL)
+}
+
+// proposeUnacked proposes raftCommand and replies without waiting for it to
+// commit, calling done once it is applied. Failures are only logged.
+func (c *Cohort) proposeUnacked(raftCommand *raftpb.RaftCommand, done func(), reply *raftpb.RPCResponse) error {
+	if c.store.raft.State() != raft.Leader {
+		done()
+		return raft.ErrNotLeader
+	}
+	command := raftCommand.Commands[0]
+	command.Time = time.Now().UnixNano()
+	b, err := proto.Marshal(raftCommand)
+	if err != nil {
+		done()
+		return err
+	}
+	start := time.Now()
+	applyFuture := c.store.raft.Apply(b, common.RaftTimeout)
+	go func() {
+		defer done()
+		err := applyFuture.Error()
+		c.store.slow.Observe(common.SlowCommit, command.Key, "", start)
+		if err == nil {
+			if resp, ok := applyFuture.Response().(*FSMApplyResponse); ok {
+				err = resp.err
+			}
+		}
+		if err != nil {
+			c.store.log.Errorf("write of %s acknowledged by the leader failed: %s", command.Key, err)
+		}
+	}()
+	*reply = raftpb.RPCResponse{Status: 0}
+	return nil
+}
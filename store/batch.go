@@ -0,0 +1,99 @@
+package store
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// txnProposal is a committed transaction waiting to be proposed in a batch.
+type txnProposal struct {
+	cmds *raftpb.RaftCommand
+	// rev is the revision of the entry, set before done
+	rev  int64
+	done chan error
+}
+
+// proposeTxn applies the committed transaction cmds through the store raft
+// group, packed with other transactions in a single entry when batching is
+// enabled and every member decodes batches. It returns the revision of the
+// entry, or the index it may have been appended at if it fails.
+func (s *Store) proposeTxn(cmds *raftpb.RaftCommand) (int64, error) {
+	if common.TxnBatchSize <= 1 || s.checkProtocolVersion([]*raftpb.Command{{Method: common.BATCH}}) != nil {
+		b, err := proto.Marshal(cmds)
+		if err != nil {
+			return 0, err
+		}
+		f := s.raft.Apply(b, common.RaftTimeout)
+		return int64(f.Index()), f.Error()
+	}
+	p := &txnProposal{cmds: cmds, done: make(chan error, 1)}
+	s.txnBatch <- p
+	err := <-p.done
+	return p.rev, err
+}
+
+// batchTxns packs the transactions proposed within common.TxnBatchDelay of
+// the first one, up to common.TxnBatchSize, in a single entry. Transactions
+// hold the locks of their keys until applied, so the transactions of a batch
+// are independent.
+func (s *Store) batchTxns() {
+	for p := range s.txnBatch {
+		batch := []*txnProposal{p}
+		timer := time.NewTimer(common.TxnBatchDelay)
+	collect:
+		for len(batch) < common.TxnBatchSize {
+			select {
+			case p := <-s.txnBatch:
+				batch = append(batch, p)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		go s.applyTxnBatch(batch)
+	}
+}
+
+func (s *Store) applyTxnBatch(batch []*txnProposal) {
+	cmd := &raftpb.RaftCommand{IsTxn: true}
+	for _, p := range batch {
+		cmd.Batch = append(cmd.Batch, p.cmds)
+	}
+	b, err := proto.Marshal(cmd)
+	if err != nil {
+		for _, p := range batch {
+			p.done <- err
+		}
+		return
+	}
+	f := s.raft.Apply(b, common.RaftTimeout)
+	err = f.Error()
+	resps, _ := f.Response().([]interface{})
+	for i, p := range batch {
+		p.rev = int64(f.Index())
+		if err == nil && i < len(resps) {
+			if resp, ok := resps[i].(*FSMApplyResponse); ok {
+				p.done <- resp.err
+				continue
+			}
+		}
+		p.done <- err
+	}
+}
+
+// applyBatch applies the transactions of a batch entry in order and returns
+// their responses.
+func (f *fsm) applyBatch(batch []*raftpb.RaftCommand, rev int64) interface{} {
+	resps := make([]interface{}, len(batch))
+	for i, cmds := range batch {
+		start := time.Now()
+		resps[i] = f.applyTransaction(cmds.Commands, rev)
+		if len(cmds.Commands) > 0 {
+			f.slow.Observe(common.SlowApply, cmds.Commands[0].Key, "", start)
+		}
+	}
+	return resps
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Bulk applies the independent writes of args, SET and DEL commands of
+// distinct keys, in a single raft entry, and replies with the result of each,
+// in order. Each write succeeds or fails on its own, like a write of its own.
+func (c *Cohort) Bulk(args *raftpb.RaftCommand, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	if err := c.verify("Cohort.Bulk", args); err != nil {
+		return err
+	}
+	if err := c.store.checkProtocolVersion(append([]*raftpb.Command{{Method: common.BULK}}, args.Commands...)); err != nil {
+		return err
+	}
+	if c.store.isImporting() {
+		return errImportInProgress
+	}
+	done, err := c.store.throttle(args.Commands)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	now := time.Now().UnixNano()
+	entry := &raftpb.RaftCommand{}
+	for _, cmd := range args.Commands {
+		if cmd.Method != common.SET && cmd.Method != common.DEL {
+			return fmt.Errorf("unexpected %s command in a bulk write", cmd.Method)
+		}
+		cmd.Time = now
+		entry.Batch = append(entry.Batch, &raftpb.RaftCommand{Commands: []*raftpb.Command{cmd}})
+	}
+	b, err := proto.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f := c.store.raft.Apply(b, common.RaftTimeout)
+	if err := f.Error(); err != nil {
+		return err
+	}
+	resps, _ := f.Response().([]interface{})
+	*reply = raftpb.RPCResponse{Status: 0, Revision: int64(f.Index())}
+	for i, cmd := range args.Commands {
+		res := &raftpb.WriteResult{Key: cmd.Key, Revision: int64(f.Index())}
+		if i < len(resps) {
+			if r, ok := resps[i].(*FSMApplyResponse); ok && r.err != nil {
+				res.Revision, res.Error = 0, r.err.Error()
+			}
+		}
+		reply.Results = append(reply.Results, res)
+	}
+	return nil
+}
+
+// applyBulk applies the independent writes of batch at revision rev, and
+// returns their responses.
+func (f *fsm) applyBulk(batch []*raftpb.RaftCommand, rev int64) interface{} {
+	resps := make([]interface{}, len(batch))
+	for i, cmds := range batch {
+		start := time.Now()
+		resps[i] = f.applyCommand(cmds.Commands[0], rev)
+		f.slow.Observe(common.SlowApply, cmds.Commands[0].Key, "", start)
+	}
+	return resps
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/rpc"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hashicorp/raft"
@@ -16,6 +17,9 @@ import (
 	"github.com/raft-kv-store/raftpb"
 )
 
+// exportLockTimeout is how long an export waits for a locked key.
+const exportLockTimeout = 100 * time.Millisecond
+
 // Cohort maintains state of the cohort state machine. It also starts
 // a rpc server to listen to commands from coordinator.
 type Cohort struct {
@@ -33,6 +37,13 @@ type Cohort struct {
 	opsMap map[string]*raftpb.ShardOps
 	// TODO use lock per key
 	mu sync.Mutex
+	// txnMu holds off the transaction messages while the locks of the
+	// prepared transactions are taken again
+	txnMu sync.RWMutex
+
+	// imp is the bulk import being staged, if any
+	importMu sync.Mutex
+	imp      *importState
 }
 
 func startCohort(store *Store, listenAddress string, nodeID, raftAddress, raftDir string, enableSingle bool, cohortJoinAddress string) {
@@ -43,13 +54,21 @@ func startCohort(store *Store, listenAddress string, nodeID, raftAddress, raftDi
 		store:       store,
 		opsMap:      make(map[string]*raftpb.ShardOps),
 	}
-	rpc.Register(c)
-	rpc.HandleHTTP()
+	store.versions.Set(nodeID, common.ProtocolVersion)
+	// a server of its own lets a process embedding the node start it again
+	server := rpc.NewServer()
+	server.Register(c)
+	// serve rpc alone, the default mux also carries the debug endpoints
+	mux := http.NewServeMux()
+	mux.Handle(rpc.DefaultRPCPath, server)
 	listener, err := net.Listen("tcp", listenAddress)
 	if err != nil {
 		log.Fatal("listen error:", err)
 	}
-	go http.Serve(listener, nil)
+	if listener, err = common.AllowListener(listener, common.ClientAllow, store.log); err != nil {
+		log.Fatal("allowlist error:", err)
+	}
+	go http.Serve(listener, mux)
 
 	// setup raft for cohort
 	ra, err := common.SetupRaft((*cohortfsm)(c), c.ID, c.RaftAddress, c.RaftDir, enableSingle)
@@ -57,6 +76,11 @@ func startCohort(store *Store, listenAddress string, nodeID, raftAddress, raftDi
 		c.store.log.Fatalf("Unable to setup raft instance for cohort store:%s", err)
 	}
 	c.raft = ra
+	store.setCohort(c, listener)
+	if store.witness {
+		go store.yieldLeadership(ra, CohortInstance)
+	}
+	go c.holdPreparedLocks()
 	c.start(cohortJoinAddress, c.ID)
 	c.store.log.Infof("cohort setup successfully raftAddress:%s listenAddress:%s", c.RaftAddress, listenAddress)
 
@@ -68,70 +92,179 @@ func (c *Cohort) start(joinHTTPAddress, id string) {
 	if joinHTTPAddress == "" {
 		return
 	}
+	clusterID, err := common.ClusterID(c.RaftDir)
+	if err != nil {
+		c.store.log.Fatal(err)
+	}
 	var response raftpb.RPCResponse
 	msg := &raftpb.JoinMsg{
 		RaftAddress: c.RaftAddress,
 		ID:          id,
 		TYPE:        CohortInstance,
+		Version:     common.ProtocolVersion,
+		Nonvoter:    common.Nonvoter,
+		ClusterID:   clusterID,
 	}
 
-	client, err := rpc.DialHTTP("tcp", joinHTTPAddress)
-	if err != nil {
-		c.store.log.Fatalf("Unable to reach leader: %s", err)
+	err = common.RetryJoinAny(joinHTTPAddress, func(addr string) error {
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("Unable to reach leader: %s", err)
+		}
+		defer client.Close()
+		if err := client.Call("Cohort.ProcessJoin", common.SignJoin(common.ClusterSecret, msg), &response); err != nil {
+			return fmt.Errorf("Unable to join cluster: %s", err)
+		}
+		return nil
+	})
+	if err == nil {
+		err = common.SetClusterID(c.RaftDir, response.ClusterId)
 	}
-
-	err = client.Call("Cohort.ProcessJoin", msg, &response)
 	if err != nil {
-		c.store.log.Fatalf("Unable to join cluster: %s", err)
+		c.store.log.Fatal(err)
 	}
 }
 
 // ProcessCommands will process simple Get/Set (non-transactional) cmds from
-// the coordinator.
+// the coordinator. The key lock a command fails on is replied in reply.Lock
+// if raftCommand.LockReply is set.
 func (c *Cohort) ProcessCommands(raftCommand *raftpb.RaftCommand, reply *raftpb.RPCResponse) error {
+	if !readCommand(raftCommand) {
+		if err := c.verify("Cohort.ProcessCommands", raftCommand); err != nil {
+			return err
+		}
+	}
+	lockReply := raftCommand.LockReply
+	raftCommand.LockReply = false
+	err := c.processCommands(raftCommand, reply)
+	var lock *common.LockError
+	if lockReply && errors.As(err, &lock) {
+		*reply = raftpb.RPCResponse{Status: -1, Lock: common.LockProto(lock)}
+		return nil
+	}
+	return err
+}
+
+// readCommand reports whether raftCommand is served without going through
+// raft, and so without a signature.
+func readCommand(raftCommand *raftpb.RaftCommand) bool {
+	if len(raftCommand.Commands) == 0 {
+		return false
+	}
+	switch raftCommand.Commands[0].Method {
+	case common.GET, common.HISTORY, common.LEADER:
+		return true
+	}
+	return false
+}
+
+// verify checks the signature of req, the request of the mutating rpc
+// method, with the cluster secret, and clears it.
+func (c *Cohort) verify(method string, req proto.Message) error {
+	if err := common.VerifyRequest(common.ClusterSecret, method, req); err != nil {
+		c.store.log.Warnf("rejecting %s: %s", method, err)
+		return err
+	}
+	return nil
+}
+
+func (c *Cohort) processCommands(raftCommand *raftpb.RaftCommand, reply *raftpb.RPCResponse) error {
 	// No need to go to raft for Get/Leader cmds
 	c.store.log.Info("Processing rpc call", raftCommand)
 	if len(raftCommand.Commands) != 1 {
 		c.store.log.Errorf("Unexpected cmd %+v", raftCommand)
 	}
 	command := raftCommand.Commands[0]
+	if c.store.witness && command.Method != common.LEADER {
+		return errWitness
+	}
 	switch command.Method {
 	case common.GET:
-		if val, ok, err := c.store.kv.Get(command.Key); ok && err == nil {
+		// the leader lease is not trusted while the clocks are skewed
+		consistency, err := c.store.checkReadOf(command)
+		if err != nil {
+			return err
+		}
+		if err := c.store.waitApplied(command.MinRevision); err != nil {
+			return err
+		}
+		// the value read is at least as recent as the revision applied now
+		applied := int64(c.store.raft.AppliedIndex())
+		if command.Revision != 0 {
+			if err := c.getRevision(command, reply); err != nil {
+				return err
+			}
+			reply.Read = c.store.readInfo(consistency)
+			return nil
+		}
+		if val, meta, ok, err := c.store.kv.GetWithMeta(command.Key); ok && err == nil {
 			*reply = raftpb.RPCResponse{
-				Status: 0,
-				Value:  val.(int64),
+				Status:   0,
+				Meta:     common.MetaProto(meta, val),
+				Revision: applied,
+				Read:     c.store.readInfo(consistency),
 			}
+			common.SetResponseValue(reply, val)
 			return nil
 		} else if !ok {
 			return fmt.Errorf("Key=%s does not exist", command.Key)
 		} else {
 			return err
 		}
+	case common.HISTORY:
+		*reply = raftpb.RPCResponse{Status: 0}
+		for _, r := range c.store.history.List(command.Key, int(command.Limit)) {
+			reply.Commands = append(reply.Commands, revisionCommand(command.Key, r))
+		}
+		return nil
 	case common.LEADER:
-		if c.store.raft.State() == raft.Leader {
+		// a witness leader is about to transfer the leadership
+		if c.store.raft.State() == raft.Leader && !c.store.witness {
 			*reply = raftpb.RPCResponse{
 				Status: 0,
 				Addr:   c.store.rpcAddress,
 			}
 		}
 		return nil
+	case common.COMPARE:
+		return c.compareTxn(raftCommand, reply)
 	}
 
+	if err := c.store.checkProtocolVersion(raftCommand.Commands); err != nil {
+		return err
+	}
+	if c.store.isImporting() {
+		return errImportInProgress
+	}
+	done, err := c.store.throttle(raftCommand.Commands)
+	if err != nil {
+		return err
+	}
+	if ackedOnProposal(command) {
+		return c.proposeUnacked(raftCommand, done, reply)
+	}
+	defer done()
+
 	// Only Set and Del is apply to fsm
+	// the leader clock keeps the session of the write alive
+	command.Time = time.Now().UnixNano()
 	b, err := proto.Marshal(raftCommand)
 	if err != nil {
 		return err
 	}
 
+	start := time.Now()
 	applyFuture := c.store.raft.Apply(b, common.RaftTimeout)
-	if err := applyFuture.Error(); err != nil {
+	err = applyFuture.Error()
+	c.store.slow.Observe(common.SlowCommit, raftCommand.Commands[0].Key, "", start)
+	if err != nil {
 		c.store.log.Errorf("apply error: %s", err.Error())
 		return err
 	}
 
 	resp, ok := applyFuture.Response().(*FSMApplyResponse)
 	*reply = resp.reply
+	reply.Revision = int64(applyFuture.Index())
 	if ok && resp.err != nil {
 		c.store.log.Errorf("Fsm resp err: %s", resp.err.Error())
 		return resp.err
@@ -140,9 +273,126 @@ func (c *Cohort) ProcessCommands(raftCommand *raftpb.RaftCommand, reply *raftpb.
 	return nil
 }
 
+// getRevision replies with the value key had at command.Revision.
+func (c *Cohort) getRevision(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	val, meta, ok, err := c.store.kv.GetWithMeta(command.Key)
+	if err != nil {
+		return err
+	}
+	if ok && meta.ModRevision <= command.Revision {
+		*reply = raftpb.RPCResponse{Status: 0, Meta: common.MetaProto(meta, val)}
+		common.SetResponseValue(reply, val)
+		return nil
+	}
+	r, err := c.store.history.At(command.Key, command.Revision)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0}
+	common.SetResponseValue(reply, r.Value)
+	return nil
+}
+
+// revisionCommand returns r as the command that produced it.
+func revisionCommand(key string, r common.Revision) *raftpb.Command {
+	cmd := &raftpb.Command{Method: common.SET, Key: key, Revision: r.Rev}
+	if r.Evicted {
+		cmd.Method = common.EVICT
+	} else if r.Deleted {
+		cmd.Method = common.DEL
+	} else {
+		common.SetCommandValue(cmd, r.Value)
+	}
+	return cmd
+}
+
+// Export replies with the entries of the keys starting with command.Key.
+func (c *Cohort) Export(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	entries, err := c.store.kv.SnapshotPrefix(command.Key, exportLockTimeout)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Entries: entries}
+	return nil
+}
+
+// Usage replies with the number of keys and bytes of every namespace.
+func (c *Cohort) Usage(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Usage: c.store.kv.Usage(exportLockTimeout)}
+	return nil
+}
+
+// Stats replies with the statistics of the keys of the shard on this node.
+func (c *Cohort) Stats(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	*reply = raftpb.RPCResponse{Status: 0, ShardStats: c.store.kv.KeyStats()}
+	return nil
+}
+
+// SlowLog replies with the recent slow operations of the node.
+func (c *Cohort) SlowLog(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	*reply = raftpb.RPCResponse{Status: 0, SlowOps: c.store.slow.List()}
+	return nil
+}
+
+// Member replies with the metadata of the node, answering the liveness
+// probes of the coordinators.
+func (c *Cohort) Member(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	*reply = raftpb.RPCResponse{Status: 0, Node: &raftpb.NodeInfo{
+		Id:       c.store.ID,
+		Addr:     c.store.rpcAddress,
+		Zone:     common.Zone,
+		Capacity: common.Capacity,
+		Version:  common.ProtocolVersion,
+		Witness:  c.store.witness,
+		Cluster:  common.Cluster,
+	}}
+	return nil
+}
+
+// Locks replies with the keys locked by transactions on the node.
+func (c *Cohort) Locks(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	locks, err := c.store.kv.Locks(exportLockTimeout)
+	if err != nil {
+		return err
+	}
+	for _, l := range locks {
+		l.Node = c.store.ID
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Locks: locks}
+	return nil
+}
+
+// RaftStats replies with the raft stats of the shard on this node, and its
+// clock in nanoseconds to estimate the clock skew between the replicas.
+func (c *Cohort) RaftStats(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	*reply = raftpb.RPCResponse{Status: 0, Stats: c.store.raft.Stats(), Value: time.Now().UnixNano()}
+	return nil
+}
+
 // ProcessTransactionMessages processes prepare/commit messages from the coordinator.
 func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.RPCResponse) error {
 	c.store.log.Infof("Processing Transaction message :%v :%v", ops.Phase, ops.Cmds)
+	if c.store.witness {
+		return errWitness
+	}
+	if err := common.VerifyShardOps(common.ClusterSecret, ops); err != nil {
+		c.store.log.Warnf("rejecting transaction message: %s", err)
+		return err
+	}
+	c.txnMu.RLock()
+	defer c.txnMu.RUnlock()
 	switch ops.Phase {
 	case common.Prepare:
 		//Note that the transaction is read-only, iff it is read-only across all shards.
@@ -150,6 +400,19 @@ func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.
 			return c.ProcessReadOnly(ops, reply)
 		}
 
+		if err := c.store.checkProtocolVersion(ops.Cmds.Commands); err != nil {
+			return err
+		}
+		if c.store.isImporting() {
+			return errImportInProgress
+		}
+		// throttle before taking the locks, committing cannot be throttled
+		done, err := c.store.throttle(ops.Cmds.Commands)
+		if err != nil {
+			return err
+		}
+		defer done()
+
 		if err := c.store.kv.TryLocks(ops.Cmds.Commands, ops.Txid); err != nil {
 			// If it fails to get some of the lock, prepare should return "No"
 			// no need to update cohort state machine, it is equivalent to a no transaction.
@@ -159,7 +422,7 @@ func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.
 		// This should be replicated via raft with raft Apply, once setup
 		// if raft fails, send NotPrepared. log 2 pc message
 		ops.Phase = common.Prepared
-		err := c.replicate(ops.Txid, common.SET, ops)
+		err = c.replicate(ops.Txid, common.SET, ops)
 		if err == nil {
 			*reply = raftpb.RPCResponse{
 				Status: 0,
@@ -180,14 +443,14 @@ func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.
 		}
 
 		//Apply to fsm
-		b, err := proto.Marshal(ops.Cmds)
+		start := time.Now()
+		rev, err := c.store.proposeTxn(ops.Cmds)
+		c.store.slow.Observe(common.SlowCommit, ops.MasterKey, ops.Txid, start)
 		if err != nil {
-			return err
-		}
-		if f := c.store.raft.Apply(b, common.RaftTimeout); f.Error() != nil {
 			// if this happens, we cannot abort the transaction at this stage. It means
 			// this shard does not have a majority of replicas
-			c.store.log.Warnf("Unable to apply operations to kv raft instance: %s", f.Error())
+			c.store.log.Warnf("Unable to apply operations to kv raft instance: %s", err)
+			rev = 0
 		}
 
 		// log 2 pc commit message, replicate via raft
@@ -195,8 +458,9 @@ func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.
 		err = c.replicate(ops.Txid, common.SET, ops)
 		if err == nil {
 			*reply = raftpb.RPCResponse{
-				Status: 0,
-				Phase:  common.Committed,
+				Status:   0,
+				Phase:    common.Committed,
+				Revision: rev,
 			}
 			return nil
 		}
@@ -204,6 +468,14 @@ func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.
 		// will eventually try again during recovery
 		return fmt.Errorf("Unable to replicate during commit phase: %s", err)
 
+	case common.OnePhase:
+		err := c.commitOnePhase(ops, reply)
+		if ops.OutcomeReply && errors.Is(err, common.ErrOutcomeUnknown) {
+			*reply = raftpb.RPCResponse{Status: -1, Phase: common.OutcomeUnknown}
+			return nil
+		}
+		return err
+
 	case common.Abort:
 
 		c.store.kv.AbortWithLocks(ops.Cmds.Commands, ops.Txid)
@@ -228,18 +500,54 @@ func (c *Cohort) ProcessTransactionMessages(ops *raftpb.ShardOps, reply *raftpb.
 }
 
 func (c *Cohort) ProcessReadOnly(ops *raftpb.ShardOps, reply *raftpb.RPCResponse) error {
+	consistency, err := c.store.checkRead(false)
+	if err != nil {
+		return err
+	}
 	m, err := c.store.kv.MGet(ops.Cmds.Commands, ops.Txid)
 	if err != nil {
 		return err
 	}
 	var res []*raftpb.Command
 	for k, v := range m {
-		res = append(res, &raftpb.Command{Key: k, Value: v.(int64)})
+		cmd := &raftpb.Command{Key: k}
+		common.SetCommandValue(cmd, v)
+		res = append(res, cmd)
 	}
 	*reply = raftpb.RPCResponse{
 		Status:   0,
 		Phase:    common.Prepared,
 		Commands: res,
+		Read:     c.store.readInfo(consistency),
+	}
+	return nil
+}
+
+// SnapshotRead replies with the committed values of the keys of a declared
+// read-only transaction, after a read index fence or under the leader lease,
+// and with the transactions holding locks on them. Nothing is proposed and no
+// lock is taken.
+func (c *Cohort) SnapshotRead(ops *raftpb.ShardOps, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	consistency, err := c.store.checkRead(false)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(ops.Cmds.Commands))
+	for i, cmd := range ops.Cmds.Commands {
+		keys[i] = cmd.Key
+	}
+	m, txids, err := c.store.kv.SnapshotGet(keys, exportLockTimeout)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Txids: txids, Read: c.store.readInfo(consistency)}
+	for k, v := range m {
+		cmd := &raftpb.Command{Key: k}
+		common.SetCommandValue(cmd, v)
+		reply.Commands = append(reply.Commands, cmd)
 	}
 	return nil
 }
@@ -249,8 +557,9 @@ func (c *Cohort) ProcessReadOnly(ops *raftpb.ShardOps, reply *raftpb.RPCResponse
 // Note: Ideally, we would like to avoid duplicate code. But this is specific to
 // to each raft instance. An interface wouldn't be helpful each type has to implement
 // it again resulting in duplicate code.
-func (c *Cohort) join(nodeID, addr string) error {
-	c.store.log.Infof("received join request for remote node %s at %s", nodeID, addr)
+func (c *Cohort) join(nodeID, addr string, version int32, nonvoter, verified bool) error {
+	c.store.log.Infof("received join request for remote node %s at %s (protocol version %d)", nodeID, addr, version)
+	c.store.versions.Set(nodeID, version)
 
 	configFuture := c.raft.GetConfiguration()
 	if err := configFuture.Error(); err != nil {
@@ -258,6 +567,15 @@ func (c *Cohort) join(nodeID, addr string) error {
 		return err
 	}
 
+	moved, err := common.MoveMember(c.raft, nodeID, addr, verified)
+	if err != nil {
+		return fmt.Errorf("error moving existing node %s to %s: %w", nodeID, addr, err)
+	}
+	if moved {
+		c.store.log.Infof("node %s moved to %s", nodeID, addr)
+		return nil
+	}
+
 	for _, srv := range configFuture.Configuration().Servers {
 		// If a node already exists with either the joining node's ID or address,
 		// that node may need to be removed from the config first.
@@ -276,7 +594,12 @@ func (c *Cohort) join(nodeID, addr string) error {
 		}
 	}
 
-	f := c.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	var f raft.IndexFuture
+	if nonvoter {
+		f = c.raft.AddNonvoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	} else {
+		f = c.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	}
 	if f.Error() != nil {
 		return f.Error()
 	}
@@ -284,13 +607,35 @@ func (c *Cohort) join(nodeID, addr string) error {
 	return nil
 }
 
-// ProcessJoin processes join message.
+// ProcessJoin processes join message. It refuses nodes with the state of
+// another cluster or of a removed node, and replies with the cluster of the
+// group joined.
 func (c *Cohort) ProcessJoin(joinMsg *raftpb.JoinMsg, reply *raftpb.RPCResponse) error {
-
+	if err := common.VerifyJoin(common.ClusterSecret, joinMsg); err != nil {
+		c.store.log.Warnf("refusing join: %s", err)
+		return err
+	}
+	dir, ra := c.store.RaftDir, c.store.raft
+	if joinMsg.TYPE != StoreInstance {
+		dir, ra = c.RaftDir, c.raft
+	}
+	clusterID, err := common.ClusterID(dir)
+	if err != nil {
+		return err
+	}
+	if err := common.CheckJoin(ra, clusterID, joinMsg.ID, joinMsg.ClusterID); err != nil {
+		c.store.log.Errorf("refusing join: %s", err)
+		return err
+	}
+	// the signature of the join was checked above
+	verified := common.JoinVerified(clusterID, joinMsg.ClusterID, common.ClusterSecret != "")
 	if joinMsg.TYPE == StoreInstance {
-		return c.store.Join(joinMsg.ID, joinMsg.RaftAddress)
+		err = c.store.Join(joinMsg.ID, joinMsg.RaftAddress, joinMsg.Version, joinMsg.Nonvoter, verified)
+	} else {
+		err = c.join(joinMsg.ID, joinMsg.RaftAddress, joinMsg.Version, joinMsg.Nonvoter, verified)
 	}
-	return c.join(joinMsg.ID, joinMsg.RaftAddress)
+	*reply = raftpb.RPCResponse{Status: 0, ClusterId: clusterID}
+	return err
 }
 
 // replicate replicates put/deletes on cohort's
@@ -76,7 +76,12 @@ func (f *cohortfsm) Restore(rc io.ReadCloser) error {
 		return err
 	}
 
+	if o.Map == nil {
+		o.Map = make(map[string]*raftpb.ShardOps)
+	}
+	f.mu.Lock()
 	f.opsMap = o.Map
+	f.mu.Unlock()
 	return nil
 }
 
@@ -84,8 +89,23 @@ type cohortfsmSnapshot struct {
 	opsMap raftpb.OpsMap
 }
 
+// Persist writes the transactions of the shard, so that the prepared ones,
+// their keys and pending values, outlive the compaction of the log.
 func (f *cohortfsmSnapshot) Persist(sink raft.SnapshotSink) error {
-	return nil
+	err := func() error {
+		b, err := proto.Marshal(&f.opsMap)
+		if err != nil {
+			return err
+		}
+		if _, err := sink.Write(b); err != nil {
+			return err
+		}
+		return sink.Close()
+	}()
+	if err != nil {
+		sink.Cancel()
+	}
+	return err
 }
 
 func (f *cohortfsmSnapshot) Release() {}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Storage returns the storage stats of the raft groups of the node, the store
+// and the cohort once it is started.
+func (s *Store) Storage() (map[string]common.RaftStorageStats, error) {
+	stats := make(map[string]common.RaftStorageStats)
+	var err error
+	if stats[StoreInstance], err = common.RaftStorage(s.raft); err != nil {
+		return nil, err
+	}
+	if c := s.getCohort(); c != nil {
+		if stats[CohortInstance], err = common.RaftStorage(c.raft); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// Metrics returns the storage metrics of the raft groups of the node, and
+// its memory, webhook, lock contention and arena metrics.
+func (s *Store) Metrics() *common.Metrics {
+	m := common.NewMetrics()
+	common.RegisterRaftStorageMetrics(m)
+	common.RegisterMemoryMetrics(m)
+	s.memory.SetMetrics(m)
+	common.RegisterWebhookMetrics(m)
+	s.webhooks.SetMetrics(m)
+	common.RegisterLockMetrics(m)
+	s.lockStats.SetMetrics(m, s.kv)
+	common.RegisterArenaMetrics(m)
+	s.kv.Arena().SetMetrics(m)
+	stats, err := s.Storage()
+	if err != nil {
+		s.log.Errorf("unable to read the raft storage: %s", err)
+		return m
+	}
+	now := time.Now()
+	for group, st := range stats {
+		common.SetRaftStorageMetrics(m, group, st, now)
+	}
+	return m
+}
+
+// LockStats returns the lock contention stats of the key-value map.
+func (s *Store) LockStats() common.LockStatsSnapshot {
+	return s.lockStats.Snapshot(s.kv)
+}
+
+// Compact snapshots the raft groups of the node now, truncating their logs,
+// ahead of the snapshot threshold and interval.
+func (s *Store) Compact() error {
+	s.log.Infof("compacting the raft logs of node-%s", s.ID)
+	if err := common.CompactRaft(s.raft); err != nil {
+		return err
+	}
+	if c := s.getCohort(); c != nil {
+		return common.CompactRaft(c.raft)
+	}
+	return nil
+}
+
+// Compact snapshots the raft groups of the node now.
+func (c *Cohort) Compact(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if err := c.verify("Cohort.Compact", command); err != nil {
+		return err
+	}
+	if err := c.store.Compact(); err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0}
+	return nil
+}
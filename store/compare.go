@@ -0,0 +1,137 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// compareKeys returns the distinct keys of the compare transaction ct, as
+// lock operations.
+func compareKeys(ct *raftpb.CompareTxn) []*raftpb.Command {
+	seen := make(map[string]bool)
+	var ops []*raftpb.Command
+	add := func(key string) {
+		if !seen[key] {
+			seen[key] = true
+			ops = append(ops, &raftpb.Command{Method: common.GET, Key: key})
+		}
+	}
+	for _, cmp := range ct.Compare {
+		add(cmp.Key)
+	}
+	for _, cmd := range ct.Success {
+		add(cmd.Key)
+	}
+	for _, cmd := range ct.Failure {
+		add(cmd.Key)
+	}
+	return ops
+}
+
+// compareTxn proposes the compare transaction of raftCommand. The leader
+// locks its keys until the entry is applied, so transactions prepared in the
+// meantime neither see nor change them half way.
+func (c *Cohort) compareTxn(raftCommand *raftpb.RaftCommand, reply *raftpb.RPCResponse) error {
+	if err := c.store.checkProtocolVersion(raftCommand.Commands); err != nil {
+		return err
+	}
+	if c.store.isImporting() {
+		return errImportInProgress
+	}
+	done, err := c.store.throttle(raftCommand.Commands)
+	if err != nil {
+		return err
+	}
+	defer done()
+	ops := compareKeys(raftCommand.Commands[0].Compare)
+	txid := xid.New().String()
+	if err := c.store.kv.TryLocks(ops, txid); err != nil {
+		return err
+	}
+	// keys rewritten by the entry are not locked by the transaction anymore
+	defer c.store.kv.AbortWithLocks(ops, txid)
+	b, err := proto.Marshal(raftCommand)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	applyFuture := c.store.raft.Apply(b, common.RaftTimeout)
+	err = applyFuture.Error()
+	c.store.slow.Observe(common.SlowCommit, raftCommand.Commands[0].Key, txid, start)
+	if err != nil {
+		return err
+	}
+	resp := applyFuture.Response().(*FSMApplyResponse)
+	*reply = resp.reply
+	reply.Revision = int64(applyFuture.Index())
+	return resp.err
+}
+
+// applyCompare evaluates the comparisons of ct and applies its success
+// commands if they all hold, its failure commands otherwise. It replies with
+// a Value of 1 if the comparisons held, and with the results of the gets of
+// the branch, which see the writes before them. Keys that do not exist are
+// left out of the results.
+func (f *fsm) applyCompare(ct *raftpb.CompareTxn, rev int64) interface{} {
+	succeeded := true
+	for _, cmp := range ct.Compare {
+		val, meta, ok, err := f.kv.Peek(cmp.Key, common.RaftTimeout)
+		if err != nil {
+			return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+		}
+		if !common.CompareHolds(cmp, val, meta, ok) {
+			succeeded = false
+			break
+		}
+	}
+	branch := ct.Success
+	if !succeeded {
+		branch = ct.Failure
+	}
+	reply := raftpb.RPCResponse{Status: 0}
+	if succeeded {
+		reply.Value = 1
+	}
+	var writes []*raftpb.Command
+	pending := make(map[string]*raftpb.Command)
+	for _, cmd := range branch {
+		switch cmd.Method {
+		case common.GET:
+			if w, ok := pending[cmd.Key]; ok {
+				if w.Method == common.SET {
+					get := &raftpb.Command{Method: common.GET, Key: cmd.Key}
+					common.SetCommandValue(get, common.CommandValue(w))
+					reply.Commands = append(reply.Commands, get)
+				}
+				continue
+			}
+			val, _, ok, err := f.kv.Peek(cmd.Key, common.RaftTimeout)
+			if err != nil {
+				return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+			}
+			if ok {
+				get := &raftpb.Command{Method: common.GET, Key: cmd.Key}
+				common.SetCommandValue(get, val)
+				reply.Commands = append(reply.Commands, get)
+			}
+		case common.SET, common.DEL:
+			writes = append(writes, cmd)
+			pending[cmd.Key] = cmd
+		default:
+			return &FSMApplyResponse{err: fmt.Errorf("unsupported compare command %s", cmd.Method), reply: raftpb.RPCResponse{Status: -1}}
+		}
+	}
+	if len(writes) > 0 {
+		f.kv.Write(writes, rev)
+		for _, cmd := range writes {
+			f.recordHistory(cmd.Key, rev, cmd.Method == common.DEL)
+		}
+		f.trackExpiry(writes...)
+	}
+	return &FSMApplyResponse{reply: reply}
+}
@@ -2,11 +2,63 @@ package store
 
 import (
 	"encoding/binary"
+	"errors"
 	"github.com/boltdb/bolt"
+	"github.com/raft-kv-store/common"
 	log "github.com/sirupsen/logrus"
 	"time"
 )
 
+// blobBucket returns the bucket holding the codec encoded values of bucketName.
+// They are kept apart from the int64 values so that the layout of existing
+// snapshot files does not change.
+func blobBucket(bucketName string) []byte {
+	return []byte(bucketName + "-blobs")
+}
+
+// metaBucket returns the bucket holding the key metadata of bucketName.
+func metaBucket(bucketName string) []byte {
+	return []byte(bucketName + "-meta")
+}
+
+func encodeMeta(m common.KeyMeta) []byte {
+	buf := make([]byte, 24)
+	binary.LittleEndian.PutUint64(buf, uint64(m.CreateRevision))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(m.ModRevision))
+	binary.LittleEndian.PutUint64(buf[16:], uint64(m.Version))
+	return buf
+}
+
+func decodeMeta(buf []byte) (common.KeyMeta, error) {
+	if len(buf) != 24 {
+		return common.KeyMeta{}, errors.New("corrupted key metadata")
+	}
+	return common.KeyMeta{
+		CreateRevision: int64(binary.LittleEndian.Uint64(buf)),
+		ModRevision:    int64(binary.LittleEndian.Uint64(buf[8:])),
+		Version:        int64(binary.LittleEndian.Uint64(buf[16:])),
+	}, nil
+}
+
+// encodeBlob lays out a blob as codec length, codec, data.
+func encodeBlob(b *common.Blob) []byte {
+	buf := make([]byte, 1+len(b.Codec)+len(b.Data))
+	buf[0] = byte(len(b.Codec))
+	copy(buf[1:], b.Codec)
+	copy(buf[1+len(b.Codec):], b.Data)
+	return buf
+}
+
+func decodeBlob(buf []byte) (*common.Blob, error) {
+	if len(buf) == 0 || len(buf) < 1+int(buf[0]) {
+		return nil, errors.New("corrupted blob")
+	}
+	n := 1 + int(buf[0])
+	data := make([]byte, len(buf)-n)
+	copy(data, buf[n:])
+	return &common.Blob{Codec: string(buf[1:n]), Data: data}, nil
+}
+
 type persistKvDB struct {
 	db      *bolt.DB
 	options bolt.Options
@@ -26,6 +78,14 @@ func newDBConn(file string, bucketName string, logger *log.Logger) (s *persistKv
 		if err != nil {
 			l.Fatalf(" Error in creating bucket: %s with err: %s", bucketName, err)
 		}
+		_, err = tx.CreateBucketIfNotExists(blobBucket(bucketName))
+		if err != nil {
+			l.Fatalf(" Error in creating bucket: %s with err: %s", blobBucket(bucketName), err)
+		}
+		_, err = tx.CreateBucketIfNotExists(metaBucket(bucketName))
+		if err != nil {
+			l.Fatalf(" Error in creating bucket: %s with err: %s", metaBucket(bucketName), err)
+		}
 		return nil
 	})
 	if err != nil {
@@ -41,11 +101,26 @@ func newDBConn(file string, bucketName string, logger *log.Logger) (s *persistKv
 func (f *fsmSnapshot) save() {
 	err := f.persistDBConn.db.Batch(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(f.bucketName))
+		blobs := tx.Bucket(blobBucket(f.bucketName))
+		meta := tx.Bucket(metaBucket(f.bucketName))
 
 		for key, value := range f.store {
-			buf := make([]byte, 8)
-			binary.LittleEndian.PutUint64(buf, uint64(value))
-			err := b.Put([]byte(key), buf)
+			var err error
+			switch v := value.(type) {
+			case int64:
+				buf := make([]byte, 8)
+				binary.LittleEndian.PutUint64(buf, uint64(v))
+				if err = b.Put([]byte(key), buf); err == nil {
+					err = blobs.Delete([]byte(key))
+				}
+			case *common.Blob:
+				if err = blobs.Put([]byte(key), encodeBlob(v)); err == nil {
+					err = b.Delete([]byte(key))
+				}
+			}
+			if err == nil {
+				err = meta.Put([]byte(key), encodeMeta(f.meta[key]))
+			}
 			if err != nil {
 				f.persistDBConn.log.Warnf(" Snapshot save failed for bucket: %s, "+
 					"key: %s", f.bucketName, key)
@@ -59,19 +134,38 @@ func (f *fsmSnapshot) save() {
 	}
 }
 
-func (f *fsm) restore() (kv map[string]int64) {
+func (f *fsm) restore() (kv map[string]interface{}, meta map[string]common.KeyMeta) {
 	if err := f.persistKvDbConn.db.View(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(f.persistBucketName))
 		c := b.Cursor()
-		kv = make(map[string]int64)
+		kv = make(map[string]interface{})
 
 		for k, v := c.First(); k != nil; k, v = c.Next() {
 			kv[string(k)] = int64(binary.LittleEndian.Uint64(v))
 		}
 
+		c = tx.Bucket(blobBucket(f.persistBucketName)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			blob, err := decodeBlob(v)
+			if err != nil {
+				return err
+			}
+			kv[string(k)] = blob
+		}
+
+		meta = make(map[string]common.KeyMeta)
+		c = tx.Bucket(metaBucket(f.persistBucketName)).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			m, err := decodeMeta(v)
+			if err != nil {
+				return err
+			}
+			meta[string(k)] = m
+		}
+
 		return nil
 	}); err != nil {
 		f.persistKvDbConn.log.Fatalf(" Snapshot restore failed from bucket: %s ", f.persistBucketName)
 	}
-	return kv
+	return kv, meta
 }
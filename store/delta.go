@@ -0,0 +1,194 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// errDeltaTooLarge is returned for the replicas too far behind for a delta.
+var errDeltaTooLarge = errors.New("too many changes for a delta, a full snapshot is needed")
+
+// SnapshotDelta replies with the keys set and deleted since req.Since, and
+// the state of the store up to its last applied entry.
+func (c *Cohort) SnapshotDelta(req *raftpb.SnapshotDelta, reply *raftpb.SnapshotDelta) error {
+	if c.store.witness {
+		return errWitness
+	}
+	delta, err := c.store.delta(req.Since)
+	if err != nil {
+		return err
+	}
+	*reply = *delta
+	return nil
+}
+
+// delta returns the changes of the store after index since.
+func (s *Store) delta(since uint64) (*raftpb.SnapshotDelta, error) {
+	s.applyMu.Lock()
+	view := s.kv.View()
+	deleted, ok := s.deleted.Since(int64(since))
+	delta := &raftpb.SnapshotDelta{
+		Since:    since,
+		Index:    s.appliedIndex,
+		Term:     s.appliedTerm,
+		Deleted:  deleted,
+		Sessions: s.sessionsSnapshot(),
+		Versions: s.snapshotVersions(),
+	}
+	expires := s.expiryTimes()
+	s.applyMu.Unlock()
+	defer view.Close()
+	if !ok {
+		return nil, fmt.Errorf("deletions since index %d are not known anymore", since)
+	}
+	if delta.Index <= since {
+		return delta, nil
+	}
+
+	configFuture := s.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return nil, err
+	}
+	// the configuration must not be ahead of the state
+	index, _ := strconv.ParseUint(s.raft.Stats()["latest_configuration_index"], 10, 64)
+	if index > delta.Index {
+		return nil, fmt.Errorf("configuration at index %d is ahead of index %d", index, delta.Index)
+	}
+	delta.ConfigurationIndex = index
+	for _, srv := range configFuture.Configuration().Servers {
+		delta.Configuration = append(delta.Configuration, &raftpb.RaftServer{
+			Id:       string(srv.ID),
+			Address:  string(srv.Address),
+			Suffrage: int32(srv.Suffrage),
+		})
+	}
+
+	values, meta := view.Read()
+	for k, v := range values {
+		if meta[k].ModRevision <= int64(since) {
+			continue
+		}
+		if len(delta.Entries)+len(delta.Deleted) >= common.DeltaMaxKeys {
+			return nil, errDeltaTooLarge
+		}
+		delta.Entries = append(delta.Entries, common.NewEntry(k, v, meta[k]))
+		if at, ok := expires[k]; ok {
+			if delta.Expires == nil {
+				delta.Expires = make(map[string]int64)
+			}
+			delta.Expires[k] = at
+		}
+	}
+	return delta, nil
+}
+
+// catchUp brings the latest local snapshot of a restarting replica up to
+// date with a delta from the first of the nodes at addrs able to send it,
+// and installs it in the snapshot store. raft then restores it and the
+// leader only sends the entries after it, even though it compacted its log
+// past the last entry of the replica. Replicas without snapshot are not
+// caught up.
+func (s *Store) catchUp(addrs []string) error {
+	if _, err := os.Stat(filepath.Join(s.RaftDir, "raft.db")); err != nil {
+		return nil
+	}
+	snapshots, err := s.snapshotStore()
+	if err != nil {
+		return err
+	}
+	metas, err := snapshots.List()
+	if err != nil || len(metas) == 0 {
+		return err
+	}
+	local := metas[0]
+
+	var delta *raftpb.SnapshotDelta
+	var source string
+	for _, addr := range addrs {
+		d, err := fetchSnapshotDelta(addr, &raftpb.SnapshotDelta{Since: local.Index})
+		if err != nil {
+			s.log.Infof("unable to catch up from %s: %s", addr, err)
+			continue
+		}
+		if delta == nil || d.Index > delta.Index {
+			source, delta = addr, d
+		}
+	}
+	if delta == nil {
+		return fmt.Errorf("no node to catch up from among %v", addrs)
+	}
+	if delta.Index <= local.Index {
+		return nil
+	}
+	s.log.Infof("catching up from index %d to %d of %s, %d keys set and %d deleted",
+		local.Index, delta.Index, source, len(delta.Entries), len(delta.Deleted))
+
+	_, rc, err := snapshots.Open(local.ID)
+	if err != nil {
+		return err
+	}
+	m := make(map[string]interface{})
+	meta := make(map[string]common.KeyMeta)
+	expires := make(map[string]int64)
+	err = readSnapshot(rc, func(e *raftpb.KVEntry) error {
+		if e.Session == nil && e.MemberVersion == nil && e.StateHash == nil {
+			m[e.Key] = common.EntryValue(e)
+			meta[e.Key] = common.EntryMeta(e)
+			expires[e.Key] = e.Expires
+		}
+		return nil
+	})
+	rc.Close()
+	if err != nil {
+		return err
+	}
+	for _, k := range delta.Deleted {
+		delete(m, k)
+		delete(meta, k)
+	}
+	for _, e := range delta.Entries {
+		m[e.Key] = common.EntryValue(e)
+		meta[e.Key] = common.EntryMeta(e)
+		expires[e.Key] = delta.Expires[e.Key]
+	}
+
+	var configuration raft.Configuration
+	for _, srv := range delta.Configuration {
+		configuration.Servers = append(configuration.Servers, raft.Server{
+			ID:       raft.ServerID(srv.Id),
+			Address:  raft.ServerAddress(srv.Address),
+			Suffrage: raft.ServerSuffrage(srv.Suffrage),
+		})
+	}
+	sink, err := snapshots.Create(local.Version, delta.Index, delta.Term, configuration, delta.ConfigurationIndex, nil)
+	if err != nil {
+		return err
+	}
+	hash := &raftpb.StateHash{Index: int64(delta.Index), Hash: common.StateHashOf(m, meta)}
+	if err := writeSnapshot(sink, m, meta, expires, delta.Sessions, delta.Versions, hash); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func fetchSnapshotDelta(addr string, req *raftpb.SnapshotDelta) (*raftpb.SnapshotDelta, error) {
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	var reply raftpb.SnapshotDelta
+	if err := client.Call("Cohort.SnapshotDelta", req, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
@@ -0,0 +1,156 @@
+//go:build determinism
+// +build determinism
+
+package store
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+// newReplica returns the FSM of a store node without raft, applying the
+// entries it is given.
+func newReplica(id string) *fsm {
+	logger := log.New()
+	logger.SetLevel(log.WarnLevel)
+	s := &Store{
+		ID:            id,
+		kv:            common.NewCmap(logger, common.LockContention),
+		history:       common.NewHistory(common.HistoryRetention),
+		deleted:       common.NewTombstones(common.DeltaTombstones),
+		watches:       common.NewWatchHub(),
+		events:        common.NewEventLog(common.WatchHistorySize),
+		log:           logger.WithField("node", id),
+		versions:      common.NewMemberVersions(),
+		slow:          common.NewSlowLog(id, common.SlowLogSize),
+		lockStats:     common.NewLockStats(),
+		stateHashes:   common.NewStateHashes(id),
+		applyCheck:    common.NewApplyCheck(id),
+		sessions:      make(map[string]*raftpb.Session),
+		sessionExpiry: common.NewExpiryIndex(),
+		keyExpiry:     common.NewExpiryIndex(),
+	}
+	s.kv.SetNode(id)
+	return (*fsm)(s)
+}
+
+// entries returns cmds as the entries of a raft log, from index 1.
+func entries(t *testing.T, cmds ...*raftpb.RaftCommand) []*raft.Log {
+	logs := make([]*raft.Log, len(cmds))
+	for i, cmd := range cmds {
+		b, err := proto.Marshal(cmd)
+		assert.Nil(t, err)
+		logs[i] = &raft.Log{Index: uint64(i + 1), Term: 1, Data: b}
+	}
+	return logs
+}
+
+// single returns the entry of the commands proposed on their own.
+func single(cmds ...*raftpb.Command) *raftpb.RaftCommand {
+	return &raftpb.RaftCommand{Commands: cmds}
+}
+
+// assertSameState checks that the replicas hold the same keys, expiry times
+// and sessions.
+func assertSameState(t *testing.T, replicas []*fsm, index uint64) {
+	first := replicas[0]
+	for _, r := range replicas[1:] {
+		assert.Equal(t, first.kv.StateHash(), r.kv.StateHash(), "keys of %s after index %d", r.ID, index)
+		assert.Equal(t, (*Store)(first).expiryTimes(), (*Store)(r).expiryTimes(), "expiry times of %s after index %d", r.ID, index)
+		assert.Equal(t, (*Store)(first).sessionsSnapshot(), (*Store)(r).sessionsSnapshot(), "sessions of %s after index %d", r.ID, index)
+	}
+}
+
+// TestReplicasApplyIdentically replays the same log into several replicas,
+// the last one applying every entry later than the others, past the expiry
+// of the keys and sessions written. Map iteration order differing between
+// the replicas, and the wall clock between their applies, any state
+// depending on them diverges: the replicas panic on their apply check, or
+// differ after an entry.
+func TestReplicasApplyIdentically(t *testing.T) {
+	now := time.Now().UnixNano()
+	expires := now + int64(5*time.Millisecond)
+	var bulk []*raftpb.RaftCommand
+	for i := 0; i < 20; i++ {
+		bulk = append(bulk, single(&raftpb.Command{Method: common.SET, Key: "bulk" + strconv.Itoa(i), Value: int64(i), Time: now}))
+	}
+	logs := entries(t,
+		single(&raftpb.Command{Method: common.VERSION, Key: "n1", Value: int64(common.ProtocolVersion)}),
+		single(&raftpb.Command{Method: common.SET, Key: "a", Value: 1, Time: now}),
+		single(&raftpb.Command{Method: common.SET, Key: "ttl", Value: 2, Time: now, Expires: expires}),
+		single(&raftpb.Command{Method: common.OPEN, Key: "s1", Time: now}),
+		single(&raftpb.Command{Method: common.SET, Key: "b", Value: 3, Time: now, Session: "s1", Seq: 1}),
+		single(&raftpb.Command{Method: common.SET, Key: "b", Value: 3, Time: now, Session: "s1", Seq: 1}),
+		single(&raftpb.Command{Method: common.SETNX, Key: "a", Value: 4, Time: now}),
+		single(&raftpb.Command{Method: common.GETSET, Key: "a", Value: 5, Time: now}),
+		&raftpb.RaftCommand{IsTxn: true, Commands: []*raftpb.Command{
+			{Method: common.SET, Key: "c", Value: 6, Time: now, Expires: expires},
+			{Method: common.DEL, Key: "b", Time: now},
+		}},
+		&raftpb.RaftCommand{Batch: bulk},
+		&raftpb.RaftCommand{IsTxn: true, Batch: []*raftpb.RaftCommand{
+			{Commands: []*raftpb.Command{{Method: common.SET, Key: "d", Value: 7, Time: now}}},
+			{Commands: []*raftpb.Command{{Method: common.SET, Key: "e", Value: 8, Time: now}}},
+		}},
+		single(&raftpb.Command{Method: common.COMPARE, Time: now, Compare: &raftpb.CompareTxn{
+			Compare: []*raftpb.Compare{{Key: "a", Target: "value", Result: "=", Value: 5}},
+			Success: []*raftpb.Command{{Method: common.SET, Key: "f", Value: 9}},
+			Failure: []*raftpb.Command{{Method: common.SET, Key: "g", Value: 10}},
+		}}),
+		single(&raftpb.Command{Method: common.DEL, Key: "d", Time: now}),
+		single(&raftpb.Command{Method: common.UNDELETE, Key: "d", Time: now}),
+		single(&raftpb.Command{Method: common.EVICT, Key: "ttl", Revision: 3}, &raftpb.Command{Method: common.EVICT, Key: "c", Revision: 9}),
+		single(&raftpb.Command{Method: common.EXPIRE, Value: now + 1, Time: now}),
+		single(&raftpb.Command{Method: common.HASH}),
+		single(&raftpb.Command{Method: common.GETDEL, Key: "e", Time: now}),
+	)
+
+	replicas := []*fsm{newReplica("replica1"), newReplica("replica2"), newReplica("late")}
+	for _, l := range logs {
+		for _, r := range replicas[:2] {
+			r.Apply(l)
+		}
+		assertSameState(t, replicas[:2], l.Index)
+	}
+	time.Sleep(time.Until(time.Unix(0, expires).Add(5 * time.Millisecond)))
+	for _, l := range logs {
+		replicas[2].Apply(l)
+	}
+	assertSameState(t, replicas, logs[len(logs)-1].Index)
+
+	values, _ := replicas[2].kv.View().Read()
+	assert.Equal(t, int64(5), values["a"])
+	assert.Equal(t, int64(9), values["f"])
+	assert.NotContains(t, values, "ttl")
+	assert.Empty(t, (*Store)(replicas[2]).sessionsSnapshot())
+}
+
+// TestReplicasApplyIdenticallyDetects checks that the harness catches an
+// apply depending on the clock of the replica.
+func TestReplicasApplyIdenticallyDetects(t *testing.T) {
+	hooksMu.Lock()
+	saved := applyHooks
+	hooksMu.Unlock()
+	defer func() {
+		hooksMu.Lock()
+		applyHooks = saved
+		hooksMu.Unlock()
+	}()
+	RegisterApplyHook(func(cmd *raftpb.Command) error {
+		cmd.Value = time.Now().UnixNano()
+		return nil
+	})
+
+	logs := entries(t, single(&raftpb.Command{Method: common.SET, Key: "clock", Time: time.Now().UnixNano()}))
+	newReplica("clock1").Apply(logs[0])
+	time.Sleep(time.Millisecond)
+	assert.Panics(t, func() { newReplica("clock2").Apply(logs[0]) })
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+	"github.com/rs/xid"
+)
+
+// errStarting is returned for the in-process requests made before the cohort
+// of the node is started.
+var errStarting = errors.New("node is starting")
+
+func (s *Store) getCohort() *Cohort {
+	s.cohortMu.Lock()
+	defer s.cohortMu.Unlock()
+	return s.cohort
+}
+
+// Do serves cmd in process as Cohort.ProcessCommands does over rpc, for
+// applications embedding the node. Writes must be sent to the leader.
+func (s *Store) Do(cmd *raftpb.Command) (*raftpb.RPCResponse, error) {
+	c := s.getCohort()
+	if c == nil {
+		return nil, errStarting
+	}
+	var reply raftpb.RPCResponse
+	args := &raftpb.RaftCommand{Commands: []*raftpb.Command{cmd}}
+	err := c.ProcessCommands(common.SignRequest(common.ClusterSecret, "Cohort.ProcessCommands", args).(*raftpb.RaftCommand), &reply)
+	return &reply, err
+}
+
+// Transaction commits the sets and deletes cmds atomically in process, with
+// a single raft entry, on the leader.
+func (s *Store) Transaction(cmds []*raftpb.Command) error {
+	c := s.getCohort()
+	if c == nil {
+		return errStarting
+	}
+	if len(cmds) == 0 {
+		return errors.New("no key given")
+	}
+	ops := &raftpb.ShardOps{
+		Txid:      xid.New().String(),
+		MasterKey: cmds[0].Key,
+		Phase:     common.OnePhase,
+		Cmds:      &raftpb.RaftCommand{Commands: cmds, IsTxn: true},
+	}
+	var reply raftpb.RPCResponse
+	return c.ProcessTransactionMessages(common.SignShardOps(common.ClusterSecret, ops), &reply)
+}
+
+// Ready reports whether the node serves in-process requests and its shard
+// has a leader.
+func (s *Store) Ready() bool {
+	return s.getCohort() != nil && s.raft.Leader() != ""
+}
+
+// IsLeader reports whether the node leads its shard.
+func (s *Store) IsLeader() bool {
+	return s.raft.State() == raft.Leader
+}
+
+// Shutdown stops the raft groups and the rpc listener of the node and
+// closes its files, after which a node can be started again on the same
+// addresses and directory in the same process.
+func (s *Store) Shutdown() error {
+	var firstErr error
+	keep := func(err error) {
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if c := s.getCohort(); c != nil {
+		keep(common.ShutdownRaft(c.raft))
+		s.cohortMu.Lock()
+		keep(s.listener.Close())
+		s.cohortMu.Unlock()
+	}
+	keep(common.ShutdownRaft(s.raft))
+	s.webhooks.Close()
+	keep(s.persistKvDbConn.db.Close())
+	return firstErr
+}
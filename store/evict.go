@@ -0,0 +1,63 @@
+package store
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// evictBatchSize is the most keys evicted by a raft entry.
+const evictBatchSize = 1000
+
+// applyEvict evicts the keys of the EVICT commands, recording their eviction
+// in their history, told apart from the deletes of the clients.
+func (f *fsm) applyEvict(cmds []*raftpb.Command, rev int64) interface{} {
+	evicted := f.kv.Evict(cmds)
+	f.forgetExpiry(evicted)
+	for _, key := range evicted {
+		f.deleted.Delete(key, rev)
+		f.recordRevision(key, common.Revision{Rev: rev, Deleted: true, Evicted: true})
+	}
+	f.log.Infof("evicted %d of %d keys", len(evicted), len(cmds))
+	return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
+}
+
+// evictKeys has the leader of the store raft group evict the coldest keys
+// once the shard holds more than common.CacheBytes, through raft so that the
+// replicas evict the same keys.
+func (s *Store) evictKeys() {
+	if common.CacheBytes <= 0 {
+		return
+	}
+	for range time.Tick(common.EvictInterval) {
+		if s.witness || s.raft.State() != raft.Leader {
+			continue
+		}
+		cmds := s.kv.EvictionCandidates(common.CacheBytes, common.CachePolicy, 0)
+		for len(cmds) > 0 {
+			n := len(cmds)
+			if n > evictBatchSize {
+				n = evictBatchSize
+			}
+			if err := s.proposeEvict(cmds[:n]); err != nil {
+				s.log.Warnf("unable to evict keys: %s", err)
+				break
+			}
+			cmds = cmds[n:]
+		}
+	}
+}
+
+func (s *Store) proposeEvict(cmds []*raftpb.Command) error {
+	if err := s.checkProtocolVersion(cmds); err != nil {
+		return err
+	}
+	b, err := proto.Marshal(&raftpb.RaftCommand{Commands: cmds})
+	if err != nil {
+		return err
+	}
+	return s.raft.Apply(b, common.RaftTimeout).Error()
+}
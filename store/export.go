@@ -0,0 +1,83 @@
+package store
+
+import (
+	"sort"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ExportPage replies with up to command.Limit entries of the keys starting
+// with command.Key after command.After, in key order, as of revision
+// command.Revision, the last applied if 0. The Value of the reply is the
+// revision read.
+func (c *Cohort) ExportPage(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	if err := c.store.waitApplied(command.Revision); err != nil {
+		return err
+	}
+	entries, rev, err := c.store.exportPage(command.Key, command.After, command.Revision, int(command.Limit))
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Entries: entries, Value: rev}
+	return nil
+}
+
+// exportPage returns up to limit entries of the keys starting with prefix
+// after key after, as of revision rev, the last applied if 0, and the
+// revision read. The keys changed since rev are read from their history, so
+// the page fails with common.ErrCompacted once the event log or the history
+// of one of them does not reach back to rev.
+func (s *Store) exportPage(prefix, after string, rev int64, limit int) ([]*raftpb.KVEntry, int64, error) {
+	s.applyMu.Lock()
+	entries, err := s.kv.SnapshotPrefix(prefix, exportLockTimeout)
+	if err != nil {
+		s.applyMu.Unlock()
+		return nil, 0, err
+	}
+	var changes []*raftpb.Command
+	if rev == 0 {
+		rev = int64(s.appliedIndex)
+	} else if rev < int64(s.appliedIndex) {
+		if changes, err = s.events.Since(prefix, rev); err != nil {
+			s.applyMu.Unlock()
+			return nil, 0, err
+		}
+	}
+	history := s.history
+	s.applyMu.Unlock()
+
+	page := make(map[string]*raftpb.KVEntry)
+	for _, e := range entries {
+		if e.Key > after {
+			page[e.Key] = e
+		}
+	}
+	for _, ev := range changes {
+		if ev.Key <= after {
+			continue
+		}
+		r, ok, err := history.Lookup(ev.Key, rev)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !ok {
+			delete(page, ev.Key)
+			continue
+		}
+		page[ev.Key] = common.NewEntry(ev.Key, r.Value, common.KeyMeta{ModRevision: r.Rev, Version: r.Version})
+	}
+
+	res := make([]*raftpb.KVEntry, 0, len(page))
+	for _, e := range page {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, rev, nil
+}
@@ -3,6 +3,8 @@ package store
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hashicorp/raft"
@@ -20,85 +22,211 @@ type FSMApplyResponse struct {
 
 // Apply applies a Raft log entry to the key-value store.
 func (f *fsm) Apply(l *raft.Log) interface{} {
+	if f.witness {
+		return &FSMApplyResponse{}
+	}
+	f.applyMu.Lock()
+	defer f.applyMu.Unlock()
+	defer f.hashState(int64(l.Index))
 	var raftCommand raftpb.RaftCommand
+	checked := f.applyCheck.Apply(l.Index, l.Data, func() string { return raftCommand.String() })
+	defer func() { checked(f.kv.StateHash) }()
+	// the deletions before are not known since the store is restored
+	f.deleted.Start(int64(l.Index) - 1)
+	f.events.Start(int64(l.Index) - 1)
+	f.appliedIndex, f.appliedTerm = l.Index, l.Term
 	if err := proto.Unmarshal(l.Data, &raftCommand); err != nil {
 		panic(fmt.Sprintf("failed to unmarshal command: %s", err.Error()))
 	}
 	f.log.Infof("Apply %v", raftCommand)
+	if len(raftCommand.Batch) > 0 && !raftCommand.IsTxn {
+		return f.applyBulk(raftCommand.Batch, int64(l.Index))
+	}
+	if len(raftCommand.Batch) > 0 {
+		return f.applyBatch(raftCommand.Batch, int64(l.Index))
+	}
+	defer f.slow.Observe(common.SlowApply, raftCommand.Commands[0].Key, "", time.Now())
 	// txn set is locked already in prepare
 	if !raftCommand.IsTxn {
 		command := raftCommand.Commands[0]
 		switch command.Method {
-		case common.SET:
-			if command.Cond == nil {
-				return f.applySet(command.Key, command.Value)
-			} else {
-				return f.applySetCond(command.Key, command.Value, command.Cond.Value)
-			}
-		case common.DEL:
-			return f.applyDelete(command.Key)
-		default:
-			panic(fmt.Sprintf("unrecognized command: %+v", command))
+		case common.OPEN, common.CLOSE, common.EXPIRE:
+			return f.applySessionCommand(command)
+		case common.VERSION:
+			return f.applyVersion(command)
+		case common.EVICT:
+			return f.applyEvict(raftCommand.Commands, int64(l.Index))
+		case common.COMPARE:
+			return f.applyCompare(command.Compare, int64(l.Index))
+		case common.HASH:
+			f.stateHashes.Record(int64(l.Index), f.kv.StateHash())
+			return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
+		case common.MERKLE:
+			(*Store)(f).captureMerkle(int64(l.Index))
+			return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
+		}
+		// retried writes of a session reply with the outcome of the first
+		if command.Session == "" {
+			return f.applyCommand(command, int64(l.Index))
 		}
+		if resp := f.sessionReply(command); resp != nil {
+			return resp
+		}
+		resp := f.applyCommand(command, int64(l.Index))
+		f.recordSessionReply(command, resp)
+		return resp
 	}
-	return f.applyTransaction(raftCommand.Commands)
+	return f.applyTransaction(raftCommand.Commands, int64(l.Index))
 }
 
-// Snapshot returns a snapshot of the key-value store.
-func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
-	m := f.kv.Snapshot()
+// hashState records the hash of the keys once the entry at index is applied,
+// every common.StateHashInterval entries, at the same indexes on every
+// replica.
+func (f *fsm) hashState(index int64) {
+	if common.StateHashInterval <= 0 || index%common.StateHashInterval != 0 {
+		return
+	}
+	f.stateHashes.Record(index, f.kv.StateHash())
+}
 
-	// Clone the map.
-	o := make(map[string]int64)
-	for k, v := range m {
-		o[k] = v.(int64)
+func (f *fsm) applyCommand(command *raftpb.Command, rev int64) interface{} {
+	if err := runApplyHooks(command); err != nil {
+		return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+	}
+	var resp *FSMApplyResponse
+	switch command.Method {
+	case common.SET:
+		resp = f.applySet(command.Key, common.CommandValue(command), command.Cond, rev).(*FSMApplyResponse)
+	case common.DEL:
+		resp = f.applyDeleteCommand(command, rev)
+	case common.GETSET, common.GETDEL:
+		resp = f.applyGetAnd(command, rev).(*FSMApplyResponse)
+	case common.SETNX:
+		resp = f.applySetIfAbsent(command.Key, common.CommandValue(command), rev).(*FSMApplyResponse)
+	case common.UNDELETE:
+		resp = f.applyUndelete(command, rev)
+	default:
+		panic(fmt.Sprintf("unrecognized command: %+v", command))
 	}
+	if resp.err == nil && (command.Method != common.SETNX && command.Method != common.UNDELETE || resp.reply.Value == 1) {
+		f.trackExpiry(command)
+	}
+	return resp
+}
 
-	return &fsmSnapshot{store: o, persistDBConn: f.persistKvDbConn, bucketName: f.persistBucketName,
+// Snapshot returns a snapshot of the key-value store.
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	if f.witness {
+		return witnessSnapshot{}, nil
+	}
+	// the keys are read while persisting, without holding off the writes
+	return &fsmSnapshot{view: f.kv.View(), index: int64(f.appliedIndex), sessions: (*Store)(f).sessionsSnapshot(), expires: (*Store)(f).expiryTimes(), versions: (*Store)(f).snapshotVersions(), persistDBConn: f.persistKvDbConn, bucketName: f.persistBucketName,
 		logger: f.log}, nil
 }
 
 // Restore stores the key-value store to a previous state.
-func (f *fsm) Restore(_ io.ReadCloser) error {
-	rst := make(map[string]int64)
-	rst = f.restore()
-	f.log.Infof(" Snapshot restore from bucket: %s with kv-size: %d", f.persistBucketName, len(rst))
+func (f *fsm) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	if f.witness {
+		_, err := io.Copy(ioutil.Discard, rc)
+		return err
+	}
+	rst := make(map[string]interface{})
+	meta := make(map[string]common.KeyMeta)
+	sessions := make(map[string]*raftpb.Session)
+	expires := make(map[string]int64)
+	versions := make(map[string]int32)
+	var hash *raftpb.StateHash
+	err := readSnapshot(rc, func(e *raftpb.KVEntry) error {
+		if e.Session != nil {
+			sessions[e.Session.Id] = e.Session
+			return nil
+		}
+		if e.MemberVersion != nil {
+			versions[e.MemberVersion.Id] = e.MemberVersion.Version
+			return nil
+		}
+		if e.StateHash != nil {
+			hash = e.StateHash
+			return nil
+		}
+		rst[e.Key] = common.EntryValue(e)
+		meta[e.Key] = common.EntryMeta(e)
+		if e.Expires != 0 {
+			expires[e.Key] = e.Expires
+		}
+		return nil
+	})
+	if err == errLegacySnapshot {
+		rst, meta = f.restore()
+		f.log.Infof(" Snapshot restore from bucket: %s with kv-size: %d", f.persistBucketName, len(rst))
+	} else if err != nil {
+		return err
+	} else {
+		f.log.Infof(" Snapshot restore with kv-size: %d", len(rst))
+	}
+
+	if hash != nil {
+		if sum := common.StateHashOf(rst, meta); sum != hash.Hash {
+			return fmt.Errorf("snapshot at index %d hashes to %016x, expecting %016x", hash.Index, sum, hash.Hash)
+		}
+	}
+	// the hashes before are of the replaced keys
+	f.stateHashes.Reset()
+	f.applyCheck.Reset()
+	if hash != nil {
+		f.stateHashes.Record(hash.Index, hash.Hash)
+	}
 
 	// Set the state from the snapshot, no lock required according to
 	// Hashicorp docs.
-	o := make(map[string]interface{})
-	for k, v := range rst {
-		o[k] = v
-	}
-	f.kv = common.NewCmapFromMap(f.log.Logger, o, common.LockContention)
+	kv := common.NewCmapFromMap(f.log.Logger, rst, common.LockContention)
+	kv.RestoreMeta(meta)
+	kv.SetSlowLog(f.slow)
+	kv.SetLockStats(f.lockStats)
+	kv.SetNode(f.ID)
+	f.applyMu.Lock()
+	f.kv = kv
+	f.deleted = common.NewTombstones(common.DeltaTombstones)
+	// revisions older than the snapshot are compacted
+	f.history = common.NewHistory(common.HistoryRetention)
+	f.events.Reset()
+	f.applyMu.Unlock()
+	// the changes up to the snapshot are unknown to the watchers
+	f.watches.CancelAll(fmt.Errorf("%w: snapshot restored", common.ErrCompacted))
+	f.sessionMu.Lock()
+	f.sessions = sessions
+	f.sessionExpiry = expiryOf(sessions)
+	f.sessionMu.Unlock()
+	(*Store)(f).restoreExpiry(expires)
+	f.restoreVersions(versions)
 	return nil
 }
 
-func (f *fsm) applySet(key string, value int64) interface{} {
-
-	err := f.kv.Set(key, value)
-	if err == nil {
-		return &FSMApplyResponse{
-			reply: raftpb.RPCResponse{Status: 0},
-		}
+// applySetIfAbsent sets key if it does not exist, and replies with a Value of
+// 1 if it was set, 0 otherwise.
+func (f *fsm) applySetIfAbsent(key string, value interface{}, rev int64) interface{} {
+	ok, err := f.kv.SetIfAbsent(key, value, rev)
+	if err != nil {
+		return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
 	}
-	f.log.Infof(err.Error())
-	return &FSMApplyResponse{
-		err:   err,
-		reply: raftpb.RPCResponse{Status: -1},
+	if !ok {
+		return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
 	}
-
+	f.recordHistory(key, rev, false)
+	return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0, Value: 1}}
 }
 
-func (f *fsm) applySetCond(key string, value, value0 int64) interface{} {
+func (f *fsm) applySet(key string, value interface{}, cond *raftpb.Cond, rev int64) interface{} {
 
-	err := f.kv.SetCond(key, value, value0)
+	err := f.kv.SetRev(key, value, cond, rev)
 	if err == nil {
+		f.recordHistory(key, rev, false)
 		return &FSMApplyResponse{
 			reply: raftpb.RPCResponse{Status: 0},
 		}
 	}
-
+	f.log.Infof(err.Error())
 	return &FSMApplyResponse{
 		err:   err,
 		reply: raftpb.RPCResponse{Status: -1},
@@ -106,9 +234,10 @@ func (f *fsm) applySetCond(key string, value, value0 int64) interface{} {
 
 }
 
-func (f *fsm) applyDelete(key string) interface{} {
+func (f *fsm) applyDelete(key string, rev int64) interface{} {
 	err := f.kv.Del(key)
 	if err == nil {
+		f.recordHistory(key, rev, true)
 		return &FSMApplyResponse{
 			reply: raftpb.RPCResponse{Status: 0},
 		}
@@ -121,15 +250,51 @@ func (f *fsm) applyDelete(key string) interface{} {
 }
 
 // return transaction result
-func (f *fsm) applyTransaction(ops []*raftpb.Command) interface{} {
+func (f *fsm) applyTransaction(ops []*raftpb.Command, rev int64) interface{} {
 	// WriteWithLocks will fail in recovery
 	// Write is safe as long as it's only used in txn
-	f.kv.Write(ops)
+	f.kv.Write(ops, rev)
+	for _, op := range ops {
+		f.recordHistory(op.Key, rev, op.Method == common.DEL)
+	}
+	f.trackExpiry(ops...)
 	return nil
 }
 
+// recordHistory appends the value of key at rev, or its deletion, to the
+// history of the key.
+func (f *fsm) recordHistory(key string, rev int64, deleted bool) {
+	if deleted {
+		f.deleted.Delete(key, rev)
+		f.recordRevision(key, common.Revision{Rev: rev, Deleted: true})
+		return
+	}
+	if val, meta, ok, err := f.kv.GetWithMeta(key); ok && err == nil {
+		f.recordRevision(key, common.Revision{Rev: rev, Value: val, Version: meta.Version})
+	}
+}
+
+// recordRevision appends r to the history of key and to the event log, and
+// sends it to the watchers of key, and to its webhooks if leading.
+func (f *fsm) recordRevision(key string, r common.Revision) {
+	f.history.Record(key, r)
+	cmd := revisionCommand(key, r)
+	f.events.Append(cmd)
+	f.watches.Publish(cmd)
+	if f.raft != nil && f.raft.State() == raft.Leader {
+		f.webhooks.Publish(cmd)
+	}
+}
+
 type fsmSnapshot struct {
-	store         map[string]int64
+	view *common.CmapView
+	// index is the last entry applied to the view
+	index         int64
+	store         map[string]interface{}
+	meta          map[string]common.KeyMeta
+	sessions      []*raftpb.Session
+	expires       map[string]int64
+	versions      map[string]int32
 	persistDBConn *persistKvDB
 	bucketName    string
 	logger        *log.Entry
@@ -137,8 +302,15 @@ type fsmSnapshot struct {
 
 func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 	f.logger.Infof(" Snapshot persisted to bucket: %s", f.bucketName)
+	f.store, f.meta = f.view.Read()
+	f.view.Close()
 	err := func() error {
-		// Persist data.
+		// Write the snapshot to the sink so that it is shipped to lagging
+		// followers, and keep a copy in the bolt bucket.
+		hash := &raftpb.StateHash{Index: f.index, Hash: common.StateHashOf(f.store, f.meta)}
+		if err := writeSnapshot(sink, f.store, f.meta, f.expires, f.sessions, f.versions, hash); err != nil {
+			return err
+		}
 		f.save()
 
 		// Close the sink.
@@ -152,4 +324,6 @@ func (f *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
 	return err
 }
 
-func (f *fsmSnapshot) Release() {}
+func (f *fsmSnapshot) Release() {
+	f.view.Close()
+}
@@ -0,0 +1,27 @@
+package store
+
+import (
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// applyGetAnd applies the set of a GETSET or the delete of a GETDEL command,
+// and replies with the value the key had before, along with its metadata.
+// Meta is not set if the key did not exist.
+func (f *fsm) applyGetAnd(command *raftpb.Command, rev int64) interface{} {
+	val, meta, ok, err := f.kv.GetWithMeta(command.Key)
+	if err != nil {
+		return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+	}
+	var resp *FSMApplyResponse
+	if command.Method == common.GETSET {
+		resp = f.applySet(command.Key, common.CommandValue(command), command.Cond, rev).(*FSMApplyResponse)
+	} else {
+		resp = f.applyDeleteCommand(command, rev)
+	}
+	if resp.err == nil && ok {
+		resp.reply.Meta = common.MetaProto(meta, val)
+		common.SetResponseValue(&resp.reply, val)
+	}
+	return resp
+}
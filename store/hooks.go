@@ -0,0 +1,72 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/raft-kv-store/raftpb"
+)
+
+// ProposeHook is called by the leader with the commands of a write before
+// proposing them: single writes, bulk writes, compare transactions and the
+// prepare of transactions. It may change the commands in place, or return an
+// error to refuse the write, which is then replied to the client.
+type ProposeHook func(cmds []*raftpb.Command) error
+
+// ApplyHook is called by every replica with a single or bulk write before
+// applying it. It may change the command in place, or return an error to
+// fail the write. Replicas apply the same log and must reach the same state,
+// so an ApplyHook depends on the command alone: not on the clock, the node
+// or anything outside the log. The writes of transactions are not passed to
+// it, their keys being locked at prepare: check them in a ProposeHook.
+type ApplyHook func(cmd *raftpb.Command) error
+
+var (
+	hooksMu      sync.RWMutex
+	proposeHooks []ProposeHook
+	applyHooks   []ApplyHook
+)
+
+// RegisterProposeHook adds h to the hooks called before proposing a write,
+// after the hooks registered before it. Hooks are registered by the
+// application before starting the node, to validate, limit or rewrite the
+// writes without changing the store.
+func RegisterProposeHook(h ProposeHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	proposeHooks = append(proposeHooks, h)
+}
+
+// RegisterApplyHook adds h to the hooks called before applying a write,
+// after the hooks registered before it. Every replica must register the same
+// hooks.
+func RegisterApplyHook(h ApplyHook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	applyHooks = append(applyHooks, h)
+}
+
+// runProposeHooks passes cmds through the propose hooks, stopping at the
+// first error.
+func runProposeHooks(cmds []*raftpb.Command) error {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, h := range proposeHooks {
+		if err := h(cmds); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runApplyHooks passes cmd through the apply hooks, stopping at the first
+// error.
+func runApplyHooks(cmd *raftpb.Command) error {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	for _, h := range applyHooks {
+		if err := h(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// errImportInProgress is returned for writes while a bulk import is staged.
+var errImportInProgress = errors.New("bulk import in progress, writes are rejected")
+
+// importState is a bulk import staged on the shard leader. Chunks are
+// appended to a file in the snapshot stream format and installed at once
+// through a raft user restore, bypassing per key proposals.
+type importState struct {
+	id   string
+	path string
+	file *os.File
+	w    *snapshotWriter
+	n    int
+}
+
+// ImportChunk stages a chunk of a bulk import. Writes to the shard are
+// rejected until the import is installed or aborted.
+func (c *Cohort) ImportChunk(chunk *raftpb.ImportChunk, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	if err := c.verify("Cohort.ImportChunk", chunk); err != nil {
+		return err
+	}
+	if c.store.raft.State() != raft.Leader {
+		return errors.New("not the shard leader")
+	}
+	c.importMu.Lock()
+	defer c.importMu.Unlock()
+
+	if c.imp == nil {
+		path := filepath.Join(c.store.RaftDir, "import-"+chunk.Id)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		w, err := newSnapshotWriter(f)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		c.imp = &importState{id: chunk.Id, path: path, file: f, w: w}
+		c.store.setImporting(true)
+		c.store.log.Infof("staging bulk import %s in %s", chunk.Id, path)
+	} else if c.imp.id != chunk.Id {
+		return fmt.Errorf("bulk import %s is already in progress", c.imp.id)
+	}
+
+	// chunks are written sorted, like the rest of the snapshot
+	sort.Slice(chunk.Entries, func(i, j int) bool { return chunk.Entries[i].Key < chunk.Entries[j].Key })
+	for _, e := range chunk.Entries {
+		if err := c.imp.w.write(e); err != nil {
+			return err
+		}
+	}
+	c.imp.n += len(chunk.Entries)
+	*reply = raftpb.RPCResponse{Status: 0, Value: int64(c.imp.n)}
+	return nil
+}
+
+// InstallImport installs the staged import chunk.Id. The current content of
+// the shard is written first so that imported keys overwrite existing ones and
+// other keys are kept. Imported keys are stamped with the last log index.
+func (c *Cohort) InstallImport(chunk *raftpb.ImportChunk, reply *raftpb.RPCResponse) error {
+	if err := c.verify("Cohort.InstallImport", chunk); err != nil {
+		return err
+	}
+	c.importMu.Lock()
+	defer c.importMu.Unlock()
+	if c.imp == nil || c.imp.id != chunk.Id {
+		return fmt.Errorf("bulk import %s is not staged", chunk.Id)
+	}
+	imp := c.imp
+	defer c.discardImport()
+
+	if err := imp.w.close(); err != nil {
+		return err
+	}
+	path := imp.path + ".snap"
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+	defer f.Close()
+
+	// no write is applied while importing, the shard content is stable
+	w, err := newSnapshotWriter(f)
+	if err != nil {
+		return err
+	}
+	view := c.store.kv.View()
+	values, keyMeta := view.Read()
+	view.Close()
+	if err := writeEntries(w, values, keyMeta, c.store.expiryTimes()); err != nil {
+		return err
+	}
+	rev := int64(c.store.raft.LastIndex())
+	staged, err := os.Open(imp.path)
+	if err != nil {
+		return err
+	}
+	defer staged.Close()
+	err = readSnapshot(staged, func(e *raftpb.KVEntry) error {
+		e.Meta = &raftpb.KeyMeta{CreateRevision: rev, ModRevision: rev, Version: 1}
+		return w.write(e)
+	})
+	if err != nil {
+		return err
+	}
+	// the installed snapshot replaces the client sessions too
+	if err := writeSessions(w, c.store.sessionsSnapshot()); err != nil {
+		return err
+	}
+	if err := writeVersions(w, c.store.snapshotVersions()); err != nil {
+		return err
+	}
+	if err := w.close(); err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+	meta := &raft.SnapshotMeta{Version: raft.SnapshotVersionMax, Size: info.Size()}
+	if err := c.store.raft.Restore(meta, f, common.RaftTimeout); err != nil {
+		return fmt.Errorf("unable to install bulk import %s: %s", imp.id, err)
+	}
+	c.store.log.Infof("installed bulk import %s with %d keys", imp.id, imp.n)
+	*reply = raftpb.RPCResponse{Status: 0, Value: int64(imp.n)}
+	return nil
+}
+
+// AbortImport discards the staged import chunk.Id and accepts writes again.
+func (c *Cohort) AbortImport(chunk *raftpb.ImportChunk, reply *raftpb.RPCResponse) error {
+	if err := c.verify("Cohort.AbortImport", chunk); err != nil {
+		return err
+	}
+	c.importMu.Lock()
+	defer c.importMu.Unlock()
+	if c.imp != nil && c.imp.id == chunk.Id {
+		c.store.log.Infof("aborting bulk import %s", chunk.Id)
+		c.discardImport()
+	}
+	*reply = raftpb.RPCResponse{Status: 0}
+	return nil
+}
+
+func (c *Cohort) discardImport() {
+	c.imp.file.Close()
+	os.Remove(c.imp.path)
+	c.imp = nil
+	c.store.setImporting(false)
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// readIndexPoll is how often a read waits for the read index to be applied.
+const readIndexPoll = time.Millisecond
+
+// lease is the time until which the leader may serve reads locally.
+type lease struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (l *lease) extend(until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until.After(l.until) {
+		l.until = until
+	}
+}
+
+func (l *lease) valid(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return now.Before(l.until)
+}
+
+// renewLease keeps confirming the leadership of the store raft group with a
+// quorum while leader. The lease starts when the confirmation is sent, so
+// that it never outlives what the followers acknowledged.
+func (s *Store) renewLease() {
+	d := common.LeaseDuration()
+	if common.ReadMode != common.ReadLease || d <= 0 {
+		s.log.Infof("leader leases disabled, reads use a read index")
+		return
+	}
+	for range time.Tick(d / 3) {
+		if s.raft.State() != raft.Leader {
+			continue
+		}
+		start := time.Now()
+		if err := s.raft.VerifyLeader().Error(); err == nil {
+			s.lease.extend(start.Add(d))
+		}
+	}
+}
+
+// checkRead makes sure that a read served now by the leader observes every
+// write acknowledged before it. With verify, or when the lease does not hold,
+// the leader confirms its leadership with a quorum and waits until it has
+// applied the entries committed when the read arrived. It returns the check
+// made: common.ReadLinearizable, common.ReadLease or common.ReadLocal.
+func (s *Store) checkRead(verify bool) (string, error) {
+	switch {
+	case common.ReadMode == common.ReadLocal && !verify:
+		return common.ReadLocal, nil
+	case common.ReadMode == common.ReadLease && !verify && s.raft.State() == raft.Leader && s.lease.valid(time.Now()):
+		return common.ReadLease, nil
+	}
+	return common.ReadLinearizable, s.readIndex()
+}
+
+// checkReadOf is checkRead for the read command, at its consistency level if
+// set: stale reads are served by any replica without check, and leader-local
+// reads by the leader without confirming its leadership.
+func (s *Store) checkReadOf(command *raftpb.Command) (string, error) {
+	switch {
+	case command.Consistency == common.ReadStale:
+		return common.ReadStale, nil
+	case command.Verify || command.Consistency == common.ReadLinearizable:
+		return common.ReadLinearizable, s.readIndex()
+	case command.Consistency == common.ReadLeaderLocal:
+		if s.raft.State() != raft.Leader {
+			return "", raft.ErrNotLeader
+		}
+		return common.ReadLeaderLocal, nil
+	}
+	return s.checkRead(false)
+}
+
+// readInfo describes a read served now with the consistency check made.
+func (s *Store) readInfo(consistency string) *raftpb.ReadInfo {
+	s.applyMu.Lock()
+	index, term := s.appliedIndex, s.appliedTerm
+	s.applyMu.Unlock()
+	return &raftpb.ReadInfo{
+		Node:         s.ID,
+		State:        s.raft.State().String(),
+		AppliedIndex: int64(index),
+		AppliedTerm:  int64(term),
+		Consistency:  consistency,
+	}
+}
+
+// waitApplied waits until the node has applied revision rev, for
+// common.RaftTimeout at most.
+func (s *Store) waitApplied(rev int64) error {
+	deadline := time.Now().Add(common.RaftTimeout)
+	for int64(s.raft.AppliedIndex()) < rev {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: %d, applied %d", common.ErrRevisionNotApplied, rev, s.raft.AppliedIndex())
+		}
+		time.Sleep(readIndexPoll)
+	}
+	return nil
+}
+
+// readIndex confirms the leadership with a quorum and waits until the commit
+// index at the time of the call is applied.
+func (s *Store) readIndex() error {
+	index, err := strconv.ParseUint(s.raft.Stats()["commit_index"], 10, 64)
+	if err != nil {
+		return err
+	}
+	if err := s.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(common.RaftTimeout)
+	for s.raft.AppliedIndex() < index {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out applying read index %d", index)
+		}
+		time.Sleep(readIndexPoll)
+	}
+	return nil
+}
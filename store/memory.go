@@ -0,0 +1,41 @@
+package store
+
+import (
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// accountMemory recomputes the bytes of the keys and values of the store
+// every common.MemoryInterval.
+func (s *Store) accountMemory() {
+	for range time.Tick(common.MemoryInterval) {
+		if s.witness {
+			continue
+		}
+		s.memory.Set(common.MemoryStore, s.kv.Bytes(0))
+	}
+}
+
+// accountHeap measures the heap of the node every common.HeapInterval, if it
+// has a heap limit.
+func (s *Store) accountHeap() {
+	if common.HeapLimit <= 0 {
+		return
+	}
+	for range time.Tick(common.HeapInterval) {
+		s.memory.Set(common.MemoryHeap, common.HeapInUse())
+	}
+}
+
+// grows reports whether cmds may add keys or grow values, the deletes being
+// let through once the store is over its memory budget.
+func grows(cmds []*raftpb.Command) bool {
+	for _, cmd := range cmds {
+		if cmd.Method != common.DEL && cmd.Method != common.GETDEL {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"net/rpc"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// merkleViewTTL is how long the keys a Merkle tree was built from are kept
+// for the repairs.
+const merkleViewTTL = time.Minute
+
+// merkleCapture is the Merkle tree of the keys of the node built when the
+// MERKLE entry at index was applied, with a view of those keys.
+type merkleCapture struct {
+	index int64
+	tree  *common.MerkleTree
+	view  *common.CmapView
+}
+
+// captureMerkle builds the Merkle tree of the keys once the MERKLE entry at
+// index is applied, replacing the previous one.
+func (s *Store) captureMerkle(index int64) {
+	m := &merkleCapture{index: index, tree: s.kv.MerkleTree(), view: s.kv.View()}
+	s.merkleMu.Lock()
+	if s.merkle != nil {
+		s.merkle.view.Close()
+	}
+	s.merkle = m
+	s.merkleMu.Unlock()
+	time.AfterFunc(merkleViewTTL, func() {
+		s.merkleMu.Lock()
+		defer s.merkleMu.Unlock()
+		if s.merkle == m {
+			m.view.Close()
+			s.merkle = nil
+		}
+	})
+}
+
+// merkleAt waits for the Merkle tree built at index.
+func (s *Store) merkleAt(index int64) (*merkleCapture, error) {
+	deadline := time.Now().Add(common.RaftTimeout)
+	for {
+		s.merkleMu.Lock()
+		m := s.merkle
+		s.merkleMu.Unlock()
+		if m != nil && m.index == index {
+			return m, nil
+		}
+		if m != nil && m.index > index || time.Now().After(deadline) {
+			return nil, fmt.Errorf("no Merkle tree of the keys at index %d", index)
+		}
+		time.Sleep(readIndexPoll)
+	}
+}
+
+// BuildMerkle has the leader propose a MERKLE entry, applied by every replica
+// by building the Merkle tree of its keys at the same index, and replies with
+// the index.
+func (c *Cohort) BuildMerkle(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	index, err := c.proposeMarker(common.MERKLE)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Revision: index}
+	return nil
+}
+
+// MerkleNodes replies with the hashes of the nodes and the entries of the
+// buckets of the Merkle tree built at req.Index.
+func (c *Cohort) MerkleNodes(req *raftpb.MerkleRequest, reply *raftpb.MerkleReply) error {
+	if c.store.witness {
+		return errWitness
+	}
+	m, err := c.store.merkleAt(req.Index)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.MerkleReply{Index: m.index, Hashes: m.tree.Hashes(req.Nodes)}
+	if len(req.Buckets) > 0 {
+		reply.Entries = m.view.ReadBuckets(req.Buckets)
+	}
+	return nil
+}
+
+// Repair compares the Merkle tree of the keys of the node built at req.Index
+// with the one of req.Source, and replaces the keys of the buckets that
+// differ with those of req.Source. The keys written since the trees were
+// built are left. It replies with the number of keys repaired.
+func (c *Cohort) Repair(req *raftpb.MerkleRequest, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	if err := c.verify("Cohort.Repair", req); err != nil {
+		return err
+	}
+	if c.store.raft.State() == raft.Leader {
+		return errors.New("the leader is the source of the repairs, transfer its leadership first")
+	}
+	m, err := c.store.merkleAt(req.Index)
+	if err != nil {
+		return err
+	}
+	client, err := rpc.DialHTTP("tcp", req.Source)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	buckets, err := m.tree.Diff(func(nodes []int32) ([]uint64, error) {
+		var r raftpb.MerkleReply
+		err := client.Call("Cohort.MerkleNodes", &raftpb.MerkleRequest{Index: req.Index, Nodes: nodes}, &r)
+		return r.Hashes, err
+	})
+	if err != nil {
+		return err
+	}
+	repaired := 0
+	if len(buckets) > 0 {
+		var r raftpb.MerkleReply
+		if err := client.Call("Cohort.MerkleNodes", &raftpb.MerkleRequest{Index: req.Index, Buckets: buckets}, &r); err != nil {
+			return err
+		}
+		repaired = c.store.repairKeys(m.view.ReadBuckets(buckets), r.Entries)
+	}
+	c.store.log.Infof("repaired %d keys of %d buckets from %s at index %d", repaired, len(buckets), req.Source, req.Index)
+	*reply = raftpb.RPCResponse{Status: 0, Value: int64(repaired), Revision: req.Index}
+	return nil
+}
+
+// repairKeys replaces the keys of local that differ from those of source, and
+// returns the number of keys replaced.
+func (s *Store) repairKeys(local, source []*raftpb.KVEntry) int {
+	mine := make(map[string]*raftpb.KVEntry, len(local))
+	for _, e := range local {
+		mine[e.Key] = e
+	}
+	repaired := 0
+	for _, e := range source {
+		l := mine[e.Key]
+		delete(mine, e.Key)
+		if l != nil && proto.Equal(l, e) {
+			continue
+		}
+		if s.kv.RepairKey(e.Key, l, e) {
+			repaired++
+		}
+	}
+	// the keys the source does not hold
+	for k, l := range mine {
+		if s.kv.RepairKey(k, l, nil) {
+			repaired++
+		}
+	}
+	return repaired
+}
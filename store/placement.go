@@ -0,0 +1,201 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// SetReplication sets the number of voters of the raft groups this node leads
+// to command.Value, and replies with their members. Voters over the count are
+// demoted to non-voters, which keep replicating, and non-voters are promoted
+// while under the count. A count of 0 only reports the members.
+func (c *Cohort) SetReplication(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	if err := c.verify("Cohort.SetReplication", command); err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0}
+	c.store.membershipMu.Lock()
+	defer c.store.membershipMu.Unlock()
+	for _, g := range c.ledGroups() {
+		members, err := c.store.setVoters(g.ra, g.self, int(command.Value))
+		if err != nil {
+			return err
+		}
+		members.Group = g.name
+		reply.Members = append(reply.Members, members)
+	}
+	return nil
+}
+
+// ChangeMembers joins, promotes and removes the members of change and then
+// transfers the leadership, in the raft groups this node leads, and replies
+// with their members and the steps taken, or only planned with
+// change.DryRun. The steps already in place are skipped. The store node ids
+// of change are mapped to the cohort ids.
+func (c *Cohort) ChangeMembers(change *raftpb.MemberChange, reply *raftpb.RPCResponse) error {
+	if err := c.verify("Cohort.ChangeMembers", change); err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0}
+	c.store.membershipMu.Lock()
+	defer c.store.membershipMu.Unlock()
+	for _, g := range c.ledGroups() {
+		members, err := c.store.changeMembers(g.ra, g.self, g.joins(change.Join), g.ids(change.Promote), g.ids(change.Remove), g.id(change.Transfer), change.DryRun)
+		if err != nil {
+			return fmt.Errorf("%s group: %s", g.name, err)
+		}
+		members.Group = g.name
+		reply.Members = append(reply.Members, members)
+	}
+	return nil
+}
+
+// group is a raft group of the node.
+type group struct {
+	name string
+	// self is the id of the node in the group
+	self   string
+	ra     *raft.Raft
+	prefix string
+}
+
+// ids returns the ids in the group of the store nodes.
+func (g *group) ids(nodes []string) []string {
+	res := make([]string, len(nodes))
+	for i, id := range nodes {
+		res[i] = g.id(id)
+	}
+	return res
+}
+
+// id returns the id in the group of the store node id, empty if empty.
+func (g *group) id(id string) string {
+	if id == "" {
+		return ""
+	}
+	return g.prefix + id
+}
+
+// joins returns the servers of the group of the store nodes joining.
+func (g *group) joins(joins []*raftpb.MemberJoin) []raft.Server {
+	res := make([]raft.Server, len(joins))
+	for i, j := range joins {
+		res[i] = raft.Server{ID: raft.ServerID(g.id(j.Id)), Address: raft.ServerAddress(j.Address), Suffrage: raft.Nonvoter}
+		if g.name == CohortInstance {
+			res[i].Address = raft.ServerAddress(j.CohortAddress)
+		}
+		if j.Voter {
+			res[i].Suffrage = raft.Voter
+		}
+	}
+	return res
+}
+
+// ledGroups returns the raft groups this node leads.
+func (c *Cohort) ledGroups() []*group {
+	var res []*group
+	for _, g := range []*group{
+		{name: StoreInstance, self: c.store.ID, ra: c.store.raft},
+		{name: CohortInstance, self: c.ID, ra: c.raft, prefix: "c-"},
+	} {
+		if g.ra != nil && g.ra.State() == raft.Leader {
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
+// changeMembers joins joins, promotes the non-voters promote, removes the
+// members remove and then transfers the leadership to transfer in ra, led by
+// the server self, unless dryRun. It returns the members and the steps.
+func (s *Store) changeMembers(ra *raft.Raft, self string, joins []raft.Server, promote, remove []string, transfer string, dryRun bool) (*raftpb.GroupMembers, error) {
+	future := ra.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	plan, err := common.PlanMembers(future.Configuration().Servers, self, joins, promote, remove, transfer)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		for _, a := range plan {
+			s.log.Infof("member change: %s %s", a.Op, a.Id)
+		}
+		if err := common.ApplyMembers(ra, plan); err != nil {
+			return nil, err
+		}
+	}
+	members, err := groupMembers(ra)
+	if err != nil {
+		return nil, err
+	}
+	members.Actions = plan
+	return members, nil
+}
+
+// setVoters brings the number of voters of ra, led by the server self, to n
+// when there are enough members and returns the members.
+func (s *Store) setVoters(ra *raft.Raft, self string, n int) (*raftpb.GroupMembers, error) {
+	members, err := groupMembers(ra)
+	if err != nil {
+		return nil, err
+	}
+	if n <= 0 {
+		return members, nil
+	}
+	// the leader is never demoted, the last voters joined go first
+	for i := len(members.Voters) - 1; i >= 0 && len(members.Voters) > n; i-- {
+		id := members.Voters[i]
+		if id == self {
+			continue
+		}
+		s.log.Infof("demoting %s to non-voter, %d voters wanted", id, n)
+		if err := ra.DemoteVoter(raft.ServerID(id), 0, 0).Error(); err != nil {
+			return nil, err
+		}
+		members.Voters = append(members.Voters[:i], members.Voters[i+1:]...)
+		members.Nonvoters = append(members.Nonvoters, id)
+	}
+	if len(members.Voters) >= n || len(members.Nonvoters) == 0 {
+		return members, nil
+	}
+	future := ra.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	voters := len(members.Voters)
+	for _, srv := range future.Configuration().Servers {
+		if voters >= n {
+			break
+		}
+		if srv.Suffrage == raft.Voter {
+			continue
+		}
+		s.log.Infof("promoting %s to voter, %d voters wanted", srv.ID, n)
+		if err := ra.AddVoter(srv.ID, srv.Address, 0, 0).Error(); err != nil {
+			return nil, err
+		}
+		voters++
+	}
+	return groupMembers(ra)
+}
+
+// groupMembers returns the voters and non-voters of ra.
+func groupMembers(ra *raft.Raft) (*raftpb.GroupMembers, error) {
+	future := ra.GetConfiguration()
+	if err := future.Error(); err != nil {
+		return nil, err
+	}
+	members := &raftpb.GroupMembers{}
+	for _, srv := range future.Configuration().Servers {
+		if srv.Suffrage == raft.Voter {
+			members.Voters = append(members.Voters, string(srv.ID))
+		} else {
+			members.Nonvoters = append(members.Nonvoters, string(srv.ID))
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// preparedLocksInterval is how often the leader checks that it holds the
+// locks of the prepared transactions.
+const preparedLocksInterval = time.Second
+
+// onePhaseResolveInterval is how often the leader checks whether the entry of
+// a one-phase commit of unknown outcome is applied.
+const onePhaseResolveInterval = 10 * time.Millisecond
+
+// preparedOps returns the transactions prepared on the shard and not yet
+// committed or aborted.
+func (c *Cohort) preparedOps() []*raftpb.ShardOps {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var prepared []*raftpb.ShardOps
+	for _, so := range c.opsMap {
+		if so.Phase == common.Prepared && so.Cmds != nil {
+			prepared = append(prepared, so)
+		}
+	}
+	return prepared
+}
+
+// unlockedPreparedOps returns the prepared transactions whose keys are not
+// locked on this node.
+func (c *Cohort) unlockedPreparedOps() []*raftpb.ShardOps {
+	var unlocked []*raftpb.ShardOps
+	for _, so := range c.preparedOps() {
+		if !c.store.kv.HoldsLocks(so.Cmds.Commands, so.Txid) {
+			unlocked = append(unlocked, so)
+		}
+	}
+	return unlocked
+}
+
+// holdPreparedLocks has the leader of the store raft group take the locks of
+// the transactions prepared on the shard. The locks are only kept in memory
+// while the prepare records are in the cohort log, so after a restart of the
+// shard or a change of leader the keys of a prepared transaction would
+// otherwise be writable until it commits.
+func (c *Cohort) holdPreparedLocks() {
+	for range time.Tick(preparedLocksInterval) {
+		if c.store.witness || c.store.raft.State() != raft.Leader {
+			continue
+		}
+		if len(c.unlockedPreparedOps()) == 0 {
+			continue
+		}
+		// no commit or abort is half done while the phases are checked again
+		c.txnMu.Lock()
+		for _, so := range c.unlockedPreparedOps() {
+			if err := c.store.kv.TryLocks(so.Cmds.Commands, so.Txid); err != nil {
+				c.store.log.Warnf("unable to lock the keys of prepared transaction %s: %s", so.Txid, err)
+				continue
+			}
+			c.store.log.Infof("locked the keys of prepared transaction %s again", so.Txid)
+		}
+		c.txnMu.Unlock()
+	}
+}
+
+// resolveOnePhase keeps the locks of the one-phase transaction ops, whose
+// entry may have been appended at index, the last index of the log if not
+// known, until its outcome is known: once the node has applied the entries
+// up to index, the keys were rewritten if the entry committed, and the locks
+// left are released. A node which is not the leader anymore releases them
+// right away, as it applies the writes of the next leader to the same keys,
+// the entry included if it commits.
+func (c *Cohort) resolveOnePhase(ops *raftpb.ShardOps, index uint64) {
+	if index == 0 {
+		index = c.store.raft.LastIndex()
+	}
+	for c.store.raft.AppliedIndex() < index && c.store.raft.State() == raft.Leader {
+		time.Sleep(onePhaseResolveInterval)
+	}
+	c.store.kv.AbortWithLocks(ops.Cmds.Commands, ops.Txid)
+}
+
+// commitOnePhase commits a transaction whose keys all belong to this shard
+// with a single entry of the store raft group, without prepare record. The
+// locks keep concurrent transactions out until the entry is applied, which
+// releases them. The outcome is unknown, see common.ErrOutcomeUnknown, if
+// proposing the entry fails once it may have been appended, and the locks
+// are kept until it is known, see resolveOnePhase.
+func (c *Cohort) commitOnePhase(ops *raftpb.ShardOps, reply *raftpb.RPCResponse) error {
+	if err := c.store.checkProtocolVersion(ops.Cmds.Commands); err != nil {
+		return err
+	}
+	if c.store.isImporting() {
+		return errImportInProgress
+	}
+	done, err := c.store.throttle(ops.Cmds.Commands)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if err := c.store.kv.TryLocks(ops.Cmds.Commands, ops.Txid); err != nil {
+		return err
+	}
+	start := time.Now()
+	rev, err := c.store.proposeTxn(ops.Cmds)
+	c.store.slow.Observe(common.SlowCommit, ops.MasterKey, ops.Txid, start)
+	if err == raft.ErrNotLeader || err == raft.ErrEnqueueTimeout {
+		// the entry was not appended
+		c.store.kv.AbortWithLocks(ops.Cmds.Commands, ops.Txid)
+		return fmt.Errorf("Unable to commit in one phase: %s", err)
+	}
+	if err != nil {
+		// the entry may have been appended and may still be committed, by
+		// this leader or the next one
+		go c.resolveOnePhase(ops, uint64(rev))
+		return fmt.Errorf("%w: %s", common.ErrOutcomeUnknown, err)
+	}
+	*reply = raftpb.RPCResponse{
+		Status:   0,
+		Phase:    common.Committed,
+		Revision: rev,
+	}
+	return nil
+}
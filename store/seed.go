@@ -0,0 +1,201 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// seedChunkSize is the size of the snapshot chunks sent to seed a replica.
+const seedChunkSize = 4 << 20
+
+// seedStream is the snapshot being read by a seeding replica.
+type seedStream struct {
+	id     string
+	offset int64
+	rc     io.ReadCloser
+}
+
+// snapshotStore returns the snapshot store of the store raft group, opened
+// besides the one of raft to read the snapshots it writes.
+func (s *Store) snapshotStore() (*raft.FileSnapshotStore, error) {
+	s.seedMu.Lock()
+	defer s.seedMu.Unlock()
+	if s.snapshots == nil {
+		snapshots, err := raft.NewFileSnapshotStore(common.SnapshotDirOf(s.RaftDir), common.RetainSnapshotCount, ioutil.Discard)
+		if err != nil {
+			return nil, err
+		}
+		s.snapshots = snapshots
+	}
+	return s.snapshots, nil
+}
+
+// SnapshotChunk replies with the chunk at req.Offset of the latest snapshot
+// of the store, or with its metadata only if req.Id is empty. The snapshot
+// is read sequentially for every session.
+func (c *Cohort) SnapshotChunk(req *raftpb.SnapshotChunk, reply *raftpb.SnapshotChunk) error {
+	if c.store.witness {
+		return errWitness
+	}
+	snapshots, err := c.store.snapshotStore()
+	if err != nil {
+		return err
+	}
+	if req.Id == "" {
+		metas, err := snapshots.List()
+		if err != nil {
+			return err
+		}
+		if len(metas) == 0 {
+			return errors.New("no snapshot to seed from")
+		}
+		*reply = *snapshotChunkMeta(metas[0])
+		reply.Leader = c.store.raft.State() == raft.Leader
+		return nil
+	}
+
+	c.store.seedMu.Lock()
+	defer c.store.seedMu.Unlock()
+	stream := c.store.seeds[req.Session]
+	if stream == nil || stream.id != req.Id || stream.offset != req.Offset {
+		if stream != nil {
+			stream.rc.Close()
+			delete(c.store.seeds, req.Session)
+		}
+		meta, rc, err := snapshots.Open(req.Id)
+		if err != nil {
+			return err
+		}
+		if common.SnapshotBandwidth > 0 {
+			rc = common.NewBandwidth(common.SnapshotBandwidth).Reader(rc)
+		}
+		if _, err := io.CopyN(ioutil.Discard, rc, req.Offset); err != nil {
+			rc.Close()
+			return err
+		}
+		stream = &seedStream{id: meta.ID, offset: req.Offset, rc: rc}
+		c.store.seeds[req.Session] = stream
+	}
+
+	data := make([]byte, seedChunkSize)
+	n, err := io.ReadFull(stream.rc, data)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		stream.rc.Close()
+		delete(c.store.seeds, req.Session)
+		return err
+	}
+	stream.offset += int64(n)
+	if n < seedChunkSize {
+		stream.rc.Close()
+		delete(c.store.seeds, req.Session)
+	}
+	*reply = raftpb.SnapshotChunk{Session: req.Session, Id: req.Id, Offset: req.Offset, Data: data[:n]}
+	return nil
+}
+
+func snapshotChunkMeta(meta *raft.SnapshotMeta) *raftpb.SnapshotChunk {
+	chunk := &raftpb.SnapshotChunk{
+		Id:                 meta.ID,
+		Version:            int32(meta.Version),
+		Index:              meta.Index,
+		Term:               meta.Term,
+		ConfigurationIndex: meta.ConfigurationIndex,
+		Size:               meta.Size,
+	}
+	for _, srv := range meta.Configuration.Servers {
+		chunk.Configuration = append(chunk.Configuration, &raftpb.RaftServer{
+			Id:       string(srv.ID),
+			Address:  string(srv.Address),
+			Suffrage: int32(srv.Suffrage),
+		})
+	}
+	return chunk
+}
+
+// seed installs in the snapshot store of a new replica the latest snapshot
+// of the nearest of the nodes at addrs, preferring followers to the leader.
+// raft then restores it and the leader only sends the entries after it,
+// rather than its own snapshot. Replicas with raft state are not seeded.
+func (s *Store) seed(addrs []string) error {
+	if _, err := os.Stat(filepath.Join(s.RaftDir, "raft.db")); err == nil {
+		return nil
+	}
+	var source string
+	var meta *raftpb.SnapshotChunk
+	var best time.Duration
+	for _, addr := range addrs {
+		start := time.Now()
+		m, err := fetchSnapshotChunk(addr, &raftpb.SnapshotChunk{Session: s.ID})
+		if err != nil {
+			s.log.Infof("unable to seed from %s: %s", addr, err)
+			continue
+		}
+		rtt := time.Since(start)
+		if m.Leader {
+			// the leader is the last resort
+			rtt += time.Hour
+		}
+		if meta == nil || rtt < best {
+			source, meta, best = addr, m, rtt
+		}
+	}
+	if meta == nil {
+		return fmt.Errorf("no node to seed from among %v", addrs)
+	}
+	s.log.Infof("seeding from snapshot %s of %s, %d bytes at index %d", meta.Id, source, meta.Size, meta.Index)
+
+	snapshots, err := s.snapshotStore()
+	if err != nil {
+		return err
+	}
+	var configuration raft.Configuration
+	for _, srv := range meta.Configuration {
+		configuration.Servers = append(configuration.Servers, raft.Server{
+			ID:       raft.ServerID(srv.Id),
+			Address:  raft.ServerAddress(srv.Address),
+			Suffrage: raft.ServerSuffrage(srv.Suffrage),
+		})
+	}
+	sink, err := snapshots.Create(raft.SnapshotVersion(meta.Version), meta.Index, meta.Term, configuration, meta.ConfigurationIndex, nil)
+	if err != nil {
+		return err
+	}
+	for offset := int64(0); offset < meta.Size; {
+		chunk, err := fetchSnapshotChunk(source, &raftpb.SnapshotChunk{Session: s.ID, Id: meta.Id, Offset: offset})
+		if err == nil && len(chunk.Data) == 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil {
+			_, err = sink.Write(chunk.Data)
+		}
+		if err != nil {
+			sink.Cancel()
+			return fmt.Errorf("seeding from %s: %s", source, err)
+		}
+		offset += int64(len(chunk.Data))
+	}
+	return sink.Close()
+}
+
+func fetchSnapshotChunk(addr string, req *raftpb.SnapshotChunk) (*raftpb.SnapshotChunk, error) {
+	client, err := rpc.DialHTTP("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	var reply raftpb.SnapshotChunk
+	if err := client.Call("Cohort.SnapshotChunk", req, &reply); err != nil {
+		return nil, err
+	}
+	return &reply, nil
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// sessionReply returns the outcome of the write command of a session if it
+// was already applied, nil if it has to be applied.
+func (f *fsm) sessionReply(command *raftpb.Command) *FSMApplyResponse {
+	f.sessionMu.Lock()
+	defer f.sessionMu.Unlock()
+	sess, ok := f.sessions[command.Session]
+	if !ok {
+		return &FSMApplyResponse{err: common.ErrUnknownSession, reply: raftpb.RPCResponse{Status: -1}}
+	}
+	sess.LastActive = command.Time
+	f.sessionExpiry.Set(sess.Id, sess.LastActive)
+	switch {
+	case command.Seq < sess.LastSeq:
+		err := fmt.Errorf("write %d of session %s is older than the last one, %d", command.Seq, command.Session, sess.LastSeq)
+		return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+	case command.Seq == sess.LastSeq:
+		resp := &FSMApplyResponse{}
+		if sess.Reply != nil {
+			resp.reply = *sess.Reply
+		}
+		if sess.Error != "" {
+			resp.err = errors.New(sess.Error)
+		}
+		return resp
+	}
+	return nil
+}
+
+// recordSessionReply records resp as the outcome of the write command of a
+// session.
+func (f *fsm) recordSessionReply(command *raftpb.Command, resp interface{}) {
+	f.sessionMu.Lock()
+	defer f.sessionMu.Unlock()
+	sess, ok := f.sessions[command.Session]
+	if !ok {
+		return
+	}
+	sess.LastSeq = command.Seq
+	sess.Reply, sess.Error = nil, ""
+	if r, ok := resp.(*FSMApplyResponse); ok {
+		reply := r.reply
+		sess.Reply = &reply
+		if r.err != nil {
+			sess.Error = r.err.Error()
+		}
+	}
+}
+
+// applySessionCommand opens, closes or expires client sessions. EXPIRE drops
+// the sessions without writes since command.Value, set by the leader, so that
+// every replica expires the same sessions.
+func (f *fsm) applySessionCommand(command *raftpb.Command) interface{} {
+	f.sessionMu.Lock()
+	defer f.sessionMu.Unlock()
+	switch command.Method {
+	case common.OPEN:
+		if _, ok := f.sessions[command.Key]; !ok {
+			f.sessions[command.Key] = &raftpb.Session{Id: command.Key, LastActive: command.Time}
+			f.sessionExpiry.Set(command.Key, command.Time)
+		}
+	case common.CLOSE:
+		delete(f.sessions, command.Key)
+		f.sessionExpiry.Remove(command.Key)
+	case common.EXPIRE:
+		for _, id := range f.sessionExpiry.Expire(command.Value) {
+			delete(f.sessions, id)
+		}
+	}
+	return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
+}
+
+// expiryOf returns the index of the last writes of sessions.
+func expiryOf(sessions map[string]*raftpb.Session) *common.ExpiryIndex {
+	x := common.NewExpiryIndex()
+	for id, sess := range sessions {
+		x.Set(id, sess.LastActive)
+	}
+	return x
+}
+
+// sessionsSnapshot returns a copy of the client sessions, by id.
+func (s *Store) sessionsSnapshot() []*raftpb.Session {
+	s.sessionMu.Lock()
+	defer s.sessionMu.Unlock()
+	sessions := make([]*raftpb.Session, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		sessions = append(sessions, proto.Clone(sess).(*raftpb.Session))
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Id < sessions[j].Id })
+	return sessions
+}
+
+// writeSessions writes the client sessions after the keys of a snapshot.
+func writeSessions(sw *snapshotWriter, sessions []*raftpb.Session) error {
+	for _, sess := range sessions {
+		if err := sw.write(&raftpb.KVEntry{Session: sess}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expireSessions has the leader of the store raft group expire the sessions
+// idle for common.SessionTimeout.
+func (s *Store) expireSessions() {
+	if common.SessionTimeout <= 0 {
+		return
+	}
+	for range time.Tick(common.SessionTimeout / 4) {
+		if s.witness || s.raft.State() != raft.Leader {
+			continue
+		}
+		now := time.Now()
+		before := now.Add(-common.SessionTimeout).UnixNano()
+		// no entry is proposed until a session is idle for long enough
+		s.sessionMu.Lock()
+		next, ok := s.sessionExpiry.Next()
+		s.sessionMu.Unlock()
+		if !ok || next >= before {
+			continue
+		}
+		cmd := &raftpb.RaftCommand{Commands: []*raftpb.Command{{
+			Method: common.EXPIRE,
+			Value:  before,
+			Time:   now.UnixNano(),
+		}}}
+		if err := s.checkProtocolVersion(cmd.Commands); err != nil {
+			continue
+		}
+		b, err := proto.Marshal(cmd)
+		if err != nil {
+			continue
+		}
+		if err := s.raft.Apply(b, common.RaftTimeout).Error(); err != nil {
+			s.log.Warnf("unable to expire sessions: %s", err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// snapshotMagic starts every kv snapshot stream. Snapshots taken before the
+// stream format existed are empty and restored from the bolt bucket instead.
+// Streams of the first version, snapshotMagicV1, are a sequence of records
+// without chunks.
+var (
+	snapshotMagic   = []byte("RKVSNAP2")
+	snapshotMagicV1 = []byte("RKVSNAP1")
+)
+
+const (
+	// maxEntrySize bounds a single snapshot entry, to fail fast on corrupted streams.
+	maxEntrySize = 64 << 20
+	// snapshotChunkSize is the size from which a chunk of records is written.
+	snapshotChunkSize = 1 << 20
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// snapshotWriter writes length-prefixed KVEntry records after the magic, in
+// chunks. A chunk is its length, the crc32c of its records and the records.
+// An empty chunk ends the stream, so that truncated streams are detected.
+type snapshotWriter struct {
+	w     *bufio.Writer
+	chunk bytes.Buffer
+	buf   [binary.MaxVarintLen64]byte
+}
+
+func newSnapshotWriter(w io.Writer) (*snapshotWriter, error) {
+	sw := &snapshotWriter{w: bufio.NewWriter(w)}
+	if _, err := sw.w.Write(snapshotMagic); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (sw *snapshotWriter) write(e *raftpb.KVEntry) error {
+	b, err := proto.Marshal(e)
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(sw.buf[:], uint64(len(b)))
+	sw.chunk.Write(sw.buf[:n])
+	sw.chunk.Write(b)
+	if sw.chunk.Len() >= snapshotChunkSize {
+		return sw.writeChunk()
+	}
+	return nil
+}
+
+func (sw *snapshotWriter) writeChunk() error {
+	n := binary.PutUvarint(sw.buf[:], uint64(sw.chunk.Len()))
+	if _, err := sw.w.Write(sw.buf[:n]); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.Checksum(sw.chunk.Bytes(), castagnoli))
+	if _, err := sw.w.Write(sum[:]); err != nil {
+		return err
+	}
+	_, err := sw.w.Write(sw.chunk.Bytes())
+	sw.chunk.Reset()
+	return err
+}
+
+// close writes the pending records and the end of the stream.
+func (sw *snapshotWriter) close() error {
+	if sw.chunk.Len() > 0 {
+		if err := sw.writeChunk(); err != nil {
+			return err
+		}
+	}
+	if err := sw.writeChunk(); err != nil {
+		return err
+	}
+	return sw.w.Flush()
+}
+
+// writeEntries writes the keys of m, in key order, with their metadata and
+// expiry time.
+func writeEntries(sw *snapshotWriter, m map[string]interface{}, meta map[string]common.KeyMeta, expires map[string]int64) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e := common.NewEntry(k, m[k], meta[k])
+		e.Expires = expires[k]
+		if err := sw.write(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSnapshot writes a stream of the keys of m with their metadata and
+// expiry time, of the client sessions, of the protocol versions of the
+// members, and of hash, the hash of the keys, if given.
+func writeSnapshot(w io.Writer, m map[string]interface{}, meta map[string]common.KeyMeta, expires map[string]int64, sessions []*raftpb.Session, versions map[string]int32, hash *raftpb.StateHash) error {
+	sw, err := newSnapshotWriter(w)
+	if err != nil {
+		return err
+	}
+	if err := writeEntries(sw, m, meta, expires); err != nil {
+		return err
+	}
+	if err := writeSessions(sw, sessions); err != nil {
+		return err
+	}
+	if err := writeVersions(sw, versions); err != nil {
+		return err
+	}
+	if hash != nil {
+		if err := sw.write(&raftpb.KVEntry{StateHash: hash}); err != nil {
+			return err
+		}
+	}
+	return sw.close()
+}
+
+// errLegacySnapshot is returned by readSnapshot for streams without the magic.
+var errLegacySnapshot = errors.New("snapshot predates the stream format")
+
+// readSnapshot calls fn for every entry of the stream r.
+func readSnapshot(r io.Reader, fn func(*raftpb.KVEntry) error) error {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err == io.EOF {
+		return errLegacySnapshot
+	} else if err != nil {
+		return err
+	}
+	switch {
+	case bytes.Equal(magic, snapshotMagicV1):
+		return readRecords(br, fn)
+	case bytes.Equal(magic, witnessSnapshotMagic):
+		return errWitnessSnapshot
+	case !bytes.Equal(magic, snapshotMagic):
+		return errors.New("unknown snapshot format")
+	}
+
+	var sum [4]byte
+	for i := 0; ; i++ {
+		size, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		// a chunk ends with the record that brings it over snapshotChunkSize
+		if size > snapshotChunkSize+binary.MaxVarintLen64+maxEntrySize {
+			return fmt.Errorf("snapshot chunk %d of %d bytes is too large", i, size)
+		}
+		if _, err := io.ReadFull(br, sum[:]); err != nil {
+			return err
+		}
+		if size == 0 {
+			return nil
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return fmt.Errorf("snapshot chunk %d is truncated: %s", i, err)
+		}
+		if crc32.Checksum(chunk, castagnoli) != binary.BigEndian.Uint32(sum[:]) {
+			return fmt.Errorf("snapshot chunk %d is corrupted", i)
+		}
+		if err := readRecords(bytes.NewReader(chunk), fn); err != nil {
+			return fmt.Errorf("snapshot chunk %d: %s", i, err)
+		}
+	}
+}
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// readRecords calls fn for every length-prefixed record of r.
+func readRecords(r byteReader, fn func(*raftpb.KVEntry) error) error {
+	for {
+		size, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if size > maxEntrySize {
+			return fmt.Errorf("snapshot entry of %d bytes is too large", size)
+		}
+		b := make([]byte, size)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		e := &raftpb.KVEntry{}
+		if err := proto.Unmarshal(b, e); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// applyDeleteCommand deletes the key of a DEL or GETDEL command, keeping its
+// value in its tombstone if soft deleted.
+func (f *fsm) applyDeleteCommand(command *raftpb.Command, rev int64) *FSMApplyResponse {
+	if command.Expires == 0 {
+		return f.applyDelete(command.Key, rev).(*FSMApplyResponse)
+	}
+	val, ok, err := f.kv.Get(command.Key)
+	if err != nil {
+		return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+	}
+	resp := f.applyDelete(command.Key, rev).(*FSMApplyResponse)
+	if resp.err != nil || !ok {
+		return resp
+	}
+	tombstone := common.TombstonePrefix + command.Key
+	if err := f.kv.SetRev(tombstone, val, nil, rev); err != nil {
+		f.log.Warnf("unable to keep the tombstone of Key=%s: %s", command.Key, err)
+		return resp
+	}
+	f.trackExpiry(&raftpb.Command{Method: common.SET, Key: tombstone, Expires: command.Expires})
+	return resp
+}
+
+// applyUndelete restores the key of command from its tombstone, unless the
+// key exists, and replies with a Value of 1 if it did, 0 otherwise. The key
+// is restored without ttl.
+func (f *fsm) applyUndelete(command *raftpb.Command, rev int64) *FSMApplyResponse {
+	tombstone := common.TombstonePrefix + command.Key
+	val, ok, err := f.kv.Get(tombstone)
+	if err != nil {
+		return &FSMApplyResponse{err: err, reply: raftpb.RPCResponse{Status: -1}}
+	}
+	if !ok {
+		return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
+	}
+	resp := f.applySetIfAbsent(command.Key, val, rev).(*FSMApplyResponse)
+	if resp.err != nil || resp.reply.Value != 1 {
+		return resp
+	}
+	if err := f.kv.Del(tombstone); err == nil {
+		f.deleted.Delete(tombstone, rev)
+		f.forgetExpiry([]string{tombstone})
+	}
+	return resp
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// HashState has the leader propose a HASH entry, applied by every replica by
+// hashing its keys at the same index, and replies with the index.
+func (c *Cohort) HashState(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	index, err := c.proposeMarker(common.HASH)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Revision: index}
+	return nil
+}
+
+// proposeMarker has the leader propose an entry of method without key, for
+// every replica to act on at the same index, and returns the index.
+func (c *Cohort) proposeMarker(method string) (int64, error) {
+	if c.store.raft.State() != raft.Leader {
+		return 0, raft.ErrNotLeader
+	}
+	cmds := []*raftpb.Command{{Method: method}}
+	if err := c.store.checkProtocolVersion(cmds); err != nil {
+		return 0, err
+	}
+	b, err := proto.Marshal(&raftpb.RaftCommand{Commands: cmds})
+	if err != nil {
+		return 0, err
+	}
+	f := c.store.raft.Apply(b, common.RaftTimeout)
+	if err := f.Error(); err != nil {
+		return 0, err
+	}
+	return int64(f.Index()), nil
+}
+
+// StateHashes replies with the recent hashes of the keys of the node, once
+// it has hashed them at command.Revision or later.
+func (c *Cohort) StateHashes(command *raftpb.Command, reply *raftpb.RPCResponse) error {
+	// witnesses keep no keys
+	if c.store.witness {
+		*reply = raftpb.RPCResponse{Status: 0}
+		return nil
+	}
+	deadline := time.Now().Add(common.RaftTimeout)
+	for {
+		hashes := c.store.stateHashes.List()
+		if n := len(hashes); command.Revision <= 0 || n > 0 && hashes[n-1].Index >= command.Revision {
+			*reply = raftpb.RPCResponse{Status: 0, StateHashes: hashes}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w: %d", common.ErrRevisionNotApplied, command.Revision)
+		}
+		time.Sleep(readIndexPoll)
+	}
+}
@@ -9,9 +9,13 @@ package store
 
 import (
 	"fmt"
+	"net"
 	"net/rpc"
 	"os"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/raft"
 	"github.com/raft-kv-store/common"
@@ -36,26 +40,97 @@ type Store struct {
 	rpcAddress string
 
 	kv *common.Cmap // The key-value store for the system.
+	// history keeps the recent revisions of every key
+	history *common.History
+	// deleted remembers the keys recently deleted, for the snapshot deltas
+	deleted *common.Tombstones
+	// watches are the watchers of the changes of the keys
+	watches *common.WatchHub
+	// events keeps the recent changes, for the watchers to start from
+	events *common.EventLog
+	// webhooks posts the changes applied while leading, nil without webhooks
+	webhooks *common.WebhookSender
+
+	// applyMu is held while applying an entry, the last applied
+	applyMu      sync.Mutex
+	appliedIndex uint64
+	appliedTerm  uint64
 
 	raft              *raft.Raft // The consensus mechanism
 	log               *log.Entry
 	persistBucketName string
 	persistKvDbConn   *persistKvDB // persistent store
 
+	// versions holds the protocol version announced by each raft member
+	versions *common.MemberVersions
+
+	// importing is set while a bulk import is staged on this node
+	importing int32
+
+	// slow keeps the recent operations over the slow thresholds
+	slow *common.SlowLog
+	// lockStats counts the lock timeouts and waits of kv
+	lockStats *common.LockStats
+	// stateHashes are the recent hashes of kv, every StateHashInterval
+	// entries applied
+	stateHashes *common.StateHashes
+	// applyCheck compares the states reached by the replicas of the
+	// process, in the determinism builds
+	applyCheck *common.ApplyCheck
+	// merkle is the last Merkle tree of kv built for a repair
+	merkleMu sync.Mutex
+	merkle   *merkleCapture
+
+	// witness is set on nodes that vote but keep no keys
+	witness bool
+
+	// lease lets the leader serve reads without confirming its leadership
+	lease lease
+
+	// seeds are the snapshots being read by seeding replicas, by session
+	seedMu    sync.Mutex
+	seeds     map[string]*seedStream
+	snapshots *raft.FileSnapshotStore
+
+	// sessions is the dedup table of the client sessions, by id
+	sessionMu sync.Mutex
+	sessions  map[string]*raftpb.Session
+	// sessionExpiry orders the sessions by last write, to expire them
+	sessionExpiry *common.ExpiryIndex
+
+	// keyExpiry orders the keys with a ttl by expiry time, to delete them
+	expiryMu  sync.Mutex
+	keyExpiry *common.ExpiryIndex
+
+	// txnBatch queues the committed transactions to pack in an entry
+	txnBatch chan *txnProposal
+
+	// proposals bounds the writes and prepares in flight, by priority
+	proposals *common.PriorityGate
+	// memory accounts the bytes of the keys and of the proposals in flight
+	memory *common.MemoryAccount
+
+	// membershipMu serializes the changes of the members of the raft groups
+	membershipMu sync.Mutex
+
+	// cohort and its rpc listener, once started
+	cohortMu sync.Mutex
+	cohort   *Cohort
+	listener net.Listener
 }
 
 // NewStore returns a new Store.
 func NewStore(logger *log.Logger, nodeID, raftDir, raftAddress string, enableSingle bool, rpcAddress string, bucketName, cohortRaftAddress, cohortJoinAddress string) *Store {
-	if nodeID == "" {
-		nodeID = "node-" + common.RandNodeID(common.NodeIDLen)
-	}
-
 	if raftDir == "" {
 		raftDir, _ = os.Hostname()
 	}
 
 	l := logger.WithField("component", "store")
 	shardsDir := filepath.Join(common.RaftPVBaseDir, raftDir)
+	nodeID, err := common.NodeIdentity(shardsDir, nodeID)
+	if err != nil {
+		l.Fatal(err)
+	}
 
 	l.Infof("Preparing node-%s with persistent directory %s, raftAddress %s", nodeID, shardsDir, raftAddress)
 	os.MkdirAll(shardsDir, 0700)
@@ -68,11 +143,50 @@ func NewStore(logger *log.Logger, nodeID, raftDir, raftAddress string, enableSin
 		ID:                nodeID,
 		RaftAddress:       raftAddress,
 		kv:                common.NewCmap(logger, common.LockContention),
+		history:           common.NewHistory(common.HistoryRetention),
+		deleted:           common.NewTombstones(common.DeltaTombstones),
+		watches:           common.NewWatchHub(),
+		events:            common.NewEventLog(common.WatchHistorySize),
 		log:               l,
 		rpcAddress:        rpcAddress,
 		persistKvDbConn:   persistDbConn,
 		persistBucketName: bucketName,
 		RaftDir:           shardsDir,
+		versions:          common.NewMemberVersions(),
+		slow:              common.NewSlowLog(nodeID, common.SlowLogSize),
+		lockStats:         common.NewLockStats(),
+		stateHashes:       common.NewStateHashes(nodeID),
+		applyCheck:        common.NewApplyCheck(nodeID),
+		witness:           common.Witness,
+		seeds:             make(map[string]*seedStream),
+		sessions:          make(map[string]*raftpb.Session),
+		sessionExpiry:     common.NewExpiryIndex(),
+		keyExpiry:         common.NewExpiryIndex(),
+		txnBatch:          make(chan *txnProposal),
+		proposals:         common.NewPriorityGate(common.MaxProposals),
+		memory: common.NewMemoryAccount(map[string]int64{
+			common.MemoryStore:     common.StoreMemoryBudget,
+			common.MemoryProposals: common.ProposalMemoryBudget,
+			common.MemoryHeap:      common.HeapBudget(),
+		}),
+	}
+	s.kv.SetSlowLog(s.slow)
+	s.kv.SetLockStats(s.lockStats)
+	s.kv.SetNode(nodeID)
+	s.versions.Set(nodeID, common.ProtocolVersion)
+	webhooks, err := common.NewWebhookSender(logger, bucketName, common.Webhooks, common.WebhookSecret)
+	if err != nil {
+		l.Fatalf("Unable to setup the webhooks: %s", err)
+	}
+	s.webhooks = webhooks
+
+	if len(common.SeedFrom) > 0 && !enableSingle {
+		if err := s.seed(common.SeedFrom); err != nil {
+			l.Warnf("unable to seed the store, the leader will send its snapshot: %s", err)
+		}
+		if err := s.catchUp(common.SeedFrom); err != nil {
+			l.Warnf("unable to catch up with a delta, the leader may send its snapshot: %s", err)
+		}
 	}
 
 	ra, err := common.SetupRaft((*fsm)(s), s.ID, s.RaftAddress, shardsDir, enableSingle)
@@ -80,6 +194,20 @@ func NewStore(logger *log.Logger, nodeID, raftDir, raftAddress string, enableSin
 		l.Fatalf("Unable to setup raft instance for kv store:%s", err)
 	}
 	s.raft = ra
+	go s.replicateOwnVersion()
+	go s.renewLease()
+	go s.expireSessions()
+	go s.evictKeys()
+	go s.expireKeys()
+	go s.accountMemory()
+	go s.accountHeap()
+	if common.TxnBatchSize > 1 {
+		go s.batchTxns()
+	}
+	if s.witness {
+		l.Infof("node-%s is a witness, it votes but stores no keys", nodeID)
+		go s.yieldLeadership(ra, StoreInstance)
+	}
 	go startCohort(s, rpcAddress, "c-"+s.ID, cohortRaftAddress, "cohort"+s.RaftDir, enableSingle, cohortJoinAddress)
 	return s
 }
@@ -91,22 +219,108 @@ func (s *Store) Start(joinHTTPAddress, id string) {
 	if joinHTTPAddress == "" {
 		return
 	}
+	clusterID, err := common.ClusterID(s.RaftDir)
+	if err != nil {
+		s.log.Fatal(err)
+	}
 	var response raftpb.RPCResponse
 	msg := &raftpb.JoinMsg{
 		RaftAddress: s.RaftAddress,
 		ID:          id,
 		TYPE:        StoreInstance,
+		Version:     common.ProtocolVersion,
+		Nonvoter:    common.Nonvoter,
+		ClusterID:   clusterID,
 	}
 
-	client, err := rpc.DialHTTP("tcp", joinHTTPAddress)
+	err = common.RetryJoinAny(joinHTTPAddress, func(addr string) error {
+		client, err := rpc.DialHTTP("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("Unable to reach leader: %s", err)
+		}
+		defer client.Close()
+		if err := client.Call("Cohort.ProcessJoin", common.SignJoin(common.ClusterSecret, msg), &response); err != nil {
+			return fmt.Errorf("Unable to join cluster: %s", err)
+		}
+		return nil
+	})
+	if err == nil {
+		err = common.SetClusterID(s.RaftDir, response.ClusterId)
+	}
 	if err != nil {
-		s.log.Fatalf("Unable to reach leader: %s", err)
+		s.log.Fatal(err)
 	}
+}
+
+func (s *Store) setImporting(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&s.importing, v)
+}
 
-	err = client.Call("Cohort.ProcessJoin", msg, &response)
+func (s *Store) isImporting() bool {
+	return atomic.LoadInt32(&s.importing) == 1
+}
+
+// throttle delays, or rejects, a new proposal of cmds while the log has
+// entries not yet committed by a quorum and applied. The leader applies
+// entries as soon as the followers needed for a quorum acknowledge them, so
+// this is their lag. The proposal is also rejected if it would put the node
+// over its memory budgets, or while its heap is over the watermark of its
+// limit. It then waits for the turn of the proposal among those in flight,
+// by priority then in turn with the other clients, and returns the function
+// ending it. The propose hooks check, or rewrite, cmds first.
+func (s *Store) throttle(cmds []*raftpb.Command) (func(), error) {
+	if err := runProposeHooks(cmds); err != nil {
+		return nil, err
+	}
+	priority := common.Priority(cmds)
+	lag := int64(s.raft.LastIndex()) - int64(s.raft.AppliedIndex())
+	d, err := common.ThrottleDelayAt(lag, priority)
+	if err != nil {
+		return nil, err
+	}
+	if d > 0 {
+		s.log.Infof("throttling %s priority proposal by %s, %d entries not applied", common.PriorityName(priority), d, lag)
+		time.Sleep(d)
+	}
+	if grows(cmds) {
+		if err := s.memory.Check(common.MemoryStore, priority); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.memory.Check(common.MemoryHeap, priority); err != nil {
+		return nil, err
+	}
+	release, err := s.memory.Reserve(common.MemoryProposals, common.CommandsSize(cmds), priority)
 	if err != nil {
-		s.log.Fatalf("Unable to join cluster: %s", err)
+		return nil, err
+	}
+	done := s.proposals.Acquire(priority, common.Client(cmds))
+	return func() {
+		done()
+		release()
+	}, nil
+}
+
+func (s *Store) setCohort(c *Cohort, listener net.Listener) {
+	s.cohortMu.Lock()
+	defer s.cohortMu.Unlock()
+	s.cohort, s.listener = c, listener
+}
+
+// RaftStats returns the stats of the raft instances of the node, the store
+// and the cohort once it is started.
+func (s *Store) RaftStats() map[string]map[string]string {
+	stats := map[string]map[string]string{"store": s.raft.Stats()}
+	s.cohortMu.Lock()
+	defer s.cohortMu.Unlock()
+	if s.cohort != nil {
+		stats["cohort"] = s.cohort.raft.Stats()
 	}
+	return stats
 }
 
 // Leader returns the current leader of the cluster
@@ -116,8 +330,15 @@ func (s *Store) Leader() string {
 
 // Join joins a node, identified by nodeID and located at addr, to this store.
 // The node must be ready to respond to Raft communications at that address.
-func (s *Store) Join(nodeID, addr string) error {
-	s.log.Infof("received join request for remote node %s at %s", nodeID, addr)
+// version is the protocol version announced by the node. A nonvoter node
+// replicates the log without a vote until it is promoted. The address of a
+// member is only changed if verified, see common.MoveMember.
+func (s *Store) Join(nodeID, addr string, version int32, nonvoter, verified bool) error {
+	s.log.Infof("received join request for remote node %s at %s (protocol version %d)", nodeID, addr, version)
+	s.versions.Set(nodeID, version)
+	if err := s.replicateVersion(nodeID, version); err != nil {
+		s.log.Warnf("unable to replicate the protocol version of %s: %s", nodeID, err)
+	}
 
 	configFuture := s.raft.GetConfiguration()
 	if err := configFuture.Error(); err != nil {
@@ -125,6 +346,15 @@ func (s *Store) Join(nodeID, addr string) error {
 		return err
 	}
 
+	moved, err := common.MoveMember(s.raft, nodeID, addr, verified)
+	if err != nil {
+		return fmt.Errorf("error moving existing node %s to %s: %w", nodeID, addr, err)
+	}
+	if moved {
+		s.log.Infof("node %s moved to %s", nodeID, addr)
+		return nil
+	}
+
 	for _, srv := range configFuture.Configuration().Servers {
 		// If a node already exists with either the joining node's ID or address,
 		// that node may need to be removed from the config first.
@@ -143,10 +373,46 @@ func (s *Store) Join(nodeID, addr string) error {
 		}
 	}
 
-	f := s.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	var f raft.IndexFuture
+	if nonvoter {
+		f = s.raft.AddNonvoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	} else {
+		f = s.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	}
 	if f.Error() != nil {
 		return f.Error()
 	}
 	s.log.Infof("node %s at %s joined successfully", nodeID, addr)
 	return nil
 }
+
+// checkProtocolVersion returns an error if any member of the raft group is
+// too old to apply cmds. This keeps mixed-version clusters safe during a
+// rolling upgrade: new command types are only proposed once every member
+// has announced a version able to decode them. Members announce their
+// version when they (re)join, and the leader replicates it through raft, so
+// that a newly elected leader knows it too. Members it has not heard of are
+// treated as the base version until they rejoin.
+func (s *Store) checkProtocolVersion(cmds []*raftpb.Command) error {
+	configFuture := s.raft.GetConfiguration()
+	if err := configFuture.Error(); err != nil {
+		return err
+	}
+	var ids []string
+	for _, srv := range configFuture.Configuration().Servers {
+		ids = append(ids, string(srv.ID))
+	}
+	for _, cmd := range cmds {
+		method := cmd.Method
+		// the expiring writes and soft deletes are applied by the replicas
+		// applying undeletes, expires having come without a version of its
+		// own
+		if cmd.Expires != 0 {
+			method = common.UNDELETE
+		}
+		if err := s.versions.Supports(ids, method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
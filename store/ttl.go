@@ -0,0 +1,104 @@
+package store
+
+import (
+	"time"
+
+	"github.com/hashicorp/raft"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// trackExpiry indexes the expiry time of the keys written by cmds, those
+// written without one no longer expiring.
+func (f *fsm) trackExpiry(cmds ...*raftpb.Command) {
+	f.expiryMu.Lock()
+	defer f.expiryMu.Unlock()
+	for _, cmd := range cmds {
+		switch {
+		case cmd.Method == common.DEL || cmd.Method == common.GETDEL || cmd.Expires == 0:
+			f.keyExpiry.Remove(cmd.Key)
+		default:
+			f.keyExpiry.Set(cmd.Key, cmd.Expires)
+		}
+	}
+}
+
+// forgetExpiry drops the expiry time of keys.
+func (f *fsm) forgetExpiry(keys []string) {
+	f.expiryMu.Lock()
+	defer f.expiryMu.Unlock()
+	for _, key := range keys {
+		f.keyExpiry.Remove(key)
+	}
+}
+
+// expiryTimes returns the expiry time of the keys with one.
+func (s *Store) expiryTimes() map[string]int64 {
+	s.expiryMu.Lock()
+	defer s.expiryMu.Unlock()
+	return s.keyExpiry.Times()
+}
+
+// restoreExpiry replaces the expiry times of the keys with expires.
+func (s *Store) restoreExpiry(expires map[string]int64) {
+	index := common.NewExpiryIndex()
+	for key, at := range expires {
+		index.Set(key, at)
+	}
+	s.expiryMu.Lock()
+	s.keyExpiry = index
+	s.expiryMu.Unlock()
+}
+
+// expireKeys has the leader of the store raft group delete the keys past
+// their expiry time, every common.ExpiryInterval. They are evicted through
+// raft as of the revision they expired at, so that the replicas delete the
+// same keys and keep those written again since.
+func (s *Store) expireKeys() {
+	if common.ExpiryInterval <= 0 {
+		return
+	}
+	for range time.Tick(common.ExpiryInterval) {
+		if s.witness || s.raft.State() != raft.Leader {
+			continue
+		}
+		for {
+			s.expiryMu.Lock()
+			keys := s.keyExpiry.Before(time.Now().UnixNano(), evictBatchSize)
+			pending := s.keyExpiry.Len()
+			s.expiryMu.Unlock()
+			if len(keys) == 0 {
+				break
+			}
+			cmds := make([]*raftpb.Command, 0, len(keys))
+			var missing []string
+			for _, key := range keys {
+				_, meta, ok, err := s.kv.Peek(key, common.RaftTimeout)
+				if err != nil {
+					continue
+				}
+				if !ok {
+					missing = append(missing, key)
+					continue
+				}
+				cmds = append(cmds, &raftpb.Command{Method: common.EVICT, Key: key, Revision: meta.ModRevision})
+			}
+			// keys gone without expiring are not in the snapshots either
+			(*fsm)(s).forgetExpiry(missing)
+			if len(cmds) == 0 {
+				break
+			}
+			if err := s.proposeEvict(cmds); err != nil {
+				s.log.Warnf("unable to expire keys: %s", err)
+				break
+			}
+			// the keys locked by transactions are left for the next tick
+			s.expiryMu.Lock()
+			progress := s.keyExpiry.Len() < pending
+			s.expiryMu.Unlock()
+			if len(keys) < evictBatchSize || !progress {
+				break
+			}
+		}
+	}
+}
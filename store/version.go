@@ -0,0 +1,79 @@
+package store
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// replicateVersion proposes the protocol version announced by member id, so
+// that the replicas, and so the leaders elected later, know it without the
+// member joining them again. The versions stay in the memory of the leader
+// while a member is too old to apply them.
+func (s *Store) replicateVersion(id string, version int32) error {
+	if version < common.MinProtocolVersion(common.VERSION) {
+		return nil
+	}
+	cmds := []*raftpb.Command{{Method: common.VERSION, Key: id, Value: int64(version)}}
+	if s.checkProtocolVersion(cmds) != nil {
+		return nil
+	}
+	b, err := proto.Marshal(&raftpb.RaftCommand{Commands: cmds})
+	if err != nil {
+		return err
+	}
+	return s.raft.Apply(b, common.RaftTimeout).Error()
+}
+
+// replicateOwnVersion has the node replicate its protocol version whenever
+// it becomes the leader, as it may run a newer build than the one the other
+// members last heard of.
+func (s *Store) replicateOwnVersion() {
+	for leader := range s.raft.LeaderCh() {
+		if !leader || s.witness {
+			continue
+		}
+		if err := s.replicateVersion(s.ID, common.ProtocolVersion); err != nil {
+			s.log.Warnf("unable to replicate the protocol version: %s", err)
+		}
+	}
+}
+
+// applyVersion records the protocol version of a member. The node keeps its
+// own, that of its build.
+func (f *fsm) applyVersion(command *raftpb.Command) *FSMApplyResponse {
+	if command.Key != f.ID {
+		f.versions.Set(command.Key, int32(command.Value))
+	}
+	return &FSMApplyResponse{reply: raftpb.RPCResponse{Status: 0}}
+}
+
+// restoreVersions records the protocol versions of the members of a
+// snapshot.
+func (f *fsm) restoreVersions(versions map[string]int32) {
+	for id, version := range versions {
+		if id != f.ID {
+			f.versions.Set(id, version)
+		}
+	}
+}
+
+// writeVersions writes the protocol versions of the members after the keys
+// of a snapshot.
+func writeVersions(sw *snapshotWriter, versions map[string]int32) error {
+	for id, version := range versions {
+		if err := sw.write(&raftpb.KVEntry{MemberVersion: &raftpb.MemberVersion{Id: id, Version: version}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotVersions returns the protocol versions of the members written in
+// the snapshots, none while a member is too old to read them.
+func (s *Store) snapshotVersions() map[string]int32 {
+	if s.checkProtocolVersion([]*raftpb.Command{{Method: common.VERSION}}) != nil {
+		return nil
+	}
+	return s.versions.Versions()
+}
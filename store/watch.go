@@ -0,0 +1,80 @@
+package store
+
+import (
+	"time"
+
+	"github.com/raft-kv-store/common"
+	"github.com/raft-kv-store/raftpb"
+)
+
+// Watch replies with the changes of the keys starting with req.Prefix
+// selected by req.Filter, as the SET, DEL and EVICT commands that made them
+// at their revision, waiting up to req.Wait for some. The first poll of a
+// watcher registers it, and replies with the revision it watches from in
+// Value, and with the keys as of that revision in Entries if req.List is set.
+// Every reply carries the revision after which the changes are kept, for the
+// watchers to know from which revision they can still resume.
+func (c *Cohort) Watch(req *raftpb.WatchRequest, reply *raftpb.RPCResponse) error {
+	if c.store.witness {
+		return errWitness
+	}
+	hub := c.store.watches
+	if req.Cancel {
+		hub.Cancel(req.Id)
+		*reply = raftpb.RPCResponse{Status: 0}
+		return nil
+	}
+	var from int64
+	var entries []*raftpb.KVEntry
+	if !hub.Registered(req.Id) {
+		filter, err := common.NewWatchFilter(req.Filter)
+		if err != nil {
+			return err
+		}
+		if from, entries, err = c.store.watch(req.Id, req.Prefix, filter, req.After, req.List); err != nil {
+			return err
+		}
+	}
+	wait := time.Duration(req.Wait) * time.Millisecond
+	if wait > common.WatchMaxWait {
+		wait = common.WatchMaxWait
+	}
+	events, err := hub.Poll(req.Id, wait)
+	if err != nil {
+		return err
+	}
+	*reply = raftpb.RPCResponse{Status: 0, Commands: events, Value: from, Entries: entries, CompactRevision: c.store.events.CompactRevision()}
+	return nil
+}
+
+// watch registers the watcher id of the changes selected by filter of the
+// keys starting with prefix, catching
+// up from the event log with their changes after revision after, if not 0.
+// It returns the revision the watcher is up to date with. If list is set, the
+// watcher starts from the last revision applied instead, and watch also
+// returns the keys as of that revision.
+func (s *Store) watch(id, prefix string, filter *common.WatchFilter, after int64, list bool) (int64, []*raftpb.KVEntry, error) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	if list {
+		// no entry is applied until the watcher is registered
+		entries, err := s.kv.SnapshotPrefix(prefix, exportLockTimeout)
+		if err != nil {
+			return 0, nil, err
+		}
+		s.watches.Register(id, prefix, filter, nil)
+		return int64(s.appliedIndex), entries, nil
+	}
+	var backlog []*raftpb.Command
+	if after > 0 {
+		var err error
+		if backlog, err = s.events.Since(prefix, after); err != nil {
+			return 0, nil, err
+		}
+	}
+	s.watches.Register(id, prefix, filter, backlog)
+	if after > 0 {
+		return after, nil, nil
+	}
+	return int64(s.appliedIndex), nil, nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// witnessSnapshotMagic is the whole snapshot of a witness, which keeps no keys.
+var witnessSnapshotMagic = []byte("RKVWITN1")
+
+var (
+	// errWitness is returned by a witness for requests that need the keys.
+	errWitness = errors.New("node is a witness and stores no keys")
+	// errWitnessSnapshot is returned when restoring the snapshot of a witness
+	// on a node that stores the keys, which would lose them.
+	errWitnessSnapshot = errors.New("snapshot of a witness cannot be restored on a data node")
+)
+
+// witnessYieldInterval is how often a witness checks whether it has been
+// elected leader.
+const witnessYieldInterval = time.Second
+
+// yieldLeadership transfers the leadership of ra to another voter whenever
+// the witness is elected. A witness votes and may win an election, since its
+// log is as up to date as the others, but it cannot serve requests.
+func (s *Store) yieldLeadership(ra *raft.Raft, group string) {
+	for range time.Tick(witnessYieldInterval) {
+		if ra.State() != raft.Leader {
+			continue
+		}
+		s.log.Infof("witness elected leader of the %s group, transferring leadership", group)
+		if err := ra.LeadershipTransfer().Error(); err != nil {
+			s.log.Warnf("unable to transfer leadership of the %s group: %s", group, err)
+		}
+	}
+}
+
+// witnessSnapshot is the snapshot of a witness.
+type witnessSnapshot struct{}
+
+func (witnessSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(witnessSnapshotMagic); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (witnessSnapshot) Release() {}